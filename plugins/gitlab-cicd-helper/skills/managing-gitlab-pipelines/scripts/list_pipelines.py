@@ -36,9 +36,14 @@ Examples:
 
     # Combine filters
     list_pipelines.py --auto --ref main --status success --limit 5
+
+    # Tab/comma separated output for spreadsheets or awk
+    list_pipelines.py --auto --output tsv
+    list_pipelines.py --auto --output csv > pipelines.csv
 """
 
 import sys
+import csv
 import argparse
 from datetime import datetime, timezone
 from pathlib import Path
@@ -127,6 +132,14 @@ def parse_args():
         help="Output in JSON format"
     )
 
+    parser.add_argument(
+        "--output",
+        type=str,
+        choices=["text", "tsv", "csv"],
+        default="text",
+        help="Output format: text (default), tsv or csv with a header row"
+    )
+
     parser.add_argument(
         "--quiet", "-q",
         action="store_true",
@@ -193,6 +206,41 @@ def format_time_ago(dt_str):
         return dt_str[:10] if len(dt_str) >= 10 else dt_str
 
 
+# Stable column order for --output tsv/csv
+TABLE_COLUMNS = ["id", "status", "ref", "sha", "source", "created_at", "updated_at", "web_url"]
+
+
+def pipeline_row(p):
+    """Build a table row for a pipeline in TABLE_COLUMNS order."""
+    return [
+        str(p.id),
+        p.status,
+        p.ref,
+        p.sha if hasattr(p, 'sha') and p.sha else "",
+        p.source if hasattr(p, 'source') else "",
+        p.created_at or "",
+        p.updated_at or "",
+        p.web_url,
+    ]
+
+
+def write_table(pipelines, fmt):
+    """Write pipelines as TSV or CSV with a header row."""
+    if fmt == "csv":
+        writer = csv.writer(sys.stdout, lineterminator="\n")
+        writer.writerow(TABLE_COLUMNS)
+        writer.writerows(pipeline_row(p) for p in pipelines)
+        return
+
+    def escape(value):
+        return (value.replace("\\", "\\\\").replace("\t", "\\t")
+                .replace("\n", "\\n").replace("\r", "\\r"))
+
+    print("\t".join(TABLE_COLUMNS))
+    for p in pipelines:
+        print("\t".join(escape(v) for v in pipeline_row(p)))
+
+
 def list_pipelines(project, limit=10, ref=None, status=None, source=None, username=None):
     """List pipelines for a project.
 
@@ -229,38 +277,41 @@ def main():
     """Main entry point."""
     args = parse_args()
 
+    # Suppress progress messages when output is meant for other programs
+    machine_output = args.quiet or args.latest or args.json or args.output != "text"
+
     try:
         # Initialize configuration and client
-        if not args.quiet and not args.latest and not args.json:
+        if not machine_output:
             print("🔐 Validating tokens...")
 
         config = GitLabConfig()
         gl = config.get_gitlab_client()
 
-        if not args.quiet and not args.latest and not args.json:
+        if not machine_output:
             print("✅ Tokens validated")
 
         # Determine project identifier
         if args.auto:
-            if not args.quiet and not args.latest and not args.json:
+            if not machine_output:
                 print("🔍 Auto-resolving project...")
             resolver = ProjectResolver(gitlab_client=gl)
             project_id, project_name, project_path = resolver.resolve_from_repo()
             project = gl.projects.get(project_id)
-            if not args.quiet and not args.latest and not args.json:
+            if not machine_output:
                 print(f"✅ Project: {project_name} (ID: {project.id})\n")
         elif args.project:
             project = gl.projects.get(args.project)
-            if not args.quiet and not args.latest and not args.json:
+            if not machine_output:
                 print(f"✅ Project: {project.name} (ID: {project.id})\n")
         else:
             # Try to auto-resolve
-            if not args.quiet and not args.latest and not args.json:
+            if not machine_output:
                 print("🔍 Auto-resolving project from current directory...")
             resolver = ProjectResolver(gitlab_client=gl)
             project_id, project_name, project_path = resolver.resolve_from_repo()
             project = gl.projects.get(project_id)
-            if not args.quiet and not args.latest and not args.json:
+            if not machine_output:
                 print(f"✅ Project: {project_name} (ID: {project.id})\n")
 
         # Get pipelines
@@ -287,6 +338,9 @@ def main():
             elif args.json:
                 print("[]")
                 return 0
+            elif args.output != "text":
+                write_table([], args.output)
+                return 0
             elif args.quiet:
                 return 0
             else:
@@ -325,6 +379,10 @@ def main():
             print(json.dumps(output, indent=2))
             return 0
 
+        elif args.output != "text":
+            write_table(pipelines, args.output)
+            return 0
+
         elif args.quiet:
             # Just output pipeline IDs
             for p in pipelines:
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	create := flag.String("create", "", "Create a project access token with this name")
+	scopes := flag.String("scopes", "", "Comma-separated scopes for --create, e.g. api,read_repository")
+	accessLevel := flag.String("access-level", "maintainer", "Role of the token's bot user for --create: guest, reporter, developer, maintainer, owner")
+	expires := flag.String("expires", "90d", "Expiry for --create: YYYY-MM-DD or <days>d")
+	revoke := flag.Int("revoke", 0, "Revoke the token with this ID")
+	warnDays := flag.Int("warn-days", 30, "Flag tokens expiring within N days")
+	output := flag.String("output", "text", "Output format for the listing: text, tsv, csv")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if err := lib.ValidateOutputFormat(*output); err != nil {
+		lib.Exit("Error", err)
+	}
+	if *create != "" && *revoke != 0 {
+		lib.Usagef("--create and --revoke are mutually exclusive")
+	}
+	var req *lib.CreateAccessTokenRequest
+	if *create != "" {
+		if *scopes == "" {
+			lib.Usagef("--create needs --scopes")
+		}
+		level, err := lib.ParseAccessLevel(*accessLevel)
+		if err != nil {
+			lib.Usagef("%v", err)
+		}
+		expiresAt, err := lib.ParseExpiry(*expires, time.Now())
+		if err != nil {
+			lib.Usagef("%v", err)
+		}
+		req = &lib.CreateAccessTokenRequest{Name: *create, AccessLevel: level, ExpiresAt: expiresAt}
+		req.Scopes = lib.SplitList(*scopes)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected && *output == lib.OutputText {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+
+	switch {
+	case req != nil:
+		token, err := client.CreateProjectAccessToken(projectPath, req)
+		if err != nil {
+			lib.Exit("Error creating access token", err)
+		}
+		// The value is shown once; GitLab cannot return it again
+		if ui.Quiet {
+			fmt.Println(token.Token)
+			return
+		}
+		fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Access token #%d %s created", token.ID, token.Name)))
+		fmt.Printf("  Scopes: %s\n", strings.Join(token.Scopes, ", "))
+		fmt.Printf("  Role: %s\n", lib.AccessLevelName(token.AccessLevel))
+		fmt.Printf("  Expires: %s\n", token.ExpiresAt)
+		fmt.Printf("  Token: %s\n", token.Token)
+		fmt.Fprintln(os.Stderr, "Store the token now; it cannot be shown again.")
+
+	case *revoke != 0:
+		if err := client.RevokeProjectAccessToken(projectPath, *revoke); err != nil {
+			lib.Exit("Error revoking access token", err)
+		}
+		if ui.Quiet {
+			fmt.Println(*revoke)
+			return
+		}
+		fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Access token #%d revoked", *revoke)))
+
+	default:
+		tokens, err := client.ListProjectAccessTokens(projectPath)
+		if err != nil {
+			lib.Exit("Error listing access tokens", err)
+		}
+		listTokens(ui, tokens, *output, *warnDays)
+	}
+}
+
+// listTokens prints the tokens with their expiry, flagging those that
+// expire within warnDays
+func listTokens(ui *lib.UI, tokens []lib.AccessToken, output string, warnDays int) {
+	now := time.Now()
+	if output != lib.OutputText {
+		header := []string{"id", "name", "scopes", "role", "active", "expires_at", "days_left", "last_used_at"}
+		var rows [][]string
+		for _, t := range tokens {
+			days := ""
+			if !t.ExpiresAt.IsZero() {
+				days = strconv.Itoa(t.ExpiresAt.DaysLeft(now))
+			}
+			lastUsed := ""
+			if !t.LastUsedAt.IsZero() {
+				lastUsed = t.LastUsedAt.Format(time.RFC3339)
+			}
+			rows = append(rows, []string{strconv.Itoa(t.ID), t.Name, strings.Join(t.Scopes, ","), lib.AccessLevelName(t.AccessLevel),
+				strconv.FormatBool(t.Active), t.ExpiresAt.String(), days, lastUsed})
+		}
+		if err := lib.WriteTable(os.Stdout, output, header, rows); err != nil {
+			lib.Exit("Error", err)
+		}
+		return
+	}
+
+	if ui.Quiet {
+		for _, t := range tokens {
+			fmt.Println(t.ID)
+		}
+		return
+	}
+	if len(tokens) == 0 {
+		fmt.Println("No project access tokens")
+		return
+	}
+
+	soonCount := 0
+	for _, t := range tokens {
+		status, soon := lib.ExpiryStatus(t.ExpiresAt, now, warnDays)
+		line := fmt.Sprintf("#%d  %s  (%s; %s)  %s", t.ID, t.Name, lib.AccessLevelName(t.AccessLevel), strings.Join(t.Scopes, ", "), status)
+		if !t.Active {
+			line += "  [inactive]"
+		}
+		if soon {
+			soonCount++
+			fmt.Println(ui.Warning(line))
+		} else {
+			fmt.Println("  " + line)
+		}
+	}
+	fmt.Printf("\nTotal: %d token(s)", len(tokens))
+	if soonCount > 0 {
+		fmt.Printf(", %d expired or expiring within %d days", soonCount, warnDays)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	mrFlag := lib.RegisterMRFlag("Merge request IID, web URL or source branch (required)")
+	window := flag.Int("window", 3, "Lines around a commented line a commit must change to address the thread")
+	resolve := flag.Bool("resolve", false, "Also resolve the threads that were replied to")
+	rerequest := flag.Bool("rerequest", true, "Re-request review from the authors of addressed threads (--rerequest=false to skip)")
+	dryRun := flag.Bool("dry-run", false, "Report the matches without replying or re-requesting review")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Validate MR reference
+	if err := mrFlag.Parse(); err != nil {
+		lib.Exit("Error", err)
+	}
+	if *window < 0 {
+		lib.Usagef("--window must not be negative")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	mrIID, err := mrFlag.Resolve(client, projectPath)
+	if err != nil {
+		lib.Exit("Error finding MR", err)
+	}
+	discussions, err := client.ListMRDiscussions(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error listing discussions", err)
+	}
+	commits, err := client.ListMRCommits(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error listing commits", err)
+	}
+
+	// Only commits pushed after the oldest open thread can address one
+	threads := lib.MatchThreadCommits(discussions, nil, nil, 0)
+	if len(threads) == 0 {
+		ui.Printf("No unresolved diff threads on !%d\n", mrIID)
+		return
+	}
+	oldest := threads[0].Discussion.Notes[0].CreatedAt
+	for _, t := range threads {
+		if started := t.Discussion.Notes[0].CreatedAt; started.Before(oldest) {
+			oldest = started
+		}
+	}
+	var candidates []lib.Commit
+	for _, c := range commits {
+		if c.CreatedAt.After(oldest) {
+			candidates = append(candidates, c)
+		}
+	}
+	diffs := make([][]lib.Diff, len(candidates))
+	errs := client.ForEach(len(candidates), func(i int) error {
+		var err error
+		diffs[i], err = client.GetCommitDiff(projectPath, candidates[i].ID)
+		return err
+	})
+	commitDiffs := make(map[string][]lib.Diff)
+	for i, c := range candidates {
+		if errs[i] != nil {
+			lib.Exit("Error getting diff of "+c.ShortID, errs[i])
+		}
+		commitDiffs[c.ID] = diffs[i]
+	}
+	threads = lib.MatchThreadCommits(discussions, candidates, commitDiffs, *window)
+
+	prefix := ""
+	if *dryRun {
+		prefix = "[dry-run] "
+	}
+
+	// The token user does not re-request their own review
+	var self string
+	if *rerequest {
+		user, err := client.GetCurrentUser()
+		if err != nil {
+			lib.Exit("Error getting current user", err)
+		}
+		self = user.Username
+	}
+
+	replied := 0
+	reviewers := make(map[string]bool)
+	var firstErr error
+	for _, t := range threads {
+		d := t.Discussion
+		where := d.Notes[0].Position.Location()
+		author := d.Notes[0].Author.Username
+		if len(t.Commits) == 0 {
+			ui.Printf("  %s (thread %s, @%s): no matching commit\n", where, d.ID, author)
+			continue
+		}
+
+		shas := make([]string, len(t.Commits))
+		for i, c := range t.Commits {
+			shas[i] = c.ShortID
+		}
+		if !*dryRun {
+			if _, err := client.ReplyToDiscussion(projectPath, mrIID, d.ID, lib.AddressedPrefix+strings.Join(shas, ", ")); err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", ui.Failure(fmt.Sprintf("replying to thread %s: %v", d.ID, err)))
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			if *resolve {
+				if err := client.ResolveDiscussion(projectPath, mrIID, d.ID, true); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: could not resolve thread %s: %v\n", d.ID, err)
+				}
+			}
+		}
+		replied++
+		if author != self {
+			reviewers[author] = true
+		}
+		if ui.Quiet {
+			fmt.Printf("%s %s\n", d.ID, strings.Join(shas, ","))
+			continue
+		}
+		fmt.Printf("%s%s\n", prefix, ui.Success(fmt.Sprintf("%s (thread %s, @%s): addressed in %s", where, d.ID, author, strings.Join(shas, ", "))))
+	}
+
+	var requested []string
+	if *rerequest && len(reviewers) > 0 {
+		for r := range reviewers {
+			requested = append(requested, "@"+r)
+		}
+		sort.Strings(requested)
+		if !*dryRun {
+			if _, err := client.CreateMRNote(projectPath, mrIID, "/request_review "+strings.Join(requested, " ")); err != nil {
+				lib.Exit("Error re-requesting review", err)
+			}
+		}
+	}
+
+	ui.Printf("\n%sReplied to %d of %d unresolved thread(s) on !%d\n", prefix, replied, len(threads), mrIID)
+	if len(requested) > 0 {
+		ui.Printf("  Review re-requested: %s\n", strings.Join(requested, " "))
+	}
+	if firstErr != nil {
+		lib.ExitWith(lib.ExitCode(firstErr))
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+// maxCommentLines caps the diff quoted in an MR comment
+const maxCommentLines = 300
+
+// side is one of the two compared artifacts
+type side struct {
+	pipeline *lib.Pipeline
+	job      *lib.Job
+	data     []byte
+}
+
+func main() {
+	// Flags
+	job := flag.String("job", "", "Name of the job that produces the artifact (required)")
+	path := flag.String("path", "", "Path of the file in the job's artifacts, e.g. reports/size.json (required)")
+	from := flag.Int("from", 0, "Pipeline ID to compare from (with --to)")
+	to := flag.Int("to", 0, "Pipeline ID to compare to (with --from)")
+	mrFlag := lib.RegisterMRFlag("Merge request IID, web URL or source branch: compare its head pipeline with the target branch's latest successful one")
+	threshold := flag.Float64("threshold", 0, "Hide numeric changes smaller than this percentage")
+	maxBytes := flag.Int64("max-bytes", 10<<20, "Refuse artifacts larger than this")
+	context := flag.Int("context", 3, "Context lines around changes in a text diff")
+	comment := flag.Bool("comment", false, "Post the summary on the MR, updating the previous one for the same artifact")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *job == "" || *path == "" {
+		lib.Usagef("--job and --path are required")
+	}
+	if (*from == 0) != (*to == 0) {
+		lib.Usagef("--from and --to go together")
+	}
+	if *threshold < 0 || *maxBytes <= 0 || *context < 0 {
+		lib.Usagef("--threshold, --max-bytes and --context must be positive")
+	}
+	useMR := *from == 0 || *comment || mrFlag.Value != ""
+	if useMR {
+		if err := mrFlag.Parse(); err != nil {
+			lib.Exit("Error", err)
+		}
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectArg := lib.ProjectArg()
+	if useMR {
+		projectArg = mrFlag.ProjectArg()
+	}
+	projectPath, detected, err := projectFlags.Resolve(projectArg)
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	var mr *lib.MergeRequest
+	if useMR {
+		mrIID, err := mrFlag.Resolve(client, projectPath)
+		if err != nil {
+			lib.Exit("Error finding MR", err)
+		}
+		if mr, err = client.GetMR(projectPath, mrIID); err != nil {
+			lib.Exit("Error getting MR", err)
+		}
+	}
+
+	// Pick the two pipelines
+	sides := make([]side, 2)
+	if *from != 0 {
+		errs := client.ForEach(2, func(i int) error {
+			var err error
+			sides[i].pipeline, err = client.GetPipeline(projectPath, []int{*from, *to}[i])
+			return err
+		})
+		for _, err := range errs {
+			if err != nil {
+				lib.Exit("Error getting pipeline", err)
+			}
+		}
+	} else {
+		if mr.HeadPipeline == nil {
+			lib.Exit("Error", fmt.Errorf("%w: MR !%d has no pipeline", lib.ErrNotFound, mr.IID))
+		}
+		base, err := client.ListPipelines(projectPath, &lib.PipelineListOptions{Ref: mr.TargetBranch, Status: "success", Limit: 1})
+		if err != nil {
+			lib.Exit("Error listing pipelines", err)
+		}
+		if len(base) == 0 {
+			lib.Exit("Error", fmt.Errorf("%w: no successful pipeline on %s to compare with (use --from)", lib.ErrNotFound, mr.TargetBranch))
+		}
+		sides[0].pipeline, sides[1].pipeline = &base[0], mr.HeadPipeline
+	}
+
+	// Download the artifact of both
+	errs := client.ForEach(2, func(i int) error {
+		s := &sides[i]
+		var err error
+		if s.job, err = client.FindPipelineJob(projectPath, s.pipeline.ID, *job); err != nil {
+			return err
+		}
+		body, err := client.GetJobArtifact(projectPath, s.job.ID, *path)
+		if err != nil {
+			return fmt.Errorf("job %s (#%d): %w", s.job.Name, s.job.ID, err)
+		}
+		defer body.Close()
+		if s.data, err = io.ReadAll(io.LimitReader(body, *maxBytes+1)); err != nil {
+			return err
+		}
+		if int64(len(s.data)) > *maxBytes {
+			return fmt.Errorf("%s of job #%d is larger than %d bytes (raise --max-bytes)", *path, s.job.ID, *maxBytes)
+		}
+		return nil
+	})
+	for i, err := range errs {
+		if err != nil {
+			lib.Exit(fmt.Sprintf("Error downloading artifact of pipeline #%d", sides[i].pipeline.ID), err)
+		}
+	}
+
+	// Compare values when both are JSON, lines otherwise
+	changes, jsonErr := lib.DiffJSON(sides[0].data, sides[1].data)
+	var lines []string
+	hidden := 0
+	if jsonErr == nil {
+		shown := changes[:0]
+		for _, c := range changes {
+			if c.HasDelta && math.Abs(c.Delta)*100 < *threshold {
+				hidden++
+				continue
+			}
+			shown = append(shown, c)
+		}
+		changes = shown
+	} else if lines, err = lib.DiffLines(string(sides[0].data), string(sides[1].data), *context); err != nil {
+		lib.Exit("Error comparing artifacts", err)
+	}
+
+	if !ui.Quiet {
+		fmt.Printf("%s in job %s: pipeline #%d (%s) "+lib.Arrow+" #%d (%s)\n", *path, *job, sides[0].pipeline.ID, sides[0].pipeline.Ref, sides[1].pipeline.ID, sides[1].pipeline.Ref)
+		fmt.Printf("  From: %s\n", sides[0].job.WebURL)
+		fmt.Printf("  To: %s\n", sides[1].job.WebURL)
+		switch {
+		case jsonErr == nil:
+			printChanges(changes, hidden, *threshold)
+		case lines == nil:
+			fmt.Println("\nNo changes")
+		default:
+			fmt.Println()
+			for _, l := range lines {
+				fmt.Println(l)
+			}
+		}
+	} else if !*comment {
+		for _, c := range changes {
+			fmt.Println(c.Path)
+		}
+		for _, l := range lines {
+			fmt.Println(l)
+		}
+	}
+
+	if !*comment {
+		return
+	}
+	body := renderComment(*job, *path, sides, changes, jsonErr == nil, lines, hidden)
+	note, created, err := client.UpsertMarkedNote(projectPath, mr.IID, marker(*job, *path), body, false)
+	if err != nil {
+		lib.Exit("Error writing comment", err)
+	}
+	action := "updated"
+	if created {
+		action = "posted"
+	}
+	noteURL := fmt.Sprintf("%s#note_%d", mr.WebURL, note.ID)
+	if ui.Quiet {
+		fmt.Println(noteURL)
+		return
+	}
+	fmt.Printf("\n%s\n", ui.Success(fmt.Sprintf("Comment %s on MR !%d", action, mr.IID)))
+	fmt.Printf("  URL: %s\n", noteURL)
+}
+
+// printChanges lists the value changes of a JSON artifact
+func printChanges(changes []lib.ValueChange, hidden int, threshold float64) {
+	if len(changes) == 0 {
+		fmt.Print("\nNo changes")
+	} else {
+		fmt.Printf("\n%d change(s)", len(changes))
+	}
+	if hidden > 0 {
+		fmt.Printf(" (%d below %g%% hidden)", hidden, threshold)
+	}
+	fmt.Println()
+	for _, c := range changes {
+		fmt.Printf("  %s: %s\n", c.Path, describeChange(c))
+	}
+}
+
+// describeChange formats a value change, e.g. "1000 → 1250 (+25.0%)"
+func describeChange(c lib.ValueChange) string {
+	switch {
+	case c.Old == "":
+		return c.New + " (added)"
+	case c.New == "":
+		return c.Old + " (removed)"
+	case c.HasDelta:
+		return fmt.Sprintf("%s "+lib.Arrow+" %s (%+.1f%%)", c.Old, c.New, c.Delta*100)
+	}
+	return c.Old + " " + lib.Arrow + " " + c.New
+}
+
+// marker identifies the comment of one artifact among the MR's notes
+func marker(job, path string) string {
+	return fmt.Sprintf("<!-- gitlab-helper:artifact-diff:%s:%s -->", job, path)
+}
+
+// renderComment formats the comparison as a markdown MR comment
+func renderComment(job, path string, sides []side, changes []lib.ValueChange, isJSON bool, lines []string, hidden int) string {
+	var sb strings.Builder
+	sb.WriteString(marker(job, path) + "\n")
+	fmt.Fprintf(&sb, "### `%s` from job `%s`\n\n", path, job)
+	fmt.Fprintf(&sb, "Compared pipeline [#%d](%s) (`%s`) with [#%d](%s) (`%s`).\n\n",
+		sides[0].pipeline.ID, sides[0].pipeline.WebURL, sides[0].pipeline.Ref,
+		sides[1].pipeline.ID, sides[1].pipeline.WebURL, sides[1].pipeline.Ref)
+
+	switch {
+	case isJSON && len(changes) > 0:
+		sb.WriteString("| Value | Before | After | Change |\n|-------|--------|-------|--------|\n")
+		for _, c := range changes {
+			change := ""
+			switch {
+			case c.Old == "":
+				change = "added"
+			case c.New == "":
+				change = "removed"
+			case c.HasDelta:
+				change = fmt.Sprintf("%+.1f%%", c.Delta*100)
+			}
+			fmt.Fprintf(&sb, "| `%s` | %s | %s | %s |\n", cell(c.Path), cell(c.Old), cell(c.New), change)
+		}
+	case !isJSON && lines != nil:
+		sb.WriteString("```diff\n")
+		for i, l := range lines {
+			if i == maxCommentLines {
+				fmt.Fprintf(&sb, "... %d more line(s)\n", len(lines)-i)
+				break
+			}
+			sb.WriteString(l + "\n")
+		}
+		sb.WriteString("```\n")
+	default:
+		sb.WriteString("No changes.\n")
+	}
+	if hidden > 0 {
+		fmt.Fprintf(&sb, "\n_%d smaller numeric change(s) hidden._\n", hidden)
+	}
+	return sb.String()
+}
+
+// cell keeps a value on one table row
+func cell(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "|", `\|`), "\n", " ")
+}
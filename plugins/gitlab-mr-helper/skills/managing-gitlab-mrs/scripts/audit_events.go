@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	group := flag.String("group", "", "List the audit events of this group instead of a project")
+	instance := flag.Bool("instance", false, "List the audit events of the whole instance (administrators only)")
+	since := flag.String("since", "7d", "Oldest events to list: YYYY-MM-DD, an RFC 3339 time, or e.g. 12h, 7d, 2w")
+	until := flag.String("until", "", "Newest events to list, in the same formats as --since (default: now)")
+	author := flag.String("author", "", "Only events by this user (name or email)")
+	match := flag.String("match", "", "Only events whose description, name or target matches this regular expression (case insensitive)")
+	limit := flag.Int("limit", 100, "Maximum number of events to list (0 for all)")
+	output := flag.String("output", "text", "Output format: text, tsv, csv")
+	format := flag.String("format", "", "Go template applied to each event (e.g. '{{.CreatedAt}} {{.Details.AuthorName}} {{.Summary}}')")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if err := lib.ValidateOutputFormat(*output); err != nil {
+		lib.Exit("Error", err)
+	}
+	if *group != "" && *instance {
+		lib.Usagef("--group and --instance are mutually exclusive")
+	}
+
+	now := time.Now()
+	opts := &lib.AuditEventListOptions{}
+	var err error
+	if *since != "" {
+		if opts.CreatedAfter, err = lib.ParseTimeBound(*since, now); err != nil {
+			lib.Exit("Error", err)
+		}
+	}
+	if *until != "" {
+		if opts.CreatedBefore, err = lib.ParseTimeBound(*until, now); err != nil {
+			lib.Exit("Error", err)
+		}
+		// A date alone means the end of that day
+		if len(*until) == len("2006-01-02") {
+			opts.CreatedBefore = opts.CreatedBefore.AddDate(0, 0, 1).Add(-time.Second)
+		}
+	}
+	if !opts.CreatedBefore.IsZero() && opts.CreatedBefore.Before(opts.CreatedAfter) {
+		lib.Usagef("--until is before --since")
+	}
+	// Validate --match before calling the API
+	if _, err := lib.FilterAuditEvents(nil, "", *match); err != nil {
+		lib.Exit("Error", err)
+	}
+
+	var tmpl *lib.OutputTemplate
+	if *format != "" {
+		if tmpl, err = lib.ParseOutputTemplate(*format); err != nil {
+			lib.Exit("Error", err)
+		}
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	client := lib.NewClient(config)
+
+	// Author and pattern filters are applied locally, so --limit is too
+	var events []lib.AuditEvent
+	var scope string
+	switch {
+	case *instance:
+		scope = "the instance"
+		events, err = client.ListInstanceAuditEvents(opts)
+	case *group != "":
+		scope = *group
+		events, err = client.ListGroupAuditEvents(*group, opts)
+	default:
+		projectPath, detected, resolveErr := projectFlags.Resolve(flag.Arg(0))
+		if resolveErr != nil {
+			lib.Exit("Error resolving project", resolveErr)
+		}
+		if detected && tmpl == nil && *output == lib.OutputText {
+			ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+		}
+		scope = projectPath
+		events, err = client.ListProjectAuditEvents(projectPath, opts)
+	}
+	if err != nil {
+		lib.Exit("Error listing audit events", err)
+	}
+	if events, err = lib.FilterAuditEvents(events, *author, *match); err != nil {
+		lib.Exit("Error", err)
+	}
+	truncated := *limit > 0 && len(events) > *limit
+	if truncated {
+		events = events[:*limit]
+	}
+
+	if *output != lib.OutputText {
+		if err := lib.WriteTable(os.Stdout, *output, lib.AuditTableHeader, lib.AuditTableRows(events)); err != nil {
+			lib.Exit("Error", err)
+		}
+		return
+	}
+
+	if tmpl != nil {
+		for _, e := range events {
+			if err := tmpl.Execute(os.Stdout, e); err != nil {
+				lib.Exit("Error", err)
+			}
+		}
+		return
+	}
+
+	if ui.Quiet {
+		for _, e := range events {
+			fmt.Println(e.ID)
+		}
+		return
+	}
+
+	var bounds []string
+	if !opts.CreatedAfter.IsZero() {
+		bounds = append(bounds, "since "+opts.CreatedAfter.Local().Format("2006-01-02 15:04"))
+	}
+	if !opts.CreatedBefore.IsZero() {
+		bounds = append(bounds, "until "+opts.CreatedBefore.Local().Format("2006-01-02 15:04"))
+	}
+	if len(bounds) == 0 {
+		bounds = append(bounds, "last 30 days")
+	}
+	window := strings.Join(bounds, ", ")
+	if len(events) == 0 {
+		fmt.Printf("No audit events for %s %s\n", scope, window)
+		return
+	}
+
+	fmt.Printf("Audit events for %s (%s):\n", scope, window)
+	fmt.Println(strings.Repeat("-", 80))
+	for _, e := range events {
+		fmt.Printf("#%d  %s  %s\n", e.ID, e.CreatedAt.Local().Format("2006-01-02 15:04"), e.Summary())
+		details := []string{e.Details.AuthorName}
+		if e.Details.TargetType != "" {
+			details = append(details, e.Details.TargetType+" "+e.Details.TargetDetails)
+		}
+		if e.Details.EntityPath != "" && e.Details.EntityPath != scope && e.Details.EntityPath != e.Details.TargetDetails {
+			details = append(details, e.Details.EntityPath)
+		}
+		if e.EventName != "" {
+			details = append(details, e.EventName)
+		}
+		fmt.Printf("     %s\n", strings.Join(details, "  |  "))
+	}
+
+	fmt.Printf("\nTotal: %d event(s)", len(events))
+	if truncated {
+		fmt.Printf(" (limited to %d; use --limit 0 for all)", *limit)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// The action comes before the flags: auth.go login --keychain
+	action := "status"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	// Flags
+	keychain := flag.Bool("keychain", false, "login: keep the token in the OS keychain instead of encrypting it with a passphrase")
+	verify := flag.Bool("verify", true, "login: check the token against the API before storing it (--verify=false to skip)")
+	ui := lib.RegisterUIFlags()
+
+	flag.CommandLine.Parse(args)
+	if flag.NArg() > 0 {
+		lib.Usagef("unexpected argument %q", flag.Arg(0))
+	}
+
+	host := lib.GitLabHost()
+	switch action {
+	case "login":
+		login(ui, host, *keychain, *verify)
+	case "logout":
+		if err := lib.DeleteStoredToken(host); err != nil {
+			lib.Exit("Error removing token", err)
+		}
+		ui.Printf("%s\n", ui.Success("Token for "+host+" removed"))
+	case "status":
+		status(ui, host)
+	default:
+		lib.Usagef("unknown action %q (expected login, logout or status)", action)
+	}
+}
+
+func login(ui *lib.UI, host string, keychain, verify bool) {
+	token, err := readToken()
+	if err != nil {
+		lib.Exit("Error reading token", err)
+	}
+	if token == "" {
+		lib.Usagef("no token given")
+	}
+
+	var user *lib.User
+	if verify {
+		config, err := lib.GetConfigWithToken(token)
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+		client := lib.NewClient(config)
+		if user, err = client.GetCurrentUser(); err != nil {
+			lib.Exit("Error verifying token", err)
+		}
+	}
+
+	backend := lib.BackendPassphrase
+	var passphrase []byte
+	if keychain {
+		backend = lib.BackendKeychain
+	} else if passphrase, err = lib.Passphrase("New passphrase: ", true); err != nil {
+		lib.Exit("Error reading passphrase", err)
+	}
+	if err := lib.StoreToken(host, token, backend, passphrase); err != nil {
+		lib.Exit("Error storing token", err)
+	}
+
+	if ui.Quiet {
+		fmt.Println(lib.CredentialStorePath())
+		return
+	}
+	fmt.Printf("%s\n", ui.Success("Token stored for "+host))
+	if user != nil {
+		fmt.Printf("  User: @%s\n", user.Username)
+	}
+	fmt.Printf("  Backend: %s\n", backend)
+	fmt.Printf("  Store: %s\n", lib.CredentialStorePath())
+	if os.Getenv("GITLAB_TOKEN") != "" {
+		fmt.Fprintln(os.Stderr, "Warning: GITLAB_TOKEN is set and takes precedence over the stored token")
+	}
+}
+
+// readToken prompts for the token on a terminal, and otherwise reads it
+// from stdin so it can be piped in
+func readToken() (string, error) {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		return lib.ReadSecret(fmt.Sprintf("GitLab token for %s: ", lib.GitLabHost()))
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", nil
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func status(ui *lib.UI, host string) {
+	creds, err := lib.StoredCredentials()
+	if err != nil {
+		lib.Exit("Error reading credential store", err)
+	}
+	hosts := make([]string, 0, len(creds))
+	for h := range creds {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+
+	if ui.Quiet {
+		for _, h := range hosts {
+			fmt.Println(h)
+		}
+		return
+	}
+	if len(hosts) == 0 {
+		fmt.Printf("No stored tokens in %s\n", lib.CredentialStorePath())
+		return
+	}
+	fmt.Printf("Stored tokens (%s):\n", lib.CredentialStorePath())
+	for _, h := range hosts {
+		marker := " "
+		if h == host {
+			marker = "*"
+		}
+		fmt.Printf("%s %s  %s  stored %s\n", marker, h, creds[h].Backend, lib.FormatAge(creds[h].StoredAt))
+	}
+	if _, ok := creds[host]; ok && os.Getenv("GITLAB_TOKEN") != "" {
+		fmt.Fprintln(os.Stderr, "Warning: GITLAB_TOKEN is set and takes precedence over the stored token")
+	}
+}
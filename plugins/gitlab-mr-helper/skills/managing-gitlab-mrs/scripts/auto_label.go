@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	mrFlag := lib.RegisterMRFlag("Merge request IID, web URL or source branch (required; in a merge request pipeline, that MR)")
+	rulesFile := flag.String("rules", "", "Label rules file (default: "+lib.LabelRulesFileName+" at the repository root)")
+	prune := flag.Bool("prune", false, "Also remove labels from the rules whose files no longer change")
+	dryRun := flag.Bool("dry-run", false, "Show the label changes without making them")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Validate MR reference
+	if err := mrFlag.Parse(); err != nil {
+		lib.Exit("Error", err)
+	}
+
+	rules, err := lib.LoadLabelRules(*rulesFile)
+	if err != nil {
+		lib.Exit("Error loading label rules", err)
+	}
+	if rules == nil || len(rules.Labels) == 0 {
+		lib.Usagef("no label rules: add %s at the repository root or pass --rules", lib.LabelRulesFileName)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	mrIID, err := mrFlag.Resolve(client, projectPath)
+	if err != nil {
+		lib.Exit("Error finding MR", err)
+	}
+	mr, err := client.GetMR(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR", err)
+	}
+	diffs, err := client.GetMRDiffs(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR changes", err)
+	}
+
+	matches := rules.Suggest(diffs)
+	add, remove := rules.LabelChanges(mr.Labels, matches, *prune)
+
+	if ui.Quiet {
+		for _, l := range add {
+			fmt.Printf("+%s\n", l)
+		}
+		for _, l := range remove {
+			fmt.Printf("-%s\n", l)
+		}
+	} else {
+		fmt.Printf("MR !%d: %d label(s) match the %d changed file(s)\n", mr.IID, len(matches), len(diffs))
+		for _, m := range matches {
+			mark := "+"
+			if !lib.ContainsString(add, m.Label) {
+				mark = "="
+			}
+			fmt.Printf("  %s %s  (%s)\n", mark, m.Label, lib.FormatFileList(m.Files))
+		}
+		for _, l := range remove {
+			fmt.Printf("  - %s  (no matching changes)\n", l)
+		}
+	}
+
+	if len(add) == 0 && len(remove) == 0 {
+		ui.Printf("%s\n", ui.Success("Labels are up to date"))
+		return
+	}
+	if *dryRun {
+		ui.Printf("Dry run: no labels changed\n")
+		return
+	}
+	req := &lib.UpdateMRRequest{AddLabels: strings.Join(add, ","), RemoveLabels: strings.Join(remove, ",")}
+	if _, err := client.UpdateMR(projectPath, mr.IID, req); err != nil {
+		lib.Exit("Error updating labels", err)
+	}
+	ui.Printf("%s\n", ui.Success(fmt.Sprintf("Added %d and removed %d label(s) on MR !%d", len(add), len(remove), mr.IID)))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	file := flag.String("file", "", "Repository path of the file to blame (required)")
+	ref := flag.String("ref", "", "Branch, tag or SHA to blame at (default: the default branch)")
+	lines := flag.String("lines", "", "Only these lines: N or N-M, e.g. 120-140")
+	mrs := flag.Bool("mrs", false, "Look up the MR that brought in each commit")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *file == "" {
+		lib.Usagef("--file is required")
+	}
+	start, end := 0, 0
+	if *lines != "" {
+		var err error
+		if start, end, err = parseLines(*lines); err != nil {
+			lib.Exit("Error", err)
+		}
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	if *ref == "" {
+		if *ref, err = client.DefaultBranch(projectPath); err != nil {
+			lib.Exit("Error getting default branch", err)
+		}
+	}
+	ranges, err := client.Blame(projectPath, *file, *ref, start, end)
+	if err != nil {
+		lib.Exit("Error blaming "+*file, err)
+	}
+	blamed := lib.BlameLines(ranges, max(start, 1))
+	if len(blamed) == 0 {
+		lib.Exit("Error", fmt.Errorf("%w: %s@%s has no lines %s", lib.ErrNotFound, *file, *ref, *lines))
+	}
+
+	// The commits in order of first appearance
+	var shas []string
+	commits := make(map[string]*lib.BlameCommit)
+	for _, l := range blamed {
+		if commits[l.Commit.ID] == nil {
+			commits[l.Commit.ID] = l.Commit
+			shas = append(shas, l.Commit.ID)
+		}
+	}
+	if ui.Quiet {
+		for _, sha := range shas {
+			fmt.Println(sha)
+		}
+		return
+	}
+
+	var byCommit map[string]*lib.MergeRequest
+	if *mrs {
+		if byCommit, err = client.FindCommitMRs(projectPath, shas); err != nil {
+			lib.Exit("Error finding MRs", err)
+		}
+	}
+
+	authorWidth, numberWidth := 0, len(strconv.Itoa(blamed[len(blamed)-1].Number))
+	for _, l := range blamed {
+		authorWidth = max(authorWidth, len(l.Commit.AuthorName))
+	}
+	fmt.Printf("%s@%s:\n", *file, *ref)
+	for _, l := range blamed {
+		fmt.Printf("%s %-*s %s %*d  %s\n", lib.ShortSHA(l.Commit.ID), authorWidth, l.Commit.AuthorName,
+			l.Commit.AuthoredDate.Format("2006-01-02"), numberWidth, l.Number, l.Text)
+	}
+
+	fmt.Printf("\n%d commit(s):\n", len(shas))
+	for _, sha := range shas {
+		c := commits[sha]
+		fmt.Printf("  %s %s (%s, %s)\n", lib.ShortSHA(sha), c.Title(), c.AuthorName, lib.FormatAge(c.AuthoredDate))
+		if mr := byCommit[sha]; mr != nil {
+			fmt.Printf("    !%d %s (%s) %s\n", mr.IID, mr.Title, mr.State, mr.WebURL)
+		} else if *mrs {
+			fmt.Println("    no MR: pushed directly")
+		}
+	}
+}
+
+// parseLines parses a --lines value, N or N-M
+func parseLines(s string) (start, end int, err error) {
+	first, last, isRange := strings.Cut(s, "-")
+	start, err1 := strconv.Atoi(strings.TrimSpace(first))
+	end = start
+	var err2 error
+	if isRange {
+		end, err2 = strconv.Atoi(strings.TrimSpace(last))
+	}
+	if err1 != nil || err2 != nil || start < 1 || end < start {
+		return 0, 0, lib.UsageErrorf("invalid --lines %q (expected N or N-M, counting from 1)", s)
+	}
+	return start, end, nil
+}
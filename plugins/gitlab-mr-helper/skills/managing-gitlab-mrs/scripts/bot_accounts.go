@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// The action comes before the flags: bot_accounts.go create --username ci-bot
+	action := ""
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	// Flags
+	username := flag.String("username", "", "Username of the account (required; create: generated for service accounts when omitted)")
+	name := flag.String("name", "", "create: display name (default: the username)")
+	email := flag.String("email", "", "create: email of a regular account (required unless --service-account)")
+	serviceAccount := flag.Bool("service-account", false, "create: create a service account (GitLab Premium) instead of a regular user")
+	groups := flag.String("group", "", "create, add: comma-separated groups to add the account to")
+	role := flag.String("role", "developer", "create, add: role in the groups: guest, reporter, developer, maintainer, owner")
+	memberExpires := flag.String("member-expires", "", "create, add: end of the group membership: YYYY-MM-DD or <days>d (default: never)")
+	scopes := flag.String("scopes", "", "create, token: comma-separated scopes of a personal access token to create, e.g. api,write_repository")
+	tokenName := flag.String("token-name", "automation", "create, token: name of the token")
+	expires := flag.String("expires", "90d", "create, token: token expiry: YYYY-MM-DD or <days>d")
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.CommandLine.Parse(args)
+	defer lib.ReportStats()
+	if flag.NArg() > 0 {
+		lib.Usagef("unexpected argument %q", flag.Arg(0))
+	}
+
+	switch action {
+	case "create":
+		if !*serviceAccount && (*username == "" || *email == "") {
+			lib.Usagef("create needs --username and --email, or --service-account")
+		}
+	case "add":
+		if *username == "" || *groups == "" {
+			lib.Usagef("add needs --username and --group")
+		}
+	case "token":
+		if *username == "" || *scopes == "" {
+			lib.Usagef("token needs --username and --scopes")
+		}
+	case "":
+		lib.Usagef("an action is required: create, add or token")
+	default:
+		lib.Usagef("unknown action %q (expected create, add or token)", action)
+	}
+	now := time.Now()
+	level, err := lib.ParseAccessLevel(*role)
+	if err != nil {
+		lib.Usagef("%v", err)
+	}
+	var memberUntil lib.Date
+	if *memberExpires != "" {
+		if memberUntil, err = lib.ParseExpiry(*memberExpires, now); err != nil {
+			lib.Usagef("%v", err)
+		}
+	}
+	var tokenReq *lib.CreateAccessTokenRequest
+	if *scopes != "" {
+		expiresAt, err := lib.ParseExpiry(*expires, now)
+		if err != nil {
+			lib.Usagef("%v", err)
+		}
+		tokenReq = &lib.CreateAccessTokenRequest{Name: *tokenName, ExpiresAt: expiresAt}
+		tokenReq.Scopes = lib.SplitList(*scopes)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	client := lib.NewClient(config)
+
+	// Each step builds on the previous one, so the first failure stops
+	// the run; the steps done are printed before the error
+	var user *lib.User
+	if action == "create" {
+		if *name == "" {
+			*name = *username
+		}
+		if *serviceAccount {
+			user, err = client.CreateServiceAccount(*username, *name)
+		} else {
+			user, err = client.CreateUser(&lib.CreateUserRequest{Username: *username, Name: *name, Email: *email})
+		}
+		if err != nil {
+			lib.Exit("Error creating account", err)
+		}
+		kind := "User"
+		if *serviceAccount {
+			kind = "Service account"
+		}
+		ui.Printf("%s\n", ui.Success(fmt.Sprintf("%s @%s created (ID %d)", kind, user.Username, user.ID)))
+		if ui.Quiet && tokenReq == nil {
+			fmt.Println(user.ID)
+		}
+	} else if user, err = client.FindUser(*username); err != nil {
+		lib.Exit("Error", err)
+	}
+
+	if *groups != "" {
+		for _, group := range lib.SplitList(*groups) {
+			m, err := client.AddGroupMember(group, user.ID, level, memberUntil)
+			if err != nil {
+				lib.Exit("Error adding @"+user.Username+" to "+group, err)
+			}
+			ui.Printf("%s\n", ui.Success(fmt.Sprintf("@%s is %s of %s", user.Username, lib.AccessLevelName(m.AccessLevel), group)))
+		}
+	}
+
+	if tokenReq != nil {
+		token, err := client.CreateUserToken(user.ID, tokenReq)
+		if err != nil {
+			lib.Exit("Error creating token for @"+user.Username, err)
+		}
+		// The value is shown once; GitLab cannot return it again
+		if ui.Quiet {
+			fmt.Println(token.Token)
+			return
+		}
+		fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Personal access token #%d %s created for @%s", token.ID, token.Name, user.Username)))
+		fmt.Printf("  Scopes: %s\n", strings.Join(token.Scopes, ", "))
+		fmt.Printf("  Expires: %s\n", token.ExpiresAt)
+		fmt.Printf("  Token: %s\n", token.Token)
+		fmt.Fprintln(os.Stderr, "Store the token now; it cannot be shown again.")
+	}
+}
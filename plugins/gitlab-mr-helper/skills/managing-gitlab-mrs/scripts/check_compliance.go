@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	policyFile := flag.String("policy", "", "YAML compliance policy (default: "+lib.PolicyFileName+" at the repository root, else the built-in policy)")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	policy, path, err := lib.LoadCompliancePolicy(*policyFile)
+	if err != nil {
+		lib.Exit("Error loading compliance policy", err)
+	}
+	if path == "" {
+		path = "the built-in policy"
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	checks, err := client.CheckCompliance(projectPath, policy)
+	if err != nil {
+		lib.Exit("Error checking compliance", err)
+	}
+
+	// Quiet mode prints the failed checks only, for scripts
+	failed := 0
+	ui.Printf("Compliance of %s against %s:\n", projectPath, path)
+	for _, check := range checks {
+		if !check.Passed {
+			failed++
+		}
+		if ui.Quiet {
+			if !check.Passed {
+				fmt.Println(check.Name)
+			}
+			continue
+		}
+		if check.Passed {
+			fmt.Printf("  %s\n", ui.Success(fmt.Sprintf("%s: %s", check.Name, check.Detail)))
+			continue
+		}
+		fmt.Printf("  %s\n", ui.Failure(fmt.Sprintf("%s: %s", check.Name, check.Detail)))
+		fmt.Printf("      %s\n", check.Remediation)
+	}
+	ui.Printf("\n%d passed, %d failed\n", len(checks)-failed, failed)
+
+	if failed > 0 {
+		lib.Exit("Error", fmt.Errorf("%s fails %d of %d compliance check(s)", projectPath, failed, len(checks)))
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	mrFlag := lib.RegisterMRFlag("Merge request IID, web URL or source branch (required)")
+	check := flag.String("check", "", "Check the task matching this text or 1-based index")
+	uncheck := flag.String("uncheck", "", "Uncheck the task matching this text or 1-based index")
+	all := flag.Bool("all", false, "Apply to every matching task instead of failing when the text is ambiguous")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Validate MR reference
+	if err := mrFlag.Parse(); err != nil {
+		lib.Exit("Error", err)
+	}
+	if *check != "" && *uncheck != "" {
+		lib.Usagef("--check and --uncheck are mutually exclusive")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	mrIID, err := mrFlag.Resolve(client, projectPath)
+	if err != nil {
+		lib.Exit("Error finding MR", err)
+	}
+	mr, err := client.GetMR(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR", err)
+	}
+
+	tasks := lib.ParseTasks(mr.Description)
+
+	// Without --check/--uncheck, list the task list
+	if *check == "" && *uncheck == "" {
+		if len(tasks) == 0 {
+			ui.Printf("MR !%d has no task list\n", mr.IID)
+			return
+		}
+		done := 0
+		for _, t := range tasks {
+			if t.Checked {
+				done++
+			}
+		}
+		if ui.Quiet {
+			fmt.Printf("%d/%d\n", done, len(tasks))
+			return
+		}
+		fmt.Printf("MR !%d tasks (%d/%d done):\n", mr.IID, done, len(tasks))
+		for _, t := range tasks {
+			fmt.Printf("  %2d. %s\n", t.Index, taskLine(t))
+		}
+		return
+	}
+
+	selector, checked := *check, true
+	if *uncheck != "" {
+		selector, checked = *uncheck, false
+	}
+
+	matches := lib.FindTasks(tasks, selector)
+	if len(matches) == 0 {
+		lib.Exit("Error", fmt.Errorf("%w: no task in MR !%d matches %q", lib.ErrNotFound, mr.IID, selector))
+	}
+	if len(matches) > 1 && !*all {
+		for _, t := range matches {
+			ui.Printf("  %2d. %s\n", t.Index, taskLine(t))
+		}
+		lib.Usagef("%q matches %d tasks; use its index or --all", selector, len(matches))
+	}
+
+	var changed []lib.Task
+	for _, t := range matches {
+		if t.Checked != checked {
+			changed = append(changed, t)
+		}
+	}
+
+	if len(changed) > 0 {
+		description, err := lib.SetTasks(mr.Description, changed, checked)
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+		mr, err = client.UpdateMR(projectPath, mr.IID, &lib.UpdateMRRequest{Description: description})
+		if err != nil {
+			lib.Exit("Error updating MR", err)
+		}
+	}
+
+	if ui.Quiet {
+		fmt.Println(mr.WebURL)
+		return
+	}
+
+	for _, t := range matches {
+		t.Checked = checked
+		status := "updated"
+		if !containsTask(changed, t) {
+			status = "already set"
+		}
+		fmt.Printf("%s\n", ui.Success(fmt.Sprintf("%s (%s)", taskLine(t), status)))
+	}
+	fmt.Printf("  URL: %s\n", mr.WebURL)
+}
+
+func taskLine(t lib.Task) string {
+	if t.Checked {
+		return "[x] " + t.Text
+	}
+	return "[ ] " + t.Text
+}
+
+func containsTask(tasks []lib.Task, t lib.Task) bool {
+	for _, c := range tasks {
+		if c.Index == t.Index {
+			return true
+		}
+	}
+	return false
+}
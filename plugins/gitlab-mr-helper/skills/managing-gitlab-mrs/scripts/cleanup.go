@@ -0,0 +1,191 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	olderThan := flag.Int("older-than", 30, "Delete merged branches whose last commit is older than N days")
+	keep := flag.String("keep", "", "Regex of branch names never to delete, e.g. '^(release|stable)/'")
+	branches := flag.Bool("branches", true, "Delete stale merged branches (--branches=false to skip)")
+	orphans := flag.Bool("close-orphans", true, "Close open MRs whose source branch no longer exists (--close-orphans=false to skip)")
+	dryRun := flag.Bool("dry-run", false, "Report what would be done without changing anything")
+	notify := flag.Bool("notify", false, "Post a summary to the configured Slack/Mattermost webhook when done")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	var keepRe *regexp.Regexp
+	if *keep != "" {
+		var err error
+		if keepRe, err = regexp.Compile(*keep); err != nil {
+			lib.Usagef("invalid --keep pattern: %v", err)
+		}
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	var notifier *lib.Notifier
+	if *notify {
+		settings, err := lib.LoadSettings()
+		if err != nil {
+			lib.Exit("Error loading settings", err)
+		}
+		notifier = lib.NewNotifier(settings.Notify)
+		if notifier == nil {
+			lib.Usagef("--notify needs notify.webhook_url in settings or GITLAB_NOTIFY_WEBHOOK")
+		}
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	allBranches, err := client.ListBranches(projectPath, "")
+	if err != nil {
+		lib.Exit("Error listing branches", err)
+	}
+	openMRs, err := client.ListProjectMRs(projectPath, &lib.MRListOptions{State: "opened"})
+	if err != nil {
+		lib.Exit("Error listing MRs", err)
+	}
+
+	exists := make(map[string]bool)
+	for _, b := range allBranches {
+		exists[b.Name] = true
+	}
+	// Branches still under review are never deleted, merged or not
+	inReview := make(map[string]bool)
+	for _, mr := range openMRs {
+		if !fromFork(&mr) {
+			inReview[mr.SourceBranch] = true
+		}
+	}
+
+	prefix := ""
+	if *dryRun {
+		prefix = "[dry-run] "
+	}
+
+	// A branch that cannot be deleted or an MR that cannot be closed does not
+	// stop the cleanup; it goes into the notification as "failed: ..."
+	var report []string
+	var firstErr error
+	fail := func(msg string, err error) {
+		fmt.Fprintf(os.Stderr, "%s\n", ui.Failure(fmt.Sprintf("%s: %v", msg, err)))
+		report = append(report, fmt.Sprintf("failed: %s: %v", msg, err))
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	done := func(line string) {
+		report = append(report, line)
+		if ui.Quiet {
+			fmt.Println(line)
+			return
+		}
+		fmt.Printf("%s%s\n", prefix, ui.Success(line))
+	}
+
+	deleted := 0
+	if *branches {
+		cutoff := time.Now().AddDate(0, 0, -*olderThan)
+		var stale []lib.Branch
+		for _, b := range allBranches {
+			switch {
+			case !b.Merged, b.Protected, b.Default, inReview[b.Name]:
+				continue
+			case keepRe != nil && keepRe.MatchString(b.Name):
+				continue
+			case b.Commit.CreatedAt.After(cutoff):
+				continue
+			}
+			stale = append(stale, b)
+		}
+		errs := make([]error, len(stale))
+		if !*dryRun {
+			errs = client.ForEach(len(stale), func(i int) error {
+				return client.DeleteBranch(projectPath, stale[i].Name)
+			})
+		}
+		for i, b := range stale {
+			if errs[i] != nil {
+				fail("deleting branch "+b.Name, errs[i])
+				continue
+			}
+			delete(exists, b.Name)
+			deleted++
+			done(fmt.Sprintf("deleted branch %s (merged, last commit %s)", b.Name, lib.FormatAge(b.Commit.CreatedAt)))
+		}
+	}
+
+	closed := 0
+	if *orphans {
+		var orphaned []lib.MergeRequest
+		for _, mr := range openMRs {
+			if !fromFork(&mr) && !exists[mr.SourceBranch] {
+				orphaned = append(orphaned, mr)
+			}
+		}
+		errs := make([]error, len(orphaned))
+		if !*dryRun {
+			errs = client.ForEach(len(orphaned), func(i int) error {
+				mr := orphaned[i]
+				note := fmt.Sprintf("Closed automatically: source branch `%s` no longer exists.", mr.SourceBranch)
+				if _, err := client.CreateMRNote(projectPath, mr.IID, note); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: could not comment on !%d: %v\n", mr.IID, err)
+				}
+				_, err := client.UpdateMR(projectPath, mr.IID, &lib.UpdateMRRequest{StateEvent: "close"})
+				return err
+			})
+		}
+		for i, mr := range orphaned {
+			if errs[i] != nil {
+				fail(fmt.Sprintf("closing !%d", mr.IID), errs[i])
+				continue
+			}
+			closed++
+			done(fmt.Sprintf("closed !%d %s (source branch %s is gone)", mr.IID, mr.Title, mr.SourceBranch))
+		}
+	}
+
+	summary := fmt.Sprintf("%s%s: deleted %d branch(es), closed %d MR(s)", prefix, projectPath, deleted, closed)
+	ui.Printf("\n%s\n", summary)
+
+	if notifier != nil {
+		title := "cleanup.go: " + summary
+		if err := notifier.Send(lib.Notification{Title: title, Text: strings.Join(report, "\n"), Success: firstErr == nil}); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	if firstErr != nil {
+		lib.ExitWith(lib.ExitCode(firstErr))
+	}
+}
+
+// fromFork reports whether the MR's source branch lives in another project
+func fromFork(mr *lib.MergeRequest) bool {
+	return mr.SourceProjectID != 0 && mr.SourceProjectID != mr.ProjectID
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	agentName := flag.String("agent", "", "Show this agent's tokens and when each was last used")
+	check := flag.Bool("check", false, "Exit with code 1 when an agent is not connected")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	agents, err := client.ListClusterAgents(projectPath)
+	if err != nil {
+		lib.Exit("Error listing agents", err)
+	}
+	if *agentName != "" {
+		var found []lib.ClusterAgent
+		for _, a := range agents {
+			if a.Name == *agentName {
+				found = append(found, a)
+			}
+		}
+		if len(found) == 0 {
+			lib.Exit("Error", fmt.Errorf("%w: agent %q in %s", lib.ErrNotFound, *agentName, projectPath))
+		}
+		agents = found
+	}
+
+	tokens := make([][]lib.AgentToken, len(agents))
+	var clusters []lib.Cluster
+	errs := client.ForEach(len(agents)+1, func(i int) error {
+		var err error
+		if i == len(agents) {
+			clusters, err = client.ListClusters(projectPath)
+			return err
+		}
+		if tokens[i], err = client.ListAgentTokens(projectPath, agents[i].ID); err != nil {
+			return fmt.Errorf("agent %s: %w", agents[i].Name, err)
+		}
+		return nil
+	})
+	if err := errors.Join(errs...); err != nil {
+		lib.Exit("Error getting agent tokens", err)
+	}
+
+	now := time.Now()
+	down := 0
+	if len(agents) == 0 {
+		ui.Printf("No agents for Kubernetes registered in %s\n", projectPath)
+	} else {
+		ui.Printf("Agents for Kubernetes of %s:\n", projectPath)
+	}
+	for i, a := range agents {
+		status, last := lib.AgentConnection(tokens[i], now)
+		if status != lib.AgentConnected {
+			down++
+			if ui.Quiet {
+				fmt.Println(a.Name)
+			}
+		}
+		if ui.Quiet {
+			continue
+		}
+		line := fmt.Sprintf("%s: %s", a.Name, status)
+		if last != nil {
+			line += ", last contact " + lib.FormatAge(*last)
+		}
+		switch status {
+		case lib.AgentConnected:
+			fmt.Printf("  %s\n", ui.Success(line))
+		case lib.AgentNotConnected:
+			fmt.Printf("  %s\n", ui.Failure(line))
+		default:
+			fmt.Printf("  %s\n", ui.Warning(line))
+		}
+		fmt.Printf("      config: %s:.gitlab/agents/%s/config.yaml, %d active token(s)\n", a.ConfigProject.PathWithNamespace, a.Name, len(tokens[i]))
+		if *agentName == "" {
+			continue
+		}
+		for _, t := range tokens[i] {
+			used := "never used"
+			if t.LastUsedAt != nil {
+				used = "last used " + lib.FormatAge(*t.LastUsedAt)
+			}
+			fmt.Printf("      token %d %s: created %s, %s\n", t.ID, t.Name, lib.FormatAge(t.CreatedAt), used)
+		}
+	}
+	if down > 0 && !ui.Quiet {
+		fmt.Printf("\n%d agent(s) not connected: check that agentk runs in the cluster and can reach GitLab; its pod logs say why not\n", down)
+	}
+
+	if len(clusters) > 0 && *agentName == "" && !ui.Quiet {
+		fmt.Printf("\nCertificate-based clusters (deprecated, migrate them to agents):\n")
+		for _, c := range clusters {
+			state := "enabled"
+			if !c.Enabled {
+				state = "disabled"
+			}
+			fmt.Printf("  %s: %s, environments %s, %s\n", c.Name, c.PlatformKubernetes.APIURL, c.EnvironmentScope, state)
+		}
+	}
+
+	if *check && down > 0 {
+		lib.Exit("Error", fmt.Errorf("%d agent(s) not connected", down))
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+// template is one of the project templates the command manages
+type template struct {
+	flag, attr, title string
+	commit            bool // a commit message template, with %{variables}
+	value             *string
+}
+
+func main() {
+	// Flags
+	templates := []*template{
+		{flag: "merge-commit", attr: "merge_commit_template", title: "Merge commit", commit: true},
+		{flag: "squash-commit", attr: "squash_commit_template", title: "Squash commit", commit: true},
+		{flag: "mr-description", attr: "merge_requests_template", title: "MR description"},
+	}
+	texts := make([]*string, len(templates))
+	files := make([]*string, len(templates))
+	for i, t := range templates {
+		texts[i] = flag.String(t.flag, "", "Set the "+strings.ToLower(t.title)+" template")
+		files[i] = flag.String(t.flag+"-file", "", "Set the "+strings.ToLower(t.title)+" template from a file (- for stdin)")
+	}
+	reset := flag.String("reset", "", "Restore GitLab's default for these comma-separated templates: merge-commit, squash-commit, mr-description")
+	dryRun := flag.Bool("dry-run", false, "Show the changes without making them")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	stdin := 0
+	for i, t := range templates {
+		if set[t.flag] && set[t.flag+"-file"] {
+			lib.Usagef("--%s and --%s-file are mutually exclusive", t.flag, t.flag)
+		}
+		switch {
+		case set[t.flag]:
+			templates[i].value = texts[i]
+		case set[t.flag+"-file"]:
+			if *files[i] == "-" {
+				if stdin++; stdin > 1 {
+					lib.Usagef("only one template can be read from stdin")
+				}
+			}
+			text, err := lib.ReadTextFile(*files[i])
+			if err != nil {
+				lib.Exit("Error reading the "+strings.ToLower(t.title)+" template", err)
+			}
+			text = strings.TrimRight(text, "\n")
+			templates[i].value = &text
+		}
+	}
+	for _, name := range lib.SplitList(*reset) {
+		t := findTemplate(templates, name)
+		if t == nil {
+			lib.Usagef("--reset: unknown template %q, expected merge-commit, squash-commit or mr-description", name)
+		}
+		if t.value != nil {
+			lib.Usagef("--reset %s conflicts with --%s", name, name)
+		}
+		empty := ""
+		t.value = &empty
+	}
+	changing := false
+	for _, t := range templates {
+		if t.value == nil {
+			continue
+		}
+		changing = true
+		if t.commit && len(*t.value) > lib.CommitTemplateMaxLength {
+			lib.Usagef("the %s template is %d characters long, GitLab accepts %d at most", strings.ToLower(t.title), len(*t.value), lib.CommitTemplateMaxLength)
+		}
+		if unknown := lib.UnknownTemplateVariables(*t.value); t.commit && len(unknown) > 0 {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.Warning(fmt.Sprintf("The %s template uses unknown variables, which GitLab leaves as is: %%{%s}",
+				strings.ToLower(t.title), strings.Join(unknown, "}, %{"))))
+		}
+	}
+	if *dryRun && !changing {
+		lib.Usagef("--dry-run needs a template to change")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	settings, err := client.GetProjectSettings(projectPath)
+	if err != nil {
+		lib.Exit("Error getting project settings", err)
+	}
+	current := map[string]string{
+		"merge_commit_template":   settings.MergeCommitTemplate,
+		"squash_commit_template":  settings.SquashCommitTemplate,
+		"merge_requests_template": settings.MergeRequestsTemplate,
+	}
+
+	if !changing {
+		ui.Printf("Templates of %s:\n", projectPath)
+		for _, t := range templates {
+			text := current[t.attr]
+			if text != "" && ui.Quiet {
+				fmt.Println(t.flag)
+			}
+			if text == "" {
+				ui.Printf("  %s: default\n", t.title)
+				continue
+			}
+			ui.Printf("  %s:\n", t.title)
+			for _, line := range strings.Split(text, "\n") {
+				ui.Printf("%s\n", strings.TrimRight("      "+line, " "))
+			}
+		}
+		ui.Printf("\nWithout a description template, new MRs use .gitlab/merge_request_templates/Default.md when the repository has one\n")
+		return
+	}
+
+	changes := map[string]interface{}{}
+	prefix := ""
+	if *dryRun {
+		prefix = "[dry-run] "
+	}
+	for _, t := range templates {
+		if t.value == nil || *t.value == current[t.attr] {
+			continue
+		}
+		changes[t.attr] = *t.value
+		if ui.Quiet {
+			fmt.Println(t.flag)
+			continue
+		}
+		fmt.Printf("%s~ %s: %s "+lib.Arrow+" %s\n", prefix, t.title, shown(current[t.attr]), shown(*t.value))
+	}
+	if len(changes) == 0 {
+		ui.Printf("%s already uses these templates\n", projectPath)
+		return
+	}
+	if *dryRun {
+		return
+	}
+	if err := client.UpdateProjectSettings(projectPath, changes); err != nil {
+		lib.Exit("Error updating templates", err)
+	}
+	ui.Printf("%s\n", ui.Success(fmt.Sprintf("Updated %d template(s) of %s", len(changes), projectPath)))
+}
+
+// findTemplate finds a template by flag name
+func findTemplate(templates []*template, name string) *template {
+	for _, t := range templates {
+		if t.flag == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// shown quotes a template on one line, or names GitLab's default
+func shown(s string) string {
+	if s == "" {
+		return "default"
+	}
+	return fmt.Sprintf("%q", s)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+// reportID tells the components comment apart from other status comments
+const reportID = "components"
+
+func main() {
+	// Flags
+	mrFlag := lib.RegisterMRFlag("Merge request IID, web URL or source branch (required; in a merge request pipeline, that MR)")
+	configFile := flag.String("config", "", "Components file (default: "+lib.ComponentsFileName+" at the repository root)")
+	labels := flag.Bool("labels", false, "Add the labels of the affected components to the MR")
+	assign := flag.Bool("assign", false, "Add the owners of the affected components as reviewers")
+	trigger := flag.Bool("trigger", false, "Run the downstream pipelines of the affected components")
+	comment := flag.Bool("comment", false, "List the affected components in a comment, and keep an existing one up to date")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Validate MR reference
+	if err := mrFlag.Parse(); err != nil {
+		lib.Exit("Error", err)
+	}
+
+	components, err := lib.LoadComponents(*configFile)
+	if err != nil {
+		lib.Exit("Error loading components", err)
+	}
+	if components == nil || len(components.Components) == 0 {
+		lib.Usagef("no components: add %s at the repository root or pass --config", lib.ComponentsFileName)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	mrIID, err := mrFlag.Resolve(client, projectPath)
+	if err != nil {
+		lib.Exit("Error finding MR", err)
+	}
+	mr, err := client.GetMR(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR", err)
+	}
+	diffs, err := client.GetMRDiffs(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR changes", err)
+	}
+
+	affected := components.Affected(diffs)
+	if ui.Quiet {
+		for _, a := range affected {
+			fmt.Println(a.Name)
+		}
+	} else {
+		fmt.Printf("MR !%d affects %d of %d component(s):\n", mr.IID, len(affected), len(components.Components))
+		for _, a := range affected {
+			fmt.Printf("  %s  (%s)\n", a.Name, lib.FormatFileList(a.Files))
+			var details []string
+			if len(a.Owners) > 0 {
+				details = append(details, "owners: "+mentions(lib.ComponentOwners([]lib.AffectedComponent{a})))
+			}
+			if len(a.Labels) > 0 {
+				details = append(details, "labels: "+strings.Join(a.Labels, ", "))
+			}
+			if a.Pipeline != nil {
+				details = append(details, "downstream pipeline")
+			}
+			if len(details) > 0 {
+				fmt.Printf("     %s\n", strings.Join(details, "  |  "))
+			}
+		}
+	}
+
+	// Labels and reviewers go in one update. A failed action does not stop
+	// the later ones (pipelines, comment); the failures are reported at the
+	// end and decide the exit code.
+	var errs []error
+	req := &lib.UpdateMRRequest{}
+	var addLabels, addReviewers []string
+	if *labels {
+		for _, l := range lib.ComponentLabels(affected) {
+			if !lib.ContainsString(mr.Labels, l) {
+				addLabels = append(addLabels, l)
+			}
+		}
+		req.AddLabels = strings.Join(addLabels, ",")
+	}
+	if *assign {
+		skip := []string{mr.Author.Username}
+		for _, r := range mr.Reviewers {
+			skip = append(skip, r.Username)
+		}
+		if owners := lib.ComponentOwners(affected, skip...); len(owners) > 0 {
+			ids, unknown, err := client.MemberIDs(projectPath, owners)
+			switch {
+			case err != nil:
+				errs = append(errs, fmt.Errorf("listing project members: %w", err))
+			case len(ids) > 0:
+				for _, o := range owners {
+					if !lib.ContainsString(unknown, o) {
+						addReviewers = append(addReviewers, o)
+					}
+				}
+				for _, r := range mr.Reviewers {
+					req.ReviewerIDs = append(req.ReviewerIDs, r.ID)
+				}
+				req.ReviewerIDs = append(req.ReviewerIDs, ids...)
+			}
+			if len(unknown) > 0 {
+				errs = append(errs, fmt.Errorf("%w: owners not project members: %s", lib.ErrNotFound, strings.Join(unknown, ", ")))
+			}
+		}
+	}
+	if req.AddLabels != "" || req.ReviewerIDs != nil {
+		if _, err := client.UpdateMR(projectPath, mr.IID, req); err != nil {
+			errs = append(errs, fmt.Errorf("updating MR: %w", err))
+		} else {
+			if len(addLabels) > 0 {
+				ui.Printf("%s\n", ui.Success("Labels added: "+strings.Join(addLabels, ", ")))
+			}
+			if len(addReviewers) > 0 {
+				ui.Printf("%s\n", ui.Success("Reviewers added: "+mentions(addReviewers)))
+			}
+		}
+	}
+
+	pipelines := make(map[string]*lib.Pipeline)
+	if *trigger {
+		for _, a := range affected {
+			if a.Pipeline == nil {
+				continue
+			}
+			p, project, err := client.RunComponentPipeline(projectPath, mr, a.Component)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("pipeline of %s in %s: %w", a.Name, project, err))
+				continue
+			}
+			pipelines[a.Name] = p
+			ui.Printf("%s\n", ui.Success(fmt.Sprintf("Pipeline of %s: %s", a.Name, p.WebURL)))
+		}
+	}
+
+	if *comment {
+		note, err := writeComment(client, projectPath, mr, affected, pipelines)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("components comment: %w", err))
+		} else if note != nil {
+			ui.Printf("  Comment: %s#note_%d\n", mr.WebURL, note.ID)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		lib.Exit("Error", err)
+	}
+}
+
+// writeComment lists the affected components in the MR's components
+// comment, creating it only when some component is affected. It returns a
+// nil note when there was nothing to write.
+func writeComment(client *lib.Client, projectPath string, mr *lib.MergeRequest, affected []lib.AffectedComponent, pipelines map[string]*lib.Pipeline) (*lib.Note, error) {
+	report := &lib.StatusReport{ID: reportID, Title: "Affected components", UpdatedAt: time.Now()}
+	for _, a := range affected {
+		row := lib.StatusRow{Name: a.Name, Status: "affected", Details: "Files: " + lib.FormatFileList(a.Files)}
+		if len(a.Owners) > 0 {
+			row.Details += "; owners: " + mentions(lib.ComponentOwners([]lib.AffectedComponent{a}))
+		}
+		if p := pipelines[a.Name]; p != nil {
+			row.Status = p.Status
+			row.Details += fmt.Sprintf("; [pipeline #%d](%s)", p.ID, p.WebURL)
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	if len(affected) == 0 {
+		report.Rows = []lib.StatusRow{{Name: "All components", Status: "skipped", Details: "No component is affected"}}
+	}
+	note, _, err := client.UpsertStatusReport(projectPath, mr.IID, report, len(affected) == 0)
+	return note, err
+}
+
+// mentions formats usernames as @mentions
+func mentions(usernames []string) string {
+	out := make([]string, len(usernames))
+	for i, u := range usernames {
+		out[i] = "@" + u
+	}
+	return strings.Join(out, ", ")
+}
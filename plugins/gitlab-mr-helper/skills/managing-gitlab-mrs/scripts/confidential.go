@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	issueIID := flag.Int("issue", 0, "IID of the issue to make confidential (required)")
+	public := flag.Bool("public", false, "Make the issue visible to everyone who can see the project instead")
+	lock := flag.Bool("lock", false, "Also lock the issue's discussion to project members")
+	comment := flag.String("comment", "", "Explain the change in a comment, posted first")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *issueIID <= 0 {
+		lib.Usagef("--issue is required")
+	}
+	if *public && *lock {
+		lib.Usagef("--lock goes with making an issue confidential")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	issue, err := client.GetIssue(projectPath, *issueIID)
+	if err != nil {
+		lib.Exit("Error getting issue", err)
+	}
+
+	confidential := !*public
+	req := &lib.UpdateIssueRequest{}
+	if issue.Confidential != confidential {
+		req.Confidential = &confidential
+	}
+	if *lock && !issue.DiscussionLocked {
+		req.DiscussionLocked = lock
+	}
+	if req.Confidential == nil && req.DiscussionLocked == nil {
+		if ui.Quiet {
+			fmt.Println(issue.WebURL)
+		}
+		ui.Printf("Issue #%d is already %s\n", issue.IID, visibility(issue))
+		return
+	}
+
+	if *comment != "" {
+		// Posted while the issue still has its old visibility: a comment
+		// announcing it is going public stays confidential until then
+		if _, err := client.CreateIssueNote(projectPath, issue.IID, *comment); err != nil {
+			lib.Exit("Error commenting", err)
+		}
+	}
+	if issue, err = client.UpdateIssue(projectPath, issue.IID, req); err != nil {
+		lib.Exit("Error updating issue", err)
+	}
+	if ui.Quiet {
+		fmt.Println(issue.WebURL)
+		return
+	}
+	fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Issue #%d is now %s", issue.IID, visibility(issue))))
+	if !issue.Confidential {
+		fmt.Printf("%s\n", ui.Warning("Everyone who can see the project can read it, including its earlier comments"))
+	}
+	fmt.Printf("  URL: %s\n", issue.WebURL)
+}
+
+// visibility describes who can see and comment on an issue
+func visibility(issue *lib.Issue) string {
+	s := "public"
+	if issue.Confidential {
+		s = "confidential"
+	}
+	if issue.DiscussionLocked {
+		s += ", with its discussion locked"
+	}
+	return s
+}
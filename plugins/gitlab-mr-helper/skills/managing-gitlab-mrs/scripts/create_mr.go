@@ -4,8 +4,6 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"os/exec"
-	"strings"
 
 	"gitlab-mr-helper/lib"
 )
@@ -18,61 +16,82 @@ func main() {
 	description := flag.String("description", "", "MR description")
 	labels := flag.String("labels", "", "Comma-separated labels")
 	removeSource := flag.Bool("remove-source-branch", false, "Remove source branch after merge")
-	auto := flag.Bool("auto", false, "Auto-detect project from git remote")
+	projectFlags := lib.RegisterProjectFlags()
+	push := flag.Bool("push", false, "Push the source branch to the GitLab remote first if it has no upstream")
+	requireUpToDate := flag.Bool("require-up-to-date", false, "Fail if the source branch is behind the target branch")
+	skipLint := flag.Bool("skip-lint", false, "Skip branch-name and commit-message lint rules from settings")
+	skipSecretScan := flag.Bool("skip-secret-scan", false, "Create the MR even if its diff or description contains likely credentials")
+	interactive := flag.Bool("interactive", false, "Prompt for title, description, target, labels and reviewers before creating")
+	idempotent := flag.Bool("idempotent", true, "Return the existing open MR for the branch pair instead of failing (--idempotent=false to fail)")
+	open := flag.Bool("open", false, "Open the new (or existing) MR in the browser")
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
 
 	flag.Parse()
+	defer lib.ReportStats()
 
 	// Get configuration
 	config, err := lib.GetConfig()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		lib.Exit("Error", err)
 	}
 
 	// Get project path
-	var projectPath string
-	if *auto {
-		projectPath, err = lib.GetProjectFromGit()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error resolving project: %v\n", err)
-			os.Exit(1)
-		}
-		fmt.Printf("✓ Project: %s\n", projectPath)
-	} else {
-		projectPath = flag.Arg(0)
-		if projectPath == "" {
-			fmt.Fprintf(os.Stderr, "Error: project path required (use --auto or provide as argument)\n")
-			os.Exit(1)
-		}
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
 	}
 
 	// Get current branch if source not specified
 	source := *sourceBranch
 	if source == "" {
-		cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-		output, err := cmd.Output()
+		source, err = lib.GetCurrentBranch()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error getting current branch: %v\n", err)
-			os.Exit(1)
+			lib.Exit("Error getting current branch", err)
 		}
-		source = strings.TrimSpace(string(output))
+	}
+
+	// A local-only branch must reach the remote before GitLab can open an MR
+	if lib.NeedsPush(source) {
+		if *push {
+			remote, _, err := lib.FindGitLabRemote(projectFlags.Remote)
+			if err != nil {
+				lib.Exit("Error finding git remote", err)
+			}
+			ui.Printf("Pushing %s to %s...\n", source, remote)
+			if err := lib.PushBranch(remote, source); err != nil {
+				lib.Exit("Error pushing branch", err)
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: branch %s has no upstream; use --push to push it before creating the MR\n", source)
+		}
+	}
+
+	settings, err := lib.LoadSettings()
+	if err != nil {
+		lib.Exit("Error loading settings", err)
 	}
 
 	// Generate title from branch name if not specified
 	mrTitle := *title
 	if mrTitle == "" {
-		mrTitle = generateTitleFromBranch(source)
+		mrTitle, err = lib.BuildTitle(source, settings.Title.Template)
+		if err != nil {
+			lib.Exit("Error generating title", err)
+		}
 	}
 
 	// Parse labels
 	var labelList []string
 	if *labels != "" {
-		labelList = strings.Split(*labels, ",")
-		for i, l := range labelList {
-			labelList[i] = strings.TrimSpace(l)
-		}
+		labelList = lib.SplitList(*labels)
 	}
 
+	client := lib.NewClient(config)
+
 	// Create MR request
 	req := &lib.CreateMRRequest{
 		SourceBranch:       source,
@@ -83,37 +102,230 @@ func main() {
 		RemoveSourceBranch: *removeSource,
 	}
 
-	fmt.Printf("Creating MR: %s → %s\n", source, *targetBranch)
-	fmt.Printf("  Title: %s\n", mrTitle)
+	// Let a human review and adjust every field before anything is submitted
+	var prompter *lib.Prompter
+	if *interactive {
+		prompter = lib.NewPrompter(os.Stdin, os.Stderr)
+		if err := runWizard(prompter, client, projectPath, req); err != nil {
+			lib.Exit("Error", err)
+		}
+	}
 
-	// Create API client and submit
-	client := lib.NewClient(config)
+	// Re-running the workflow must not create duplicates or hit GitLab's 409
+	existing, err := client.FindOpenMR(projectPath, source, req.TargetBranch)
+	if err != nil {
+		lib.Exit("Error checking for existing MR", err)
+	}
+	if existing != nil {
+		if !*idempotent {
+			lib.Exit("Error creating MR", fmt.Errorf("%w: MR !%d already exists for %s "+lib.Arrow+" %s: %s",
+				lib.ErrBlocked, existing.IID, source, req.TargetBranch, existing.WebURL))
+		}
+		if *open {
+			defer lib.OpenURLOrWarn(existing.WebURL)
+		}
+		if ui.Quiet {
+			fmt.Println(existing.WebURL)
+			return
+		}
+		fmt.Printf("%s\n", ui.Success(fmt.Sprintf("MR !%d already exists for %s "+lib.Arrow+" %s", existing.IID, source, req.TargetBranch)))
+		fmt.Printf("  URL: %s\n", existing.WebURL)
+		fmt.Printf("  State: %s\n", ui.State(existing.State))
+		return
+	}
+
+	// Preflight: a source branch behind its target needs a rebase right away
+	warning, err := client.CheckUpToDate(projectPath, source, req.TargetBranch, *requireUpToDate)
+	if err != nil {
+		lib.Exit("Error creating MR", err)
+	}
+	if warning != "" {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	// The commits and diff the MR would add, fetched once for the checks
+	// below that need them
+	var mrCmp *lib.Comparison
+	var mrCmpErr error
+	compare := func() (*lib.Comparison, error) {
+		if mrCmp == nil && mrCmpErr == nil {
+			mrCmp, mrCmpErr = client.CompareRefs(projectPath, req.TargetBranch, source)
+		}
+		return mrCmp, mrCmpErr
+	}
+
+	// Lint branch name and commit messages against the configured rules
+	if !*skipLint && settings.Lint.Enabled() {
+		violations, err := lintMR(&settings.Lint, source, compare)
+		if err != nil {
+			lib.Exit("Error linting MR", err)
+		}
+		if len(violations) > 0 {
+			fmt.Fprintf(os.Stderr, "Lint: %d violation(s):\n", len(violations))
+			for _, v := range violations {
+				fmt.Fprintf(os.Stderr, "  "+lib.Bullet+" %s\n", v)
+			}
+			if settings.Lint.Block {
+				lib.Exit("Error creating MR", fmt.Errorf("%w: lint violations (fix them or pass --skip-lint)", lib.ErrBlocked))
+			}
+		}
+	}
+
+	// Link tickets referenced by the branch or its commits to the external tracker
+	if settings.Tracker.Enabled() {
+		var commits []lib.Commit
+		if cmp, err := compare(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not list commits for ticket links: %v\n", err)
+		} else {
+			commits = cmp.Commits
+		}
+		tickets, err := lib.FindTickets(&settings.Tracker, source, commits)
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+		req.Description = lib.AppendTicketRefs(&settings.Tracker, req.Description, tickets)
+	}
+
+	// Refuse to publish likely credentials; server-side secret detection
+	// only reports them once they are pushed and visible in the MR
+	if !*skipSecretScan && !settings.Secrets.Disabled {
+		scanner, err := lib.NewSecretScanner(&settings.Secrets)
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+		var diffs []lib.Diff
+		if cmp, err := compare(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not scan the diff for secrets: %v\n", err)
+		} else {
+			diffs = cmp.Diffs
+			lib.WarnPartialScan(diffs)
+		}
+		if findings := scanner.ScanMR(diffs, req.Title, req.Description); len(findings) > 0 {
+			fmt.Fprintf(os.Stderr, "Secrets: %d likely credential(s):\n", len(findings))
+			for _, f := range findings {
+				fmt.Fprintf(os.Stderr, "  "+lib.Bullet+" %s\n", f)
+			}
+			lib.Exit("Error creating MR", fmt.Errorf("%w: likely secrets (remove them or pass --skip-secret-scan)", lib.ErrBlocked))
+		}
+	}
+
+	if prompter != nil {
+		ok, err := prompter.Confirm(fmt.Sprintf("Create MR %q (%s "+lib.Arrow+" %s)?", req.Title, source, req.TargetBranch), true)
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Aborted; no MR created")
+			lib.ExitWith(lib.ExitError)
+		}
+	}
+
+	ui.Printf("Creating MR: %s "+lib.Arrow+" %s\n", source, req.TargetBranch)
+	ui.Printf("  Title: %s\n", req.Title)
+
+	// Submit
 	mr, err := client.CreateMR(projectPath, req)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating MR: %v\n", err)
-		os.Exit(1)
+		lib.Exit("Error creating MR", err)
+	}
+	if *open {
+		defer lib.OpenURLOrWarn(mr.WebURL)
+	}
+
+	if ui.Quiet {
+		fmt.Println(mr.WebURL)
+		return
 	}
 
-	fmt.Printf("\n✓ MR !%d created successfully\n", mr.IID)
+	fmt.Printf("\n%s\n", ui.Success(fmt.Sprintf("MR !%d created successfully", mr.IID)))
 	fmt.Printf("  URL: %s\n", mr.WebURL)
-	fmt.Printf("  State: %s\n", mr.State)
+	fmt.Printf("  State: %s\n", ui.State(mr.State))
+}
+
+// lintMR checks the source branch name and the commits it adds on top of
+// the target branch, which compare is only asked for when commits are linted
+func lintMR(settings *lib.LintSettings, source string, compare func() (*lib.Comparison, error)) ([]lib.LintViolation, error) {
+	violations, err := lib.LintBranch(settings, source)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.CommitPattern == "" && !settings.ConventionalCommits {
+		return violations, nil
+	}
+	cmp, err := compare()
+	if err != nil {
+		return nil, err
+	}
+	commitViolations, err := lib.LintCommits(settings, cmp.Commits)
+	if err != nil {
+		return nil, err
+	}
+	return append(violations, commitViolations...), nil
 }
 
-func generateTitleFromBranch(branch string) string {
-	// Remove common prefixes
-	branch = strings.TrimPrefix(branch, "feature/")
-	branch = strings.TrimPrefix(branch, "fix/")
-	branch = strings.TrimPrefix(branch, "bugfix/")
-	branch = strings.TrimPrefix(branch, "hotfix/")
+// runWizard prompts for the MR fields, offering the current values as
+// defaults and the project's branches, labels and members as choices
+func runWizard(p *lib.Prompter, client *lib.Client, projectPath string, req *lib.CreateMRRequest) error {
+	var err error
+	if req.Title, err = p.Ask("Title", req.Title); err != nil {
+		return err
+	}
+	if req.Description, err = p.Ask("Description", req.Description); err != nil {
+		return err
+	}
 
-	// Replace separators with spaces
-	branch = strings.ReplaceAll(branch, "-", " ")
-	branch = strings.ReplaceAll(branch, "_", " ")
+	branches, err := client.ListBranches(projectPath, "")
+	if err != nil {
+		return fmt.Errorf("failed to list branches: %w", err)
+	}
+	var targets []string
+	for _, b := range branches {
+		if b.Name != req.SourceBranch {
+			targets = append(targets, b.Name)
+		}
+	}
+	if req.TargetBranch, err = p.Choose("Target branch", targets, req.TargetBranch); err != nil {
+		return err
+	}
+
+	labels, err := client.ListLabels(projectPath)
+	if err != nil {
+		return fmt.Errorf("failed to list labels: %w", err)
+	}
+	var labelNames []string
+	for _, l := range labels {
+		labelNames = append(labelNames, l.Name)
+	}
+	if req.Labels, err = p.ChooseMany("Labels", labelNames, req.Labels); err != nil {
+		return err
+	}
 
-	// Capitalize first letter
-	if len(branch) > 0 {
-		branch = strings.ToUpper(string(branch[0])) + branch[1:]
+	members, err := client.ListProjectMembers(projectPath)
+	if err != nil {
+		return fmt.Errorf("failed to list members: %w", err)
+	}
+	me, err := client.GetCurrentUser()
+	if err != nil {
+		return err
+	}
+	var usernames []string
+	ids := make(map[string]int)
+	for _, m := range members {
+		if m.ID != me.ID && m.State == "active" {
+			usernames = append(usernames, m.Username)
+			ids[m.Username] = m.ID
+		}
+	}
+	reviewers, err := p.ChooseMany("Reviewers", usernames, nil)
+	if err != nil {
+		return err
+	}
+	req.ReviewerIDs = nil
+	for _, r := range reviewers {
+		req.ReviewerIDs = append(req.ReviewerIDs, ids[r])
 	}
 
-	return branch
+	req.RemoveSourceBranch, err = p.Confirm("Remove source branch after merge?", req.RemoveSourceBranch)
+	return err
 }
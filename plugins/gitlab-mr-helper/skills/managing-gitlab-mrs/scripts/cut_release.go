@@ -0,0 +1,166 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	versionFlag := flag.String("version", "", "Release version x.y[.z] (default: next minor after the newest version tag)")
+	from := flag.String("from", "", "Branch to cut from (default: the project's default branch)")
+	issue := flag.Bool("issue", true, "Open a tracking issue with the release checklist (--issue=false to skip)")
+	protect := flag.Bool("protect", true, "Protect the release branch: no direct pushes, maintainers merge (--protect=false to skip)")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	settings, err := lib.LoadSettings()
+	if err != nil {
+		lib.Exit("Error loading settings", err)
+	}
+	rs := settings.Release
+
+	client := lib.NewClient(config)
+
+	var version lib.Version
+	if *versionFlag != "" {
+		if version, err = lib.ParseVersion(*versionFlag); err != nil {
+			lib.Usagef("invalid --version: %v", err)
+		}
+	} else {
+		if version, err = nextMinor(client, projectPath); err != nil {
+			lib.Exit("Error determining version", err)
+		}
+	}
+
+	source := *from
+	if source == "" {
+		if source, err = client.DefaultBranch(projectPath); err != nil {
+			lib.Exit("Error finding default branch (use --from)", err)
+		}
+	}
+
+	tmpl := rs.Branch
+	if tmpl == "" {
+		tmpl = lib.DefaultReleaseBranch
+	}
+	releaseBranch := version.Expand(tmpl)
+
+	ui.Printf("Cutting %s from %s (version %s)\n", releaseBranch, source, version)
+	if _, err := client.CreateBranch(projectPath, releaseBranch, source); err != nil {
+		lib.Exit("Error creating branch", err)
+	}
+
+	// Bump every version file in a single commit on the release branch
+	var actions []lib.CommitAction
+	for _, vf := range rs.VersionFiles {
+		file, err := client.GetFile(projectPath, vf.Path, releaseBranch)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not read %s: %v\n", vf.Path, err)
+			continue
+		}
+		text, err := file.Text()
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+		bumped, changed, err := lib.BumpVersion(text, vf.Pattern, version.String())
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+		if !changed {
+			fmt.Fprintf(os.Stderr, "Warning: no version to bump in %s\n", vf.Path)
+			continue
+		}
+		actions = append(actions, lib.CommitAction{Action: "update", FilePath: vf.Path, Content: bumped})
+	}
+	var bump *lib.Commit
+	if len(actions) > 0 {
+		bump, err = client.CreateCommit(projectPath, &lib.CreateCommitRequest{
+			Branch:        releaseBranch,
+			CommitMessage: fmt.Sprintf("Bump version to %s", version),
+			Actions:       actions,
+		})
+		if err != nil {
+			lib.Exit("Error bumping version files", err)
+		}
+	}
+
+	var tracking *lib.Issue
+	if *issue {
+		checklist := rs.Checklist
+		if checklist == nil {
+			checklist = lib.DefaultReleaseChecklist
+		}
+		labels := rs.IssueLabels
+		if labels == nil {
+			labels = []string{"release"}
+		}
+		var desc strings.Builder
+		fmt.Fprintf(&desc, "Tracking issue for release %s, cut from `%s` into `%s`.\n\n", version, source, releaseBranch)
+		for _, item := range checklist {
+			fmt.Fprintf(&desc, "- [ ] %s\n", item)
+		}
+		tracking, err = client.CreateIssue(projectPath, &lib.CreateIssueRequest{
+			Title:       fmt.Sprintf("Release %s", version),
+			Description: desc.String(),
+			Labels:      labels,
+		})
+		if err != nil {
+			lib.Exit("Error creating tracking issue", err)
+		}
+	}
+
+	if *protect {
+		if err := client.ProtectBranch(projectPath, releaseBranch, lib.AccessNoOne, lib.AccessMaintainer); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not protect %s: %v\n", releaseBranch, err)
+		}
+	}
+
+	if ui.Quiet {
+		fmt.Println(releaseBranch)
+		return
+	}
+
+	fmt.Printf("\n%s\n", ui.Success(fmt.Sprintf("Release branch %s created", releaseBranch)))
+	if bump != nil {
+		fmt.Printf("  Version bump: %s (%d files)\n", bump.ShortID, len(actions))
+	}
+	if tracking != nil {
+		fmt.Printf("  Tracking issue: #%d %s\n", tracking.IID, tracking.WebURL)
+	}
+}
+
+// nextMinor returns the minor version after the highest version tag
+func nextMinor(client *lib.Client, projectPath string) (lib.Version, error) {
+	tag, v, err := client.HighestVersionTag(projectPath)
+	if err != nil {
+		return lib.Version{}, err
+	}
+	if tag == nil {
+		return lib.Version{}, fmt.Errorf("%w: no version tag to derive the release from; use --version", lib.ErrNotFound)
+	}
+	return lib.Version{Major: v.Major, Minor: v.Minor + 1}, nil
+}
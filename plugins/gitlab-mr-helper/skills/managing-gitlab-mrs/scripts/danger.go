@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+// reportID tells the rules comment apart from other status comments
+const reportID = "danger"
+
+// statuses are the status comment statuses of rule levels
+var statuses = map[string]string{lib.DangerFail: "failed", lib.DangerWarn: "warning", lib.DangerMessage: "info"}
+
+func main() {
+	// Flags
+	mrFlag := lib.RegisterMRFlag("Merge request IID, web URL or source branch (required; in a merge request pipeline, that MR)")
+	rulesFile := flag.String("rules", "", "Rules file (default: "+lib.DangerFileName+" at the repository root)")
+	comment := flag.Bool("comment", false, "Post the results as one comment when a rule fires, and keep an existing one up to date")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Validate MR reference
+	if err := mrFlag.Parse(); err != nil {
+		lib.Exit("Error", err)
+	}
+
+	rules, err := lib.LoadDangerRules(*rulesFile)
+	if err != nil {
+		lib.Exit("Error loading rules", err)
+	}
+	if rules == nil || len(rules.Rules) == 0 {
+		lib.Usagef("no rules: add %s at the repository root or pass --rules", lib.DangerFileName)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	mrIID, err := mrFlag.Resolve(client, projectPath)
+	if err != nil {
+		lib.Exit("Error finding MR", err)
+	}
+	mr, err := client.GetMR(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR", err)
+	}
+	diffs, err := client.GetMRDiffs(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR changes", err)
+	}
+
+	results := rules.Evaluate(mr, diffs)
+	failures := 0
+	for _, r := range results {
+		line := fmt.Sprintf("%s: %s", r.Rule, r.Message)
+		if r.Level == lib.DangerFail {
+			failures++
+		}
+		switch {
+		case ui.Quiet:
+			fmt.Printf("%s %s\n", r.Level, line)
+		case r.Level == lib.DangerFail:
+			fmt.Printf("  %s\n", ui.Failure(line))
+		case r.Level == lib.DangerWarn:
+			fmt.Printf("  %s\n", ui.Warning(line))
+		default:
+			fmt.Printf("  %s\n", line)
+		}
+	}
+	if len(results) == 0 {
+		ui.Printf("%s\n", ui.Success(fmt.Sprintf("MR !%d: %d rule(s), nothing to report", mr.IID, len(rules.Rules))))
+	} else {
+		ui.Printf("MR !%d: %d failure(s), %d of %d rule(s) fired\n", mr.IID, failures, len(results), len(rules.Rules))
+	}
+
+	if *comment {
+		report := &lib.StatusReport{ID: reportID, Title: "Review rules", UpdatedAt: time.Now()}
+		for _, r := range results {
+			report.Rows = append(report.Rows, lib.StatusRow{Name: r.Rule, Status: statuses[r.Level], Details: r.Message})
+		}
+		if len(results) == 0 {
+			report.Rows = []lib.StatusRow{{Name: "All rules", Status: "passed", Details: fmt.Sprintf("%d rule(s), nothing to report", len(rules.Rules))}}
+		}
+		note, _, err := client.UpsertStatusReport(projectPath, mr.IID, report, len(results) == 0)
+		if err != nil {
+			lib.Exit("Error writing rules comment", err)
+		}
+		if note != nil {
+			ui.Printf("  Comment: %s#note_%d\n", mr.WebURL, note.ID)
+		}
+	}
+
+	if failures > 0 {
+		lib.Exit("Error", fmt.Errorf("%w: %d rule(s) failed on MR !%d", lib.ErrBlocked, failures, mr.IID))
+	}
+}
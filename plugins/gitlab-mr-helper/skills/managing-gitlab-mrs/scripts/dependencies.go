@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	pkg := flag.String("package", "", "Check whether the project depends on this package")
+	version := flag.String("version", "", "With --package, a version or constraint such as '<2.17.0' or '>=1.2, <2'")
+	managers := flag.String("package-manager", "", "Comma-separated package managers to list (npm, go, maven, bundler, ...)")
+	vulnerable := flag.Bool("vulnerable", false, "List only dependencies with known vulnerabilities")
+	sbom := flag.String("sbom", "", "Export the dependency list and write it to this file ('-' for stdout)")
+	attachTo := flag.String("attach-to", "", "Export the dependency list and attach it to the release of this tag")
+	exportType := flag.String("export-type", lib.DefaultExportType, "Export format: sbom (CycloneDX) or dependency_list")
+	timeout := flag.Duration("timeout", 5*time.Minute, "How long to wait for the export")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *version != "" && *pkg == "" {
+		lib.Usagef("--version needs --package")
+	}
+	if _, err := lib.MatchVersion("0", *version); err != nil {
+		lib.Usagef("invalid --version: %v", err)
+	}
+	exporting := *sbom != "" || *attachTo != ""
+	if exporting && (*pkg != "" || *vulnerable) {
+		lib.Usagef("--sbom and --attach-to cannot be combined with --package or --vulnerable")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+
+	if exporting {
+		export(client, ui, projectPath, *exportType, *sbom, *attachTo, *timeout)
+		return
+	}
+
+	deps, err := client.ListDependencies(projectPath, lib.SplitList(*managers))
+	if err != nil {
+		lib.Exit("Error listing dependencies", err)
+	}
+
+	if *pkg != "" {
+		found, _ := lib.FindDependencies(deps, *pkg, *version)
+		wanted := strings.TrimSpace(*pkg + " " + *version)
+		if len(found) == 0 {
+			if !ui.Quiet {
+				fmt.Printf("No: %s does not depend on %s\n", projectPath, wanted)
+				if len(deps) == 0 {
+					fmt.Println("  The dependency list is empty; dependency scanning must run on the default branch")
+				}
+			}
+			lib.ExitWith(lib.ExitNotFound)
+		}
+		if ui.Quiet {
+			for _, d := range found {
+				fmt.Println(d.Version)
+			}
+			return
+		}
+		fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Yes: %s depends on %s", projectPath, wanted)))
+		for _, d := range found {
+			printDependency(d)
+		}
+		return
+	}
+
+	if *vulnerable {
+		var out []lib.Dependency
+		for _, d := range deps {
+			if len(d.Vulnerabilities) > 0 {
+				out = append(out, d)
+			}
+		}
+		deps = out
+	}
+
+	if ui.Quiet {
+		for _, d := range deps {
+			fmt.Printf("%s@%s\n", d.Name, d.Version)
+		}
+		return
+	}
+	if len(deps) == 0 {
+		fmt.Println("No dependencies found (dependency scanning must run on the default branch)")
+		return
+	}
+	for _, d := range deps {
+		printDependency(d)
+	}
+	fmt.Printf("\nTotal: %d dependencies\n", len(deps))
+}
+
+// printDependency prints one dependency with its licenses and
+// vulnerabilities
+func printDependency(d lib.Dependency) {
+	fmt.Printf("  %s %s (%s, %s)\n", d.Name, d.Version, d.PackageManager, d.DependencyFilePath)
+	if len(d.Licenses) > 0 {
+		names := make([]string, len(d.Licenses))
+		for i, l := range d.Licenses {
+			names[i] = l.Name
+		}
+		fmt.Printf("     Licenses: %s\n", strings.Join(names, ", "))
+	}
+	for _, v := range d.Vulnerabilities {
+		fmt.Printf("     Vulnerability (%s): %s %s\n", v.Severity, v.Name, v.URL)
+	}
+}
+
+// export writes the project's dependency list export to a file and/or
+// attaches it to a release
+func export(client *lib.Client, ui *lib.UI, projectPath, exportType, path, tag string, timeout time.Duration) {
+	if path != "-" {
+		ui.Printf("Exporting dependency list (%s)...\n", exportType)
+	}
+	e, err := client.ExportDependencies(projectPath, exportType)
+	if err != nil {
+		lib.Exit("Error starting export", err)
+	}
+	if err := client.WaitDependencyListExport(e.ID, 2*time.Second, timeout); err != nil {
+		lib.Exit("Error waiting for export", err)
+	}
+	body, err := client.DownloadDependencyListExport(e.ID)
+	if err != nil {
+		lib.Exit("Error downloading export", err)
+	}
+	data, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		lib.Exit("Error downloading export", err)
+	}
+
+	switch path {
+	case "":
+	case "-":
+		os.Stdout.Write(data)
+	default:
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			lib.Exit("Error writing export", err)
+		}
+		if ui.Quiet {
+			fmt.Println(path)
+		} else {
+			fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Wrote %s (%d bytes)", path, len(data))))
+		}
+	}
+
+	if tag == "" {
+		return
+	}
+	name := fmt.Sprintf("dependencies-%s.json", tag)
+	if exportType == lib.DefaultExportType {
+		name = fmt.Sprintf("sbom-%s.cdx.json", tag)
+	}
+	link, err := client.AttachReleaseFile(projectPath, tag, name, bytes.NewReader(data))
+	if err != nil {
+		lib.Exit("Error attaching to release "+tag, err)
+	}
+	if ui.Quiet {
+		fmt.Println(link.URL)
+		return
+	}
+	fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Attached %s to release %s", name, tag)))
+	fmt.Printf("  URL: %s\n", link.URL)
+}
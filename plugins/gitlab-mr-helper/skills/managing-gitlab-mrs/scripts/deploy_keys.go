@@ -0,0 +1,164 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	create := flag.String("create", "", "Add a deploy key with this title")
+	key := flag.String("key", "", "Public key for --create (e.g. \"ssh-ed25519 AAAA...\")")
+	keyFile := flag.String("key-file", "", "Read the public key for --create from a file ('-' for stdin)")
+	canPush := flag.Bool("can-push", false, "Give the key with --create write access")
+	expires := flag.String("expires", "", "Expiry for --create: YYYY-MM-DD or <days>d (default: none)")
+	revoke := flag.Int("revoke", 0, "Remove the deploy key with this ID")
+	warnDays := flag.Int("warn-days", 30, "Flag keys expiring within N days")
+	output := flag.String("output", "text", "Output format for the listing: text, tsv, csv")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if err := lib.ValidateOutputFormat(*output); err != nil {
+		lib.Exit("Error", err)
+	}
+	if *create != "" && *revoke != 0 {
+		lib.Usagef("--create and --revoke are mutually exclusive")
+	}
+	var req *lib.CreateDeployKeyRequest
+	if *create != "" {
+		if (*key == "") == (*keyFile == "") {
+			lib.Usagef("--create needs exactly one of --key and --key-file")
+		}
+		req = &lib.CreateDeployKeyRequest{Title: *create, Key: *key, CanPush: *canPush}
+		if *keyFile != "" {
+			text, err := lib.ReadTextFile(*keyFile)
+			if err != nil {
+				lib.Exit("Error", err)
+			}
+			req.Key = strings.TrimSpace(text)
+		}
+		if *expires != "" {
+			expiresAt, err := lib.ParseExpiry(*expires, time.Now())
+			if err != nil {
+				lib.Usagef("%v", err)
+			}
+			req.ExpiresAt = &expiresAt
+		}
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected && *output == lib.OutputText {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+
+	switch {
+	case req != nil:
+		dk, err := client.CreateDeployKey(projectPath, req)
+		if err != nil {
+			lib.Exit("Error adding deploy key", err)
+		}
+		if ui.Quiet {
+			fmt.Println(dk.ID)
+			return
+		}
+		access := "read-only"
+		if dk.CanPush {
+			access = "read-write"
+		}
+		fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Deploy key #%d %s added (%s)", dk.ID, dk.Title, access)))
+		fmt.Printf("  Expires: %s\n", dk.ExpiresAt)
+
+	case *revoke != 0:
+		if err := client.DeleteDeployKey(projectPath, *revoke); err != nil {
+			lib.Exit("Error removing deploy key", err)
+		}
+		if ui.Quiet {
+			fmt.Println(*revoke)
+			return
+		}
+		fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Deploy key #%d removed", *revoke)))
+
+	default:
+		keys, err := client.ListDeployKeys(projectPath)
+		if err != nil {
+			lib.Exit("Error listing deploy keys", err)
+		}
+		listKeys(ui, keys, *output, *warnDays)
+	}
+}
+
+// listKeys prints the keys with their expiry, flagging those that expire
+// within warnDays
+func listKeys(ui *lib.UI, keys []lib.DeployKey, output string, warnDays int) {
+	now := time.Now()
+	if output != lib.OutputText {
+		header := []string{"id", "title", "fingerprint", "can_push", "expires_at", "days_left", "created_at"}
+		var rows [][]string
+		for _, k := range keys {
+			days := ""
+			if !k.ExpiresAt.IsZero() {
+				days = strconv.Itoa(k.ExpiresAt.DaysLeft(now))
+			}
+			rows = append(rows, []string{strconv.Itoa(k.ID), k.Title, k.Fingerprint, strconv.FormatBool(k.CanPush),
+				k.ExpiresAt.String(), days, k.CreatedAt.Format(time.RFC3339)})
+		}
+		if err := lib.WriteTable(os.Stdout, output, header, rows); err != nil {
+			lib.Exit("Error", err)
+		}
+		return
+	}
+
+	if ui.Quiet {
+		for _, k := range keys {
+			fmt.Println(k.ID)
+		}
+		return
+	}
+	if len(keys) == 0 {
+		fmt.Println("No deploy keys")
+		return
+	}
+
+	soonCount := 0
+	for _, k := range keys {
+		status, soon := lib.ExpiryStatus(k.ExpiresAt, now, warnDays)
+		access := "read-only"
+		if k.CanPush {
+			access = "read-write"
+		}
+		line := fmt.Sprintf("#%d  %s  (%s; %s)  %s", k.ID, k.Title, access, k.Fingerprint, status)
+		if soon {
+			soonCount++
+			fmt.Println(ui.Warning(line))
+		} else {
+			fmt.Println("  " + line)
+		}
+	}
+	fmt.Printf("\nTotal: %d key(s)", len(keys))
+	if soonCount > 0 {
+		fmt.Printf(", %d expired or expiring within %d days", soonCount, warnDays)
+	}
+	fmt.Println()
+}
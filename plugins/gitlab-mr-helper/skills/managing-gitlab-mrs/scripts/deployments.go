@@ -0,0 +1,176 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	environment := flag.String("environment", "", "Only list deployments to this environment")
+	status := flag.String("status", "blocked", "Deployment status to list: blocked, running, success, failed, ... (\"\" for all)")
+	limit := flag.Int("limit", 20, "Maximum number of deployments to list")
+	approve := flag.Int("approve", 0, "Approve the blocked deployment with this ID")
+	reject := flag.Int("reject", 0, "Reject the blocked deployment with this ID")
+	comment := flag.String("comment", "", "Comment for --approve or --reject")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *approve != 0 && *reject != 0 {
+		lib.Usagef("--approve and --reject are mutually exclusive")
+	}
+	if *comment != "" && *approve == 0 && *reject == 0 {
+		lib.Usagef("--comment needs --approve or --reject")
+	}
+	if *limit < 1 {
+		lib.Usagef("--limit must be at least 1")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+
+	if id := *approve + *reject; id != 0 {
+		decide(client, ui, projectPath, id, *approve != 0, *comment)
+		return
+	}
+
+	envs, err := client.ListProtectedEnvironments(projectPath)
+	if err != nil {
+		lib.Exit("Error listing protected environments", err)
+	}
+	deployments, err := client.ListDeployments(projectPath, &lib.DeploymentListOptions{Environment: *environment, Status: *status, Limit: *limit})
+	if err != nil {
+		lib.Exit("Error listing deployments", err)
+	}
+
+	// Approvals are only returned for single deployments
+	errs := client.ForEach(len(deployments), func(i int) error {
+		if deployments[i].Status != "blocked" {
+			return nil
+		}
+		d, err := client.GetDeployment(projectPath, deployments[i].ID)
+		if err == nil {
+			deployments[i] = *d
+		}
+		return err
+	})
+	for i, err := range errs {
+		if err != nil {
+			lib.Exit(fmt.Sprintf("Error getting deployment #%d", deployments[i].ID), err)
+		}
+	}
+
+	if ui.Quiet {
+		for _, d := range deployments {
+			fmt.Println(d.ID)
+		}
+		return
+	}
+
+	if len(envs) > 0 {
+		fmt.Println("Protected environments:")
+		for _, e := range envs {
+			if *environment != "" && e.Name != *environment {
+				continue
+			}
+			var rules []string
+			for _, r := range e.ApprovalRules {
+				rules = append(rules, fmt.Sprintf("%s: %d", approverName(r), r.RequiredApprovals))
+			}
+			line := fmt.Sprintf("  %s: %d approval(s) required", e.Name, e.RequiredApprovalCount)
+			if len(rules) > 0 {
+				line += " (" + strings.Join(rules, ", ") + ")"
+			}
+			fmt.Println(line)
+		}
+		fmt.Println()
+	}
+
+	what := "Deployments"
+	if *status != "" {
+		what = fmt.Sprintf("Deployments (%s)", *status)
+	}
+	if len(deployments) == 0 {
+		fmt.Printf("%s: none\n", what)
+		return
+	}
+	fmt.Printf("%s:\n", what)
+	for _, d := range deployments {
+		fmt.Printf("  #%d  %-12s %s (%s)  %s  by @%s  %s\n", d.ID, d.Environment.Name, d.Ref, lib.ShortSHA(d.SHA), d.Status,
+			d.User.Username, d.CreatedAt.Local().Format("2006-01-02 15:04"))
+		if d.Status != "blocked" {
+			continue
+		}
+		line := fmt.Sprintf("%d approval(s) pending", d.PendingApprovalCount)
+		for _, a := range d.Approvals {
+			line += fmt.Sprintf("; %s by @%s", a.Status, a.User.Username)
+			if a.Comment != "" {
+				line += fmt.Sprintf(" (%q)", a.Comment)
+			}
+		}
+		fmt.Printf("       %s\n", line)
+	}
+}
+
+// decide approves or rejects a blocked deployment and reports what it is
+// still waiting for
+func decide(client *lib.Client, ui *lib.UI, projectPath string, id int, approve bool, comment string) {
+	verb, action := "approved", "approving"
+	if !approve {
+		verb, action = "rejected", "rejecting"
+	}
+	if _, err := client.ApproveDeployment(projectPath, id, approve, comment); err != nil {
+		lib.Exit(fmt.Sprintf("Error %s deployment #%d", action, id), err)
+	}
+	if ui.Quiet {
+		fmt.Println(id)
+		return
+	}
+	d, err := client.GetDeployment(projectPath, id)
+	if err != nil {
+		lib.Exit("Error getting deployment", err)
+	}
+	fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Deployment #%d to %s %s", id, d.Environment.Name, verb)))
+	fmt.Printf("  Ref: %s (%s)\n", d.Ref, lib.ShortSHA(d.SHA))
+	switch {
+	case d.Status == "blocked" && approve:
+		fmt.Printf("  Pending approvals: %d\n", d.PendingApprovalCount)
+	default:
+		fmt.Printf("  Status: %s\n", d.Status)
+	}
+	if d.Deployable != nil && d.Deployable.WebURL != "" {
+		fmt.Printf("  Job: %s\n", d.Deployable.WebURL)
+	}
+}
+
+// approverName describes who an approval rule lets approve
+func approverName(r lib.EnvironmentApprovalRule) string {
+	switch {
+	case r.UserID != 0:
+		return fmt.Sprintf("user #%d", r.UserID)
+	case r.GroupID != 0:
+		return fmt.Sprintf("group #%d", r.GroupID)
+	}
+	return r.AccessLevelDescription
+}
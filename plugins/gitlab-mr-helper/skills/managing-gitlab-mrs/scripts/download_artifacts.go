@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+
+	"gitlab-mr-helper/lib"
+)
+
+var sha256Pattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+func main() {
+	// Flags
+	job := flag.String("job", "", "Job ID, or name of the job in the latest successful pipeline of --ref (required)")
+	ref := flag.String("ref", "", "Branch or tag whose latest successful pipeline has the job named by --job (default: the default branch)")
+	file := flag.String("path", "", "Download this file from the artifacts instead of the whole archive, e.g. dist/app.tar.gz")
+	output := flag.String("output", "", "Write to this file (default: artifacts_JOBID.zip, or the base name of --path)")
+	sum := flag.String("sha256", "", "Expected SHA256 of the download; a mismatch fails with exit code 1")
+	retries := flag.Int("retries", lib.DefaultDownloadRetries, "Retries in a row for a download that fails without receiving anything")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *job == "" {
+		lib.Usagef("--job is required")
+	}
+	jobID, err := strconv.Atoi(*job)
+	if err == nil && *ref != "" {
+		lib.Usagef("--ref goes with a job name, not a job ID")
+	}
+	if *sum != "" && !sha256Pattern.MatchString(*sum) {
+		lib.Usagef("invalid --sha256 %q (expected 64 hex digits)", *sum)
+	}
+	if *retries < 0 {
+		lib.Usagef("--retries must not be negative")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+
+	// Find the job by name in the ref's latest successful pipeline
+	if jobID == 0 {
+		if *ref == "" {
+			if *ref, err = client.DefaultBranch(projectPath); err != nil {
+				lib.Exit("Error getting default branch", err)
+			}
+		}
+		pipelines, err := client.ListPipelines(projectPath, &lib.PipelineListOptions{Ref: *ref, Status: "success", Limit: 1})
+		if err != nil {
+			lib.Exit("Error listing pipelines", err)
+		}
+		if len(pipelines) == 0 {
+			lib.Exit("Error", fmt.Errorf("%w: no successful pipeline on %s", lib.ErrNotFound, *ref))
+		}
+		j, err := client.FindPipelineJob(projectPath, pipelines[0].ID, *job)
+		if err != nil {
+			lib.Exit("Error finding job", err)
+		}
+		jobID = j.ID
+		ui.Printf("Job %s #%d of pipeline #%d on %s\n", j.Name, j.ID, pipelines[0].ID, *ref)
+	}
+
+	if *output == "" {
+		*output = fmt.Sprintf("artifacts_%d.zip", jobID)
+		if *file != "" {
+			*output = path.Base(*file)
+		}
+	}
+
+	var bar *lib.ProgressLine
+	if !ui.Quiet {
+		bar = lib.NewProgressLine(os.Stderr, "Downloading")
+	}
+	opts := &lib.DownloadOptions{SHA256: *sum, Retries: *retries, Progress: bar.Update}
+	if *retries == 0 {
+		opts.Retries = -1
+	}
+	d, err := client.SaveJobArtifacts(projectPath, jobID, *file, *output, opts)
+	bar.Done()
+	if err != nil {
+		lib.Exit("Error downloading artifacts", err)
+	}
+
+	if ui.Quiet {
+		fmt.Println(*output)
+		return
+	}
+	fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Wrote %s (%s)", *output, lib.FormatSize(d.Size))))
+	verified := ""
+	if d.Checked {
+		verified = " (verified)"
+	}
+	fmt.Printf("  SHA256: %s%s\n", d.SHA256, verified)
+	if d.Resumed > 0 {
+		fmt.Printf("  Resumed %d time(s) after interruptions\n", d.Resumed)
+	}
+}
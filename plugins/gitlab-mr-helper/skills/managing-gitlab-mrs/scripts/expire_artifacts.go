@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	olderThan := flag.Int("older-than", 30, "Delete artifacts of jobs older than N days (0 for any age)")
+	minSize := flag.String("min-size", "", "Only delete artifacts of at least this size, e.g. 10MB")
+	keepLatest := flag.Bool("keep-latest", true, "Keep the artifacts of the newest pipeline of each ref (--keep-latest=false to delete them too)")
+	keep := flag.String("keep", "", "Regex of refs whose artifacts are never deleted, e.g. '^(main|release/.*)$'")
+	dryRun := flag.Bool("dry-run", false, "Report what would be deleted without changing anything")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	opts := &lib.ArtifactExpiryOptions{KeepLatest: *keepLatest}
+	if *olderThan < 0 {
+		lib.Usagef("--older-than must not be negative")
+	}
+	if *olderThan > 0 {
+		opts.CreatedBefore = time.Now().AddDate(0, 0, -*olderThan)
+	}
+	if *minSize != "" {
+		var err error
+		if opts.MinSize, err = lib.ParseSize(*minSize); err != nil {
+			lib.Exit("Error", err)
+		}
+	}
+	if *keep != "" {
+		var err error
+		if opts.KeepRefs, err = regexp.Compile(*keep); err != nil {
+			lib.Usagef("invalid --keep pattern: %v", err)
+		}
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	jobs, err := client.ListProjectJobs(projectPath, &lib.JobListOptions{})
+	if err != nil {
+		lib.Exit("Error listing jobs", err)
+	}
+	expired := lib.SelectExpiredArtifacts(jobs, opts, time.Now())
+
+	prefix := ""
+	if *dryRun {
+		prefix = "[dry-run] "
+	}
+
+	errs := make([]error, len(expired))
+	if !*dryRun {
+		errs = client.ForEach(len(expired), func(i int) error {
+			return client.DeleteJobArtifacts(projectPath, expired[i].ID)
+		})
+	}
+
+	// Jobs whose artifacts could not be deleted are listed on stderr and
+	// left out of the space freed
+	var firstErr error
+	var freed int64
+	deleted := 0
+	for i, j := range expired {
+		if errs[i] != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.Failure(fmt.Sprintf("deleting artifacts of job #%d: %v", j.ID, errs[i])))
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+		deleted++
+		freed += j.ArtifactsSize()
+		if ui.Quiet {
+			fmt.Println(j.ID)
+			continue
+		}
+		line := fmt.Sprintf("deleted artifacts of job #%d %s (%s, %s): %s", j.ID, j.Name, j.Ref, lib.FormatAge(j.CreatedAt), lib.FormatSize(j.ArtifactsSize()))
+		fmt.Printf("%s%s\n", prefix, ui.Success(line))
+	}
+
+	ui.Printf("\n%s%s: deleted artifacts of %d job(s), freed %s\n", prefix, projectPath, deleted, lib.FormatSize(freed))
+
+	if firstErr != nil {
+		lib.ExitWith(lib.ExitCode(firstErr))
+	}
+}
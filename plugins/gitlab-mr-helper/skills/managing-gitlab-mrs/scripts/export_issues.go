@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	output := flag.String("output", "-", "Write the issues to this file ('-' for stdout); a .json name writes JSON")
+	asJSON := flag.Bool("json", false, "Write JSON instead of CSV")
+	state := flag.String("state", "all", "Issue state: opened, closed, all")
+	labels := flag.String("labels", "", "Only issues with all of these comma-separated labels")
+	limit := flag.Int("limit", 0, "Maximum number of issues, newest first (0 for all)")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	switch *state {
+	case "opened", "closed", "all":
+	default:
+		lib.Usagef("invalid --state %q (expected opened, closed or all)", *state)
+	}
+	format := lib.IssueFormatFromPath(*output)
+	if *asJSON {
+		format = lib.IssueFormatJSON
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+
+	// Progress goes to stderr when the issues go to stdout
+	toStdout := *output == "-"
+	progress := os.Stdout
+	if toStdout {
+		progress = os.Stderr
+	}
+	if detected && !ui.Quiet {
+		fmt.Fprintf(progress, "%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	opts := &lib.IssueListOptions{State: *state, Limit: *limit}
+	if *labels != "" {
+		opts.Labels = lib.SplitList(*labels)
+	}
+	issues, err := client.ListProjectIssues(projectPath, opts)
+	if err != nil {
+		lib.Exit("Error listing issues", err)
+	}
+
+	// Oldest first, so an import recreates them in their original order
+	records := make([]lib.IssueRecord, 0, len(issues))
+	for i := len(issues) - 1; i >= 0; i-- {
+		records = append(records, lib.NewIssueRecord(&issues[i]))
+	}
+	var buf bytes.Buffer
+	if err := lib.WriteIssueRecords(&buf, format, records); err != nil {
+		lib.Exit("Error", err)
+	}
+
+	if toStdout {
+		os.Stdout.Write(buf.Bytes())
+		if !ui.Quiet {
+			fmt.Fprintf(os.Stderr, "Exported %d issue(s)\n", len(records))
+		}
+		return
+	}
+	if err := os.WriteFile(*output, buf.Bytes(), 0o644); err != nil {
+		lib.Exit("Error writing issues", err)
+	}
+	if ui.Quiet {
+		fmt.Println(*output)
+		return
+	}
+	fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Exported %d issue(s) to %s", len(records), *output)))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	output := flag.String("output", "", "Write the archive to this file ('-' for stdout; default: GROUP_PROJECT_export.tar.gz)")
+	timeout := flag.Duration("timeout", 30*time.Minute, "How long to wait for the export")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+
+	// Progress goes to stderr when the archive goes to stdout
+	toStdout := *output == "-"
+	progress := os.Stdout
+	if toStdout {
+		progress = os.Stderr
+	}
+	if detected && !ui.Quiet {
+		fmt.Fprintf(progress, "%s\n", ui.Success("Project: "+projectPath))
+	}
+	if *output == "" {
+		*output = strings.ReplaceAll(projectPath, "/", "_") + "_export.tar.gz"
+	}
+
+	client := lib.NewClient(config)
+
+	if !ui.Quiet {
+		fmt.Fprintf(progress, "Exporting %s...\n", projectPath)
+	}
+	if err := client.ExportProject(projectPath); err != nil {
+		lib.Exit("Error starting export", err)
+	}
+	if err := client.WaitProjectExport(projectPath, 5*time.Second, *timeout); err != nil {
+		lib.Exit("Error waiting for export", err)
+	}
+
+	if toStdout {
+		body, err := client.DownloadProjectExport(projectPath)
+		if err != nil {
+			lib.Exit("Error downloading export", err)
+		}
+		defer body.Close()
+		if _, err := io.Copy(os.Stdout, body); err != nil {
+			lib.Exit("Error downloading export", err)
+		}
+		return
+	}
+
+	// A file download survives dropped connections; see download_artifacts.go
+	var bar *lib.ProgressLine
+	if !ui.Quiet {
+		bar = lib.NewProgressLine(os.Stderr, "Downloading")
+	}
+	d, err := client.SaveProjectExport(projectPath, *output, &lib.DownloadOptions{Progress: bar.Update})
+	bar.Done()
+	if err != nil {
+		lib.Exit("Error downloading export", err)
+	}
+	if ui.Quiet {
+		fmt.Println(*output)
+		return
+	}
+	fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Wrote %s (%s)", *output, lib.FormatSize(d.Size))))
+	fmt.Printf("  SHA256: %s\n", d.SHA256)
+}
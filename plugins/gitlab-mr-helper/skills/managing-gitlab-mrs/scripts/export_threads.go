@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	mrFlag := lib.RegisterMRFlag("Merge request IID, web URL or source branch (required)")
+	context := flag.Int("context", 3, "Diff lines shown on each side of a commented line")
+	all := flag.Bool("all", false, "Include resolved threads")
+	projectFlags := lib.RegisterProjectFlags()
+	lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Validate MR reference
+	if err := mrFlag.Parse(); err != nil {
+		lib.Exit("Error", err)
+	}
+	if *context < 0 {
+		lib.Usagef("--context must not be negative")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path. The digest goes to stdout, so the project is not
+	// echoed there.
+	projectPath, _, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+
+	client := lib.NewClient(config)
+	mrIID, err := mrFlag.Resolve(client, projectPath)
+	if err != nil {
+		lib.Exit("Error finding MR", err)
+	}
+	mr, err := client.GetMR(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR", err)
+	}
+	discussions, err := client.ListMRDiscussions(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error listing discussions", err)
+	}
+	diffs, err := client.GetMRDiffs(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting diffs", err)
+	}
+
+	opts := lib.ThreadDigestOptions{Context: *context, IncludeResolved: *all}
+	if err := lib.WriteThreadDigest(os.Stdout, mr, discussions, diffs, opts); err != nil {
+		lib.Exit("Error", err)
+	}
+}
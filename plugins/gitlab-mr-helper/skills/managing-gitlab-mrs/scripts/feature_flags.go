@@ -0,0 +1,197 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	scope := flag.String("scope", "", "List only enabled or disabled flags")
+	show := flag.String("show", "", "Show this feature flag only")
+	create := flag.String("create", "", "Create a feature flag with this name")
+	description := flag.String("description", "", "Description for --create")
+	environments := flag.String("environments", "*", "Comma-separated environment scopes of the --create strategies")
+	rollout := flag.Int("rollout", 0, "For --create: enable for this percentage of users (1-100) instead of everyone")
+	userIDs := flag.String("user-ids", "", "For --create: enable for these comma-separated user IDs instead of everyone")
+	inactive := flag.Bool("inactive", false, "Create the flag turned off")
+	enable := flag.String("enable", "", "Turn this feature flag on")
+	disable := flag.String("disable", "", "Turn this feature flag off (e.g. to mitigate an incident)")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	actions := 0
+	for _, name := range []string{*show, *create, *enable, *disable} {
+		if name != "" {
+			actions++
+		}
+	}
+	if actions > 1 {
+		lib.Usagef("--show, --create, --enable and --disable are mutually exclusive")
+	}
+	if *scope != "" && *scope != "enabled" && *scope != "disabled" {
+		lib.Usagef("--scope must be enabled or disabled")
+	}
+	if *create == "" && (*description != "" || *rollout != 0 || *userIDs != "" || *inactive) {
+		lib.Usagef("--description, --rollout, --user-ids and --inactive need --create")
+	}
+	if *rollout < 0 || *rollout > 100 {
+		lib.Usagef("--rollout must be between 1 and 100")
+	}
+	var req *lib.CreateFeatureFlagRequest
+	if *create != "" {
+		strategies, err := buildStrategies(*environments, *rollout, *userIDs)
+		if err != nil {
+			lib.Usagef("%v", err)
+		}
+		req = &lib.CreateFeatureFlagRequest{Name: *create, Description: *description, Active: !*inactive, Strategies: strategies}
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+
+	switch {
+	case *show != "":
+		f, err := client.GetFeatureFlag(projectPath, *show)
+		if err != nil {
+			lib.Exit("Error getting feature flag", err)
+		}
+		if ui.Quiet {
+			fmt.Println(onOff(f.Active))
+			return
+		}
+		printFlag(f)
+
+	case req != nil:
+		f, err := client.CreateFeatureFlag(projectPath, req)
+		if err != nil {
+			lib.Exit("Error creating feature flag", err)
+		}
+		if ui.Quiet {
+			fmt.Println(f.Name)
+			return
+		}
+		fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Feature flag %s created (%s)", f.Name, onOff(f.Active))))
+		printStrategies(f)
+
+	case *enable != "" || *disable != "":
+		name := *enable + *disable
+		f, err := client.SetFeatureFlagActive(projectPath, name, *enable != "")
+		if err != nil {
+			lib.Exit("Error updating feature flag", err)
+		}
+		if ui.Quiet {
+			fmt.Println(f.Name)
+			return
+		}
+		fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Feature flag %s turned %s", f.Name, onOff(f.Active))))
+		printStrategies(f)
+
+	default:
+		flags, err := client.ListFeatureFlags(projectPath, *scope)
+		if err != nil {
+			lib.Exit("Error listing feature flags", err)
+		}
+		if ui.Quiet {
+			for _, f := range flags {
+				fmt.Println(f.Name)
+			}
+			return
+		}
+		if len(flags) == 0 {
+			fmt.Println("No feature flags")
+			return
+		}
+		for _, f := range flags {
+			printFlag(&f)
+		}
+		fmt.Printf("\nTotal: %d flag(s)\n", len(flags))
+	}
+}
+
+// buildStrategies returns the strategies of a new flag: a percentage
+// rollout and/or a user list, or everyone, in the given environments
+func buildStrategies(environments string, rollout int, userIDs string) ([]lib.FlagStrategy, error) {
+	var scopes []lib.FlagScope
+	for _, env := range strings.Split(environments, ",") {
+		if env = strings.TrimSpace(env); env != "" {
+			scopes = append(scopes, lib.FlagScope{EnvironmentScope: env})
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("--environments must name at least one environment")
+	}
+
+	var strategies []lib.FlagStrategy
+	if rollout > 0 {
+		strategies = append(strategies, lib.FlagStrategy{
+			Name:       lib.StrategyFlexibleRollout,
+			Parameters: map[string]string{"groupId": "default", "rollout": strconv.Itoa(rollout), "stickiness": "default"},
+			Scopes:     scopes,
+		})
+	}
+	if userIDs != "" {
+		var ids []string
+		for _, id := range strings.Split(userIDs, ",") {
+			id = strings.TrimSpace(id)
+			if _, err := strconv.Atoi(id); err != nil {
+				return nil, fmt.Errorf("invalid user ID %q in --user-ids", id)
+			}
+			ids = append(ids, id)
+		}
+		strategies = append(strategies, lib.FlagStrategy{Name: lib.StrategyUserWithID, Parameters: map[string]string{"userIds": strings.Join(ids, ",")}, Scopes: scopes})
+	}
+	if len(strategies) == 0 {
+		strategies = append(strategies, lib.FlagStrategy{Name: lib.StrategyDefault, Parameters: map[string]string{}, Scopes: scopes})
+	}
+	return strategies, nil
+}
+
+// printFlag prints a flag's state, name and description, then its strategies
+func printFlag(f *lib.FeatureFlag) {
+	line := fmt.Sprintf("%-4s %s", onOff(f.Active), f.Name)
+	if f.Description != "" {
+		line += "  " + f.Description
+	}
+	fmt.Println(line)
+	printStrategies(f)
+}
+
+func printStrategies(f *lib.FeatureFlag) {
+	if len(f.Strategies) == 0 {
+		fmt.Println("     (no strategies: off everywhere)")
+		return
+	}
+	for _, s := range f.Strategies {
+		fmt.Printf("     %s\n", s.Summary())
+	}
+}
+
+func onOff(active bool) string {
+	if active {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	file := flag.String("file", "", "Repository path of the file whose history to list (required)")
+	ref := flag.String("ref", "", "Branch, tag or SHA whose history to walk (default: the default branch)")
+	since := flag.String("since", "", "Only commits since: YYYY-MM-DD, an RFC 3339 time, or e.g. 30d, 12w")
+	limit := flag.Int("limit", 20, "Show at most N commits (0 for all)")
+	mrs := flag.Bool("mrs", false, "Look up the MR that brought in each commit")
+	patch := flag.Bool("patch", false, "Show what each commit changed in the file")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *file == "" {
+		lib.Usagef("--file is required")
+	}
+	if *limit < 0 {
+		lib.Usagef("--limit must not be negative")
+	}
+	var start time.Time
+	if *since != "" {
+		var err error
+		if start, err = lib.ParseTimeBound(*since, time.Now()); err != nil {
+			lib.Exit("Error", err)
+		}
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	if *ref == "" {
+		if *ref, err = client.DefaultBranch(projectPath); err != nil {
+			lib.Exit("Error getting default branch", err)
+		}
+	}
+	commits, err := client.ListCommits(projectPath, &lib.CommitListOptions{RefName: *ref, Path: *file, Since: start, Limit: *limit})
+	if err != nil {
+		lib.Exit("Error listing commits", err)
+	}
+	if ui.Quiet {
+		for _, c := range commits {
+			fmt.Println(c.ID)
+		}
+		return
+	}
+	if len(commits) == 0 {
+		ui.Printf("No commits touch %s on %s%s\n", *file, *ref, sinceText(start))
+		return
+	}
+
+	shas := make([]string, len(commits))
+	for i, c := range commits {
+		shas[i] = c.ID
+	}
+	var byCommit map[string]*lib.MergeRequest
+	if *mrs {
+		if byCommit, err = client.FindCommitMRs(projectPath, shas); err != nil {
+			lib.Exit("Error finding MRs", err)
+		}
+	}
+	diffs := make([][]lib.Diff, len(commits))
+	if *patch {
+		errs := client.ForEach(len(commits), func(i int) error {
+			var err error
+			if diffs[i], err = client.GetCommitDiff(projectPath, shas[i]); err != nil {
+				return fmt.Errorf("commit %s: %w", lib.ShortSHA(shas[i]), err)
+			}
+			return nil
+		})
+		if err := errors.Join(errs...); err != nil {
+			lib.Exit("Error getting commit diffs", err)
+		}
+	}
+
+	fmt.Printf("%d commit(s) touching %s on %s%s, newest first:\n", len(commits), *file, *ref, sinceText(start))
+	for i, c := range commits {
+		fmt.Printf("\n%s %s\n", lib.ShortSHA(c.ID), c.Title)
+		fmt.Printf("  %s, %s\n", lib.FormatAuthor(c.AuthorName, c.AuthorEmail), lib.FormatAge(c.CreatedAt))
+		if mr := byCommit[c.ID]; mr != nil {
+			fmt.Printf("  !%d %s (%s) %s\n", mr.IID, mr.Title, mr.State, mr.WebURL)
+		} else if *mrs {
+			fmt.Println("  no MR: pushed directly")
+		}
+		if body := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(c.Message), c.Title)); body != "" {
+			for _, line := range strings.Split(body, "\n") {
+				fmt.Printf("  | %s\n", line)
+			}
+		}
+		for _, d := range diffs[i] {
+			if d.NewPath != *file && d.OldPath != *file {
+				continue
+			}
+			if d.RenamedFile {
+				fmt.Printf("  renamed %s "+lib.Arrow+" %s\n", d.OldPath, d.NewPath)
+			}
+			for _, line := range strings.Split(strings.TrimRight(d.Diff, "\n"), "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+			if d.Truncated {
+				fmt.Println("    ... (diff truncated)")
+			}
+		}
+	}
+	if *limit > 0 && len(commits) == *limit {
+		fmt.Printf("\nShowing the newest %d; raise --limit for more\n", *limit)
+	}
+}
+
+// sinceText renders the start of the history period, if any
+func sinceText(start time.Time) string {
+	if start.IsZero() {
+		return ""
+	}
+	return " since " + start.Format("2006-01-02")
+}
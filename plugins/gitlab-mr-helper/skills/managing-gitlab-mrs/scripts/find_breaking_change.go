@@ -0,0 +1,153 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	ref := flag.String("ref", "", "Branch to investigate (default: the default branch)")
+	limit := flag.Int("pipelines", 50, "Number of recent pipelines to walk back through")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *limit < 2 {
+		lib.Usagef("--pipelines must be at least 2")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	branch := *ref
+	if branch == "" {
+		if branch, err = client.DefaultBranch(projectPath); err != nil {
+			lib.Exit("Error finding default branch (use --ref)", err)
+		}
+	}
+
+	pipelines, err := client.ListPipelines(projectPath, &lib.PipelineListOptions{Ref: branch, Limit: *limit})
+	if err != nil {
+		lib.Exit("Error listing pipelines", err)
+	}
+	green, red := lib.FindFirstRed(pipelines)
+	switch {
+	case red == nil && green == nil:
+		ui.Printf("No finished pipeline among the last %d on %s\n", len(pipelines), branch)
+		return
+	case red == nil:
+		ui.Printf("%s\n", ui.Success(fmt.Sprintf("%s is green: pipeline #%d passed on %s", branch, green.ID, lib.ShortSHA(green.SHA))))
+		return
+	case green == nil:
+		lib.Exit("Error", fmt.Errorf("%w: no successful pipeline among the last %d on %s to compare with; raise --pipelines", lib.ErrNotFound, len(pipelines), branch))
+	}
+
+	jobs, err := client.ListPipelineJobs(projectPath, red.ID, "failed")
+	if err != nil {
+		lib.Exit("Error listing failed jobs", err)
+	}
+	cmp, err := client.CompareRefs(projectPath, green.SHA, red.SHA)
+	if err != nil {
+		lib.Exit("Error comparing pipelines", err)
+	}
+
+	// Group the commits by the MR that brought them in; the rest were
+	// pushed directly
+	mrsOf := make([][]lib.MergeRequest, len(cmp.Commits))
+	errs := client.ForEach(len(cmp.Commits), func(i int) error {
+		var err error
+		mrsOf[i], err = client.ListCommitMRs(projectPath, cmp.Commits[i].ID)
+		return err
+	})
+	type suspect struct {
+		mr      *lib.MergeRequest // nil for a direct push
+		commits []lib.Commit
+	}
+	var suspects []*suspect
+	byIID := make(map[int]*suspect)
+	for i, c := range cmp.Commits {
+		if errs[i] != nil {
+			lib.Exit("Error finding the MR of "+lib.ShortSHA(c.ID), errs[i])
+		}
+		if len(mrsOf[i]) == 0 {
+			suspects = append(suspects, &suspect{commits: []lib.Commit{c}})
+			continue
+		}
+		mr := mrsOf[i][0]
+		if s := byIID[mr.IID]; s != nil {
+			s.commits = append(s.commits, c)
+			continue
+		}
+		byIID[mr.IID] = &suspect{mr: &mr, commits: []lib.Commit{c}}
+		suspects = append(suspects, byIID[mr.IID])
+	}
+
+	if ui.Quiet {
+		for _, s := range suspects {
+			if s.mr != nil {
+				fmt.Println(s.mr.WebURL)
+			} else {
+				fmt.Println(s.commits[0].ID)
+			}
+		}
+		return
+	}
+
+	fmt.Printf("%s broke between pipeline #%d (%s, passed) and #%d (%s, failed)\n", branch, green.ID, lib.ShortSHA(green.SHA), red.ID, lib.ShortSHA(red.SHA))
+	fmt.Printf("  First red: %s\n", red.WebURL)
+	for _, j := range jobs {
+		fmt.Printf("  Failed job: %s (%s) %s\n", j.Name, j.Stage, j.WebURL)
+	}
+
+	fmt.Printf("\nChanges in between (%d commit(s), %d file(s)):\n", len(cmp.Commits), len(cmp.Diffs))
+	if len(suspects) == 0 {
+		fmt.Println("  none: the failure may be flaky or caused outside the repository; retry the pipeline")
+		return
+	}
+	for _, s := range suspects {
+		if s.mr != nil {
+			fmt.Printf("  !%d %s by @%s\n", s.mr.IID, s.mr.Title, s.mr.Author.Username)
+			fmt.Printf("     %s\n", s.mr.WebURL)
+		} else {
+			fmt.Println("  Direct push")
+		}
+		for _, c := range s.commits {
+			fmt.Printf("     %s %s (%s)\n", lib.ShortSHA(c.ID), c.Title, c.AuthorName)
+		}
+	}
+
+	if len(suspects) == 1 {
+		fmt.Printf("\nLikely culprit: %s\n", describe(suspects[0].mr, suspects[0].commits[0]))
+	} else {
+		// The commit the red pipeline ran on is the last one in
+		last := suspects[len(suspects)-1]
+		fmt.Printf("\n%d suspects; the first red pipeline ran on %s\n", len(suspects), describe(last.mr, last.commits[len(last.commits)-1]))
+	}
+}
+
+// describe names an MR, or a directly pushed commit
+func describe(mr *lib.MergeRequest, c lib.Commit) string {
+	if mr != nil {
+		return fmt.Sprintf("!%d %s", mr.IID, mr.WebURL)
+	}
+	return fmt.Sprintf("%s %s (direct push)", lib.ShortSHA(c.ID), c.Title)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	mrFlag := lib.RegisterMRFlag("Merge request IID, web URL or source branch (required)")
+	projectFlags := lib.RegisterProjectFlags()
+	format := flag.String("format", "", "Go template applied to the MR (e.g. '{{.State}} {{.WebURL}}')")
+	open := flag.Bool("open", false, "Also open the MR in the browser")
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	var tmpl *lib.OutputTemplate
+	if *format != "" {
+		var err error
+		tmpl, err = lib.ParseOutputTemplate(*format)
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+	}
+
+	// Validate MR reference
+	if err := mrFlag.Parse(); err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected && tmpl == nil {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	mrIID, err := mrFlag.Resolve(client, projectPath)
+	if err != nil {
+		lib.Exit("Error finding MR", err)
+	}
+	mr, err := client.GetMR(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR", err)
+	}
+	if *open {
+		defer lib.OpenURLOrWarn(mr.WebURL)
+	}
+
+	if tmpl != nil {
+		if err := tmpl.Execute(os.Stdout, mr); err != nil {
+			lib.Exit("Error", err)
+		}
+		return
+	}
+
+	if ui.Quiet {
+		fmt.Println(mr.WebURL)
+		return
+	}
+
+	draftPrefix := ""
+	if mr.Draft {
+		draftPrefix = "[Draft] "
+	}
+
+	fmt.Printf("%s!%d  %s%s\n", ui.StateIcon(mr.State), mr.IID, draftPrefix, mr.Title)
+	fmt.Printf("  %s "+lib.Arrow+" %s\n", mr.SourceBranch, mr.TargetBranch)
+	fmt.Printf("  State: %s\n", ui.State(mr.State))
+	if status := mr.DetailedMergeStatus; status != "" {
+		fmt.Printf("  Merge status: %s\n", status)
+	}
+	fmt.Printf("  Author: @%s  |  created %s  |  updated %s\n",
+		mr.Author.Username, lib.FormatAge(mr.CreatedAt), lib.FormatAge(mr.UpdatedAt))
+	if len(mr.Reviewers) > 0 {
+		var names []string
+		for _, r := range mr.Reviewers {
+			names = append(names, "@"+r.Username)
+		}
+		fmt.Printf("  Reviewers: %s\n", strings.Join(names, ", "))
+	}
+	if len(mr.Labels) > 0 {
+		fmt.Printf("  Labels: %s\n", strings.Join(mr.Labels, ", "))
+	}
+	fmt.Printf("  URL: %s\n", mr.WebURL)
+
+	if mr.Description != "" {
+		fmt.Printf("\n%s\n", mr.Description)
+	}
+}
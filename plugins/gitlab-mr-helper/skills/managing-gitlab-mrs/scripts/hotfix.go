@@ -0,0 +1,193 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	tag := flag.String("tag", "", "Production tag to branch from (default: newest tag matching hotfix.tag_pattern)")
+	commit := flag.String("commit", "", "Fix commit SHA to cherry-pick onto the hotfix branch")
+	branch := flag.String("branch", "", "Hotfix branch name (default: hotfix/<tag>, or hotfix/<tag>-<sha> with --commit)")
+	target := flag.String("target", "", "Release branch the MR targets (default: hotfix.target_branch, release/{major}.{minor} of the tag)")
+	title := flag.String("title", "", "MR title (default: derived from the fix commit or tag)")
+	description := flag.String("description", "", "MR description")
+	labels := flag.String("labels", "", "Comma-separated labels added to hotfix.labels")
+	pipeline := flag.Bool("pipeline", true, "Trigger a pipeline on the hotfix branch (--pipeline=false to skip)")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	settings, err := lib.LoadSettings()
+	if err != nil {
+		lib.Exit("Error loading settings", err)
+	}
+	hs := settings.Hotfix
+
+	client := lib.NewClient(config)
+
+	// Production tag and the release branch it belongs to
+	tagName := *tag
+	if tagName == "" {
+		tagName, err = newestTag(client, projectPath, hs.TagPattern)
+		if err != nil {
+			lib.Exit("Error finding production tag", err)
+		}
+	}
+	targetBranch := *target
+	if targetBranch == "" {
+		version, err := lib.ParseVersion(tagName)
+		if err != nil {
+			lib.Usagef("cannot derive the release branch from tag %s (%v); use --target", tagName, err)
+		}
+		tmpl := hs.TargetBranch
+		if tmpl == "" {
+			tmpl = lib.DefaultHotfixTarget
+		}
+		targetBranch = version.Expand(tmpl)
+	}
+
+	hotfixBranch := *branch
+	if hotfixBranch == "" {
+		hotfixBranch = "hotfix/" + tagName
+		if *commit != "" {
+			hotfixBranch += "-" + lib.ShortSHA(*commit)
+		}
+	}
+
+	ui.Printf("Hotfix from %s "+lib.Arrow+" %s\n", tagName, targetBranch)
+	ui.Printf("  Branch: %s\n", hotfixBranch)
+	if _, err := client.CreateBranch(projectPath, hotfixBranch, tagName); err != nil {
+		lib.Exit("Error creating branch", err)
+	}
+
+	mrTitle := *title
+	if *commit != "" {
+		picked, err := client.CherryPickCommit(projectPath, *commit, hotfixBranch)
+		if err != nil {
+			// Don't leave a half-prepared branch behind
+			if derr := client.DeleteBranch(projectPath, hotfixBranch); derr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not delete branch %s: %v\n", hotfixBranch, derr)
+			}
+			lib.Exit("Error cherry-picking commit", err)
+		}
+		ui.Printf("  Cherry-picked %s: %s\n", lib.ShortSHA(*commit), picked.Title)
+		if mrTitle == "" {
+			mrTitle = "Hotfix: " + picked.Title
+		}
+	}
+	if mrTitle == "" {
+		mrTitle = fmt.Sprintf("Hotfix for %s", tagName)
+	}
+
+	labelList := hs.Labels
+	if labelList == nil {
+		labelList = []string{"hotfix"}
+	}
+	if *labels != "" {
+		labelList = append(labelList, lib.SplitList(*labels)...)
+	}
+
+	reviewerIDs, err := resolveReviewers(client, projectPath, hs.Reviewers)
+	if err != nil {
+		lib.Exit("Error resolving reviewers", err)
+	}
+
+	desc := *description
+	if desc == "" {
+		desc = fmt.Sprintf("Hotfix based on production tag `%s`.", tagName)
+		if *commit != "" {
+			desc += fmt.Sprintf("\n\nCherry-picks %s.", *commit)
+		}
+	}
+
+	mr, err := client.CreateMR(projectPath, &lib.CreateMRRequest{
+		SourceBranch:       hotfixBranch,
+		TargetBranch:       targetBranch,
+		Title:              mrTitle,
+		Description:        desc,
+		Labels:             labelList,
+		ReviewerIDs:        reviewerIDs,
+		RemoveSourceBranch: true,
+	})
+	if err != nil {
+		lib.Exit("Error creating MR", err)
+	}
+
+	var pl *lib.Pipeline
+	if *pipeline {
+		if pl, err = client.CreatePipeline(projectPath, hotfixBranch, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not trigger pipeline: %v\n", err)
+		}
+	}
+
+	if ui.Quiet {
+		fmt.Println(mr.WebURL)
+		return
+	}
+
+	fmt.Printf("\n%s\n", ui.Success(fmt.Sprintf("Hotfix MR !%d created", mr.IID)))
+	fmt.Printf("  URL: %s\n", mr.WebURL)
+	fmt.Printf("  State: %s\n", ui.State(mr.State))
+	if pl != nil {
+		fmt.Printf("  Pipeline: #%d (%s) %s\n", pl.ID, pl.Status, pl.WebURL)
+	}
+}
+
+// newestTag returns the most recently updated tag matching pattern, or the
+// newest tag with a version number when pattern is empty
+func newestTag(client *lib.Client, projectPath, pattern string) (string, error) {
+	var re *regexp.Regexp
+	if pattern != "" {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return "", fmt.Errorf("invalid hotfix.tag_pattern: %w", err)
+		}
+	}
+	tags, err := client.ListTags(projectPath, "")
+	if err != nil {
+		return "", err
+	}
+	for _, t := range tags {
+		if re != nil && re.MatchString(t.Name) {
+			return t.Name, nil
+		}
+		if _, err := lib.ParseVersion(t.Name); re == nil && err == nil {
+			return t.Name, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no production tag found; use --tag", lib.ErrNotFound)
+}
+
+// resolveReviewers maps usernames to user IDs via the project members.
+// Unknown usernames are reported and skipped.
+func resolveReviewers(client *lib.Client, projectPath string, usernames []string) ([]int, error) {
+	ids, unknown, err := client.MemberIDs(projectPath, usernames)
+	for _, u := range unknown {
+		fmt.Fprintf(os.Stderr, "Warning: reviewer %s is not a project member; skipped\n", u)
+	}
+	return ids, err
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	file := flag.String("file", "", "CSV or JSON file of issues to import ('-' for stdin; required)")
+	asJSON := flag.Bool("json", false, "Read JSON instead of CSV (default: JSON for .json files)")
+	update := flag.Bool("update", false, "Update the issues whose iid is given instead of creating new ones")
+	dryRun := flag.Bool("dry-run", false, "Report what would be created or updated without changing anything")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *file == "" {
+		lib.Usagef("--file is required")
+	}
+	format := lib.IssueFormatFromPath(*file)
+	if *asJSON {
+		format = lib.IssueFormatJSON
+	}
+
+	var in io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	records, err := lib.ReadIssueRecords(in, format)
+	if err != nil {
+		lib.Exit("Error reading issues", err)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+
+	// Assignees are usernames; those who are not project members, e.g.
+	// users of another tracker, are left out with a warning
+	members, err := client.ListProjectMembers(projectPath)
+	if err != nil {
+		lib.Exit("Error listing members", err)
+	}
+	ids := make(map[string]int)
+	for _, m := range members {
+		ids[m.Username] = m.ID
+	}
+	unknown := make(map[string]bool)
+	for _, rec := range records {
+		for _, a := range rec.Assignees {
+			if _, ok := ids[a]; !ok {
+				unknown[a] = true
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		names := make([]string, 0, len(unknown))
+		for name := range unknown {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(os.Stderr, "Warning: not project members, not assigned: %s\n", strings.Join(names, ", "))
+	}
+
+	existing := make(map[int]*lib.Issue)
+	if *update {
+		issues, err := client.ListProjectIssues(projectPath, &lib.IssueListOptions{State: "all"})
+		if err != nil {
+			lib.Exit("Error listing issues", err)
+		}
+		for i := range issues {
+			existing[issues[i].IID] = &issues[i]
+		}
+	}
+
+	prefix := ""
+	if *dryRun {
+		prefix = "[dry-run] "
+	}
+
+	// Issues are imported one at a time, in file order, so their IIDs
+	// follow it. Rows without an IID (or all rows, without --update) are
+	// created; a row that fails is skipped and makes the import exit
+	// non-zero once the rest are in.
+	var firstErr error
+	created, updated, unchanged := 0, 0, 0
+	for i := range records {
+		rec := &records[i]
+		var line string
+		var err error
+		if *update && rec.IID > 0 {
+			line, err = updateIssue(client, projectPath, rec, existing[rec.IID], ids, *dryRun)
+			if line == "" && err == nil {
+				unchanged++
+				continue
+			}
+			if err == nil {
+				updated++
+			}
+		} else {
+			line, err = createIssue(client, projectPath, rec, ids, *dryRun)
+			if err == nil {
+				created++
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.Failure(fmt.Sprintf("issue %d (%s): %v", i+1, rec.Title, err)))
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if ui.Quiet {
+			if rec.IID > 0 {
+				fmt.Println(rec.IID)
+			}
+			continue
+		}
+		fmt.Printf("%s%s\n", prefix, ui.Success(line))
+	}
+
+	ui.Printf("\n%s%s: created %d issue(s), updated %d, %d unchanged\n", prefix, projectPath, created, updated, unchanged)
+
+	if firstErr != nil {
+		lib.ExitWith(lib.ExitCode(firstErr))
+	}
+}
+
+// createIssue creates rec, closing it when it is closed, and sets rec.IID
+// to the new issue's
+func createIssue(client *lib.Client, projectPath string, rec *lib.IssueRecord, ids map[string]int, dryRun bool) (string, error) {
+	if dryRun {
+		rec.IID = 0
+		return fmt.Sprintf("created %s (%s)", rec.Title, rec.State), nil
+	}
+	issue, err := client.CreateIssue(projectPath, rec.CreateRequest(ids))
+	if err != nil {
+		return "", err
+	}
+	rec.IID = issue.IID
+	if rec.State == "closed" {
+		if _, err := client.UpdateIssue(projectPath, issue.IID, &lib.UpdateIssueRequest{StateEvent: "close"}); err != nil {
+			return "", fmt.Errorf("created #%d but could not close it: %w", issue.IID, err)
+		}
+	}
+	return fmt.Sprintf("created #%d %s (%s)", issue.IID, rec.Title, rec.State), nil
+}
+
+// updateIssue makes issue match rec, returning an empty line when it
+// already does
+func updateIssue(client *lib.Client, projectPath string, rec *lib.IssueRecord, issue *lib.Issue, ids map[string]int, dryRun bool) (string, error) {
+	if issue == nil {
+		return "", fmt.Errorf("%w: issue #%d", lib.ErrNotFound, rec.IID)
+	}
+	req, changed := rec.UpdateRequest(issue, ids)
+	if req == nil {
+		return "", nil
+	}
+	if !dryRun {
+		if _, err := client.UpdateIssue(projectPath, rec.IID, req); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("updated #%d %s (%s)", rec.IID, rec.Title, strings.Join(changed, ", ")), nil
+}
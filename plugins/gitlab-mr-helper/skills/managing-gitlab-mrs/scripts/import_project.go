@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	file := flag.String("file", "", "Export archive to import ('-' for stdin)")
+	from := flag.String("from", "", "Export this project of the same instance and import it (instead of --file)")
+	namespace := flag.String("namespace", "", "Group or user path to import into (default: your user)")
+	path := flag.String("path", "", "Path of the new project (required)")
+	name := flag.String("name", "", "Name of the new project (default: --path)")
+	overwrite := flag.Bool("overwrite", false, "Replace an existing project at the same path")
+	noWait := flag.Bool("no-wait", false, "Return once the import is scheduled instead of waiting for it")
+	timeout := flag.Duration("timeout", 30*time.Minute, "How long to wait for the export and the import")
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if (*file == "") == (*from == "") {
+		lib.Usagef("exactly one of --file and --from is required")
+	}
+	if *path == "" {
+		lib.Usagef("--path is required")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	client := lib.NewClient(config)
+
+	// Open the archive: a file, stdin, or a fresh export of --from
+	var archive io.Reader
+	switch {
+	case *file == "-":
+		archive = os.Stdin
+	case *file != "":
+		f, err := os.Open(*file)
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+		defer f.Close()
+		archive = f
+	default:
+		ui.Printf("Exporting %s...\n", *from)
+		if err := client.ExportProject(*from); err != nil {
+			lib.Exit("Error starting export", err)
+		}
+		if err := client.WaitProjectExport(*from, 5*time.Second, *timeout); err != nil {
+			lib.Exit("Error waiting for export", err)
+		}
+		body, err := client.DownloadProjectExport(*from)
+		if err != nil {
+			lib.Exit("Error downloading export", err)
+		}
+		defer body.Close()
+		archive = body
+	}
+
+	req := &lib.ImportProjectRequest{Namespace: *namespace, Path: *path, Name: *name, Overwrite: *overwrite}
+	ui.Printf("Importing into %s...\n", config.URL)
+	imp, err := client.ImportProject(req, archive)
+	if err != nil {
+		lib.Exit("Error importing project", err)
+	}
+	projectPath := imp.PathWithNamespace
+	webURL := config.URL + "/" + projectPath
+
+	if !*noWait {
+		if imp, err = client.WaitProjectImport(projectPath, 5*time.Second, *timeout); err != nil {
+			lib.Exit("Error waiting for import", err)
+		}
+	}
+
+	if ui.Quiet {
+		fmt.Println(webURL)
+		return
+	}
+	if *noWait {
+		fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Import of %s scheduled", projectPath)))
+	} else {
+		fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Imported %s", projectPath)))
+	}
+	fmt.Printf("  Status: %s\n", imp.ImportStatus)
+	fmt.Printf("  URL: %s\n", webURL)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	jobID := flag.Int("job", 0, "Job ID (required)")
+	maxBytes := flag.Int64("max-bytes", 1<<20, "Show at most this many bytes of the log, 0 for all")
+	head := flag.Bool("head", false, "Keep the start of a long log instead of its end")
+	follow := flag.Bool("follow", false, "Keep printing the log as the job runs, until it finishes; exits 1 unless it succeeded")
+	interval := flag.Duration("interval", 2*time.Second, "With --follow, shortest wait between checks, used while output is coming")
+	maxInterval := flag.Duration("max-interval", 30*time.Second, "With --follow, longest wait between checks, while the job is pending or quiet")
+	timeout := flag.Duration("timeout", 0, "With --follow, give up after this long with exit code 6 (default: wait for the job)")
+	projectFlags := lib.RegisterProjectFlags()
+	lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *jobID == 0 {
+		lib.Usagef("--job is required")
+	}
+	if *maxBytes < 0 {
+		lib.Usagef("--max-bytes must not be negative")
+	}
+	if *follow && *head {
+		lib.Usagef("--head cannot be combined with --follow")
+	}
+	if *interval <= 0 || *maxInterval < *interval {
+		lib.Usagef("--interval must be positive and --max-interval at least --interval")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path. The log goes to stdout, so the project is not
+	// echoed there.
+	projectPath, _, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+
+	client := lib.NewClient(config)
+
+	// The job's status is read before its log, so the log read after the
+	// job finished is complete
+	var job *lib.Job
+	if *follow {
+		if job, err = client.GetJob(projectPath, *jobID); err != nil {
+			lib.Exit(fmt.Sprintf("Error getting job %d", *jobID), err)
+		}
+	}
+
+	trace, err := client.GetJobTrace(projectPath, *jobID)
+	if err != nil {
+		lib.Exit(fmt.Sprintf("Error getting log of job %d", *jobID), err)
+	}
+	defer trace.Close()
+
+	// Logs are streamed, never held in memory beyond --max-bytes
+	counted := &countingReader{r: trace}
+	switch {
+	case *maxBytes == 0:
+		_, err = io.Copy(os.Stdout, counted)
+	case *head:
+		_, err = io.Copy(os.Stdout, lib.HeadReader(counted, *maxBytes))
+	default:
+		err = lib.WriteTail(os.Stdout, counted, *maxBytes)
+	}
+	if err != nil {
+		lib.Exit("Error reading job log", err)
+	}
+	if !*follow {
+		return
+	}
+
+	// Follow the log: quickly while output is coming, less and less often
+	// while the job waits for a runner or is quiet
+	offset := counted.n
+	poller := lib.Poller{Interval: *interval, MaxInterval: *maxInterval, Timeout: *timeout}
+	err = poller.Poll(func() (bool, time.Duration, error) {
+		if lib.PipelineFinished(job.Status) {
+			return true, 0, nil
+		}
+		var err error
+		if job, err = client.GetJob(projectPath, *jobID); err != nil {
+			return false, 0, err
+		}
+		n, err := printFrom(client, projectPath, *jobID, offset)
+		if err != nil {
+			return false, 0, err
+		}
+		offset += n
+		switch {
+		case lib.PipelineFinished(job.Status):
+			return true, 0, nil
+		case n > 0:
+			return false, 0, nil
+		}
+		return false, lib.PollBackoff, nil
+	})
+	if err != nil {
+		lib.Exit(fmt.Sprintf("Error following log of job %d", *jobID), err)
+	}
+	if job.Status != "success" {
+		lib.Exit("Error", fmt.Errorf("job %d %s: %s", job.ID, job.Status, job.WebURL))
+	}
+}
+
+// printFrom prints the log of a job from byte offset on and returns how
+// many bytes it printed. The trace API has no ranges, so the start is
+// read and skipped.
+func printFrom(client *lib.Client, projectPath string, jobID int, offset int64) (int64, error) {
+	trace, err := client.GetJobTrace(projectPath, jobID)
+	if err != nil {
+		return 0, err
+	}
+	defer trace.Close()
+	if _, err := io.CopyN(io.Discard, trace, offset); err != nil {
+		if err == io.EOF {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return io.Copy(os.Stdout, trace)
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	mrFlag := lib.RegisterMRFlag("Merge request IID, web URL or source branch whose LFS files to list (in a merge request pipeline, that MR)")
+	file := flag.String("file", "", "Read this repository file instead of an MR's changes, resolving it when it is an LFS pointer")
+	ref := flag.String("ref", "", "Branch, tag or SHA to read --file at (default: the default branch)")
+	download := flag.String("download", "", "Download the real content of the LFS files into this directory, at their repository paths")
+	old := flag.Bool("old", false, "Download the versions from before the MR instead of its changes")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *file == "" {
+		if err := mrFlag.Parse(); err != nil {
+			lib.Exit("Error", err)
+		}
+		if *ref != "" {
+			lib.Usagef("--ref goes with --file")
+		}
+	} else if mrFlag.Value != "" || *old {
+		lib.Usagef("--file reads one file; --mr and --old go with an MR's changes")
+	}
+	if *old && *download == "" {
+		lib.Usagef("--old goes with --download")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	if *file != "" {
+		readFile(client, ui, projectPath, *file, *ref, *download)
+		return
+	}
+
+	mrIID, err := mrFlag.Resolve(client, projectPath)
+	if err != nil {
+		lib.Exit("Error finding MR", err)
+	}
+	diffs, err := client.GetMRDiffs(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR changes", err)
+	}
+	changes := lib.LFSChanges(diffs)
+	if len(changes) == 0 {
+		ui.Printf("MR !%d changes no LFS files (%d file(s) changed)\n", mrIID, len(diffs))
+		return
+	}
+
+	ui.Printf("MR !%d: %d LFS file(s) of %d changed\n", mrIID, len(changes), len(diffs))
+	var wanted []lib.LFSPointer
+	var paths []string
+	for _, c := range changes {
+		if ui.Quiet && *download == "" {
+			fmt.Println(c.NewPath)
+		}
+		ui.Printf("  %s\n", describe(c))
+		p, name := c.New, c.NewPath
+		if *old {
+			p, name = c.Old, c.OldPath
+		}
+		if p != nil {
+			wanted = append(wanted, *p)
+			paths = append(paths, name)
+		}
+	}
+	if *download == "" {
+		return
+	}
+	if len(wanted) == 0 {
+		ui.Printf("Nothing to download: the LFS files are all %s\n", map[bool]string{true: "new", false: "deleted"}[*old])
+		return
+	}
+
+	objects, err := client.LFSBatch(projectPath, wanted)
+	if err != nil {
+		lib.Exit("Error requesting LFS downloads", err)
+	}
+	byOID := make(map[string]*lib.LFSObject)
+	for i := range objects {
+		byOID[objects[i].OID] = &objects[i]
+	}
+	failed := 0
+	for i, p := range wanted {
+		obj := byOID[p.OID]
+		if obj == nil {
+			obj = &lib.LFSObject{OID: p.OID, Error: &lib.LFSError{Code: 404, Message: "missing from the batch response"}}
+		}
+		if err := save(client, ui, obj, paths[i], *download); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.Failure(fmt.Sprintf("%s: %v", paths[i], err)))
+			failed++
+		}
+	}
+	if failed > 0 {
+		lib.Exit("Error", fmt.Errorf("%d of %d LFS download(s) failed", failed, len(wanted)))
+	}
+}
+
+// describe renders an LFS change as a status letter, the path and the
+// sizes and OIDs on both sides
+func describe(c lib.LFSChange) string {
+	switch {
+	case c.Old == nil:
+		return fmt.Sprintf("A %s  %s  (sha256 %s)", c.NewPath, lib.FormatSize(c.New.Size), lib.ShortSHA(c.New.OID))
+	case c.New == nil:
+		return fmt.Sprintf("D %s  %s  (sha256 %s)", c.OldPath, lib.FormatSize(c.Old.Size), lib.ShortSHA(c.Old.OID))
+	}
+	name := c.NewPath
+	if c.OldPath != c.NewPath {
+		name = c.OldPath + " " + lib.Arrow + " " + c.NewPath
+	}
+	return fmt.Sprintf("M %s  %s "+lib.Arrow+" %s  (sha256 %s "+lib.Arrow+" %s)", name, lib.FormatSize(c.Old.Size), lib.FormatSize(c.New.Size), lib.ShortSHA(c.Old.OID), lib.ShortSHA(c.New.OID))
+}
+
+// readFile prints what a repository file is and, with a download
+// directory, writes its real content there
+func readFile(client *lib.Client, ui *lib.UI, projectPath, name, ref, dir string) {
+	if ref == "" {
+		var err error
+		if ref, err = client.DefaultBranch(projectPath); err != nil {
+			lib.Exit("Error getting default branch", err)
+		}
+	}
+	f, err := client.GetFile(projectPath, name, ref)
+	if err != nil {
+		lib.Exit("Error reading "+name, err)
+	}
+	p := f.LFSPointer()
+	if p == nil {
+		ui.Printf("%s@%s is not stored in Git LFS\n", name, ref)
+		if dir != "" {
+			text, err := f.Text()
+			if err != nil {
+				lib.Exit("Error decoding "+name, err)
+			}
+			target, err := localPath(dir, name)
+			if err == nil {
+				err = os.WriteFile(target, []byte(text), 0o644)
+			}
+			if err != nil {
+				lib.Exit("Error writing "+name, err)
+			}
+			if ui.Quiet {
+				fmt.Println(target)
+			}
+			ui.Printf("%s\n", ui.Success(fmt.Sprintf("Wrote %s (%s)", target, lib.FormatSize(int64(len(text))))))
+		}
+		return
+	}
+
+	if ui.Quiet && dir == "" {
+		fmt.Printf("%s %d\n", p.OID, p.Size)
+	}
+	ui.Printf("%s@%s is stored in Git LFS: %s, sha256 %s\n", name, ref, lib.FormatSize(p.Size), p.OID)
+	if dir == "" {
+		return
+	}
+	objects, err := client.LFSBatch(projectPath, []lib.LFSPointer{*p})
+	if err != nil {
+		lib.Exit("Error requesting LFS download", err)
+	}
+	if len(objects) == 0 {
+		lib.Exit("Error", fmt.Errorf("%w: LFS object %s", lib.ErrNotFound, lib.ShortSHA(p.OID)))
+	}
+	if err := save(client, ui, &objects[0], name, dir); err != nil {
+		lib.Exit("Error downloading "+name, err)
+	}
+}
+
+// save downloads an LFS object to its repository path under dir
+func save(client *lib.Client, ui *lib.UI, obj *lib.LFSObject, name, dir string) error {
+	target, err := localPath(dir, name)
+	if err != nil {
+		return err
+	}
+	var bar *lib.ProgressLine
+	if !ui.Quiet {
+		bar = lib.NewProgressLine(os.Stderr, "Downloading "+name)
+	}
+	d, err := client.SaveLFSObject(obj, target, &lib.DownloadOptions{Progress: bar.Update})
+	bar.Done()
+	if err != nil {
+		return err
+	}
+	if ui.Quiet {
+		fmt.Println(target)
+	}
+	ui.Printf("  %s\n", ui.Success(fmt.Sprintf("Wrote %s (%s, verified)", target, lib.FormatSize(d.Size))))
+	return nil
+}
+
+// localPath places a repository path under dir, creating its directories
+func localPath(dir, name string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", fmt.Errorf("refusing to write %q outside %s", name, dir)
+	}
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", err
+	}
+	return target, nil
+}
@@ -0,0 +1,746 @@
+package lib_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+// wantExit asserts that err maps to the expected exit code
+func wantExit(t *testing.T, err error, code int) {
+	t.Helper()
+	if got := lib.ExitCode(err); got != code {
+		t.Fatalf("ExitCode(%v) = %d, want %d", err, got, code)
+	}
+}
+
+func TestCreateMR(t *testing.T) {
+	tests := []struct {
+		name     string
+		project  string
+		req      lib.CreateMRRequest
+		wantIID  int
+		wantExit int
+	}{
+		{
+			name:    "creates MR",
+			project: gitlabtest.ProjectPath,
+			req:     lib.CreateMRRequest{SourceBranch: "feature/new", TargetBranch: "main", Title: "New", Labels: []string{"a"}},
+			wantIID: 4,
+		},
+		{
+			name:    "nested project path",
+			project: gitlabtest.NestedProjectPath,
+			req:     lib.CreateMRRequest{SourceBranch: "feature/new", TargetBranch: "main", Title: "New"},
+			wantIID: 2,
+		},
+		{
+			name:     "duplicate source branch conflicts",
+			project:  gitlabtest.ProjectPath,
+			req:      lib.CreateMRRequest{SourceBranch: "feature/login", TargetBranch: "main", Title: "Dup"},
+			wantExit: lib.ExitConflict,
+		},
+		{
+			name:     "unknown project",
+			project:  "nope/nope",
+			req:      lib.CreateMRRequest{SourceBranch: "a", TargetBranch: "main", Title: "x"},
+			wantExit: lib.ExitNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			mr, err := srv.Client().CreateMR(tt.project, &tt.req)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateMR: %v", err)
+			}
+			if mr.IID != tt.wantIID || mr.Title != tt.req.Title || mr.State != "opened" {
+				t.Errorf("got !%d %q (%s), want !%d %q (opened)", mr.IID, mr.Title, mr.State, tt.wantIID, tt.req.Title)
+			}
+		})
+	}
+}
+
+func TestListMRs(t *testing.T) {
+	tests := []struct {
+		name     string
+		project  string
+		state    string
+		limit    int
+		wantIIDs []int
+		wantExit int
+	}{
+		{name: "opened", project: gitlabtest.ProjectPath, state: "opened", wantIIDs: []int{1, 2}},
+		{name: "merged", project: gitlabtest.ProjectPath, state: "merged", wantIIDs: []int{3}},
+		{name: "all", project: gitlabtest.ProjectPath, state: "all", wantIIDs: []int{1, 2, 3}},
+		{name: "limit", project: gitlabtest.ProjectPath, state: "all", limit: 2, wantIIDs: []int{1, 2}},
+		{name: "nested", project: gitlabtest.NestedProjectPath, state: "opened", wantIIDs: []int{1}},
+		{name: "unknown project", project: "nope/nope", wantExit: lib.ExitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			mrs, err := srv.Client().ListMRs(tt.project, tt.state, tt.limit)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("ListMRs: %v", err)
+			}
+			if got := iids(mrs); !equalInts(got, tt.wantIIDs) {
+				t.Errorf("IIDs = %v, want %v", got, tt.wantIIDs)
+			}
+		})
+	}
+}
+
+func TestListProjectMRs(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     lib.MRListOptions
+		wantIIDs []int
+	}{
+		{name: "opened", opts: lib.MRListOptions{State: "opened"}, wantIIDs: []int{1, 2}},
+		{name: "by source", opts: lib.MRListOptions{SourceBranch: "old-work"}, wantIIDs: []int{3}},
+		{name: "limit", opts: lib.MRListOptions{Limit: 1}, wantIIDs: []int{1}},
+		{name: "by label", opts: lib.MRListOptions{State: "all", Labels: []string{"frontend"}}, wantIIDs: []int{1}},
+		{name: "by milestone", opts: lib.MRListOptions{State: "all", Milestone: "v1.0"}, wantIIDs: []int{1, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			mrs, err := srv.Client().ListProjectMRs(gitlabtest.ProjectPath, &tt.opts)
+			if err != nil {
+				t.Fatalf("ListProjectMRs: %v", err)
+			}
+			if got := iids(mrs); !equalInts(got, tt.wantIIDs) {
+				t.Errorf("IIDs = %v, want %v", got, tt.wantIIDs)
+			}
+		})
+	}
+}
+
+func TestFindOpenMR(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		target  string
+		wantIID int // 0 means no MR expected
+	}{
+		{name: "existing open MR", source: "feature/login", target: "main", wantIID: 1},
+		{name: "different target", source: "feature/login", target: "develop"},
+		{name: "merged MR is ignored", source: "old-work", target: "main"},
+		{name: "unknown branch", source: "nope", target: "main"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			mr, err := srv.Client().FindOpenMR(gitlabtest.ProjectPath, tt.source, tt.target)
+			if err != nil {
+				t.Fatalf("FindOpenMR: %v", err)
+			}
+			got := 0
+			if mr != nil {
+				got = mr.IID
+			}
+			if got != tt.wantIID {
+				t.Errorf("got !%d, want !%d", got, tt.wantIID)
+			}
+		})
+	}
+}
+
+func TestFindMRByBranch(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	tests := []struct {
+		branch   string
+		wantIID  int
+		wantExit int
+	}{
+		{branch: "fix/crash", wantIID: 2},
+		{branch: "old-work", wantIID: 3}, // merged, found when none is open
+		{branch: "nope", wantExit: lib.ExitNotFound},
+	}
+	for _, tt := range tests {
+		mr, err := client.FindMRByBranch(gitlabtest.ProjectPath, tt.branch)
+		if tt.wantExit != 0 {
+			wantExit(t, err, tt.wantExit)
+			continue
+		}
+		if err != nil || mr.IID != tt.wantIID {
+			t.Errorf("FindMRByBranch(%q) = %+v, %v; want !%d", tt.branch, mr, err, tt.wantIID)
+		}
+	}
+
+	if _, err := client.CreateMR(gitlabtest.ProjectPath, &lib.CreateMRRequest{SourceBranch: "fix/crash", TargetBranch: "release", Title: "Backport"}); err != nil {
+		t.Fatalf("CreateMR: %v", err)
+	}
+	_, err := client.FindMRByBranch(gitlabtest.ProjectPath, "fix/crash")
+	wantExit(t, err, lib.ExitUsage)
+}
+
+func TestUpdateMR(t *testing.T) {
+	tests := []struct {
+		name      string
+		iid       int
+		req       lib.UpdateMRRequest
+		wantTitle string
+		wantState string
+		wantExit  int
+	}{
+		{name: "title", iid: 1, req: lib.UpdateMRRequest{Title: "Renamed"}, wantTitle: "Renamed", wantState: "opened"},
+		{name: "close", iid: 1, req: lib.UpdateMRRequest{StateEvent: "close"}, wantTitle: "Add login page", wantState: "closed"},
+		{name: "invalid state event", iid: 1, req: lib.UpdateMRRequest{StateEvent: "explode"}, wantExit: lib.ExitError},
+		{name: "unknown MR", iid: 99, req: lib.UpdateMRRequest{Title: "x"}, wantExit: lib.ExitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			mr, err := srv.Client().UpdateMR(gitlabtest.ProjectPath, tt.iid, &tt.req)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateMR: %v", err)
+			}
+			if mr.Title != tt.wantTitle || mr.State != tt.wantState {
+				t.Errorf("got %q (%s), want %q (%s)", mr.Title, mr.State, tt.wantTitle, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestUpsertMR(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		update     lib.UpdateMRRequest
+		wantAction string
+		wantIID    int
+		wantTitle  string
+		wantLabels []string
+	}{
+		{name: "creates when none is open", source: "feature/new", wantAction: lib.UpsertCreated, wantIID: 4, wantTitle: "Created title"},
+		{name: "creates when the MR is merged", source: "old-work", wantAction: lib.UpsertCreated, wantIID: 4, wantTitle: "Created title"},
+		{name: "updates the open MR", source: "feature/login", update: lib.UpdateMRRequest{Title: "Updated title", Labels: []string{"deps"}},
+			wantAction: lib.UpsertUpdated, wantIID: 1, wantTitle: "Updated title", wantLabels: []string{"deps"}},
+		{name: "empty update leaves it", source: "feature/login", wantAction: lib.UpsertUnchanged, wantIID: 1, wantTitle: "Add login page", wantLabels: []string{"frontend"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			create := &lib.CreateMRRequest{SourceBranch: tt.source, TargetBranch: "main", Title: "Created title"}
+			mr, action, err := srv.Client().UpsertMR(gitlabtest.ProjectPath, create, &tt.update)
+			if err != nil {
+				t.Fatalf("UpsertMR: %v", err)
+			}
+			if action != tt.wantAction || mr.IID != tt.wantIID || mr.Title != tt.wantTitle {
+				t.Errorf("got !%d %q %s, want !%d %q %s", mr.IID, mr.Title, action, tt.wantIID, tt.wantTitle, tt.wantAction)
+			}
+			if tt.wantLabels != nil && strings.Join(mr.Labels, ",") != strings.Join(tt.wantLabels, ",") {
+				t.Errorf("labels = %v, want %v", mr.Labels, tt.wantLabels)
+			}
+			var writes []string
+			for _, r := range srv.Requests() {
+				if r.Method != "GET" {
+					writes = append(writes, r.Method)
+				}
+			}
+			want := map[string]string{lib.UpsertCreated: "POST", lib.UpsertUpdated: "PUT", lib.UpsertUnchanged: ""}[tt.wantAction]
+			if strings.Join(writes, ",") != want {
+				t.Errorf("requests %v, want %q", writes, want)
+			}
+		})
+	}
+}
+
+func TestGetMR(t *testing.T) {
+	tests := []struct {
+		name          string
+		project       string
+		iid           int
+		wantTitle     string
+		wantConflicts bool
+		wantExit      int
+	}{
+		{name: "by path", project: gitlabtest.ProjectPath, iid: 1, wantTitle: "Add login page"},
+		{name: "by numeric ID", project: "42", iid: 2, wantTitle: "Fix crash on start", wantConflicts: true},
+		{name: "nested", project: gitlabtest.NestedProjectPath, iid: 1, wantTitle: "Update readme"},
+		{name: "unknown MR", project: gitlabtest.ProjectPath, iid: 99, wantExit: lib.ExitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			mr, err := srv.Client().GetMR(tt.project, tt.iid)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetMR: %v", err)
+			}
+			if mr.Title != tt.wantTitle || mr.HasConflicts != tt.wantConflicts {
+				t.Errorf("got %q conflicts=%v, want %q conflicts=%v", mr.Title, mr.HasConflicts, tt.wantTitle, tt.wantConflicts)
+			}
+		})
+	}
+}
+
+func TestGetMRDiffs(t *testing.T) {
+	tests := []struct {
+		name      string
+		iid       int
+		wantPaths []string
+		wantExit  int
+	}{
+		{name: "single file", iid: 1, wantPaths: []string{"web/login.html"}},
+		{name: "two files", iid: 2, wantPaths: []string{"cmd/main.go", "README.md"}},
+		{name: "no diffs", iid: 3, wantPaths: nil},
+		{name: "unknown MR", iid: 99, wantExit: lib.ExitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			diffs, err := srv.Client().GetMRDiffs(gitlabtest.ProjectPath, tt.iid)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetMRDiffs: %v", err)
+			}
+			var paths []string
+			for _, d := range diffs {
+				paths = append(paths, d.NewPath)
+			}
+			if strings.Join(paths, ",") != strings.Join(tt.wantPaths, ",") {
+				t.Errorf("paths = %v, want %v", paths, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestGetMRDiffsPaginates(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	p := srv.Project(gitlabtest.ProjectPath)
+	for i := 0; i < 250; i++ {
+		p.Diffs[3] = append(p.Diffs[3], lib.Diff{NewPath: "f"})
+	}
+
+	diffs, err := srv.Client().GetMRDiffs(gitlabtest.ProjectPath, 3)
+	if err != nil {
+		t.Fatalf("GetMRDiffs: %v", err)
+	}
+	if len(diffs) != 250 {
+		t.Errorf("got %d diffs, want 250", len(diffs))
+	}
+}
+
+func TestListGroupMRs(t *testing.T) {
+	tests := []struct {
+		name     string
+		group    string
+		opts     lib.MRListOptions
+		wantRefs []string
+		wantExit int
+	}{
+		{
+			name:     "group opened",
+			group:    "group",
+			opts:     lib.MRListOptions{State: "opened"},
+			wantRefs: []string{"group/project!1", "group/project!2", "group/sub/nested!1"},
+		},
+		{
+			name:     "subgroup",
+			group:    "group/sub",
+			opts:     lib.MRListOptions{State: "opened"},
+			wantRefs: []string{"group/sub/nested!1"},
+		},
+		{
+			name:     "reviewer filter",
+			group:    "group",
+			opts:     lib.MRListOptions{State: "opened", ReviewerID: gitlabtest.Alice.ID},
+			wantRefs: []string{"group/project!2", "group/sub/nested!1"},
+		},
+		{
+			name:     "instance-wide with source branch",
+			opts:     lib.MRListOptions{SourceBranch: "fix/crash"},
+			wantRefs: []string{"group/project!2"},
+		},
+		{
+			name:     "unknown group",
+			group:    "nope",
+			wantExit: lib.ExitNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			mrs, err := srv.Client().ListGroupMRs(tt.group, &tt.opts)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("ListGroupMRs: %v", err)
+			}
+			var refs []string
+			for _, mr := range mrs {
+				refs = append(refs, mr.References.Full)
+			}
+			if strings.Join(refs, ",") != strings.Join(tt.wantRefs, ",") {
+				t.Errorf("refs = %v, want %v", refs, tt.wantRefs)
+			}
+		})
+	}
+}
+
+func TestGetMRApprovals(t *testing.T) {
+	tests := []struct {
+		name        string
+		iid         int
+		approvedBy  lib.User
+		wantApprove bool
+		wantExit    int
+	}{
+		{name: "approved by bob", iid: 1, approvedBy: gitlabtest.Bob, wantApprove: true},
+		{name: "not approved by alice", iid: 1, approvedBy: gitlabtest.Alice, wantApprove: false},
+		{name: "no approvals", iid: 2, approvedBy: gitlabtest.Bob, wantApprove: false},
+		{name: "unknown MR", iid: 99, wantExit: lib.ExitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			approvals, err := srv.Client().GetMRApprovals(gitlabtest.ProjectPath, tt.iid)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetMRApprovals: %v", err)
+			}
+			if got := approvals.HasApproved(tt.approvedBy.ID); got != tt.wantApprove {
+				t.Errorf("HasApproved(%s) = %v, want %v", tt.approvedBy.Username, got, tt.wantApprove)
+			}
+		})
+	}
+}
+
+func TestGetCurrentUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		sudo     string
+		notAdmin bool
+		wantUser string
+		wantExit int
+	}{
+		{name: "valid token", token: gitlabtest.Token, wantUser: "alice"},
+		{name: "invalid token", token: "wrong", wantExit: lib.ExitAuth},
+		{name: "sudo", token: gitlabtest.Token, sudo: "bob", wantUser: "bob"},
+		{name: "sudo unknown user", token: gitlabtest.Token, sudo: "mallory", wantExit: lib.ExitNotFound},
+		{name: "sudo without admin", token: gitlabtest.Token, sudo: "bob", notAdmin: true, wantExit: lib.ExitAuth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			srv.SetAdmin(!tt.notAdmin)
+			client := lib.NewClient(&lib.Config{URL: srv.URL, Token: tt.token, Sudo: tt.sudo})
+			user, err := client.GetCurrentUser()
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetCurrentUser: %v", err)
+			}
+			if user.Username != tt.wantUser {
+				t.Errorf("username = %q, want %q", user.Username, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestCompareRefs(t *testing.T) {
+	tests := []struct {
+		name        string
+		project     string
+		from, to    string
+		wantCommits int
+		wantDiffs   int
+		wantExit    int
+	}{
+		{name: "behind target", project: gitlabtest.ProjectPath, from: "fix/crash", to: "main", wantCommits: 1, wantDiffs: 1},
+		{name: "up to date", project: gitlabtest.ProjectPath, from: "feature/login", to: "main"},
+		{name: "unknown project", project: "nope/nope", from: "a", to: "b", wantExit: lib.ExitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			cmp, err := srv.Client().CompareRefs(tt.project, tt.from, tt.to)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("CompareRefs: %v", err)
+			}
+			if len(cmp.Commits) != tt.wantCommits || len(cmp.Diffs) != tt.wantDiffs {
+				t.Errorf("got %d commits / %d diffs, want %d / %d", len(cmp.Commits), len(cmp.Diffs), tt.wantCommits, tt.wantDiffs)
+			}
+		})
+	}
+}
+
+func TestCheckUpToDate(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		require     bool
+		wantWarning string
+		wantExit    int
+	}{
+		{name: "behind warns", source: "fix/crash", wantWarning: "fix/crash is 1 commit(s) behind main; consider rebasing before review"},
+		{name: "behind with --require-up-to-date fails", source: "fix/crash", require: true, wantExit: lib.ExitConflict},
+		{name: "up to date is quiet", source: "feature/login"},
+		{name: "up to date with --require-up-to-date is quiet", source: "feature/login", require: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			warning, err := srv.Client().CheckUpToDate(gitlabtest.ProjectPath, tt.source, "main", tt.require)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				if err == nil || !strings.Contains(err.Error(), "rebase first") || warning != "" {
+					t.Errorf("got %q, %v; want only a rebase error", warning, err)
+				}
+				return
+			}
+			if err != nil || warning != tt.wantWarning {
+				t.Errorf("got %q, %v; want %q", warning, err, tt.wantWarning)
+			}
+		})
+	}
+
+	// A comparison that fails must not stop the MR
+	srv := gitlabtest.NewServer(t)
+	warning, err := srv.Client().CheckUpToDate("nope/nope", "a", "b", true)
+	if err != nil || !strings.HasPrefix(warning, "could not compare a with b") {
+		t.Errorf("failed comparison = %q, %v; want a warning", warning, err)
+	}
+}
+
+func TestListCommitMRs(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	mrs, err := srv.Client().ListCommitMRs(gitlabtest.ProjectPath, "bbb222")
+	if err != nil || !equalInts(iids(mrs), []int{3}) {
+		t.Errorf("ListCommitMRs(bbb222) = %v, %v; want !3", iids(mrs), err)
+	}
+	if mrs, err := srv.Client().ListCommitMRs(gitlabtest.ProjectPath, "b1b1b1"); err != nil || len(mrs) != 0 {
+		t.Errorf("ListCommitMRs of a direct push = %v, %v", iids(mrs), err)
+	}
+}
+
+func TestRevertCommit(t *testing.T) {
+	tests := []struct {
+		name     string
+		branch   string
+		wantExit int
+	}{
+		{name: "onto branch", branch: "develop"},
+		{name: "unknown branch", branch: "nope", wantExit: lib.ExitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			commit, err := srv.Client().RevertCommit(gitlabtest.ProjectPath, "ddd444", tt.branch)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("RevertCommit: %v", err)
+			}
+			if !strings.Contains(commit.Message, "reverts commit ddd444") {
+				t.Errorf("message = %q", commit.Message)
+			}
+		})
+	}
+}
+
+func TestCherryPickCommit(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+	if _, err := client.CreateBranch(gitlabtest.ProjectPath, "hotfix/v1.1.0", "v1.1.0"); err != nil {
+		t.Fatalf("CreateBranch from tag: %v", err)
+	}
+	commit, err := client.CherryPickCommit(gitlabtest.ProjectPath, "abc123", "hotfix/v1.1.0")
+	if err != nil {
+		t.Fatalf("CherryPickCommit: %v", err)
+	}
+	if !strings.Contains(commit.Message, "cherry picked from commit abc123") {
+		t.Errorf("message = %q", commit.Message)
+	}
+	_, err = client.CherryPickCommit(gitlabtest.ProjectPath, "abc123", "nope")
+	wantExit(t, err, lib.ExitNotFound)
+}
+
+func TestListTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		search string
+		want   []string
+	}{
+		{name: "all", want: []string{"nightly-20240301", "v1.1.0", "v1.0.0"}},
+		{name: "search", search: "v1", want: []string{"v1.1.0", "v1.0.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			tags, err := srv.Client().ListTags(gitlabtest.ProjectPath, tt.search)
+			if err != nil {
+				t.Fatalf("ListTags: %v", err)
+			}
+			var got []string
+			for _, tag := range tags {
+				got = append(got, tag.Name)
+			}
+			if !equalStrings(got, tt.want) {
+				t.Errorf("tags = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighestVersionTag(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+	tag, v, err := client.HighestVersionTag(gitlabtest.ProjectPath)
+	if err != nil || tag == nil || tag.Name != "v1.1.0" || v != (lib.Version{Major: 1, Minor: 1}) {
+		t.Fatalf("HighestVersionTag = %v, %v, %v; want v1.1.0", tag, v, err)
+	}
+
+	// A hotfix of an older release pushed last is not the highest version
+	p := srv.Project(gitlabtest.ProjectPath)
+	p.Tags = append([]lib.Tag{{Name: "v1.0.1"}, {Name: "v1.10.0"}, {Name: "v1.2.0"}}, p.Tags...)
+	tag, v, err = client.HighestVersionTag(gitlabtest.ProjectPath)
+	if err != nil || tag == nil || tag.Name != "v1.10.0" || v != (lib.Version{Major: 1, Minor: 10}) {
+		t.Errorf("HighestVersionTag = %v, %v, %v; want v1.10.0", tag, v, err)
+	}
+
+	p.Tags = []lib.Tag{{Name: "nightly"}}
+	if tag, _, err := client.HighestVersionTag(gitlabtest.ProjectPath); tag != nil || err != nil {
+		t.Errorf("HighestVersionTag without version tags = %v, %v", tag, err)
+	}
+}
+
+func TestGetFileAndCreateCommit(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	file, err := client.GetFile(gitlabtest.ProjectPath, "VERSION", "main")
+	if err != nil {
+		t.Fatalf("GetFile: %v", err)
+	}
+	if text, err := file.Text(); err != nil || text != "1.1.0\n" {
+		t.Fatalf("Text() = %q, %v", text, err)
+	}
+	_, err = client.GetFile(gitlabtest.ProjectPath, "nope.txt", "main")
+	wantExit(t, err, lib.ExitNotFound)
+
+	commit, err := client.CreateCommit(gitlabtest.ProjectPath, &lib.CreateCommitRequest{
+		Branch:        "develop",
+		CommitMessage: "Bump version to 1.2.0\n\nDetails",
+		Actions:       []lib.CommitAction{{Action: "update", FilePath: "VERSION", Content: "1.2.0\n"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateCommit: %v", err)
+	}
+	if commit.Title != "Bump version to 1.2.0" {
+		t.Errorf("title = %q", commit.Title)
+	}
+	if got := srv.Project(gitlabtest.ProjectPath).Files["VERSION"]; got != "1.2.0\n" {
+		t.Errorf("VERSION = %q after commit", got)
+	}
+
+	_, err = client.CreateCommit(gitlabtest.ProjectPath, &lib.CreateCommitRequest{
+		Branch:        "develop",
+		CommitMessage: "Update missing file",
+		Actions:       []lib.CommitAction{{Action: "update", FilePath: "nope.txt", Content: "x"}},
+	})
+	wantExit(t, err, lib.ExitError)
+}
+
+func TestAPIErrorExitCodes(t *testing.T) {
+	tests := []struct {
+		status int
+		want   int
+	}{
+		{401, lib.ExitAuth},
+		{403, lib.ExitAuth},
+		{404, lib.ExitNotFound},
+		{409, lib.ExitConflict},
+		{405, lib.ExitConflict},
+		{504, lib.ExitTimeout},
+		{500, lib.ExitError},
+	}
+
+	for _, tt := range tests {
+		err := error(&lib.APIError{StatusCode: tt.status})
+		if got := lib.ExitCode(err); got != tt.want {
+			t.Errorf("status %d: exit %d, want %d", tt.status, got, tt.want)
+		}
+	}
+
+	wantExit(t, lib.ErrNoToken, lib.ExitAuth)
+	wantExit(t, lib.UsageErrorf("bad flag"), lib.ExitUsage)
+	wantExit(t, errors.Join(errors.New("ctx"), lib.ErrBlocked), lib.ExitConflict)
+	wantExit(t, fmt.Errorf("%w: no task matches", lib.ErrNotFound), lib.ExitNotFound)
+}
+
+func iids(mrs []lib.MergeRequest) []int {
+	var out []int
+	for _, mr := range mrs {
+		out = append(out, mr.IID)
+	}
+	return out
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,208 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxDiffLines caps each side of a line diff; the diff is quadratic in the
+// number of lines
+const MaxDiffLines = 5000
+
+// ValueChange is a leaf value that differs between two JSON documents
+type ValueChange struct {
+	Path string // e.g. "bundles.app\.js" or "results[2].ns_per_op"
+	// Old and New are the values as JSON, empty when the key was added or
+	// removed
+	Old, New string
+	// Delta is the relative change when both values are non-zero numbers,
+	// e.g. 0.25 for +25%
+	Delta    float64
+	HasDelta bool
+}
+
+// DiffJSON compares two JSON documents leaf by leaf, e.g. size reports or
+// benchmark results. Changes are sorted by path; arrays are compared by
+// index.
+func DiffJSON(old, new []byte) ([]ValueChange, error) {
+	before, err := flattenJSON(old)
+	if err != nil {
+		return nil, fmt.Errorf("old artifact: %w", err)
+	}
+	after, err := flattenJSON(new)
+	if err != nil {
+		return nil, fmt.Errorf("new artifact: %w", err)
+	}
+
+	var changes []ValueChange
+	for path, o := range before {
+		n, ok := after[path]
+		if ok && o == n {
+			continue
+		}
+		c := ValueChange{Path: path, Old: o, New: n}
+		a, errA := strconv.ParseFloat(o, 64)
+		b, errB := strconv.ParseFloat(n, 64)
+		if ok && errA == nil && errB == nil && a != 0 {
+			c.Delta, c.HasDelta = (b-a)/a, true
+		}
+		changes = append(changes, c)
+	}
+	for path, n := range after {
+		if _, ok := before[path]; !ok {
+			changes = append(changes, ValueChange{Path: path, New: n})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// flattenJSON maps the path of every leaf of a JSON document to its value
+func flattenJSON(data []byte) (map[string]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	leaves := make(map[string]string)
+	var walk func(path string, v interface{})
+	walk = func(path string, v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, child := range v {
+				key := strings.ReplaceAll(k, ".", `\.`)
+				if path != "" {
+					key = path + "." + key
+				}
+				walk(key, child)
+			}
+			if len(v) == 0 && path != "" {
+				leaves[path] = "{}"
+			}
+		case []interface{}:
+			for i, child := range v {
+				walk(fmt.Sprintf("%s[%d]", path, i), child)
+			}
+			if len(v) == 0 && path != "" {
+				leaves[path] = "[]"
+			}
+		default:
+			data, _ := json.Marshal(v)
+			leaves[path] = string(data)
+		}
+	}
+	walk("", v)
+	return leaves, nil
+}
+
+// DiffLines returns a unified diff of two texts with context lines around
+// each change ("@@ -1,3 +1,4 @@" hunk headers, then lines prefixed with
+// " ", "-" or "+"), or nil when they are equal
+func DiffLines(old, new string, context int) ([]string, error) {
+	a, b := splitLines(old), splitLines(new)
+	if len(a) > MaxDiffLines || len(b) > MaxDiffLines {
+		return nil, fmt.Errorf("too long to diff (over %d lines)", MaxDiffLines)
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type op struct {
+		kind byte // ' ', '-' or '+'
+		text string
+		i, j int // line indexes in a and b before this op
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, op{' ', a[i], i, j})
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{'-', a[i], i, j})
+			i++
+		default:
+			ops = append(ops, op{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	var out []string
+	for k := 0; k < len(ops); {
+		if ops[k].kind == ' ' {
+			k++
+			continue
+		}
+		// A hunk spans changes closer than 2*context lines apart
+		start := max(k-context, 0)
+		end := k
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*context {
+				end = min(end+context, len(ops))
+				break
+			}
+			end = next
+		}
+
+		oldLines, newLines := 0, 0
+		var lines []string
+		for _, o := range ops[start:end] {
+			if o.kind != '+' {
+				oldLines++
+			}
+			if o.kind != '-' {
+				newLines++
+			}
+			lines = append(lines, string(o.kind)+o.text)
+		}
+		out = append(out, fmt.Sprintf("@@ -%s +%s @@", hunkRange(ops[start].i, oldLines), hunkRange(ops[start].j, newLines)))
+		out = append(out, lines...)
+		k = end
+	}
+	return out, nil
+}
+
+// hunkRange formats the start and length of one side of a hunk
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if n == 1 {
+		return strconv.Itoa(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,72 @@
+package lib_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestDiffJSON(t *testing.T) {
+	old := `{"total": 6000, "bundles": {"app.js": 1000, "vendor.js": 5000, "gone.js": 1}, "tags": ["a", "b"], "ok": true}`
+	new := `{"total": 6260, "bundles": {"app.js": 1250, "vendor.js": 5000, "new.js": 10}, "tags": ["a", "c"], "ok": false}`
+	changes, err := lib.DiffJSON([]byte(old), []byte(new))
+	if err != nil {
+		t.Fatalf("DiffJSON: %v", err)
+	}
+	var got []string
+	for _, c := range changes {
+		line := fmt.Sprintf("%s: %q → %q", c.Path, c.Old, c.New)
+		if c.HasDelta {
+			line += fmt.Sprintf(" %+.3f", c.Delta)
+		}
+		got = append(got, line)
+	}
+	want := []string{
+		`bundles.app\.js: "1000" → "1250" +0.250`,
+		`bundles.gone\.js: "1" → ""`,
+		`bundles.new\.js: "" → "10"`,
+		`ok: "true" → "false"`,
+		`tags[1]: "\"b\"" → "\"c\""`,
+		`total: "6000" → "6260" +0.043`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffJSON =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := lib.DiffJSON([]byte("{"), []byte("{}")); err == nil || !strings.Contains(err.Error(), "old artifact") {
+		t.Errorf("DiffJSON of invalid JSON: error = %v", err)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		context  int
+		want     []string
+	}{
+		{name: "equal", old: "a\nb\n", new: "a\nb\n"},
+		{name: "changed line", old: "a\nb\nc\n", new: "a\nB\nc\n", context: 1, want: []string{"@@ -1,3 +1,3 @@", " a", "-b", "+B", " c"}},
+		{name: "added to empty", old: "", new: "x\n", want: []string{"@@ -0,0 +1 @@", "+x"}},
+		{
+			name: "separate hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n",
+			new:  "0\n1\n2\n3\n4\n5\n6\n7\n",
+			want: []string{"@@ -0,0 +1 @@", "+0", "@@ -8 +8,0 @@", "-8"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lib.DiffLines(tt.old, tt.new, tt.context)
+			if err != nil {
+				t.Fatalf("DiffLines: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffLines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
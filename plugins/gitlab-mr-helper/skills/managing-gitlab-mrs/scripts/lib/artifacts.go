@@ -0,0 +1,102 @@
+package lib
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// JobArtifact is one file kept for a job: its archive, metadata, log or a
+// report
+type JobArtifact struct {
+	FileType   string `json:"file_type"` // archive, metadata, trace, junit, ...
+	Filename   string `json:"filename"`
+	FileFormat string `json:"file_format"`
+	Size       int64  `json:"size"`
+}
+
+// ArtifactsSize is the size of the artifacts DeleteJobArtifacts would
+// remove; the job log is not one of them
+func (j *Job) ArtifactsSize() int64 {
+	var n int64
+	for _, a := range j.Artifacts {
+		if a.FileType != "trace" {
+			n += a.Size
+		}
+	}
+	return n
+}
+
+// JobListOptions holds filters for project job listings
+type JobListOptions struct {
+	Scope []string // statuses, e.g. success and failed; empty for all
+	Limit int      // 0 means no limit
+}
+
+// ListProjectJobs lists the jobs of all pipelines of a project, newest
+// first
+func (c *Client) ListProjectJobs(projectPath string, opts *JobListOptions) ([]Job, error) {
+	endpoint := c.apiURL("/projects/%s/jobs", url.PathEscape(projectPath))
+	query := url.Values{}
+	for _, s := range opts.Scope {
+		query.Add("scope[]", s)
+	}
+	return getAll[Job](c, endpoint, keysetQuery(query), opts.Limit)
+}
+
+// DeleteJobArtifacts deletes the artifacts of a job, keeping its log. It
+// needs the Maintainer role.
+func (c *Client) DeleteJobArtifacts(projectPath string, jobID int) error {
+	endpoint := c.apiURL("/projects/%s/jobs/%d/artifacts", url.PathEscape(projectPath), jobID)
+	return c.do("DELETE", endpoint, nil, nil, http.StatusNoContent)
+}
+
+// ArtifactExpiryOptions selects the job artifacts SelectExpiredArtifacts
+// deletes
+type ArtifactExpiryOptions struct {
+	// CreatedBefore excludes jobs created at or after it, unless zero
+	CreatedBefore time.Time
+	// MinSize excludes jobs whose artifacts are smaller
+	MinSize int64
+	// KeepRefs excludes jobs of the refs it matches, unless nil
+	KeepRefs *regexp.Regexp
+	// KeepLatest keeps the artifacts of the newest pipeline of each ref
+	// that has any, like GitLab's "keep artifacts from most recent
+	// successful jobs" setting
+	KeepLatest bool
+}
+
+// SelectExpiredArtifacts returns the jobs of a project listing whose
+// artifacts opts allows to delete, in listing order. Jobs whose artifacts
+// already expired or that only have a log are skipped. The newest pipeline
+// of each ref is found among all jobs, so the listing should not be
+// filtered by age beforehand.
+func SelectExpiredArtifacts(jobs []Job, opts *ArtifactExpiryOptions, now time.Time) []Job {
+	hasArtifacts := func(j *Job) bool {
+		return j.ArtifactsSize() > 0 && (j.ArtifactsExpireAt == nil || j.ArtifactsExpireAt.After(now))
+	}
+	latest := make(map[string]int)
+	for i := range jobs {
+		if j := &jobs[i]; j.Pipeline != nil && hasArtifacts(j) && j.Pipeline.ID > latest[j.Ref] {
+			latest[j.Ref] = j.Pipeline.ID
+		}
+	}
+
+	var out []Job
+	for i := range jobs {
+		j := &jobs[i]
+		switch {
+		case !hasArtifacts(j), j.ArtifactsSize() < opts.MinSize:
+			continue
+		case !opts.CreatedBefore.IsZero() && !j.CreatedAt.Before(opts.CreatedBefore):
+			continue
+		case opts.KeepRefs != nil && opts.KeepRefs.MatchString(j.Ref):
+			continue
+		case opts.KeepLatest && j.Pipeline != nil && j.Pipeline.ID == latest[j.Ref]:
+			continue
+		}
+		out = append(out, *j)
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+package lib_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestListProjectJobs(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	jobs, err := client.ListProjectJobs(gitlabtest.ProjectPath, &lib.JobListOptions{})
+	if err != nil {
+		t.Fatalf("ListProjectJobs: %v", err)
+	}
+	if len(jobs) != 5 || jobs[0].ID != 3003 || jobs[0].Ref != "feature/login" || jobs[0].Pipeline == nil || jobs[0].Pipeline.ID != 902 {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+	if size := jobs[0].ArtifactsSize(); size != 12<<20+1<<10 {
+		t.Errorf("ArtifactsSize() = %d, want archive and metadata without the log", size)
+	}
+	if jobs, err = client.ListProjectJobs(gitlabtest.ProjectPath, &lib.JobListOptions{Scope: []string{"failed"}}); err != nil || len(jobs) != 1 || jobs[0].ID != 3001 {
+		t.Errorf("failed jobs = %+v, %v", jobs, err)
+	}
+
+	if err := client.DeleteJobArtifacts(gitlabtest.ProjectPath, 2990); err != nil {
+		t.Fatalf("DeleteJobArtifacts: %v", err)
+	}
+	jobs, _ = client.ListProjectJobs(gitlabtest.ProjectPath, &lib.JobListOptions{})
+	if last := jobs[len(jobs)-1]; last.ID != 2990 || last.ArtifactsSize() != 0 || len(last.Artifacts) != 1 {
+		t.Errorf("after delete, job = %+v", last)
+	}
+	wantExit(t, client.DeleteJobArtifacts(gitlabtest.ProjectPath, 1), lib.ExitNotFound)
+}
+
+func TestListProjectJobsKeyset(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	p := srv.Project(gitlabtest.ProjectPath)
+	for i := 0; i < 250; i++ {
+		p.Jobs[900] = append(p.Jobs[900], lib.Job{ID: 10000 + i, Name: "test", Status: "success"})
+	}
+
+	jobs, err := srv.Client().ListProjectJobs(gitlabtest.ProjectPath, &lib.JobListOptions{})
+	if err != nil {
+		t.Fatalf("ListProjectJobs: %v", err)
+	}
+	if len(jobs) != 255 || jobs[0].ID != 10249 || jobs[254].ID != 2990 {
+		t.Fatalf("got %d jobs, %d to %d", len(jobs), jobs[0].ID, jobs[len(jobs)-1].ID)
+	}
+
+	// Three keyset pages, following the cursor links rather than offsets
+	var cursors []string
+	for _, r := range srv.Requests() {
+		if q := r.URL.Query(); strings.HasSuffix(r.URL.Path, "/jobs") && !q.Has("page") {
+			cursors = append(cursors, q.Get("cursor"))
+		}
+	}
+	if strings.Join(cursors, ",") != ",10150,10050" {
+		t.Errorf("job page cursors = %q", cursors)
+	}
+}
+
+func TestSelectExpiredArtifacts(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	jobs, err := srv.Client().ListProjectJobs(gitlabtest.ProjectPath, &lib.JobListOptions{})
+	if err != nil {
+		t.Fatalf("ListProjectJobs: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts lib.ArtifactExpiryOptions
+		want []int
+	}{
+		// 3000 already expired and 3001's log is kept anyway
+		{"everything", lib.ArtifactExpiryOptions{}, []int{3003, 3002, 3001, 2990}},
+		{"keep latest per ref", lib.ArtifactExpiryOptions{KeepLatest: true}, []int{2990}},
+		{"older than 30 days", lib.ArtifactExpiryOptions{CreatedBefore: gitlabtest.FixtureTime.AddDate(0, 0, -30)}, []int{2990}},
+		{"at least 10MB", lib.ArtifactExpiryOptions{MinSize: 10 << 20}, []int{3003, 3002, 2990}},
+		{"keep refs", lib.ArtifactExpiryOptions{KeepRefs: regexp.MustCompile(`^main$`)}, []int{3003}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, j := range lib.SelectExpiredArtifacts(jobs, &tt.opts, gitlabtest.FixtureTime) {
+				got = append(got, j.ID)
+			}
+			if !equalInts(got, tt.want) {
+				t.Errorf("selected %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Artifacts expire on their own once their time has come
+	if got := lib.SelectExpiredArtifacts(jobs, &lib.ArtifactExpiryOptions{}, gitlabtest.FixtureTime.Add(-2*time.Hour)); len(got) != 5 {
+		t.Errorf("before 3000 expired, selected %d job(s), want 5", len(got))
+	}
+}
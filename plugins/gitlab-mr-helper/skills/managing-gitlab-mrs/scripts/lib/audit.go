@@ -0,0 +1,173 @@
+package lib
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuditEvent is an entry of the audit log of a project, group or instance
+type AuditEvent struct {
+	ID         int               `json:"id"`
+	AuthorID   int               `json:"author_id"`
+	EntityID   int               `json:"entity_id"`
+	EntityType string            `json:"entity_type"` // Project, Group or User
+	EventName  string            `json:"event_name"`  // e.g. protected_branch_updated; empty on older instances
+	Details    AuditEventDetails `json:"details"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// AuditEventDetails describes what an audit event changed. Which fields
+// are set depends on the kind of event.
+type AuditEventDetails struct {
+	AuthorName    string `json:"author_name"`
+	AuthorEmail   string `json:"author_email"`
+	CustomMessage string `json:"custom_message"`
+	Change        string `json:"change"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+	Add           string `json:"add"`
+	Remove        string `json:"remove"`
+	As            string `json:"as"`
+	TargetType    string `json:"target_type"`
+	TargetDetails string `json:"target_details"`
+	EntityPath    string `json:"entity_path"`
+	IPAddress     string `json:"ip_address"`
+}
+
+// Summary describes an audit event in one line, e.g. "Changed visibility
+// from private to internal" or "Added user_access bob as Developer"
+func (e AuditEvent) Summary() string {
+	d := e.Details
+	var s string
+	switch {
+	case d.CustomMessage != "":
+		s = d.CustomMessage
+	case d.Change != "":
+		s = fmt.Sprintf("Changed %s from %s to %s", d.Change, orNone(d.From), orNone(d.To))
+	case d.Add != "":
+		s = "Added " + d.Add
+	case d.Remove != "":
+		s = "Removed " + d.Remove
+	default:
+		s = strings.ReplaceAll(e.EventName, "_", " ")
+	}
+	if (d.Add != "" || d.Remove != "") && d.CustomMessage == "" {
+		if d.TargetDetails != "" {
+			s += " " + d.TargetDetails
+		}
+		if d.As != "" {
+			s += " as " + d.As
+		}
+	}
+	return s
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// AuditEventListOptions filters audit events by creation time; GitLab
+// returns the last 30 days when neither bound is set
+type AuditEventListOptions struct {
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Limit         int // 0 means no limit
+}
+
+func (o *AuditEventListOptions) query() url.Values {
+	q := url.Values{}
+	if !o.CreatedAfter.IsZero() {
+		q.Set("created_after", o.CreatedAfter.UTC().Format(time.RFC3339))
+	}
+	if !o.CreatedBefore.IsZero() {
+		q.Set("created_before", o.CreatedBefore.UTC().Format(time.RFC3339))
+	}
+	return q
+}
+
+// ListProjectAuditEvents lists the audit events of a project, newest first.
+// It needs the Maintainer role.
+func (c *Client) ListProjectAuditEvents(projectPath string, opts *AuditEventListOptions) ([]AuditEvent, error) {
+	endpoint := c.apiURL("/projects/%s/audit_events", url.PathEscape(projectPath))
+	return getAll[AuditEvent](c, endpoint, opts.query(), opts.Limit)
+}
+
+// ListGroupAuditEvents lists the audit events of a group, without those of
+// its projects. It needs the Owner role.
+func (c *Client) ListGroupAuditEvents(group string, opts *AuditEventListOptions) ([]AuditEvent, error) {
+	endpoint := c.apiURL("/groups/%s/audit_events", url.PathEscape(group))
+	return getAll[AuditEvent](c, endpoint, opts.query(), opts.Limit)
+}
+
+// ListInstanceAuditEvents lists the audit events of the whole instance. It
+// needs an administrator token.
+func (c *Client) ListInstanceAuditEvents(opts *AuditEventListOptions) ([]AuditEvent, error) {
+	endpoint := c.apiURL("/audit_events")
+	return getAll[AuditEvent](c, endpoint, opts.query(), opts.Limit)
+}
+
+// FilterAuditEvents keeps the events by author (a name or email, case
+// insensitive) whose summary, event name or target matches pattern (a
+// case-insensitive regular expression). Empty filters match everything.
+func FilterAuditEvents(events []AuditEvent, author, pattern string) ([]AuditEvent, error) {
+	var re *regexp.Regexp
+	if pattern != "" {
+		var err error
+		if re, err = regexp.Compile("(?i)" + pattern); err != nil {
+			return nil, UsageErrorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+	author = strings.TrimPrefix(author, "@")
+	var out []AuditEvent
+	for _, e := range events {
+		d := e.Details
+		if author != "" && !strings.EqualFold(d.AuthorName, author) && !strings.EqualFold(d.AuthorEmail, author) {
+			continue
+		}
+		if re != nil && !re.MatchString(strings.Join([]string{e.Summary(), e.EventName, d.TargetType, d.TargetDetails}, "\n")) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// ParseTimeBound parses a --since/--until value: a date (YYYY-MM-DD), an
+// RFC 3339 time, or a duration before now in days, hours or weeks ("7d",
+// "12h", "2w")
+func ParseTimeBound(s string, now time.Time) (time.Time, error) {
+	if n := len(s); n > 1 {
+		unit := map[byte]time.Duration{'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}[s[n-1]]
+		if v, err := strconv.Atoi(s[:n-1]); err == nil && unit != 0 && v >= 0 {
+			return now.Add(-time.Duration(v) * unit), nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, UsageErrorf("invalid time %q (expected YYYY-MM-DD, an RFC 3339 time or e.g. 7d, 12h, 2w)", s)
+}
+
+// AuditTableHeader is the column order of audit event tables
+var AuditTableHeader = []string{"id", "created_at", "author", "entity_type", "entity_path", "event_name", "summary", "target_type", "target", "ip_address"}
+
+// AuditTableRows returns one AuditTableHeader row per event
+func AuditTableRows(events []AuditEvent) [][]string {
+	rows := make([][]string, len(events))
+	for i, e := range events {
+		d := e.Details
+		rows[i] = []string{strconv.Itoa(e.ID), e.CreatedAt.UTC().Format(time.RFC3339), d.AuthorName, e.EntityType, d.EntityPath,
+			e.EventName, e.Summary(), d.TargetType, d.TargetDetails, d.IPAddress}
+	}
+	return rows
+}
@@ -0,0 +1,122 @@
+package lib_test
+
+import (
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestListAuditEvents(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	opts := &lib.AuditEventListOptions{CreatedAfter: gitlabtest.FixtureTime.AddDate(0, 0, -7)}
+	events, err := client.ListProjectAuditEvents(gitlabtest.ProjectPath, opts)
+	if err != nil {
+		t.Fatalf("ListProjectAuditEvents: %v", err)
+	}
+	if len(events) != 2 || events[0].EventName != "protected_branch_updated" || events[0].Details.AuthorName != gitlabtest.Alice.Name {
+		t.Fatalf("project events = %+v", events)
+	}
+
+	opts.CreatedBefore = gitlabtest.FixtureTime.AddDate(0, 0, -3)
+	if events, err = client.ListProjectAuditEvents(gitlabtest.ProjectPath, opts); err != nil || len(events) != 1 || events[0].ID != 2002 {
+		t.Errorf("events before 3 days ago = %+v, %v", events, err)
+	}
+
+	if events, err = client.ListGroupAuditEvents("group", &lib.AuditEventListOptions{}); err != nil || len(events) != 1 || events[0].EntityType != "Group" {
+		t.Errorf("group events = %+v, %v", events, err)
+	}
+	_, err = client.ListGroupAuditEvents("missing", &lib.AuditEventListOptions{})
+	wantExit(t, err, lib.ExitNotFound)
+
+	if events, err = client.ListInstanceAuditEvents(&lib.AuditEventListOptions{Limit: 2}); err != nil || len(events) != 2 || events[0].ID != 2004 {
+		t.Errorf("instance events = %+v, %v", events, err)
+	}
+	srv.SetAdmin(false)
+	_, err = client.ListInstanceAuditEvents(&lib.AuditEventListOptions{})
+	if err == nil {
+		t.Error("ListInstanceAuditEvents succeeded without admin")
+	}
+}
+
+func TestAuditEventSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   lib.AuditEvent
+		summary string
+	}{
+		{"custom message", lib.AuditEvent{Details: lib.AuditEventDetails{CustomMessage: "Unprotected branch", Add: "x"}}, "Unprotected branch"},
+		{"change", lib.AuditEvent{Details: lib.AuditEventDetails{Change: "visibility", From: "Private", To: "Public"}}, "Changed visibility from Private to Public"},
+		{"change from nothing", lib.AuditEvent{Details: lib.AuditEventDetails{Change: "description", To: "New"}}, "Changed description from (none) to New"},
+		{"add", lib.AuditEvent{Details: lib.AuditEventDetails{Add: "user_access", TargetDetails: "Carol", As: "Developer"}}, "Added user_access Carol as Developer"},
+		{"remove", lib.AuditEvent{Details: lib.AuditEventDetails{Remove: "deploy_key", TargetDetails: "ci"}}, "Removed deploy_key ci"},
+		{"event name only", lib.AuditEvent{EventName: "project_archived"}, "project archived"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.event.Summary(); got != tt.summary {
+				t.Errorf("Summary() = %q, want %q", got, tt.summary)
+			}
+		})
+	}
+}
+
+func TestFilterAuditEvents(t *testing.T) {
+	events := []lib.AuditEvent{
+		{ID: 1, EventName: "protected_branch_updated", Details: lib.AuditEventDetails{AuthorName: "Alice Admin", AuthorEmail: "alice@example.com", TargetType: "ProtectedBranch", TargetDetails: "main"}},
+		{ID: 2, Details: lib.AuditEventDetails{AuthorName: "Bob Builder", AuthorEmail: "bob@example.com", Add: "user_access", TargetDetails: "Carol"}},
+	}
+	tests := []struct {
+		author, pattern string
+		want            []int
+	}{
+		{"", "", []int{1, 2}},
+		{"alice admin", "", []int{1}},
+		{"BOB@example.com", "", []int{2}},
+		{"", "protected.?branch", []int{1}},
+		{"", "carol", []int{2}},
+		{"Alice Admin", "carol", nil},
+	}
+	for _, tt := range tests {
+		got, err := lib.FilterAuditEvents(events, tt.author, tt.pattern)
+		if err != nil {
+			t.Fatalf("FilterAuditEvents(%q, %q): %v", tt.author, tt.pattern, err)
+		}
+		var ids []int
+		for _, e := range got {
+			ids = append(ids, e.ID)
+		}
+		if len(ids) != len(tt.want) || len(ids) > 0 && ids[0] != tt.want[0] {
+			t.Errorf("FilterAuditEvents(%q, %q) = %v, want %v", tt.author, tt.pattern, ids, tt.want)
+		}
+	}
+	_, err := lib.FilterAuditEvents(events, "", "(")
+	wantExit(t, err, lib.ExitUsage)
+}
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2024, 3, 8, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+		ok   bool
+	}{
+		{"7d", now.AddDate(0, 0, -7), true},
+		{"12h", now.Add(-12 * time.Hour), true},
+		{"2w", now.AddDate(0, 0, -14), true},
+		{"2024-03-01T10:00:00Z", time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), true},
+		{"2024-03-01", time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), true},
+		{"d", time.Time{}, false},
+		{"7m", time.Time{}, false},
+		{"last week", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, err := lib.ParseTimeBound(tt.in, now)
+		if (err == nil) != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("ParseTimeBound(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package lib
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// LabelRulesFileName is the repository's path-to-label mapping, looked up
+// at the root of the current git work tree
+const LabelRulesFileName = ".gitlab-helper-labels.yml"
+
+// LabelRules map changed paths to MR labels, e.g.
+//
+//	labels:
+//	  - label: documentation
+//	    files: ["docs/**", "*.md"]
+//	  - label: database
+//	    files: ["*.sql", "db/migrate/**"]
+//	    exclude: ["db/migrate/README.md"]
+type LabelRules struct {
+	Labels []LabelRule `json:"labels"`
+}
+
+// LabelRule suggests Label when a changed file matches one of Files and
+// none of Exclude. Globs follow MatchGlob.
+type LabelRule struct {
+	Label   string   `json:"label"`
+	Files   []string `json:"files"`
+	Exclude []string `json:"exclude"`
+}
+
+// LabelMatch is a label suggested for an MR, with the files that matched
+type LabelMatch struct {
+	Label string
+	Files []string
+}
+
+// LoadLabelRules reads a label rules file. An empty path looks for
+// LabelRulesFileName at the repository root; it returns nil rules when
+// there is none.
+func LoadLabelRules(file string) (*LabelRules, error) {
+	data, file, err := readRepoFile(file, LabelRulesFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read label rules: %w", err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	rules := &LabelRules{}
+	if err := DecodeYAML(data, rules); err != nil {
+		return nil, fmt.Errorf("invalid label rules %s: %w", file, err)
+	}
+	if err := rules.validate(); err != nil {
+		return nil, fmt.Errorf("invalid label rules %s: %w", file, err)
+	}
+	return rules, nil
+}
+
+func (lr *LabelRules) validate() error {
+	for i, r := range lr.Labels {
+		if strings.TrimSpace(r.Label) == "" {
+			return fmt.Errorf("rule %d: label is required", i+1)
+		}
+		if strings.Contains(r.Label, ",") {
+			return fmt.Errorf("%s: label names cannot contain commas", r.Label)
+		}
+		if len(r.Files) == 0 {
+			return fmt.Errorf("%s: needs files", r.Label)
+		}
+		for _, pattern := range append(append([]string{}, r.Files...), r.Exclude...) {
+			if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+				return fmt.Errorf("%s: invalid pattern %q", r.Label, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// Managed returns the labels the rules can suggest, in rule order
+func (lr *LabelRules) Managed() []string {
+	var labels []string
+	for _, r := range lr.Labels {
+		if !ContainsString(labels, r.Label) {
+			labels = append(labels, r.Label)
+		}
+	}
+	return labels
+}
+
+// Suggest returns the labels whose rules match the changed files, in rule
+// order. Several rules for one label are merged.
+func (lr *LabelRules) Suggest(diffs []Diff) []LabelMatch {
+	var matches []LabelMatch
+	index := map[string]int{} // label → position in matches
+	for _, r := range lr.Labels {
+		var files []string
+		for _, d := range diffs {
+			paths := diffPaths(d)
+			if matchAnyGlob(r.Files, paths...) && !matchAnyGlob(r.Exclude, paths...) {
+				files = append(files, d.NewPath)
+			}
+		}
+		if len(files) == 0 {
+			continue
+		}
+		i, ok := index[r.Label]
+		if !ok {
+			i = len(matches)
+			index[r.Label] = i
+			matches = append(matches, LabelMatch{Label: r.Label})
+		}
+		for _, f := range files {
+			if !ContainsString(matches[i].Files, f) {
+				matches[i].Files = append(matches[i].Files, f)
+			}
+		}
+	}
+	return matches
+}
+
+// LabelChanges returns the suggested labels an MR lacks and, with prune,
+// the managed labels it has that no longer match
+func (lr *LabelRules) LabelChanges(current []string, matches []LabelMatch, prune bool) (add, remove []string) {
+	suggested := make([]string, len(matches))
+	for i, m := range matches {
+		suggested[i] = m.Label
+		if !ContainsString(current, m.Label) {
+			add = append(add, m.Label)
+		}
+	}
+	if prune {
+		for _, l := range lr.Managed() {
+			if ContainsString(current, l) && !ContainsString(suggested, l) {
+				remove = append(remove, l)
+			}
+		}
+	}
+	return add, remove
+}
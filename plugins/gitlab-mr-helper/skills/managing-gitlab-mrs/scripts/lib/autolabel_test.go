@@ -0,0 +1,68 @@
+package lib_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+const testLabelRules = `labels:
+  - label: documentation
+    files: ["docs/**", "*.md"]
+    exclude: [CHANGELOG.md]
+  - label: database
+    files: ["*.sql"]
+  - label: database
+    files: ["db/migrate/**"]
+  - label: frontend
+    files: ["web/**"]
+`
+
+func TestLabelRulesSuggest(t *testing.T) {
+	rules, err := lib.LoadLabelRules(writeRules(t, testLabelRules))
+	if err != nil {
+		t.Fatalf("LoadLabelRules: %v", err)
+	}
+
+	diffs := []lib.Diff{
+		{NewPath: "docs/guide/setup.md"},
+		{NewPath: "CHANGELOG.md"},
+		{NewPath: "db/migrate/001_users.rb"},
+		{NewPath: "schema/users.sql"},
+		{OldPath: "README.md", NewPath: "docs/README.md"},
+		{NewPath: "cmd/main.go"},
+	}
+	got := rules.Suggest(diffs)
+	want := []lib.LabelMatch{
+		{Label: "documentation", Files: []string{"docs/guide/setup.md", "docs/README.md"}},
+		{Label: "database", Files: []string{"schema/users.sql", "db/migrate/001_users.rb"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Suggest = %+v, want %+v", got, want)
+	}
+
+	add, remove := rules.LabelChanges([]string{"database", "frontend", "bug"}, got, false)
+	if !reflect.DeepEqual(add, []string{"documentation"}) || remove != nil {
+		t.Errorf("LabelChanges = %v, %v; want [documentation], []", add, remove)
+	}
+	_, remove = rules.LabelChanges([]string{"database", "frontend", "bug"}, got, true)
+	if !reflect.DeepEqual(remove, []string{"frontend"}) {
+		t.Errorf("LabelChanges with prune removes %v, want [frontend]", remove)
+	}
+}
+
+func TestLoadLabelRulesInvalid(t *testing.T) {
+	for content, want := range map[string]string{
+		"labels:\n  - files: [docs/**]\n":                "label is required",
+		"labels:\n  - label: docs\n":                     "needs files",
+		"labels:\n  - label: a,b\n    files: [x]\n":      "commas",
+		"labels:\n  - label: docs\n    files: [\"[\"]\n": "invalid pattern",
+	} {
+		_, err := lib.LoadLabelRules(writeRules(t, content))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadLabelRules(%q) = %v, want an error containing %q", content, err, want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BlameCommit is the commit that last changed a range of lines
+type BlameCommit struct {
+	ID           string    `json:"id"`
+	Message      string    `json:"message"`
+	AuthorName   string    `json:"author_name"`
+	AuthorEmail  string    `json:"author_email"`
+	AuthoredDate time.Time `json:"authored_date"`
+}
+
+// Title is the first line of the commit message
+func (c *BlameCommit) Title() string {
+	title, _, _ := strings.Cut(strings.TrimSpace(c.Message), "\n")
+	return title
+}
+
+// BlameRange is a run of consecutive lines last changed by one commit
+type BlameRange struct {
+	Commit BlameCommit `json:"commit"`
+	Lines  []string    `json:"lines"`
+}
+
+// BlameLine is one line of a blamed file
+type BlameLine struct {
+	Number int
+	Text   string
+	Commit *BlameCommit
+}
+
+// Blame returns who last changed the lines of a file at ref, from line
+// start to line end (1-based, inclusive); start 0 means the whole file
+func (c *Client) Blame(projectPath, filePath, ref string, start, end int) ([]BlameRange, error) {
+	endpoint := c.apiURL("/projects/%s/repository/files/%s/blame", url.PathEscape(projectPath), url.PathEscape(filePath))
+	q := url.Values{"ref": {ref}}
+	if start > 0 {
+		q.Set("range[start]", strconv.Itoa(start))
+		q.Set("range[end]", strconv.Itoa(end))
+	}
+
+	var ranges []BlameRange
+	if err := c.do("GET", endpoint+"?"+q.Encode(), nil, &ranges, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return ranges, nil
+}
+
+// BlameLines numbers the lines of blame ranges, the first one being line
+// first
+func BlameLines(ranges []BlameRange, first int) []BlameLine {
+	var lines []BlameLine
+	n := first
+	for i := range ranges {
+		for _, text := range ranges[i].Lines {
+			lines = append(lines, BlameLine{Number: n, Text: text, Commit: &ranges[i].Commit})
+			n++
+		}
+	}
+	return lines
+}
+
+// FindCommitMRs finds the MR that brought in each commit, in parallel. A
+// commit in several MRs maps to the merged one; direct pushes are left out.
+func (c *Client) FindCommitMRs(projectPath string, shas []string) (map[string]*MergeRequest, error) {
+	found := make([]*MergeRequest, len(shas))
+	errs := c.ForEach(len(shas), func(i int) error {
+		mrs, err := c.ListCommitMRs(projectPath, shas[i])
+		if err != nil {
+			return fmt.Errorf("commit %s: %w", ShortSHA(shas[i]), err)
+		}
+		for j := range mrs {
+			if found[i] == nil || mrs[j].State == "merged" {
+				found[i] = &mrs[j]
+			}
+			if mrs[j].State == "merged" {
+				break
+			}
+		}
+		return nil
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	byCommit := make(map[string]*MergeRequest)
+	for i, mr := range found {
+		if mr != nil {
+			byCommit[shas[i]] = mr
+		}
+	}
+	return byCommit, nil
+}
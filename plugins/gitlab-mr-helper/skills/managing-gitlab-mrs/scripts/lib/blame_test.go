@@ -0,0 +1,49 @@
+package lib_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestBlame(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	ranges, err := client.Blame(gitlabtest.ProjectPath, "cmd/main.go", "main", 0, 0)
+	if err != nil {
+		t.Fatalf("Blame: %v", err)
+	}
+	lines := lib.BlameLines(ranges, 1)
+	if len(lines) != 6 || lines[3].Number != 4 || lines[3].Commit.ID != "c4r01c" || lines[3].Commit.Title() != "Handle signals" {
+		t.Errorf("Blame lines = %+v", lines)
+	}
+
+	ranges, err = client.Blame(gitlabtest.ProjectPath, "cmd/main.go", "main", 4, 5)
+	if err != nil {
+		t.Fatalf("Blame 4-5: %v", err)
+	}
+	lines = lib.BlameLines(ranges, 4)
+	if len(lines) != 2 || lines[0].Commit.ID != "c4r01c" || lines[1].Number != 5 || lines[1].Text != "\trun()" || lines[1].Commit.ID != "a1a1a1" {
+		t.Errorf("Blame 4-5 lines = %+v", lines)
+	}
+
+	var apiErr *lib.APIError
+	if _, err := client.Blame(gitlabtest.ProjectPath, "missing.go", "main", 0, 0); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Blame of a missing file: err = %v, want a 404", err)
+	}
+}
+
+func TestFindCommitMRs(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	byCommit, err := srv.Client().FindCommitMRs(gitlabtest.ProjectPath, []string{"a1a1a1", "c4r01c"})
+	if err != nil {
+		t.Fatalf("FindCommitMRs: %v", err)
+	}
+	if len(byCommit) != 1 || byCommit["a1a1a1"] == nil || byCommit["a1a1a1"].IID != 3 {
+		t.Errorf("FindCommitMRs = %v, want a1a1a1 in !3 and c4r01c pushed directly", byCommit)
+	}
+}
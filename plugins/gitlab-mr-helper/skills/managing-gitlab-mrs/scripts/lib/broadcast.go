@@ -0,0 +1,116 @@
+package lib
+
+import (
+	"net/http"
+	"time"
+)
+
+// BroadcastMessage is an instance-wide announcement shown to every user
+// between StartsAt and EndsAt
+type BroadcastMessage struct {
+	ID      int    `json:"id"`
+	Message string `json:"message"`
+	// BroadcastType is banner (top of every page) or notification
+	// (bottom-right popup)
+	BroadcastType string    `json:"broadcast_type"`
+	Theme         string    `json:"theme"`
+	Dismissable   bool      `json:"dismissable"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	Active        bool      `json:"active"`
+}
+
+// CreateBroadcastRequest schedules a broadcast message. Zero times let
+// GitLab start it now and end it an hour later.
+type CreateBroadcastRequest struct {
+	Message       string
+	BroadcastType string
+	Theme         string
+	Dismissable   bool
+	StartsAt      time.Time
+	EndsAt        time.Time
+}
+
+// MaintenanceMode is the instance's maintenance mode (GitLab Premium):
+// while enabled, the instance is read-only and shows Message in a banner
+type MaintenanceMode struct {
+	Enabled bool   `json:"maintenance_mode"`
+	Message string `json:"maintenance_mode_message"`
+}
+
+// ListBroadcastMessages lists the broadcast messages, past ones included
+func (c *Client) ListBroadcastMessages() ([]BroadcastMessage, error) {
+	endpoint := c.apiURL("/broadcast_messages")
+	return getAll[BroadcastMessage](c, endpoint, nil, 0)
+}
+
+// CreateBroadcastMessage schedules a broadcast message (administrators only)
+func (c *Client) CreateBroadcastMessage(req *CreateBroadcastRequest) (*BroadcastMessage, error) {
+	endpoint := c.apiURL("/broadcast_messages")
+	body := map[string]interface{}{"message": req.Message, "dismissable": req.Dismissable}
+	if req.BroadcastType != "" {
+		body["broadcast_type"] = req.BroadcastType
+	}
+	if req.Theme != "" {
+		body["theme"] = req.Theme
+	}
+	if !req.StartsAt.IsZero() {
+		body["starts_at"] = req.StartsAt
+	}
+	if !req.EndsAt.IsZero() {
+		body["ends_at"] = req.EndsAt
+	}
+
+	var m BroadcastMessage
+	if err := c.do("POST", endpoint, body, &m, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// DeleteBroadcastMessage removes a broadcast message (administrators only)
+func (c *Client) DeleteBroadcastMessage(id int) error {
+	endpoint := c.apiURL("/broadcast_messages/%d", id)
+	return c.do("DELETE", endpoint, nil, nil, http.StatusOK)
+}
+
+// GetMaintenanceMode reads the maintenance mode from the application
+// settings (administrators only)
+func (c *Client) GetMaintenanceMode() (*MaintenanceMode, error) {
+	endpoint := c.apiURL("/application/settings")
+	var m MaintenanceMode
+	if err := c.do("GET", endpoint, nil, &m, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// SetMaintenanceMode enables or disables maintenance mode (administrators
+// only). An empty message keeps the current one.
+func (c *Client) SetMaintenanceMode(enabled bool, message string) (*MaintenanceMode, error) {
+	endpoint := c.apiURL("/application/settings")
+	body := map[string]interface{}{"maintenance_mode": enabled}
+	if message != "" {
+		body["maintenance_mode_message"] = message
+	}
+	var m MaintenanceMode
+	if err := c.do("PUT", endpoint, body, &m, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// ParseScheduleTime parses a broadcast start or end: "now", an RFC 3339
+// time, or a duration after base ("30m", "2h")
+func ParseScheduleTime(s string, base time.Time) (time.Time, error) {
+	if s == "now" {
+		return base, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return base.Add(d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, UsageErrorf("invalid time %q (expected now, an RFC 3339 time or a duration such as 30m or 2h)", s)
+}
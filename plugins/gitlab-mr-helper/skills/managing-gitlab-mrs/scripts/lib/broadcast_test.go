@@ -0,0 +1,89 @@
+package lib_test
+
+import (
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestBroadcastMessages(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	active, err := client.CreateBroadcastMessage(&lib.CreateBroadcastRequest{Message: "Upgrade in progress", Dismissable: true})
+	if err != nil || !active.Active || active.BroadcastType != "banner" || !active.EndsAt.After(active.StartsAt) {
+		t.Fatalf("CreateBroadcastMessage = %+v, %v", active, err)
+	}
+	scheduled, err := client.CreateBroadcastMessage(&lib.CreateBroadcastRequest{Message: "Maintenance tonight", BroadcastType: "notification",
+		StartsAt: now.Add(2 * time.Hour), EndsAt: now.Add(4 * time.Hour)})
+	if err != nil || scheduled.Active || !scheduled.StartsAt.Equal(now.Add(2*time.Hour)) {
+		t.Fatalf("CreateBroadcastMessage scheduled = %+v, %v", scheduled, err)
+	}
+
+	messages, err := client.ListBroadcastMessages()
+	if err != nil || len(messages) != 2 || !messages[0].Active || messages[1].Active {
+		t.Fatalf("ListBroadcastMessages = %+v, %v", messages, err)
+	}
+
+	if err := client.DeleteBroadcastMessage(active.ID); err != nil {
+		t.Fatalf("DeleteBroadcastMessage: %v", err)
+	}
+	if left := srv.Broadcasts(); len(left) != 1 || left[0].ID != scheduled.ID {
+		t.Errorf("broadcasts after delete = %+v", left)
+	}
+	if err := client.DeleteBroadcastMessage(active.ID); lib.ExitCode(err) != lib.ExitNotFound {
+		t.Errorf("DeleteBroadcastMessage of a deleted message error = %v, want not found", err)
+	}
+
+	srv.SetAdmin(false)
+	if _, err := client.CreateBroadcastMessage(&lib.CreateBroadcastRequest{Message: "hi"}); lib.ExitCode(err) != lib.ExitAuth {
+		t.Errorf("CreateBroadcastMessage without admin error = %v, want auth failure", err)
+	}
+}
+
+func TestMaintenanceMode(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	m, err := client.SetMaintenanceMode(true, "Upgrading to 17.5")
+	if err != nil || !m.Enabled || m.Message != "Upgrading to 17.5" {
+		t.Fatalf("SetMaintenanceMode(true) = %+v, %v", m, err)
+	}
+	// Turning it off keeps the message for next time
+	if _, err := client.SetMaintenanceMode(false, ""); err != nil {
+		t.Fatalf("SetMaintenanceMode(false): %v", err)
+	}
+	if m, err := client.GetMaintenanceMode(); err != nil || m.Enabled || m.Message != "Upgrading to 17.5" {
+		t.Errorf("GetMaintenanceMode = %+v, %v", m, err)
+	}
+}
+
+func TestParseScheduleTime(t *testing.T) {
+	base := time.Date(2024, 3, 1, 22, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "now", want: base},
+		{in: "90m", want: base.Add(90 * time.Minute)},
+		{in: "2024-03-02T06:00:00Z", want: time.Date(2024, 3, 2, 6, 0, 0, 0, time.UTC)},
+		{in: "-1h", wantErr: true},
+		{in: "tonight", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := lib.ParseScheduleTime(tt.in, base)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseScheduleTime(%q) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseScheduleTime(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrNoBrowser means no command to open a browser was found, as on a
+// headless machine or in CI
+var ErrNoBrowser = errors.New("no browser available (set $BROWSER)")
+
+// BrowserCommand returns the command that opens a URL in the browser:
+// $BROWSER, then open on macOS, then xdg-open when a display is
+// available. It returns "" when there is none.
+func BrowserCommand() string {
+	if b := strings.TrimSpace(os.Getenv("BROWSER")); b != "" {
+		return b
+	}
+	if runtime.GOOS == "darwin" {
+		return "open"
+	}
+	if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		return ""
+	}
+	if _, err := exec.LookPath("xdg-open"); err != nil {
+		return ""
+	}
+	return "xdg-open"
+}
+
+// OpenURL opens u in the browser without waiting for it to exit. The
+// browser command may include arguments (e.g. "firefox --new-tab").
+func OpenURL(u string) error {
+	browser := BrowserCommand()
+	if browser == "" {
+		return ErrNoBrowser
+	}
+	cmd := exec.Command("sh", "-c", browser+` "$1"`, "sh", u)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("browser %q failed: %w", browser, err)
+	}
+	return cmd.Process.Release()
+}
+
+// OpenURLOrWarn opens u in the browser, only warning on stderr when it
+// cannot: scripts print the URL anyway
+func OpenURLOrWarn(u string) {
+	if err := OpenURL(u); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not open the browser: %v\n", err)
+	}
+}
@@ -0,0 +1,50 @@
+package lib_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestOpenURL(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "opened")
+	t.Setenv("BROWSER", "printf %s >"+out)
+
+	u := "https://gitlab.example.com/group/project/-/merge_requests/1?a=1&b=2"
+	if err := lib.OpenURL(u); err != nil {
+		t.Fatalf("OpenURL: %v", err)
+	}
+	// The browser runs in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, err := os.ReadFile(out)
+		if err == nil && len(data) > 0 {
+			if got := string(data); got != u {
+				t.Errorf("browser got %q, want %q", got, u)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("browser was not run")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestOpenURLNoBrowser(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("macOS always has open")
+	}
+	t.Setenv("BROWSER", "")
+	t.Setenv("DISPLAY", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+
+	if err := lib.OpenURL("https://gitlab.example.com"); !errors.Is(err, lib.ErrNoBrowser) {
+		t.Errorf("OpenURL = %v, want ErrNoBrowser", err)
+	}
+}
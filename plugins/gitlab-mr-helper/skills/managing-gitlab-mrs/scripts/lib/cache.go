@@ -0,0 +1,250 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrOffline is returned for API calls that cannot be served from the local
+// cache in --offline mode
+var ErrOffline = errors.New("not available offline")
+
+// ProjectCache is the locally synced state of one project
+type ProjectCache struct {
+	Project  string    `json:"project"`
+	URL      string    `json:"url"`
+	SyncedAt time.Time `json:"synced_at"`
+	// State and Limit are the MR filters of the sync, which a delta sync
+	// must share
+	State       string               `json:"state,omitempty"`
+	Limit       int                  `json:"limit,omitempty"`
+	MRs         []MergeRequest       `json:"merge_requests"`
+	Discussions map[int][]Discussion `json:"discussions"`
+	Pipelines   []Pipeline           `json:"pipelines"`
+}
+
+// cacheDir returns the per-instance cache directory:
+// <user cache dir>/gitlab-helper/<host>
+func cacheDir(baseURL string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+	host := baseURL
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		host = strings.ReplaceAll(u.Host, ":", "_")
+	}
+	return filepath.Join(dir, "gitlab-helper", host), nil
+}
+
+// CachePath returns the cache file of a project on a GitLab instance:
+// <user cache dir>/gitlab-helper/<host>/<project path>.json
+func CachePath(baseURL, projectPath string) (string, error) {
+	dir, err := cacheDir(baseURL)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.FromSlash(projectPath)+".json"), nil
+}
+
+// LoadProjectCache reads the cache of a project
+func LoadProjectCache(baseURL, projectPath string) (*ProjectCache, error) {
+	path, err := CachePath(baseURL, projectPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s has not been synced (run sync.go first)", ErrOffline, projectPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache: %w", err)
+	}
+	var pc ProjectCache
+	if err := json.Unmarshal(data, &pc); err != nil {
+		return nil, fmt.Errorf("invalid cache file %s: %w", path, err)
+	}
+	return &pc, nil
+}
+
+// Save writes the cache atomically and returns its path
+func (pc *ProjectCache) Save() (string, error) {
+	path, err := CachePath(pc.URL, pc.Project)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	data, err := json.MarshalIndent(pc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cache: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("failed to write cache: %w", err)
+	}
+	return path, nil
+}
+
+// projectCache returns the cache of a project, loading it on first use
+func (c *Client) projectCache(projectPath string) (*ProjectCache, error) {
+	c.cachesMu.Lock()
+	defer c.cachesMu.Unlock()
+	if pc, ok := c.caches[projectPath]; ok {
+		return pc, nil
+	}
+	pc, err := LoadProjectCache(c.config.URL, projectPath)
+	if err != nil {
+		return nil, err
+	}
+	if c.caches == nil {
+		c.caches = make(map[string]*ProjectCache)
+	}
+	c.caches[projectPath] = pc
+	return pc, nil
+}
+
+func (c *Client) cachedMRs(projectPath, state string, limit int) ([]MergeRequest, error) {
+	pc, err := c.projectCache(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	var mrs []MergeRequest
+	for _, mr := range pc.MRs {
+		if state != "" && state != "all" && mr.State != state {
+			continue
+		}
+		mrs = append(mrs, mr)
+		if limit > 0 && len(mrs) == limit {
+			break
+		}
+	}
+	return mrs, nil
+}
+
+func (c *Client) cachedMR(projectPath string, mrIID int) (*MergeRequest, error) {
+	pc, err := c.projectCache(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pc.MRs {
+		if pc.MRs[i].IID == mrIID {
+			return &pc.MRs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: MR !%d is not in the %s cache (synced %s)", ErrNotFound, mrIID, projectPath, FormatAge(pc.SyncedAt))
+}
+
+func (c *Client) cachedDiscussions(projectPath string, mrIID int) ([]Discussion, error) {
+	pc, err := c.projectCache(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	discussions, ok := pc.Discussions[mrIID]
+	if !ok {
+		return nil, fmt.Errorf("%w: discussions of MR !%d were not synced", ErrOffline, mrIID)
+	}
+	return discussions, nil
+}
+
+func (c *Client) cachedPipelines(projectPath string, opts *PipelineListOptions) ([]Pipeline, error) {
+	pc, err := c.projectCache(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	var pipelines []Pipeline
+	for _, p := range pc.Pipelines {
+		if (opts.Ref != "" && p.Ref != opts.Ref) || (opts.Status != "" && p.Status != opts.Status) || (opts.Source != "" && p.Source != opts.Source) {
+			continue
+		}
+		if !opts.UpdatedAfter.IsZero() && !p.UpdatedAt.After(opts.UpdatedAfter) {
+			continue
+		}
+		pipelines = append(pipelines, p)
+		if opts.Limit > 0 && len(pipelines) == opts.Limit {
+			break
+		}
+	}
+	return pipelines, nil
+}
+
+// MergeMRs updates a cache of the newest limit MRs in state (0 for no
+// limit) with the MRs changed since it was synced, listed in any state:
+// those in state replace or join the cached ones and the others leave it,
+// with their discussions. It returns the IIDs of the cached MRs that
+// changed, whose discussions need refreshing, and whether the cache is
+// complete: when MRs left a full cache, older ones it never held may now
+// belong in it, and only a full sync can tell.
+func (pc *ProjectCache) MergeMRs(changed []MergeRequest, state string, limit int) (updated []int, complete bool) {
+	full := limit > 0 && len(pc.MRs) >= limit
+	byIID := make(map[int]MergeRequest, len(pc.MRs))
+	for _, mr := range pc.MRs {
+		byIID[mr.IID] = mr
+	}
+	left := false
+	isUpdated := make(map[int]bool)
+	for _, mr := range changed {
+		if state == "" || state == "all" || mr.State == state {
+			byIID[mr.IID] = mr
+			isUpdated[mr.IID] = true
+		} else if _, ok := byIID[mr.IID]; ok {
+			delete(byIID, mr.IID)
+			left = true
+		}
+	}
+
+	pc.MRs = pc.MRs[:0]
+	for _, mr := range byIID {
+		pc.MRs = append(pc.MRs, mr)
+	}
+	// Newest first, as GitLab lists them
+	sort.Slice(pc.MRs, func(i, j int) bool { return pc.MRs[i].IID > pc.MRs[j].IID })
+	if limit > 0 && len(pc.MRs) > limit {
+		pc.MRs = pc.MRs[:limit]
+	}
+
+	kept := make(map[int]bool, len(pc.MRs))
+	for _, mr := range pc.MRs {
+		kept[mr.IID] = true
+		if isUpdated[mr.IID] {
+			updated = append(updated, mr.IID)
+		}
+	}
+	for iid := range pc.Discussions {
+		if !kept[iid] {
+			delete(pc.Discussions, iid)
+		}
+	}
+	return updated, !(full && left)
+}
+
+// MergePipelines updates a cache of the newest limit pipelines with the
+// pipelines changed since it was synced
+func (pc *ProjectCache) MergePipelines(changed []Pipeline, limit int) {
+	byID := make(map[int]Pipeline, len(pc.Pipelines)+len(changed))
+	for _, p := range pc.Pipelines {
+		byID[p.ID] = p
+	}
+	for _, p := range changed {
+		byID[p.ID] = p
+	}
+	pc.Pipelines = pc.Pipelines[:0]
+	for _, p := range byID {
+		pc.Pipelines = append(pc.Pipelines, p)
+	}
+	sort.Slice(pc.Pipelines, func(i, j int) bool { return pc.Pipelines[i].ID > pc.Pipelines[j].ID })
+	if limit > 0 && len(pc.Pipelines) > limit {
+		pc.Pipelines = pc.Pipelines[:limit]
+	}
+}
@@ -0,0 +1,129 @@
+package lib_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestOfflineCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	mrs, err := client.ListMRs(gitlabtest.ProjectPath, "all", 0)
+	if err != nil {
+		t.Fatalf("ListMRs: %v", err)
+	}
+	discussions, err := client.ListMRDiscussions(gitlabtest.ProjectPath, 1)
+	if err != nil {
+		t.Fatalf("ListMRDiscussions: %v", err)
+	}
+	pipelines, err := client.ListPipelines(gitlabtest.ProjectPath, &lib.PipelineListOptions{})
+	if err != nil {
+		t.Fatalf("ListPipelines: %v", err)
+	}
+
+	pc := &lib.ProjectCache{
+		Project:     gitlabtest.ProjectPath,
+		URL:         srv.URL,
+		SyncedAt:    time.Now(),
+		MRs:         mrs,
+		Discussions: map[int][]lib.Discussion{1: discussions},
+		Pipelines:   pipelines,
+	}
+	if _, err := pc.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// The offline client never reaches the server
+	srv.Close()
+	offline := lib.NewClient(&lib.Config{URL: srv.URL, Offline: true})
+
+	opened, err := offline.ListMRs(gitlabtest.ProjectPath, "opened", 0)
+	if err != nil {
+		t.Fatalf("offline ListMRs: %v", err)
+	}
+	if got := iids(opened); !equalInts(got, []int{1, 2}) {
+		t.Errorf("offline opened MRs = %v, want [1 2]", got)
+	}
+
+	mr, err := offline.GetMR(gitlabtest.ProjectPath, 2)
+	if err != nil || mr.SourceBranch != "fix/crash" {
+		t.Errorf("offline GetMR = %+v, %v", mr, err)
+	}
+	_, err = offline.GetMR(gitlabtest.ProjectPath, 99)
+	wantExit(t, err, lib.ExitNotFound)
+
+	if d, err := offline.ListMRDiscussions(gitlabtest.ProjectPath, 1); err != nil || len(d) != 3 {
+		t.Errorf("offline ListMRDiscussions = %d threads, %v", len(d), err)
+	}
+	if p, err := offline.ListPipelines(gitlabtest.ProjectPath, &lib.PipelineListOptions{Status: "failed"}); err != nil || len(p) != 1 {
+		t.Errorf("offline ListPipelines = %d pipelines, %v", len(p), err)
+	}
+
+	// Writes and unsynced projects fail with ErrOffline
+	if _, err := offline.UpdateMR(gitlabtest.ProjectPath, 1, &lib.UpdateMRRequest{Title: "x"}); !errors.Is(err, lib.ErrOffline) {
+		t.Errorf("offline UpdateMR err = %v, want ErrOffline", err)
+	}
+	if _, err := offline.ListMRs(gitlabtest.NestedProjectPath, "", 0); !errors.Is(err, lib.ErrOffline) {
+		t.Errorf("unsynced project err = %v, want ErrOffline", err)
+	}
+}
+
+func TestMergeMRs(t *testing.T) {
+	mr := func(iid int, state string) lib.MergeRequest {
+		return lib.MergeRequest{IID: iid, State: state}
+	}
+	tests := []struct {
+		name         string
+		cached       []int
+		changed      []lib.MergeRequest
+		limit        int
+		want         []int
+		wantUpdated  []int
+		wantComplete bool
+	}{
+		{name: "new and updated", cached: []int{3, 2, 1}, changed: []lib.MergeRequest{mr(4, "opened"), mr(2, "opened")},
+			want: []int{4, 3, 2, 1}, wantUpdated: []int{4, 2}, wantComplete: true},
+		{name: "merged leaves", cached: []int{3, 2, 1}, changed: []lib.MergeRequest{mr(2, "merged")},
+			want: []int{3, 1}, wantComplete: true},
+		{name: "limit drops the oldest", cached: []int{3, 2}, changed: []lib.MergeRequest{mr(4, "opened")}, limit: 2,
+			want: []int{4, 3}, wantUpdated: []int{4}, wantComplete: true},
+		{name: "full cache shrinks", cached: []int{3, 2}, changed: []lib.MergeRequest{mr(3, "closed")}, limit: 2,
+			want: []int{2}, wantComplete: false},
+		{name: "unknown MR closed", cached: []int{3, 2}, changed: []lib.MergeRequest{mr(1, "closed")}, limit: 2,
+			want: []int{3, 2}, wantComplete: true},
+	}
+	for _, tt := range tests {
+		pc := &lib.ProjectCache{Discussions: make(map[int][]lib.Discussion)}
+		for _, iid := range tt.cached {
+			pc.MRs = append(pc.MRs, mr(iid, "opened"))
+			pc.Discussions[iid] = nil
+		}
+		updated, complete := pc.MergeMRs(tt.changed, "opened", tt.limit)
+		if got := iids(pc.MRs); !equalInts(got, tt.want) {
+			t.Errorf("%s: MRs = %v, want %v", tt.name, got, tt.want)
+		}
+		if !equalInts(updated, tt.wantUpdated) || complete != tt.wantComplete {
+			t.Errorf("%s: updated %v, complete %v; want %v, %v", tt.name, updated, complete, tt.wantUpdated, tt.wantComplete)
+		}
+		for iid := range pc.Discussions {
+			if !slices.Contains(tt.want, iid) {
+				t.Errorf("%s: discussions of !%d survived", tt.name, iid)
+			}
+		}
+	}
+}
+
+func TestMergePipelines(t *testing.T) {
+	pc := &lib.ProjectCache{Pipelines: []lib.Pipeline{{ID: 30, Status: "running"}, {ID: 20}, {ID: 10}}}
+	pc.MergePipelines([]lib.Pipeline{{ID: 40, Status: "pending"}, {ID: 30, Status: "success"}}, 3)
+	if len(pc.Pipelines) != 3 || pc.Pipelines[0].ID != 40 || pc.Pipelines[1].Status != "success" || pc.Pipelines[2].ID != 20 {
+		t.Errorf("pipelines = %+v", pc.Pipelines)
+	}
+}
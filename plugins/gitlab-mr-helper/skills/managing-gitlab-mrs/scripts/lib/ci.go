@@ -0,0 +1,41 @@
+package lib
+
+import (
+	"os"
+	"strconv"
+)
+
+// CIJob is the context of a GitLab CI job, read from the predefined CI_*
+// variables, that scripts use in place of flags and the local checkout
+type CIJob struct {
+	ServerURL   string // CI_SERVER_URL
+	ProjectPath string // the MR's project in merge request pipelines, else CI_PROJECT_PATH
+	Ref         string // source branch in merge request pipelines, else CI_COMMIT_REF_NAME
+	MRIID       int    // CI_MERGE_REQUEST_IID, 0 outside merge request pipelines
+	PipelineID  int    // CI_PIPELINE_ID
+}
+
+// CurrentCIJob returns the CI job the scripts run in, nil outside GitLab CI
+func CurrentCIJob() *CIJob {
+	if os.Getenv("GITLAB_CI") != "true" {
+		return nil
+	}
+	job := &CIJob{
+		ServerURL:   os.Getenv("CI_SERVER_URL"),
+		ProjectPath: firstEnv("CI_MERGE_REQUEST_PROJECT_PATH", "CI_PROJECT_PATH"),
+		Ref:         firstEnv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME", "CI_COMMIT_REF_NAME"),
+	}
+	job.MRIID, _ = strconv.Atoi(os.Getenv("CI_MERGE_REQUEST_IID"))
+	job.PipelineID, _ = strconv.Atoi(os.Getenv("CI_PIPELINE_ID"))
+	return job
+}
+
+// firstEnv returns the first of the variables that is set
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
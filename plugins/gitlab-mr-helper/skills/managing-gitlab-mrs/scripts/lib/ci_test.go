@@ -0,0 +1,74 @@
+package lib_test
+
+import (
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+// setCIEnv simulates a merge request pipeline of group/fork's MR !12 into
+// group/project
+func setCIEnv(t *testing.T) {
+	t.Helper()
+	for k, v := range map[string]string{
+		"GITLAB_CI":                           "true",
+		"CI_SERVER_URL":                       "https://gitlab.example.com",
+		"CI_PROJECT_PATH":                     "group/fork",
+		"CI_MERGE_REQUEST_PROJECT_PATH":       "group/project",
+		"CI_COMMIT_REF_NAME":                  "refs/merge-requests/12/head",
+		"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME": "feature/login",
+		"CI_MERGE_REQUEST_IID":                "12",
+		"CI_PIPELINE_ID":                      "9001",
+		"GITLAB_URL":                          "",
+		"GITLAB_PROJECT":                      "",
+	} {
+		t.Setenv(k, v)
+	}
+}
+
+func TestCurrentCIJob(t *testing.T) {
+	t.Setenv("GITLAB_CI", "")
+	if job := lib.CurrentCIJob(); job != nil {
+		t.Fatalf("outside CI: %+v", job)
+	}
+
+	setCIEnv(t)
+	job := lib.CurrentCIJob()
+	want := lib.CIJob{ServerURL: "https://gitlab.example.com", ProjectPath: "group/project", Ref: "feature/login", MRIID: 12, PipelineID: 9001}
+	if job == nil || *job != want {
+		t.Fatalf("CurrentCIJob = %+v, want %+v", job, want)
+	}
+	if got := lib.GitLabURL(); got != "https://gitlab.example.com" {
+		t.Errorf("GitLabURL = %q", got)
+	}
+	t.Setenv("GITLAB_URL", "https://override.example.com/")
+	if got := lib.GitLabURL(); got != "https://override.example.com" {
+		t.Errorf("GitLabURL with GITLAB_URL = %q", got)
+	}
+}
+
+func TestCIJobDefaults(t *testing.T) {
+	setCIEnv(t)
+
+	project, detected, err := (&lib.ProjectFlags{}).Resolve("")
+	if err != nil || project != "group/project" || detected {
+		t.Errorf("Resolve = %q, %v, %v; want the MR's project", project, detected, err)
+	}
+
+	m := &lib.MRFlag{}
+	if err := m.Parse(); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if iid, err := m.Resolve(nil, project); err != nil || iid != 12 {
+		t.Errorf("MR = %d, %v; want 12", iid, err)
+	}
+
+	// CI checks out a detached HEAD
+	chdirRepo(t, "")
+	git(t, "init", "-q")
+	git(t, "-c", "user.name=ci", "-c", "user.email=ci@example.com", "commit", "-q", "--allow-empty", "-m", "init")
+	git(t, "checkout", "-q", "--detach")
+	if branch, err := lib.GetCurrentBranch(); err != nil || branch != "feature/login" {
+		t.Errorf("GetCurrentBranch = %q, %v; want feature/login", branch, err)
+	}
+}
@@ -0,0 +1,271 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PolicyFileName is the repository's compliance policy, looked up at the
+// root of the current git work tree
+const PolicyFileName = ".gitlab-helper-policy.yml"
+
+// CompliancePolicy is what check_compliance.go requires of a project, e.g.
+//
+//	default_branch_protected: true
+//	no_direct_push: true
+//	min_approvals: 2
+//	pipeline_must_succeed: true
+//	secret_detection: true
+//
+// Checks left out or false are skipped.
+type CompliancePolicy struct {
+	// DefaultBranchProtected requires a protection matching the default
+	// branch; NoDirectPush and NoForcePush imply it and also require it to
+	// allow no pushes and no force pushes
+	DefaultBranchProtected bool `json:"default_branch_protected"`
+	NoDirectPush           bool `json:"no_direct_push"`
+	NoForcePush            bool `json:"no_force_push"`
+	// MinApprovals is the least number of approvals MRs must need
+	MinApprovals         int  `json:"min_approvals"`
+	ResetApprovalsOnPush bool `json:"reset_approvals_on_push"`
+	NoAuthorApproval     bool `json:"no_author_approval"`
+	// PipelineMustSucceed and DiscussionsMustBeResolved require the merge
+	// checks of the same names
+	PipelineMustSucceed       bool `json:"pipeline_must_succeed"`
+	DiscussionsMustBeResolved bool `json:"discussions_must_be_resolved"`
+	// SecretDetection requires the default branch's .gitlab-ci.yml to run
+	// GitLab secret detection
+	SecretDetection bool `json:"secret_detection"`
+}
+
+// DefaultCompliancePolicy is checked when the repository has no policy
+// file
+var DefaultCompliancePolicy = CompliancePolicy{
+	DefaultBranchProtected: true,
+	MinApprovals:           2,
+	PipelineMustSucceed:    true,
+	SecretDetection:        true,
+}
+
+// LoadCompliancePolicy reads a policy file. An empty path looks for
+// PolicyFileName at the repository root, and falls back to
+// DefaultCompliancePolicy when there is none. The returned path is empty
+// for the default policy.
+func LoadCompliancePolicy(file string) (*CompliancePolicy, string, error) {
+	data, path, err := readRepoFile(file, PolicyFileName)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read compliance policy: %w", err)
+	}
+	if data == nil {
+		policy := DefaultCompliancePolicy
+		return &policy, "", nil
+	}
+	policy := &CompliancePolicy{}
+	if err := DecodeYAML(data, policy); err != nil {
+		return nil, "", fmt.Errorf("invalid compliance policy %s: %w", path, err)
+	}
+	if policy.MinApprovals < 0 {
+		return nil, "", fmt.Errorf("invalid compliance policy %s: min_approvals must not be negative", path)
+	}
+	return policy, path, nil
+}
+
+// ComplianceCheck is the outcome of one policy check
+type ComplianceCheck struct {
+	Name   string // e.g. "default branch protected"
+	Passed bool
+	Detail string // what was found
+	// Remediation is a shell command or instruction that fixes a failed
+	// check
+	Remediation string
+}
+
+// secretDetectionPattern matches CI configurations that run secret
+// detection: its template, directly or through Auto DevOps, or a job of
+// its name
+var secretDetectionPattern = regexp.MustCompile(`Secret-Detection(\.latest)?\.gitlab-ci\.yml|Auto-DevOps\.gitlab-ci\.yml|(?m)^secret_detection:`)
+
+// CheckCompliance audits a project against a policy, in the policy's field
+// order
+func (c *Client) CheckCompliance(projectPath string, policy *CompliancePolicy) ([]ComplianceCheck, error) {
+	settings, err := c.GetProjectSettings(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	api := func(method, endpoint string, params url.Values) string {
+		return apiCommand(method, "/projects/"+url.PathEscape(projectPath)+endpoint, params)
+	}
+	branch := settings.DefaultBranch
+	var checks []ComplianceCheck
+
+	if policy.DefaultBranchProtected || policy.NoDirectPush || policy.NoForcePush {
+		protected, err := c.ListProtectedBranches(projectPath)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, branchChecks(policy, branch, protected, api)...)
+	}
+
+	if policy.MinApprovals > 0 {
+		rules, err := c.ListApprovalRules(projectPath)
+		if err != nil {
+			return nil, err
+		}
+		required := 0
+		var anyApprover *ApprovalRule
+		for i, r := range rules {
+			required = max(required, r.ApprovalsRequired)
+			if r.RuleType == "any_approver" {
+				anyApprover = &rules[i]
+			}
+		}
+		check := ComplianceCheck{Name: fmt.Sprintf("at least %d approval(s)", policy.MinApprovals), Passed: required >= policy.MinApprovals,
+			Detail: fmt.Sprintf("%d required", required)}
+		n := strconv.Itoa(policy.MinApprovals)
+		switch {
+		case check.Passed:
+		case anyApprover != nil:
+			check.Remediation = api("PUT", "/approval_rules/"+strconv.Itoa(anyApprover.ID), url.Values{"approvals_required": {n}})
+		default:
+			check.Remediation = api("POST", "/approval_rules", url.Values{"name": {anyApproverRule}, "rule_type": {"any_approver"}, "approvals_required": {n}})
+		}
+		checks = append(checks, check)
+	}
+
+	if policy.ResetApprovalsOnPush || policy.NoAuthorApproval {
+		s, err := c.GetApprovalSettings(projectPath)
+		if err != nil {
+			return nil, err
+		}
+		if policy.ResetApprovalsOnPush {
+			checks = append(checks, settingCheck("approvals reset on push", s.ResetApprovalsOnPush,
+				api("POST", "/approvals", url.Values{"reset_approvals_on_push": {"true"}})))
+		}
+		if policy.NoAuthorApproval {
+			checks = append(checks, settingCheck("authors cannot approve", !s.MergeRequestsAuthorApproval,
+				api("POST", "/approvals", url.Values{"merge_requests_author_approval": {"false"}})))
+		}
+	}
+
+	if policy.PipelineMustSucceed {
+		checks = append(checks, settingCheck("pipelines must succeed", settings.OnlyAllowMergeIfPipelineSucceeds,
+			api("PUT", "", url.Values{"only_allow_merge_if_pipeline_succeeds": {"true"}})))
+	}
+	if policy.DiscussionsMustBeResolved {
+		checks = append(checks, settingCheck("threads must be resolved", settings.OnlyAllowMergeIfAllDiscussionsAreResolved,
+			api("PUT", "", url.Values{"only_allow_merge_if_all_discussions_are_resolved": {"true"}})))
+	}
+
+	if policy.SecretDetection {
+		check := ComplianceCheck{Name: "secret detection", Passed: true, Detail: "enabled in .gitlab-ci.yml"}
+		file, err := c.GetFile(projectPath, ".gitlab-ci.yml", branch)
+		var apiErr *APIError
+		switch {
+		case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+			check.Passed, check.Detail = false, "no .gitlab-ci.yml on "+branch
+		case err != nil:
+			return nil, err
+		default:
+			ci, err := file.Text()
+			if err != nil {
+				return nil, err
+			}
+			if !secretDetectionPattern.MatchString(ci) {
+				check.Passed, check.Detail = false, "not in .gitlab-ci.yml on "+branch
+			}
+		}
+		if !check.Passed {
+			check.Remediation = "add '- template: Jobs/Secret-Detection.gitlab-ci.yml' to the include list of .gitlab-ci.yml"
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// branchChecks checks the protection of the default branch. Protections
+// are matched by name or wildcard, and the most permissive one applies, as
+// in GitLab.
+func branchChecks(policy *CompliancePolicy, branch string, protected []ProtectedBranch, api func(string, string, url.Values) string) []ComplianceCheck {
+	var matching []ProtectedBranch
+	for _, p := range protected {
+		if branchMatches(p.Name, branch) {
+			matching = append(matching, p)
+		}
+	}
+	pushLevel := AccessMaintainer
+	if policy.NoDirectPush {
+		pushLevel = AccessNoOne
+	}
+	protect := api("POST", "/protected_branches", url.Values{"name": {branch},
+		"push_access_level": {strconv.Itoa(pushLevel)}, "merge_access_level": {strconv.Itoa(AccessMaintainer)}})
+
+	if len(matching) == 0 {
+		return []ComplianceCheck{{Name: "default branch protected", Detail: branch + " is not protected", Remediation: protect}}
+	}
+	push, merge, force := AccessNoOne, AccessNoOne, false
+	for _, p := range matching {
+		push, merge = permissive(push, p.PushLevel()), permissive(merge, p.MergeLevel())
+		force = force || p.AllowForcePush
+	}
+	checks := []ComplianceCheck{{Name: "default branch protected", Passed: true, Detail: branch + ": " + formatLevels(push, merge)}}
+	name := matching[0].Name
+	if policy.NoDirectPush {
+		check := ComplianceCheck{Name: "no direct pushes to default branch", Passed: push == AccessNoOne,
+			Detail: AccessLevelName(push) + " may push to " + branch}
+		if !check.Passed {
+			check.Remediation = api("DELETE", "/protected_branches/"+url.PathEscape(name), nil) + " && " + protect
+		}
+		checks = append(checks, check)
+	}
+	if policy.NoForcePush {
+		checks = append(checks, settingCheck("no force pushes to default branch", !force,
+			api("PATCH", "/protected_branches/"+url.PathEscape(name), url.Values{"allow_force_push": {"false"}})))
+	}
+	return checks
+}
+
+// permissive returns the lower of two access levels, where AccessNoOne
+// allows nobody
+func permissive(a, b int) int {
+	switch {
+	case a == AccessNoOne:
+		return b
+	case b == AccessNoOne:
+		return a
+	}
+	return min(a, b)
+}
+
+// branchMatches reports whether a protected branch name or wildcard covers
+// branch. GitLab's * matches any characters, slashes included.
+func branchMatches(pattern, branch string) bool {
+	if !strings.Contains(pattern, "*") {
+		return pattern == branch
+	}
+	re := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+	return regexp.MustCompile(re).MatchString(branch)
+}
+
+// settingCheck checks a boolean setting, fixed by remediation
+func settingCheck(name string, passed bool, remediation string) ComplianceCheck {
+	check := ComplianceCheck{Name: name, Passed: passed, Detail: "enabled"}
+	if !passed {
+		check.Detail, check.Remediation = "disabled", remediation
+	}
+	return check
+}
+
+// apiCommand renders a REST API call as a curl command reading the
+// instance and token from GITLAB_URL and GITLAB_TOKEN
+func apiCommand(method, endpoint string, params url.Values) string {
+	target := "$GITLAB_URL/api/" + DefaultAPIVersion + endpoint
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+	return fmt.Sprintf(`curl --request %s --header "PRIVATE-TOKEN: $GITLAB_TOKEN" "%s"`, method, target)
+}
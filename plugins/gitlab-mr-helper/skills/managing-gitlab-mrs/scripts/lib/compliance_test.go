@@ -0,0 +1,118 @@
+package lib_test
+
+import (
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestLoadCompliancePolicy(t *testing.T) {
+	policy, path, err := lib.LoadCompliancePolicy(writeRules(t, "no_direct_push: true\nmin_approvals: 1\n"))
+	if err != nil || path == "" {
+		t.Fatalf("LoadCompliancePolicy = %v, %q", err, path)
+	}
+	if !policy.NoDirectPush || policy.MinApprovals != 1 || policy.SecretDetection {
+		t.Errorf("policy = %+v", policy)
+	}
+
+	for content, wantErr := range map[string]string{
+		"min_approvals: -1\n": "min_approvals must not be negative",
+		"min_aprovals: 2\n":   "min_aprovals",
+	} {
+		if _, _, err := lib.LoadCompliancePolicy(writeRules(t, content)); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("LoadCompliancePolicy(%q) error = %v, want %q", content, err, wantErr)
+		}
+	}
+}
+
+func TestCheckCompliance(t *testing.T) {
+	policy := &lib.CompliancePolicy{NoDirectPush: true, MinApprovals: 2, PipelineMustSucceed: true, ResetApprovalsOnPush: true, SecretDetection: true}
+
+	tests := []struct {
+		name    string
+		project string
+		setup   func(p *gitlabtest.Project)
+		// want maps check names to their remediation, empty when passing
+		want map[string]string
+	}{
+		{
+			name:    "fixture project",
+			project: gitlabtest.ProjectPath,
+			setup: func(p *gitlabtest.Project) {
+				p.Files[".gitlab-ci.yml"] = "include:\n  - template: Jobs/Secret-Detection.gitlab-ci.yml\n"
+			},
+			want: map[string]string{
+				"default branch protected":           "",
+				"no direct pushes to default branch": `curl --request DELETE --header "PRIVATE-TOKEN: $GITLAB_TOKEN" "$GITLAB_URL/api/v4/projects/group%2Fproject/protected_branches/main" && curl --request POST`,
+				"at least 2 approval(s)":             "/approval_rules/80?approvals_required=2",
+				"approvals reset on push":            "",
+				"pipelines must succeed":             "",
+				"secret detection":                   "",
+			},
+		},
+		{
+			name:    "unconfigured project",
+			project: gitlabtest.NestedProjectPath,
+			setup: func(p *gitlabtest.Project) {
+				p.Branches = []lib.Branch{{Name: "main", Default: true}}
+				p.Files[".gitlab-ci.yml"] = "test:\n  script: go test ./...\n"
+			},
+			want: map[string]string{
+				"default branch protected": "/protected_branches?merge_access_level=40&name=main&push_access_level=0",
+				"at least 2 approval(s)":   "/approval_rules?approvals_required=2&name=All+Members&rule_type=any_approver",
+				"approvals reset on push":  "/approvals?reset_approvals_on_push=true",
+				"pipelines must succeed":   "group%2Fsub%2Fnested?only_allow_merge_if_pipeline_succeeds=true",
+				"secret detection":         "Jobs/Secret-Detection.gitlab-ci.yml",
+			},
+		},
+		{
+			name:    "wildcard protection",
+			project: gitlabtest.NestedProjectPath,
+			setup: func(p *gitlabtest.Project) {
+				p.Branches = []lib.Branch{{Name: "main", Default: true}}
+				p.ProtectedBranches = []lib.ProtectedBranch{{Name: "ma*",
+					PushAccessLevels:  []lib.BranchAccessLevel{{AccessLevel: lib.AccessNoOne}},
+					MergeAccessLevels: []lib.BranchAccessLevel{{AccessLevel: lib.AccessDeveloper}}}}
+				p.Settings.OnlyAllowMergeIfPipelineSucceeds = true
+				p.ApprovalSettings.ResetApprovalsOnPush = true
+				p.ApprovalRules = []lib.ApprovalRule{{ID: 1, RuleType: "regular", ApprovalsRequired: 2}}
+				p.Files[".gitlab-ci.yml"] = "include:\n  - template: Auto-DevOps.gitlab-ci.yml\n"
+			},
+			want: map[string]string{
+				"default branch protected":           "",
+				"no direct pushes to default branch": "",
+				"at least 2 approval(s)":             "",
+				"approvals reset on push":            "",
+				"pipelines must succeed":             "",
+				"secret detection":                   "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			tt.setup(srv.Project(tt.project))
+			checks, err := srv.Client().CheckCompliance(tt.project, policy)
+			if err != nil {
+				t.Fatalf("CheckCompliance: %v", err)
+			}
+			if len(checks) != len(tt.want) {
+				t.Fatalf("checks = %+v", checks)
+			}
+			for _, check := range checks {
+				want, ok := tt.want[check.Name]
+				switch {
+				case !ok:
+					t.Errorf("unexpected check %+v", check)
+				case want == "" && !check.Passed:
+					t.Errorf("%s failed: %s", check.Name, check.Detail)
+				case want != "" && (check.Passed || !strings.Contains(check.Remediation, want)):
+					t.Errorf("%s: passed %v, remediation %q, want %q", check.Name, check.Passed, check.Remediation, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package lib
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ComponentsFileName is the repository's monorepo layout, looked up at the
+// root of the current git work tree
+const ComponentsFileName = ".gitlab-helper-components.yml"
+
+// Components describe the parts of a monorepo, e.g.
+//
+//	components:
+//	  - name: api
+//	    paths: ["services/api/**", "proto/**"]
+//	    owners: ["@alice", "@bob"]
+//	    labels: ["component::api"]
+//	    pipeline:
+//	      project: group/api-deploy
+//	      ref: main
+//	      variables: {DEPLOY_ENV: review}
+type Components struct {
+	Components []Component `json:"components"`
+}
+
+// Component is one part of a monorepo. Paths are globs following
+// MatchGlob; an MR affects the component when it changes a matching file.
+type Component struct {
+	Name   string   `json:"name"`
+	Paths  []string `json:"paths"`
+	Owners []string `json:"owners"` // usernames, with or without @
+	Labels []string `json:"labels"`
+	// Pipeline is run for MRs affecting the component, nil for none
+	Pipeline *ComponentPipeline `json:"pipeline"`
+}
+
+// ComponentPipeline is the downstream pipeline of a component
+type ComponentPipeline struct {
+	// Project runs the pipeline, the MR's project when empty
+	Project string `json:"project"`
+	// Ref defaults to the MR's source branch in the MR's project, and to
+	// the default branch in another project
+	Ref       string            `json:"ref"`
+	Variables map[string]string `json:"variables"`
+}
+
+// AffectedComponent is a component an MR changes, with the changed files
+type AffectedComponent struct {
+	*Component
+	Files []string
+}
+
+// LoadComponents reads a components file. An empty path looks for
+// ComponentsFileName at the repository root; it returns nil components
+// when there is none.
+func LoadComponents(file string) (*Components, error) {
+	data, file, err := readRepoFile(file, ComponentsFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read components: %w", err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	cs := &Components{}
+	if err := DecodeYAML(data, cs); err != nil {
+		return nil, fmt.Errorf("invalid components %s: %w", file, err)
+	}
+	if err := cs.validate(); err != nil {
+		return nil, fmt.Errorf("invalid components %s: %w", file, err)
+	}
+	return cs, nil
+}
+
+func (cs *Components) validate() error {
+	seen := map[string]bool{}
+	for i, c := range cs.Components {
+		if strings.TrimSpace(c.Name) == "" {
+			return fmt.Errorf("component %d: name is required", i+1)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("%s: defined twice", c.Name)
+		}
+		seen[c.Name] = true
+		if len(c.Paths) == 0 {
+			return fmt.Errorf("%s: needs paths", c.Name)
+		}
+		for _, pattern := range c.Paths {
+			if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+				return fmt.Errorf("%s: invalid pattern %q", c.Name, pattern)
+			}
+		}
+		for _, l := range c.Labels {
+			if strings.Contains(l, ",") {
+				return fmt.Errorf("%s: label names cannot contain commas", c.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// Affected returns the components whose paths match the changed files, in
+// the order of the components file
+func (cs *Components) Affected(diffs []Diff) []AffectedComponent {
+	var affected []AffectedComponent
+	for i := range cs.Components {
+		c := &cs.Components[i]
+		var files []string
+		for _, d := range diffs {
+			if matchAnyGlob(c.Paths, diffPaths(d)...) {
+				files = append(files, d.NewPath)
+			}
+		}
+		if len(files) > 0 {
+			affected = append(affected, AffectedComponent{Component: c, Files: files})
+		}
+	}
+	return affected
+}
+
+// ComponentOwners returns the owners of the affected components, without
+// @ and without skip (e.g. the MR author), each once
+func ComponentOwners(affected []AffectedComponent, skip ...string) []string {
+	var owners []string
+	for _, a := range affected {
+		for _, o := range a.Owners {
+			o = strings.TrimPrefix(o, "@")
+			if !ContainsString(owners, o) && !ContainsString(skip, o) {
+				owners = append(owners, o)
+			}
+		}
+	}
+	return owners
+}
+
+// ComponentLabels returns the labels of the affected components, each once
+func ComponentLabels(affected []AffectedComponent) []string {
+	var labels []string
+	for _, a := range affected {
+		for _, l := range a.Labels {
+			if !ContainsString(labels, l) {
+				labels = append(labels, l)
+			}
+		}
+	}
+	return labels
+}
+
+// RunComponentPipeline runs the downstream pipeline of a component for an
+// MR of projectPath, returning it and the project it runs in. It gets the
+// COMPONENT, UPSTREAM_PROJECT and UPSTREAM_MR_IID variables, which the
+// component's own variables override.
+func (c *Client) RunComponentPipeline(projectPath string, mr *MergeRequest, comp *Component) (*Pipeline, string, error) {
+	p := comp.Pipeline
+	project, ref := p.Project, p.Ref
+	if project == "" {
+		project = projectPath
+	}
+	if ref == "" {
+		if project == projectPath {
+			ref = mr.SourceBranch
+		} else {
+			var err error
+			if ref, err = c.DefaultBranch(project); err != nil {
+				return nil, project, err
+			}
+		}
+	}
+	variables := map[string]string{
+		"COMPONENT":        comp.Name,
+		"UPSTREAM_PROJECT": projectPath,
+		"UPSTREAM_MR_IID":  fmt.Sprint(mr.IID),
+	}
+	for k, v := range p.Variables {
+		variables[k] = v
+	}
+	pipeline, err := c.CreatePipeline(project, ref, variables)
+	return pipeline, project, err
+}
@@ -0,0 +1,112 @@
+package lib_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+const testComponents = `components:
+  - name: api
+    paths: ["services/api/**", "proto/**"]
+    owners: ["@alice", bob]
+    labels: ["component::api"]
+    pipeline:
+      variables: {DEPLOY_ENV: review, COMPONENT: backend}
+  - name: web
+    paths: ["web/**"]
+    owners: [carol, "@bob"]
+    labels: ["component::web", frontend]
+  - name: docs
+    paths: ["*.md"]
+`
+
+func TestComponentsAffected(t *testing.T) {
+	cs, err := lib.LoadComponents(writeRules(t, testComponents))
+	if err != nil {
+		t.Fatalf("LoadComponents: %v", err)
+	}
+
+	affected := cs.Affected([]lib.Diff{
+		{NewPath: "web/login.html"},
+		{NewPath: "proto/user.proto"},
+		{NewPath: "services/api/main.go"},
+		{NewPath: "Makefile"},
+	})
+	var names []string
+	for _, a := range affected {
+		names = append(names, a.Name)
+	}
+	if want := []string{"api", "web"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("Affected = %v, want %v", names, want)
+	}
+	if want := []string{"proto/user.proto", "services/api/main.go"}; !reflect.DeepEqual(affected[0].Files, want) {
+		t.Errorf("api files = %v, want %v", affected[0].Files, want)
+	}
+
+	if got, want := lib.ComponentOwners(affected, "alice"), []string{"bob", "carol"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ComponentOwners = %v, want %v", got, want)
+	}
+	if got, want := lib.ComponentLabels(affected), []string{"component::api", "component::web", "frontend"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ComponentLabels = %v, want %v", got, want)
+	}
+}
+
+func TestLoadComponentsInvalid(t *testing.T) {
+	for content, want := range map[string]string{
+		"components:\n  - paths: [x]\n": "name is required",
+		"components:\n  - name: api\n":  "needs paths",
+		"components:\n  - name: api\n    paths: [a]\n  - name: api\n    paths: [b]\n": "defined twice",
+		"components:\n  - name: api\n    paths: [\"[\"]\n":                            "invalid pattern",
+	} {
+		_, err := lib.LoadComponents(writeRules(t, content))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadComponents(%q) = %v, want an error containing %q", content, err, want)
+		}
+	}
+}
+
+func TestRunComponentPipeline(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+	cs, err := lib.LoadComponents(writeRules(t, testComponents))
+	if err != nil {
+		t.Fatalf("LoadComponents: %v", err)
+	}
+	mr, err := client.GetMR(gitlabtest.ProjectPath, 1)
+	if err != nil {
+		t.Fatalf("GetMR: %v", err)
+	}
+
+	p, project, err := client.RunComponentPipeline(gitlabtest.ProjectPath, mr, &cs.Components[0])
+	if err != nil {
+		t.Fatalf("RunComponentPipeline: %v", err)
+	}
+	if project != gitlabtest.ProjectPath || p.Ref != mr.SourceBranch {
+		t.Errorf("pipeline runs in %s on %s, want %s on %s", project, p.Ref, gitlabtest.ProjectPath, mr.SourceBranch)
+	}
+	want := []lib.PipelineVariable{
+		{Key: "COMPONENT", Value: "backend"},
+		{Key: "DEPLOY_ENV", Value: "review"},
+		{Key: "UPSTREAM_MR_IID", Value: "1"},
+		{Key: "UPSTREAM_PROJECT", Value: gitlabtest.ProjectPath},
+	}
+	if got := srv.Project(gitlabtest.ProjectPath).PipelineVariables[p.ID]; !reflect.DeepEqual(got, want) {
+		t.Errorf("variables = %v, want %v", got, want)
+	}
+}
+
+func TestFormatFileList(t *testing.T) {
+	for files, want := range map[string]string{
+		"a.go":                "a.go",
+		"a.go b.go c.go":      "a.go, b.go, c.go",
+		"a.go b.go c.go d.go": "a.go, b.go, c.go and 1 more",
+	} {
+		if got := lib.FormatFileList(strings.Fields(files)); got != want {
+			t.Errorf("FormatFileList(%s) = %q, want %q", files, got, want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ETagCache keeps the ETag and body of GET responses by URL, so repeated
+// polling can send If-None-Match and reuse the stored body when GitLab
+// answers 304 Not Modified: unchanged listings cost a status line instead
+// of a full transfer. It is saved as JSON with the state of its user.
+type ETagCache struct {
+	mu      sync.Mutex
+	Entries map[string]*ETagEntry `json:"entries"`
+}
+
+// ETagEntry is one cached response
+type ETagEntry struct {
+	ETag    string            `json:"etag"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body"`
+	// Used is when the response was last stored or served
+	Used time.Time `json:"used"`
+}
+
+// Prune forgets the responses not used since cutoff
+func (e *ETagCache) Prune(cutoff time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for url, entry := range e.Entries {
+		if entry.Used.Before(cutoff) {
+			delete(e.Entries, url)
+		}
+	}
+}
+
+// UseETagCache makes the client's GET requests conditional on the
+// responses in cache, and stores the new ones there
+func (c *Client) UseETagCache(cache *ETagCache) {
+	if cache.Entries == nil {
+		cache.Entries = make(map[string]*ETagEntry)
+	}
+	c.httpClient.Transport = &conditionalTransport{next: c.httpClient.Transport, cache: cache}
+}
+
+// conditionalTransport implements UseETagCache. Only JSON responses are
+// stored; others pass through.
+type conditionalTransport struct {
+	next  http.RoundTripper
+	cache *ETagCache
+}
+
+func (t *conditionalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.next.RoundTrip(req)
+	}
+	key := req.URL.String()
+	t.cache.mu.Lock()
+	entry := t.cache.Entries[key]
+	t.cache.mu.Unlock()
+
+	if entry != nil {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+		t.cache.mu.Lock()
+		entry.Used = now
+		t.cache.mu.Unlock()
+		resp.StatusCode, resp.Status = http.StatusOK, "200 OK"
+		for name, value := range entry.Headers {
+			resp.Header.Set(name, value)
+		}
+		resp.ContentLength = int64(len(entry.Body))
+		resp.Body = io.NopCloser(bytes.NewReader(entry.Body))
+		return resp, nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return resp, nil
+	}
+	body, err := io.ReadAll(capReader(resp.Body, MaxJSONBytes))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if !json.Valid(body) {
+		return resp, nil
+	}
+
+	stored := &ETagEntry{ETag: etag, Headers: make(map[string]string), Body: body, Used: now}
+	for _, name := range recordedHeaders {
+		if v := resp.Header.Get(name); v != "" {
+			stored.Headers[name] = v
+		}
+	}
+	t.cache.mu.Lock()
+	t.cache.Entries[key] = stored
+	t.cache.mu.Unlock()
+	return resp, nil
+}
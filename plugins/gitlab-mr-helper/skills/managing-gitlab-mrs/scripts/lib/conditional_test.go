@@ -0,0 +1,62 @@
+package lib_test
+
+import (
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestETagCache(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	p := srv.Project(gitlabtest.ProjectPath)
+	for i := 0; i < 150; i++ {
+		p.Events = append(p.Events, lib.Event{ID: 9000 + i, ActionName: "pushed to", CreatedAt: gitlabtest.FixtureTime})
+	}
+	client := srv.Client()
+	cache := &lib.ETagCache{}
+	client.UseETagCache(cache)
+
+	// notModified counts the requests answered 304 since the last call
+	seen := 0
+	notModified := func() (n int) {
+		requests := srv.Requests()
+		for _, r := range requests[seen:] {
+			if r.Header.Get("If-None-Match") != "" {
+				n++
+			}
+		}
+		seen = len(requests)
+		return n
+	}
+
+	first, err := client.ListProjectEvents(gitlabtest.ProjectPath, &lib.EventListOptions{})
+	if err != nil || len(first) != len(p.Events) {
+		t.Fatalf("ListProjectEvents = %d events, %v", len(first), err)
+	}
+	if n := notModified(); n != 0 || len(cache.Entries) != 2 {
+		t.Fatalf("first listing: %d conditional requests, %d cached pages", n, len(cache.Entries))
+	}
+
+	// Both pages come back unchanged, with their pagination headers
+	again, err := client.ListProjectEvents(gitlabtest.ProjectPath, &lib.EventListOptions{})
+	if err != nil || len(again) != len(first) || again[0].ID != first[0].ID {
+		t.Fatalf("repeated ListProjectEvents = %d events, %v", len(again), err)
+	}
+	if n := notModified(); n != 2 {
+		t.Errorf("repeated listing made %d conditional requests, want 2", n)
+	}
+
+	// A change is fetched in full
+	p.Events = append(p.Events, lib.Event{ID: 9999, ActionName: "opened", CreatedAt: gitlabtest.FixtureTime})
+	changed, err := client.ListProjectEvents(gitlabtest.ProjectPath, &lib.EventListOptions{})
+	if err != nil || len(changed) != len(first)+1 {
+		t.Errorf("ListProjectEvents after a change = %d events, %v", len(changed), err)
+	}
+
+	cache.Prune(time.Now().Add(time.Minute))
+	if len(cache.Entries) != 0 {
+		t.Errorf("%d entries survived pruning", len(cache.Entries))
+	}
+}
@@ -1,158 +1,662 @@
 package lib
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
 	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds GitLab connection configuration
 type Config struct {
-	Token     string
-	URL       string
+	Token string
+	URL   string
+	// APIURL is the base of REST endpoints, e.g.
+	// https://gitlab.example.com/api/v4 (URL/api/DefaultAPIVersion when
+	// empty); see Endpoint
+	APIURL    string
 	ProjectID string
+	Debug     bool   // trace every API call to stderr
+	Offline   bool   // serve reads from the sync.go cache, never the network
+	Sudo      string // act as this user (admin tokens only)
+
+	// Stats records every API call when not nil (see --stats)
+	Stats *Stats
+
+	// Concurrency bounds the parallel requests of multi-item commands
+	// (DefaultConcurrency when zero)
+	Concurrency int
+
+	// MaxConns caps the connections to the instance (DefaultMaxConns when
+	// zero), IdleTimeout is how long unused ones stay open
+	// (DefaultIdleTimeout when zero) and NoHTTP2 sticks to HTTP/1.1; see
+	// NewTransport
+	MaxConns    int
+	IdleTimeout time.Duration
+	NoHTTP2     bool
+
+	// RateLimits caps the request rate by host (see RateLimit)
+	RateLimits map[string]RateLimit
+
+	// TokenSource is where Token came from: TokenSourceEnv or the path of
+	// the credential file it was read from, empty for GetConfigWithToken
+	TokenSource string
+
+	// VCRMode ("record" or "replay") and VCRCassette route API calls through
+	// a cassette file instead of, or in addition to, the network
+	VCRMode     string
+	VCRCassette string
+}
+
+// TokenSourceEnv is the TokenSource of tokens taken from GITLAB_TOKEN
+const TokenSourceEnv = "GITLAB_TOKEN"
+
+// configFlags holds values of the connection flags registered by
+// RegisterConfigFlags; GetConfig applies them over the environment.
+var configFlags struct {
+	debug       bool
+	offline     bool
+	sudo        string
+	concurrency int
+	stats       bool
+	tz          string
+	locale      string
+	ascii       bool
+}
+
+// RegisterConfigFlags registers the flags that adjust the GitLab connection
+// (--debug, --offline, --sudo, --concurrency, --stats) and text output
+// (--tz, --locale, --ascii) on the default flag set. Call it before
+// flag.Parse.
+func RegisterConfigFlags() {
+	flag.BoolVar(&configFlags.debug, "debug", false, "Trace API requests and responses to stderr (also GITLAB_DEBUG=1)")
+	flag.BoolVar(&configFlags.offline, "offline", false, "Read from the local cache written by sync.go instead of the API (also GITLAB_OFFLINE=1)")
+	flag.StringVar(&configFlags.sudo, "sudo", "", "Perform requests as this user; needs an admin token (also GITLAB_SUDO)")
+	flag.IntVar(&configFlags.concurrency, "concurrency", 0, fmt.Sprintf("Parallel requests for multi-item work, 1-%d (default %d, also GITLAB_CONCURRENCY)", MaxConcurrency, DefaultConcurrency))
+	flag.BoolVar(&configFlags.stats, "stats", false, "Print the API calls and time the command used to stderr when done (also GITLAB_STATS=1)")
+	flag.StringVar(&configFlags.tz, "tz", "", "Show times in this timezone, e.g. UTC or Europe/Berlin (also GITLAB_TZ; default: the system zone)")
+	flag.StringVar(&configFlags.locale, "locale", "", "Format dates and sizes for this locale: en, en-GB, de, fr, es (also GITLAB_LOCALE; default: LANG)")
+	flag.BoolVar(&configFlags.ascii, "ascii", false, "Print plain ASCII instead of emoji and Unicode symbols (also GITLAB_ASCII=1)")
 }
 
 // GetConfig retrieves GitLab configuration from environment and git
 func GetConfig() (*Config, error) {
-	config := &Config{}
+	return getConfig("")
+}
+
+// GetConfigWithToken is GetConfig with token in place of the configured
+// one, for a token that is not stored yet
+func GetConfigWithToken(token string) (*Config, error) {
+	return getConfig(token)
+}
+
+func getConfig(token string) (*Config, error) {
+	config := &Config{
+		VCRMode:     os.Getenv("GITLAB_VCR"),
+		VCRCassette: os.Getenv("GITLAB_VCR_CASSETTE"),
+	}
+
+	switch config.VCRMode {
+	case "", VCRRecord, VCRReplay:
+	default:
+		return nil, UsageErrorf("invalid GITLAB_VCR %q (expected record or replay)", config.VCRMode)
+	}
+	if config.VCRMode != "" && config.VCRCassette == "" {
+		return nil, UsageErrorf("GITLAB_VCR_CASSETTE is required when GITLAB_VCR is set")
+	}
+
+	config.Offline = configFlags.offline || os.Getenv("GITLAB_OFFLINE") != ""
 
-	// Get token from environment or credential files
-	token, err := getToken()
+	// Get token from environment or credential files. Replaying a cassette
+	// or reading the offline cache never reaches the network, so no token is
+	// needed.
+	var err error
+	if token != "" {
+		config.Token = token
+	} else if config.Token, config.TokenSource, err = getToken(); err != nil && config.VCRMode != VCRReplay && !config.Offline {
+		return nil, err
+	}
+
+	config.URL = GitLabURL()
+	if config.APIURL, err = apiURL(config.URL); err != nil {
+		return nil, err
+	}
+
+	config.Debug = configFlags.debug || os.Getenv("GITLAB_DEBUG") != ""
+	config.Sudo = configFlags.sudo
+	if config.Sudo == "" {
+		config.Sudo = os.Getenv("GITLAB_SUDO")
+	}
+	config.Sudo = strings.TrimPrefix(config.Sudo, "@")
+
+	if configFlags.stats || os.Getenv("GITLAB_STATS") != "" {
+		if commandStats == nil {
+			commandStats = NewStats()
+		}
+		config.Stats = commandStats
+	}
+
+	config.Concurrency = configFlags.concurrency
+	if v := os.Getenv("GITLAB_CONCURRENCY"); v != "" && config.Concurrency == 0 {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, UsageErrorf("invalid GITLAB_CONCURRENCY %q", v)
+		}
+		config.Concurrency = n
+	}
+	if config.Concurrency < 0 || config.Concurrency > MaxConcurrency {
+		return nil, UsageErrorf("concurrency must be between 1 and %d", MaxConcurrency)
+	}
+	if err := connectionConfig(config); err != nil {
+		return nil, err
+	}
+	settings, err := LoadSettings()
 	if err != nil {
 		return nil, err
 	}
-	config.Token = token
+	if err := applyDisplay(settings.Display); err != nil {
+		return nil, err
+	}
+	if config.RateLimits, err = rateLimits(settings.RateLimits, config.URL); err != nil {
+		return nil, err
+	}
 
-	// Get GitLab URL (default or from environment)
-	config.URL = os.Getenv("GITLAB_URL")
-	if config.URL == "" {
-		config.URL = "https://gitlab.com"
+	// Warn about an expiring token before it starts failing requests
+	if config.Token != "" && config.VCRMode == "" && !config.Offline {
+		CheckTokenExpiry(config, tokenWarnDays(), os.Stderr)
 	}
-	config.URL = strings.TrimSuffix(config.URL, "/")
 
 	return config, nil
 }
 
-// GetProjectFromGit resolves project path from git remote
-func GetProjectFromGit() (string, error) {
-	cmd := exec.Command("git", "remote", "get-url", "origin")
-	output, err := cmd.Output()
+// DefaultAPIVersion is the REST API version scripts are written against
+const DefaultAPIVersion = "v4"
+
+var apiVersionPattern = regexp.MustCompile(`^v[0-9]+$`)
+
+// apiURL returns the base of REST endpoints: GITLAB_API_URL for proxies
+// that serve the API somewhere else than the instance, or the instance URL
+// followed by /api/ and GITLAB_API_VERSION (DefaultAPIVersion when unset)
+func apiURL(instanceURL string) (string, error) {
+	if u := os.Getenv("GITLAB_API_URL"); u != "" {
+		if _, err := url.ParseRequestURI(u); err != nil {
+			return "", UsageErrorf("invalid GITLAB_API_URL %q", u)
+		}
+		return strings.TrimSuffix(u, "/"), nil
+	}
+	version := os.Getenv("GITLAB_API_VERSION")
+	if version == "" {
+		version = DefaultAPIVersion
+	}
+	if !apiVersionPattern.MatchString(version) {
+		return "", UsageErrorf("invalid GITLAB_API_VERSION %q (expected e.g. %s)", version, DefaultAPIVersion)
+	}
+	return instanceURL + "/api/" + version, nil
+}
+
+// Endpoint returns the URL of a REST endpoint: the API base followed by
+// path, formatted with a like fmt.Sprintf. Arguments must already be
+// escaped, with url.PathEscape for project paths (group%2Fproject) and
+// url.QueryEscape in the query.
+func (c *Config) Endpoint(path string, a ...interface{}) string {
+	base := c.APIURL
+	if base == "" {
+		base = c.URL + "/api/" + DefaultAPIVersion
+	}
+	return base + fmt.Sprintf(path, a...)
+}
+
+// GraphQLEndpoint returns the URL of the GraphQL API, next to the REST one
+func (c *Config) GraphQLEndpoint() string {
+	base := c.Endpoint("")
+	return base[:strings.LastIndex(base, "/")] + "/graphql"
+}
+
+// GitLabURL returns the instance URL: GITLAB_URL without a trailing slash,
+// the job's instance in GitLab CI, https://gitlab.com otherwise
+func GitLabURL() string {
+	u := os.Getenv("GITLAB_URL")
+	if job := CurrentCIJob(); u == "" && job != nil {
+		u = job.ServerURL
+	}
+	if u == "" {
+		u = "https://gitlab.com"
+	}
+	return strings.TrimSuffix(u, "/")
+}
+
+// GitLabHost returns the host name of GitLabURL
+func GitLabHost() string {
+	u, err := url.Parse(GitLabURL())
 	if err != nil {
-		return "", fmt.Errorf("failed to get git remote: %w", err)
+		return ""
+	}
+	return u.Hostname()
+}
+
+// ProjectFlags holds the project selection flags registered by
+// RegisterProjectFlags
+type ProjectFlags struct {
+	Project string
+	Auto    bool
+	Remote  string // git remote to detect from ("" picks the GitLab one)
+}
+
+// RegisterProjectFlags registers --project, --auto and --remote on the
+// default flag set. Call it before flag.Parse.
+func RegisterProjectFlags() *ProjectFlags {
+	p := &ProjectFlags{}
+	flag.StringVar(&p.Project, "project", "", "Project path, ID or URL (also GITLAB_PROJECT, or the CI job's project; default: the git remote)")
+	flag.BoolVar(&p.Auto, "auto", false, "Detect the project from the git remote, ignoring GITLAB_PROJECT")
+	flag.StringVar(&p.Remote, "remote", "", "Git remote to detect the project from (default: the one on the GITLAB_URL host, else origin)")
+	return p
+}
+
+// Resolve returns the project to work on, taken from the first of:
+// --project, --auto, arg (a positional project argument, "" when none),
+// GITLAB_PROJECT, the project of the GitLab CI job (CIJob.ProjectPath) and
+// the git remote of the working directory. Explicit values may be a path, a
+// numeric ID or a web URL of the project or one of its pages. detected
+// reports that the project came from the git remote, which scripts echo so
+// the user sees what was picked.
+func (p *ProjectFlags) Resolve(arg string) (project string, detected bool, err error) {
+	flagProject, err := normalizeProject(p.Project)
+	if err != nil {
+		return "", false, err
+	}
+	if arg, err = normalizeProject(arg); err != nil {
+		return "", false, err
+	}
+	if flagProject != "" && arg != "" && arg != flagProject {
+		return "", false, UsageErrorf("project given twice: --project %s and argument %s", p.Project, arg)
+	}
+	switch {
+	case flagProject != "":
+		return flagProject, false, nil
+	case p.Auto:
+		project, err := GetProjectFromGit(p.Remote)
+		return project, err == nil, err
+	case arg != "":
+		return arg, false, nil
+	}
+	// In merge request pipelines the MR's project can differ from the job's
+	for _, env := range []string{"GITLAB_PROJECT", "CI_MERGE_REQUEST_PROJECT_PATH", "CI_PROJECT_PATH"} {
+		if project := os.Getenv(env); project != "" {
+			project, err := normalizeProject(project)
+			return project, false, err
+		}
+	}
+	project, err = GetProjectFromGit(p.Remote)
+	if err != nil {
+		return "", false, UsageErrorf("project required: use --project, GITLAB_PROJECT, an argument, or run inside a git checkout (%v)", err)
+	}
+	return project, true, nil
+}
+
+// normalizeProject turns a project path, numeric ID or web URL into the
+// path or ID the API takes
+func normalizeProject(project string) (string, error) {
+	if strings.Contains(project, "://") {
+		project, _, err := ParseWebURL(project)
+		return project, err
+	}
+	return strings.Trim(project, "/"), nil
+}
+
+// ParseWebURL splits a GitLab web URL, such as one copied from the browser
+// (https://gitlab.com/group/proj/-/merge_requests/5), into the project path
+// and the IID of the MR it points at, 0 for other pages. A GITLAB_URL with
+// a path (an instance under a relative URL root) is stripped from the front.
+func ParseWebURL(raw string) (project string, iid int, err error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", 0, UsageErrorf("invalid GitLab URL %q", raw)
 	}
+	path, page, _ := strings.Cut(strings.Trim(stripURLRoot(u.Host, u.Path), "/"), "/-/")
+	project = strings.TrimSuffix(path, ".git")
+	if !strings.Contains(project, "/") {
+		return "", 0, UsageErrorf("no project in URL %q", raw)
+	}
+	if rest, ok := strings.CutPrefix(page, "merge_requests/"); ok {
+		n, _, _ := strings.Cut(rest, "/")
+		if iid, err = strconv.Atoi(n); err != nil || iid <= 0 {
+			return "", 0, UsageErrorf("invalid MR IID in URL %q", raw)
+		}
+	}
+	return project, iid, nil
+}
+
+// ProjectArg returns the first positional argument that is not an MR IID,
+// for scripts that accept the project and IIDs in any order. MR web URLs
+// name the project too and are returned as is.
+func ProjectArg() string {
+	for _, arg := range flag.Args() {
+		if _, err := strconv.Atoi(strings.TrimPrefix(arg, "!")); err != nil {
+			return arg
+		}
+	}
+	return ""
+}
 
-	remoteURL := strings.TrimSpace(string(output))
-	return parseProjectPath(remoteURL)
+// SplitList splits a comma-separated flag or field value, trimming items
+// and dropping blanks. It returns an empty, non-nil slice when there are
+// none.
+func SplitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
-func parseProjectPath(remoteURL string) (string, error) {
-	// Handle SSH URLs: git@gitlab.com:group/project.git
-	if strings.HasPrefix(remoteURL, "git@") {
-		parts := strings.SplitN(remoteURL, ":", 2)
-		if len(parts) != 2 {
-			return "", fmt.Errorf("invalid SSH remote URL: %s", remoteURL)
+// MRArg returns the MR IID among the positional arguments: the first that
+// is a number (optionally written !5) or an MR web URL, 0 when none is
+func MRArg() int {
+	for _, arg := range flag.Args() {
+		if iid, err := strconv.Atoi(strings.TrimPrefix(arg, "!")); err == nil {
+			return iid
+		}
+		if strings.Contains(arg, "://") {
+			if _, iid, err := ParseWebURL(arg); err == nil && iid > 0 {
+				return iid
+			}
 		}
-		path := strings.TrimSuffix(parts[1], ".git")
-		return path, nil
 	}
+	return 0
+}
 
-	// Handle HTTPS URLs: https://gitlab.com/group/project.git
-	u, err := url.Parse(remoteURL)
+// GetProjectFromGit resolves project path from a git remote, the one
+// FindGitLabRemote picks when remote is ""
+func GetProjectFromGit(remote string) (string, error) {
+	_, remoteURL, err := FindGitLabRemote(remote)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse remote URL: %w", err)
+		return "", err
+	}
+	_, project, err := ParseRemoteURL(remoteURL)
+	return project, err
+}
+
+// ParseRemoteURL splits a git remote URL into the host and the project
+// path. It understands the forms git does:
+//
+//	https://gitlab.com/group/sub/project.git
+//	ssh://git@gitlab.example.com:2222/group/sub/project.git
+//	git@gitlab.com:group/sub/project.git (scp-like)
+//
+// with optional credentials, ports, trailing slashes and .git suffixes.
+// For http(s) remotes of an instance under a relative URL root, the path
+// of GITLAB_URL is stripped from the front.
+func ParseRemoteURL(remoteURL string) (host, project string, err error) {
+	var path string
+	if scheme, _, ok := strings.Cut(remoteURL, "://"); ok {
+		u, err := url.Parse(remoteURL)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse remote URL: %w", err)
+		}
+		host, path = u.Hostname(), u.Path
+		if scheme == "http" || scheme == "https" {
+			path = stripURLRoot(u.Host, path)
+		}
+	} else {
+		// scp-like [user@]host:path, where the colon comes before any slash;
+		// git also allows [user@host:port]:path
+		var ok bool
+		host, path, ok = cutSCPHost(remoteURL)
+		if !ok {
+			return "", "", fmt.Errorf("remote %s is a local path, not a GitLab URL", remoteURL)
+		}
 	}
 
-	path := strings.TrimPrefix(u.Path, "/")
-	path = strings.TrimSuffix(path, ".git")
-	return path, nil
+	project = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	project = strings.TrimSuffix(project, "/")
+	if host == "" || !strings.Contains(project, "/") {
+		return "", "", fmt.Errorf("no group/project path in remote URL %s", remoteURL)
+	}
+	return host, project, nil
+}
+
+// cutSCPHost splits an scp-like remote into host and path
+func cutSCPHost(remote string) (host, path string, ok bool) {
+	if strings.HasPrefix(remote, "[") {
+		end := strings.Index(remote, "]:")
+		if end < 0 {
+			return "", "", false
+		}
+		host, path = remote[1:end], remote[end+2:]
+		if i := strings.LastIndex(host, "@"); i >= 0 {
+			host = host[i+1:]
+		}
+		host, _, _ = strings.Cut(host, ":")
+		return host, path, true
+	}
+	colon := strings.Index(remote, ":")
+	if colon <= 0 || strings.Contains(remote[:colon], "/") {
+		return "", "", false
+	}
+	host, path = remote[:colon], remote[colon+1:]
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	return host, path, true
+}
+
+// stripURLRoot removes the path of GITLAB_URL from the front of a web path
+// on the same host, for instances served under a relative URL root
+func stripURLRoot(host, path string) string {
+	base, err := url.Parse(os.Getenv("GITLAB_URL"))
+	if err != nil || !strings.EqualFold(base.Host, host) {
+		return path
+	}
+	root := strings.TrimSuffix(base.Path, "/")
+	if root == "" || (path != root && !strings.HasPrefix(path, root+"/")) {
+		return path
+	}
+	return strings.TrimPrefix(path, root)
 }
 
-func getToken() (string, error) {
+// getToken returns the token and where it was found
+func getToken() (token, source string, err error) {
 	// 1. Check environment variable
 	if token := os.Getenv("GITLAB_TOKEN"); token != "" {
-		return token, nil
+		return token, TokenSourceEnv, nil
 	}
 
-	// 2. Check .netrc file
+	// 2. Check the encrypted credential store of auth.go
+	if token, err := loadStoredToken(GitLabHost()); err != nil {
+		return "", "", err
+	} else if token != "" {
+		return token, CredentialStorePath(), nil
+	}
+
+	// 3. Check .netrc file
 	if token := getTokenFromNetrc(); token != "" {
-		return token, nil
+		return token, homePath(".netrc"), nil
 	}
 
-	// 3. Check .git-credentials
+	// 4. Check .git-credentials
 	if token := getTokenFromGitCredentials(); token != "" {
-		return token, nil
+		return token, homePath(".git-credentials"), nil
 	}
 
-	return "", fmt.Errorf("no GitLab token found. Set GITLAB_TOKEN environment variable or configure ~/.netrc or ~/.git-credentials")
+	return "", "", ErrNoToken
 }
 
-func getTokenFromNetrc() string {
+// homePath returns name in the home directory, or "" when there is none
+func homePath(name string) string {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return ""
 	}
+	return filepath.Join(home, name)
+}
 
-	netrcPath := filepath.Join(home, ".netrc")
-	file, err := os.Open(netrcPath)
+func getTokenFromNetrc() string {
+	netrcPath := homePath(".netrc")
+	if netrcPath == "" {
+		return ""
+	}
+	data, err := os.ReadFile(netrcPath)
 	if err != nil {
 		return ""
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	var inGitlab bool
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		fields := strings.Fields(line)
-
-		for i := 0; i < len(fields); i++ {
-			switch fields[i] {
-			case "machine":
-				if i+1 < len(fields) && strings.Contains(fields[i+1], "gitlab") {
-					inGitlab = true
-				} else {
-					inGitlab = false
+	return netrcPassword(string(data), GitLabHost())
+}
+
+// netrcPassword returns the password of the .netrc entry for host, falling
+// back to the default entry
+func netrcPassword(data, host string) string {
+	var entries []netrcEntry
+	var cur *netrcEntry
+	tokens := netrcTokens(data)
+	for i := 0; i < len(tokens); i++ {
+		next := func() string {
+			if i+1 < len(tokens) {
+				i++
+				return tokens[i]
+			}
+			return ""
+		}
+		switch tokens[i] {
+		case "machine":
+			entries = append(entries, netrcEntry{machine: next()})
+			cur = &entries[len(entries)-1]
+		case "default":
+			entries = append(entries, netrcEntry{isDefault: true})
+			cur = &entries[len(entries)-1]
+		case "login", "account":
+			next()
+		case "password":
+			if p := next(); cur != nil {
+				cur.password = p
+			}
+		}
+	}
+
+	for _, e := range entries {
+		if !e.isDefault && strings.EqualFold(e.machine, host) {
+			return e.password
+		}
+	}
+	for _, e := range entries {
+		if e.isDefault {
+			return e.password
+		}
+	}
+	return ""
+}
+
+type netrcEntry struct {
+	machine   string
+	isDefault bool
+	password  string
+}
+
+// netrcTokens splits .netrc content into tokens. Values may be quoted with
+// backslash escapes, # starts a comment at the beginning of a line, and
+// macdef bodies (up to the next blank line) are skipped.
+func netrcTokens(data string) []string {
+	var tokens []string
+	lines := strings.Split(data, "\n")
+	for n := 0; n < len(lines); n++ {
+		line := lines[n]
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for rest := line; ; {
+			rest = strings.TrimLeft(rest, " \t\r")
+			if rest == "" {
+				break
+			}
+			var tok string
+			if rest[0] == '"' {
+				var b strings.Builder
+				i := 1
+				for ; i < len(rest) && rest[i] != '"'; i++ {
+					if rest[i] == '\\' && i+1 < len(rest) {
+						i++
+					}
+					b.WriteByte(rest[i])
 				}
-			case "password":
-				if inGitlab && i+1 < len(fields) {
-					return fields[i+1]
+				tok, rest = b.String(), rest[min(i+1, len(rest)):]
+			} else {
+				end := strings.IndexAny(rest, " \t\r")
+				if end < 0 {
+					end = len(rest)
+				}
+				tok, rest = rest[:end], rest[end:]
+			}
+			tokens = append(tokens, tok)
+			if tok == "macdef" {
+				// The macro name ends the line; its body runs to a blank line
+				for n++; n < len(lines) && strings.TrimSpace(lines[n]) != ""; n++ {
 				}
+				break
 			}
 		}
 	}
-	return ""
+	return tokens
 }
 
 func getTokenFromGitCredentials() string {
-	home, err := os.UserHomeDir()
-	if err != nil {
+	credPath := homePath(".git-credentials")
+	if credPath == "" {
 		return ""
 	}
-
-	credPath := filepath.Join(home, ".git-credentials")
-	file, err := os.Open(credPath)
+	data, err := os.ReadFile(credPath)
 	if err != nil {
 		return ""
 	}
-	defer file.Close()
+	return gitCredentialsPassword(string(data), GitLabHost())
+}
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if strings.Contains(line, "gitlab") {
-			u, err := url.Parse(line)
-			if err != nil {
-				continue
-			}
-			if password, ok := u.User.Password(); ok {
-				return password
-			}
+// gitCredentialsPassword returns the password of the first .git-credentials
+// URL on host
+func gitCredentialsPassword(data, host string) string {
+	for _, line := range strings.Split(data, "\n") {
+		u, err := url.Parse(strings.TrimSpace(line))
+		if err != nil || !strings.EqualFold(u.Hostname(), host) {
+			continue
+		}
+		if password, ok := u.User.Password(); ok {
+			return password
 		}
 	}
 	return ""
 }
+
+// UpdateStoredToken replaces oldToken with newToken in the credential file
+// at path (.netrc, .git-credentials or the encrypted credential store),
+// keeping its permissions
+func UpdateStoredToken(path, oldToken, newToken string) error {
+	if path == CredentialStorePath() {
+		return updateStoredToken(GitLabHost(), oldToken, newToken)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if !strings.Contains(string(data), oldToken) {
+		return fmt.Errorf("%w: token not found in %s", ErrNotFound, path)
+	}
+	updated := strings.ReplaceAll(string(data), oldToken, newToken)
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(updated), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package lib
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+// CheckMergeRef reports whether GitLab can merge an MR into its target,
+// from the merge ref (refs/merge-requests/:iid/merge) it keeps up to date.
+// has_conflicts lags behind pushes to either branch while GitLab rechecks
+// the MR; the merge ref fails as soon as the merge does.
+func (c *Client) CheckMergeRef(projectPath string, mrIID int) (mergeable bool, err error) {
+	endpoint := c.apiURL("/projects/%s/merge_requests/%d/merge_ref", url.PathEscape(projectPath), mrIID)
+	err = c.do("GET", endpoint, nil, nil, http.StatusOK)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// OverlappingPaths returns the sorted paths changed by both diff sets, by
+// their old or new name: the files an MR and its target branch both
+// touched since their merge base, where conflicts can be
+func OverlappingPaths(a, b []Diff) []string {
+	seen := make(map[string]bool)
+	for _, d := range a {
+		seen[d.OldPath] = true
+		seen[d.NewPath] = true
+	}
+
+	set := make(map[string]bool)
+	for _, d := range b {
+		if seen[d.OldPath] {
+			set[d.OldPath] = true
+		}
+		if seen[d.NewPath] {
+			set[d.NewPath] = true
+		}
+	}
+
+	paths := []string{}
+	for p := range set {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
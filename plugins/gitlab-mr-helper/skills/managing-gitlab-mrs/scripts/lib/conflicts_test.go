@@ -0,0 +1,72 @@
+package lib_test
+
+import (
+	"reflect"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestCheckMergeRef(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	if ok, err := client.CheckMergeRef(gitlabtest.ProjectPath, 1); err != nil || !ok {
+		t.Errorf("CheckMergeRef(!1) = %v, %v; want mergeable", ok, err)
+	}
+	if ok, err := client.CheckMergeRef(gitlabtest.ProjectPath, 2); err != nil || ok {
+		t.Errorf("CheckMergeRef(!2) = %v, %v; want conflicts", ok, err)
+	}
+	_, err := client.CheckMergeRef(gitlabtest.ProjectPath, 99)
+	wantExit(t, err, lib.ExitNotFound)
+}
+
+func TestOverlappingPaths(t *testing.T) {
+	tests := []struct {
+		name   string
+		mr     []lib.Diff
+		target []lib.Diff
+		want   []string
+	}{
+		{name: "no changes", want: []string{}},
+		{
+			name:   "disjoint",
+			mr:     []lib.Diff{{OldPath: "a.go", NewPath: "a.go"}},
+			target: []lib.Diff{{OldPath: "b.go", NewPath: "b.go"}},
+			want:   []string{},
+		},
+		{
+			name:   "same files sorted",
+			mr:     []lib.Diff{{OldPath: "z.go", NewPath: "z.go"}, {OldPath: "a.go", NewPath: "a.go"}, {OldPath: "m.go", NewPath: "m.go"}},
+			target: []lib.Diff{{OldPath: "m.go", NewPath: "m.go"}, {OldPath: "a.go", NewPath: "a.go"}, {OldPath: "z.go", NewPath: "z.go"}},
+			want:   []string{"a.go", "m.go", "z.go"},
+		},
+		{
+			name:   "renamed on the MR",
+			mr:     []lib.Diff{{OldPath: "old.go", NewPath: "new.go", RenamedFile: true}},
+			target: []lib.Diff{{OldPath: "old.go", NewPath: "old.go"}},
+			want:   []string{"old.go"},
+		},
+		{
+			name:   "renamed on the target",
+			mr:     []lib.Diff{{OldPath: "old.go", NewPath: "old.go"}},
+			target: []lib.Diff{{OldPath: "old.go", NewPath: "new.go", RenamedFile: true}},
+			want:   []string{"old.go"},
+		},
+		{
+			name:   "added on both sides",
+			mr:     []lib.Diff{{OldPath: "new.go", NewPath: "new.go", NewFile: true}},
+			target: []lib.Diff{{OldPath: "new.go", NewPath: "new.go", NewFile: true}},
+			want:   []string{"new.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lib.OverlappingPaths(tt.mr, tt.target); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("OverlappingPaths = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package lib
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Credential store backends
+const (
+	BackendPassphrase = "passphrase" // AES-256-GCM, key derived from a passphrase
+	BackendKeychain   = "keychain"   // macOS Keychain or the Secret Service (secret-tool)
+)
+
+// kdfIterations is the PBKDF2-HMAC-SHA256 work factor for new entries
+const kdfIterations = 600000
+
+// keychainService names the tokens in the OS keychain
+const keychainService = "gitlab-helper"
+
+// StoredCredential is one host's entry in the credential store. Keychain
+// entries hold no secret; the token lives in the OS keychain.
+type StoredCredential struct {
+	Backend    string    `json:"backend"`
+	Salt       []byte    `json:"salt,omitempty"`
+	Nonce      []byte    `json:"nonce,omitempty"`
+	Ciphertext []byte    `json:"ciphertext,omitempty"`
+	Iterations int       `json:"iterations,omitempty"`
+	StoredAt   time.Time `json:"stored_at"`
+}
+
+type credentialStore struct {
+	Hosts map[string]*StoredCredential `json:"hosts"`
+}
+
+// unlockedPassphrase is remembered after the store is opened, so the token
+// can be read and a rotated one stored again without another prompt
+var unlockedPassphrase []byte
+
+// CredentialStorePath returns the credential store written by auth.go,
+// credentials.json next to the user settings file
+func CredentialStorePath() string {
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		configDir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configDir, "gitlab-helper", "credentials.json")
+}
+
+// StoredCredentials returns the entries of the credential store by host
+func StoredCredentials() (map[string]*StoredCredential, error) {
+	store, err := readCredentialStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.Hosts, nil
+}
+
+// StoreToken saves token for host, encrypted with passphrase or, for
+// BackendKeychain, in the OS keychain
+func StoreToken(host, token, backend string, passphrase []byte) error {
+	entry := &StoredCredential{Backend: backend, StoredAt: time.Now().UTC()}
+	switch backend {
+	case BackendPassphrase:
+		if len(passphrase) == 0 {
+			return UsageErrorf("an empty passphrase cannot protect the token")
+		}
+		if err := entry.seal(host, token, passphrase); err != nil {
+			return err
+		}
+	case BackendKeychain:
+		if err := keychainSet(host, token); err != nil {
+			return err
+		}
+	default:
+		return UsageErrorf("unknown credential backend %q", backend)
+	}
+
+	store, err := readCredentialStore()
+	if err != nil {
+		return err
+	}
+	store.Hosts[host] = entry
+	return writeCredentialStore(store)
+}
+
+// DeleteStoredToken removes host from the credential store and the OS
+// keychain. It returns ErrNotFound when there is no entry.
+func DeleteStoredToken(host string) error {
+	store, err := readCredentialStore()
+	if err != nil {
+		return err
+	}
+	entry, ok := store.Hosts[host]
+	if !ok {
+		return fmt.Errorf("%w: no stored token for %s", ErrNotFound, host)
+	}
+	if entry.Backend == BackendKeychain {
+		if err := keychainDelete(host); err != nil {
+			return err
+		}
+	}
+	delete(store.Hosts, host)
+	return writeCredentialStore(store)
+}
+
+// loadStoredToken returns the stored token for host, "" when there is
+// none. Passphrase entries are unlocked with GITLAB_HELPER_PASSPHRASE or a
+// prompt on the terminal.
+func loadStoredToken(host string) (string, error) {
+	store, err := readCredentialStore()
+	if err != nil {
+		return "", err
+	}
+	entry, ok := store.Hosts[host]
+	if !ok {
+		return "", nil
+	}
+	switch entry.Backend {
+	case BackendKeychain:
+		token, err := keychainGet(host)
+		if err != nil {
+			return "", fmt.Errorf("%v (%w)", err, ErrNoToken)
+		}
+		return token, nil
+	case BackendPassphrase:
+		passphrase := unlockedPassphrase
+		if passphrase == nil {
+			if passphrase, err = Passphrase(fmt.Sprintf("Passphrase for the %s token: ", host), false); err != nil {
+				return "", fmt.Errorf("stored token for %s is locked: %v (%w)", host, err, ErrNoToken)
+			}
+		}
+		token, err := entry.open(host, passphrase)
+		if err != nil {
+			return "", fmt.Errorf("stored token for %s: %v (%w)", host, err, ErrNoToken)
+		}
+		unlockedPassphrase = passphrase
+		return token, nil
+	}
+	return "", fmt.Errorf("unknown credential backend %q for %s in %s", entry.Backend, host, CredentialStorePath())
+}
+
+// updateStoredToken stores newToken in place of oldToken for host, with the
+// entry's backend and the passphrase it was unlocked with
+func updateStoredToken(host, oldToken, newToken string) error {
+	token, err := loadStoredToken(host)
+	if err != nil {
+		return err
+	}
+	if token != oldToken {
+		return fmt.Errorf("%w: token not found in %s", ErrNotFound, CredentialStorePath())
+	}
+	store, err := readCredentialStore()
+	if err != nil {
+		return err
+	}
+	return StoreToken(host, newToken, store.Hosts[host].Backend, unlockedPassphrase)
+}
+
+// Passphrase returns GITLAB_HELPER_PASSPHRASE, or reads a passphrase from
+// the terminal without echo. With confirm, it is asked for twice.
+func Passphrase(prompt string, confirm bool) ([]byte, error) {
+	if p := os.Getenv("GITLAB_HELPER_PASSPHRASE"); p != "" {
+		return []byte(p), nil
+	}
+	p, err := ReadSecret(prompt)
+	if err != nil {
+		return nil, err
+	}
+	if confirm {
+		again, err := ReadSecret("Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if again != p {
+			return nil, UsageErrorf("passphrases do not match")
+		}
+	}
+	return []byte(p), nil
+}
+
+// ReadSecret prompts on the controlling terminal and reads a line with
+// echo turned off
+func ReadSecret(prompt string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", errors.New("no terminal to prompt on; set GITLAB_HELPER_PASSPHRASE")
+	}
+	defer tty.Close()
+
+	stty := func(arg string) error {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = tty
+		return cmd.Run()
+	}
+	fmt.Fprint(tty, prompt)
+	if err := stty("-echo"); err != nil {
+		return "", fmt.Errorf("failed to turn off terminal echo: %w", err)
+	}
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	stty("echo")
+	fmt.Fprintln(tty)
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (e *StoredCredential) seal(host, token string, passphrase []byte) error {
+	e.Iterations = kdfIterations
+	e.Salt = make([]byte, 16)
+	if _, err := rand.Read(e.Salt); err != nil {
+		return err
+	}
+	gcm, err := e.cipher(passphrase)
+	if err != nil {
+		return err
+	}
+	e.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(e.Nonce); err != nil {
+		return err
+	}
+	// The host is authenticated too, so an entry cannot be moved to another
+	e.Ciphertext = gcm.Seal(nil, e.Nonce, []byte(token), []byte(host))
+	return nil
+}
+
+func (e *StoredCredential) open(host string, passphrase []byte) (string, error) {
+	gcm, err := e.cipher(passphrase)
+	if err != nil {
+		return "", err
+	}
+	token, err := gcm.Open(nil, e.Nonce, e.Ciphertext, []byte(host))
+	if err != nil {
+		return "", errors.New("wrong passphrase or corrupted credential store")
+	}
+	return string(token), nil
+}
+
+func (e *StoredCredential) cipher(passphrase []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2SHA256(passphrase, e.Salt, e.Iterations, 32))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2SHA256 derives a key as in RFC 8018 section 5.2
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		key = append(key, pbkdf2Block(prf, salt, iterations, block)...)
+	}
+	return key[:keyLen]
+}
+
+func pbkdf2Block(prf hash.Hash, salt []byte, iterations int, block uint32) []byte {
+	prf.Reset()
+	prf.Write(salt)
+	prf.Write(binary.BigEndian.AppendUint32(nil, block))
+	u := prf.Sum(nil)
+	t := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+		for j := range t {
+			t[j] ^= u[j]
+		}
+	}
+	return t
+}
+
+func readCredentialStore() (*credentialStore, error) {
+	store := &credentialStore{Hosts: map[string]*StoredCredential{}}
+	path := CredentialStorePath()
+	if path == "" {
+		return store, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("invalid credential store %s: %w", path, err)
+	}
+	if store.Hosts == nil {
+		store.Hosts = map[string]*StoredCredential{}
+	}
+	return store, nil
+}
+
+func writeCredentialStore(store *credentialStore) error {
+	path := CredentialStorePath()
+	if path == "" {
+		return errors.New("no home directory for the credential store")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// keychainSet stores the token with the macOS security tool or, elsewhere,
+// the Secret Service's secret-tool. Neither gets the token as an argument,
+// where other local users could read it in ps: security takes the whole
+// command on stdin in interactive mode, secret-tool the secret.
+func keychainSet(host, token string) error {
+	if runtime.GOOS != "darwin" {
+		cmd := exec.Command("secret-tool", "store", "--label", "GitLab token ("+host+")", "service", keychainService, "host", host)
+		cmd.Stdin = strings.NewReader(token)
+		return runKeychain(cmd)
+	}
+
+	if strings.ContainsAny(token, "\r\n") {
+		return errors.New("token contains a line break")
+	}
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		securityQuote(keychainService), securityQuote(host), securityQuote(token)))
+	if err := runKeychain(cmd); err != nil {
+		return err
+	}
+	// Interactive mode exits 0 even when the command fails
+	if stored, err := keychainGet(host); err != nil || stored != token {
+		return fmt.Errorf("security could not store the token for %s in the keychain", host)
+	}
+	return nil
+}
+
+// securityQuote quotes an argument for a command line of `security -i`
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func keychainGet(host string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "find-generic-password", "-s", keychainService, "-a", host, "-w")
+	} else {
+		cmd = exec.Command("secret-tool", "lookup", "service", keychainService, "host", host)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("no token for %s in the OS keychain (%s: %v)", host, cmd.Args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func keychainDelete(host string) error {
+	if runtime.GOOS == "darwin" {
+		return runKeychain(exec.Command("security", "delete-generic-password", "-s", keychainService, "-a", host))
+	}
+	return runKeychain(exec.Command("secret-tool", "clear", "service", keychainService, "host", host))
+}
+
+func runKeychain(cmd *exec.Cmd) error {
+	if _, err := exec.LookPath(cmd.Args[0]); err != nil {
+		return fmt.Errorf("no OS keychain available: %s not found (on Linux install libsecret-tools)", cmd.Args[0])
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %v: %s", cmd.Args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
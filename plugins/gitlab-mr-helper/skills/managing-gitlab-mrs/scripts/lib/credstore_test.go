@@ -0,0 +1,96 @@
+package lib_test
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestCredentialStore(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	t.Setenv("GITLAB_URL", "https://gitlab.example.com")
+	t.Setenv("GITLAB_TOKEN", "")
+	t.Setenv("GITLAB_TOKEN_WARN_DAYS", "0")
+	// The store takes precedence over plaintext files
+	if err := os.WriteFile(filepath.Join(home, ".netrc"), []byte("machine gitlab.example.com password glpat-netrc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lib.StoreToken("gitlab.example.com", "glpat-stored", lib.BackendPassphrase, []byte("correct horse")); err != nil {
+		t.Fatalf("StoreToken: %v", err)
+	}
+	if err := lib.StoreToken("gitlab.example.com", "glpat-stored", lib.BackendPassphrase, nil); err == nil {
+		t.Error("StoreToken with an empty passphrase: want error")
+	}
+	path := lib.CredentialStorePath()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("store mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// Before the store is unlocked once: the passphrase is remembered after
+	t.Setenv("GITLAB_HELPER_PASSPHRASE", "wrong")
+	_, err = lib.GetConfig()
+	wantExit(t, err, lib.ExitAuth)
+
+	t.Setenv("GITLAB_HELPER_PASSPHRASE", "correct horse")
+	config, err := lib.GetConfig()
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	if config.Token != "glpat-stored" || config.TokenSource != path {
+		t.Errorf("token %q from %s, want glpat-stored from %s", config.Token, config.TokenSource, path)
+	}
+
+	// Entries are per host; neither file has one for gitlab.com
+	t.Setenv("GITLAB_URL", "https://gitlab.com")
+	_, err = lib.GetConfig()
+	wantExit(t, err, lib.ExitAuth)
+	t.Setenv("GITLAB_URL", "https://gitlab.example.com")
+
+	if err := lib.UpdateStoredToken(path, "glpat-stored", "glpat-rotated"); err != nil {
+		t.Fatalf("UpdateStoredToken: %v", err)
+	}
+	if config, err := lib.GetConfig(); err != nil || config.Token != "glpat-rotated" {
+		t.Errorf("after rotation: %+v, %v", config, err)
+	}
+
+	if err := lib.DeleteStoredToken("gitlab.example.com"); err != nil {
+		t.Fatalf("DeleteStoredToken: %v", err)
+	}
+	wantExit(t, lib.DeleteStoredToken("gitlab.example.com"), lib.ExitNotFound)
+	if config, err := lib.GetConfig(); err != nil || config.Token != "glpat-netrc" {
+		t.Errorf("after logout: %+v, %v", config, err)
+	}
+}
+
+// TestPBKDF2SHA256 checks the key derivation against the PBKDF2-HMAC-SHA256
+// vectors of RFC 7914 section 11, so stores stay readable by other tools
+func TestPBKDF2SHA256(t *testing.T) {
+	tests := []struct {
+		password, salt string
+		iterations     int
+		want           string
+	}{
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	}
+	for _, tt := range tests {
+		got := hex.EncodeToString(lib.PBKDF2SHA256([]byte(tt.password), []byte(tt.salt), tt.iterations, 64))
+		if got != tt.want {
+			t.Errorf("PBKDF2(%q, %q, %d) = %s, want %s", tt.password, tt.salt, tt.iterations, got, tt.want)
+		}
+	}
+	// Keys shorter than a block are its prefix
+	if got := hex.EncodeToString(lib.PBKDF2SHA256([]byte("passwd"), []byte("salt"), 1, 32)); got != tests[0].want[:64] {
+		t.Errorf("32-byte key = %s, want %s", got, tests[0].want[:64])
+	}
+}
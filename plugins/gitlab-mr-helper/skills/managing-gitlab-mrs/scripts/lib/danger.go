@@ -0,0 +1,352 @@
+package lib
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DangerFileName is the repository's MR review rules file, looked up at the
+// root of the current git work tree
+const DangerFileName = ".gitlab-helper-danger.yml"
+
+// Levels of danger rules: failures block the MR, warnings and messages
+// only inform
+const (
+	DangerFail    = "fail"
+	DangerWarn    = "warn"
+	DangerMessage = "message"
+)
+
+// DangerRules are checks of an MR's changes, in the spirit of Danger, e.g.
+//
+//	rules:
+//	  - name: Migrations
+//	    files: ["db/migrate/**"]
+//	    message: "Migrations need a review by @dba"
+//	  - name: Tests
+//	    missing_tests: true
+//	    level: fail
+type DangerRules struct {
+	Rules []DangerRule `json:"rules"`
+}
+
+// DangerRule reports its message when all of its conditions hold
+type DangerRule struct {
+	Name string `json:"name"`
+	// Level is DangerFail, DangerWarn (default) or DangerMessage
+	Level   string `json:"level"`
+	Message string `json:"message"`
+
+	// Files are globs ("**" spans directories, patterns without a slash
+	// match base names); the rule needs a changed file matching one, and
+	// the other conditions look only at those files
+	Files []string `json:"files"`
+	// Require holds when no changed file matches these globs, e.g. a
+	// changelog or lock file that should change along
+	Require []string `json:"require"`
+	// MaxLines holds when more lines are added and removed, and MaxFiles
+	// when more files are changed
+	MaxLines int `json:"max_lines"`
+	MaxFiles int `json:"max_files"`
+	// AddedPattern is a regular expression that holds when an added line
+	// matches it, e.g. "console\\.log|debugger"
+	AddedPattern string `json:"added_pattern"`
+	// MissingTests holds when source files change but no test files do
+	MissingTests bool `json:"missing_tests"`
+	// SkipLabels turn the rule off for MRs with one of these labels, e.g.
+	// "no-changelog"
+	SkipLabels []string `json:"skip_labels"`
+
+	added *regexp.Regexp
+}
+
+// DangerResult is a rule that fired
+type DangerResult struct {
+	Rule    string
+	Level   string
+	Message string
+}
+
+// LoadDangerRules reads a rules file. An empty path looks for
+// DangerFileName at the repository root; it returns nil rules when there
+// is none.
+func LoadDangerRules(file string) (*DangerRules, error) {
+	data, file, err := readRepoFile(file, DangerFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read danger rules: %w", err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	rules := &DangerRules{}
+	if err := DecodeYAML(data, rules); err != nil {
+		return nil, fmt.Errorf("invalid danger rules %s: %w", file, err)
+	}
+	if err := rules.validate(); err != nil {
+		return nil, fmt.Errorf("invalid danger rules %s: %w", file, err)
+	}
+	return rules, nil
+}
+
+func (d *DangerRules) validate() error {
+	for i := range d.Rules {
+		r := &d.Rules[i]
+		if r.Name == "" {
+			r.Name = "rule " + strconv.Itoa(i+1)
+		}
+		switch r.Level {
+		case "":
+			r.Level = DangerWarn
+		case DangerFail, DangerWarn, DangerMessage:
+		default:
+			return fmt.Errorf("%s: level must be fail, warn or message, not %q", r.Name, r.Level)
+		}
+		if len(r.Files) == 0 && len(r.Require) == 0 && r.MaxLines == 0 && r.MaxFiles == 0 && r.AddedPattern == "" && !r.MissingTests {
+			return fmt.Errorf("%s: needs a condition (files, require, max_lines, max_files, added_pattern or missing_tests)", r.Name)
+		}
+		for _, pattern := range append(append([]string{}, r.Files...), r.Require...) {
+			if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+				return fmt.Errorf("%s: invalid pattern %q", r.Name, pattern)
+			}
+		}
+		if r.AddedPattern != "" {
+			re, err := regexp.Compile(r.AddedPattern)
+			if err != nil {
+				return fmt.Errorf("%s: invalid added_pattern: %w", r.Name, err)
+			}
+			r.added = re
+		}
+	}
+	return nil
+}
+
+// Evaluate checks the rules against an MR's labels and changes, returning
+// the rules that fired: failures first, then warnings and messages
+func (d *DangerRules) Evaluate(mr *MergeRequest, diffs []Diff) []DangerResult {
+	var results []DangerResult
+	for i := range d.Rules {
+		r := &d.Rules[i]
+		if r.skipped(mr.Labels) {
+			continue
+		}
+		if detail, fired := r.check(diffs); fired {
+			message := r.Message
+			if message == "" {
+				message = detail
+			} else if detail != "" {
+				message += " (" + detail + ")"
+			}
+			results = append(results, DangerResult{Rule: r.Name, Level: r.Level, Message: message})
+		}
+	}
+	rank := map[string]int{DangerFail: 0, DangerWarn: 1, DangerMessage: 2}
+	slices.SortStableFunc(results, func(a, b DangerResult) int { return rank[a.Level] - rank[b.Level] })
+	return results
+}
+
+func (r *DangerRule) skipped(labels []string) bool {
+	for _, l := range r.SkipLabels {
+		if ContainsString(labels, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// check reports whether all of the rule's conditions hold for the changed
+// files, with what made them hold
+func (r *DangerRule) check(diffs []Diff) (string, bool) {
+	var details []string
+
+	files := diffs
+	if len(r.Files) > 0 {
+		files = nil
+		for _, d := range diffs {
+			if matchAnyGlob(r.Files, diffPaths(d)...) {
+				files = append(files, d)
+			}
+		}
+		if len(files) == 0 {
+			return "", false
+		}
+		details = append(details, changedFiles(files))
+	}
+
+	if len(r.Require) > 0 {
+		for _, d := range diffs {
+			if matchAnyGlob(r.Require, diffPaths(d)...) {
+				return "", false
+			}
+		}
+		details = append(details, "no change to "+strings.Join(r.Require, ", "))
+	}
+
+	if r.MaxFiles > 0 {
+		if len(files) <= r.MaxFiles {
+			return "", false
+		}
+		details = append(details, fmt.Sprintf("%d files changed, more than %d", len(files), r.MaxFiles))
+	}
+
+	if r.MaxLines > 0 {
+		added, removed := 0, 0
+		for _, d := range files {
+			a, rm := DiffLineCounts(d.Diff)
+			added, removed = added+a, removed+rm
+		}
+		if added+removed <= r.MaxLines {
+			return "", false
+		}
+		details = append(details, fmt.Sprintf("+%d/-%d lines, more than %d", added, removed, r.MaxLines))
+	}
+
+	if r.added != nil {
+		var hits []string
+		for _, d := range files {
+			for _, line := range strings.Split(d.Diff, "\n") {
+				if strings.HasPrefix(line, "+") && r.added.MatchString(line[1:]) {
+					hits = append(hits, d.NewPath)
+					break
+				}
+			}
+		}
+		if len(hits) == 0 {
+			return "", false
+		}
+		details = append(details, "added lines match in "+strings.Join(hits, ", "))
+	}
+
+	if r.MissingTests {
+		var sources []Diff
+		for _, d := range files {
+			if !d.DeletedFile && isSourceFile(d.NewPath) && !IsTestFile(d.NewPath) {
+				sources = append(sources, d)
+			}
+		}
+		// Tests outside the rule's files count too
+		for _, d := range diffs {
+			if IsTestFile(d.NewPath) {
+				return "", false
+			}
+		}
+		if len(sources) == 0 {
+			return "", false
+		}
+		details = append(details, "source changes without tests: "+changedFiles(sources))
+	}
+	return strings.Join(details, "; "), true
+}
+
+// changedFiles lists the new paths of diffs, the first few of them
+func changedFiles(diffs []Diff) string {
+	const shown = 5
+	var names []string
+	for i, d := range diffs {
+		if i == shown {
+			names = append(names, fmt.Sprintf("and %d more", len(diffs)-shown))
+			break
+		}
+		names = append(names, d.NewPath)
+	}
+	return strings.Join(names, ", ")
+}
+
+// diffPaths returns the paths a diff touches: both for renames
+func diffPaths(d Diff) []string {
+	if d.OldPath != "" && d.OldPath != d.NewPath {
+		return []string{d.NewPath, d.OldPath}
+	}
+	return []string{d.NewPath}
+}
+
+// DiffLineCounts returns the lines a unified diff adds and removes
+func DiffLineCounts(diff string) (added, removed int) {
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "--- "):
+			// File headers of git diffs
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// MatchGlob matches a slash-separated path against a glob in which "**"
+// stands for any number of directories; other segments follow path.Match.
+// A pattern without a slash matches the base name.
+func MatchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchGlobSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchGlobSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchGlobSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+func matchAnyGlob(patterns []string, names ...string) bool {
+	for _, p := range patterns {
+		for _, name := range names {
+			if MatchGlob(p, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// testDirs are directories that hold tests
+var testDirs = map[string]bool{"test": true, "tests": true, "__tests__": true, "spec": true, "testdata": true}
+
+// IsTestFile guesses whether a path is a test by the common conventions:
+// foo_test.go, foo.test.ts, foo.spec.js, test_foo.py, FooTest.java,
+// foo_spec.rb, and files under test/, tests/, __tests__/ or spec/
+func IsTestFile(p string) bool {
+	dir, base := path.Split(p)
+	for _, segment := range strings.Split(strings.Trim(dir, "/"), "/") {
+		if testDirs[segment] {
+			return true
+		}
+	}
+	name := strings.TrimSuffix(base, path.Ext(base))
+	return strings.HasSuffix(name, "_test") || strings.HasSuffix(name, ".test") || strings.HasSuffix(name, ".spec") ||
+		strings.HasPrefix(name, "test_") || strings.HasSuffix(name, "_spec") ||
+		strings.HasSuffix(name, "Test") || strings.HasSuffix(name, "Tests")
+}
+
+// sourceExts are the extensions of code that missing_tests expects tests for
+var sourceExts = map[string]bool{
+	".go": true, ".py": true, ".rb": true, ".js": true, ".jsx": true, ".ts": true, ".tsx": true,
+	".java": true, ".kt": true, ".scala": true, ".cs": true, ".php": true, ".rs": true,
+	".c": true, ".cc": true, ".cpp": true, ".h": true, ".swift": true,
+}
+
+func isSourceFile(p string) bool {
+	return sourceExts[path.Ext(p)]
+}
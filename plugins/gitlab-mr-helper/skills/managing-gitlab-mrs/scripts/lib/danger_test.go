@@ -0,0 +1,137 @@
+package lib_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.md", "docs/guide/README.md", true},
+		{"CHANGELOG.md", "CHANGELOG.md", true},
+		{"db/migrate/**", "db/migrate/2024/001_users.rb", true},
+		{"db/migrate/**", "db/seeds.rb", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "cmd/tool/main.go", true},
+		{"src/**/api/*.ts", "src/api/client.ts", true},
+		{"src/**/api/*.ts", "src/v2/internal/api/client.ts", true},
+		{"src/*.ts", "src/api/client.ts", false},
+	}
+	for _, tt := range tests {
+		if got := lib.MatchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsTestFile(t *testing.T) {
+	for _, p := range []string{"lib/api_test.go", "src/login.test.ts", "web/app.spec.js", "tests/conftest.py", "test_login.py",
+		"src/main/java/LoginTest.java", "spec/models/user_spec.rb", "web/__tests__/login.jsx"} {
+		if !lib.IsTestFile(p) {
+			t.Errorf("IsTestFile(%q) = false", p)
+		}
+	}
+	for _, p := range []string{"lib/api.go", "src/testing.ts", "contest/app.py", "web/login.html"} {
+		if lib.IsTestFile(p) {
+			t.Errorf("IsTestFile(%q) = true", p)
+		}
+	}
+}
+
+func TestDiffLineCounts(t *testing.T) {
+	diff := "--- a/app.go\n+++ b/app.go\n@@ -1,3 +1,3 @@\n package main\n-var x = 1\n+var x = 2\n+var y = 3\n"
+	if added, removed := lib.DiffLineCounts(diff); added != 2 || removed != 1 {
+		t.Errorf("DiffLineCounts = +%d/-%d, want +2/-1", added, removed)
+	}
+}
+
+func TestDangerRules(t *testing.T) {
+	rules, err := lib.LoadDangerRules(writeRules(t, `rules:
+  - name: Changelog
+    require: [CHANGELOG.md]
+    skip_labels: [no-changelog]
+  - name: Migrations
+    files: ["db/migrate/**"]
+    message: Migrations need a review by @dba
+    level: message
+  - name: Debug
+    files: ["*.js"]
+    added_pattern: "console\\.log|debugger"
+    level: fail
+  - name: Size
+    max_lines: 3
+  - name: Many files
+    max_files: 10
+  - name: Tests
+    missing_tests: true
+    level: fail
+`))
+	if err != nil {
+		t.Fatalf("LoadDangerRules: %v", err)
+	}
+
+	diffs := []lib.Diff{
+		{NewPath: "db/migrate/001_users.rb", Diff: "+create_table :users\n"},
+		{NewPath: "web/app.js", Diff: "@@ -1 +1,2 @@\n-init()\n+init()\n+console.log('ready')\n"},
+	}
+	mr := &lib.MergeRequest{}
+	got := rules.Evaluate(mr, diffs)
+	want := []struct{ rule, level, message string }{
+		{"Debug", lib.DangerFail, "web/app.js; added lines match in web/app.js"},
+		{"Tests", lib.DangerFail, "source changes without tests: db/migrate/001_users.rb, web/app.js"},
+		{"Changelog", lib.DangerWarn, "no change to CHANGELOG.md"},
+		{"Size", lib.DangerWarn, "+3/-1 lines, more than 3"},
+		{"Migrations", lib.DangerMessage, "Migrations need a review by @dba (db/migrate/001_users.rb)"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Evaluate = %+v, want %d results", got, len(want))
+	}
+	for i, w := range want {
+		if got[i].Rule != w.rule || got[i].Level != w.level || got[i].Message != w.message {
+			t.Errorf("result %d = %+v, want %+v", i, got[i], w)
+		}
+	}
+
+	// A changelog entry, a skip label and tests quiet the rules down
+	mr.Labels = []string{"no-changelog"}
+	diffs = append(diffs, lib.Diff{NewPath: "web/app.test.js", Diff: "+test('ready')\n"})
+	got = rules.Evaluate(mr, diffs)
+	var names []string
+	for _, r := range got {
+		names = append(names, r.Rule)
+	}
+	if strings.Join(names, ",") != "Debug,Size,Migrations" {
+		t.Errorf("with tests and no-changelog: fired %v, want Debug, Size, Migrations", names)
+	}
+}
+
+func TestLoadDangerRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "bad level", content: "rules:\n  - name: x\n    level: error\n    max_lines: 10\n", wantErr: "x: level must be"},
+		{name: "no condition", content: "rules:\n  - name: x\n    message: hi\n", wantErr: "x: needs a condition"},
+		{name: "bad pattern", content: "rules:\n  - files: [\"[\"]\n", wantErr: `rule 1: invalid pattern "["`},
+		{name: "bad regexp", content: "rules:\n  - name: x\n    added_pattern: \"(\"\n", wantErr: "x: invalid added_pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := lib.LoadDangerRules(writeRules(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadDangerRules error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := lib.LoadDangerRules(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+		t.Error("LoadDangerRules of a missing explicit file succeeded")
+	}
+}
@@ -0,0 +1,93 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// redactedHeaders are never printed in debug traces
+var redactedHeaders = map[string]bool{
+	"Private-Token": true,
+	"Authorization": true,
+	"Job-Token":     true,
+	"Sudo":          true, // the admin acting on the token's behalf
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+// traceResponseHeaders are the response headers worth showing when
+// diagnosing a misbehaving instance
+var traceResponseHeaders = []string{
+	"Content-Type",
+	"X-Request-Id",
+	"X-Gitlab-Meta",
+	"Ratelimit-Remaining",
+	"Retry-After",
+	"Location",
+}
+
+// debugTransport logs every request and response to out
+type debugTransport struct {
+	next http.RoundTripper
+	out  io.Writer
+}
+
+// NewDebugTransport wraps next to trace every request and response to out,
+// with credential headers redacted
+func NewDebugTransport(next http.RoundTripper, out io.Writer) http.RoundTripper {
+	return &debugTransport{next: next, out: out}
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fmt.Fprintf(t.out, "[debug] "+Arrow+" %s %s\n", req.Method, req.URL.Redacted())
+	for _, line := range formatHeaders(req.Header, nil) {
+		fmt.Fprintf(t.out, "[debug]     %s\n", line)
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	if err != nil {
+		fmt.Fprintf(t.out, "[debug] "+BackArrow+" error after %s: %v\n", elapsed, err)
+		return nil, err
+	}
+
+	fmt.Fprintf(t.out, "[debug] "+BackArrow+" %s (%s)\n", resp.Status, elapsed)
+	for _, line := range formatHeaders(resp.Header, traceResponseHeaders) {
+		fmt.Fprintf(t.out, "[debug]     %s\n", line)
+	}
+	return resp, nil
+}
+
+// formatHeaders renders headers sorted by name with secrets redacted. When
+// only is non-nil, just those headers are included.
+func formatHeaders(h http.Header, only []string) []string {
+	var names []string
+	if only != nil {
+		for _, name := range only {
+			if _, ok := h[http.CanonicalHeaderKey(name)]; ok {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	} else {
+		for name := range h {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	var lines []string
+	for _, name := range names {
+		value := strings.Join(h[name], ", ")
+		if redactedHeaders[name] {
+			value = "[REDACTED]"
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", name, value))
+	}
+	return lines
+}
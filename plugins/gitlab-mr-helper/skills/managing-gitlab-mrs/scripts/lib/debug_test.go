@@ -0,0 +1,49 @@
+package lib_test
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestDebugTransportRedactsCredentials(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	var out bytes.Buffer
+	client := &http.Client{Transport: lib.NewDebugTransport(http.DefaultTransport, &out)}
+
+	secrets := map[string]string{
+		"PRIVATE-TOKEN": srv.CurrentToken(),
+		"Authorization": "Bearer oauth-secret-value",
+		"Sudo":          "sudo-secret-user", // unknown, so GitLab answers 404
+	}
+	req, err := http.NewRequest("GET", srv.URL+"/api/v4/user", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range secrets {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+
+	trace := out.String()
+	for name, value := range secrets {
+		if strings.Contains(trace, value) {
+			t.Errorf("%s value leaked into the trace:\n%s", name, trace)
+		}
+		if !strings.Contains(trace, http.CanonicalHeaderKey(name)+": [REDACTED]") {
+			t.Errorf("%s is not listed as redacted:\n%s", name, trace)
+		}
+	}
+	if !strings.Contains(trace, "Accept: application/json") || !strings.Contains(trace, "[debug] "+lib.BackArrow+" 404") {
+		t.Errorf("trace lacks the other headers or the response:\n%s", trace)
+	}
+}
@@ -0,0 +1,228 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultExportType is the dependency list export of a CycloneDX SBOM
+const DefaultExportType = "sbom"
+
+// Dependency is a package found by dependency scanning on the default
+// branch
+type Dependency struct {
+	Name               string                    `json:"name"`
+	Version            string                    `json:"version"`
+	PackageManager     string                    `json:"package_manager"`
+	DependencyFilePath string                    `json:"dependency_file_path"`
+	Vulnerabilities    []DependencyVulnerability `json:"vulnerabilities"`
+	Licenses           []DependencyLicense       `json:"licenses"`
+}
+
+// DependencyVulnerability is a known vulnerability of a dependency
+type DependencyVulnerability struct {
+	Name     string `json:"name"`
+	Severity string `json:"severity"`
+	URL      string `json:"url"`
+}
+
+// DependencyLicense is a license a dependency is distributed under
+type DependencyLicense struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// DependencyListExport is an asynchronous export of the dependency list
+type DependencyListExport struct {
+	ID          int    `json:"id"`
+	HasFinished bool   `json:"has_finished"`
+	Self        string `json:"self"`
+	Download    string `json:"download"`
+}
+
+// ListDependencies lists the dependencies of a project, optionally only
+// those of some package managers (npm, bundler, go, maven, ...). It needs
+// dependency scanning to have run on the default branch.
+func (c *Client) ListDependencies(projectPath string, packageManagers []string) ([]Dependency, error) {
+	endpoint := c.apiURL("/projects/%s/dependencies", url.PathEscape(projectPath))
+	q := url.Values{}
+	for _, pm := range packageManagers {
+		q.Add("package_manager[]", pm)
+	}
+	return getAll[Dependency](c, endpoint, q, 0)
+}
+
+// ExportDependencies starts an export of the project's dependency list as
+// exportType, e.g. DefaultExportType. Wait for it with
+// WaitDependencyListExport.
+func (c *Client) ExportDependencies(projectPath, exportType string) (*DependencyListExport, error) {
+	endpoint := c.apiURL("/projects/%s/dependency_list_exports", url.PathEscape(projectPath))
+	var export DependencyListExport
+	if err := c.do("POST", endpoint, map[string]string{"export_type": exportType}, &export, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// GetDependencyListExport returns the state of an export; GitLab answers
+// 202 Accepted until it has finished
+func (c *Client) GetDependencyListExport(exportID int) (*DependencyListExport, error) {
+	endpoint := c.apiURL("/dependency_list_exports/%d", exportID)
+	var export DependencyListExport
+	err := c.do("GET", endpoint, nil, &export, http.StatusOK)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusAccepted {
+		return &DependencyListExport{ID: exportID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// WaitDependencyListExport polls an export until it has finished
+func (c *Client) WaitDependencyListExport(exportID int, interval, timeout time.Duration) error {
+	err := Poll(interval, timeout, func() (bool, error) {
+		export, err := c.GetDependencyListExport(exportID)
+		if err != nil {
+			return false, err
+		}
+		return export.HasFinished, nil
+	})
+	if err != nil {
+		return fmt.Errorf("dependency list export %d: %w", exportID, err)
+	}
+	return nil
+}
+
+// DownloadDependencyListExport streams a finished export. The caller must
+// close it.
+func (c *Client) DownloadDependencyListExport(exportID int) (io.ReadCloser, error) {
+	endpoint := c.apiURL("/dependency_list_exports/%d/download", exportID)
+	resp, err := c.send("GET", endpoint, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// FindDependencies returns the dependencies named name (case-insensitive)
+// whose version satisfies constraint (see MatchVersion)
+func FindDependencies(deps []Dependency, name, constraint string) ([]Dependency, error) {
+	var out []Dependency
+	for _, d := range deps {
+		if !strings.EqualFold(d.Name, name) {
+			continue
+		}
+		ok, err := MatchVersion(d.Version, constraint)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// MatchVersion reports whether version satisfies constraint: comparisons
+// like "<2.17.0" or ">=1.2, <2" (all must hold), or a plain version for an
+// exact match. An empty constraint or "*" matches any version.
+func MatchVersion(version, constraint string) (bool, error) {
+	constraint = strings.TrimSpace(constraint)
+	if constraint == "" || constraint == "*" {
+		return true, nil
+	}
+	for _, clause := range strings.Split(constraint, ",") {
+		clause = strings.TrimSpace(clause)
+		rest := strings.TrimLeft(clause, "<>=!")
+		op, want := clause[:len(clause)-len(rest)], strings.TrimSpace(rest)
+		if want == "" || strings.ContainsAny(want, "<>=!~^ ") {
+			return false, fmt.Errorf("invalid version constraint %q", clause)
+		}
+		cmp := CompareVersions(version, want)
+		var ok bool
+		switch op {
+		case "", "=", "==":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		default:
+			return false, fmt.Errorf("invalid version constraint %q", clause)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// CompareVersions orders package versions like "1.2.10", "v1.2" or
+// "2.0.0-rc1" by their numeric parts; "1.2" equals "1.2.0" and a
+// pre-release sorts before its release. It returns -1, 0 or 1.
+func CompareVersions(a, b string) int {
+	as, bs := versionParts(a), versionParts(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, errX := strconv.Atoi(x)
+		yn, errY := strconv.Atoi(y)
+		switch {
+		case x == y:
+			continue
+		case x == "" && errY == nil, y == "" && errX == nil:
+			// A missing part counts as 0
+			if xn != yn {
+				return compareInts(xn, yn)
+			}
+		case x == "":
+			return 1 // "1.0" > "1.0-rc1"
+		case y == "":
+			return -1
+		case errX == nil && errY == nil:
+			return compareInts(xn, yn)
+		case errX == nil:
+			return 1 // "1.0.1" > "1.0.rc1"
+		case errY == nil:
+			return -1
+		default:
+			return strings.Compare(x, y)
+		}
+	}
+	return 0
+}
+
+// versionParts splits a version at dots, dashes and plus signs
+func versionParts(v string) []string {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	return strings.FieldsFunc(v, func(r rune) bool { return r == '.' || r == '-' || r == '+' || r == '_' })
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
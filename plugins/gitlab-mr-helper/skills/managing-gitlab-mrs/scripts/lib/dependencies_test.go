@@ -0,0 +1,92 @@
+package lib_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestMatchVersion(t *testing.T) {
+	tests := []struct {
+		version, constraint string
+		want                bool
+		wantErr             bool
+	}{
+		{version: "4.17.20", constraint: "", want: true},
+		{version: "4.17.20", constraint: "4.17.20", want: true},
+		{version: "v4.17.20", constraint: "4.17.20", want: true},
+		{version: "1.2", constraint: "=1.2.0", want: true},
+		{version: "4.17.20", constraint: "<4.17.21", want: true},
+		{version: "4.17.9", constraint: "<4.17.10", want: true},
+		{version: "2.0.0-rc1", constraint: "<2.0.0", want: true},
+		{version: "2.0.0", constraint: ">=1.2, <2", want: false},
+		{version: "1.9.3", constraint: ">=1.2, <2", want: true},
+		{version: "1.0.1", constraint: "> 1.0.rc1", want: true},
+		{version: "3.1", constraint: "!=3.1.0", want: false},
+		{version: "1.0", constraint: "~>1.0", wantErr: true},
+		{version: "1.0", constraint: ">=", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := lib.MatchVersion(tt.version, tt.constraint)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("MatchVersion(%q, %q) = %v, %v, want %v", tt.version, tt.constraint, got, err, tt.want)
+		}
+	}
+}
+
+func TestListDependencies(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	deps, err := client.ListDependencies(gitlabtest.ProjectPath, []string{"npm", "go"})
+	if err != nil || len(deps) != 2 {
+		t.Fatalf("ListDependencies = %+v, %v", deps, err)
+	}
+	found, err := lib.FindDependencies(deps, "Lodash", "<4.17.21")
+	if err != nil || len(found) != 1 || found[0].Vulnerabilities[0].Severity != "high" {
+		t.Errorf("FindDependencies = %+v, %v", found, err)
+	}
+	if found, _ := lib.FindDependencies(deps, "lodash", ">=4.17.21"); len(found) != 0 {
+		t.Errorf("FindDependencies of a fixed version = %+v", found)
+	}
+}
+
+func TestExportDependencies(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	export, err := client.ExportDependencies(gitlabtest.ProjectPath, lib.DefaultExportType)
+	if err != nil {
+		t.Fatalf("ExportDependencies: %v", err)
+	}
+	if _, err := client.DownloadDependencyListExport(export.ID); err == nil {
+		t.Error("download of an unfinished export succeeded")
+	}
+	if err := client.WaitDependencyListExport(export.ID, time.Millisecond, time.Second); err != nil {
+		t.Fatalf("WaitDependencyListExport: %v", err)
+	}
+	body, err := client.DownloadDependencyListExport(export.ID)
+	if err != nil {
+		t.Fatalf("DownloadDependencyListExport: %v", err)
+	}
+	sbom, _ := io.ReadAll(body)
+	body.Close()
+	if !strings.Contains(string(sbom), `"bomFormat":"CycloneDX"`) {
+		t.Errorf("SBOM = %s", sbom)
+	}
+
+	link, err := client.AttachReleaseFile(gitlabtest.ProjectPath, "v1.1.0", "sbom.cdx.json", strings.NewReader(string(sbom)))
+	if err != nil {
+		t.Fatalf("AttachReleaseFile: %v", err)
+	}
+	path := strings.TrimPrefix(link.URL, srv.URL)
+	if got := srv.Project(gitlabtest.ProjectPath).Uploads[path]; got != string(sbom) || link.Name != "sbom.cdx.json" {
+		t.Errorf("link = %+v, uploaded %q", link, got)
+	}
+	_, err = client.AttachReleaseFile(gitlabtest.ProjectPath, "v1.0.0", "sbom.cdx.json", strings.NewReader("{}"))
+	wantExit(t, err, lib.ExitNotFound)
+}
@@ -0,0 +1,124 @@
+package lib
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Deployment is a deployment of a ref to an environment. Deployments to a
+// protected environment that requires approval wait with status "blocked".
+type Deployment struct {
+	ID          int       `json:"id"`
+	IID         int       `json:"iid"`
+	Ref         string    `json:"ref"`
+	SHA         string    `json:"sha"`
+	Status      string    `json:"status"`
+	User        User      `json:"user"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Environment struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"environment"`
+	Deployable *struct {
+		ID     int    `json:"id"`
+		Name   string `json:"name"`
+		WebURL string `json:"web_url"`
+	} `json:"deployable,omitempty"`
+	// PendingApprovalCount and Approvals are only set by GetDeployment
+	PendingApprovalCount int                  `json:"pending_approval_count"`
+	Approvals            []DeploymentApproval `json:"approvals"`
+}
+
+// DeploymentApproval is one user's approval or rejection of a deployment
+type DeploymentApproval struct {
+	User      User      `json:"user"`
+	Status    string    `json:"status"` // approved or rejected
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ProtectedEnvironment restricts who may deploy to an environment and how
+// many approvals a deployment needs
+type ProtectedEnvironment struct {
+	Name                  string                    `json:"name"`
+	RequiredApprovalCount int                       `json:"required_approval_count"`
+	ApprovalRules         []EnvironmentApprovalRule `json:"approval_rules"`
+}
+
+// EnvironmentApprovalRule names who may approve deployments: a user, a
+// group or an access level
+type EnvironmentApprovalRule struct {
+	UserID                 int    `json:"user_id,omitempty"`
+	GroupID                int    `json:"group_id,omitempty"`
+	AccessLevel            int    `json:"access_level,omitempty"`
+	AccessLevelDescription string `json:"access_level_description"`
+	RequiredApprovals      int    `json:"required_approvals"`
+}
+
+// DeploymentListOptions holds filters for deployment listings
+type DeploymentListOptions struct {
+	Environment string
+	Status      string // created, running, success, failed, canceled, blocked
+	Limit       int    // 0 means no limit
+}
+
+func (o *DeploymentListOptions) query() url.Values {
+	q := url.Values{}
+	q.Set("order_by", "created_at")
+	q.Set("sort", "desc")
+	if o.Environment != "" {
+		q.Set("environment", o.Environment)
+	}
+	if o.Status != "" {
+		q.Set("status", o.Status)
+	}
+	return q
+}
+
+// ListDeployments lists a project's deployments, newest first
+func (c *Client) ListDeployments(projectPath string, opts *DeploymentListOptions) ([]Deployment, error) {
+	endpoint := c.apiURL("/projects/%s/deployments", url.PathEscape(projectPath))
+	return getAll[Deployment](c, endpoint, opts.query(), opts.Limit)
+}
+
+// GetDeployment gets a single deployment including its approvals
+func (c *Client) GetDeployment(projectPath string, deploymentID int) (*Deployment, error) {
+	endpoint := c.apiURL("/projects/%s/deployments/%d", url.PathEscape(projectPath), deploymentID)
+
+	var d Deployment
+	if err := c.do("GET", endpoint, nil, &d, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListProtectedEnvironments lists a project's protected environments
+func (c *Client) ListProtectedEnvironments(projectPath string) ([]ProtectedEnvironment, error) {
+	endpoint := c.apiURL("/projects/%s/protected_environments", url.PathEscape(projectPath))
+	return getAll[ProtectedEnvironment](c, endpoint, nil, 0)
+}
+
+// approveDeploymentRequest represents the request body for approving or
+// rejecting a blocked deployment
+type approveDeploymentRequest struct {
+	Status  string `json:"status"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// ApproveDeployment approves a blocked deployment, or rejects it when
+// approve is false. GitLab refuses (400) approvals of one's own deployment.
+func (c *Client) ApproveDeployment(projectPath string, deploymentID int, approve bool, comment string) (*DeploymentApproval, error) {
+	endpoint := c.apiURL("/projects/%s/deployments/%d/approval", url.PathEscape(projectPath), deploymentID)
+	req := &approveDeploymentRequest{Status: "approved", Comment: comment}
+	if !approve {
+		req.Status = "rejected"
+	}
+
+	var approval DeploymentApproval
+	if err := c.do("POST", endpoint, req, &approval, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &approval, nil
+}
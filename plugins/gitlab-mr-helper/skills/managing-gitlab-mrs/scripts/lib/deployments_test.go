@@ -0,0 +1,72 @@
+package lib_test
+
+import (
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestListDeployments(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	tests := []struct {
+		name string
+		opts lib.DeploymentListOptions
+		want []int
+	}{
+		{name: "all", want: []int{502, 501}},
+		{name: "blocked", opts: lib.DeploymentListOptions{Status: "blocked"}, want: []int{502}},
+		{name: "environment", opts: lib.DeploymentListOptions{Environment: "staging"}, want: []int{501}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := client.ListDeployments(gitlabtest.ProjectPath, &tt.opts)
+			if err != nil {
+				t.Fatalf("ListDeployments: %v", err)
+			}
+			var ids []int
+			for _, d := range got {
+				ids = append(ids, d.ID)
+			}
+			if len(ids) != len(tt.want) || (len(ids) > 0 && ids[0] != tt.want[0]) {
+				t.Errorf("deployments = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	envs, err := client.ListProtectedEnvironments(gitlabtest.ProjectPath)
+	if err != nil || len(envs) != 1 || envs[0].RequiredApprovalCount != 2 || envs[0].ApprovalRules[0].AccessLevelDescription != "Maintainers" {
+		t.Errorf("ListProtectedEnvironments = %+v, %v", envs, err)
+	}
+}
+
+func TestApproveDeployment(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	approval, err := client.ApproveDeployment(gitlabtest.ProjectPath, 502, true, "smoke tests passed")
+	if err != nil {
+		t.Fatalf("ApproveDeployment: %v", err)
+	}
+	if approval.Status != "approved" || approval.User.Username != "alice" || approval.Comment != "smoke tests passed" {
+		t.Errorf("approval = %+v", approval)
+	}
+	d, err := client.GetDeployment(gitlabtest.ProjectPath, 502)
+	if err != nil || d.PendingApprovalCount != 1 || len(d.Approvals) != 1 || d.Status != "blocked" {
+		t.Errorf("GetDeployment = %+v, %v", d, err)
+	}
+
+	if _, err := client.ApproveDeployment(gitlabtest.ProjectPath, 501, false, ""); err == nil || !strings.Contains(err.Error(), "not waiting for approvals") {
+		t.Errorf("rejecting a finished deployment: error = %v", err)
+	}
+	srv.SetUser(gitlabtest.Bob)
+	if _, err := client.ApproveDeployment(gitlabtest.ProjectPath, 502, true, ""); err == nil || !strings.Contains(err.Error(), "your own deployment") {
+		t.Errorf("approving one's own deployment: error = %v", err)
+	}
+	if _, err := client.GetDeployment(gitlabtest.ProjectPath, 999); lib.ExitCode(err) != lib.ExitNotFound {
+		t.Errorf("GetDeployment(999) error = %v, want not found", err)
+	}
+}
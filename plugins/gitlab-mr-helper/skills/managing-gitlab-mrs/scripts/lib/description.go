@@ -0,0 +1,118 @@
+package lib
+
+import (
+	"strings"
+)
+
+// AppendText adds text after a description, separated by a blank line
+func AppendText(description, text string) string {
+	description = strings.TrimRight(description, "\n")
+	text = strings.Trim(text, "\n")
+	if description == "" {
+		return text
+	}
+	return description + "\n\n" + text
+}
+
+// PrependText adds text before a description, separated by a blank line
+func PrependText(description, text string) string {
+	description = strings.Trim(description, "\n")
+	text = strings.Trim(text, "\n")
+	if description == "" {
+		return text
+	}
+	return text + "\n\n" + description
+}
+
+// ReplaceSection replaces the body of the markdown section introduced by
+// heading (e.g. "## Testing") with body, appending the section when it does
+// not exist yet. A section ends at the next heading of the same or a higher
+// level; headings inside fenced code blocks are ignored. A heading without
+// leading '#' is treated as a level-2 heading.
+func ReplaceSection(description, heading, body string) string {
+	heading = strings.TrimSpace(heading)
+	if !strings.HasPrefix(heading, "#") {
+		heading = "## " + heading
+	}
+	level, title := parseHeading(heading)
+
+	// Don't duplicate the heading when the new body already starts with it
+	body = strings.Trim(body, "\n")
+	if first, rest, _ := strings.Cut(body, "\n"); sameHeading(first, level, title) {
+		body = strings.Trim(rest, "\n")
+	}
+	section := heading + "\n\n" + body
+
+	lines := strings.Split(description, "\n")
+	start, end := -1, len(lines)
+	inFence := false
+	for i, line := range lines {
+		if isFence(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		l, t := parseHeading(line)
+		if l == 0 {
+			continue
+		}
+		if start < 0 {
+			if l == level && t == title {
+				start = i
+			}
+		} else if l <= level {
+			end = i
+			break
+		}
+	}
+
+	if start < 0 {
+		return AppendText(description, section)
+	}
+
+	before := strings.TrimRight(strings.Join(lines[:start], "\n"), "\n")
+	after := strings.Trim(strings.Join(lines[end:], "\n"), "\n")
+	out := section
+	if before != "" {
+		out = before + "\n\n" + out
+	}
+	if after != "" {
+		out += "\n\n" + after
+	}
+	return out
+}
+
+// parseHeading returns the level and text of an ATX heading line, or 0 when
+// the line is not a heading
+func parseHeading(line string) (int, string) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return 0, ""
+	}
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, ""
+	}
+	rest := trimmed[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, ""
+	}
+	text := strings.TrimSpace(rest)
+	text = strings.TrimSpace(strings.TrimRight(text, "#"))
+	return level, text
+}
+
+func sameHeading(line string, level int, title string) bool {
+	l, t := parseHeading(line)
+	return l == level && t == title
+}
+
+func isFence(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+}
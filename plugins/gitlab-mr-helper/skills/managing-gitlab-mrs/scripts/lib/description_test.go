@@ -0,0 +1,80 @@
+package lib_test
+
+import (
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestAppendPrependText(t *testing.T) {
+	if got, want := lib.AppendText("Intro\n", "\nMore\n"), "Intro\n\nMore"; got != want {
+		t.Errorf("AppendText = %q, want %q", got, want)
+	}
+	if got, want := lib.AppendText("", "More"), "More"; got != want {
+		t.Errorf("AppendText(empty) = %q, want %q", got, want)
+	}
+	if got, want := lib.PrependText("Intro", "> Note"), "> Note\n\nIntro"; got != want {
+		t.Errorf("PrependText = %q, want %q", got, want)
+	}
+}
+
+func TestReplaceSection(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		heading     string
+		body        string
+		want        string
+	}{
+		{
+			name:        "appends missing section",
+			description: "Adds login.",
+			heading:     "## Testing",
+			body:        "All green",
+			want:        "Adds login.\n\n## Testing\n\nAll green",
+		},
+		{
+			name:        "replaces until next heading of same level",
+			description: "Intro\n\n## Testing\n\nold\n\n### Details\n\nold details\n\n## Notes\n\nkeep me",
+			heading:     "## Testing",
+			body:        "new results\n",
+			want:        "Intro\n\n## Testing\n\nnew results\n\n## Notes\n\nkeep me",
+		},
+		{
+			name:        "replaces last section",
+			description: "## Summary\n\nhuman text\n\n## Testing\n\nold",
+			heading:     "Testing",
+			body:        "new",
+			want:        "## Summary\n\nhuman text\n\n## Testing\n\nnew",
+		},
+		{
+			name:        "body repeating heading",
+			description: "## Testing\n\nold",
+			heading:     "## Testing",
+			body:        "## Testing\n\nnew",
+			want:        "## Testing\n\nnew",
+		},
+		{
+			name:        "ignores headings in code fences",
+			description: "## Testing\n\n```\n## not a heading\n```\n\n## Notes\n\nkeep",
+			heading:     "## Testing",
+			body:        "new",
+			want:        "## Testing\n\nnew\n\n## Notes\n\nkeep",
+		},
+		{
+			name:        "different level is another section",
+			description: "### Testing\n\nsub",
+			heading:     "## Testing",
+			body:        "new",
+			want:        "### Testing\n\nsub\n\n## Testing\n\nnew",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lib.ReplaceSection(tt.description, tt.heading, tt.body); got != tt.want {
+				t.Errorf("ReplaceSection =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
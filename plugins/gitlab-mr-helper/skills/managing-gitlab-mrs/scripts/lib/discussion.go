@@ -0,0 +1,116 @@
+package lib
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Note is a comment on a merge request
+type Note struct {
+	ID         int           `json:"id"`
+	Type       string        `json:"type,omitempty"` // DiffNote, DiscussionNote or empty
+	Body       string        `json:"body"`
+	Author     User          `json:"author"`
+	System     bool          `json:"system"`
+	Resolvable bool          `json:"resolvable"`
+	Resolved   bool          `json:"resolved"`
+	Position   *NotePosition `json:"position,omitempty"` // diff notes only
+	CreatedAt  time.Time     `json:"created_at"`
+	// Internal notes are only visible to project members
+	Internal bool `json:"internal,omitempty"`
+}
+
+// NotePosition is where a diff note is anchored. NewLine is 0 for a
+// removed line and OldLine is 0 for an added one.
+type NotePosition struct {
+	BaseSHA  string `json:"base_sha"`
+	StartSHA string `json:"start_sha"`
+	HeadSHA  string `json:"head_sha"`
+	OldPath  string `json:"old_path"`
+	NewPath  string `json:"new_path"`
+	OldLine  int    `json:"old_line"`
+	NewLine  int    `json:"new_line"`
+}
+
+// Location renders the position as path:line, the old path and line for a
+// removed line
+func (p *NotePosition) Location() string {
+	if p.NewLine == 0 && p.OldLine != 0 {
+		return fmt.Sprintf("%s:%d (removed)", p.OldPath, p.OldLine)
+	}
+	return fmt.Sprintf("%s:%d", p.NewPath, p.NewLine)
+}
+
+// Discussion is a thread of notes. Standalone comments are discussions with
+// IndividualNote set and a single note.
+type Discussion struct {
+	ID             string `json:"id"`
+	IndividualNote bool   `json:"individual_note"`
+	Notes          []Note `json:"notes"`
+}
+
+// Resolved reports whether every resolvable note of the thread is resolved
+func (d *Discussion) Resolved() bool {
+	for _, n := range d.Notes {
+		if n.Resolvable && !n.Resolved {
+			return false
+		}
+	}
+	return true
+}
+
+// ListMRDiscussions lists the discussion threads of a merge request
+func (c *Client) ListMRDiscussions(projectPath string, mrIID int) ([]Discussion, error) {
+	if c.config.Offline {
+		return c.cachedDiscussions(projectPath, mrIID)
+	}
+	endpoint := c.apiURL("/projects/%s/merge_requests/%d/discussions", url.PathEscape(projectPath), mrIID)
+	return getAll[Discussion](c, endpoint, nil, 0)
+}
+
+// ListMRNotes lists the notes (comments and system notes) of a merge request
+func (c *Client) ListMRNotes(projectPath string, mrIID int) ([]Note, error) {
+	endpoint := c.apiURL("/projects/%s/merge_requests/%d/notes", url.PathEscape(projectPath), mrIID)
+	return getAll[Note](c, endpoint, nil, 0)
+}
+
+// CreateMRNote adds a comment to a merge request
+func (c *Client) CreateMRNote(projectPath string, mrIID int, body string) (*Note, error) {
+	endpoint := c.apiURL("/projects/%s/merge_requests/%d/notes", url.PathEscape(projectPath), mrIID)
+
+	var note Note
+	if err := c.do("POST", endpoint, map[string]string{"body": body}, &note, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// UpdateMRNote replaces the body of a merge request comment
+func (c *Client) UpdateMRNote(projectPath string, mrIID, noteID int, body string) (*Note, error) {
+	endpoint := c.apiURL("/projects/%s/merge_requests/%d/notes/%d", url.PathEscape(projectPath), mrIID, noteID)
+
+	var note Note
+	if err := c.do("PUT", endpoint, map[string]string{"body": body}, &note, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// ReplyToDiscussion adds a note to an existing discussion thread
+func (c *Client) ReplyToDiscussion(projectPath string, mrIID int, discussionID, body string) (*Note, error) {
+	endpoint := c.apiURL("/projects/%s/merge_requests/%d/discussions/%s/notes", url.PathEscape(projectPath), mrIID, url.PathEscape(discussionID))
+
+	var note Note
+	if err := c.do("POST", endpoint, map[string]string{"body": body}, &note, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// ResolveDiscussion resolves or unresolves a discussion thread
+func (c *Client) ResolveDiscussion(projectPath string, mrIID int, discussionID string, resolved bool) error {
+	endpoint := c.apiURL("/projects/%s/merge_requests/%d/discussions/%s", url.PathEscape(projectPath), mrIID, url.PathEscape(discussionID))
+	return c.do("PUT", endpoint, map[string]bool{"resolved": resolved}, nil, http.StatusOK)
+}
@@ -0,0 +1,90 @@
+package lib_test
+
+import (
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestListMRDiscussions(t *testing.T) {
+	tests := []struct {
+		name         string
+		iid          int
+		wantThreads  int
+		wantResolved []bool
+		wantExit     int
+	}{
+		{name: "threads", iid: 1, wantThreads: 3, wantResolved: []bool{false, true, true}},
+		{name: "no discussions", iid: 2},
+		{name: "unknown MR", iid: 99, wantExit: lib.ExitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			discussions, err := srv.Client().ListMRDiscussions(gitlabtest.ProjectPath, tt.iid)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("ListMRDiscussions: %v", err)
+			}
+			if len(discussions) != tt.wantThreads {
+				t.Fatalf("got %d threads, want %d", len(discussions), tt.wantThreads)
+			}
+			for i, d := range discussions {
+				if d.Resolved() != tt.wantResolved[i] {
+					t.Errorf("thread %s resolved = %v, want %v", d.ID, d.Resolved(), tt.wantResolved[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMRNotes(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	note, err := client.CreateMRNote(gitlabtest.ProjectPath, 2, "first")
+	if err != nil {
+		t.Fatalf("CreateMRNote: %v", err)
+	}
+	if _, err := client.UpdateMRNote(gitlabtest.ProjectPath, 2, note.ID, "second"); err != nil {
+		t.Fatalf("UpdateMRNote: %v", err)
+	}
+	notes, err := client.ListMRNotes(gitlabtest.ProjectPath, 2)
+	if err != nil {
+		t.Fatalf("ListMRNotes: %v", err)
+	}
+	if len(notes) != 1 || notes[0].Body != "second" {
+		t.Errorf("notes = %+v", notes)
+	}
+
+	// Notes by other users cannot be edited
+	_, err = client.UpdateMRNote(gitlabtest.ProjectPath, 1, 501, "hijack")
+	wantExit(t, err, lib.ExitAuth)
+}
+
+func TestMRNotesSudo(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := lib.NewClient(&lib.Config{URL: srv.URL, Token: gitlabtest.Token, Sudo: "bob"})
+
+	// Acting as Bob, his notes can be edited and new ones are his
+	if _, err := client.UpdateMRNote(gitlabtest.ProjectPath, 1, 501, "Looks great"); err != nil {
+		t.Fatalf("UpdateMRNote: %v", err)
+	}
+	note, err := client.CreateMRNote(gitlabtest.ProjectPath, 1, "migrated")
+	if err != nil {
+		t.Fatalf("CreateMRNote: %v", err)
+	}
+	if note.Author.Username != "bob" {
+		t.Errorf("author = %q, want bob", note.Author.Username)
+	}
+	for _, r := range srv.Requests() {
+		if got := r.Header.Get("Sudo"); got != "bob" {
+			t.Errorf("%s %s: Sudo = %q", r.Method, r.URL.Path, got)
+		}
+	}
+}
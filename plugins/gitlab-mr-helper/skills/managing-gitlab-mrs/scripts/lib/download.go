@@ -0,0 +1,389 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDownloadRetries is how many times in a row a download is retried
+// without progress before giving up
+const DefaultDownloadRetries = 5
+
+// DownloadOptions controls how a file is downloaded
+type DownloadOptions struct {
+	// SHA256 is the expected checksum of the file (hex). When empty, the
+	// checksum of the response's digest header is checked, if it has one.
+	SHA256 string
+	// Retries is how many times in a row a transfer is retried when it
+	// fails without receiving anything (DefaultDownloadRetries when zero,
+	// none when negative); transfers that break off after receiving data
+	// are always resumed
+	Retries int
+	// Progress is called as data arrives, a few times a second, with the
+	// bytes received so far and the total size (-1 when unknown)
+	Progress func(done, total int64)
+	// Header, when set, is sent instead of the token, for URLs that come
+	// with their own authorization, e.g. LFS download actions
+	Header map[string]string
+}
+
+// Download is a file written by a download
+type Download struct {
+	Path    string
+	Size    int64
+	SHA256  string
+	Checked bool // SHA256 matched an expected checksum
+	Resumed int  // transfers continued from a partial file
+}
+
+// ErrChecksum is wrapped by downloads whose content does not match the
+// expected checksum
+var ErrChecksum = errors.New("checksum mismatch")
+
+// partState is saved next to a partial download, so a later run can resume
+// it when the file has not changed on the server
+type partState struct {
+	URL string `json:"url"`
+	// Validator is the ETag or Last-Modified date of the partial content
+	Validator string `json:"validator"`
+}
+
+// retryable marks download failures worth resuming after: dropped
+// connections, timeouts and server errors
+type retryable struct{ err error }
+
+func (e *retryable) Error() string { return e.err.Error() }
+func (e *retryable) Unwrap() error { return e.err }
+
+// downloadFile downloads endpoint to path. Data goes to path.part first
+// and is moved into place once complete and verified. An interrupted
+// transfer continues from where it stopped with a Range request: within
+// the run, and in a later one when the server gave the file a validator
+// (ETag or Last-Modified) to check it has not changed.
+func (c *Client) downloadFile(endpoint, path string, opts *DownloadOptions) (*Download, error) {
+	if c.config.Offline {
+		return nil, fmt.Errorf("%w: GET %s", ErrOffline, strings.TrimPrefix(endpoint, c.config.URL))
+	}
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	retries := opts.Retries
+	if retries == 0 {
+		retries = DefaultDownloadRetries
+	}
+
+	part, statePath := path+".part", path+".part.json"
+	var state partState
+	if data, err := os.ReadFile(statePath); err != nil || json.Unmarshal(data, &state) != nil || state.URL != endpoint {
+		state = partState{URL: endpoint}
+	}
+	d := &Download{Path: path}
+	var offset int64
+	if info, err := os.Stat(part); err == nil && state.Validator != "" {
+		offset = info.Size()
+		d.Resumed++
+	}
+
+	want := strings.ToLower(opts.SHA256)
+	t := &transfer{client: c.transferClient(), endpoint: endpoint, part: part, statePath: statePath, state: &state, progress: opts.Progress, header: opts.Header}
+	backoff, failures := time.Second, 0
+	for {
+		digest, err := t.fetch(offset)
+		if err == nil {
+			if want == "" {
+				want = digest
+			}
+			break
+		}
+		var r *retryable
+		if !errors.As(err, &r) {
+			return nil, err
+		}
+		// Within a run the file is taken to be the same even without a
+		// validator. A transfer that broke off after making progress is
+		// resumed at once; failures without any back off, up to retries
+		// in a row.
+		previous := offset
+		offset = 0
+		if info, statErr := os.Stat(part); statErr == nil {
+			offset = info.Size()
+		}
+		if offset > previous {
+			backoff, failures = time.Second, 0
+		} else {
+			if failures++; failures > retries {
+				return nil, err
+			}
+			time.Sleep(backoff)
+			backoff = min(2*backoff, 30*time.Second)
+		}
+		d.Resumed++
+	}
+
+	sum, size, err := fileSHA256(part)
+	if err != nil {
+		return nil, err
+	}
+	d.SHA256, d.Size = sum, size
+	if want != "" {
+		if sum != want {
+			// Resuming corrupt data would only fail again
+			os.Remove(part)
+			os.Remove(statePath)
+			return nil, fmt.Errorf("%w: %s has SHA256 %s, expected %s", ErrChecksum, path, sum, want)
+		}
+		d.Checked = true
+	}
+	if err := os.Rename(part, path); err != nil {
+		return nil, err
+	}
+	os.Remove(statePath)
+	return d, nil
+}
+
+// transfer is one file being downloaded by downloadFile
+type transfer struct {
+	client    *Client
+	endpoint  string
+	part      string
+	statePath string
+	state     *partState
+	progress  func(done, total int64)
+	header    map[string]string
+	reported  time.Time
+}
+
+// fetch requests the file from offset on and appends it to the partial
+// file. It returns the SHA256 of the response's digest header, if any.
+func (t *transfer) fetch(offset int64) (string, error) {
+	req, err := http.NewRequest("GET", t.endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	if t.header != nil {
+		for k, v := range t.header {
+			req.Header.Set(k, v)
+		}
+	} else {
+		t.client.setHeaders(req)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if t.state.Validator != "" {
+			req.Header.Set("If-Range", t.state.Validator)
+		}
+	}
+	resp, err := t.client.httpClient.Do(req)
+	if err != nil {
+		return "", &retryable{fmt.Errorf("failed to execute request: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	total := int64(-1)
+	flags := os.O_WRONLY | os.O_CREATE
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The whole file, either asked for or because it changed since the
+		// partial download
+		offset = 0
+		flags |= os.O_TRUNC
+		total = resp.ContentLength
+	case http.StatusPartialContent:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return "", fmt.Errorf("unexpected Content-Range %q for a download from byte %d", resp.Header.Get("Content-Range"), offset)
+		}
+		flags |= os.O_APPEND
+		total = size
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Nothing after offset: the partial file is complete, or longer
+		// than the file and useless
+		if _, size, ok := parseContentRange(resp.Header.Get("Content-Range")); ok && size == offset {
+			return "", nil
+		}
+		os.Remove(t.part)
+		t.state.Validator = ""
+		return "", &retryable{fmt.Errorf("partial download of %d bytes does not fit the file", offset)}
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBytes))
+		err := &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return "", &retryable{err}
+		}
+		return "", err
+	}
+
+	// Only a strong ETag or a date can validate a range
+	t.state.Validator = resp.Header.Get("ETag")
+	if t.state.Validator == "" || strings.HasPrefix(t.state.Validator, "W/") {
+		t.state.Validator = resp.Header.Get("Last-Modified")
+	}
+	if data, err := json.Marshal(t.state); err == nil {
+		os.WriteFile(t.statePath, data, 0o600)
+	}
+
+	f, err := os.OpenFile(t.part, flags, 0o644)
+	if err != nil {
+		return "", err
+	}
+	done := offset
+	buf := make([]byte, 256<<10)
+	for {
+		n, readErr := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := f.Write(buf[:n]); err != nil {
+				f.Close()
+				return "", err
+			}
+			done += int64(n)
+			t.report(done, total, false)
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			f.Close()
+			return "", &retryable{fmt.Errorf("download interrupted after %d bytes: %w", done, readErr)}
+		}
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	t.report(done, total, true)
+	if total >= 0 && done != total {
+		return "", &retryable{fmt.Errorf("download ended after %d of %d bytes", done, total)}
+	}
+	return ContentDigest(resp.Header), nil
+}
+
+// report calls the progress function at most every 200ms, and always for
+// the last bytes
+func (t *transfer) report(done, total int64, last bool) {
+	if t.progress == nil || (!last && time.Since(t.reported) < 200*time.Millisecond) {
+		return
+	}
+	t.reported = time.Now()
+	t.progress(done, total)
+}
+
+// parseContentRange parses "bytes START-END/SIZE" or "bytes */SIZE"; SIZE
+// is -1 when given as "*"
+func parseContentRange(s string) (start, size int64, ok bool) {
+	spec, found := strings.CutPrefix(s, "bytes ")
+	rng, total, found2 := strings.Cut(spec, "/")
+	if !found || !found2 {
+		return 0, 0, false
+	}
+	size = -1
+	if total != "*" {
+		n, err := strconv.ParseInt(total, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		size = n
+	}
+	if rng == "*" {
+		return 0, size, true
+	}
+	first, _, _ := strings.Cut(rng, "-")
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, size, true
+}
+
+// ContentDigest returns the SHA256 (hex) a response declares for its whole
+// content in a Repr-Digest (RFC 9530) or Digest (RFC 3230) header, or ""
+// when it has none. GitLab does not send them itself; object storage and
+// proxies in front of it may.
+func ContentDigest(h http.Header) string {
+	for _, field := range strings.Split(h.Get("Repr-Digest"), ",") {
+		alg, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if ok && strings.EqualFold(alg, "sha-256") {
+			if sum := decodeDigest(strings.Trim(value, ":")); sum != "" {
+				return sum
+			}
+		}
+	}
+	for _, field := range strings.Split(h.Get("Digest"), ",") {
+		alg, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if ok && strings.EqualFold(alg, "sha-256") {
+			if sum := decodeDigest(value); sum != "" {
+				return sum
+			}
+		}
+	}
+	return ""
+}
+
+// decodeDigest turns a base64 SHA256 into hex, or "" if it is not one
+func decodeDigest(value string) string {
+	sum, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(sum) != sha256.Size {
+		return ""
+	}
+	return hex.EncodeToString(sum)
+}
+
+// fileSHA256 returns the SHA256 (hex) and size of a file
+func fileSHA256(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// ProgressLine draws download progress on one terminal line, e.g.
+// "Downloading: 1.2 GB of 3.4 GB (35%)"
+type ProgressLine struct {
+	w     io.Writer
+	label string
+	shown bool
+}
+
+// NewProgressLine returns a progress line written to w, or nil when w is
+// not a terminal. A nil ProgressLine draws nothing.
+func NewProgressLine(w *os.File, label string) *ProgressLine {
+	if !IsTerminal(w) {
+		return nil
+	}
+	return &ProgressLine{w: w, label: label}
+}
+
+// Update redraws the line; it fits DownloadOptions.Progress
+func (p *ProgressLine) Update(done, total int64) {
+	if p == nil {
+		return
+	}
+	p.shown = true
+	if total <= 0 {
+		fmt.Fprintf(p.w, "\r%s: %s\033[K", p.label, FormatSize(done))
+		return
+	}
+	fmt.Fprintf(p.w, "\r%s: %s of %s (%d%%)\033[K", p.label, FormatSize(done), FormatSize(total), done*100/total)
+}
+
+// Done ends the line, if one was drawn
+func (p *ProgressLine) Done() {
+	if p != nil && p.shown {
+		fmt.Fprintln(p.w)
+	}
+}
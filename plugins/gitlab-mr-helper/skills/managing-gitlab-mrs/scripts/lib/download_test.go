@@ -0,0 +1,180 @@
+package lib_test
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+// bigArtifact adds a file of a few hundred KB to the artifacts of job 3002
+// and returns its content
+func bigArtifact(srv *gitlabtest.Server) string {
+	content := strings.Repeat("0123456789abcdef", 32<<10)
+	srv.Project(gitlabtest.ProjectPath).Artifacts[3002]["dist/app.bin"] = content
+	return content
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// rangeRequests returns the Range headers of the requests made so far
+func rangeRequests(srv *gitlabtest.Server) []string {
+	var ranges []string
+	for _, r := range srv.Requests() {
+		if v := r.Header.Get("Range"); v != "" {
+			ranges = append(ranges, v)
+		}
+	}
+	return ranges
+}
+
+func TestSaveJobArtifacts(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+	bigArtifact(srv)
+	path := filepath.Join(t.TempDir(), "artifacts.zip")
+
+	d, err := client.SaveJobArtifacts(gitlabtest.ProjectPath, 3002, "", path, nil)
+	if err != nil {
+		t.Fatalf("SaveJobArtifacts: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if d.Size != int64(len(data)) || d.SHA256 != sha256Hex(string(data)) || d.Checked || d.Resumed != 0 {
+		t.Errorf("download = %+v for %d bytes", d, len(data))
+	}
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	defer zr.Close()
+	if len(zr.File) != 2 || zr.File[0].Name != "dist/app.bin" || zr.File[1].Name != "reports/size.json" {
+		t.Errorf("archive files = %v", zr.File)
+	}
+	if _, err := os.Stat(path + ".part"); !os.IsNotExist(err) {
+		t.Errorf("partial file left behind: %v", err)
+	}
+}
+
+func TestSaveJobArtifactsResume(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+	content := bigArtifact(srv)
+	path := filepath.Join(t.TempDir(), "app.bin")
+
+	// The connection drops twice; the transfer goes on from where it was
+	srv.CutTransfers(100<<10, 50<<10)
+	var last int64
+	d, err := client.SaveJobArtifacts(gitlabtest.ProjectPath, 3002, "dist/app.bin", path, &lib.DownloadOptions{
+		SHA256:   sha256Hex(content),
+		Progress: func(done, total int64) { last = done },
+	})
+	if err != nil {
+		t.Fatalf("SaveJobArtifacts: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != content {
+		t.Fatalf("downloaded %d bytes, want the %d of the artifact", len(data), len(content))
+	}
+	if !d.Checked || d.Resumed != 2 || last != int64(len(content)) {
+		t.Errorf("download = %+v, last progress %d", d, last)
+	}
+	if got, want := rangeRequests(srv), []string{"bytes=102400-", "bytes=153600-"}; !slices.Equal(got, want) {
+		t.Errorf("Range requests = %v, want %v", got, want)
+	}
+}
+
+func TestSaveJobArtifactsLaterRun(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+	content := bigArtifact(srv)
+	path := filepath.Join(t.TempDir(), "app.bin")
+
+	// Without retries the first run fails once a resume receives nothing,
+	// and keeps what it received
+	srv.CutTransfers(1000, 0)
+	if _, err := client.SaveJobArtifacts(gitlabtest.ProjectPath, 3002, "dist/app.bin", path, &lib.DownloadOptions{Retries: -1}); err == nil {
+		t.Fatal("interrupted download succeeded")
+	}
+	if info, err := os.Stat(path + ".part"); err != nil || info.Size() != 1000 {
+		t.Fatalf("partial file: %v, %v", info, err)
+	}
+
+	d, err := client.SaveJobArtifacts(gitlabtest.ProjectPath, 3002, "dist/app.bin", path, nil)
+	if err != nil || d.Resumed != 1 {
+		t.Fatalf("second run = %+v, %v", d, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != content {
+		t.Errorf("resumed download has %d bytes, want %d", len(data), len(content))
+	}
+	last := srv.Requests()[len(srv.Requests())-1]
+	if last.Header.Get("Range") != "bytes=1000-" || last.Header.Get("If-Range") == "" {
+		t.Errorf("second run sent Range %q, If-Range %q", last.Header.Get("Range"), last.Header.Get("If-Range"))
+	}
+
+	// A file changed since the partial download is fetched whole
+	srv.CutTransfers(1000, 0)
+	client.SaveJobArtifacts(gitlabtest.ProjectPath, 3002, "dist/app.bin", path, &lib.DownloadOptions{Retries: -1})
+	changed := strings.ToUpper(content)
+	srv.Project(gitlabtest.ProjectPath).Artifacts[3002]["dist/app.bin"] = changed
+	if _, err := client.SaveJobArtifacts(gitlabtest.ProjectPath, 3002, "dist/app.bin", path, nil); err != nil {
+		t.Fatalf("download of the changed file: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != changed {
+		t.Error("download of the changed file mixes both versions")
+	}
+}
+
+func TestSaveJobArtifactsChecksum(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	path := filepath.Join(t.TempDir(), "size.json")
+
+	_, err := srv.Client().SaveJobArtifacts(gitlabtest.ProjectPath, 3002, "reports/size.json", path, &lib.DownloadOptions{SHA256: sha256Hex("other")})
+	if !errors.Is(err, lib.ErrChecksum) {
+		t.Fatalf("SaveJobArtifacts error = %v, want a checksum mismatch", err)
+	}
+	for _, p := range []string{path, path + ".part", path + ".part.json"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s left behind", filepath.Base(p))
+		}
+	}
+
+	_, err = srv.Client().SaveJobArtifacts(gitlabtest.ProjectPath, 9999, "", path, nil)
+	wantExit(t, err, lib.ExitNotFound)
+}
+
+func TestContentDigest(t *testing.T) {
+	// SHA256 of "hello"
+	const hexSum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	const b64Sum = "LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ="
+	tests := []struct {
+		header map[string]string
+		want   string
+	}{
+		{header: map[string]string{"Repr-Digest": "sha-256=:" + b64Sum + ":"}, want: hexSum},
+		{header: map[string]string{"Repr-Digest": "sha-512=:abc:, sha-256=:" + b64Sum + ":"}, want: hexSum},
+		{header: map[string]string{"Digest": "SHA-256=" + b64Sum}, want: hexSum},
+		{header: map[string]string{"Digest": "md5=XUFAKrxLKna5cZ2REBfFkg=="}},
+		{header: map[string]string{"Digest": "sha-256=not-base64"}},
+		{},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		for k, v := range tt.header {
+			h.Set(k, v)
+		}
+		if got := lib.ContentDigest(h); got != tt.want {
+			t.Errorf("ContentDigest(%v) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
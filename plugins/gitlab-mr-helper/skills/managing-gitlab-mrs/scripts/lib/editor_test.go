@@ -0,0 +1,27 @@
+package lib_test
+
+import (
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestEditText(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "sed -i -e s/draft/final/")
+
+	got, err := lib.EditText("## Summary\n\ndraft text\n", "mr-*.md")
+	if err != nil {
+		t.Fatalf("EditText: %v", err)
+	}
+	if want := "## Summary\n\nfinal text\n"; got != want {
+		t.Errorf("EditText = %q, want %q", got, want)
+	}
+}
+
+func TestEditTextEditorFails(t *testing.T) {
+	t.Setenv("VISUAL", "false")
+	if _, err := lib.EditText("x", "mr-*.md"); err == nil {
+		t.Error("expected an error when the editor exits non-zero")
+	}
+}
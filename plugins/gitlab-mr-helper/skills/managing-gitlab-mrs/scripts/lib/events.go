@@ -0,0 +1,149 @@
+package lib
+
+import (
+	"net/url"
+	"time"
+)
+
+// Event is an entry of a project's activity feed
+type Event struct {
+	ID          int        `json:"id"`
+	ActionName  string     `json:"action_name"` // opened, closed, accepted, commented on, approved, ...
+	TargetType  string     `json:"target_type"` // MergeRequest, Note, DiffNote, Issue, ...
+	TargetIID   int        `json:"target_iid"`
+	TargetTitle string     `json:"target_title"`
+	Author      User       `json:"author"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Note        *EventNote `json:"note"`
+}
+
+// EventNote is the comment attached to a "commented on" event
+type EventNote struct {
+	Body         string `json:"body"`
+	NoteableType string `json:"noteable_type"`
+	NoteableIID  int    `json:"noteable_iid"`
+}
+
+// EventListOptions holds filters for project event listings
+type EventListOptions struct {
+	After      time.Time // only events after this day (GitLab compares dates, not times)
+	TargetType string    // merge_request, note, issue, ...
+	Limit      int       // 0 means no limit
+}
+
+func (o *EventListOptions) query() url.Values {
+	q := url.Values{}
+	if !o.After.IsZero() {
+		q.Set("after", o.After.Format("2006-01-02"))
+	}
+	if o.TargetType != "" {
+		q.Set("target_type", o.TargetType)
+	}
+	return q
+}
+
+// ListProjectEvents lists a project's activity events, newest first
+func (c *Client) ListProjectEvents(projectPath string, opts *EventListOptions) ([]Event, error) {
+	endpoint := c.apiURL("/projects/%s/events", url.PathEscape(projectPath))
+	return getAll[Event](c, endpoint, opts.query(), opts.Limit)
+}
+
+// LabelEvent is a label added to or removed from a merge request
+type LabelEvent struct {
+	ID     int    `json:"id"`
+	Action string `json:"action"` // add or remove
+	Label  *Label `json:"label"`  // nil when the label was deleted since
+	User   User   `json:"user"`
+	// CreatedAt is when the label changed
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListMRLabelEvents lists the label changes of a merge request, oldest first
+func (c *Client) ListMRLabelEvents(projectPath string, mrIID int) ([]LabelEvent, error) {
+	endpoint := c.apiURL("/projects/%s/merge_requests/%d/resource_label_events", url.PathEscape(projectPath), mrIID)
+	return getAll[LabelEvent](c, endpoint, nil, 0)
+}
+
+// mrEventActions maps activity-feed actions to webhook MR actions
+var mrEventActions = map[string]string{
+	"opened":   "open",
+	"closed":   "close",
+	"reopened": "reopen",
+	"accepted": "merge",
+	"approved": "approved",
+}
+
+// WebhookEventFromEvent converts an activity event to the webhook event
+// format used by serve.go, or nil for events that have no webhook
+// counterpart. mrURL builds the MR link from its IID.
+func WebhookEventFromEvent(projectPath string, e *Event, mrURL func(iid int) string) *WebhookEvent {
+	ev := &WebhookEvent{
+		Project:    projectPath,
+		Author:     e.Author.Username,
+		ReceivedAt: e.CreatedAt,
+	}
+	switch {
+	case e.TargetType == "MergeRequest":
+		action, ok := mrEventActions[e.ActionName]
+		if !ok {
+			return nil
+		}
+		ev.Kind = "merge_request"
+		ev.Action = action
+		ev.MRIID = e.TargetIID
+		ev.Title = e.TargetTitle
+	case (e.TargetType == "Note" || e.TargetType == "DiffNote") && e.Note != nil && e.Note.NoteableType == "MergeRequest":
+		ev.Kind = "note"
+		ev.Action = "comment"
+		ev.MRIID = e.Note.NoteableIID
+		ev.Title = e.TargetTitle
+		ev.Body = e.Note.Body
+	default:
+		return nil
+	}
+	ev.URL = mrURL(ev.MRIID)
+	return ev
+}
+
+// WebhookEventFromPipeline converts a pipeline to the webhook event format
+// used by serve.go
+func WebhookEventFromPipeline(projectPath string, p *Pipeline) *WebhookEvent {
+	return &WebhookEvent{
+		Kind:       "pipeline",
+		Action:     p.Status,
+		Project:    projectPath,
+		PipelineID: p.ID,
+		Ref:        p.Ref,
+		SHA:        p.SHA,
+		Status:     p.Status,
+		URL:        p.WebURL,
+		ReceivedAt: p.UpdatedAt,
+	}
+}
+
+// WebhookEventFromLabelEvent converts a label change of mr to the "update"
+// event a merge request webhook sends for it, or nil when the label no
+// longer exists
+func WebhookEventFromLabelEvent(projectPath string, mr *MergeRequest, e *LabelEvent) *WebhookEvent {
+	if e.Label == nil {
+		return nil
+	}
+	ev := &WebhookEvent{
+		Kind:       "merge_request",
+		Action:     "update",
+		Project:    projectPath,
+		MRIID:      mr.IID,
+		Title:      mr.Title,
+		Ref:        mr.SourceBranch,
+		Status:     mr.State,
+		Author:     e.User.Username,
+		URL:        mr.WebURL,
+		ReceivedAt: e.CreatedAt,
+	}
+	if e.Action == "remove" {
+		ev.RemovedLabels = []string{e.Label.Name}
+	} else {
+		ev.AddedLabels = []string{e.Label.Name}
+	}
+	return ev
+}
@@ -0,0 +1,141 @@
+package lib_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestListProjectEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    lib.EventListOptions
+		wantIDs []int
+	}{
+		{name: "newest first", wantIDs: []int{704, 703, 702, 701, 700}},
+		{name: "merge requests", opts: lib.EventListOptions{TargetType: "merge_request"}, wantIDs: []int{702, 701, 700}},
+		{name: "after day", opts: lib.EventListOptions{After: gitlabtest.FixtureTime}, wantIDs: nil},
+		{name: "after previous day", opts: lib.EventListOptions{After: gitlabtest.FixtureTime.AddDate(0, 0, -1), Limit: 2}, wantIDs: []int{704, 703}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			events, err := srv.Client().ListProjectEvents(gitlabtest.ProjectPath, &tt.opts)
+			if err != nil {
+				t.Fatalf("ListProjectEvents: %v", err)
+			}
+			var got []int
+			for _, e := range events {
+				got = append(got, e.ID)
+			}
+			if !equalInts(got, tt.wantIDs) {
+				t.Errorf("events = %v, want %v", got, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestWebhookEventFromEvent(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	events, err := srv.Client().ListProjectEvents(gitlabtest.ProjectPath, &lib.EventListOptions{})
+	if err != nil {
+		t.Fatalf("ListProjectEvents: %v", err)
+	}
+	mrURL := func(iid int) string {
+		return fmt.Sprintf("https://gitlab.example.com/group/project/-/merge_requests/%d", iid)
+	}
+
+	var got []string
+	for i := len(events) - 1; i >= 0; i-- {
+		if ev := lib.WebhookEventFromEvent(gitlabtest.ProjectPath, &events[i], mrURL); ev != nil {
+			got = append(got, fmt.Sprintf("%s:%s !%d", ev.Kind, ev.Action, ev.MRIID))
+		}
+	}
+	want := []string{"merge_request:open !1", "merge_request:open !2", "merge_request:merge !3", "note:comment !1"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestWatchState(t *testing.T) {
+	path := t.TempDir() + "/state.json"
+	state, err := lib.LoadWatchState(path)
+	if err != nil {
+		t.Fatalf("LoadWatchState: %v", err)
+	}
+
+	now := time.Now()
+	if !state.Mark("event:1", now.Add(-10*24*time.Hour)) || !state.Mark("event:2", now) {
+		t.Fatal("fresh keys reported as seen")
+	}
+	if state.Mark("event:2", now) {
+		t.Error("duplicate key reported as new")
+	}
+	state.Prune(now.Add(-7 * 24 * time.Hour))
+	if err := state.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	reloaded, err := lib.LoadWatchState(path)
+	if err != nil {
+		t.Fatalf("LoadWatchState: %v", err)
+	}
+	if _, ok := reloaded.Seen["event:1"]; ok {
+		t.Error("pruned key survived")
+	}
+	if reloaded.Mark("event:2", now) {
+		t.Error("persisted key reported as new")
+	}
+}
+
+func TestWatchStateUpdatedSince(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	horizon := now.AddDate(0, 0, -7)
+
+	state := &lib.WatchState{Since: now.Add(-time.Hour)}
+	if got := state.UpdatedSince(horizon); !got.Equal(state.Since) {
+		t.Errorf("before the first poll: %v, want %v", got, state.Since)
+	}
+	state.Polled = now.Add(-10 * time.Minute)
+	if got, want := state.UpdatedSince(horizon), now.Add(-11*time.Minute); !got.Equal(want) {
+		t.Errorf("after a poll: %v, want %v", got, want)
+	}
+	state.Polled = now.AddDate(0, -1, 0)
+	if got := state.UpdatedSince(horizon); !got.Equal(horizon) {
+		t.Errorf("after a month: %v, want the horizon %v", got, horizon)
+	}
+}
+
+func TestWatchStateAcrossHorizon(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	created := start.Add(time.Hour)
+	state := &lib.WatchState{Since: start, Seen: make(map[string]time.Time)}
+
+	// Poll daily for two weeks, each time listing the same event as a
+	// lookback that never advanced would
+	var emitted []time.Time
+	for now := created; now.Before(start.AddDate(0, 0, 14)); now = now.AddDate(0, 0, 1) {
+		if state.Fresh("event:1", created, now) {
+			emitted = append(emitted, now)
+		}
+		if after := state.UpdatedSince(now.Add(-lib.WatchHorizon)); now.After(created) && after.Before(now.AddDate(0, 0, -1).Add(-time.Hour)) {
+			t.Errorf("poll at %v lists from %v, before the previous poll", now, after)
+		}
+		state.Polled = now
+		state.Prune(now.Add(-lib.WatchHorizon))
+	}
+	if len(emitted) != 1 || !emitted[0].Equal(created) {
+		t.Errorf("event emitted at %v, want once at %v", emitted, created)
+	}
+	if _, ok := state.Seen["event:1"]; ok {
+		t.Error("key kept past the horizon")
+	}
+
+	if state.Fresh("event:2", start.Add(-time.Minute), start.Add(time.Minute)) {
+		t.Error("event from before Since reported as new")
+	}
+}
@@ -0,0 +1,127 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+)
+
+// Exit codes shared by every script. Agents can branch on these instead of
+// parsing stderr.
+const (
+	ExitOK       = 0 // success
+	ExitError    = 1 // any other failure
+	ExitUsage    = 2 // invalid flags or arguments
+	ExitAuth     = 3 // missing, invalid or insufficient token
+	ExitNotFound = 4 // project, MR or other resource not found
+	ExitConflict = 5 // conflict or operation blocked (e.g. MR already exists)
+	ExitTimeout  = 6 // request or wait timed out
+)
+
+var (
+	// ErrNoToken is returned when no GitLab token can be found
+	ErrNoToken = errors.New("no GitLab token found. Set GITLAB_TOKEN environment variable, run auth.go login, or configure ~/.netrc or ~/.git-credentials")
+
+	// ErrBlocked is wrapped by client-side checks that refuse an operation
+	ErrBlocked = errors.New("operation blocked")
+
+	// ErrTimeout is wrapped by waits that give up after a deadline
+	ErrTimeout = errors.New("timed out")
+
+	// ErrNotFound is wrapped by client-side lookups that find nothing
+	ErrNotFound = errors.New("not found")
+)
+
+// APIError is returned for any unexpected HTTP status from the GitLab API
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// UsageError reports invalid flags or arguments
+type UsageError struct {
+	msg string
+}
+
+func (e *UsageError) Error() string {
+	return e.msg
+}
+
+// UsageErrorf formats a UsageError
+func UsageErrorf(format string, a ...interface{}) error {
+	return &UsageError{msg: fmt.Sprintf(format, a...)}
+}
+
+// ExitCode maps an error to the documented exit code
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var usageErr *UsageError
+	if errors.As(err, &usageErr) {
+		return ExitUsage
+	}
+	if errors.Is(err, ErrNoToken) {
+		return ExitAuth
+	}
+	if errors.Is(err, ErrBlocked) {
+		return ExitConflict
+	}
+	if errors.Is(err, ErrTimeout) {
+		return ExitTimeout
+	}
+	if errors.Is(err, ErrNotFound) {
+		return ExitNotFound
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return ExitAuth
+		case http.StatusNotFound:
+			return ExitNotFound
+		case http.StatusConflict, http.StatusMethodNotAllowed, http.StatusNotAcceptable:
+			return ExitConflict
+		case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+			return ExitTimeout
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ExitTimeout
+	}
+
+	return ExitError
+}
+
+// Exit prints "prefix: err" to stderr, followed by a remediation hint when
+// Diagnose recognizes the failure, and exits with the code mapped from err
+func Exit(prefix string, err error) {
+	fmt.Fprintf(os.Stderr, "%s: %v\n", prefix, err)
+	if d := Diagnose(err); d != nil {
+		fmt.Fprintf(os.Stderr, "  Hint: %s. %s\n", d.Problem, d.Hint)
+	}
+	ExitWith(ExitCode(err))
+}
+
+// ExitWith exits with code after printing the --stats summary; scripts use
+// it instead of os.Exit, which skips the deferred ReportStats
+func ExitWith(code int) {
+	ReportStats()
+	os.Exit(code)
+}
+
+// Usagef prints a usage error to stderr and exits with ExitUsage
+func Usagef(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", a...)
+	ExitWith(ExitUsage)
+}
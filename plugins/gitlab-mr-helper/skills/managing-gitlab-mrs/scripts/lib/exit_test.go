@@ -0,0 +1,53 @@
+package lib_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "nil", err: nil, want: lib.ExitOK},
+		{name: "plain error", err: errors.New("boom"), want: lib.ExitError},
+		{name: "usage", err: lib.UsageErrorf("bad flag"), want: lib.ExitUsage},
+		{name: "wrapped usage", err: fmt.Errorf("parsing: %w", lib.UsageErrorf("bad flag")), want: lib.ExitUsage},
+		{name: "no token", err: fmt.Errorf("config: %w", lib.ErrNoToken), want: lib.ExitAuth},
+		{name: "401", err: &lib.APIError{StatusCode: 401}, want: lib.ExitAuth},
+		{name: "403", err: fmt.Errorf("merging: %w", &lib.APIError{StatusCode: 403}), want: lib.ExitAuth},
+		{name: "404", err: &lib.APIError{StatusCode: 404}, want: lib.ExitNotFound},
+		{name: "not found", err: fmt.Errorf("%w: no MR for branch", lib.ErrNotFound), want: lib.ExitNotFound},
+		{name: "blocked", err: fmt.Errorf("%w: MR !1 already exists", lib.ErrBlocked), want: lib.ExitConflict},
+		{name: "405", err: &lib.APIError{StatusCode: 405}, want: lib.ExitConflict},
+		{name: "409", err: &lib.APIError{StatusCode: 409}, want: lib.ExitConflict},
+		{name: "500", err: &lib.APIError{StatusCode: 500}, want: lib.ExitError},
+		{name: "timeout", err: fmt.Errorf("%w: pipeline still running", lib.ErrTimeout), want: lib.ExitTimeout},
+		{name: "504", err: &lib.APIError{StatusCode: 504}, want: lib.ExitTimeout},
+		{
+			name: "network timeout",
+			err:  fmt.Errorf("failed to execute request: %w", &url.Error{Op: "Get", URL: "https://gitlab.example.com", Err: &net.DNSError{Err: "i/o timeout", IsTimeout: true}}),
+			want: lib.ExitTimeout,
+		},
+		{
+			name: "network error",
+			err:  fmt.Errorf("failed to execute request: %w", &url.Error{Op: "Get", URL: "https://gitlab.example.com", Err: &net.DNSError{Err: "no such host"}}),
+			want: lib.ExitError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lib.ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
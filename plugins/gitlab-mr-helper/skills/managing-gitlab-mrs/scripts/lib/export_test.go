@@ -0,0 +1,4 @@
+package lib
+
+// PBKDF2SHA256 exposes the key derivation of the credential store to tests
+var PBKDF2SHA256 = pbkdf2SHA256
@@ -0,0 +1,138 @@
+package lib
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Feature flag strategy names
+const (
+	StrategyDefault         = "default"
+	StrategyFlexibleRollout = "flexibleRollout"
+	StrategyUserWithID      = "userWithId"
+)
+
+// FeatureFlag is a project feature flag, served to applications through
+// GitLab's Unleash API. An active flag is on wherever one of its strategies
+// applies.
+type FeatureFlag struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Active      bool           `json:"active"`
+	Version     string         `json:"version,omitempty"`
+	Strategies  []FlagStrategy `json:"strategies"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// FlagStrategy turns a flag on for some users in some environments
+type FlagStrategy struct {
+	ID   int    `json:"id,omitempty"`
+	Name string `json:"name"`
+	// Parameters depend on Name, e.g. rollout for flexibleRollout and
+	// userIds for userWithId
+	Parameters map[string]string `json:"parameters"`
+	Scopes     []FlagScope       `json:"scopes"`
+}
+
+// FlagScope is an environment (or wildcard like "review/*") a strategy
+// applies to
+type FlagScope struct {
+	ID               int    `json:"id,omitempty"`
+	EnvironmentScope string `json:"environment_scope"`
+}
+
+// Summary describes who a strategy enables the flag for and where, e.g.
+// "25% of users on production, staging"
+func (s *FlagStrategy) Summary() string {
+	var who string
+	switch s.Name {
+	case StrategyDefault:
+		who = "all users"
+	case StrategyFlexibleRollout, "gradualRolloutUserId":
+		pct := s.Parameters["rollout"]
+		if pct == "" {
+			pct = s.Parameters["percentage"]
+		}
+		who = pct + "% of users"
+	case StrategyUserWithID:
+		who = "users " + s.Parameters["userIds"]
+	case "gitlabUserList":
+		who = "a user list"
+	default:
+		who = s.Name
+	}
+	envs := make([]string, len(s.Scopes))
+	for i, sc := range s.Scopes {
+		envs[i] = sc.EnvironmentScope
+	}
+	if len(envs) == 0 || (len(envs) == 1 && envs[0] == "*") {
+		return who + " in all environments"
+	}
+	sort.Strings(envs)
+	return who + " on " + strings.Join(envs, ", ")
+}
+
+// CreateFeatureFlagRequest represents the request body for creating a flag
+type CreateFeatureFlagRequest struct {
+	Name        string         `json:"name"`
+	Version     string         `json:"version"`
+	Description string         `json:"description,omitempty"`
+	Active      bool           `json:"active"`
+	Strategies  []FlagStrategy `json:"strategies,omitempty"`
+}
+
+// ListFeatureFlags lists a project's feature flags; scope is "enabled",
+// "disabled" or "" for all
+func (c *Client) ListFeatureFlags(projectPath, scope string) ([]FeatureFlag, error) {
+	endpoint := c.apiURL("/projects/%s/feature_flags", url.PathEscape(projectPath))
+	q := url.Values{}
+	if scope != "" {
+		q.Set("scope", scope)
+	}
+	return getAll[FeatureFlag](c, endpoint, q, 0)
+}
+
+// GetFeatureFlag gets a single feature flag by name
+func (c *Client) GetFeatureFlag(projectPath, name string) (*FeatureFlag, error) {
+	endpoint := c.apiURL("/projects/%s/feature_flags/%s", url.PathEscape(projectPath), url.PathEscape(name))
+
+	var flag FeatureFlag
+	if err := c.do("GET", endpoint, nil, &flag, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// CreateFeatureFlag creates a feature flag. A flag without strategies is
+// off everywhere, even when active.
+func (c *Client) CreateFeatureFlag(projectPath string, req *CreateFeatureFlagRequest) (*FeatureFlag, error) {
+	endpoint := c.apiURL("/projects/%s/feature_flags", url.PathEscape(projectPath))
+	if req.Version == "" {
+		req.Version = "new_version_flag"
+	}
+
+	var flag FeatureFlag
+	if err := c.do("POST", endpoint, req, &flag, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// SetFeatureFlagActive turns a feature flag on or off, keeping its
+// strategies
+func (c *Client) SetFeatureFlagActive(projectPath, name string, active bool) (*FeatureFlag, error) {
+	endpoint := c.apiURL("/projects/%s/feature_flags/%s", url.PathEscape(projectPath), url.PathEscape(name))
+	req := struct {
+		Active bool `json:"active"`
+	}{active}
+
+	var flag FeatureFlag
+	if err := c.do("PUT", endpoint, &req, &flag, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
@@ -0,0 +1,62 @@
+package lib_test
+
+import (
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	for scope, want := range map[string]int{"": 2, "enabled": 1, "disabled": 1} {
+		flags, err := client.ListFeatureFlags(gitlabtest.ProjectPath, scope)
+		if err != nil || len(flags) != want {
+			t.Errorf("ListFeatureFlags(%q) = %d flags, %v; want %d", scope, len(flags), err, want)
+		}
+	}
+
+	req := &lib.CreateFeatureFlagRequest{Name: "beta_search", Active: true, Strategies: []lib.FlagStrategy{
+		{Name: lib.StrategyUserWithID, Parameters: map[string]string{"userIds": "1,2"}, Scopes: []lib.FlagScope{{EnvironmentScope: "staging"}}},
+	}}
+	flag, err := client.CreateFeatureFlag(gitlabtest.ProjectPath, req)
+	if err != nil {
+		t.Fatalf("CreateFeatureFlag: %v", err)
+	}
+	if flag.Version != "new_version_flag" || flag.Strategies[0].ID == 0 || flag.Strategies[0].Scopes[0].ID == 0 {
+		t.Errorf("created flag = %+v", flag)
+	}
+	if _, err := client.CreateFeatureFlag(gitlabtest.ProjectPath, req); err == nil || !strings.Contains(err.Error(), "already been taken") {
+		t.Errorf("creating a duplicate flag: error = %v", err)
+	}
+
+	if flag, err = client.SetFeatureFlagActive(gitlabtest.ProjectPath, "new_checkout", false); err != nil || flag.Active || len(flag.Strategies) != 2 {
+		t.Errorf("SetFeatureFlagActive = %+v, %v", flag, err)
+	}
+	if flag, err = client.GetFeatureFlag(gitlabtest.ProjectPath, "new_checkout"); err != nil || flag.Active {
+		t.Errorf("GetFeatureFlag after disabling = %+v, %v", flag, err)
+	}
+	if _, err := client.SetFeatureFlagActive(gitlabtest.ProjectPath, "missing", true); lib.ExitCode(err) != lib.ExitNotFound {
+		t.Errorf("SetFeatureFlagActive(missing) error = %v, want not found", err)
+	}
+}
+
+func TestFlagStrategySummary(t *testing.T) {
+	tests := []struct {
+		strategy lib.FlagStrategy
+		want     string
+	}{
+		{lib.FlagStrategy{Name: lib.StrategyDefault, Scopes: []lib.FlagScope{{EnvironmentScope: "*"}}}, "all users in all environments"},
+		{lib.FlagStrategy{Name: lib.StrategyFlexibleRollout, Parameters: map[string]string{"rollout": "25"}, Scopes: []lib.FlagScope{{EnvironmentScope: "staging"}, {EnvironmentScope: "production"}}}, "25% of users on production, staging"},
+		{lib.FlagStrategy{Name: lib.StrategyUserWithID, Parameters: map[string]string{"userIds": "1,2"}}, "users 1,2 in all environments"},
+		{lib.FlagStrategy{Name: "gradualRolloutUserId", Parameters: map[string]string{"percentage": "10"}, Scopes: []lib.FlagScope{{EnvironmentScope: "review/*"}}}, "10% of users on review/*"},
+	}
+	for _, tt := range tests {
+		if got := tt.strategy.Summary(); got != tt.want {
+			t.Errorf("Summary() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package lib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatAge renders a timestamp relative to now ("5m ago", "3h ago", "2d ago"),
+// falling back to FormatDate for anything older than a week. The words
+// follow the display locale (see SetLocale).
+func FormatAge(t time.Time) string {
+	duration := time.Since(t)
+
+	if duration < time.Hour {
+		return fmt.Sprintf(locale.Ago, fmt.Sprintf("%d%s", int(duration.Minutes()), locale.Minutes))
+	} else if duration < 24*time.Hour {
+		return fmt.Sprintf(locale.Ago, fmt.Sprintf("%d%s", int(duration.Hours()), locale.Hours))
+	} else if duration < 7*24*time.Hour {
+		return fmt.Sprintf(locale.Ago, fmt.Sprintf("%d%s", int(duration.Hours()/24), locale.Days))
+	} else {
+		return FormatDate(t)
+	}
+}
+
+// ShortSHA abbreviates a commit SHA to 8 characters, like GitLab's short_id
+func ShortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
+
+// FormatFileList lists the first few of files, e.g. "a.go, b.go, c.go and
+// 2 more"
+func FormatFileList(files []string) string {
+	const shown = 3
+	if len(files) > shown {
+		return fmt.Sprintf("%s and %d more", strings.Join(files[:shown], ", "), len(files)-shown)
+	}
+	return strings.Join(files, ", ")
+}
+
+// FormatSize renders a byte count in binary units, e.g. "1.5 MB", with the
+// decimal separator of the display locale
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	num := strings.Replace(fmt.Sprintf("%.1f", float64(n)/float64(div)), ".", locale.Decimal, 1)
+	return fmt.Sprintf("%s %cB", num, "KMGTPE"[exp])
+}
+
+// ParseSize parses a byte count like FormatSize renders it: a number with
+// an optional binary unit, e.g. "512", "100KB", "1.5 GB" or "2GiB"
+func ParseSize(s string) (int64, error) {
+	num := strings.TrimSpace(s)
+	unit := strings.TrimLeft(num, "0123456789.")
+	num = strings.TrimSpace(strings.TrimSuffix(num, unit))
+	shift := map[string]uint{"": 0, "B": 0, "K": 10, "M": 20, "G": 30, "T": 40, "P": 50}
+	u := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(unit)), "B"), "I")
+	n, err := strconv.ParseFloat(num, 64)
+	bits, ok := shift[u]
+	if err != nil || !ok || n < 0 {
+		return 0, UsageErrorf("invalid size %q (expected e.g. 500MB or 10GB)", s)
+	}
+	return int64(n * float64(int64(1)<<bits)), nil
+}
@@ -0,0 +1,164 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// GetCurrentBranch returns the branch checked out in the working directory.
+// CI jobs check out a detached HEAD, so there it is the job's ref.
+func GetCurrentBranch() (string, error) {
+	output, err := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD").Output()
+	branch := strings.TrimSpace(string(output))
+	if job := CurrentCIJob(); job != nil && job.Ref != "" && (err != nil || branch == "HEAD") {
+		return job.Ref, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get current branch: %w", err)
+	}
+	return branch, nil
+}
+
+// LocalBranchExists reports whether branch exists in the local repository
+func LocalBranchExists(branch string) bool {
+	return exec.Command("git", "show-ref", "--verify", "--quiet", "refs/heads/"+branch).Run() == nil
+}
+
+// HasUpstream reports whether a local branch tracks a remote branch
+func HasUpstream(branch string) bool {
+	return exec.Command("git", "rev-parse", "--abbrev-ref", "--symbolic-full-name", branch+"@{upstream}").Run() == nil
+}
+
+// NeedsPush reports whether branch exists only locally, without an
+// upstream: GitLab cannot open an MR from it until it is pushed
+func NeedsPush(branch string) bool {
+	return LocalBranchExists(branch) && !HasUpstream(branch)
+}
+
+// PushBranch pushes a local branch to remote and sets it as upstream. No push
+// options are passed, so GitLab does not create an MR on its own
+// (merge_request.create) and the caller stays in control of MR creation.
+func PushBranch(remote, branch string) error {
+	cmd := exec.Command("git", "push", "--set-upstream", remote, branch)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git push %s %s failed: %w", remote, branch, err)
+	}
+	return nil
+}
+
+// FindGitLabRemote returns the remote of the working directory that points
+// at the GitLab instance, and its URL. A named remote is used as is.
+// Otherwise, among all remotes, one on the host of GITLAB_URL wins over the
+// rest, and origin over other remotes on the same footing, so fork layouts
+// with origin and upstream on different hosts resolve to the GitLab one.
+func FindGitLabRemote(name string) (remote, remoteURL string, err error) {
+	if name != "" {
+		remoteURL, err := remoteGetURL(name)
+		if err != nil {
+			return "", "", err
+		}
+		return name, remoteURL, nil
+	}
+
+	output, err := exec.Command("git", "remote").Output()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to list git remotes: %w", err)
+	}
+	names := strings.Fields(string(output))
+	if len(names) == 0 {
+		return "", "", fmt.Errorf("no git remote configured")
+	}
+
+	host := GitLabHost()
+	best, bestScore := -1, -1
+	urls := make([]string, len(names))
+	for i, n := range names {
+		if urls[i], err = remoteGetURL(n); err != nil {
+			return "", "", err
+		}
+		score := 0
+		if h, _, err := ParseRemoteURL(urls[i]); err == nil && strings.EqualFold(h, host) {
+			score += 2
+		}
+		if n == "origin" {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return names[best], urls[best], nil
+}
+
+func remoteGetURL(name string) (string, error) {
+	output, err := exec.Command("git", "remote", "get-url", name).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get git remote %s: %w", name, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// LocalDiff returns the per-file diffs of `git diff` with args, e.g.
+// "main...HEAD" or "--cached", in the shape of the GitLab diff endpoints
+func LocalDiff(args ...string) ([]Diff, error) {
+	cmd := exec.Command("git", append([]string{"diff", "--no-color", "--no-ext-diff", "--no-renames"}, args...)...)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			msg, _, _ := strings.Cut(strings.TrimSpace(string(exitErr.Stderr)), "\n")
+			err = fmt.Errorf("%s", msg)
+		}
+		return nil, fmt.Errorf("git diff %s failed: %w", strings.Join(args, " "), err)
+	}
+	return ParseGitDiff(string(output)), nil
+}
+
+// ParseGitDiff splits the output of `git diff` into per-file diffs. Diff
+// holds the hunks, from the first "@@" line, as GitLab returns them.
+func ParseGitDiff(output string) []Diff {
+	var diffs []Diff
+	var d *Diff
+	inHunks := false
+	for _, line := range strings.SplitAfter(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			diffs = append(diffs, Diff{})
+			d = &diffs[len(diffs)-1]
+			inHunks = false
+		case d == nil:
+		case inHunks || strings.HasPrefix(line, "@@"):
+			inHunks = true
+			d.Diff += line
+		case strings.HasPrefix(line, "--- "):
+			d.OldPath = diffPath(line[4:], "a/")
+		case strings.HasPrefix(line, "+++ "):
+			d.NewPath = diffPath(line[4:], "b/")
+		case strings.HasPrefix(line, "new file mode"):
+			d.NewFile = true
+		case strings.HasPrefix(line, "deleted file mode"):
+			d.DeletedFile = true
+		}
+	}
+	for i := range diffs {
+		if diffs[i].NewFile {
+			diffs[i].OldPath = diffs[i].NewPath
+		}
+		if diffs[i].DeletedFile {
+			diffs[i].NewPath = diffs[i].OldPath
+		}
+	}
+	return diffs
+}
+
+// diffPath strips the a/ or b/ prefix of a ---/+++ line; /dev/null is empty
+func diffPath(s, prefix string) string {
+	s = strings.TrimSpace(s)
+	if s == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(s, prefix)
+}
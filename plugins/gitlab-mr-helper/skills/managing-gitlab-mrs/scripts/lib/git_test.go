@@ -0,0 +1,30 @@
+package lib_test
+
+import (
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestNeedsPush(t *testing.T) {
+	remote := t.TempDir()
+	git(t, "init", "-q", "--bare", remote)
+	chdirRepo(t, remote)
+	git(t, "-c", "user.name=dev", "-c", "user.email=dev@example.com", "commit", "-q", "--allow-empty", "-m", "init")
+	git(t, "checkout", "-q", "-b", "feature/local")
+
+	if !lib.NeedsPush("feature/local") {
+		t.Error("a branch without upstream does not need a push")
+	}
+	if lib.NeedsPush("feature/remote-only") {
+		t.Error("a branch that is not local needs a push")
+	}
+
+	if err := lib.PushBranch("origin", "feature/local"); err != nil {
+		t.Fatalf("PushBranch: %v", err)
+	}
+	if lib.NeedsPush("feature/local") {
+		t.Error("a pushed branch still needs a push")
+	}
+	git(t, "--git-dir", remote, "rev-parse", "--verify", "-q", "refs/heads/feature/local")
+}
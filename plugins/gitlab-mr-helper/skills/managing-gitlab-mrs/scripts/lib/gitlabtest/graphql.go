@@ -0,0 +1,320 @@
+package gitlabtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+// notFoundMessage is GitLab's GraphQL error for missing resources
+const notFoundMessage = "The resource that you are attempting to access does not exist or you don't have permission to perform this action"
+
+// mutationName finds the mutation of a SetVulnerabilityState query
+var mutationName = regexp.MustCompile(`result:\s*(\w+)\(`)
+
+// serveGraphQL answers the named operations lib sends to /api/graphql
+func (s *Server) serveGraphQL(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var req struct {
+		OperationName string                     `json:"operationName"`
+		Query         string                     `json:"query"`
+		Variables     map[string]json.RawMessage `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid GraphQL request")
+		return
+	}
+	var input struct {
+		ID               string   `json:"id"`
+		Comment          string   `json:"comment"`
+		DismissalReason  string   `json:"dismissalReason"`
+		IssueID          string   `json:"issueId"`
+		VulnerabilityIDs []string `json:"vulnerabilityIds"`
+	}
+	json.Unmarshal(req.Variables["input"], &input)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch req.OperationName {
+	case "ProjectVulnerabilities":
+		s.graphQLVulnerabilities(w, req.Variables)
+
+	case "SetVulnerabilityState":
+		_, v := s.findVulnerability(input.ID)
+		if v == nil {
+			writeGraphQL(w, map[string]interface{}{"result": nil}, notFoundMessage)
+			return
+		}
+		m := mutationName.FindStringSubmatch(req.Query)
+		if m == nil {
+			writeGraphQL(w, nil, "no mutation in query")
+			return
+		}
+		state := map[string]string{
+			"vulnerabilityConfirm":          lib.VulnerabilityConfirmed,
+			"vulnerabilityResolve":          lib.VulnerabilityResolved,
+			"vulnerabilityDismiss":          lib.VulnerabilityDismissed,
+			"vulnerabilityRevertToDetected": lib.VulnerabilityDetected,
+		}[m[1]]
+		if state == "" {
+			writeGraphQL(w, nil, "unknown mutation "+m[1])
+			return
+		}
+		var errs []string
+		if v.State == state {
+			errs = append(errs, fmt.Sprintf("Vulnerability is already %s", state))
+		} else {
+			v.State = state
+			v.DismissalReason = ""
+			if state == lib.VulnerabilityDismissed {
+				v.DismissalReason = strings.ToLower(input.DismissalReason)
+			}
+		}
+		writeGraphQL(w, map[string]interface{}{"result": map[string]interface{}{"vulnerability": vulnerabilityNode(v), "errors": errs}})
+
+	case "LinkVulnerabilityIssue":
+		issueID, _ := lib.ParseGlobalID(input.IssueID)
+		var errs []string
+		for _, gid := range input.VulnerabilityIDs {
+			p, v := s.findVulnerability(gid)
+			if v == nil {
+				writeGraphQL(w, map[string]interface{}{"vulnerabilityIssueLinkCreate": nil}, notFoundMessage)
+				return
+			}
+			var issue *lib.Issue
+			for _, i := range p.Issues {
+				if i.ID == issueID {
+					issue = i
+				}
+			}
+			switch {
+			case issue == nil:
+				writeGraphQL(w, map[string]interface{}{"vulnerabilityIssueLinkCreate": nil}, notFoundMessage)
+				return
+			case linked(v, issue.IID):
+				errs = append(errs, "Issue has already been linked to vulnerability")
+			default:
+				v.Issues = append(v.Issues, lib.Issue{IID: issue.IID, WebURL: issue.WebURL})
+			}
+		}
+		writeGraphQL(w, map[string]interface{}{"vulnerabilityIssueLinkCreate": map[string]interface{}{"errors": errs}})
+
+	case "NamespaceStorage":
+		var fullPath string
+		json.Unmarshal(req.Variables["fullPath"], &fullPath)
+		var used int64
+		found := false
+		for _, p := range s.projects {
+			if p.Group == fullPath || strings.HasPrefix(p.Group, fullPath+"/") {
+				used += p.Statistics.StorageSize
+				found = true
+			}
+		}
+		if !found {
+			writeGraphQL(w, map[string]interface{}{"namespace": nil})
+			return
+		}
+		ns := map[string]interface{}{"fullPath": fullPath, "storageSizeLimit": nil, "additionalPurchasedStorageSize": 0,
+			"rootStorageStatistics": map[string]interface{}{"storageSize": used}}
+		if limit, ok := s.storageLimits[fullPath]; ok {
+			ns["storageSizeLimit"] = limit
+		}
+		writeGraphQL(w, map[string]interface{}{"namespace": ns})
+
+	case "ProjectTerraformStates":
+		var fullPath string
+		json.Unmarshal(req.Variables["fullPath"], &fullPath)
+		p := s.findProject(fullPath)
+		if p == nil {
+			writeGraphQL(w, map[string]interface{}{"project": nil})
+			return
+		}
+		nodes := []interface{}{}
+		for _, st := range p.TerraformStates {
+			nodes = append(nodes, terraformStateNode(st))
+		}
+		writeGraphQL(w, map[string]interface{}{"project": map[string]interface{}{"terraformStates": map[string]interface{}{
+			"nodes":    nodes,
+			"pageInfo": map[string]interface{}{"hasNextPage": false, "endCursor": ""},
+		}}})
+
+	case "LockTerraformState", "UnlockTerraformState":
+		st := s.findTerraformState(input.ID)
+		if st == nil {
+			writeGraphQL(w, map[string]interface{}{"result": nil}, notFoundMessage)
+			return
+		}
+		errs := []string{}
+		switch {
+		case req.OperationName == "UnlockTerraformState":
+			st.LockedAt, st.LockedBy = nil, ""
+		case st.Locked():
+			errs = append(errs, "state is already locked")
+		default:
+			now := time.Now().UTC()
+			st.LockedAt, st.LockedBy = &now, s.user.Username
+		}
+		writeGraphQL(w, map[string]interface{}{"result": map[string]interface{}{"errors": errs}})
+
+	default:
+		writeGraphQL(w, nil, "unknown operation "+req.OperationName)
+	}
+}
+
+// findTerraformState looks a Terraform state up by global ID in every
+// project. Callers must hold s.mu.
+func (s *Server) findTerraformState(gid string) *lib.TerraformState {
+	id, err := lib.ParseGlobalID(gid)
+	if err != nil {
+		return nil
+	}
+	for _, p := range s.projects {
+		for _, st := range p.TerraformStates {
+			if st.ID == id {
+				return st
+			}
+		}
+	}
+	return nil
+}
+
+// terraformStateNode renders a Terraform state the way GraphQL returns it
+func terraformStateNode(st *lib.TerraformState) map[string]interface{} {
+	user := func(username string) interface{} {
+		if username == "" {
+			return nil
+		}
+		return map[string]interface{}{"username": username}
+	}
+	var job interface{}
+	if st.Job != "" {
+		job = map[string]interface{}{"name": st.Job}
+	}
+	return map[string]interface{}{
+		"id":           lib.GlobalID("Terraform::State", st.ID),
+		"name":         st.Name,
+		"lockedAt":     st.LockedAt,
+		"updatedAt":    st.UpdatedAt,
+		"lockedByUser": user(st.LockedBy),
+		"latestVersion": map[string]interface{}{
+			"serial": st.Serial, "createdAt": st.WrittenAt, "createdByUser": user(st.WrittenBy), "job": job,
+		},
+	}
+}
+
+// graphQLVulnerabilities answers ProjectVulnerabilities; the cursor is the
+// index of the next vulnerability. Callers must hold s.mu.
+func (s *Server) graphQLVulnerabilities(w http.ResponseWriter, vars map[string]json.RawMessage) {
+	var fullPath, after string
+	var states, severities, types []string
+	first := 100
+	json.Unmarshal(vars["fullPath"], &fullPath)
+	json.Unmarshal(vars["after"], &after)
+	json.Unmarshal(vars["state"], &states)
+	json.Unmarshal(vars["severity"], &severities)
+	json.Unmarshal(vars["reportType"], &types)
+	json.Unmarshal(vars["first"], &first)
+
+	p := s.findProject(fullPath)
+	if p == nil {
+		writeGraphQL(w, map[string]interface{}{"project": nil})
+		return
+	}
+	var matched []*lib.Vulnerability
+	for _, v := range p.Vulnerabilities {
+		if (len(states) == 0 || contains(states, strings.ToUpper(v.State))) &&
+			(len(severities) == 0 || contains(severities, strings.ToUpper(v.Severity))) &&
+			(len(types) == 0 || contains(types, strings.ToUpper(v.ReportType))) {
+			matched = append(matched, v)
+		}
+	}
+	start, _ := strconv.Atoi(after)
+	end := min(start+first, len(matched))
+	nodes := []interface{}{}
+	for _, v := range matched[start:end] {
+		nodes = append(nodes, vulnerabilityNode(v))
+	}
+	writeGraphQL(w, map[string]interface{}{"project": map[string]interface{}{"vulnerabilities": map[string]interface{}{
+		"nodes":    nodes,
+		"pageInfo": map[string]interface{}{"hasNextPage": end < len(matched), "endCursor": strconv.Itoa(end)},
+	}}})
+}
+
+// findVulnerability looks a vulnerability up by global ID in every
+// project. Callers must hold s.mu.
+func (s *Server) findVulnerability(gid string) (*Project, *lib.Vulnerability) {
+	id, err := lib.ParseGlobalID(gid)
+	if err != nil {
+		return nil, nil
+	}
+	for _, p := range s.projects {
+		for _, v := range p.Vulnerabilities {
+			if v.ID == id {
+				return p, v
+			}
+		}
+	}
+	return nil, nil
+}
+
+// vulnerabilityNode renders a vulnerability the way GraphQL returns it
+func vulnerabilityNode(v *lib.Vulnerability) map[string]interface{} {
+	links := []interface{}{}
+	for _, i := range v.Issues {
+		links = append(links, map[string]interface{}{"issue": map[string]interface{}{"iid": strconv.Itoa(i.IID), "webUrl": i.WebURL}})
+	}
+	var reason interface{}
+	if v.DismissalReason != "" {
+		reason = strings.ToUpper(v.DismissalReason)
+	}
+	return map[string]interface{}{
+		"id":                lib.GlobalID("Vulnerability", v.ID),
+		"title":             v.Title,
+		"state":             strings.ToUpper(v.State),
+		"severity":          strings.ToUpper(v.Severity),
+		"reportType":        strings.ToUpper(v.ReportType),
+		"detectedAt":        v.DetectedAt,
+		"webUrl":            v.WebURL,
+		"dismissalReason":   reason,
+		"scanner":           map[string]string{"name": v.Scanner},
+		"primaryIdentifier": map[string]string{"name": v.Identifier},
+		"issueLinks":        map[string]interface{}{"nodes": links},
+	}
+}
+
+func linked(v *lib.Vulnerability, iid int) bool {
+	for _, i := range v.Issues {
+		if i.IID == iid {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// writeGraphQL writes a GraphQL response with the given data and errors
+func writeGraphQL(w http.ResponseWriter, data interface{}, errs ...string) {
+	resp := map[string]interface{}{"data": data}
+	if len(errs) > 0 {
+		var list []map[string]string
+		for _, e := range errs {
+			list = append(list, map[string]string{"message": e})
+		}
+		resp["errors"] = list
+	}
+	WriteJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,98 @@
+package gitlabtest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+// AddLFSObject stores content as a Git LFS object of the project and
+// returns the pointer file that stands in for it in the repository
+func (p *Project) AddLFSObject(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	oid := hex.EncodeToString(sum[:])
+	p.LFSObjects[oid] = content
+	return fmt.Sprintf("%s\noid sha256:%s\nsize %d\n", lib.LFSPointerVersion, oid, len(content))
+}
+
+// pointerDiff is the diff between two pointer files, which differ in all
+// but the version line
+func pointerDiff(old, new string) string {
+	o, n := strings.Split(old, "\n"), strings.Split(new, "\n")
+	return fmt.Sprintf("@@ -1,3 +1,3 @@\n %s\n-%s\n-%s\n+%s\n+%s\n", o[0], o[1], o[2], n[1], n[2])
+}
+
+// lfsAuthorization is the header the batch API hands out with download
+// actions; downloads must present it instead of the token
+func lfsAuthorization(token string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte("gitlab-lfs:"+token))
+}
+
+// serveGit answers the Git LFS endpoints of repositories, which GitLab
+// serves outside the API and authenticates over HTTP basic authentication
+func (s *Server) serveGit(w http.ResponseWriter, r *http.Request, token string) {
+	repo, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), ".git/")
+	s.mu.Lock()
+	p := s.findProject(repo)
+	s.mu.Unlock()
+	if p == nil {
+		WriteError(w, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	switch oid, isObject := strings.CutPrefix(rest, "gitlab-lfs/objects/"); {
+	case r.Method == http.MethodPost && rest == "info/lfs/objects/batch":
+		if _, password, ok := r.BasicAuth(); !ok || password != token {
+			WriteError(w, http.StatusUnauthorized, "401 Unauthorized")
+			return
+		}
+		var req struct {
+			Operation string           `json:"operation"`
+			Objects   []lib.LFSPointer `json:"objects"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Operation != "download" {
+			WriteError(w, http.StatusUnprocessableEntity, "only download batches are supported")
+			return
+		}
+		objects := []lib.LFSObject{}
+		s.mu.Lock()
+		for _, ptr := range req.Objects {
+			obj := lib.LFSObject{OID: ptr.OID, Size: ptr.Size}
+			if _, ok := p.LFSObjects[ptr.OID]; ok {
+				obj.Actions.Download = &lib.LFSAction{
+					Href:      fmt.Sprintf("%s/%s.git/gitlab-lfs/objects/%s", s.URL, p.Path, ptr.OID),
+					Header:    map[string]string{"Authorization": lfsAuthorization(token)},
+					ExpiresIn: 3600,
+				}
+			} else {
+				obj.Error = &lib.LFSError{Code: http.StatusNotFound, Message: "Object does not exist on the server or you don't have permissions to access it"}
+			}
+			objects = append(objects, obj)
+		}
+		s.mu.Unlock()
+		WriteJSON(w, http.StatusOK, map[string]any{"objects": objects})
+
+	case r.Method == http.MethodGet && isObject:
+		if r.Header.Get("Authorization") != lfsAuthorization(token) {
+			WriteError(w, http.StatusUnauthorized, "401 Unauthorized")
+			return
+		}
+		s.mu.Lock()
+		content, ok := p.LFSObjects[oid]
+		s.mu.Unlock()
+		if !ok {
+			WriteError(w, http.StatusNotFound, "404 Not Found")
+			return
+		}
+		s.serveConditional(w, r, func(w http.ResponseWriter) { ServeFile(w, r, oid, content) })
+
+	default:
+		WriteError(w, http.StatusNotFound, "404 Not Found")
+	}
+}
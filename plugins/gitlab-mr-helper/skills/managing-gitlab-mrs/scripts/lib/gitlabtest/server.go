@@ -0,0 +1,2962 @@
+// Package gitlabtest provides an in-memory fake of the GitLab REST API for
+// testing the lib client without a real instance.
+package gitlabtest
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+// Token is the PRIVATE-TOKEN the fake server accepts
+const Token = "glpat-test-token"
+
+// Version is the GitLab version the fake server reports
+const Version = "16.9.1"
+
+// Project holds the state of one fake project
+type Project struct {
+	ID        int
+	Path      string
+	Group     string
+	MRs       []*lib.MergeRequest
+	Diffs     map[int][]lib.Diff
+	Approvals map[int]*lib.Approvals
+	Notes     map[int][]lib.Note
+	Pipelines []lib.Pipeline
+	Labels    []lib.Label
+	Branches  []lib.Branch
+	Tags      []lib.Tag         // most recently updated first
+	Files     map[string]string // path → content, shared by all refs
+	Traces    map[int]string    // job ID → log
+	Issues    []*lib.Issue
+	// IssueNotes maps issue IIDs to their comments, oldest first
+	IssueNotes map[int][]lib.Note
+	Milestones []lib.Milestone
+	// AccessTokens and DeployKeys are the project's credentials
+	AccessTokens []lib.AccessToken
+	DeployKeys   []lib.DeployKey
+	Members      []lib.Member
+	Events       []lib.Event // activity feed, oldest first
+	// Discussions maps MR IIDs to their threads
+	Discussions map[int][]lib.Discussion
+	// Compare maps "from...to" to the comparison returned for those refs
+	Compare map[string]*lib.Comparison
+	// Commits maps MR IIDs to their commits, newest first; CommitDiffs and
+	// Signatures map commit SHAs to the diffs they introduced and to their
+	// signatures, absent for unsigned commits
+	Commits     map[int][]lib.Commit
+	CommitDiffs map[string][]lib.Diff
+	Signatures  map[string]lib.CommitSignature
+	// LFSObjects maps the OIDs of Git LFS objects to their content
+	LFSObjects map[string]string
+	// FileHistory maps file paths to the commits of the default branch
+	// that touched them, newest first
+	FileHistory map[string][]lib.Commit
+	// Blame maps file paths to who last changed their lines
+	Blame map[string][]lib.BlameRange
+	// Languages maps languages to their percentage of the code, and
+	// Contributors are the authors of the default branch
+	Languages    map[string]float64
+	Contributors []lib.Contributor
+	// ClusterAgents are the agents for Kubernetes, AgentTokens maps agent
+	// IDs to their tokens and Clusters are the certificate-based clusters
+	ClusterAgents []lib.ClusterAgent
+	AgentTokens   map[int][]lib.AgentToken
+	Clusters      []lib.Cluster
+	// TerraformStates are served over GraphQL
+	TerraformStates []*lib.TerraformState
+	// Pages is the Pages site, nil while nothing is deployed
+	Pages *lib.Pages
+	// LabelEvents maps MR IIDs to their label changes, oldest first
+	LabelEvents map[int][]lib.LabelEvent
+	// Deployments are newest first; blocked ones wait for approvals
+	Deployments           []*lib.Deployment
+	ProtectedEnvironments []lib.ProtectedEnvironment
+	FeatureFlags          []*lib.FeatureFlag
+	// Jobs maps pipeline IDs to their jobs, and CommitMRs commit SHAs to
+	// the IIDs of the MRs that introduced them
+	Jobs      map[int][]lib.Job
+	CommitMRs map[string][]int
+	// PipelineVariables maps the IDs of pipelines created through the API
+	// to the variables they were given
+	PipelineVariables map[int][]lib.PipelineVariable
+	// Artifacts maps job IDs to their artifact files (path → content)
+	Artifacts map[int]map[string]string
+	// Dependencies is the dependency list of the default branch
+	Dependencies []lib.Dependency
+	// Vulnerabilities are served over GraphQL, and Findings maps pipeline
+	// IDs to the findings of their security reports
+	Vulnerabilities []*lib.Vulnerability
+	Findings        map[int][]lib.VulnerabilityFinding
+	// ReleaseLinks maps the tags that have a release to its asset links,
+	// and Uploads the full paths of uploaded files to their content
+	ReleaseLinks map[string][]lib.ReleaseLink
+	Uploads      map[string]string
+	// ExportStatus and ImportStatus advance one state per poll, and
+	// Archive is the export archive, or the one the project was imported
+	// from
+	ExportStatus string
+	ImportStatus string
+	Archive      string
+	// RemoteMirrors are the push mirrors, and PullMirror the pull mirror
+	// if the project has one
+	RemoteMirrors []*lib.RemoteMirror
+	PullMirror    *lib.PullMirror
+	// AuditEvents are newest first
+	AuditEvents []lib.AuditEvent
+	// Statistics is the storage the project uses
+	Statistics lib.ProjectStatistics
+	// Settings, ApprovalSettings, ApprovalRules, ProtectedBranches and
+	// Variables are the configuration propagate_settings.go manages
+	Settings          lib.ProjectSettings
+	ApprovalSettings  lib.ApprovalSettings
+	ApprovalRules     []lib.ApprovalRule
+	ProtectedBranches []lib.ProtectedBranch
+	Variables         []lib.Variable
+	// ServiceDesk is served along with the settings
+	ServiceDesk lib.ServiceDesk
+	// Description, Topics, Visibility and Avatar, the image's content,
+	// are served along with the settings too
+	Description string
+	Topics      []string
+	Visibility  string
+	Avatar      string
+}
+
+// dependencyExport is a dependency list export, which finishes when first
+// polled
+type dependencyExport struct {
+	lib.DependencyListExport
+	project *Project
+}
+
+// HandlerFunc handles a routed request; params holds the decoded :name
+// segments of the route pattern.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request, params map[string]string)
+
+type route struct {
+	method   string
+	segments []string
+	handler  HandlerFunc
+}
+
+// Server is a fake GitLab instance backed by httptest
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	user     lib.User
+	admin    bool            // whether the token may use the Sudo header
+	token    string          // PRIVATE-TOKEN accepted, Token until rotated
+	pat      lib.AccessToken // personal access token behind token
+	projects []*Project
+	routes   []route
+	requests []*http.Request
+	nextID   int
+	exports  map[int]*dependencyExport
+	// groupAudit maps group paths to their audit events, newest first
+	groupAudit map[string][]lib.AuditEvent
+	// storageLimits maps top-level groups to their storage quota in bytes
+	storageLimits map[string]int64
+	// users are the instance's accounts, groupMembers maps group paths to
+	// their direct members, and userTokens user IDs to the personal access
+	// tokens administrators created for them
+	users        []lib.User
+	groupMembers map[string][]lib.Member
+	userTokens   map[int][]lib.AccessToken
+	// broadcasts are the instance's broadcast messages and maintenance its
+	// maintenance mode application settings
+	broadcasts  []lib.BroadcastMessage
+	maintenance lib.MaintenanceMode
+	// cuts are the byte counts after which the next file downloads break
+	// off, see CutTransfers
+	cuts []int
+}
+
+// NewServer starts a fake GitLab seeded with the default fixtures. It is
+// closed automatically when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{nextID: 1000, token: Token, exports: make(map[int]*dependencyExport), groupAudit: make(map[string][]lib.AuditEvent),
+		storageLimits: make(map[string]int64), groupMembers: make(map[string][]lib.Member), userTokens: make(map[int][]lib.AccessToken)}
+	s.registerRoutes()
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+
+	seedFixtures(s)
+	return s
+}
+
+// Client returns a lib.Client configured against the fake server
+func (s *Server) Client() *lib.Client {
+	return lib.NewClient(&lib.Config{URL: s.URL, Token: s.CurrentToken()})
+}
+
+// CurrentToken returns the PRIVATE-TOKEN the server accepts, which changes
+// when the token is rotated
+func (s *Server) CurrentToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
+}
+
+// Project returns the fake project with the given path, or nil
+func (s *Server) Project(path string) *Project {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findProject(path)
+}
+
+// AddProject registers a new, empty project
+func (s *Server) AddProject(id int, path string) *Project {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addProject(id, path)
+}
+
+// addProject is AddProject for callers holding s.mu
+func (s *Server) addProject(id int, path string) *Project {
+	group := ""
+	if i := strings.LastIndex(path, "/"); i > 0 {
+		group = path[:i]
+	}
+	p := &Project{
+		ID:                id,
+		Path:              path,
+		Group:             group,
+		Diffs:             make(map[int][]lib.Diff),
+		Approvals:         make(map[int]*lib.Approvals),
+		Notes:             make(map[int][]lib.Note),
+		Discussions:       make(map[int][]lib.Discussion),
+		Files:             make(map[string]string),
+		Traces:            make(map[int]string),
+		Compare:           make(map[string]*lib.Comparison),
+		Commits:           make(map[int][]lib.Commit),
+		CommitDiffs:       make(map[string][]lib.Diff),
+		Signatures:        make(map[string]lib.CommitSignature),
+		LFSObjects:        make(map[string]string),
+		Blame:             make(map[string][]lib.BlameRange),
+		AgentTokens:       make(map[int][]lib.AgentToken),
+		LabelEvents:       make(map[int][]lib.LabelEvent),
+		FileHistory:       make(map[string][]lib.Commit),
+		Jobs:              make(map[int][]lib.Job),
+		CommitMRs:         make(map[string][]int),
+		PipelineVariables: make(map[int][]lib.PipelineVariable),
+		Artifacts:         make(map[int]map[string]string),
+		ReleaseLinks:      make(map[string][]lib.ReleaseLink),
+		Uploads:           make(map[string]string),
+		Findings:          make(map[int][]lib.VulnerabilityFinding),
+		IssueNotes:        make(map[int][]lib.Note),
+		Settings:          lib.ProjectSettings{ID: id, PathWithNamespace: path, DefaultBranch: "main", MergeMethod: "merge"},
+		Topics:            []string{},
+		Visibility:        lib.VisibilityPrivate,
+	}
+	s.projects = append(s.projects, p)
+	return p
+}
+
+// SetUser sets the user returned by GET /user
+func (s *Server) SetUser(u lib.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user = u
+}
+
+// SetAdmin sets whether the token belongs to an administrator, who may act
+// as other users through the Sudo header
+func (s *Server) SetAdmin(admin bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.admin = admin
+}
+
+// CutTransfers makes the next file downloads (responses other than JSON)
+// break off after the given numbers of bytes, one per download, as on a
+// flaky connection
+func (s *Server) CutTransfers(after ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cuts = append(s.cuts, after...)
+}
+
+// SetPersonalToken sets the token returned by GET /personal_access_tokens/self
+func (s *Server) SetPersonalToken(t lib.AccessToken) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pat = t
+}
+
+// AddUser registers an instance account, found by GET /users
+func (s *Server) AddUser(u lib.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = append(s.users, u)
+}
+
+// GroupMembers returns the direct members of a group
+func (s *Server) GroupMembers(group string) []lib.Member {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]lib.Member{}, s.groupMembers[group]...)
+}
+
+// UserTokens returns the personal access tokens created for a user through
+// the admin API
+func (s *Server) UserTokens(userID int) []lib.AccessToken {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]lib.AccessToken{}, s.userTokens[userID]...)
+}
+
+// Broadcasts returns the instance's broadcast messages
+func (s *Server) Broadcasts() []lib.BroadcastMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]lib.BroadcastMessage{}, s.broadcasts...)
+}
+
+// Maintenance returns the instance's maintenance mode settings
+func (s *Server) Maintenance() lib.MaintenanceMode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maintenance
+}
+
+// groupExists reports whether any project lives in group or its subgroups.
+// Callers must hold s.mu.
+func (s *Server) groupExists(group string) bool {
+	for _, p := range s.projects {
+		if p.Group == group || strings.HasPrefix(p.Group, group+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle registers (or overrides) a route, e.g. "GET /projects/:id/labels".
+// Routes registered later take precedence.
+func (s *Server) Handle(pattern string, h HandlerFunc) {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		panic("gitlabtest: pattern must be \"METHOD /path\": " + pattern)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes = append([]route{{method: method, segments: splitPath(path), handler: h}}, s.routes...)
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request(nil), s.requests...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r)
+	routes := s.routes
+	token := s.token
+	admin := s.admin
+	s.mu.Unlock()
+
+	if strings.Contains(r.URL.Path, ".git/") {
+		s.serveGit(w, r, token)
+		return
+	}
+	if r.Header.Get("PRIVATE-TOKEN") != token {
+		WriteError(w, http.StatusUnauthorized, "401 Unauthorized")
+		return
+	}
+	if sudo := r.Header.Get("Sudo"); sudo != "" {
+		if !admin {
+			WriteError(w, http.StatusForbidden, "403 Forbidden - Must be admin to use sudo")
+			return
+		}
+		s.mu.Lock()
+		u, ok := s.findUser(sudo)
+		s.mu.Unlock()
+		if !ok {
+			WriteError(w, http.StatusNotFound, "404 User Not Found")
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), actorKey{}, u))
+	}
+
+	// The API may be mounted under a subpath, as behind a proxy
+	_, path, ok := strings.Cut(r.URL.EscapedPath(), "/api/v4/")
+	path = "/" + path
+	if strings.HasSuffix(r.URL.Path, "/api/graphql") {
+		// Routed as POST /graphql
+		path, ok = "/graphql", true
+	}
+	if !ok {
+		WriteError(w, http.StatusNotFound, "404 Not Found")
+		return
+	}
+	segments := splitPath(path)
+
+	for _, rt := range routes {
+		if rt.method != r.Method {
+			continue
+		}
+		if params, ok := match(rt.segments, segments); ok {
+			if r.Method == http.MethodGet {
+				s.serveConditional(w, r, func(w http.ResponseWriter) { rt.handler(w, r, params) })
+				return
+			}
+			rt.handler(w, r, params)
+			return
+		}
+	}
+	WriteError(w, http.StatusNotFound, "404 Not Found")
+}
+
+// serveConditional runs a GET handler the way GitLab's ETag middleware
+// wraps it: a 200 response without an ETag of its own gets a weak one of
+// its body, and a request whose If-None-Match matches it is answered 304
+// Not Modified, without the body. Downloads are cut short as CutTransfers
+// asks.
+func (s *Server) serveConditional(w http.ResponseWriter, r *http.Request, handler func(http.ResponseWriter)) {
+	rec := httptest.NewRecorder()
+	handler(rec)
+	status := rec.Code
+	if status == http.StatusOK {
+		etag := rec.Header().Get("ETag")
+		if etag == "" {
+			sum := sha256.Sum256(rec.Body.Bytes())
+			etag = fmt.Sprintf(`W/"%x"`, sum[:16])
+			rec.Header().Set("ETag", etag)
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			status = http.StatusNotModified
+		}
+	}
+	for name, values := range rec.Header() {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(status)
+	if status == http.StatusNotModified {
+		return
+	}
+
+	body := rec.Body.Bytes()
+	if (status == http.StatusOK || status == http.StatusPartialContent) && !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		s.mu.Lock()
+		cut := -1
+		if len(s.cuts) > 0 {
+			cut, s.cuts = s.cuts[0], s.cuts[1:]
+		}
+		s.mu.Unlock()
+		if cut >= 0 && cut < len(body) {
+			w.Write(body[:cut])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+	}
+	w.Write(body)
+}
+
+// ServeFile serves a download the way GitLab does, with a strong ETag and
+// Range requests
+func ServeFile(w http.ResponseWriter, r *http.Request, name, content string) {
+	sum := sha256.Sum256([]byte(content))
+	w.Header().Set("ETag", fmt.Sprintf(`"%x"`, sum[:16]))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, name, FixtureTime, strings.NewReader(content))
+}
+
+// actorKey is the request context key of the user named by the Sudo header
+type actorKey struct{}
+
+// actor returns the user a request acts as: the Sudo user, or the token's
+// owner
+func (s *Server) actor(r *http.Request) lib.User {
+	if u, ok := r.Context().Value(actorKey{}).(lib.User); ok {
+		return u
+	}
+	return s.user
+}
+
+// findUser looks a username up among the token owner and project members.
+// The caller must hold s.mu.
+func (s *Server) findUser(username string) (lib.User, bool) {
+	if s.user.Username == username {
+		return s.user, true
+	}
+	for _, p := range s.projects {
+		for _, m := range p.Members {
+			if m.User.Username == username {
+				return m.User, true
+			}
+		}
+	}
+	return lib.User{}, false
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// match compares escaped request segments against a route pattern. A final
+// "*name" segment matches the rest of the path.
+func match(pattern, segments []string) (map[string]string, bool) {
+	if n := len(pattern); strings.HasPrefix(pattern[n-1], "*") && len(segments) > n {
+		segments = append(segments[:n-1:n-1], strings.Join(segments[n-1:], "/"))
+	}
+	if len(pattern) != len(segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, p := range pattern {
+		if strings.HasPrefix(p, ":") || strings.HasPrefix(p, "*") {
+			v, err := url.PathUnescape(segments[i])
+			if err != nil {
+				return nil, false
+			}
+			params[p[1:]] = v
+		} else if p != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// artifactsZip builds the artifacts archive of a job from its files, the
+// same bytes every time
+func artifactsZip(files map[string]string) string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		f, _ := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: FixtureTime})
+		io.WriteString(f, files[name])
+	}
+	zw.Close()
+	return buf.String()
+}
+
+// WriteJSON writes v as a JSON response with the given status
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// WriteError writes a GitLab-style {"message": ...} error response
+func WriteError(w http.ResponseWriter, status int, message string) {
+	WriteJSON(w, status, map[string]string{"message": message})
+}
+
+// Paginate applies the page/per_page query parameters to items and sets the
+// GitLab pagination headers
+func Paginate[T any](w http.ResponseWriter, r *http.Request, items []T) []T {
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage <= 0 {
+		perPage = 20
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+
+	w.Header().Set("X-Total", strconv.Itoa(len(items)))
+	w.Header().Set("X-Page", strconv.Itoa(page))
+	w.Header().Set("X-Per-Page", strconv.Itoa(perPage))
+
+	start := (page - 1) * perPage
+	if start >= len(items) {
+		return []T{}
+	}
+	end := start + perPage
+	if end >= len(items) {
+		end = len(items)
+	} else {
+		w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+	}
+	return items[start:end]
+}
+
+// PaginateKeyset serves items, sorted by descending id, a page at a time
+// like GitLab's keyset pagination when the request asks for it: the next
+// page is linked from the Link header through a cursor, and the offset
+// headers are left out. Other requests are paginated by offset.
+func PaginateKeyset[T any](w http.ResponseWriter, r *http.Request, items []T, id func(T) int) []T {
+	q := r.URL.Query()
+	if q.Get("pagination") != "keyset" {
+		return Paginate(w, r, items)
+	}
+	perPage, _ := strconv.Atoi(q.Get("per_page"))
+	if perPage <= 0 {
+		perPage = 20
+	}
+	start := 0
+	if cursor, err := strconv.Atoi(q.Get("cursor")); err == nil {
+		for start < len(items) && id(items[start]) >= cursor {
+			start++
+		}
+	}
+	end := min(start+perPage, len(items))
+	if end < len(items) {
+		q.Set("cursor", strconv.Itoa(id(items[end-1])))
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?%s>; rel="next"`, r.Host, r.URL.EscapedPath(), q.Encode()))
+	}
+	return items[start:end]
+}
+
+// findProject looks a project up by numeric ID or full path. Callers must
+// hold s.mu.
+func (s *Server) findProject(id string) *Project {
+	for _, p := range s.projects {
+		if p.Path == id || strconv.Itoa(p.ID) == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// findMR looks an MR up by IID. Callers must hold s.mu.
+func (p *Project) findMR(iid int) *lib.MergeRequest {
+	for _, mr := range p.MRs {
+		if mr.IID == iid {
+			return mr
+		}
+	}
+	return nil
+}
+
+// findRemoteMirror looks a push mirror up by ID. Callers must hold s.mu.
+func (p *Project) findRemoteMirror(id string) *lib.RemoteMirror {
+	for _, m := range p.RemoteMirrors {
+		if strconv.Itoa(m.ID) == id {
+			return m
+		}
+	}
+	return nil
+}
+
+// applyMirrorRequest sets the fields of a push mirror update
+func applyMirrorRequest(m *lib.RemoteMirror, req *lib.RemoteMirrorRequest) {
+	if req.Enabled != nil {
+		m.Enabled = *req.Enabled
+	}
+	if req.OnlyProtectedBranches != nil {
+		m.OnlyProtectedBranches = *req.OnlyProtectedBranches
+	}
+	if req.KeepDivergentRefs != nil {
+		m.KeepDivergentRefs = *req.KeepDivergentRefs
+	}
+	if req.MirrorBranchRegex != "" {
+		m.MirrorBranchRegex = req.MirrorBranchRegex
+	}
+	if req.AuthMethod != "" {
+		m.AuthMethod = req.AuthMethod
+	}
+}
+
+// findDeployment looks a deployment up by ID. Callers must hold s.mu.
+func (p *Project) findDeployment(id int) *lib.Deployment {
+	for _, d := range p.Deployments {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+// findFeatureFlag looks a feature flag up by name. Callers must hold s.mu.
+func (p *Project) findFeatureFlag(name string) *lib.FeatureFlag {
+	for _, f := range p.FeatureFlags {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// findBranch looks a branch up by name. Callers must hold s.mu.
+func (p *Project) findBranch(name string) *lib.Branch {
+	for i := range p.Branches {
+		if p.Branches[i].Name == name {
+			return &p.Branches[i]
+		}
+	}
+	return nil
+}
+
+// resolveRef returns the commit a branch or tag points to. Callers must hold
+// s.mu.
+func (p *Project) resolveRef(ref string) (lib.Commit, bool) {
+	if b := p.findBranch(ref); b != nil {
+		return b.Commit, true
+	}
+	for _, t := range p.Tags {
+		if t.Name == ref {
+			return t.Commit, true
+		}
+	}
+	return lib.Commit{}, false
+}
+
+// commitOnBranch adds a commit to the branch named in the request body, as
+// the revert and cherry-pick endpoints do. Callers must hold s.mu.
+func (s *Server) commitOnBranch(w http.ResponseWriter, r *http.Request, p *Project, title, message string) {
+	var req struct {
+		Branch string `json:"branch"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	b := p.findBranch(req.Branch)
+	if b == nil {
+		WriteError(w, http.StatusNotFound, "404 Branch Not Found")
+		return
+	}
+	s.nextID++
+	b.Commit = lib.Commit{
+		ID:        fmt.Sprintf("%040x", s.nextID),
+		Title:     title,
+		Message:   message,
+		CreatedAt: time.Now().UTC(),
+	}
+	b.Commit.ShortID = b.Commit.ID[:8]
+	WriteJSON(w, http.StatusCreated, b.Commit)
+}
+
+// withProject resolves the :id param, replying 404 when it is unknown
+func (s *Server) withProject(h func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string)) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		p := s.findProject(params["id"])
+		if p == nil {
+			WriteError(w, http.StatusNotFound, "404 Project Not Found")
+			return
+		}
+		h(w, r, p, params)
+	}
+}
+
+// withMR additionally resolves the :iid param
+func (s *Server) withMR(h func(w http.ResponseWriter, r *http.Request, p *Project, mr *lib.MergeRequest)) HandlerFunc {
+	return s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		iid, _ := strconv.Atoi(params["iid"])
+		mr := p.findMR(iid)
+		if mr == nil {
+			WriteError(w, http.StatusNotFound, "404 Not found")
+			return
+		}
+		h(w, r, p, mr)
+	})
+}
+
+func (s *Server) registerRoutes() {
+	s.Handle("POST /graphql", s.serveGraphQL)
+
+	s.Handle("GET /user", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		WriteJSON(w, http.StatusOK, s.actor(r))
+	})
+
+	s.Handle("GET /version", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		WriteJSON(w, http.StatusOK, map[string]string{"version": Version, "revision": "6f0b6d2"})
+	})
+
+	s.Handle("GET /personal_access_tokens/self", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		WriteJSON(w, http.StatusOK, s.pat)
+	})
+
+	s.Handle("POST /personal_access_tokens/self/rotate", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var req struct {
+			ExpiresAt lib.Date `json:"expires_at"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.ExpiresAt.IsZero() {
+			req.ExpiresAt = lib.Date{Time: time.Now().UTC().AddDate(0, 0, 7)}
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.nextID++
+		s.pat.ID = s.nextID
+		s.pat.ExpiresAt = req.ExpiresAt
+		s.pat.CreatedAt = time.Now().UTC()
+		s.token = fmt.Sprintf("glpat-rotated%012d", s.pat.ID)
+		token := s.pat
+		token.Token = s.token
+		WriteJSON(w, http.StatusOK, token)
+	})
+
+	s.Handle("GET /projects/:id/merge_requests", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		WriteJSON(w, http.StatusOK, Paginate(w, r, filterMRs(p.MRs, r.URL.Query(), s.actor(r))))
+	}))
+
+	s.Handle("POST /projects/:id/merge_requests", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		var req lib.CreateMRRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if req.SourceBranch == "" || req.TargetBranch == "" || req.Title == "" {
+			WriteError(w, http.StatusBadRequest, "source_branch, target_branch and title are required")
+			return
+		}
+		for _, mr := range p.MRs {
+			if mr.State == "opened" && mr.SourceBranch == req.SourceBranch && mr.TargetBranch == req.TargetBranch {
+				WriteError(w, http.StatusConflict, fmt.Sprintf("Another open merge request already exists for this source branch: !%d", mr.IID))
+				return
+			}
+		}
+
+		s.nextID++
+		now := time.Now().UTC()
+		mr := &lib.MergeRequest{
+			ID:           s.nextID,
+			IID:          nextIID(p),
+			ProjectID:    p.ID,
+			Title:        req.Title,
+			Description:  req.Description,
+			State:        "opened",
+			SourceBranch: req.SourceBranch,
+			TargetBranch: req.TargetBranch,
+			Labels:       req.Labels,
+			Author:       s.actor(r),
+			Draft:        strings.HasPrefix(req.Title, "Draft:"),
+			CreatedAt:    now,
+			UpdatedAt:    now,
+			MergeStatus:  "checking",
+		}
+		mr.WebURL = fmt.Sprintf("%s/%s/-/merge_requests/%d", s.URL, p.Path, mr.IID)
+		mr.References.Full = fmt.Sprintf("%s!%d", p.Path, mr.IID)
+		p.MRs = append(p.MRs, mr)
+		WriteJSON(w, http.StatusCreated, mr)
+	}))
+
+	s.Handle("GET /projects/:id/merge_requests/:iid", s.withMR(func(w http.ResponseWriter, r *http.Request, p *Project, mr *lib.MergeRequest) {
+		WriteJSON(w, http.StatusOK, mr)
+	}))
+
+	s.Handle("PUT /projects/:id/merge_requests/:iid", s.withMR(func(w http.ResponseWriter, r *http.Request, p *Project, mr *lib.MergeRequest) {
+		var req map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := applyMRUpdate(p, mr, req); err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		mr.UpdatedAt = time.Now().UTC()
+		WriteJSON(w, http.StatusOK, mr)
+	}))
+
+	s.Handle("GET /projects/:id/merge_requests/:iid/merge_ref", s.withMR(func(w http.ResponseWriter, r *http.Request, p *Project, mr *lib.MergeRequest) {
+		if mr.HasConflicts {
+			WriteError(w, http.StatusBadRequest, "Merge request is not mergeable")
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]string{"commit_id": mr.SHA})
+	}))
+
+	s.Handle("PUT /projects/:id/merge_requests/:iid/rebase", s.withMR(func(w http.ResponseWriter, r *http.Request, p *Project, mr *lib.MergeRequest) {
+		if mr.State != "opened" {
+			WriteError(w, http.StatusForbidden, "403 Forbidden")
+			return
+		}
+		// Rebases finish instantly; conflicts surface as merge_error
+		mr.MergeError = ""
+		if mr.HasConflicts {
+			mr.MergeError = "Rebase failed: Rebase locally, resolve all conflicts, then push the branch."
+		}
+		WriteJSON(w, http.StatusAccepted, map[string]bool{"rebase_in_progress": true})
+	}))
+
+	s.Handle("PUT /projects/:id/merge_requests/:iid/merge", s.withMR(func(w http.ResponseWriter, r *http.Request, p *Project, mr *lib.MergeRequest) {
+		var req lib.MergeMRRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		switch {
+		case mr.State != "opened":
+			WriteError(w, http.StatusMethodNotAllowed, "405 Method Not Allowed")
+		case mr.Draft || mr.HasConflicts:
+			WriteError(w, http.StatusNotAcceptable, "Branch cannot be merged")
+		case req.SHA != "" && req.SHA != mr.SHA:
+			WriteError(w, http.StatusConflict, "SHA does not match HEAD of source branch")
+		default:
+			mr.State = "merged"
+			mr.UpdatedAt = time.Now().UTC()
+			WriteJSON(w, http.StatusOK, mr)
+		}
+	}))
+
+	s.Handle("GET /projects/:id/merge_requests/:iid/diffs", s.withMR(func(w http.ResponseWriter, r *http.Request, p *Project, mr *lib.MergeRequest) {
+		WriteJSON(w, http.StatusOK, Paginate(w, r, p.Diffs[mr.IID]))
+	}))
+
+	s.Handle("GET /projects/:id/merge_requests/:iid/approvals", s.withMR(func(w http.ResponseWriter, r *http.Request, p *Project, mr *lib.MergeRequest) {
+		approvals := p.Approvals[mr.IID]
+		if approvals == nil {
+			approvals = &lib.Approvals{}
+		}
+		WriteJSON(w, http.StatusOK, approvals)
+	}))
+
+	s.Handle("GET /projects/:id/merge_requests/:iid/notes", s.withMR(func(w http.ResponseWriter, r *http.Request, p *Project, mr *lib.MergeRequest) {
+		WriteJSON(w, http.StatusOK, Paginate(w, r, p.Notes[mr.IID]))
+	}))
+
+	s.Handle("PUT /projects/:id/merge_requests/:iid/notes/:note_id", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		iid, _ := strconv.Atoi(params["iid"])
+		noteID, _ := strconv.Atoi(params["note_id"])
+		var req struct {
+			Body string `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Body == "" {
+			WriteError(w, http.StatusBadRequest, "body is required")
+			return
+		}
+		for i := range p.Notes[iid] {
+			if note := &p.Notes[iid][i]; note.ID == noteID {
+				if note.Author.ID != s.actor(r).ID {
+					WriteError(w, http.StatusForbidden, "403 Forbidden")
+					return
+				}
+				note.Body = req.Body
+				WriteJSON(w, http.StatusOK, note)
+				return
+			}
+		}
+		WriteError(w, http.StatusNotFound, "404 Note Not Found")
+	}))
+
+	s.Handle("GET /projects/:id/merge_requests/:iid/discussions", s.withMR(func(w http.ResponseWriter, r *http.Request, p *Project, mr *lib.MergeRequest) {
+		WriteJSON(w, http.StatusOK, Paginate(w, r, p.Discussions[mr.IID]))
+	}))
+
+	s.Handle("POST /projects/:id/merge_requests/:iid/discussions/:discussion_id/notes", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		iid, _ := strconv.Atoi(params["iid"])
+		var req struct {
+			Body string `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Body == "" {
+			WriteError(w, http.StatusBadRequest, "body is required")
+			return
+		}
+		for i := range p.Discussions[iid] {
+			if d := &p.Discussions[iid][i]; d.ID == params["discussion_id"] {
+				s.nextID++
+				note := lib.Note{ID: s.nextID, Body: req.Body, Author: s.actor(r), Resolvable: d.Notes[0].Resolvable, CreatedAt: time.Now().UTC()}
+				d.Notes = append(d.Notes, note)
+				WriteJSON(w, http.StatusCreated, note)
+				return
+			}
+		}
+		WriteError(w, http.StatusNotFound, "404 Discussion Not Found")
+	}))
+
+	s.Handle("PUT /projects/:id/merge_requests/:iid/discussions/:discussion_id", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		iid, _ := strconv.Atoi(params["iid"])
+		var req struct {
+			Resolved *bool `json:"resolved"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Resolved == nil {
+			WriteError(w, http.StatusBadRequest, "resolved is missing")
+			return
+		}
+		for i := range p.Discussions[iid] {
+			if d := &p.Discussions[iid][i]; d.ID == params["discussion_id"] {
+				for j := range d.Notes {
+					if d.Notes[j].Resolvable {
+						d.Notes[j].Resolved = *req.Resolved
+					}
+				}
+				WriteJSON(w, http.StatusOK, d)
+				return
+			}
+		}
+		WriteError(w, http.StatusNotFound, "404 Discussion Not Found")
+	}))
+
+	s.Handle("GET /projects/:id/merge_requests/:iid/resource_label_events", s.withMR(func(w http.ResponseWriter, r *http.Request, p *Project, mr *lib.MergeRequest) {
+		WriteJSON(w, http.StatusOK, Paginate(w, r, p.LabelEvents[mr.IID]))
+	}))
+
+	s.Handle("GET /projects/:id/merge_requests/:iid/commits", s.withMR(func(w http.ResponseWriter, r *http.Request, p *Project, mr *lib.MergeRequest) {
+		WriteJSON(w, http.StatusOK, Paginate(w, r, p.Commits[mr.IID]))
+	}))
+
+	s.Handle("GET /projects/:id/repository/commits", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		q := r.URL.Query()
+		var history []lib.Commit
+		if path := q.Get("path"); path != "" {
+			history = p.FileHistory[path]
+		} else {
+			seen := make(map[string]bool)
+			for _, commits := range p.FileHistory {
+				for _, c := range commits {
+					if !seen[c.ID] {
+						seen[c.ID] = true
+						history = append(history, c)
+					}
+				}
+			}
+			sort.Slice(history, func(i, j int) bool { return history[i].CreatedAt.After(history[j].CreatedAt) })
+		}
+		out := []lib.Commit{}
+		for _, c := range history {
+			if since, err := time.Parse(time.RFC3339, q.Get("since")); err == nil && c.CreatedAt.Before(since) {
+				continue
+			}
+			out = append(out, c)
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, out))
+	}))
+
+	s.Handle("GET /projects/:id/languages", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		languages := p.Languages
+		if languages == nil {
+			languages = map[string]float64{}
+		}
+		WriteJSON(w, http.StatusOK, languages)
+	}))
+
+	s.Handle("GET /projects/:id/cluster_agents", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		WriteJSON(w, http.StatusOK, Paginate(w, r, p.ClusterAgents))
+	}))
+
+	s.Handle("GET /projects/:id/cluster_agents/:agent_id/tokens", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		id, _ := strconv.Atoi(params["agent_id"])
+		for _, a := range p.ClusterAgents {
+			if a.ID == id {
+				WriteJSON(w, http.StatusOK, Paginate(w, r, p.AgentTokens[id]))
+				return
+			}
+		}
+		WriteError(w, http.StatusNotFound, "404 Agent Not Found")
+	}))
+
+	s.Handle("GET /projects/:id/clusters", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		WriteJSON(w, http.StatusOK, Paginate(w, r, p.Clusters))
+	}))
+
+	s.Handle("GET /projects/:id/pages", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		if p.Pages == nil {
+			WriteError(w, http.StatusNotFound, "404 Not Found")
+			return
+		}
+		WriteJSON(w, http.StatusOK, p.Pages)
+	}))
+
+	s.Handle("GET /projects/:id/repository/contributors", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		WriteJSON(w, http.StatusOK, Paginate(w, r, append([]lib.Contributor{}, p.Contributors...)))
+	}))
+
+	s.Handle("GET /projects/:id/repository/commits/:sha/merge_requests", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		out := []lib.MergeRequest{}
+		for _, iid := range p.CommitMRs[params["sha"]] {
+			if mr := p.findMR(iid); mr != nil {
+				out = append(out, *mr)
+			}
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, out))
+	}))
+
+	s.Handle("GET /projects/:id/repository/commits/:sha/diff", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		diffs, ok := p.CommitDiffs[params["sha"]]
+		if !ok {
+			WriteError(w, http.StatusNotFound, "404 Commit Not Found")
+			return
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, diffs))
+	}))
+
+	s.Handle("GET /projects/:id/repository/commits/:sha/signature", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		sig, ok := p.Signatures[params["sha"]]
+		if !ok {
+			WriteError(w, http.StatusNotFound, "404 Signature Not Found")
+			return
+		}
+		WriteJSON(w, http.StatusOK, sig)
+	}))
+
+	s.Handle("POST /projects/:id/merge_requests/:iid/notes", s.withMR(func(w http.ResponseWriter, r *http.Request, p *Project, mr *lib.MergeRequest) {
+		var req struct {
+			Body string `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Body == "" {
+			WriteError(w, http.StatusBadRequest, "body is required")
+			return
+		}
+		s.nextID++
+		note := lib.Note{ID: s.nextID, Body: req.Body, Author: s.actor(r), CreatedAt: time.Now().UTC()}
+		p.Notes[mr.IID] = append(p.Notes[mr.IID], note)
+		WriteJSON(w, http.StatusCreated, note)
+	}))
+
+	s.Handle("GET /projects/:id/pipelines", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		q := r.URL.Query()
+		var out []lib.Pipeline
+		for _, pl := range p.Pipelines {
+			if ref := q.Get("ref"); ref != "" && pl.Ref != ref {
+				continue
+			}
+			if status := q.Get("status"); status != "" && pl.Status != status {
+				continue
+			}
+			if after, err := time.Parse(time.RFC3339, q.Get("updated_after")); err == nil && !pl.UpdatedAt.After(after) {
+				continue
+			}
+			out = append(out, pl)
+		}
+		// Newest first, like GitLab
+		sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+		WriteJSON(w, http.StatusOK, Paginate(w, r, out))
+	}))
+
+	s.Handle("GET /projects/:id/pipelines/:pipeline_id/jobs", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		id, _ := strconv.Atoi(params["pipeline_id"])
+		scopes := r.URL.Query()["scope[]"]
+		out := []lib.Job{}
+		for _, j := range p.Jobs[id] {
+			keep := len(scopes) == 0
+			for _, scope := range scopes {
+				keep = keep || scope == j.Status
+			}
+			if keep {
+				out = append(out, j)
+			}
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, out))
+	}))
+
+	s.Handle("GET /projects/:id/pipelines/:pipeline_id", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		id, _ := strconv.Atoi(params["pipeline_id"])
+		for _, pl := range p.Pipelines {
+			if pl.ID == id {
+				WriteJSON(w, http.StatusOK, pl)
+				return
+			}
+		}
+		WriteError(w, http.StatusNotFound, "404 Not found")
+	}))
+
+	s.Handle("GET /projects/:id/labels", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		WriteJSON(w, http.StatusOK, Paginate(w, r, p.Labels))
+	}))
+
+	s.Handle("GET /projects/:id/repository/branches", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		out := []lib.Branch{}
+		for _, b := range p.Branches {
+			if search := r.URL.Query().Get("search"); search == "" || strings.Contains(b.Name, search) {
+				out = append(out, b)
+			}
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, out))
+	}))
+
+	s.Handle("POST /projects/:id/repository/branches", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		var req struct {
+			Branch string `json:"branch"`
+			Ref    string `json:"ref"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Branch == "" || req.Ref == "" {
+			WriteError(w, http.StatusBadRequest, "branch and ref are required")
+			return
+		}
+		if p.findBranch(req.Branch) != nil {
+			WriteError(w, http.StatusBadRequest, "Branch already exists")
+			return
+		}
+		commit, ok := p.resolveRef(req.Ref)
+		if !ok {
+			WriteError(w, http.StatusBadRequest, "Invalid reference name: "+req.Ref)
+			return
+		}
+		p.Branches = append(p.Branches, lib.Branch{Name: req.Branch, Commit: commit})
+		WriteJSON(w, http.StatusCreated, p.Branches[len(p.Branches)-1])
+	}))
+
+	s.Handle("DELETE /projects/:id/repository/branches/:branch", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		for i, b := range p.Branches {
+			if b.Name == params["branch"] {
+				p.Branches = append(p.Branches[:i], p.Branches[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		WriteError(w, http.StatusNotFound, "404 Branch Not Found")
+	}))
+
+	s.Handle("POST /projects/:id/repository/commits/:sha/revert", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		s.commitOnBranch(w, r, p, fmt.Sprintf("Revert %s", params["sha"]), fmt.Sprintf("This reverts commit %s.", params["sha"]))
+	}))
+
+	s.Handle("POST /projects/:id/repository/commits/:sha/cherry_pick", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		s.commitOnBranch(w, r, p, fmt.Sprintf("Cherry-pick %s", params["sha"]), fmt.Sprintf("(cherry picked from commit %s)", params["sha"]))
+	}))
+
+	s.Handle("GET /projects/:id/repository/tags", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		out := []lib.Tag{}
+		for _, t := range p.Tags {
+			if search := r.URL.Query().Get("search"); search == "" || strings.Contains(t.Name, search) {
+				out = append(out, t)
+			}
+		}
+		// order_by=version&sort=desc puts the highest versions first and
+		// tags without one last
+		if r.URL.Query().Get("order_by") == "version" {
+			sort.SliceStable(out, func(i, j int) bool {
+				a, errA := lib.ParseVersion(out[i].Name)
+				b, errB := lib.ParseVersion(out[j].Name)
+				if errA != nil || errB != nil {
+					return errA == nil && errB != nil
+				}
+				return b.Less(a)
+			})
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, out))
+	}))
+
+	s.Handle("POST /projects/:id/pipeline", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		var req struct {
+			Ref       string                 `json:"ref"`
+			Variables []lib.PipelineVariable `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Ref == "" {
+			WriteError(w, http.StatusBadRequest, "ref is required")
+			return
+		}
+		commit, ok := p.resolveRef(req.Ref)
+		if !ok {
+			WriteError(w, http.StatusBadRequest, "Reference not found")
+			return
+		}
+		s.nextID++
+		now := time.Now().UTC()
+		pipeline := lib.Pipeline{
+			ID:        s.nextID,
+			IID:       len(p.Pipelines) + 1,
+			ProjectID: p.ID,
+			Status:    "created",
+			Ref:       req.Ref,
+			SHA:       commit.ID,
+			Source:    "api",
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		pipeline.WebURL = fmt.Sprintf("%s/%s/-/pipelines/%d", s.URL, p.Path, pipeline.ID)
+		p.Pipelines = append(p.Pipelines, pipeline)
+		if len(req.Variables) > 0 {
+			p.PipelineVariables[pipeline.ID] = req.Variables
+		}
+		WriteJSON(w, http.StatusCreated, pipeline)
+	}))
+
+	s.Handle("GET /projects/:id/protected_branches", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		out := []lib.ProtectedBranch{}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, append(out, p.ProtectedBranches...)))
+	}))
+
+	// Branch names must exist, wildcards such as release/* need not match
+	s.Handle("POST /projects/:id/protected_branches", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		var req struct {
+			Name           string `json:"name"`
+			PushLevel      *int   `json:"push_access_level"`
+			MergeLevel     *int   `json:"merge_access_level"`
+			AllowForcePush bool   `json:"allow_force_push"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+			WriteError(w, http.StatusBadRequest, "name is required")
+			return
+		}
+		b := p.findBranch(req.Name)
+		if b == nil && !strings.Contains(req.Name, "*") {
+			WriteError(w, http.StatusNotFound, "404 Branch Not Found")
+			return
+		}
+		for _, pb := range p.ProtectedBranches {
+			if pb.Name == req.Name {
+				WriteError(w, http.StatusConflict, fmt.Sprintf("Protected branch '%s' already exists", req.Name))
+				return
+			}
+		}
+		push, merge := lib.AccessMaintainer, lib.AccessMaintainer
+		if req.PushLevel != nil {
+			push = *req.PushLevel
+		}
+		if req.MergeLevel != nil {
+			merge = *req.MergeLevel
+		}
+		s.nextID++
+		pb := protectedBranch(s.nextID, req.Name, push, merge)
+		pb.AllowForcePush = req.AllowForcePush
+		p.ProtectedBranches = append(p.ProtectedBranches, pb)
+		if b != nil {
+			b.Protected = true
+		}
+		WriteJSON(w, http.StatusCreated, pb)
+	}))
+
+	s.Handle("DELETE /projects/:id/protected_branches/:name", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		for i, pb := range p.ProtectedBranches {
+			if pb.Name == params["name"] {
+				p.ProtectedBranches = append(p.ProtectedBranches[:i], p.ProtectedBranches[i+1:]...)
+				if b := p.findBranch(pb.Name); b != nil {
+					b.Protected = false
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		WriteError(w, http.StatusNotFound, "404 Not found")
+	}))
+
+	s.Handle("GET /projects/:id/repository/files/:file_path", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		ref := r.URL.Query().Get("ref")
+		if _, ok := p.resolveRef(ref); !ok {
+			WriteError(w, http.StatusNotFound, "404 Commit Not Found")
+			return
+		}
+		content, ok := p.Files[params["file_path"]]
+		if !ok {
+			WriteError(w, http.StatusNotFound, "404 File Not Found")
+			return
+		}
+		WriteJSON(w, http.StatusOK, lib.RepositoryFile{
+			FilePath: params["file_path"],
+			Ref:      ref,
+			Encoding: "base64",
+			Content:  base64.StdEncoding.EncodeToString([]byte(content)),
+		})
+	}))
+
+	s.Handle("GET /projects/:id/repository/files/:file_path/blame", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		q := r.URL.Query()
+		if _, ok := p.resolveRef(q.Get("ref")); !ok {
+			WriteError(w, http.StatusNotFound, "404 Commit Not Found")
+			return
+		}
+		ranges, ok := p.Blame[params["file_path"]]
+		if !ok {
+			WriteError(w, http.StatusNotFound, "404 File Not Found")
+			return
+		}
+		if q.Has("range[start]") {
+			start, err1 := strconv.Atoi(q.Get("range[start]"))
+			end, err2 := strconv.Atoi(q.Get("range[end]"))
+			if err1 != nil || err2 != nil || start < 1 || end < start {
+				WriteError(w, http.StatusBadRequest, "range[start] and range[end] must be line numbers, start <= end")
+				return
+			}
+			// Keep the lines in the range, grouped by their commit as before
+			var cut []lib.BlameRange
+			n := 0
+			for _, br := range ranges {
+				var lines []string
+				for _, line := range br.Lines {
+					if n++; n >= start && n <= end {
+						lines = append(lines, line)
+					}
+				}
+				if len(lines) > 0 {
+					cut = append(cut, lib.BlameRange{Commit: br.Commit, Lines: lines})
+				}
+			}
+			ranges = cut
+		}
+		WriteJSON(w, http.StatusOK, ranges)
+	}))
+
+	s.Handle("GET /projects/:id/jobs/:job_id", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		id, _ := strconv.Atoi(params["job_id"])
+		for _, pl := range p.Pipelines {
+			for _, j := range p.Jobs[pl.ID] {
+				if j.ID == id {
+					pipeline := pl
+					j.Ref, j.Pipeline = pl.Ref, &pipeline
+					WriteJSON(w, http.StatusOK, j)
+					return
+				}
+			}
+		}
+		WriteError(w, http.StatusNotFound, "404 Job Not Found")
+	}))
+
+	s.Handle("GET /projects/:id/jobs/:job_id/trace", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		id, _ := strconv.Atoi(params["job_id"])
+		trace, ok := p.Traces[id]
+		if !ok {
+			WriteError(w, http.StatusNotFound, "404 Job Not Found")
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, trace)
+	}))
+
+	s.Handle("GET /projects/:id/jobs/:job_id/artifacts/*artifact_path", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		id, _ := strconv.Atoi(params["job_id"])
+		content, ok := p.Artifacts[id][params["artifact_path"]]
+		if !ok {
+			WriteError(w, http.StatusNotFound, "404 Not Found")
+			return
+		}
+		ServeFile(w, r, params["artifact_path"], content)
+	}))
+
+	s.Handle("GET /projects/:id/jobs/:job_id/artifacts", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		id, _ := strconv.Atoi(params["job_id"])
+		files, ok := p.Artifacts[id]
+		if !ok {
+			WriteError(w, http.StatusNotFound, "404 Not Found")
+			return
+		}
+		ServeFile(w, r, "artifacts.zip", artifactsZip(files))
+	}))
+
+	s.Handle("GET /projects/:id/jobs", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		scopes := r.URL.Query()["scope[]"]
+		out := []lib.Job{}
+		for _, pl := range p.Pipelines {
+			for _, j := range p.Jobs[pl.ID] {
+				if len(scopes) > 0 && !contains(scopes, j.Status) {
+					continue
+				}
+				pipeline := pl
+				j.Ref, j.Pipeline = pl.Ref, &pipeline
+				if j.CreatedAt.IsZero() {
+					j.CreatedAt = pl.CreatedAt
+				}
+				out = append(out, j)
+			}
+		}
+		// Newest first, like GitLab
+		sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+		WriteJSON(w, http.StatusOK, PaginateKeyset(w, r, out, func(j lib.Job) int { return j.ID }))
+	}))
+
+	s.Handle("DELETE /projects/:id/jobs/:job_id/artifacts", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		id, _ := strconv.Atoi(params["job_id"])
+		for _, jobs := range p.Jobs {
+			for i := range jobs {
+				if jobs[i].ID != id {
+					continue
+				}
+				// The log survives, like on GitLab
+				var kept []lib.JobArtifact
+				for _, a := range jobs[i].Artifacts {
+					if a.FileType == "trace" {
+						kept = append(kept, a)
+					}
+				}
+				jobs[i].Artifacts, jobs[i].ArtifactsExpireAt = kept, nil
+				delete(p.Artifacts, id)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		WriteError(w, http.StatusNotFound, "404 Job Not Found")
+	}))
+
+	s.Handle("GET /projects/:id/dependencies", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		managers := make(map[string]bool)
+		for _, m := range r.URL.Query()["package_manager[]"] {
+			managers[m] = true
+		}
+		out := []lib.Dependency{}
+		for _, d := range p.Dependencies {
+			if len(managers) == 0 || managers[d.PackageManager] {
+				out = append(out, d)
+			}
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, out))
+	}))
+
+	s.Handle("GET /projects/:id/vulnerability_findings", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		id, _ := strconv.Atoi(r.URL.Query().Get("pipeline_id"))
+		severities, types := r.URL.Query()["severity[]"], r.URL.Query()["report_type[]"]
+		out := []lib.VulnerabilityFinding{}
+		for _, f := range p.Findings[id] {
+			if (len(severities) == 0 || contains(severities, f.Severity)) && (len(types) == 0 || contains(types, f.ReportType)) {
+				out = append(out, f)
+			}
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, out))
+	}))
+
+	s.Handle("POST /projects/:id/dependency_list_exports", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		var req struct {
+			ExportType string `json:"export_type"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.ExportType != "sbom" && req.ExportType != "dependency_list" {
+			WriteError(w, http.StatusBadRequest, "export_type does not have a valid value")
+			return
+		}
+		s.nextID++
+		e := &dependencyExport{project: p}
+		e.ID = s.nextID
+		e.Self = fmt.Sprintf("%s/api/v4/dependency_list_exports/%d", s.URL, e.ID)
+		e.Download = e.Self + "/download"
+		s.exports[e.ID] = e
+		WriteJSON(w, http.StatusCreated, e.DependencyListExport)
+	}))
+
+	s.Handle("GET /dependency_list_exports/:export_id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		id, _ := strconv.Atoi(params["export_id"])
+		e := s.exports[id]
+		if e == nil {
+			WriteError(w, http.StatusNotFound, "404 Not Found")
+			return
+		}
+		if !e.HasFinished {
+			e.HasFinished = true
+			WriteJSON(w, http.StatusAccepted, map[string]string{})
+			return
+		}
+		WriteJSON(w, http.StatusOK, e.DependencyListExport)
+	})
+
+	s.Handle("GET /dependency_list_exports/:export_id/download", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		id, _ := strconv.Atoi(params["export_id"])
+		e := s.exports[id]
+		if e == nil || !e.HasFinished {
+			WriteError(w, http.StatusNotFound, "404 Not Found")
+			return
+		}
+		type component struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		}
+		bom := struct {
+			BOMFormat   string      `json:"bomFormat"`
+			SpecVersion string      `json:"specVersion"`
+			Components  []component `json:"components"`
+		}{BOMFormat: "CycloneDX", SpecVersion: "1.4", Components: []component{}}
+		for _, d := range e.project.Dependencies {
+			bom.Components = append(bom.Components, component{d.Name, d.Version})
+		}
+		WriteJSON(w, http.StatusOK, bom)
+	})
+
+	s.Handle("POST /projects/:id/uploads", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, "file is missing")
+			return
+		}
+		defer file.Close()
+		data, _ := io.ReadAll(file)
+		s.nextID++
+		secret := fmt.Sprintf("%032x", s.nextID)
+		upload := lib.ProjectUpload{
+			Alt:      header.Filename,
+			URL:      fmt.Sprintf("/uploads/%s/%s", secret, header.Filename),
+			FullPath: fmt.Sprintf("/-/project/%d/uploads/%s/%s", p.ID, secret, header.Filename),
+		}
+		upload.Markdown = fmt.Sprintf("[%s](%s)", upload.Alt, upload.URL)
+		p.Uploads[upload.FullPath] = string(data)
+		WriteJSON(w, http.StatusCreated, upload)
+	}))
+
+	s.Handle("POST /projects/:id/releases/:tag_name/assets/links", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		links, ok := p.ReleaseLinks[params["tag_name"]]
+		if !ok {
+			WriteError(w, http.StatusNotFound, "404 Not found")
+			return
+		}
+		var link lib.ReleaseLink
+		if err := json.NewDecoder(r.Body).Decode(&link); err != nil || link.Name == "" || link.URL == "" {
+			WriteError(w, http.StatusBadRequest, "name and url are required")
+			return
+		}
+		for _, l := range links {
+			if l.Name == link.Name {
+				WriteError(w, http.StatusBadRequest, "Name has already been taken")
+				return
+			}
+		}
+		s.nextID++
+		link.ID = s.nextID
+		if link.LinkType == "" {
+			link.LinkType = "other"
+		}
+		p.ReleaseLinks[params["tag_name"]] = append(links, link)
+		WriteJSON(w, http.StatusCreated, link)
+	}))
+
+	s.Handle("POST /projects/:id/export", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		p.ExportStatus = lib.TransferQueued
+		p.Archive = ""
+		WriteJSON(w, http.StatusAccepted, map[string]string{"message": "202 Accepted"})
+	}))
+
+	s.Handle("GET /projects/:id/export", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		status := p.ExportStatus
+		switch status {
+		case "":
+			status = lib.TransferNone
+		case lib.TransferQueued:
+			p.ExportStatus = lib.TransferStarted
+		case lib.TransferStarted:
+			p.ExportStatus = lib.TransferFinished
+			p.Archive = "gitlabtest export of " + p.Path
+		}
+		WriteJSON(w, http.StatusOK, lib.ProjectExport{ID: p.ID, PathWithNamespace: p.Path, ExportStatus: status})
+	}))
+
+	s.Handle("GET /projects/:id/export/download", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		if p.ExportStatus != lib.TransferFinished {
+			WriteError(w, http.StatusNotFound, "404 Not found")
+			return
+		}
+		ServeFile(w, r, "export.tar.gz", p.Archive)
+	}))
+
+	s.Handle("POST /projects/import", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		// The archive may be streamed from an export of this server, so it
+		// is read before taking the lock
+		file, _, err := r.FormFile("file")
+		if err != nil || r.FormValue("path") == "" {
+			WriteError(w, http.StatusBadRequest, "file and path are required")
+			return
+		}
+		defer file.Close()
+		data, _ := io.ReadAll(file)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		namespace := r.FormValue("namespace")
+		if namespace == "" {
+			namespace = s.user.Username
+		}
+		path := namespace + "/" + r.FormValue("path")
+		if existing := s.findProject(path); existing != nil {
+			if r.FormValue("overwrite") != "true" {
+				WriteError(w, http.StatusBadRequest, "Path has already been taken")
+				return
+			}
+			for i, p := range s.projects {
+				if p == existing {
+					s.projects = append(s.projects[:i], s.projects[i+1:]...)
+					break
+				}
+			}
+		}
+		s.nextID++
+		p := s.addProject(s.nextID, path)
+		p.Archive = string(data)
+		p.ImportStatus = lib.TransferScheduled
+		if !strings.HasPrefix(p.Archive, "gitlabtest export of ") {
+			p.ImportStatus = lib.TransferFailed
+		}
+		WriteJSON(w, http.StatusCreated, lib.ProjectImport{ID: p.ID, PathWithNamespace: p.Path, ImportStatus: lib.TransferScheduled})
+	})
+
+	s.Handle("GET /projects/:id/import", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		imp := lib.ProjectImport{ID: p.ID, PathWithNamespace: p.Path, ImportStatus: p.ImportStatus}
+		switch p.ImportStatus {
+		case "":
+			imp.ImportStatus = lib.TransferNone
+		case lib.TransferScheduled:
+			p.ImportStatus = lib.TransferStarted
+		case lib.TransferStarted:
+			p.ImportStatus = lib.TransferFinished
+		case lib.TransferFailed:
+			imp.ImportError = "The archive is not a valid project export"
+		}
+		WriteJSON(w, http.StatusOK, imp)
+	}))
+
+	s.Handle("GET /groups/:group/projects", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		group := params["group"]
+		subgroups := r.URL.Query().Get("include_subgroups") == "true"
+		statistics := r.URL.Query().Get("statistics") == "true"
+		out := []lib.Project{}
+		for _, p := range s.projects {
+			if p.Group == group || subgroups && strings.HasPrefix(p.Group, group+"/") {
+				project := lib.Project{ID: p.ID, PathWithNamespace: p.Path, WebURL: s.URL + "/" + p.Path}
+				if statistics {
+					stats := p.Statistics
+					project.Statistics = &stats
+				}
+				out = append(out, project)
+			}
+		}
+		if len(out) == 0 {
+			WriteError(w, http.StatusNotFound, "404 Group Not Found")
+			return
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, out))
+	})
+
+	s.Handle("GET /projects/:id/remote_mirrors", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		out := []*lib.RemoteMirror{}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, append(out, p.RemoteMirrors...)))
+	}))
+
+	s.Handle("POST /projects/:id/remote_mirrors", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		var req lib.RemoteMirrorRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		u, err := url.Parse(req.URL)
+		if err != nil || u.Host == "" {
+			WriteError(w, http.StatusBadRequest, "url is invalid")
+			return
+		}
+		if u.User != nil {
+			u.User = url.UserPassword("*****", "*****")
+		}
+		s.nextID++
+		m := &lib.RemoteMirror{ID: s.nextID, URL: strings.ReplaceAll(u.String(), "%2A", "*"), UpdateStatus: lib.MirrorNone, AuthMethod: "password"}
+		applyMirrorRequest(m, &req)
+		p.RemoteMirrors = append(p.RemoteMirrors, m)
+		WriteJSON(w, http.StatusCreated, m)
+	}))
+
+	s.Handle("PUT /projects/:id/remote_mirrors/:mirror_id", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		m := p.findRemoteMirror(params["mirror_id"])
+		if m == nil {
+			WriteError(w, http.StatusNotFound, "404 Not found")
+			return
+		}
+		var req lib.RemoteMirrorRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		applyMirrorRequest(m, &req)
+		WriteJSON(w, http.StatusOK, m)
+	}))
+
+	s.Handle("DELETE /projects/:id/remote_mirrors/:mirror_id", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		for i, m := range p.RemoteMirrors {
+			if strconv.Itoa(m.ID) == params["mirror_id"] {
+				p.RemoteMirrors = append(p.RemoteMirrors[:i], p.RemoteMirrors[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		WriteError(w, http.StatusNotFound, "404 Not found")
+	}))
+
+	s.Handle("POST /projects/:id/remote_mirrors/:mirror_id/sync", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		m := p.findRemoteMirror(params["mirror_id"])
+		if m == nil || !m.Enabled {
+			WriteError(w, http.StatusNotFound, "404 Not found")
+			return
+		}
+		now := FixtureTime
+		m.UpdateStatus, m.LastUpdateStartedAt = lib.MirrorScheduled, &now
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	s.Handle("GET /projects/:id/mirror/pull", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		if p.PullMirror == nil {
+			WriteError(w, http.StatusNotFound, "404 Not found")
+			return
+		}
+		WriteJSON(w, http.StatusOK, p.PullMirror)
+	}))
+
+	s.Handle("POST /projects/:id/mirror/pull", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		if p.PullMirror == nil {
+			WriteError(w, http.StatusBadRequest, "Pull mirroring is not enabled for this project")
+			return
+		}
+		now := FixtureTime
+		p.PullMirror.UpdateStatus, p.PullMirror.LastUpdateStartedAt = lib.MirrorScheduled, &now
+		WriteJSON(w, http.StatusOK, map[string]string{})
+	}))
+
+	s.Handle("GET /projects/:id", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		var avatar string
+		if p.Avatar != "" {
+			avatar = fmt.Sprintf("%s/uploads/-/system/project/avatar/%d/avatar.png", s.URL, p.ID)
+		}
+		WriteJSON(w, http.StatusOK, struct {
+			lib.ProjectSettings
+			lib.ServiceDesk
+			WebURL      string   `json:"web_url"`
+			Description string   `json:"description"`
+			Topics      []string `json:"topics"`
+			AvatarURL   string   `json:"avatar_url,omitempty"`
+			Visibility  string   `json:"visibility"`
+		}{p.Settings, p.ServiceDesk, s.URL + "/" + p.Path, p.Description, p.Topics, avatar, p.Visibility})
+	}))
+
+	s.Handle("GET /projects/:id/avatar", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		if p.Avatar == "" {
+			WriteError(w, http.StatusNotFound, "404 Avatar Not Found")
+			return
+		}
+		io.WriteString(w, p.Avatar)
+	}))
+
+	s.Handle("PUT /projects/:id", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		// Avatars are uploaded as forms
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+			f, _, err := r.FormFile("avatar")
+			if err != nil {
+				WriteError(w, http.StatusBadRequest, "avatar is invalid")
+				return
+			}
+			data, _ := io.ReadAll(f)
+			p.Avatar = string(data)
+			WriteJSON(w, http.StatusOK, p.Settings)
+			return
+		}
+		var req struct {
+			Description                               *string   `json:"description"`
+			Topics                                    *[]string `json:"topics"`
+			Visibility                                string    `json:"visibility"`
+			Mirror                                    *bool     `json:"mirror"`
+			ImportURL                                 string    `json:"import_url"`
+			MergeMethod                               string    `json:"merge_method"`
+			OnlyAllowMergeIfPipelineSucceeds          *bool     `json:"only_allow_merge_if_pipeline_succeeds"`
+			OnlyAllowMergeIfAllDiscussionsAreResolved *bool     `json:"only_allow_merge_if_all_discussions_are_resolved"`
+			RemoveSourceBranchAfterMerge              *bool     `json:"remove_source_branch_after_merge"`
+			MergeCommitTemplate                       *string   `json:"merge_commit_template"`
+			SquashCommitTemplate                      *string   `json:"squash_commit_template"`
+			MergeRequestsTemplate                     *string   `json:"merge_requests_template"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, tmpl := range []*string{req.MergeCommitTemplate, req.SquashCommitTemplate} {
+			if tmpl != nil && len(*tmpl) > lib.CommitTemplateMaxLength {
+				WriteError(w, http.StatusBadRequest, "commit template is too long (maximum is 500 characters)")
+				return
+			}
+		}
+		switch {
+		case req.Mirror == nil:
+		case !*req.Mirror:
+			p.PullMirror = nil
+		case req.ImportURL == "" && p.PullMirror == nil:
+			WriteError(w, http.StatusBadRequest, "import_url is missing")
+			return
+		case req.ImportURL != "":
+			s.nextID++
+			p.PullMirror = &lib.PullMirror{ID: s.nextID, URL: req.ImportURL, UpdateStatus: lib.MirrorScheduled}
+		}
+		switch req.MergeMethod {
+		case "":
+		case "merge", "rebase_merge", "ff":
+			p.Settings.MergeMethod = req.MergeMethod
+		default:
+			WriteError(w, http.StatusBadRequest, "merge_method does not have a valid value")
+			return
+		}
+		setBool(&p.Settings.OnlyAllowMergeIfPipelineSucceeds, req.OnlyAllowMergeIfPipelineSucceeds)
+		setBool(&p.Settings.OnlyAllowMergeIfAllDiscussionsAreResolved, req.OnlyAllowMergeIfAllDiscussionsAreResolved)
+		setBool(&p.Settings.RemoveSourceBranchAfterMerge, req.RemoveSourceBranchAfterMerge)
+		switch req.Visibility {
+		case "":
+		case lib.VisibilityPrivate, lib.VisibilityInternal, lib.VisibilityPublic:
+			p.Visibility = req.Visibility
+		default:
+			WriteError(w, http.StatusBadRequest, "visibility does not have a valid value")
+			return
+		}
+		if req.Description != nil {
+			p.Description = *req.Description
+		}
+		if req.Topics != nil {
+			p.Topics = *req.Topics
+		}
+		for _, set := range []struct {
+			dst *string
+			v   *string
+		}{
+			{&p.Settings.MergeCommitTemplate, req.MergeCommitTemplate},
+			{&p.Settings.SquashCommitTemplate, req.SquashCommitTemplate},
+			{&p.Settings.MergeRequestsTemplate, req.MergeRequestsTemplate},
+		} {
+			if set.v != nil {
+				*set.dst = *set.v
+			}
+		}
+		WriteJSON(w, http.StatusOK, p.Settings)
+	}))
+
+	s.Handle("GET /projects/:id/approvals", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		WriteJSON(w, http.StatusOK, p.ApprovalSettings)
+	}))
+
+	s.Handle("POST /projects/:id/approvals", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		var req struct {
+			ResetApprovalsOnPush        *bool `json:"reset_approvals_on_push"`
+			MergeRequestsAuthorApproval *bool `json:"merge_requests_author_approval"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		setBool(&p.ApprovalSettings.ResetApprovalsOnPush, req.ResetApprovalsOnPush)
+		setBool(&p.ApprovalSettings.MergeRequestsAuthorApproval, req.MergeRequestsAuthorApproval)
+		WriteJSON(w, http.StatusCreated, p.ApprovalSettings)
+	}))
+
+	s.Handle("GET /projects/:id/approval_rules", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		out := []lib.ApprovalRule{}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, append(out, p.ApprovalRules...)))
+	}))
+
+	s.Handle("POST /projects/:id/approval_rules", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		var rule lib.ApprovalRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil || rule.Name == "" {
+			WriteError(w, http.StatusBadRequest, "name is missing")
+			return
+		}
+		if rule.RuleType == "" {
+			rule.RuleType = "regular"
+		}
+		for _, existing := range p.ApprovalRules {
+			if rule.RuleType == "any_approver" && existing.RuleType == "any_approver" {
+				WriteError(w, http.StatusBadRequest, "any-approver for the project already exists")
+				return
+			}
+		}
+		s.nextID++
+		rule.ID = s.nextID
+		p.ApprovalRules = append(p.ApprovalRules, rule)
+		WriteJSON(w, http.StatusCreated, rule)
+	}))
+
+	s.Handle("PUT /projects/:id/approval_rules/:rule_id", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		var req struct {
+			ApprovalsRequired *int `json:"approvals_required"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		for i := range p.ApprovalRules {
+			if rule := &p.ApprovalRules[i]; strconv.Itoa(rule.ID) == params["rule_id"] {
+				if req.ApprovalsRequired != nil {
+					rule.ApprovalsRequired = *req.ApprovalsRequired
+				}
+				WriteJSON(w, http.StatusOK, rule)
+				return
+			}
+		}
+		WriteError(w, http.StatusNotFound, "404 Not found")
+	}))
+
+	s.Handle("GET /projects/:id/variables", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		out := []lib.Variable{}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, append(out, p.Variables...)))
+	}))
+
+	s.Handle("POST /projects/:id/variables", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		var v lib.Variable
+		if err := json.NewDecoder(r.Body).Decode(&v); err != nil || v.Key == "" {
+			WriteError(w, http.StatusBadRequest, "key is missing")
+			return
+		}
+		if v.EnvironmentScope == "" {
+			v.EnvironmentScope = "*"
+		}
+		for _, existing := range p.Variables {
+			if existing.Key == v.Key && existing.EnvironmentScope == v.EnvironmentScope {
+				WriteError(w, http.StatusBadRequest, fmt.Sprintf("%s has already been taken", v.Key))
+				return
+			}
+		}
+		if v.VariableType == "" {
+			v.VariableType = "env_var"
+		}
+		p.Variables = append(p.Variables, v)
+		WriteJSON(w, http.StatusCreated, v)
+	}))
+
+	s.Handle("PUT /projects/:id/variables/:key", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		scope := r.URL.Query().Get("filter[environment_scope]")
+		if scope == "" {
+			scope = "*"
+		}
+		var req lib.Variable
+		json.NewDecoder(r.Body).Decode(&req)
+		for i := range p.Variables {
+			if v := &p.Variables[i]; v.Key == params["key"] && v.EnvironmentScope == scope {
+				v.Value, v.Protected, v.Masked = req.Value, req.Protected, req.Masked
+				WriteJSON(w, http.StatusOK, v)
+				return
+			}
+		}
+		WriteError(w, http.StatusNotFound, "404 Variable Not Found")
+	}))
+
+	s.Handle("GET /projects/:id/audit_events", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		WriteJSON(w, http.StatusOK, Paginate(w, r, filterAuditEvents(r, p.AuditEvents)))
+	}))
+
+	s.Handle("GET /groups/:group/audit_events", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		events, ok := s.groupAudit[params["group"]]
+		if !ok {
+			WriteError(w, http.StatusNotFound, "404 Group Not Found")
+			return
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, filterAuditEvents(r, events)))
+	})
+
+	s.Handle("GET /users", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		username := r.URL.Query().Get("username")
+		out := []lib.User{}
+		for _, u := range s.users {
+			if username == "" || strings.EqualFold(u.Username, username) {
+				out = append(out, u)
+			}
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, out))
+	})
+
+	s.Handle("POST /users", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var req struct {
+			Username string `json:"username"`
+			Name     string `json:"name"`
+			Email    string `json:"email"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		s.createUser(w, req.Username, req.Name, req.Email)
+	})
+
+	s.Handle("POST /service_accounts", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var req struct {
+			Username string `json:"username"`
+			Name     string `json:"name"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Username == "" {
+			req.Username = fmt.Sprintf("service_account_%d", s.nextID+1)
+		}
+		if req.Name == "" {
+			req.Name = "Service account user"
+		}
+		s.createUser(w, req.Username, req.Name, req.Username+"@noreply.example.com")
+	})
+
+	s.Handle("POST /users/:user_id/personal_access_tokens", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.admin {
+			WriteError(w, http.StatusForbidden, "403 Forbidden")
+			return
+		}
+		id, _ := strconv.Atoi(params["user_id"])
+		if !slices.ContainsFunc(s.users, func(u lib.User) bool { return u.ID == id }) {
+			WriteError(w, http.StatusNotFound, "404 User Not Found")
+			return
+		}
+		var req lib.CreateAccessTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" || len(req.Scopes) == 0 {
+			WriteError(w, http.StatusBadRequest, "name and scopes are required")
+			return
+		}
+		s.nextID++
+		token := lib.AccessToken{ID: s.nextID, Name: req.Name, Scopes: req.Scopes, Active: true, ExpiresAt: req.ExpiresAt, CreatedAt: time.Now().UTC()}
+		s.userTokens[id] = append(s.userTokens[id], token)
+		token.Token = fmt.Sprintf("glpat-user%016d", token.ID)
+		WriteJSON(w, http.StatusCreated, token)
+	})
+
+	s.Handle("GET /groups/:group/members", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.groupExists(params["group"]) {
+			WriteError(w, http.StatusNotFound, "404 Group Not Found")
+			return
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, s.groupMembers[params["group"]]))
+	})
+
+	s.Handle("POST /groups/:group/members", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		group := params["group"]
+		if !s.groupExists(group) {
+			WriteError(w, http.StatusNotFound, "404 Group Not Found")
+			return
+		}
+		members := s.groupMembers[group]
+		s.addMember(w, r, &members)
+		s.groupMembers[group] = members
+	})
+
+	s.Handle("PUT /groups/:group/members/:user_id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		editMember(w, r, s.groupMembers[params["group"]], params["user_id"])
+	})
+
+	s.Handle("DELETE /groups/:group/members/:user_id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		members := s.groupMembers[params["group"]]
+		removeMember(w, &members, params["user_id"])
+		s.groupMembers[params["group"]] = members
+	})
+
+	s.Handle("GET /projects/:id/members", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		WriteJSON(w, http.StatusOK, Paginate(w, r, p.Members))
+	}))
+
+	s.Handle("POST /projects/:id/members", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		s.addMember(w, r, &p.Members)
+	}))
+
+	s.Handle("PUT /projects/:id/members/:user_id", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		editMember(w, r, p.Members, params["user_id"])
+	}))
+
+	s.Handle("DELETE /projects/:id/members/:user_id", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		removeMember(w, &p.Members, params["user_id"])
+	}))
+
+	s.Handle("GET /broadcast_messages", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		now := time.Now()
+		messages := make([]lib.BroadcastMessage, len(s.broadcasts))
+		for i, m := range s.broadcasts {
+			m.Active = !now.Before(m.StartsAt) && now.Before(m.EndsAt)
+			messages[i] = m
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, messages))
+	})
+
+	s.Handle("POST /broadcast_messages", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.admin {
+			WriteError(w, http.StatusForbidden, "403 Forbidden")
+			return
+		}
+		m := lib.BroadcastMessage{BroadcastType: "banner", Theme: "indigo", StartsAt: time.Now().UTC()}
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil || m.Message == "" {
+			WriteError(w, http.StatusBadRequest, "message is missing")
+			return
+		}
+		if m.EndsAt.IsZero() {
+			m.EndsAt = m.StartsAt.Add(time.Hour)
+		}
+		if m.BroadcastType != "banner" && m.BroadcastType != "notification" {
+			WriteError(w, http.StatusBadRequest, "broadcast_type does not have a valid value")
+			return
+		}
+		if !m.EndsAt.After(m.StartsAt) {
+			WriteError(w, http.StatusBadRequest, "ends_at must be after starts_at")
+			return
+		}
+		s.nextID++
+		m.ID = s.nextID
+		now := time.Now()
+		m.Active = !now.Before(m.StartsAt) && now.Before(m.EndsAt)
+		s.broadcasts = append(s.broadcasts, m)
+		WriteJSON(w, http.StatusCreated, m)
+	})
+
+	s.Handle("DELETE /broadcast_messages/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.admin {
+			WriteError(w, http.StatusForbidden, "403 Forbidden")
+			return
+		}
+		i := slices.IndexFunc(s.broadcasts, func(m lib.BroadcastMessage) bool { return strconv.Itoa(m.ID) == params["id"] })
+		if i < 0 {
+			WriteError(w, http.StatusNotFound, "404 Broadcast Message Not Found")
+			return
+		}
+		m := s.broadcasts[i]
+		s.broadcasts = slices.Delete(s.broadcasts, i, i+1)
+		WriteJSON(w, http.StatusOK, m)
+	})
+
+	s.Handle("GET /application/settings", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.admin {
+			WriteError(w, http.StatusForbidden, "403 Forbidden")
+			return
+		}
+		WriteJSON(w, http.StatusOK, s.maintenance)
+	})
+
+	s.Handle("PUT /application/settings", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.admin {
+			WriteError(w, http.StatusForbidden, "403 Forbidden")
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&s.maintenance); err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		WriteJSON(w, http.StatusOK, s.maintenance)
+	})
+
+	s.Handle("GET /audit_events", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.admin {
+			WriteError(w, http.StatusForbidden, "403 Forbidden")
+			return
+		}
+		var all []lib.AuditEvent
+		for _, events := range s.groupAudit {
+			all = append(all, events...)
+		}
+		for _, p := range s.projects {
+			all = append(all, p.AuditEvents...)
+		}
+		sort.Slice(all, func(i, j int) bool { return all[i].CreatedAt.After(all[j].CreatedAt) })
+		WriteJSON(w, http.StatusOK, Paginate(w, r, filterAuditEvents(r, all)))
+	})
+
+	s.Handle("POST /projects/:id/repository/commits", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		var req lib.CreateCommitRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Branch == "" || req.CommitMessage == "" || len(req.Actions) == 0 {
+			WriteError(w, http.StatusBadRequest, "branch, commit_message and actions are required")
+			return
+		}
+		b := p.findBranch(req.Branch)
+		if b == nil {
+			WriteError(w, http.StatusBadRequest, "You can only create or edit files when you are on a branch")
+			return
+		}
+		for _, a := range req.Actions {
+			_, exists := p.Files[a.FilePath]
+			switch {
+			case a.Action == "create" && !exists, a.Action == "update" && exists:
+				p.Files[a.FilePath] = a.Content
+			case a.Action == "delete" && exists:
+				delete(p.Files, a.FilePath)
+			default:
+				WriteError(w, http.StatusBadRequest, fmt.Sprintf("A file with this name doesn't exist or already exists: %s", a.FilePath))
+				return
+			}
+		}
+		s.nextID++
+		title, _, _ := strings.Cut(req.CommitMessage, "\n")
+		b.Commit = lib.Commit{ID: fmt.Sprintf("%040x", s.nextID), Title: title, Message: req.CommitMessage, AuthorName: s.actor(r).Name, CreatedAt: time.Now().UTC()}
+		b.Commit.ShortID = b.Commit.ID[:8]
+		WriteJSON(w, http.StatusCreated, b.Commit)
+	}))
+
+	s.Handle("POST /projects/:id/issues", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		var req lib.CreateIssueRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Title == "" {
+			WriteError(w, http.StatusBadRequest, "title is required")
+			return
+		}
+		s.nextID++
+		issue := &lib.Issue{
+			ID:          s.nextID,
+			IID:         len(p.Issues) + 1,
+			ProjectID:   p.ID,
+			Title:       req.Title,
+			Description: req.Description,
+			State:       "opened",
+			Labels:      req.Labels,
+			Author:      s.actor(r),
+			Assignees:   p.membersByID(req.AssigneeIDs),
+			CreatedAt:   time.Now().UTC(),
+		}
+		issue.UpdatedAt = issue.CreatedAt
+		issue.WebURL = fmt.Sprintf("%s/%s/-/issues/%d", s.URL, p.Path, issue.IID)
+		p.Issues = append(p.Issues, issue)
+		WriteJSON(w, http.StatusCreated, issue)
+	}))
+
+	s.Handle("GET /projects/:id/issues", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		q := r.URL.Query()
+		out := []*lib.Issue{}
+		for i := len(p.Issues) - 1; i >= 0; i-- {
+			issue := p.Issues[i]
+			if state := q.Get("state"); state != "" && state != "all" && issue.State != state {
+				continue
+			}
+			if labels := q.Get("labels"); labels != "" && !hasAll(issue.Labels, strings.Split(labels, ",")) {
+				continue
+			}
+			if m := q.Get("milestone"); m != "" && (issue.Milestone == nil || issue.Milestone.Title != m) {
+				continue
+			}
+			if author := q.Get("author_username"); author != "" && issue.Author.Username != author {
+				continue
+			}
+			out = append(out, issue)
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, out))
+	}))
+
+	s.Handle("GET /projects/:id/issues/:issue_iid/notes", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		iid, _ := strconv.Atoi(params["issue_iid"])
+		if iid < 1 || iid > len(p.Issues) {
+			WriteError(w, http.StatusNotFound, "404 Issue Not Found")
+			return
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, p.IssueNotes[iid]))
+	}))
+
+	s.Handle("POST /projects/:id/issues/:issue_iid/notes", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		iid, _ := strconv.Atoi(params["issue_iid"])
+		if iid < 1 || iid > len(p.Issues) {
+			WriteError(w, http.StatusNotFound, "404 Issue Not Found")
+			return
+		}
+		var req struct {
+			Body     string `json:"body"`
+			Internal bool   `json:"internal"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Body == "" {
+			WriteError(w, http.StatusBadRequest, "body is required")
+			return
+		}
+		s.nextID++
+		note := lib.Note{ID: s.nextID, Body: req.Body, Author: s.actor(r), Internal: req.Internal, CreatedAt: time.Now().UTC()}
+		p.IssueNotes[iid] = append(p.IssueNotes[iid], note)
+		p.Issues[iid-1].UserNotesCount++
+		WriteJSON(w, http.StatusCreated, note)
+	}))
+
+	s.Handle("GET /projects/:id/issues/:issue_iid", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		iid, _ := strconv.Atoi(params["issue_iid"])
+		if iid < 1 || iid > len(p.Issues) {
+			WriteError(w, http.StatusNotFound, "404 Issue Not Found")
+			return
+		}
+		WriteJSON(w, http.StatusOK, p.Issues[iid-1])
+	}))
+
+	s.Handle("PUT /projects/:id/issues/:issue_iid", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		iid, _ := strconv.Atoi(params["issue_iid"])
+		if iid < 1 || iid > len(p.Issues) {
+			WriteError(w, http.StatusNotFound, "404 Issue Not Found")
+			return
+		}
+		issue := p.Issues[iid-1]
+		var req lib.UpdateIssueRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if req.Title != "" {
+			issue.Title = req.Title
+		}
+		if req.Description != nil {
+			issue.Description = *req.Description
+		}
+		if req.Labels != nil {
+			issue.Labels = *req.Labels
+		}
+		if req.AssigneeIDs != nil {
+			issue.Assignees = p.membersByID(*req.AssigneeIDs)
+		}
+		if req.Confidential != nil {
+			issue.Confidential = *req.Confidential
+		}
+		if req.DiscussionLocked != nil {
+			issue.DiscussionLocked = *req.DiscussionLocked
+		}
+		now := time.Now().UTC()
+		switch req.StateEvent {
+		case "":
+		case "close":
+			issue.State, issue.ClosedAt = "closed", &now
+		case "reopen":
+			issue.State, issue.ClosedAt = "opened", nil
+		default:
+			WriteError(w, http.StatusBadRequest, "state_event does not have a valid value")
+			return
+		}
+		issue.UpdatedAt = now
+		WriteJSON(w, http.StatusOK, issue)
+	}))
+
+	s.Handle("GET /projects/:id/milestones", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		out := []lib.Milestone{}
+		for _, m := range p.Milestones {
+			if title := r.URL.Query().Get("title"); title == "" || m.Title == title {
+				out = append(out, m)
+			}
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, out))
+	}))
+
+	s.Handle("GET /projects/:id/access_tokens", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		WriteJSON(w, http.StatusOK, Paginate(w, r, p.AccessTokens))
+	}))
+
+	s.Handle("POST /projects/:id/access_tokens", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		var req lib.CreateAccessTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" || len(req.Scopes) == 0 || req.ExpiresAt.IsZero() {
+			WriteError(w, http.StatusBadRequest, "name, scopes and expires_at are required")
+			return
+		}
+		if req.AccessLevel == 0 {
+			req.AccessLevel = lib.AccessMaintainer
+		}
+		s.nextID++
+		token := lib.AccessToken{
+			ID:          s.nextID,
+			Name:        req.Name,
+			Scopes:      req.Scopes,
+			AccessLevel: req.AccessLevel,
+			Active:      true,
+			ExpiresAt:   req.ExpiresAt,
+			CreatedAt:   time.Now().UTC(),
+		}
+		p.AccessTokens = append(p.AccessTokens, token)
+		token.Token = fmt.Sprintf("glpat-fake%016d", token.ID)
+		WriteJSON(w, http.StatusCreated, token)
+	}))
+
+	s.Handle("DELETE /projects/:id/access_tokens/:token_id", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		id, _ := strconv.Atoi(params["token_id"])
+		for i, t := range p.AccessTokens {
+			if t.ID == id {
+				p.AccessTokens = append(p.AccessTokens[:i], p.AccessTokens[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		WriteError(w, http.StatusNotFound, "404 Not Found")
+	}))
+
+	s.Handle("GET /projects/:id/deploy_keys", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		WriteJSON(w, http.StatusOK, Paginate(w, r, p.DeployKeys))
+	}))
+
+	s.Handle("POST /projects/:id/deploy_keys", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		var req lib.CreateDeployKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Title == "" || req.Key == "" {
+			WriteError(w, http.StatusBadRequest, "title and key are required")
+			return
+		}
+		for _, k := range p.DeployKeys {
+			if k.Key == req.Key {
+				WriteError(w, http.StatusBadRequest, "key has already been taken")
+				return
+			}
+		}
+		s.nextID++
+		key := lib.DeployKey{ID: s.nextID, Title: req.Title, Key: req.Key, CanPush: req.CanPush, CreatedAt: time.Now().UTC()}
+		if req.ExpiresAt != nil {
+			key.ExpiresAt = *req.ExpiresAt
+		}
+		p.DeployKeys = append(p.DeployKeys, key)
+		WriteJSON(w, http.StatusCreated, key)
+	}))
+
+	s.Handle("DELETE /projects/:id/deploy_keys/:key_id", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		id, _ := strconv.Atoi(params["key_id"])
+		for i, k := range p.DeployKeys {
+			if k.ID == id {
+				p.DeployKeys = append(p.DeployKeys[:i], p.DeployKeys[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		WriteError(w, http.StatusNotFound, "404 Deploy Key Not Found")
+	}))
+
+	s.Handle("GET /projects/:id/deployments", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		q := r.URL.Query()
+		out := []lib.Deployment{}
+		for _, d := range p.Deployments {
+			if env := q.Get("environment"); env != "" && d.Environment.Name != env {
+				continue
+			}
+			if status := q.Get("status"); status != "" && d.Status != status {
+				continue
+			}
+			// Approvals are only part of the single-deployment response
+			listed := *d
+			listed.PendingApprovalCount, listed.Approvals = 0, nil
+			out = append(out, listed)
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, out))
+	}))
+
+	s.Handle("GET /projects/:id/deployments/:deployment_id", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		id, _ := strconv.Atoi(params["deployment_id"])
+		if d := p.findDeployment(id); d != nil {
+			WriteJSON(w, http.StatusOK, d)
+			return
+		}
+		WriteError(w, http.StatusNotFound, "404 Deployment Not Found")
+	}))
+
+	s.Handle("POST /projects/:id/deployments/:deployment_id/approval", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		id, _ := strconv.Atoi(params["deployment_id"])
+		d := p.findDeployment(id)
+		if d == nil {
+			WriteError(w, http.StatusNotFound, "404 Deployment Not Found")
+			return
+		}
+		var req struct {
+			Status  string `json:"status"`
+			Comment string `json:"comment"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Status != "approved" && req.Status != "rejected") {
+			WriteError(w, http.StatusBadRequest, "status does not have a valid value")
+			return
+		}
+		user := s.actor(r)
+		switch {
+		case d.Status != "blocked":
+			WriteError(w, http.StatusBadRequest, "This deployment job is not waiting for approvals.")
+			return
+		case d.User.ID == user.ID:
+			WriteError(w, http.StatusBadRequest, "You cannot approve your own deployment.")
+			return
+		}
+		approval := lib.DeploymentApproval{User: user, Status: req.Status, Comment: req.Comment, CreatedAt: time.Now().UTC()}
+		d.Approvals = append(d.Approvals, approval)
+		if req.Status == "approved" && d.PendingApprovalCount > 0 {
+			d.PendingApprovalCount--
+			if d.PendingApprovalCount == 0 {
+				d.Status = "running"
+			}
+		}
+		WriteJSON(w, http.StatusCreated, approval)
+	}))
+
+	s.Handle("GET /projects/:id/protected_environments", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		WriteJSON(w, http.StatusOK, Paginate(w, r, p.ProtectedEnvironments))
+	}))
+
+	s.Handle("GET /projects/:id/feature_flags", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		scope := r.URL.Query().Get("scope")
+		out := []lib.FeatureFlag{}
+		for _, f := range p.FeatureFlags {
+			if (scope == "enabled" && !f.Active) || (scope == "disabled" && f.Active) {
+				continue
+			}
+			out = append(out, *f)
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, out))
+	}))
+
+	s.Handle("GET /projects/:id/feature_flags/:name", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		if f := p.findFeatureFlag(params["name"]); f != nil {
+			WriteJSON(w, http.StatusOK, f)
+			return
+		}
+		WriteError(w, http.StatusNotFound, "404 Feature Flag Not Found")
+	}))
+
+	s.Handle("POST /projects/:id/feature_flags", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		var req lib.CreateFeatureFlagRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+			WriteError(w, http.StatusBadRequest, "name is missing")
+			return
+		}
+		if p.findFeatureFlag(req.Name) != nil {
+			WriteError(w, http.StatusBadRequest, "Name has already been taken")
+			return
+		}
+		now := time.Now().UTC()
+		f := &lib.FeatureFlag{Name: req.Name, Description: req.Description, Active: req.Active, Version: req.Version, Strategies: req.Strategies, CreatedAt: now, UpdatedAt: now}
+		for i := range f.Strategies {
+			s.nextID++
+			f.Strategies[i].ID = s.nextID
+			for j := range f.Strategies[i].Scopes {
+				s.nextID++
+				f.Strategies[i].Scopes[j].ID = s.nextID
+			}
+		}
+		p.FeatureFlags = append(p.FeatureFlags, f)
+		WriteJSON(w, http.StatusCreated, f)
+	}))
+
+	s.Handle("PUT /projects/:id/feature_flags/:name", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, params map[string]string) {
+		f := p.findFeatureFlag(params["name"])
+		if f == nil {
+			WriteError(w, http.StatusNotFound, "404 Feature Flag Not Found")
+			return
+		}
+		var req struct {
+			Active      *bool   `json:"active"`
+			Description *string `json:"description"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteError(w, http.StatusBadRequest, "invalid request")
+			return
+		}
+		if req.Active != nil {
+			f.Active = *req.Active
+		}
+		if req.Description != nil {
+			f.Description = *req.Description
+		}
+		f.UpdatedAt = time.Now().UTC()
+		WriteJSON(w, http.StatusOK, f)
+	}))
+
+	s.Handle("GET /projects/:id/members/all", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		WriteJSON(w, http.StatusOK, Paginate(w, r, p.Members))
+	}))
+
+	s.Handle("GET /projects/:id/events", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		q := r.URL.Query()
+		after, _ := time.Parse("2006-01-02", q.Get("after"))
+		out := []lib.Event{}
+		// Newest first, like GitLab
+		for i := len(p.Events) - 1; i >= 0; i-- {
+			e := p.Events[i]
+			if !after.IsZero() && e.CreatedAt.Before(after.AddDate(0, 0, 1)) {
+				continue
+			}
+			if tt := q.Get("target_type"); tt != "" && eventTargetTypes[tt] != strings.TrimPrefix(e.TargetType, "Diff") {
+				continue
+			}
+			out = append(out, e)
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, out))
+	}))
+
+	s.Handle("GET /projects/:id/repository/compare", s.withProject(func(w http.ResponseWriter, r *http.Request, p *Project, _ map[string]string) {
+		q := r.URL.Query()
+		cmp := p.Compare[q.Get("from")+"..."+q.Get("to")]
+		if cmp == nil {
+			cmp = &lib.Comparison{Commits: []lib.Commit{}, Diffs: []lib.Diff{}}
+		}
+		WriteJSON(w, http.StatusOK, cmp)
+	}))
+
+	s.Handle("GET /merge_requests", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var all []*lib.MergeRequest
+		for _, p := range s.projects {
+			all = append(all, p.MRs...)
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, filterMRs(all, r.URL.Query(), s.actor(r))))
+	})
+
+	s.Handle("GET /groups/:group/merge_requests", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var all []*lib.MergeRequest
+		found := false
+		for _, p := range s.projects {
+			if p.Group == params["group"] || strings.HasPrefix(p.Group, params["group"]+"/") {
+				found = true
+				all = append(all, p.MRs...)
+			}
+		}
+		if !found {
+			WriteError(w, http.StatusNotFound, "404 Group Not Found")
+			return
+		}
+		WriteJSON(w, http.StatusOK, Paginate(w, r, filterMRs(all, r.URL.Query(), s.actor(r))))
+	})
+}
+
+// eventTargetTypes maps the events target_type filter to event target types
+var eventTargetTypes = map[string]string{"merge_request": "MergeRequest", "note": "Note", "issue": "Issue"}
+
+// filterMRs applies the MR listing query filters supported by the fake;
+// the created_by_me and assigned_to_me scopes are relative to actor.
+// order_by=updated_at sorts the result newest first.
+func filterMRs(mrs []*lib.MergeRequest, q url.Values, actor lib.User) []*lib.MergeRequest {
+	out := []*lib.MergeRequest{}
+	for _, mr := range mrs {
+		if state := q.Get("state"); state != "" && state != "all" && mr.State != state {
+			continue
+		}
+		if q.Get("scope") == "created_by_me" && mr.Author.ID != actor.ID {
+			continue
+		}
+		if q.Get("scope") == "assigned_to_me" && !slices.ContainsFunc(mr.Assignees, func(u lib.User) bool { return u.ID == actor.ID }) {
+			continue
+		}
+		if src := q.Get("source_branch"); src != "" && mr.SourceBranch != src {
+			continue
+		}
+		if tgt := q.Get("target_branch"); tgt != "" && mr.TargetBranch != tgt {
+			continue
+		}
+		if rid := q.Get("reviewer_id"); rid != "" && !hasReviewer(mr, rid) {
+			continue
+		}
+		if after, err := time.Parse(time.RFC3339, q.Get("updated_after")); err == nil && !mr.UpdatedAt.After(after) {
+			continue
+		}
+		if labels := q.Get("labels"); labels != "" && !hasAll(mr.Labels, strings.Split(labels, ",")) {
+			continue
+		}
+		if m := q.Get("milestone"); m != "" && (mr.Milestone == nil || mr.Milestone.Title != m) {
+			continue
+		}
+		if q.Get("draft") == "no" && mr.Draft {
+			continue
+		}
+		out = append(out, mr)
+	}
+	if q.Get("order_by") == "updated_at" {
+		sort.SliceStable(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	}
+	return out
+}
+
+func hasReviewer(mr *lib.MergeRequest, id string) bool {
+	for _, r := range mr.Reviewers {
+		if strconv.Itoa(r.ID) == id {
+			return true
+		}
+	}
+	return false
+}
+
+func nextIID(p *Project) int {
+	max := 0
+	for _, mr := range p.MRs {
+		if mr.IID > max {
+			max = mr.IID
+		}
+	}
+	return max + 1
+}
+
+// applyMRUpdate applies the fields of an update request to mr
+// membersByID returns the project members with the given user IDs, in
+// order, skipping unknown ones
+func (p *Project) membersByID(ids []int) []lib.User {
+	users := []lib.User{}
+	for _, id := range ids {
+		for _, m := range p.Members {
+			if m.ID == id {
+				users = append(users, m.User)
+			}
+		}
+	}
+	return users
+}
+
+func applyMRUpdate(p *Project, mr *lib.MergeRequest, req map[string]json.RawMessage) error {
+	for key, raw := range req {
+		var err error
+		switch key {
+		case "title":
+			err = json.Unmarshal(raw, &mr.Title)
+			mr.Draft = strings.HasPrefix(mr.Title, "Draft:")
+		case "description":
+			err = json.Unmarshal(raw, &mr.Description)
+		case "target_branch":
+			err = json.Unmarshal(raw, &mr.TargetBranch)
+		case "labels":
+			err = json.Unmarshal(raw, &mr.Labels)
+		case "add_labels", "remove_labels":
+			var names string
+			if err = json.Unmarshal(raw, &names); err != nil {
+				break
+			}
+			for _, name := range strings.Split(names, ",") {
+				if name = strings.TrimSpace(name); name == "" {
+					continue
+				}
+				mr.Labels = removeString(mr.Labels, name)
+				if key == "add_labels" {
+					mr.Labels = append(mr.Labels, name)
+				}
+			}
+		case "assignee_ids", "reviewer_ids":
+			var ids []int
+			if err = json.Unmarshal(raw, &ids); err != nil {
+				break
+			}
+			users := p.membersByID(ids)
+			if key == "assignee_ids" {
+				mr.Assignees = users
+			} else {
+				mr.Reviewers = users
+			}
+		case "discussion_locked":
+			err = json.Unmarshal(raw, &mr.DiscussionLocked)
+		case "state_event":
+			var event string
+			if err = json.Unmarshal(raw, &event); err != nil {
+				break
+			}
+			switch event {
+			case "close":
+				mr.State = "closed"
+			case "reopen":
+				mr.State = "opened"
+			default:
+				return fmt.Errorf("state_event does not have a valid value")
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func removeString(list []string, s string) []string {
+	out := list[:0:0]
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// hasAll reports whether list holds every one of want
+func hasAll(list, want []string) bool {
+	for _, w := range want {
+		if !contains(list, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterAuditEvents applies the created_after and created_before
+// parameters of an audit event listing
+func filterAuditEvents(r *http.Request, events []lib.AuditEvent) []lib.AuditEvent {
+	q := r.URL.Query()
+	out := []lib.AuditEvent{}
+	for _, e := range events {
+		if after, err := time.Parse(time.RFC3339, q.Get("created_after")); err == nil && e.CreatedAt.Before(after) {
+			continue
+		}
+		if before, err := time.Parse(time.RFC3339, q.Get("created_before")); err == nil && e.CreatedAt.After(before) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// setBool sets *dst when the request has a value for it
+func setBool(dst *bool, v *bool) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+// protectedBranch is a protected branch allowing pushes and merges from
+// the given roles
+func protectedBranch(id int, name string, push, merge int) lib.ProtectedBranch {
+	level := func(l int) []lib.BranchAccessLevel {
+		return []lib.BranchAccessLevel{{AccessLevel: l, Description: lib.AccessLevelName(l)}}
+	}
+	return lib.ProtectedBranch{ID: id, Name: name, PushAccessLevels: level(push), MergeAccessLevels: level(merge)}
+}
+
+// addMember adds the instance user of the request body to members, as
+// POST /groups/:id/members and /projects/:id/members do. Callers must
+// hold s.mu.
+func (s *Server) addMember(w http.ResponseWriter, r *http.Request, members *[]lib.Member) {
+	var req struct {
+		UserID      int `json:"user_id"`
+		AccessLevel int `json:"access_level"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	i := slices.IndexFunc(s.users, func(u lib.User) bool { return u.ID == req.UserID })
+	if i < 0 {
+		WriteError(w, http.StatusNotFound, "404 User Not Found")
+		return
+	}
+	if req.AccessLevel == 0 {
+		WriteError(w, http.StatusBadRequest, "access_level is missing")
+		return
+	}
+	if slices.ContainsFunc(*members, func(m lib.Member) bool { return m.ID == req.UserID }) {
+		WriteError(w, http.StatusConflict, "Member already exists")
+		return
+	}
+	m := lib.Member{User: s.users[i], State: "active", AccessLevel: req.AccessLevel}
+	*members = append(*members, m)
+	WriteJSON(w, http.StatusCreated, m)
+}
+
+// editMember changes the access level of a member
+func editMember(w http.ResponseWriter, r *http.Request, members []lib.Member, userID string) {
+	var req struct {
+		AccessLevel int `json:"access_level"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	for i := range members {
+		if strconv.Itoa(members[i].ID) == userID {
+			members[i].AccessLevel = req.AccessLevel
+			WriteJSON(w, http.StatusOK, members[i])
+			return
+		}
+	}
+	WriteError(w, http.StatusNotFound, "404 Member Not Found")
+}
+
+// removeMember removes a member
+func removeMember(w http.ResponseWriter, members *[]lib.Member, userID string) {
+	i := slices.IndexFunc(*members, func(m lib.Member) bool { return strconv.Itoa(m.ID) == userID })
+	if i < 0 {
+		WriteError(w, http.StatusNotFound, "404 Member Not Found")
+		return
+	}
+	*members = slices.Delete(*members, i, i+1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createUser registers an account for the admin-only user and service
+// account endpoints. Callers must hold s.mu.
+func (s *Server) createUser(w http.ResponseWriter, username, name, email string) {
+	if !s.admin {
+		WriteError(w, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+	if username == "" || name == "" || email == "" {
+		WriteError(w, http.StatusBadRequest, "username, name and email are required")
+		return
+	}
+	for _, u := range s.users {
+		if strings.EqualFold(u.Username, username) {
+			WriteError(w, http.StatusConflict, "Username has already been taken")
+			return
+		}
+	}
+	s.nextID++
+	u := lib.User{ID: s.nextID, Username: username, Name: name, WebURL: s.URL + "/" + username}
+	s.users = append(s.users, u)
+	WriteJSON(w, http.StatusCreated, u)
+}
@@ -0,0 +1,85 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// GraphQLError is returned when a GraphQL response carries errors
+type GraphQLError struct {
+	Messages []string
+}
+
+func (e *GraphQLError) Error() string {
+	return "GraphQL error: " + strings.Join(e.Messages, "; ")
+}
+
+// Unwrap maps GitLab's answer for missing or inaccessible resources to
+// ErrNotFound
+func (e *GraphQLError) Unwrap() error {
+	for _, m := range e.Messages {
+		if strings.Contains(m, "does not exist or you don't have permission") {
+			return ErrNotFound
+		}
+	}
+	return nil
+}
+
+// GraphQL runs a named query or mutation against /api/graphql and decodes
+// its data into out. Some features, like vulnerability triage, are only
+// fully available there.
+func (c *Client) GraphQL(operation, query string, variables map[string]interface{}, out interface{}) error {
+	endpoint := c.config.GraphQLEndpoint()
+	req := map[string]interface{}{"operationName": operation, "query": query, "variables": variables}
+
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := c.do("POST", endpoint, req, &resp, http.StatusOK); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		gqlErr := &GraphQLError{}
+		for _, e := range resp.Errors {
+			gqlErr.Messages = append(gqlErr.Messages, e.Message)
+		}
+		return gqlErr
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// GlobalID returns the GraphQL ID of a resource, e.g.
+// "gid://gitlab/Vulnerability/12"
+func GlobalID(kind string, id int) string {
+	return fmt.Sprintf("gid://gitlab/%s/%d", kind, id)
+}
+
+// ParseGlobalID returns the numeric ID of a GraphQL ID
+func ParseGlobalID(gid string) (int, error) {
+	id, err := strconv.Atoi(gid[strings.LastIndex(gid, "/")+1:])
+	if err != nil || !strings.HasPrefix(gid, "gid://gitlab/") {
+		return 0, fmt.Errorf("invalid GraphQL ID %q", gid)
+	}
+	return id, nil
+}
+
+// mutationErrors turns the errors field of a mutation payload into an
+// error
+func mutationErrors(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &GraphQLError{Messages: errs}
+}
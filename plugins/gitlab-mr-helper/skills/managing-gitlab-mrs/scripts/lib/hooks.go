@@ -0,0 +1,217 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HookMerge is the action fired after an MR is merged
+const HookMerge = "merge"
+
+// ActionHook is an HTTP call fired after a helper action, to keep external
+// systems in sync, e.g. a JIRA transition:
+//
+//	"hooks": {"merge": [{
+//	  "url": "https://jira.example.com/rest/api/2/issue/{ticket}/transitions",
+//	  "headers": {"Authorization": "Bearer $JIRA_TOKEN"},
+//	  "env": ["JIRA_TOKEN"],
+//	  "body": "{\"transition\": {\"id\": \"31\"}}"
+//	}]}
+//
+// URL and Body take the placeholders {action}, {project}, {iid}, {title},
+// {source_branch}, {target_branch}, {author}, {url}, {sha} and {ticket},
+// escaped for a URL path and a JSON string respectively. A hook using
+// {ticket} fires once per ticket the MR references, and not at all when it
+// references none.
+type ActionHook struct {
+	Name   string `json:"name"`
+	Method string `json:"method"` // default POST
+	URL    string `json:"url"`
+	// Headers values have $VAR and ${VAR} expanded from the environment,
+	// keeping secrets out of the settings file. Only variables listed in Env
+	// are expanded; a header using any other is an error.
+	Headers map[string]string `json:"headers"`
+	Env     []string          `json:"env"`
+	// Body defaults to the HookEvent as JSON
+	Body string `json:"body"`
+}
+
+// HookEvent describes the action a hook fires for
+type HookEvent struct {
+	Action       string `json:"action"`
+	Project      string `json:"project"`
+	IID          int    `json:"iid"`
+	Title        string `json:"title"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+	Author       string `json:"author"`
+	URL          string `json:"url"`
+	// SHA is the merge (or squash) commit, or the MR HEAD when there is none
+	SHA    string `json:"sha"`
+	Ticket string `json:"ticket,omitempty"`
+}
+
+// Hooks fires the action hooks configured in settings
+type Hooks struct {
+	hooks      map[string][]ActionHook
+	tracker    TrackerSettings
+	httpClient *http.Client
+}
+
+// NewHooks returns the hooks configured in s, or nil when there are none
+func NewHooks(s *Settings) *Hooks {
+	if len(s.Hooks) == 0 {
+		return nil
+	}
+	return &Hooks{hooks: s.Hooks, tracker: s.Tracker, httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Fire runs the hooks of action for an MR and returns how many calls
+// succeeded. Tickets are found in the source branch, title and description
+// with the tracker's ticket pattern. Every hook runs even when others fail.
+func (h *Hooks) Fire(action, projectPath string, mr *MergeRequest) (int, error) {
+	hooks := h.hooks[action]
+	if len(hooks) == 0 {
+		return 0, nil
+	}
+	ev := HookEvent{
+		Action:       action,
+		Project:      projectPath,
+		IID:          mr.IID,
+		Title:        mr.Title,
+		SourceBranch: mr.SourceBranch,
+		TargetBranch: mr.TargetBranch,
+		Author:       mr.Author.Username,
+		URL:          mr.WebURL,
+		SHA:          mr.SHA,
+	}
+	if mr.SquashCommitSHA != "" {
+		ev.SHA = mr.SquashCommitSHA
+	}
+	if mr.MergeCommitSHA != "" {
+		ev.SHA = mr.MergeCommitSHA
+	}
+	tickets, err := FindTickets(&h.tracker, mr.SourceBranch, []Commit{{Message: mr.Title + "\n" + mr.Description}})
+	if err != nil {
+		return 0, err
+	}
+
+	fired := 0
+	var errs []error
+	for i, hook := range hooks {
+		name := hook.Name
+		if name == "" {
+			name = fmt.Sprintf("%s hook %d", action, i+1)
+		}
+		events := []HookEvent{ev}
+		if strings.Contains(hook.URL, "{ticket}") || strings.Contains(hook.Body, "{ticket}") {
+			events = events[:0]
+			for _, t := range tickets {
+				e := ev
+				e.Ticket = t
+				events = append(events, e)
+			}
+		}
+		for _, e := range events {
+			if err := h.call(&hook, &e); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				continue
+			}
+			fired++
+		}
+	}
+	return fired, errors.Join(errs...)
+}
+
+// call sends one hook request for ev
+func (h *Hooks) call(hook *ActionHook, ev *HookEvent) error {
+	if hook.URL == "" {
+		return errors.New("url is required")
+	}
+	method := strings.ToUpper(hook.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	var body []byte
+	if hook.Body == "" {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+		body = data
+	} else {
+		body = []byte(ev.expand(hook.Body, jsonEscape))
+	}
+
+	req, err := http.NewRequest(method, ev.expand(hook.URL, url.PathEscape), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid hook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range hook.Headers {
+		value, err := expandAllowed(v, hook.Env)
+		if err != nil {
+			return fmt.Errorf("header %s: %w", k, err)
+		}
+		req.Header.Set(k, value)
+	}
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s returned %d: %s", method, req.URL.Redacted(), resp.StatusCode, bytes.TrimSpace(data))
+	}
+	return nil
+}
+
+// expandAllowed expands the environment variables of s, which must all be
+// listed in allowed
+func expandAllowed(s string, allowed []string) (string, error) {
+	var denied []string
+	value := os.Expand(s, func(name string) string {
+		if !ContainsString(allowed, name) {
+			denied = append(denied, name)
+			return ""
+		}
+		return os.Getenv(name)
+	})
+	if len(denied) > 0 {
+		return "", fmt.Errorf("$%s is not listed in env", strings.Join(denied, ", $"))
+	}
+	return value, nil
+}
+
+// expand replaces the placeholders of s with the escaped event fields
+func (ev *HookEvent) expand(s string, escape func(string) string) string {
+	return strings.NewReplacer(
+		"{action}", escape(ev.Action),
+		"{project}", escape(ev.Project),
+		"{iid}", strconv.Itoa(ev.IID),
+		"{title}", escape(ev.Title),
+		"{source_branch}", escape(ev.SourceBranch),
+		"{target_branch}", escape(ev.TargetBranch),
+		"{author}", escape(ev.Author),
+		"{url}", escape(ev.URL),
+		"{sha}", escape(ev.SHA),
+		"{ticket}", escape(ev.Ticket),
+	).Replace(s)
+}
+
+// jsonEscape escapes s for use inside a JSON string
+func jsonEscape(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data[1 : len(data)-1])
+}
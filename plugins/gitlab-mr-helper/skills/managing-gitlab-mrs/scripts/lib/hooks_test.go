@@ -0,0 +1,90 @@
+package lib_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestHooksFire(t *testing.T) {
+	if lib.NewHooks(&lib.Settings{}) != nil {
+		t.Fatal("hooks created without any configured")
+	}
+
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, r.Method+" "+r.URL.EscapedPath()+" "+r.Header.Get("Authorization")+" "+string(body))
+		if strings.Contains(r.URL.Path, "FAIL") {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv("JIRA_TOKEN", "s3cret")
+	hooks := lib.NewHooks(&lib.Settings{Hooks: map[string][]lib.ActionHook{
+		lib.HookMerge: {
+			{Name: "jira", URL: srv.URL + "/issue/{ticket}/transitions", Headers: map[string]string{"Authorization": "Bearer $JIRA_TOKEN"}, Env: []string{"JIRA_TOKEN"}, Body: `{"comment": "{title} merged: {url}"}`},
+			{Method: "put", URL: srv.URL + "/builds/{project}/{iid}"},
+		},
+	}})
+	mr := &lib.MergeRequest{
+		IID:            7,
+		Title:          `Add "login"`,
+		Description:    "Refs: https://jira.example.com/browse/WEB-9",
+		SourceBranch:   "feature/ABC-12-login",
+		WebURL:         "https://gitlab.example.com/group/project/-/merge_requests/7",
+		SHA:            "head",
+		MergeCommitSHA: "m3rge",
+		Author:         lib.User{Username: "bob"},
+	}
+
+	fired, err := hooks.Fire(lib.HookMerge, "group/project", mr)
+	if err != nil || fired != 3 {
+		t.Fatalf("Fire = %d, %v", fired, err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("requests = %q", got)
+	}
+	comment := `{"comment": "Add \"login\" merged: https://gitlab.example.com/group/project/-/merge_requests/7"}`
+	want := []string{
+		"POST /issue/ABC-12/transitions Bearer s3cret " + comment,
+		"POST /issue/WEB-9/transitions Bearer s3cret " + comment,
+	}
+	if !reflect.DeepEqual(got[:2], want) {
+		t.Errorf("ticket requests = %q, want %q", got[:2], want)
+	}
+	body, ok := strings.CutPrefix(got[2], "PUT /builds/group%2Fproject/7  ")
+	var ev lib.HookEvent
+	if err := json.Unmarshal([]byte(body), &ev); !ok || err != nil || ev.SHA != "m3rge" || ev.Author != "bob" || ev.Action != "merge" {
+		t.Errorf("default body request = %q (%v)", got[2], err)
+	}
+
+	// Failures are reported after every hook ran; other actions fire nothing
+	got = nil
+	mr.SourceBranch, mr.Description = "FAIL-1", ""
+	fired, err = hooks.Fire(lib.HookMerge, "group/project", mr)
+	if fired != 1 || err == nil || !strings.Contains(err.Error(), "jira: POST") || !strings.Contains(err.Error(), "returned 400") {
+		t.Errorf("Fire = %d, %v; want 1 fired and the jira failure", fired, err)
+	}
+	if fired, err := hooks.Fire("revert", "group/project", mr); fired != 0 || err != nil {
+		t.Errorf("Fire(revert) = %d, %v", fired, err)
+	}
+
+	// Headers only expand the variables listed in env
+	got = nil
+	t.Setenv("GITLAB_TOKEN", "glpat-secret")
+	leaky := lib.NewHooks(&lib.Settings{Hooks: map[string][]lib.ActionHook{
+		lib.HookMerge: {{URL: srv.URL + "/leak", Headers: map[string]string{"Authorization": "$JIRA_TOKEN ${GITLAB_TOKEN}"}, Env: []string{"JIRA_TOKEN"}}},
+	}})
+	fired, err = leaky.Fire(lib.HookMerge, "group/project", mr)
+	if fired != 0 || err == nil || !strings.Contains(err.Error(), "$GITLAB_TOKEN is not listed in env") || len(got) != 0 {
+		t.Errorf("Fire with an unlisted variable = %d, %v; requests %q", fired, err, got)
+	}
+}
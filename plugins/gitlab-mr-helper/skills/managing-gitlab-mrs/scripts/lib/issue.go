@@ -0,0 +1,159 @@
+package lib
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Issue represents a GitLab issue
+type Issue struct {
+	ID          int        `json:"id"`
+	IID         int        `json:"iid"`
+	ProjectID   int        `json:"project_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	State       string     `json:"state"`
+	Labels      []string   `json:"labels"`
+	Author      User       `json:"author"`
+	Assignees   []User     `json:"assignees"`
+	Milestone   *Milestone `json:"milestone"`
+	WebURL      string     `json:"web_url"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ClosedAt    *time.Time `json:"closed_at"`
+	// Confidential issues are only visible to their author, their
+	// assignees and project members with at least the Planner role
+	Confidential bool `json:"confidential"`
+	// DiscussionLocked limits comments to project members
+	DiscussionLocked bool `json:"discussion_locked"`
+	// ServiceDeskReplyTo is the email address of the external author of a
+	// Service Desk issue
+	ServiceDeskReplyTo string `json:"service_desk_reply_to,omitempty"`
+	UserNotesCount     int    `json:"user_notes_count"`
+}
+
+// Milestone is a project milestone
+type Milestone struct {
+	ID      int    `json:"id"`
+	IID     int    `json:"iid"`
+	Title   string `json:"title"`
+	State   string `json:"state"` // active, closed
+	DueDate Date   `json:"due_date"`
+	WebURL  string `json:"web_url"`
+}
+
+// CreateIssueRequest represents the request body for creating an issue
+type CreateIssueRequest struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	AssigneeIDs []int    `json:"assignee_ids,omitempty"`
+}
+
+// CreateIssue creates an issue
+func (c *Client) CreateIssue(projectPath string, req *CreateIssueRequest) (*Issue, error) {
+	endpoint := c.apiURL("/projects/%s/issues", url.PathEscape(projectPath))
+
+	var issue Issue
+	if err := c.do("POST", endpoint, req, &issue, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// UpdateIssueRequest represents the request body for updating an issue.
+// Description, Labels, AssigneeIDs and the flags are pointers so they can
+// be cleared.
+type UpdateIssueRequest struct {
+	Title            string    `json:"title,omitempty"`
+	Description      *string   `json:"description,omitempty"`
+	Labels           *[]string `json:"labels,omitempty"`
+	AssigneeIDs      *[]int    `json:"assignee_ids,omitempty"`
+	StateEvent       string    `json:"state_event,omitempty"` // close, reopen
+	Confidential     *bool     `json:"confidential,omitempty"`
+	DiscussionLocked *bool     `json:"discussion_locked,omitempty"`
+}
+
+// GetIssue gets a single issue by IID
+func (c *Client) GetIssue(projectPath string, issueIID int) (*Issue, error) {
+	endpoint := c.apiURL("/projects/%s/issues/%d", url.PathEscape(projectPath), issueIID)
+
+	var issue Issue
+	if err := c.do("GET", endpoint, nil, &issue, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// UpdateIssue updates an issue
+func (c *Client) UpdateIssue(projectPath string, issueIID int, req *UpdateIssueRequest) (*Issue, error) {
+	endpoint := c.apiURL("/projects/%s/issues/%d", url.PathEscape(projectPath), issueIID)
+
+	var issue Issue
+	if err := c.do("PUT", endpoint, req, &issue, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// IssueListOptions holds filters for issue listings
+type IssueListOptions struct {
+	State     string   // opened, closed, all
+	Labels    []string // issues must have all of them
+	Milestone string   // milestone title
+	// AuthorUsername keeps the issues opened by this user
+	AuthorUsername string
+	Limit          int // 0 means no limit
+}
+
+func (o *IssueListOptions) query() url.Values {
+	q := url.Values{}
+	if o.State != "" {
+		q.Set("state", o.State)
+	}
+	if o.Milestone != "" {
+		q.Set("milestone", o.Milestone)
+	}
+	if o.AuthorUsername != "" {
+		q.Set("author_username", o.AuthorUsername)
+	}
+	if len(o.Labels) > 0 {
+		q.Set("labels", strings.Join(o.Labels, ","))
+	}
+	return q
+}
+
+// ListProjectIssues lists a project's issues, newest first
+func (c *Client) ListProjectIssues(projectPath string, opts *IssueListOptions) ([]Issue, error) {
+	endpoint := c.apiURL("/projects/%s/issues", url.PathEscape(projectPath))
+	return getAll[Issue](c, endpoint, opts.query(), opts.Limit)
+}
+
+// CreateIssueNote adds a comment to an issue
+func (c *Client) CreateIssueNote(projectPath string, issueIID int, body string) (*Note, error) {
+	endpoint := c.apiURL("/projects/%s/issues/%d/notes", url.PathEscape(projectPath), issueIID)
+
+	var note Note
+	if err := c.do("POST", endpoint, map[string]string{"body": body}, &note, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// GetMilestone finds a project milestone by title
+func (c *Client) GetMilestone(projectPath, title string) (*Milestone, error) {
+	endpoint := c.apiURL("/projects/%s/milestones", url.PathEscape(projectPath))
+	query := url.Values{}
+	query.Set("title", title)
+	milestones, err := getAll[Milestone](c, endpoint, query, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(milestones) == 0 {
+		return nil, fmt.Errorf("%w: milestone %q", ErrNotFound, title)
+	}
+	return &milestones[0], nil
+}
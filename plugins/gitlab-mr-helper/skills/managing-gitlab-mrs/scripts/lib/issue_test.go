@@ -0,0 +1,135 @@
+package lib_test
+
+import (
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestCreateIssue(t *testing.T) {
+	tests := []struct {
+		name     string
+		project  string
+		req      lib.CreateIssueRequest
+		wantIID  int
+		wantExit int
+	}{
+		{name: "created", project: gitlabtest.ProjectPath, req: lib.CreateIssueRequest{Title: "Release 1.2", Labels: []string{"release"}}, wantIID: 1},
+		{name: "missing title", project: gitlabtest.ProjectPath, wantExit: lib.ExitError},
+		{name: "unknown project", project: "nope/nope", req: lib.CreateIssueRequest{Title: "x"}, wantExit: lib.ExitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			issue, err := srv.Client().CreateIssue(tt.project, &tt.req)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateIssue: %v", err)
+			}
+			if issue.IID != tt.wantIID || issue.State != "opened" || issue.WebURL == "" {
+				t.Errorf("issue = %+v", issue)
+			}
+		})
+	}
+}
+
+func TestListAndUpdateIssues(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	for _, req := range []lib.CreateIssueRequest{
+		{Title: "Crash on start", Labels: []string{"bug"}, AssigneeIDs: []int{gitlabtest.Bob.ID}},
+		{Title: "Dark mode", Labels: []string{"feature", "ui"}},
+	} {
+		if _, err := client.CreateIssue(gitlabtest.ProjectPath, &req); err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
+	}
+
+	issues, err := client.ListProjectIssues(gitlabtest.ProjectPath, &lib.IssueListOptions{})
+	if err != nil {
+		t.Fatalf("ListProjectIssues: %v", err)
+	}
+	if len(issues) != 2 || issues[0].IID != 2 || len(issues[1].Assignees) != 1 || issues[1].Assignees[0].Username != "bob" {
+		t.Fatalf("issues = %+v", issues)
+	}
+	if issues, err = client.ListProjectIssues(gitlabtest.ProjectPath, &lib.IssueListOptions{Labels: []string{"ui", "feature"}}); err != nil || len(issues) != 1 || issues[0].IID != 2 {
+		t.Errorf("feature and ui issues = %+v, %v", issues, err)
+	}
+
+	empty, none := "", []int{}
+	issue, err := client.UpdateIssue(gitlabtest.ProjectPath, 1, &lib.UpdateIssueRequest{Description: &empty, AssigneeIDs: &none, StateEvent: "close"})
+	if err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	if issue.State != "closed" || len(issue.Assignees) != 0 || issue.Title != "Crash on start" {
+		t.Errorf("updated issue = %+v", issue)
+	}
+	if issues, err = client.ListProjectIssues(gitlabtest.ProjectPath, &lib.IssueListOptions{State: "opened"}); err != nil || len(issues) != 1 {
+		t.Errorf("open issues = %+v, %v", issues, err)
+	}
+
+	_, err = client.UpdateIssue(gitlabtest.ProjectPath, 9, &lib.UpdateIssueRequest{Title: "x"})
+	wantExit(t, err, lib.ExitNotFound)
+}
+
+func TestIssueConfidentialityAndLock(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+	if _, err := client.CreateIssue(gitlabtest.ProjectPath, &lib.CreateIssueRequest{Title: "XSS in login"}); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+
+	yes := true
+	if _, err := client.UpdateIssue(gitlabtest.ProjectPath, 1, &lib.UpdateIssueRequest{Confidential: &yes, DiscussionLocked: &yes}); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	issue, err := client.GetIssue(gitlabtest.ProjectPath, 1)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if !issue.Confidential || !issue.DiscussionLocked || issue.Title != "XSS in login" {
+		t.Errorf("issue = %+v, want confidential and locked", issue)
+	}
+
+	// Leaving a flag out keeps it; false clears it
+	no := false
+	if issue, err = client.UpdateIssue(gitlabtest.ProjectPath, 1, &lib.UpdateIssueRequest{Confidential: &no}); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	if issue.Confidential || !issue.DiscussionLocked {
+		t.Errorf("issue = %+v, want public and still locked", issue)
+	}
+
+	mr, err := client.UpdateMR(gitlabtest.ProjectPath, 1, &lib.UpdateMRRequest{DiscussionLocked: &yes})
+	if err != nil || !mr.DiscussionLocked {
+		t.Fatalf("locking MR !1 = %+v, %v", mr, err)
+	}
+	if mr, err = client.UpdateMR(gitlabtest.ProjectPath, 1, &lib.UpdateMRRequest{DiscussionLocked: &no}); err != nil || mr.DiscussionLocked {
+		t.Errorf("unlocking MR !1 = %+v, %v", mr, err)
+	}
+
+	_, err = client.GetIssue(gitlabtest.ProjectPath, 9)
+	wantExit(t, err, lib.ExitNotFound)
+}
+
+func TestGetMilestone(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	m, err := client.GetMilestone(gitlabtest.ProjectPath, "v1.0")
+	if err != nil {
+		t.Fatalf("GetMilestone: %v", err)
+	}
+	if m.ID != 60 || m.State != "active" || m.DueDate.IsZero() {
+		t.Errorf("milestone = %+v", m)
+	}
+
+	_, err = client.GetMilestone(gitlabtest.ProjectPath, "v9.9")
+	wantExit(t, err, lib.ExitNotFound)
+}
@@ -0,0 +1,232 @@
+package lib
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Issue file formats read and written by ReadIssueRecords and
+// WriteIssueRecords
+const (
+	IssueFormatCSV  = "csv"
+	IssueFormatJSON = "json"
+)
+
+// IssueRecord is an issue as export_issues.go writes it and
+// import_issues.go reads it back. Labels and assignees are joined with
+// commas in CSV, which neither may contain.
+type IssueRecord struct {
+	IID         int      `json:"iid,omitempty"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Labels      []string `json:"labels"`
+	Assignees   []string `json:"assignees"` // usernames
+	State       string   `json:"state"`     // opened or closed
+	WebURL      string   `json:"web_url,omitempty"`
+}
+
+// IssueRecordHeader is the CSV column order of issue records
+var IssueRecordHeader = []string{"iid", "title", "description", "labels", "assignees", "state", "web_url"}
+
+// NewIssueRecord converts an issue to a record
+func NewIssueRecord(issue *Issue) IssueRecord {
+	rec := IssueRecord{IID: issue.IID, Title: issue.Title, Description: issue.Description,
+		Labels: issue.Labels, Assignees: []string{}, State: issue.State, WebURL: issue.WebURL}
+	if rec.Labels == nil {
+		rec.Labels = []string{}
+	}
+	for _, u := range issue.Assignees {
+		rec.Assignees = append(rec.Assignees, u.Username)
+	}
+	return rec
+}
+
+// WriteIssueRecords writes records as CSV with IssueRecordHeader or as an
+// indented JSON array
+func WriteIssueRecords(w io.Writer, format string, records []IssueRecord) error {
+	if format == IssueFormatJSON {
+		if records == nil {
+			records = []IssueRecord{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+	rows := make([][]string, 0, len(records))
+	for _, r := range records {
+		iid := ""
+		if r.IID > 0 {
+			iid = strconv.Itoa(r.IID)
+		}
+		rows = append(rows, []string{iid, r.Title, r.Description, strings.Join(r.Labels, ","), strings.Join(r.Assignees, ","), r.State, r.WebURL})
+	}
+	return WriteTable(w, OutputCSV, IssueRecordHeader, rows)
+}
+
+// ReadIssueRecords reads records written by WriteIssueRecords, or edited
+// or produced elsewhere: CSV columns are matched by name in any order and
+// case, only title is required, and unknown columns are ignored. States
+// are normalized to opened or closed, and default to opened.
+func ReadIssueRecords(r io.Reader, format string) ([]IssueRecord, error) {
+	var records []IssueRecord
+	if format == IssueFormatJSON {
+		if err := json.NewDecoder(r).Decode(&records); err != nil {
+			return nil, UsageErrorf("invalid JSON issue file: %v", err)
+		}
+	} else {
+		var err error
+		if records, err = readIssueCSV(r); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range records {
+		rec := &records[i]
+		line := i + 1
+		rec.Title = strings.TrimSpace(rec.Title)
+		if rec.Title == "" {
+			return nil, UsageErrorf("issue %d: title is required", line)
+		}
+		switch strings.ToLower(strings.TrimSpace(rec.State)) {
+		case "", "open", "opened":
+			rec.State = "opened"
+		case "close", "closed":
+			rec.State = "closed"
+		default:
+			return nil, UsageErrorf("issue %d: invalid state %q (expected opened or closed)", line, rec.State)
+		}
+		for j, a := range rec.Assignees {
+			rec.Assignees[j] = strings.TrimPrefix(a, "@")
+		}
+	}
+	return records, nil
+}
+
+func readIssueCSV(r io.Reader) ([]IssueRecord, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, UsageErrorf("invalid CSV issue file: %v", err)
+	}
+	col := make(map[string]int)
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := col["title"]; !ok {
+		return nil, UsageErrorf("CSV issue file has no title column (columns: %s)", strings.Join(IssueRecordHeader, ", "))
+	}
+	field := func(row []string, name string) string {
+		if i, ok := col[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	var records []IssueRecord
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, UsageErrorf("invalid CSV issue file: %v", err)
+		}
+		rec := IssueRecord{Title: field(row, "title"), Description: field(row, "description"), State: field(row, "state"),
+			Labels: SplitList(field(row, "labels")), Assignees: SplitList(field(row, "assignees")), WebURL: field(row, "web_url")}
+		if iid := strings.TrimSpace(field(row, "iid")); iid != "" {
+			if rec.IID, err = strconv.Atoi(iid); err != nil || rec.IID <= 0 {
+				return nil, UsageErrorf("issue %d: invalid iid %q", len(records)+1, iid)
+			}
+		}
+		records = append(records, rec)
+	}
+}
+
+// CreateRequest returns the request creating rec; closing it takes an
+// update afterwards. ids maps usernames to user IDs, and assignees missing
+// from it are left out.
+func (rec *IssueRecord) CreateRequest(ids map[string]int) *CreateIssueRequest {
+	req := &CreateIssueRequest{Title: rec.Title, Description: rec.Description, Labels: rec.Labels}
+	for _, a := range rec.Assignees {
+		if id, ok := ids[a]; ok {
+			req.AssigneeIDs = append(req.AssigneeIDs, id)
+		}
+	}
+	return req
+}
+
+// UpdateRequest returns the request turning issue into rec and the names
+// of the fields it changes, or nil when they already match. Like
+// CreateRequest, assignees missing from ids are left out.
+func (rec *IssueRecord) UpdateRequest(issue *Issue, ids map[string]int) (*UpdateIssueRequest, []string) {
+	req := &UpdateIssueRequest{}
+	var changed []string
+	if rec.Title != issue.Title {
+		req.Title = rec.Title
+		changed = append(changed, "title")
+	}
+	if rec.Description != issue.Description {
+		req.Description = &rec.Description
+		changed = append(changed, "description")
+	}
+	if !sameSet(rec.Labels, issue.Labels) {
+		labels := append([]string{}, rec.Labels...)
+		req.Labels = &labels
+		changed = append(changed, "labels")
+	}
+	assignees, known := []int{}, []string{}
+	for _, a := range rec.Assignees {
+		if id, ok := ids[a]; ok {
+			assignees = append(assignees, id)
+			known = append(known, a)
+		}
+	}
+	var current []string
+	for _, u := range issue.Assignees {
+		current = append(current, u.Username)
+	}
+	if !sameSet(known, current) {
+		req.AssigneeIDs = &assignees
+		changed = append(changed, "assignees")
+	}
+	if rec.State != issue.State {
+		req.StateEvent = "reopen"
+		if rec.State == "closed" {
+			req.StateEvent = "close"
+		}
+		changed = append(changed, "state")
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	return req, changed
+}
+
+// sameSet reports whether a and b hold the same strings, in any order
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// IssueFormatFromPath guesses an issue file format from its extension,
+// defaulting to CSV
+func IssueFormatFromPath(path string) string {
+	if strings.EqualFold(filepath.Ext(path), "."+IssueFormatJSON) {
+		return IssueFormatJSON
+	}
+	return IssueFormatCSV
+}
@@ -0,0 +1,92 @@
+package lib_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestIssueRecordsRoundTrip(t *testing.T) {
+	records := []lib.IssueRecord{
+		{IID: 1, Title: "Crash on start", Description: "Steps:\n1. open, \"quoted\"", Labels: []string{"bug", "p1"}, Assignees: []string{"bob"}, State: "closed"},
+		{IID: 2, Title: "Dark mode", Description: "", Labels: []string{}, Assignees: []string{}, State: "opened"},
+	}
+	for _, format := range []string{lib.IssueFormatCSV, lib.IssueFormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := lib.WriteIssueRecords(&buf, format, records); err != nil {
+				t.Fatalf("WriteIssueRecords: %v", err)
+			}
+			got, err := lib.ReadIssueRecords(&buf, format)
+			if err != nil {
+				t.Fatalf("ReadIssueRecords: %v", err)
+			}
+			if !reflect.DeepEqual(got, records) {
+				t.Errorf("round trip = %+v, want %+v", got, records)
+			}
+		})
+	}
+}
+
+func TestReadIssueRecords(t *testing.T) {
+	// Columns in any order and case, unknown ones ignored, e.g. from
+	// another tracker
+	in := "Summary,State,Title,Assignees\nignored,Open,First,@alice\nignored,closed,Second,\n"
+	records, err := lib.ReadIssueRecords(strings.NewReader(in), lib.IssueFormatCSV)
+	if err != nil {
+		t.Fatalf("ReadIssueRecords: %v", err)
+	}
+	if len(records) != 2 || records[0].Title != "First" || records[0].State != "opened" || records[0].Assignees[0] != "alice" || records[1].State != "closed" {
+		t.Errorf("records = %+v", records)
+	}
+
+	for name, in := range map[string]string{
+		"no title column": "name,state\nx,opened\n",
+		"empty title":     "title\n  \n",
+		"bad state":       "title,state\nx,wontfix\n",
+		"bad iid":         "iid,title\nx,y\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := lib.ReadIssueRecords(strings.NewReader(in), lib.IssueFormatCSV)
+			wantExit(t, err, lib.ExitUsage)
+		})
+	}
+	_, err = lib.ReadIssueRecords(strings.NewReader(`{"title": "x"}`), lib.IssueFormatJSON)
+	wantExit(t, err, lib.ExitUsage)
+}
+
+func TestIssueRecordUpdateRequest(t *testing.T) {
+	ids := map[string]int{"alice": 1, "bob": 2}
+	issue := &lib.Issue{IID: 3, Title: "Crash", Description: "d", Labels: []string{"bug", "p1"}, State: "opened",
+		Assignees: []lib.User{{ID: 2, Username: "bob"}}}
+
+	same := lib.IssueRecord{Title: "Crash", Description: "d", Labels: []string{"p1", "bug"}, Assignees: []string{"bob", "mallory"}, State: "opened"}
+	if req, changed := same.UpdateRequest(issue, ids); req != nil {
+		t.Errorf("unchanged record gave %+v (%v)", req, changed)
+	}
+
+	rec := lib.IssueRecord{Title: "Crash", Description: "", Labels: []string{"bug"}, Assignees: []string{"alice"}, State: "closed"}
+	req, changed := rec.UpdateRequest(issue, ids)
+	if want := []string{"description", "labels", "assignees", "state"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	if req.Title != "" || *req.Description != "" || !reflect.DeepEqual(*req.Labels, []string{"bug"}) || !reflect.DeepEqual(*req.AssigneeIDs, []int{1}) || req.StateEvent != "close" {
+		t.Errorf("request = %+v", req)
+	}
+
+	create := rec.CreateRequest(map[string]int{"bob": 2})
+	if create.Title != "Crash" || len(create.AssigneeIDs) != 0 {
+		t.Errorf("create request = %+v", create)
+	}
+}
+
+func TestIssueFormatFromPath(t *testing.T) {
+	for path, want := range map[string]string{"issues.json": "json", "ISSUES.JSON": "json", "issues.csv": "csv", "-": "csv", "json": "csv"} {
+		if got := lib.IssueFormatFromPath(path); got != want {
+			t.Errorf("IssueFormatFromPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package lib
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ClusterAgent is a GitLab agent for Kubernetes registered in a project.
+// Its configuration lives in .gitlab/agents/<name>/config.yaml of
+// ConfigProject.
+type ClusterAgent struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	ConfigProject struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"config_project"`
+	CreatedAt       time.Time `json:"created_at"`
+	CreatedByUserID int       `json:"created_by_user_id"`
+}
+
+// AgentToken is a token an agent (agentk) authenticates with. Revoked
+// tokens are not listed.
+type AgentToken struct {
+	ID          int        `json:"id"`
+	AgentID     int        `json:"agent_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"` // active, revoked
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+}
+
+// AgentConnectedWithin is how recently an agent must have used one of its
+// tokens to count as connected; the GitLab UI uses the same window
+const AgentConnectedWithin = 8 * time.Minute
+
+// Agent connection states
+const (
+	AgentConnected      = "connected"
+	AgentNotConnected   = "not connected"
+	AgentNeverConnected = "never connected"
+)
+
+// AgentConnection tells whether an agent is connected from the last use of
+// its tokens, and returns that last use
+func AgentConnection(tokens []AgentToken, now time.Time) (string, *time.Time) {
+	var last *time.Time
+	for _, t := range tokens {
+		if t.LastUsedAt != nil && (last == nil || t.LastUsedAt.After(*last)) {
+			last = t.LastUsedAt
+		}
+	}
+	switch {
+	case last == nil:
+		return AgentNeverConnected, nil
+	case now.Sub(*last) <= AgentConnectedWithin:
+		return AgentConnected, last
+	}
+	return AgentNotConnected, last
+}
+
+// ListClusterAgents lists the agents for Kubernetes registered in a project
+func (c *Client) ListClusterAgents(projectPath string) ([]ClusterAgent, error) {
+	endpoint := c.apiURL("/projects/%s/cluster_agents", url.PathEscape(projectPath))
+	return getAll[ClusterAgent](c, endpoint, nil, 0)
+}
+
+// ListAgentTokens lists the active tokens of an agent
+func (c *Client) ListAgentTokens(projectPath string, agentID int) ([]AgentToken, error) {
+	endpoint := c.apiURL("/projects/%s/cluster_agents/%d/tokens", url.PathEscape(projectPath), agentID)
+	return getAll[AgentToken](c, endpoint, nil, 0)
+}
+
+// Cluster is a certificate-based Kubernetes cluster integration, the
+// deprecated predecessor of agents
+type Cluster struct {
+	ID                 int    `json:"id"`
+	Name               string `json:"name"`
+	Enabled            bool   `json:"enabled"`
+	Managed            bool   `json:"managed"`
+	EnvironmentScope   string `json:"environment_scope"`
+	PlatformKubernetes struct {
+		APIURL string `json:"api_url"`
+	} `json:"platform_kubernetes"`
+}
+
+// ListClusters lists the certificate-based clusters of a project. It
+// returns none where GitLab no longer serves the integration.
+func (c *Client) ListClusters(projectPath string) ([]Cluster, error) {
+	endpoint := c.apiURL("/projects/%s/clusters", url.PathEscape(projectPath))
+	clusters, err := getAll[Cluster](c, endpoint, nil, 0)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	return clusters, err
+}
@@ -0,0 +1,67 @@
+package lib_test
+
+import (
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestListClusterAgents(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	agents, err := client.ListClusterAgents(gitlabtest.ProjectPath)
+	if err != nil {
+		t.Fatalf("ListClusterAgents: %v", err)
+	}
+	if len(agents) != 3 || agents[0].Name != "production" || agents[0].ConfigProject.PathWithNamespace != gitlabtest.ProjectPath {
+		t.Fatalf("ListClusterAgents = %+v", agents)
+	}
+
+	now := time.Now()
+	for i, want := range []string{lib.AgentConnected, lib.AgentNotConnected, lib.AgentNeverConnected} {
+		tokens, err := client.ListAgentTokens(gitlabtest.ProjectPath, agents[i].ID)
+		if err != nil {
+			t.Fatalf("ListAgentTokens(%s): %v", agents[i].Name, err)
+		}
+		if got, _ := lib.AgentConnection(tokens, now); got != want {
+			t.Errorf("%s is %s, want %s", agents[i].Name, got, want)
+		}
+	}
+	_, err = client.ListAgentTokens(gitlabtest.ProjectPath, 99)
+	wantExit(t, err, lib.ExitNotFound)
+
+	clusters, err := client.ListClusters(gitlabtest.ProjectPath)
+	if err != nil || len(clusters) != 1 || clusters[0].PlatformKubernetes.APIURL == "" {
+		t.Errorf("ListClusters = %+v, %v", clusters, err)
+	}
+}
+
+func TestAgentConnection(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(ago time.Duration) *time.Time {
+		t := now.Add(-ago)
+		return &t
+	}
+	tests := []struct {
+		name     string
+		tokens   []lib.AgentToken
+		want     string
+		wantLast *time.Time
+	}{
+		{name: "no tokens", want: lib.AgentNeverConnected},
+		{name: "unused token", tokens: []lib.AgentToken{{}}, want: lib.AgentNeverConnected},
+		{name: "recent use", tokens: []lib.AgentToken{{LastUsedAt: at(time.Hour)}, {LastUsedAt: at(5 * time.Minute)}}, want: lib.AgentConnected, wantLast: at(5 * time.Minute)},
+		{name: "old use", tokens: []lib.AgentToken{{LastUsedAt: at(9 * time.Minute)}}, want: lib.AgentNotConnected, wantLast: at(9 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, last := lib.AgentConnection(tt.tokens, now)
+			if got != tt.want || (last == nil) != (tt.wantLast == nil) || (last != nil && !last.Equal(*tt.wantLast)) {
+				t.Errorf("AgentConnection = %s, %v; want %s, %v", got, last, tt.want, tt.wantLast)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// LFSPointerVersion is the first line of every Git LFS pointer file
+const LFSPointerVersion = "version https://git-lfs.github.com/spec/v1"
+
+// maxLFSPointerSize is the size limit of pointer files set by the spec
+const maxLFSPointerSize = 1024
+
+// LFSPointer stands in the repository for a file stored in Git LFS
+type LFSPointer struct {
+	OID  string `json:"oid"` // SHA256 of the content (hex)
+	Size int64  `json:"size"`
+}
+
+// ParseLFSPointer parses the content of a pointer file; ok is false for
+// any other content
+func ParseLFSPointer(text string) (p *LFSPointer, ok bool) {
+	if len(text) > maxLFSPointerSize || !strings.HasPrefix(text, LFSPointerVersion+"\n") {
+		return nil, false
+	}
+	p = &LFSPointer{Size: -1}
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n")[1:] {
+		key, value, found := strings.Cut(line, " ")
+		if !found {
+			return nil, false
+		}
+		switch key {
+		case "oid":
+			oid, isSHA := strings.CutPrefix(value, "sha256:")
+			if !isSHA || len(oid) != 64 || strings.Trim(oid, "0123456789abcdef") != "" {
+				return nil, false
+			}
+			p.OID = oid
+		case "size":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n < 0 {
+				return nil, false
+			}
+			p.Size = n
+		}
+	}
+	if p.OID == "" || p.Size < 0 {
+		return nil, false
+	}
+	return p, true
+}
+
+// LFSPointer returns the pointer a file read from the repository holds, nil
+// when it is not a pointer file
+func (f *RepositoryFile) LFSPointer() *LFSPointer {
+	text, err := f.Text()
+	if err != nil {
+		return nil
+	}
+	p, _ := ParseLFSPointer(text)
+	return p
+}
+
+// LFSChange is a change of an MR or commit to a file stored in Git LFS.
+// Old is nil for added files and New for deleted ones.
+type LFSChange struct {
+	Diff
+	Old, New *LFSPointer
+}
+
+// LFSChanges picks the diffs of LFS pointer files and reads the pointers on
+// both sides from them. Pointer files are small, so their diffs hold them
+// whole.
+func LFSChanges(diffs []Diff) []LFSChange {
+	var changes []LFSChange
+	for _, d := range diffs {
+		var old, cur strings.Builder
+		for _, line := range strings.Split(d.Diff, "\n") {
+			if line == "" {
+				continue
+			}
+			switch line[0] {
+			case ' ':
+				old.WriteString(line[1:] + "\n")
+				cur.WriteString(line[1:] + "\n")
+			case '-':
+				old.WriteString(line[1:] + "\n")
+			case '+':
+				cur.WriteString(line[1:] + "\n")
+			}
+		}
+		c := LFSChange{Diff: d}
+		c.Old, _ = ParseLFSPointer(old.String())
+		c.New, _ = ParseLFSPointer(cur.String())
+		if c.Old != nil || c.New != nil {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// LFSObject is an object in the answer of the LFS batch API
+type LFSObject struct {
+	OID     string `json:"oid"`
+	Size    int64  `json:"size"`
+	Actions struct {
+		Download *LFSAction `json:"download"`
+	} `json:"actions"`
+	Error *LFSError `json:"error"`
+}
+
+// LFSError is why the LFS server cannot serve an object
+type LFSError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// LFSAction is where and how to transfer an LFS object
+type LFSAction struct {
+	Href      string            `json:"href"`
+	Header    map[string]string `json:"header"`
+	ExpiresIn int               `json:"expires_in"`
+}
+
+// LFSBatch asks the project's LFS server where to download objects from.
+// Git LFS endpoints take the token over HTTP basic authentication, not as
+// PRIVATE-TOKEN.
+func (c *Client) LFSBatch(projectPath string, pointers []LFSPointer) ([]LFSObject, error) {
+	// The endpoint is on the repository URL, which needs the full path
+	if _, err := strconv.Atoi(projectPath); err == nil {
+		settings, err := c.GetProjectSettings(projectPath)
+		if err != nil {
+			return nil, err
+		}
+		projectPath = settings.PathWithNamespace
+	}
+	endpoint := fmt.Sprintf("%s/%s.git/info/lfs/objects/batch", c.config.URL, projectPath)
+	if c.config.Offline {
+		return nil, fmt.Errorf("%w: POST %s", ErrOffline, strings.TrimPrefix(endpoint, c.config.URL))
+	}
+
+	body, err := json.Marshal(map[string]any{"operation": "download", "transfers": []string{"basic"}, "objects": pointers})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth("oauth2", c.config.Token)
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBytes))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(data)}
+	}
+
+	var batch struct {
+		Objects []LFSObject `json:"objects"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		return nil, fmt.Errorf("failed to decode LFS batch response: %w", err)
+	}
+	return batch.Objects, nil
+}
+
+// SaveLFSObject downloads an object found by LFSBatch to path, checking
+// its content against the OID
+func (c *Client) SaveLFSObject(obj *LFSObject, path string, opts *DownloadOptions) (*Download, error) {
+	if obj.Error != nil {
+		err := fmt.Errorf("LFS object %s: %s (%d)", ShortSHA(obj.OID), obj.Error.Message, obj.Error.Code)
+		if obj.Error.Code == http.StatusNotFound {
+			err = fmt.Errorf("%w: %w", ErrNotFound, err)
+		}
+		return nil, err
+	}
+	action := obj.Actions.Download
+	if action == nil {
+		return nil, fmt.Errorf("%w: no download for LFS object %s", ErrNotFound, ShortSHA(obj.OID))
+	}
+	o := DownloadOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	o.SHA256 = obj.OID
+	// The action's own headers authorize it, and its URL may point to
+	// object storage, which must not see the token
+	o.Header = action.Header
+	if o.Header == nil {
+		o.Header = map[string]string{}
+	}
+	return c.downloadFile(action.Href, path, &o)
+}
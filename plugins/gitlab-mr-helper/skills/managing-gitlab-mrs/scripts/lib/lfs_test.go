@@ -0,0 +1,96 @@
+package lib_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+const testOID = "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+
+func TestParseLFSPointer(t *testing.T) {
+	pointer := lib.LFSPointerVersion + "\noid sha256:" + testOID + "\nsize 12345\n"
+	p, ok := lib.ParseLFSPointer(pointer)
+	if !ok || p.OID != testOID || p.Size != 12345 {
+		t.Errorf("ParseLFSPointer = %+v, %v", p, ok)
+	}
+
+	for name, text := range map[string]string{
+		"plain file":   "hello\n",
+		"no size":      lib.LFSPointerVersion + "\noid sha256:" + testOID + "\n",
+		"short oid":    lib.LFSPointerVersion + "\noid sha256:4d7a21\nsize 1\n",
+		"other hash":   lib.LFSPointerVersion + "\noid sha1:" + testOID + "\nsize 1\n",
+		"bad size":     lib.LFSPointerVersion + "\noid sha256:" + testOID + "\nsize -1\n",
+		"too long":     pointer + strings.Repeat("x-extra data\n", 100),
+		"no separator": lib.LFSPointerVersion + "\noid\nsize 1\n",
+	} {
+		if p, ok := lib.ParseLFSPointer(text); ok {
+			t.Errorf("%s: ParseLFSPointer = %+v, want not a pointer", name, p)
+		}
+	}
+}
+
+func TestLFSChanges(t *testing.T) {
+	oid2 := strings.Repeat("ab", 32)
+	diffs := []lib.Diff{
+		{OldPath: "README.md", NewPath: "README.md", Diff: "@@ -1 +1 @@\n-a\n+b\n"},
+		{OldPath: "logo.png", NewPath: "logo.png", Diff: "@@ -1,3 +1,3 @@\n " + lib.LFSPointerVersion + "\n-oid sha256:" + testOID + "\n-size 10\n+oid sha256:" + oid2 + "\n+size 20\n"},
+		{OldPath: "video.mp4", NewPath: "video.mp4", NewFile: true, Diff: "@@ -0,0 +1,3 @@\n+" + lib.LFSPointerVersion + "\n+oid sha256:" + oid2 + "\n+size 30\n"},
+	}
+	changes := lib.LFSChanges(diffs)
+	if len(changes) != 2 {
+		t.Fatalf("LFSChanges = %+v, want 2 changes", changes)
+	}
+	if c := changes[0]; c.NewPath != "logo.png" || c.Old == nil || c.Old.OID != testOID || c.Old.Size != 10 || c.New == nil || c.New.OID != oid2 || c.New.Size != 20 {
+		t.Errorf("modified pointer = %+v old %+v new %+v", c.Diff, c.Old, c.New)
+	}
+	if c := changes[1]; c.NewPath != "video.mp4" || c.Old != nil || c.New == nil || c.New.Size != 30 {
+		t.Errorf("added pointer = %+v old %+v new %+v", c.Diff, c.Old, c.New)
+	}
+}
+
+func TestSaveLFSObject(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	f, err := client.GetFile(gitlabtest.NestedProjectPath, "docs/diagram.png", "main")
+	if err != nil {
+		t.Fatalf("GetFile: %v", err)
+	}
+	p := f.LFSPointer()
+	if p == nil {
+		t.Fatal("docs/diagram.png is not an LFS pointer")
+	}
+	missing := lib.LFSPointer{OID: testOID, Size: 1}
+	objects, err := client.LFSBatch(gitlabtest.NestedProjectPath, []lib.LFSPointer{*p, missing})
+	if err != nil {
+		t.Fatalf("LFSBatch: %v", err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("LFSBatch = %+v, want 2 objects", objects)
+	}
+
+	path := filepath.Join(t.TempDir(), "diagram.png")
+	d, err := client.SaveLFSObject(&objects[0], path, nil)
+	if err != nil {
+		t.Fatalf("SaveLFSObject: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new diagram, twice as big\n" || !d.Checked || d.Size != p.Size {
+		t.Errorf("downloaded %q, %+v", data, d)
+	}
+	// The object URL gets the action's authorization, never the token
+	for _, r := range srv.Requests() {
+		if strings.Contains(r.URL.Path, "/gitlab-lfs/objects/") && (r.Header.Get("PRIVATE-TOKEN") != "" || r.Header.Get("Authorization") == "") {
+			t.Errorf("object download sent PRIVATE-TOKEN %q, Authorization %q", r.Header.Get("PRIVATE-TOKEN"), r.Header.Get("Authorization"))
+		}
+	}
+
+	if _, err := client.SaveLFSObject(&objects[1], path, nil); !errors.Is(err, lib.ErrNotFound) {
+		t.Errorf("missing object: err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,97 @@
+package lib
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultConventionalTypes are the commit types accepted by default when
+// conventional commits are required
+var DefaultConventionalTypes = []string{
+	"build", "chore", "ci", "docs", "feat", "fix", "perf", "refactor", "revert", "style", "test",
+}
+
+// LintViolation describes a branch name or commit that breaks a lint rule
+type LintViolation struct {
+	Subject string // branch name or short commit SHA
+	Message string
+}
+
+func (v LintViolation) String() string {
+	return fmt.Sprintf("%s: %s", v.Subject, v.Message)
+}
+
+// Enabled reports whether any lint rule is configured
+func (s *LintSettings) Enabled() bool {
+	return s.BranchPattern != "" || s.CommitPattern != "" || s.ConventionalCommits
+}
+
+// LintBranch checks a branch name against the configured pattern
+func LintBranch(s *LintSettings, branch string) ([]LintViolation, error) {
+	if s.BranchPattern == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(s.BranchPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lint.branch_pattern: %w", err)
+	}
+	if !re.MatchString(branch) {
+		return []LintViolation{{Subject: branch, Message: fmt.Sprintf("branch name does not match %s", s.BranchPattern)}}, nil
+	}
+	return nil, nil
+}
+
+// LintCommits checks commit subjects against the configured rules. Merge
+// commits are skipped.
+func LintCommits(s *LintSettings, commits []Commit) ([]LintViolation, error) {
+	var patterns []*regexp.Regexp
+	var descriptions []string
+
+	if s.CommitPattern != "" {
+		re, err := regexp.Compile(s.CommitPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lint.commit_pattern: %w", err)
+		}
+		patterns = append(patterns, re)
+		descriptions = append(descriptions, fmt.Sprintf("does not match %s", s.CommitPattern))
+	}
+	if s.ConventionalCommits {
+		types := s.ConventionalTypes
+		if len(types) == 0 {
+			types = DefaultConventionalTypes
+		}
+		patterns = append(patterns, ConventionalCommitRegexp(types))
+		descriptions = append(descriptions, fmt.Sprintf("is not a conventional commit (%s)", strings.Join(types, "|")))
+	}
+
+	var violations []LintViolation
+	for _, c := range commits {
+		subject := c.Title
+		if subject == "" {
+			subject, _, _ = strings.Cut(c.Message, "\n")
+		}
+		if strings.HasPrefix(subject, "Merge ") {
+			continue
+		}
+		for i, re := range patterns {
+			if !re.MatchString(subject) {
+				violations = append(violations, LintViolation{
+					Subject: c.ShortID,
+					Message: fmt.Sprintf("%q %s", subject, descriptions[i]),
+				})
+			}
+		}
+	}
+	return violations, nil
+}
+
+// ConventionalCommitRegexp matches "type(scope)!: summary" subjects for the
+// given types
+func ConventionalCommitRegexp(types []string) *regexp.Regexp {
+	quoted := make([]string, len(types))
+	for i, t := range types {
+		quoted[i] = regexp.QuoteMeta(t)
+	}
+	return regexp.MustCompile(`^(` + strings.Join(quoted, "|") + `)(\([^)]+\))?!?: \S`)
+}
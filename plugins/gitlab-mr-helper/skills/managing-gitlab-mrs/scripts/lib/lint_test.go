@@ -0,0 +1,101 @@
+package lib_test
+
+import (
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestLintBranch(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		branch  string
+		want    int
+	}{
+		{name: "no rule", branch: "anything", want: 0},
+		{name: "matches", pattern: `^(feature|fix)/[a-z0-9-]+$`, branch: "feature/login-page", want: 0},
+		{name: "wrong prefix", pattern: `^(feature|fix)/[a-z0-9-]+$`, branch: "wip", want: 1},
+		{name: "uppercase", pattern: `^(feature|fix)/[a-z0-9-]+$`, branch: "feature/Login", want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lib.LintBranch(&lib.LintSettings{BranchPattern: tt.pattern}, tt.branch)
+			if err != nil {
+				t.Fatalf("LintBranch: %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("got %d violations (%v), want %d", len(got), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintCommits(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings lib.LintSettings
+		subjects []string
+		want     int
+	}{
+		{
+			name:     "conventional ok",
+			settings: lib.LintSettings{ConventionalCommits: true},
+			subjects: []string{"feat(api): add endpoint", "fix!: drop legacy flag", "docs: typo"},
+			want:     0,
+		},
+		{
+			name:     "conventional violations",
+			settings: lib.LintSettings{ConventionalCommits: true},
+			subjects: []string{"Add endpoint", "feature: nope", "fix:missing space"},
+			want:     3,
+		},
+		{
+			name:     "merge commits skipped",
+			settings: lib.LintSettings{ConventionalCommits: true},
+			subjects: []string{"Merge branch 'main' into feature/x"},
+			want:     0,
+		},
+		{
+			name:     "custom types",
+			settings: lib.LintSettings{ConventionalCommits: true, ConventionalTypes: []string{"change"}},
+			subjects: []string{"change: ok", "feat: not allowed"},
+			want:     1,
+		},
+		{
+			name:     "ticket pattern",
+			settings: lib.LintSettings{CommitPattern: `^[A-Z]+-\d+ `},
+			subjects: []string{"ABC-12 fix it", "fix it"},
+			want:     1,
+		},
+		{
+			name:     "both rules",
+			settings: lib.LintSettings{CommitPattern: `ABC-\d+`, ConventionalCommits: true},
+			subjects: []string{"oops"},
+			want:     2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var commits []lib.Commit
+			for _, s := range tt.subjects {
+				commits = append(commits, lib.Commit{ShortID: "abc1234", Title: s})
+			}
+			got, err := lib.LintCommits(&tt.settings, commits)
+			if err != nil {
+				t.Fatalf("LintCommits: %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("got %d violations (%v), want %d", len(got), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintInvalidPattern(t *testing.T) {
+	if _, err := lib.LintBranch(&lib.LintSettings{BranchPattern: "("}, "x"); err == nil {
+		t.Error("expected an error for an invalid branch pattern")
+	}
+}
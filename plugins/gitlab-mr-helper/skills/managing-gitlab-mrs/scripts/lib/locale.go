@@ -0,0 +1,140 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	_ "time/tzdata" // --tz works without a system zoneinfo database
+)
+
+// DisplaySettings configures how times and sizes are shown in text output.
+// Table formats always use RFC 3339 in UTC and plain byte counts.
+type DisplaySettings struct {
+	// Timezone is an IANA zone name such as "Europe/Berlin", or "UTC"
+	// (default: the system zone; --tz and GITLAB_TZ override it)
+	Timezone string `json:"timezone"`
+	// Locale is one of Locales (default: LC_ALL, LC_TIME or LANG, else
+	// "en"; --locale and GITLAB_LOCALE override it)
+	Locale string `json:"locale"`
+	// ASCII replaces emoji and Unicode symbols with plain ASCII (see
+	// SetASCII; --ascii and GITLAB_ASCII=1 turn it on too)
+	ASCII bool `json:"ascii"`
+}
+
+// Locale holds the conventions FormatAge, FormatDate and FormatSize follow
+type Locale struct {
+	Name string
+	// Months are the abbreviated month names, January first
+	Months [12]string
+	// Date orders a date, with {d}, {m} (month name) and {y} placeholders
+	Date string
+	// Ago phrases a relative time, with %s for the amount
+	Ago string
+	// Minutes, Hours and Days are the unit abbreviations of relative times
+	Minutes, Hours, Days string
+	// Decimal separates the fraction in sizes
+	Decimal string
+}
+
+// Locales are the supported locales by name
+var Locales = map[string]*Locale{
+	"en": {Name: "en", Months: [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		Date: "{m} {d}, {y}", Ago: "%s ago", Minutes: "m", Hours: "h", Days: "d", Decimal: "."},
+	"en-GB": {Name: "en-GB", Months: [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		Date: "{d} {m} {y}", Ago: "%s ago", Minutes: "m", Hours: "h", Days: "d", Decimal: "."},
+	"de": {Name: "de", Months: [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		Date: "{d}. {m} {y}", Ago: "vor %s", Minutes: " Min.", Hours: " Std.", Days: " T.", Decimal: ","},
+	"fr": {Name: "fr", Months: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		Date: "{d} {m} {y}", Ago: "il y a %s", Minutes: " min", Hours: " h", Days: " j", Decimal: ","},
+	"es": {Name: "es", Months: [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		Date: "{d} {m} {y}", Ago: "hace %s", Minutes: " min", Hours: " h", Days: " d", Decimal: ","},
+}
+
+// locale is the locale of this command, set by SetLocale
+var locale = Locales["en"]
+
+// FindLocale finds a locale by name, accepting POSIX names such as
+// "de_DE.UTF-8": the language and region are tried, then the language
+// alone. It returns nil for unsupported locales.
+func FindLocale(name string) *Locale {
+	name, _, _ = strings.Cut(name, ".")
+	name, _, _ = strings.Cut(name, "@")
+	lang, region, _ := strings.Cut(strings.ReplaceAll(name, "_", "-"), "-")
+	lang = strings.ToLower(lang)
+	if l := Locales[lang+"-"+strings.ToUpper(region)]; l != nil {
+		return l
+	}
+	return Locales[lang]
+}
+
+// SetLocale makes FormatAge, FormatDate and FormatSize follow a locale
+func SetLocale(name string) error {
+	l := FindLocale(name)
+	if l == nil {
+		var names []string
+		for n := range Locales {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return UsageErrorf("unsupported locale %q (expected %s)", name, strings.Join(names, ", "))
+	}
+	locale = l
+	return nil
+}
+
+// SetTimezone shows times in a zone: it replaces time.Local, so
+// Time.Local and FormatDate follow it. "Local" keeps the system zone.
+func SetTimezone(name string) error {
+	if name == "Local" {
+		return nil
+	}
+	if name == "" {
+		return UsageErrorf("empty timezone")
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return UsageErrorf("unknown timezone %q (expected an IANA name such as Europe/Berlin, or UTC)", name)
+	}
+	time.Local = loc
+	return nil
+}
+
+// applyDisplay sets the timezone, locale and ASCII profile from the flags,
+// the environment and the settings, in that order
+func applyDisplay(s DisplaySettings) error {
+	if configFlags.ascii || os.Getenv("GITLAB_ASCII") != "" || s.ASCII {
+		SetASCII(true)
+	}
+	if tz := firstNonEmpty(configFlags.tz, os.Getenv("GITLAB_TZ"), s.Timezone); tz != "" {
+		if err := SetTimezone(tz); err != nil {
+			return err
+		}
+	}
+	if name := firstNonEmpty(configFlags.locale, os.Getenv("GITLAB_LOCALE"), s.Locale); name != "" {
+		return SetLocale(name)
+	}
+	// The system locale is a hint only: C, POSIX and unsupported
+	// languages keep English
+	if l := FindLocale(firstEnv("LC_ALL", "LC_TIME", "LANG")); l != nil {
+		locale = l
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// FormatDate renders the date of a timestamp in the display timezone and
+// locale, e.g. "Mar 5, 2024" or "5. März 2024"
+func FormatDate(t time.Time) string {
+	t = t.Local()
+	return strings.NewReplacer("{d}", fmt.Sprint(t.Day()), "{m}", locale.Months[t.Month()-1], "{y}", fmt.Sprint(t.Year())).Replace(locale.Date)
+}
@@ -0,0 +1,53 @@
+package lib_test
+
+import (
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestLocaleFormatting(t *testing.T) {
+	local := time.Local
+	t.Cleanup(func() {
+		time.Local = local
+		lib.SetLocale("en")
+	})
+
+	if err := lib.SetTimezone("Asia/Tokyo"); err != nil {
+		t.Fatalf("SetTimezone: %v", err)
+	}
+	// Late on March 4 in UTC is March 5 in Tokyo
+	created := time.Date(2024, 3, 4, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		locale, date, ago, size string
+	}{
+		{"en", "Mar 5, 2024", "3h ago", "1.5 MB"},
+		{"en_GB.UTF-8", "5 Mar 2024", "3h ago", "1.5 MB"},
+		{"de_DE.UTF-8", "5. März 2024", "vor 3 Std.", "1,5 MB"},
+		{"fr", "5 mars 2024", "il y a 3 h", "1,5 MB"},
+		{"es-MX", "5 mar 2024", "hace 3 h", "1,5 MB"},
+	}
+	for _, tt := range tests {
+		if err := lib.SetLocale(tt.locale); err != nil {
+			t.Fatalf("SetLocale(%q): %v", tt.locale, err)
+		}
+		if got := lib.FormatDate(created); got != tt.date {
+			t.Errorf("%s: FormatDate = %q, want %q", tt.locale, got, tt.date)
+		}
+		if got := lib.FormatAge(time.Now().Add(-3*time.Hour - time.Minute)); got != tt.ago {
+			t.Errorf("%s: FormatAge = %q, want %q", tt.locale, got, tt.ago)
+		}
+		if got := lib.FormatSize(1536 * 1024); got != tt.size {
+			t.Errorf("%s: FormatSize = %q, want %q", tt.locale, got, tt.size)
+		}
+	}
+
+	if err := lib.SetLocale("ja"); lib.ExitCode(err) != lib.ExitUsage {
+		t.Errorf("SetLocale(ja) = %v, want a usage error", err)
+	}
+	if err := lib.SetTimezone("Mars/Olympus"); lib.ExitCode(err) != lib.ExitUsage {
+		t.Errorf("SetTimezone(Mars/Olympus) = %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,185 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MembershipFile declares the direct members of a group or project and
+// their roles, e.g.
+//
+//	members:
+//	  alice: owner
+//	  release-bot: developer
+type MembershipFile struct {
+	Members map[string]string `json:"members"`
+}
+
+// LoadMembership reads a membership file and returns the roles by
+// lowercase username ("@" prefixes are dropped), as GitLab usernames are
+// case-insensitive
+func LoadMembership(file string) (map[string]int, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read membership file: %w", err)
+	}
+	var f MembershipFile
+	if err := DecodeYAML(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid membership file %s: %w", file, err)
+	}
+	if len(f.Members) == 0 {
+		return nil, fmt.Errorf("invalid membership file %s: no members", file)
+	}
+	roles := make(map[string]int, len(f.Members))
+	for username, role := range f.Members {
+		level, err := ParseAccessLevel(role)
+		if err != nil {
+			return nil, fmt.Errorf("invalid membership file %s: %s: %w", file, username, err)
+		}
+		key := strings.ToLower(strings.TrimPrefix(username, "@"))
+		if _, ok := roles[key]; ok {
+			return nil, fmt.Errorf("invalid membership file %s: %s is listed twice", file, username)
+		}
+		roles[key] = level
+	}
+	return roles, nil
+}
+
+// MemberScope is the group or project whose direct members are managed
+type MemberScope struct {
+	Path  string
+	Group bool
+}
+
+func (s MemberScope) String() string {
+	if s.Group {
+		return "group " + s.Path
+	}
+	return "project " + s.Path
+}
+
+func (c *Client) membersEndpoint(s MemberScope) string {
+	kind := "projects"
+	if s.Group {
+		kind = "groups"
+	}
+	return c.apiURL("/%s/%s/members", kind, url.PathEscape(s.Path))
+}
+
+// ListDirectMembers lists the members of a group or project, without those
+// inherited from parent groups
+func (c *Client) ListDirectMembers(s MemberScope) ([]Member, error) {
+	return getAll[Member](c, c.membersEndpoint(s), nil, 0)
+}
+
+// tokenBotPattern matches the bot users behind project and group access
+// tokens, which are members GitLab manages itself
+var tokenBotPattern = regexp.MustCompile(`^(project|group)_\d+_bot`)
+
+// MembershipChange is one difference between the members of a group or
+// project and a membership file. From is 0 for additions and To is 0 for
+// removals.
+type MembershipChange struct {
+	Username string
+	UserID   int
+	From, To int
+}
+
+// Action names the change: add, remove, upgrade or downgrade
+func (ch MembershipChange) Action() string {
+	switch {
+	case ch.From == 0:
+		return "add"
+	case ch.To == 0:
+		return "remove"
+	case ch.To > ch.From:
+		return "upgrade"
+	default:
+		return "downgrade"
+	}
+}
+
+// actionOrder applies additions first, so a group never lacks an owner
+// while another is being replaced
+var actionOrder = map[string]int{"add": 0, "upgrade": 1, "downgrade": 2, "remove": 3}
+
+// PlanMembership compares the direct members of a group or project with
+// the wanted roles and returns the changes that would make them match, in
+// the order ApplyMembershipChange should make them. Usernames are compared
+// case-insensitively. Unlisted members are removed unless keepUnlisted;
+// access token bots and the token's own user, who would lose access midway,
+// are never removed. All wanted users must exist, or nothing is planned.
+func (c *Client) PlanMembership(s MemberScope, want map[string]int, keepUnlisted bool) ([]MembershipChange, error) {
+	members, err := c.ListDirectMembers(s)
+	if err != nil {
+		return nil, err
+	}
+	me, err := c.GetCurrentUser()
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]int, len(want))
+	for username, level := range want {
+		wanted[strings.ToLower(username)] = level
+	}
+
+	var changes []MembershipChange
+	current := make(map[string]bool, len(members))
+	for _, m := range members {
+		username := strings.ToLower(m.Username)
+		current[username] = true
+		level, listed := wanted[username]
+		switch {
+		case listed && level != m.AccessLevel:
+			changes = append(changes, MembershipChange{Username: m.Username, UserID: m.ID, From: m.AccessLevel, To: level})
+		case !listed && !keepUnlisted && !tokenBotPattern.MatchString(m.Username) && m.ID != me.ID:
+			changes = append(changes, MembershipChange{Username: m.Username, UserID: m.ID, From: m.AccessLevel})
+		}
+	}
+
+	var errs []error
+	for username, level := range wanted {
+		if current[username] {
+			continue
+		}
+		u, err := c.FindUser(username)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		changes = append(changes, MembershipChange{Username: u.Username, UserID: u.ID, To: level})
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := actionOrder[changes[i].Action()], actionOrder[changes[j].Action()]
+		if a != b {
+			return a < b
+		}
+		return changes[i].Username < changes[j].Username
+	})
+	return changes, nil
+}
+
+// ApplyMembershipChange adds, removes or changes the role of one member
+func (c *Client) ApplyMembershipChange(s MemberScope, ch MembershipChange) error {
+	endpoint := c.membersEndpoint(s)
+	switch ch.Action() {
+	case "add":
+		body := map[string]int{"user_id": ch.UserID, "access_level": ch.To}
+		return c.do("POST", endpoint, body, nil, http.StatusCreated)
+	case "remove":
+		return c.do("DELETE", fmt.Sprintf("%s/%d", endpoint, ch.UserID), nil, nil, http.StatusNoContent)
+	default:
+		body := map[string]int{"access_level": ch.To}
+		return c.do("PUT", fmt.Sprintf("%s/%d", endpoint, ch.UserID), body, nil, http.StatusOK)
+	}
+}
@@ -0,0 +1,142 @@
+package lib_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestLoadMembership(t *testing.T) {
+	roles, err := lib.LoadMembership(writeRules(t, "members:\n  alice: owner\n  \"@carol\": reporter\n  JDoe: developer\n"))
+	if err != nil {
+		t.Fatalf("LoadMembership: %v", err)
+	}
+	if len(roles) != 3 || roles["alice"] != lib.AccessOwner || roles["carol"] != lib.AccessReporter || roles["jdoe"] != lib.AccessDeveloper {
+		t.Errorf("roles = %v", roles)
+	}
+
+	for content, wantErr := range map[string]string{
+		"members:\n  alice: admin\n":                    `alice: invalid access level "admin"`,
+		"members: {}\n":                                 "no members",
+		"members:\n  alice: 40\n":                       "members",
+		"member:\n  alice: owner\n":                     "member",
+		"members:\n  jdoe: owner\n  \"@JDoe\": guest\n": "listed twice",
+	} {
+		if _, err := lib.LoadMembership(writeRules(t, content)); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("LoadMembership(%q) error = %v, want %q", content, err, wantErr)
+		}
+	}
+}
+
+func TestPlanMembership(t *testing.T) {
+	group := lib.MemberScope{Path: "group", Group: true}
+	carol := lib.User{ID: 3, Username: "carol", Name: "Carol"}
+	bot := lib.User{ID: 4, Username: "group_7_bot_abc123", Name: "Deploy token bot"}
+	jdoe := lib.User{ID: 5, Username: "JDoe", Name: "Jane Doe"}
+
+	tests := []struct {
+		name         string
+		scope        lib.MemberScope
+		want         map[string]int
+		keepUnlisted bool
+		// members are added to the group as developers
+		members []lib.User
+		// changes are formatted as action username from→to
+		changes []string
+		wantErr string
+	}{
+		{
+			name:    "in sync",
+			scope:   group,
+			want:    map[string]int{"alice": lib.AccessOwner, "bob": lib.AccessDeveloper},
+			changes: nil,
+		},
+		{
+			name:    "add, downgrade and remove",
+			scope:   group,
+			want:    map[string]int{"alice": lib.AccessMaintainer, "carol": lib.AccessReporter},
+			changes: []string{"add carol 0→20", "downgrade alice 50→40", "remove bob 30→0"},
+		},
+		{
+			name:         "keep unlisted",
+			scope:        group,
+			want:         map[string]int{"bob": lib.AccessMaintainer},
+			keepUnlisted: true,
+			changes:      []string{"upgrade bob 30→40"},
+		},
+		{
+			name:    "project",
+			scope:   lib.MemberScope{Path: gitlabtest.ProjectPath},
+			want:    map[string]int{"alice": lib.AccessOwner},
+			changes: []string{"remove bob 30→0"},
+		},
+		{
+			name:    "usernames are case-insensitive",
+			scope:   group,
+			members: []lib.User{jdoe},
+			want:    map[string]int{"Alice": lib.AccessOwner, "bob": lib.AccessDeveloper, "jdoe": lib.AccessMaintainer, "CAROL": lib.AccessGuest},
+			changes: []string{"add carol 0→10", "upgrade JDoe 30→40"},
+		},
+		{
+			name:    "token user is never removed",
+			scope:   group,
+			want:    map[string]int{"bob": lib.AccessDeveloper},
+			changes: nil,
+		},
+		{
+			name:    "unknown user",
+			scope:   group,
+			want:    map[string]int{"alice": lib.AccessOwner, "dave": lib.AccessGuest},
+			wantErr: `user "dave"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			srv.AddUser(carol)
+			srv.AddUser(bot)
+			client := srv.Client()
+			// Access token bots are never removed
+			if _, err := client.AddGroupMember("group", bot.ID, lib.AccessMaintainer, lib.Date{}); err != nil {
+				t.Fatal(err)
+			}
+			for _, u := range tt.members {
+				srv.AddUser(u)
+				if _, err := client.AddGroupMember("group", u.ID, lib.AccessDeveloper, lib.Date{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			changes, err := client.PlanMembership(tt.scope, tt.want, tt.keepUnlisted)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("PlanMembership error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PlanMembership: %v", err)
+			}
+			var got []string
+			for _, ch := range changes {
+				got = append(got, fmt.Sprintf("%s %s %d→%d", ch.Action(), ch.Username, ch.From, ch.To))
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.changes, ", ") {
+				t.Fatalf("changes = %v, want %v", got, tt.changes)
+			}
+
+			for _, ch := range changes {
+				if err := client.ApplyMembershipChange(tt.scope, ch); err != nil {
+					t.Fatalf("ApplyMembershipChange(%+v): %v", ch, err)
+				}
+			}
+			if changes, err := client.PlanMembership(tt.scope, tt.want, tt.keepUnlisted); err != nil || len(changes) != 0 {
+				t.Errorf("PlanMembership after applying = %+v, %v", changes, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package lib
+
+import "sync"
+
+// lookups memoizes the lookups a command makes repeatedly (the current
+// user, users by name, project settings, members, labels, the default
+// branch), so each is requested once per run even when several steps or
+// parallel workers need it. Workers asking for a lookup in flight wait for
+// it instead of requesting it again. Failures are not kept.
+//
+// The results are only as fresh as the run: any write through the client
+// forgets them all, and long-running commands call ForgetLookups between
+// units of work.
+type lookups struct {
+	mu    sync.Mutex
+	calls map[string]*lookup
+}
+
+// lookup is one memoized result, ready when done is closed
+type lookup struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+}
+
+// memoize returns the result of fetch for key, calling it only the first
+// time. Callers get the same value, so they must copy what they may change.
+func memoize[T any](c *Client, key string, fetch func() (T, error)) (T, error) {
+	c.lookups.mu.Lock()
+	if c.lookups.calls == nil {
+		c.lookups.calls = make(map[string]*lookup)
+	}
+	l, ok := c.lookups.calls[key]
+	if !ok {
+		l = &lookup{done: make(chan struct{})}
+		c.lookups.calls[key] = l
+		c.lookups.mu.Unlock()
+
+		l.val, l.err = fetch()
+		if l.err != nil {
+			c.lookups.mu.Lock()
+			if c.lookups.calls[key] == l {
+				delete(c.lookups.calls, key)
+			}
+			c.lookups.mu.Unlock()
+		}
+		close(l.done)
+	} else {
+		c.lookups.mu.Unlock()
+		<-l.done
+	}
+
+	if l.err != nil {
+		var zero T
+		return zero, l.err
+	}
+	return l.val.(T), nil
+}
+
+// ForgetLookups drops the memoized lookups, so the next ones ask GitLab
+// again
+func (c *Client) ForgetLookups() {
+	c.lookups.mu.Lock()
+	c.lookups.calls = nil
+	c.lookups.mu.Unlock()
+}
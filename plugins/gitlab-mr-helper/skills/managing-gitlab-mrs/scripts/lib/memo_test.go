@@ -0,0 +1,75 @@
+package lib_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestLookupsMemoized(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	// requests counts the requests to paths ending in suffix
+	requests := func(suffix string) (n int) {
+		for _, r := range srv.Requests() {
+			if strings.HasSuffix(r.URL.Path, suffix) {
+				n++
+			}
+		}
+		return n
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetCurrentUser(); err != nil {
+			t.Fatalf("GetCurrentUser: %v", err)
+		}
+	}
+	if n := requests("/user"); n != 1 {
+		t.Errorf("3 GetCurrentUser calls made %d requests, want 1", n)
+	}
+
+	// Parallel workers share the lookup in flight
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := client.MemberIDs(gitlabtest.ProjectPath, []string{"alice"}); err != nil {
+				t.Errorf("MemberIDs: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := requests("/members/all"); n != 1 {
+		t.Errorf("8 parallel MemberIDs calls made %d requests, want 1", n)
+	}
+
+	// Failures are not kept
+	for i := 0; i < 2; i++ {
+		if _, err := client.FindUser("nobody"); lib.ExitCode(err) != lib.ExitNotFound {
+			t.Fatalf("FindUser(nobody) error = %v, want not found", err)
+		}
+	}
+	if n := requests("/users"); n != 2 {
+		t.Errorf("2 failed FindUser calls made %d requests, want 2", n)
+	}
+
+	// Writes make the next lookups fresh
+	if _, err := client.GetProjectSettings(gitlabtest.ProjectPath); err != nil {
+		t.Fatalf("GetProjectSettings: %v", err)
+	}
+	if err := client.UpdateProjectSettings(gitlabtest.ProjectPath, map[string]interface{}{"merge_method": "ff"}); err != nil {
+		t.Fatalf("UpdateProjectSettings: %v", err)
+	}
+	s, err := client.GetProjectSettings(gitlabtest.ProjectPath)
+	if err != nil || s.MergeMethod != "ff" {
+		t.Errorf("GetProjectSettings after an update = %+v, %v", s, err)
+	}
+	if _, err := client.GetCurrentUser(); err != nil || requests("/user") != 2 {
+		t.Errorf("GetCurrentUser after a write made %d requests in all, want 2 (%v)", requests("/user"), err)
+	}
+}
@@ -0,0 +1,47 @@
+package lib
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// MergeMRRequest represents the request body for accepting an MR
+type MergeMRRequest struct {
+	// SHA must match the MR's HEAD, so nothing pushed after the pipeline
+	// ran gets merged untested
+	SHA                       string `json:"sha,omitempty"`
+	Squash                    bool   `json:"squash,omitempty"`
+	ShouldRemoveSourceBranch  bool   `json:"should_remove_source_branch,omitempty"`
+	MergeCommitMessage        string `json:"merge_commit_message,omitempty"`
+	MergeWhenPipelineSucceeds bool   `json:"merge_when_pipeline_succeeds,omitempty"`
+}
+
+// RebaseMR asks GitLab to rebase the MR's source branch onto its target.
+// The rebase runs asynchronously; poll GetMRRebaseStatus for the outcome.
+func (c *Client) RebaseMR(projectPath string, mrIID int) error {
+	endpoint := c.apiURL("/projects/%s/merge_requests/%d/rebase", url.PathEscape(projectPath), mrIID)
+	return c.do("PUT", endpoint, nil, nil, http.StatusAccepted)
+}
+
+// GetMRRebaseStatus gets an MR including rebase_in_progress and merge_error
+func (c *Client) GetMRRebaseStatus(projectPath string, mrIID int) (*MergeRequest, error) {
+	endpoint := c.apiURL("/projects/%s/merge_requests/%d?include_rebase_in_progress=true", url.PathEscape(projectPath), mrIID)
+
+	var mr MergeRequest
+	if err := c.do("GET", endpoint, nil, &mr, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &mr, nil
+}
+
+// MergeMR accepts (merges) an MR. GitLab answers 405, 406 or 409 when the MR
+// cannot be merged, which ExitCode maps to ExitConflict.
+func (c *Client) MergeMR(projectPath string, mrIID int, req *MergeMRRequest) (*MergeRequest, error) {
+	endpoint := c.apiURL("/projects/%s/merge_requests/%d/merge", url.PathEscape(projectPath), mrIID)
+
+	var mr MergeRequest
+	if err := c.do("PUT", endpoint, req, &mr, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &mr, nil
+}
@@ -0,0 +1,146 @@
+package lib_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestMergeMR(t *testing.T) {
+	tests := []struct {
+		name      string
+		iid       int
+		req       lib.MergeMRRequest
+		wantExit  int
+		wantState string
+	}{
+		{name: "merges", iid: 1, req: lib.MergeMRRequest{SHA: "ccc333"}, wantState: "merged"},
+		{name: "stale sha", iid: 1, req: lib.MergeMRRequest{SHA: "deadbeef"}, wantExit: lib.ExitConflict},
+		{name: "conflicts", iid: 2, wantExit: lib.ExitConflict},
+		{name: "already merged", iid: 3, wantExit: lib.ExitConflict},
+		{name: "unknown MR", iid: 99, wantExit: lib.ExitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			mr, err := srv.Client().MergeMR(gitlabtest.ProjectPath, tt.iid, &tt.req)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("MergeMR: %v", err)
+			}
+			if mr.State != tt.wantState {
+				t.Errorf("state = %q, want %q", mr.State, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestRebaseMR(t *testing.T) {
+	tests := []struct {
+		name         string
+		iid          int
+		wantExit     int
+		wantMergeErr bool
+	}{
+		{name: "clean", iid: 1},
+		{name: "conflicts", iid: 2, wantMergeErr: true},
+		{name: "merged", iid: 3, wantExit: lib.ExitAuth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			client := srv.Client()
+			err := client.RebaseMR(gitlabtest.ProjectPath, tt.iid)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("RebaseMR: %v", err)
+			}
+			mr, err := client.GetMRRebaseStatus(gitlabtest.ProjectPath, tt.iid)
+			if err != nil {
+				t.Fatalf("GetMRRebaseStatus: %v", err)
+			}
+			if mr.RebaseInProgress {
+				t.Error("rebase still in progress")
+			}
+			if got := mr.MergeError != ""; got != tt.wantMergeErr {
+				t.Errorf("merge_error = %q, want set: %v", mr.MergeError, tt.wantMergeErr)
+			}
+		})
+	}
+}
+
+func TestPoll(t *testing.T) {
+	calls := 0
+	err := lib.Poll(time.Millisecond, time.Second, func() (bool, error) {
+		calls++
+		return calls == 3, nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Poll = %v after %d calls, want nil after 3", err, calls)
+	}
+
+	err = lib.Poll(time.Millisecond, 5*time.Millisecond, func() (bool, error) { return false, nil })
+	if !errors.Is(err, lib.ErrTimeout) {
+		t.Errorf("Poll = %v, want ErrTimeout", err)
+	}
+
+	boom := errors.New("boom")
+	err = lib.Poll(time.Millisecond, 0, func() (bool, error) { return false, boom })
+	if !errors.Is(err, boom) {
+		t.Errorf("Poll = %v, want check error", err)
+	}
+}
+
+func TestPoller(t *testing.T) {
+	// Backing off doubles the wait up to MaxInterval: 1+2+4+4ms
+	poller := lib.Poller{Interval: time.Millisecond, MaxInterval: 4 * time.Millisecond}
+	calls := 0
+	start := time.Now()
+	err := poller.Poll(func() (bool, time.Duration, error) {
+		calls++
+		return calls == 5, lib.PollBackoff, nil
+	})
+	if err != nil || calls != 5 {
+		t.Fatalf("Poll = %v after %d calls, want nil after 5", err, calls)
+	}
+	if elapsed := time.Since(start); elapsed < 11*time.Millisecond {
+		t.Errorf("backed-off polls took %s, want at least 11ms", elapsed)
+	}
+
+	// A wait past the deadline is cut short for a last check at it
+	poller = lib.Poller{Interval: time.Hour, Timeout: 20 * time.Millisecond}
+	calls = 0
+	start = time.Now()
+	err = poller.Poll(func() (bool, time.Duration, error) {
+		calls++
+		return false, 0, nil
+	})
+	if !errors.Is(err, lib.ErrTimeout) || calls != 2 {
+		t.Errorf("Poll = %v after %d calls, want ErrTimeout after 2", err, calls)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Poll gave up after %s, want about 20ms", elapsed)
+	}
+
+	// Asked-for waits are kept within the bounds, and the last check at
+	// the deadline can still succeed
+	poller = lib.Poller{Interval: time.Millisecond, MaxInterval: 2 * time.Millisecond, Timeout: 30 * time.Millisecond}
+	start = time.Now()
+	err = poller.Poll(func() (bool, time.Duration, error) {
+		return time.Since(start) >= 10*time.Millisecond, time.Hour, nil
+	})
+	if err != nil {
+		t.Errorf("Poll = %v, want nil", err)
+	}
+}
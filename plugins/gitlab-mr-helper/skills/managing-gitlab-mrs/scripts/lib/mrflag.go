@@ -0,0 +1,82 @@
+package lib
+
+import (
+	"flag"
+	"strconv"
+	"strings"
+)
+
+// MRFlag is the --mr flag of scripts that work on one merge request. It
+// takes an IID (5 or !5), an MR web URL or the MR's source branch.
+type MRFlag struct {
+	Value string
+
+	project string // from a web URL
+	iid     int
+	branch  string
+}
+
+// RegisterMRFlag registers --mr on the default flag set. Call it before
+// flag.Parse.
+func RegisterMRFlag(usage string) *MRFlag {
+	m := &MRFlag{}
+	flag.StringVar(&m.Value, "mr", "", usage)
+	return m
+}
+
+// Parse validates --mr after flag.Parse. Without it, an IID or MR URL
+// among the positional arguments is used, then the MR of a GitLab CI merge
+// request pipeline; with none of these, Parse returns a usage error.
+func (m *MRFlag) Parse() error {
+	value := strings.TrimSpace(m.Value)
+	switch {
+	case value == "":
+		m.iid = MRArg()
+		if job := CurrentCIJob(); m.iid == 0 && job != nil {
+			m.iid = job.MRIID
+		}
+		if m.iid == 0 {
+			return UsageErrorf("--mr is required (an IID, MR URL or source branch)")
+		}
+	case strings.Contains(value, "://"):
+		project, iid, err := ParseWebURL(value)
+		if err != nil {
+			return err
+		}
+		if iid == 0 {
+			return UsageErrorf("--mr %s is not a merge request URL", value)
+		}
+		m.project, m.iid = project, iid
+	default:
+		if iid, err := strconv.Atoi(strings.TrimPrefix(value, "!")); err == nil {
+			if iid <= 0 {
+				return UsageErrorf("invalid MR IID %q", value)
+			}
+			m.iid = iid
+		} else {
+			m.branch = value
+		}
+	}
+	return nil
+}
+
+// ProjectArg returns the project of an MR URL given to --mr, and otherwise
+// the positional project argument (see ProjectArg)
+func (m *MRFlag) ProjectArg() string {
+	if m.project != "" {
+		return m.project
+	}
+	return ProjectArg()
+}
+
+// Resolve returns the IID of the MR, looking a source branch up in project
+func (m *MRFlag) Resolve(client *Client, project string) (int, error) {
+	if m.branch == "" {
+		return m.iid, nil
+	}
+	mr, err := client.FindMRByBranch(project, m.branch)
+	if err != nil {
+		return 0, err
+	}
+	return mr.IID, nil
+}
@@ -0,0 +1,51 @@
+package lib_test
+
+import (
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestMRFlag(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	tests := []struct {
+		value       string
+		wantProject string // from ProjectArg
+		wantIID     int
+		wantExit    int // of Parse or Resolve
+	}{
+		{value: "2", wantIID: 2},
+		{value: "!3", wantIID: 3},
+		{value: "https://gitlab.com/group/sub/nested/-/merge_requests/1", wantProject: "group/sub/nested", wantIID: 1},
+		{value: "feature/login", wantIID: 1},
+		{value: "no-such-branch", wantExit: lib.ExitNotFound},
+		{value: "https://gitlab.com/group/project/-/pipelines/5", wantExit: lib.ExitUsage},
+		{value: "0", wantExit: lib.ExitUsage},
+		{value: "", wantExit: lib.ExitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("GITLAB_URL", "")
+			t.Setenv("GITLAB_CI", "")
+			m := &lib.MRFlag{Value: tt.value}
+			err := m.Parse()
+			var iid int
+			if err == nil {
+				iid, err = m.Resolve(client, gitlabtest.ProjectPath)
+			}
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil || iid != tt.wantIID {
+				t.Fatalf("IID = %d, %v; want %d", iid, err, tt.wantIID)
+			}
+			if got := m.ProjectArg(); got != tt.wantProject {
+				t.Errorf("ProjectArg = %q, want %q", got, tt.wantProject)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// DefaultMRTemplate is the description template whose headings lint_mr.go
+// requires when no sections are configured
+const DefaultMRTemplate = "Default"
+
+// MRTemplatePath returns the repository path of a named MR description
+// template
+func MRTemplatePath(name string) string {
+	return ".gitlab/merge_request_templates/" + name + ".md"
+}
+
+// GetMRTemplate returns the MR description template of the given name on
+// the default branch, or "" when the project has none by that name
+func (c *Client) GetMRTemplate(projectPath, name string) (string, error) {
+	branch, err := c.DefaultBranch(projectPath)
+	if err != nil {
+		return "", err
+	}
+	file, err := c.GetFile(projectPath, MRTemplatePath(name), branch)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return file.Text()
+}
+
+// MRCheck is the outcome of one rule checked by LintMR
+type MRCheck struct {
+	Name    string
+	Passed  bool
+	Details string
+}
+
+// Status is the check's status in a StatusReport
+func (c MRCheck) Status() string {
+	if c.Passed {
+		return "passed"
+	}
+	return "failed"
+}
+
+var (
+	// issueRefRe matches issue references: #12, group/project#12 and issue
+	// URLs
+	issueRefRe = regexp.MustCompile(`(^|[\s(\[])([\w./-]+)?#\d+\b|/-/issues/\d+`)
+	// draftPrefixRe matches the prefixes GitLab uses to mark drafts
+	draftPrefixRe = regexp.MustCompile(`(?i)^\s*(\[draft\]|\(draft\)|draft:|draft -)\s*`)
+	htmlCommentRe = regexp.MustCompile(`(?s)<!--.*?-->`)
+)
+
+// LintMR checks an MR against the rules of s: a conventional-commit title,
+// description sections with text in them, a linked issue and labels.
+// template is the project's MR description template; when s lists no
+// sections, its headings are the required ones, and text copied from it
+// does not count as filling a section in. Only configured rules are
+// checked.
+func LintMR(s *LintSettings, tracker *TrackerSettings, mr *MergeRequest, template string) ([]MRCheck, error) {
+	var checks []MRCheck
+
+	if s.MR.ConventionalTitle {
+		types := s.ConventionalTypes
+		if len(types) == 0 {
+			types = DefaultConventionalTypes
+		}
+		title := draftPrefixRe.ReplaceAllString(mr.Title, "")
+		check := MRCheck{Name: "Title", Passed: ConventionalCommitRegexp(types).MatchString(title), Details: "conventional commit"}
+		if !check.Passed {
+			check.Details = fmt.Sprintf("%q is not a conventional commit (%s)", title, strings.Join(types, "|"))
+		}
+		checks = append(checks, check)
+	}
+
+	sections := s.MR.Sections
+	if len(sections) == 0 {
+		sections = sectionHeadings(template)
+	}
+	if len(sections) > 0 {
+		filled := descriptionSections(mr.Description)
+		placeholders := descriptionSections(template)
+		var missing, empty []string
+		for _, heading := range sections {
+			body, ok := filled[strings.ToLower(heading)]
+			switch {
+			case !ok:
+				missing = append(missing, heading)
+			case sectionText(body, placeholders[strings.ToLower(heading)]) == "":
+				empty = append(empty, heading)
+			}
+		}
+		check := MRCheck{Name: "Description", Passed: len(missing)+len(empty) == 0, Details: fmt.Sprintf("%d section(s) filled in", len(sections))}
+		if !check.Passed {
+			var problems []string
+			if len(missing) > 0 {
+				problems = append(problems, "missing "+quoteList(missing))
+			}
+			if len(empty) > 0 {
+				problems = append(problems, "empty "+quoteList(empty))
+			}
+			check.Details = strings.Join(problems, "; ")
+		}
+		checks = append(checks, check)
+	}
+
+	if s.MR.RequireIssue {
+		check := MRCheck{Name: "Linked issue", Details: "none: reference an issue (Closes #12) or a ticket"}
+		text := htmlCommentRe.ReplaceAllString(mr.Description, "")
+		if m := issueRefRe.FindString(text); m != "" {
+			check.Passed, check.Details = true, strings.TrimSpace(m)
+		} else {
+			tickets, err := FindTickets(tracker, mr.SourceBranch, []Commit{{Message: mr.Title + "\n" + text}})
+			if err != nil {
+				return nil, err
+			}
+			if len(tickets) > 0 {
+				check.Passed, check.Details = true, strings.Join(tickets, ", ")
+			}
+		}
+		checks = append(checks, check)
+	}
+
+	if len(s.MR.Labels) > 0 {
+		var unmatched []string
+		for _, pattern := range s.MR.Labels {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid lint.mr.labels pattern %q: %w", pattern, err)
+			}
+			matched := false
+			for _, label := range mr.Labels {
+				if ok, _ := path.Match(pattern, label); ok {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				unmatched = append(unmatched, pattern)
+			}
+		}
+		check := MRCheck{Name: "Labels", Passed: len(unmatched) == 0, Details: strings.Join(mr.Labels, ", ")}
+		if !check.Passed {
+			check.Details = "no label matches " + quoteList(unmatched)
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// sectionHeadings returns the headings of a description template, in order
+func sectionHeadings(template string) []string {
+	var headings []string
+	forEachHeading(template, func(i, level int, title string) {
+		headings = append(headings, title)
+	})
+	return headings
+}
+
+// descriptionSections maps the lower-cased headings of a description to
+// the text under them, up to the next heading of the same or a higher
+// level
+func descriptionSections(description string) map[string]string {
+	lines := strings.Split(description, "\n")
+	type heading struct{ line, level int }
+	var headings []heading
+	var titles []string
+	forEachHeading(description, func(i, level int, title string) {
+		headings = append(headings, heading{i, level})
+		titles = append(titles, strings.ToLower(title))
+	})
+	sections := make(map[string]string, len(headings))
+	for n, h := range headings {
+		end := len(lines)
+		for _, next := range headings[n+1:] {
+			if next.level <= h.level {
+				end = next.line
+				break
+			}
+		}
+		if _, seen := sections[titles[n]]; !seen {
+			sections[titles[n]] = strings.Join(lines[h.line+1:end], "\n")
+		}
+	}
+	return sections
+}
+
+// forEachHeading calls fn with the line number, level and text of each
+// heading of a markdown text, skipping fenced code blocks
+func forEachHeading(text string, fn func(i, level int, title string)) {
+	inFence := false
+	for i, line := range strings.Split(text, "\n") {
+		if isFence(line) {
+			inFence = !inFence
+			continue
+		}
+		if level, title := parseHeading(line); level > 0 && !inFence {
+			fn(i, level, title)
+		}
+	}
+}
+
+// sectionText returns the text of a section written by the author: without
+// HTML comments and lines left as they were in the template
+func sectionText(body, placeholder string) string {
+	template := make(map[string]bool)
+	for _, line := range strings.Split(htmlCommentRe.ReplaceAllString(placeholder, ""), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			template[line] = true
+		}
+	}
+	var kept []string
+	for _, line := range strings.Split(htmlCommentRe.ReplaceAllString(body, ""), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !template[line] {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func quoteList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = fmt.Sprintf("%q", item)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,149 @@
+package lib_test
+
+import (
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+const mrTemplate = `## Summary
+
+<!-- What does this change and why? -->
+
+## Testing
+
+- [ ] Unit tests
+`
+
+func TestLintMR(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings lib.MRLintSettings
+		mr       lib.MergeRequest
+		template string
+		want     map[string]bool // check name → passed
+		details  string          // expected in the failures
+	}{
+		{
+			name:     "conventional title",
+			settings: lib.MRLintSettings{ConventionalTitle: true},
+			mr:       lib.MergeRequest{Title: "Draft: feat(login): add page"},
+			want:     map[string]bool{"Title": true},
+		},
+		{
+			name:     "plain title",
+			settings: lib.MRLintSettings{ConventionalTitle: true},
+			mr:       lib.MergeRequest{Title: "Add login page"},
+			want:     map[string]bool{"Title": false},
+			details:  "not a conventional commit",
+		},
+		{
+			name:     "template filled in",
+			mr:       lib.MergeRequest{Description: "## Summary\n\nAdds the login page.\n\n## Testing\n\n- [x] Unit tests\n"},
+			template: mrTemplate,
+			want:     map[string]bool{"Description": true},
+		},
+		{
+			name:     "template left as is",
+			mr:       lib.MergeRequest{Description: mrTemplate},
+			template: mrTemplate,
+			want:     map[string]bool{"Description": false},
+			details:  `empty "Summary", "Testing"`,
+		},
+		{
+			name:     "configured sections",
+			settings: lib.MRLintSettings{Sections: []string{"summary", "Rollback"}},
+			mr:       lib.MergeRequest{Description: "## Summary\nDone.\n```\n## Rollback\n```\n"},
+			want:     map[string]bool{"Description": false},
+			details:  `missing "Rollback"`,
+		},
+		{
+			name:     "subsections count",
+			settings: lib.MRLintSettings{Sections: []string{"Changes"}},
+			mr:       lib.MergeRequest{Description: "## Changes\n### Backend\nNew endpoint\n## Notes\n"},
+			want:     map[string]bool{"Description": true},
+		},
+		{
+			name:     "closing pattern",
+			settings: lib.MRLintSettings{RequireIssue: true},
+			mr:       lib.MergeRequest{Description: "Closes #12"},
+			want:     map[string]bool{"Linked issue": true},
+		},
+		{
+			name:     "issue URL",
+			settings: lib.MRLintSettings{RequireIssue: true},
+			mr:       lib.MergeRequest{Description: "See https://gitlab.com/group/project/-/issues/7"},
+			want:     map[string]bool{"Linked issue": true},
+		},
+		{
+			name:     "ticket in branch",
+			settings: lib.MRLintSettings{RequireIssue: true},
+			mr:       lib.MergeRequest{SourceBranch: "feature/ABC-42-login"},
+			want:     map[string]bool{"Linked issue": true},
+		},
+		{
+			name:     "reference only in a comment",
+			settings: lib.MRLintSettings{RequireIssue: true},
+			mr:       lib.MergeRequest{Description: "<!-- Closes #12 -->", SourceBranch: "login"},
+			want:     map[string]bool{"Linked issue": false},
+		},
+		{
+			name:     "label patterns",
+			settings: lib.MRLintSettings{Labels: []string{"type::*", "priority::*"}},
+			mr:       lib.MergeRequest{Labels: []string{"type::bug", "frontend"}},
+			want:     map[string]bool{"Labels": false},
+			details:  `no label matches "priority::*"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &lib.LintSettings{MR: tt.settings}
+			checks, err := lib.LintMR(s, &lib.TrackerSettings{}, &tt.mr, tt.template)
+			if err != nil {
+				t.Fatalf("LintMR: %v", err)
+			}
+			got := make(map[string]bool)
+			var details []string
+			for _, c := range checks {
+				got[c.Name] = c.Passed
+				if !c.Passed {
+					details = append(details, c.Details)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got checks %v, want %v", got, tt.want)
+			}
+			for name, passed := range tt.want {
+				if p, ok := got[name]; !ok || p != passed {
+					t.Errorf("%s: got passed=%v (present %v), want %v; details %q", name, p, ok, passed, details)
+				}
+			}
+			if tt.details != "" && !strings.Contains(strings.Join(details, "; "), tt.details) {
+				t.Errorf("details %q do not mention %q", details, tt.details)
+			}
+		})
+	}
+
+	// Without rules or a template nothing is checked
+	checks, err := lib.LintMR(&lib.LintSettings{}, &lib.TrackerSettings{}, &lib.MergeRequest{}, "")
+	if err != nil || len(checks) != 0 {
+		t.Errorf("no rules: got %v, %v; want no checks", checks, err)
+	}
+}
+
+func TestGetMRTemplate(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	srv.Project(gitlabtest.ProjectPath).Files[lib.MRTemplatePath("Default")] = mrTemplate
+
+	got, err := srv.Client().GetMRTemplate(gitlabtest.ProjectPath, "Default")
+	if err != nil || got != mrTemplate {
+		t.Errorf("Default: got %q, %v", got, err)
+	}
+	got, err = srv.Client().GetMRTemplate(gitlabtest.ProjectPath, "Bug")
+	if err != nil || got != "" {
+		t.Errorf("missing template: got %q, %v; want empty", got, err)
+	}
+}
@@ -0,0 +1,90 @@
+package lib
+
+import (
+	"errors"
+	"slices"
+	"sort"
+)
+
+// Roles of the token user in an MR, for ListMyMRs
+const (
+	RoleAuthor   = "author"
+	RoleAssignee = "assignee"
+	RoleReviewer = "reviewer"
+)
+
+// MyMR is an MR the token user has a part in
+type MyMR struct {
+	MergeRequest
+	Roles []string // RoleAuthor, RoleAssignee and RoleReviewer, in that order
+}
+
+// ListMyMRs lists the MRs the token user authored, is assigned to or
+// reviews, across the instance or within a group (subgroups included).
+// Each role costs one request; an MR found through several roles is listed
+// once. opts filters every request (Scope, ReviewerID and OrderBy are set
+// here). The MRs come most recently updated first; with opts.Limit, each
+// role fetches only that many, so paging stops early.
+func (c *Client) ListMyMRs(group string, roles []string, opts MRListOptions) ([]MyMR, error) {
+	var reviewerID int
+	for _, role := range roles {
+		switch role {
+		case RoleAuthor, RoleAssignee:
+		case RoleReviewer:
+			me, err := c.GetCurrentUser()
+			if err != nil {
+				return nil, err
+			}
+			reviewerID = me.ID
+		default:
+			return nil, UsageErrorf("unknown role %q (expected %s, %s or %s)", role, RoleAuthor, RoleAssignee, RoleReviewer)
+		}
+	}
+
+	found := make([][]MergeRequest, len(roles))
+	errs := c.ForEach(len(roles), func(i int) error {
+		o := opts
+		o.OrderBy = "updated_at"
+		switch roles[i] {
+		case RoleAuthor:
+			o.Scope = "created_by_me"
+		case RoleAssignee:
+			o.Scope = "assigned_to_me"
+		case RoleReviewer:
+			o.Scope, o.ReviewerID = "all", reviewerID
+		}
+		var err error
+		found[i], err = c.ListGroupMRs(group, &o)
+		return err
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	var mrs []MyMR
+	index := map[int]int{} // MR ID → position in mrs
+	for _, role := range []string{RoleAuthor, RoleAssignee, RoleReviewer} {
+		for i := range roles {
+			if roles[i] != role {
+				continue
+			}
+			for _, mr := range found[i] {
+				if j, ok := index[mr.ID]; ok {
+					if !slices.Contains(mrs[j].Roles, role) {
+						mrs[j].Roles = append(mrs[j].Roles, role)
+					}
+					continue
+				}
+				index[mr.ID] = len(mrs)
+				mrs = append(mrs, MyMR{MergeRequest: mr, Roles: []string{role}})
+			}
+		}
+	}
+	sort.SliceStable(mrs, func(i, j int) bool {
+		return mrs[i].UpdatedAt.After(mrs[j].UpdatedAt)
+	})
+	if opts.Limit > 0 && len(mrs) > opts.Limit {
+		mrs = mrs[:opts.Limit]
+	}
+	return mrs, nil
+}
@@ -0,0 +1,64 @@
+package lib_test
+
+import (
+	"fmt"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestListMyMRs(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+	all := []string{lib.RoleAuthor, lib.RoleAssignee, lib.RoleReviewer}
+
+	tests := []struct {
+		group string
+		roles []string
+		state string
+		want  string
+	}{
+		{"", all, "opened", "[{group/project!2 [reviewer]} {group/sub/nested!1 [assignee reviewer]}]"},
+		{"", all, "all", "[{group/project!3 [author]} {group/project!2 [reviewer]} {group/sub/nested!1 [assignee reviewer]}]"},
+		{"group/sub", all, "opened", "[{group/sub/nested!1 [assignee reviewer]}]"},
+		{"", []string{lib.RoleAuthor}, "opened", "[]"},
+	}
+	for _, tt := range tests {
+		mrs, err := client.ListMyMRs(tt.group, tt.roles, lib.MRListOptions{State: tt.state})
+		if err != nil {
+			t.Fatalf("ListMyMRs(%q, %v, %s): %v", tt.group, tt.roles, tt.state, err)
+		}
+		type found struct {
+			ref   string
+			roles []string
+		}
+		got := []found{}
+		for _, mr := range mrs {
+			got = append(got, found{mr.References.Full, mr.Roles})
+		}
+		if s := fmt.Sprint(got); s != tt.want {
+			t.Errorf("ListMyMRs(%q, %v, %s) = %s, want %s", tt.group, tt.roles, tt.state, s, tt.want)
+		}
+	}
+
+	// A limit stops every role after one page and keeps the newest overall;
+	// drafts are filtered by GitLab, not after the limit
+	srv.Project(gitlabtest.ProjectPath).MRs[1].Draft = true
+	mrs, err := client.ListMyMRs("", all, lib.MRListOptions{State: "all", NotDraft: true, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListMyMRs with a limit: %v", err)
+	}
+	if len(mrs) != 2 || mrs[0].References.Full != "group/project!3" || mrs[1].References.Full != "group/sub/nested!1" {
+		t.Errorf("ListMyMRs with a limit = %v", mrs)
+	}
+	for _, r := range srv.Requests() {
+		if q := r.URL.Query(); q.Get("draft") == "no" && (q.Get("page") != "1" || q.Get("order_by") != "updated_at") {
+			t.Errorf("limited listing requested %s", r.URL.RawQuery)
+		}
+	}
+
+	if _, err := client.ListMyMRs("", []string{"owner"}, lib.MRListOptions{}); lib.ExitCode(err) != lib.ExitUsage {
+		t.Errorf("ListMyMRs with an unknown role = %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,74 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Notification is a message about a finished command
+type Notification struct {
+	Title   string // e.g. "sync.go finished"
+	Text    string // details, markdown allowed
+	URL     string // optional link
+	Success bool
+}
+
+// Notifier posts notifications to a Slack or Mattermost incoming webhook
+type Notifier struct {
+	settings   NotifySettings
+	httpClient *http.Client
+}
+
+// NewNotifier returns a notifier for the configured webhook, or nil when none
+// is configured. GITLAB_NOTIFY_WEBHOOK overrides notify.webhook_url.
+func NewNotifier(s NotifySettings) *Notifier {
+	if url := os.Getenv("GITLAB_NOTIFY_WEBHOOK"); url != "" {
+		s.WebhookURL = url
+	}
+	if s.WebhookURL == "" {
+		return nil
+	}
+	return &Notifier{settings: s, httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// notifyPayload is accepted by both Slack and Mattermost incoming webhooks
+type notifyPayload struct {
+	Text     string `json:"text"`
+	Channel  string `json:"channel,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// Send posts a notification
+func (n *Notifier) Send(msg Notification) error {
+	icon := "✅"
+	if !msg.Success {
+		icon = "❌"
+	}
+	text := fmt.Sprintf("%s *%s*", icon, msg.Title)
+	if msg.Text != "" {
+		text += "\n" + msg.Text
+	}
+	if msg.URL != "" {
+		text += "\n" + msg.URL
+	}
+
+	data, err := json.Marshal(&notifyPayload{Text: text, Channel: n.settings.Channel, Username: n.settings.Username})
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	resp, err := n.httpClient.Post(n.settings.WebhookURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("notification webhook returned %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package lib_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestNotifier(t *testing.T) {
+	t.Setenv("GITLAB_NOTIFY_WEBHOOK", "")
+	if lib.NewNotifier(lib.NotifySettings{}) != nil {
+		t.Fatal("notifier created without a webhook URL")
+	}
+
+	var got map[string]string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	n := lib.NewNotifier(lib.NotifySettings{WebhookURL: srv.URL, Channel: "#ci", Username: "gitlab-helper"})
+	err := n.Send(lib.Notification{Title: "sync.go failed", Text: "group/project: 404", Success: false})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got["channel"] != "#ci" || got["username"] != "gitlab-helper" || !strings.HasPrefix(got["text"], "❌ *sync.go failed*\ngroup/project: 404") {
+		t.Errorf("payload = %v", got)
+	}
+
+	status = http.StatusNotFound
+	if err := n.Send(lib.Notification{Title: "x", Success: true}); err == nil {
+		t.Error("expected an error for a non-2xx response")
+	}
+
+	// The environment overrides the settings file
+	t.Setenv("GITLAB_NOTIFY_WEBHOOK", srv.URL)
+	if lib.NewNotifier(lib.NotifySettings{}) == nil {
+		t.Error("GITLAB_NOTIFY_WEBHOOK ignored")
+	}
+}
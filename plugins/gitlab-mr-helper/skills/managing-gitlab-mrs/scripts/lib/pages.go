@@ -0,0 +1,94 @@
+package lib
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Pages is the GitLab Pages site of a project and its settings
+type Pages struct {
+	URL                   string            `json:"url"`
+	IsUniqueDomainEnabled bool              `json:"is_unique_domain_enabled"`
+	ForceHTTPS            bool              `json:"force_https"`
+	Deployments           []PagesDeployment `json:"deployments"`
+}
+
+// PagesDeployment is a deployment currently served by Pages: the main
+// one, and parallel deployments under their path prefixes
+type PagesDeployment struct {
+	CreatedAt     time.Time `json:"created_at"`
+	URL           string    `json:"url"`
+	PathPrefix    string    `json:"path_prefix"`
+	RootDirectory string    `json:"root_directory"`
+}
+
+// LatestDeployment returns the most recent deployment, or nil when there
+// is none
+func (p *Pages) LatestDeployment() *PagesDeployment {
+	var latest *PagesDeployment
+	for i := range p.Deployments {
+		if latest == nil || p.Deployments[i].CreatedAt.After(latest.CreatedAt) {
+			latest = &p.Deployments[i]
+		}
+	}
+	return latest
+}
+
+// GetPages returns the Pages site of a project, or nil when Pages is
+// disabled or nothing was ever deployed. It needs the Maintainer role.
+func (c *Client) GetPages(projectPath string) (*Pages, error) {
+	endpoint := c.apiURL("/projects/%s/pages", url.PathEscape(projectPath))
+	var pages Pages
+	err := c.do("GET", endpoint, nil, &pages, http.StatusOK)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pages, nil
+}
+
+// PagesJobName is the job name that publishes Pages unless the job sets
+// the pages keyword
+const PagesJobName = "pages"
+
+// pagesJobScan is how many recent jobs ListPagesJobs looks through
+const pagesJobScan = 300
+
+// ListPagesJobs lists the recent jobs of a project named name, newest
+// first, looking through its latest jobs only; limit 0 returns all of
+// those found. The pages:deploy step GitLab adds after them is a commit
+// status, not a job, so it is not listed.
+func (c *Client) ListPagesJobs(projectPath, name string, limit int) ([]Job, error) {
+	jobs, err := c.ListProjectJobs(projectPath, &JobListOptions{Limit: pagesJobScan})
+	if err != nil {
+		return nil, err
+	}
+	var found []Job
+	for _, j := range jobs {
+		if j.Name != name {
+			continue
+		}
+		found = append(found, j)
+		if len(found) == limit {
+			break
+		}
+	}
+	return found, nil
+}
+
+// PagesJobFor returns the newest of jobs run for the commit sha, which
+// may be abbreviated, or nil when none was
+func PagesJobFor(jobs []Job, sha string) *Job {
+	for i := range jobs {
+		if p := jobs[i].Pipeline; p != nil && sha != "" && strings.HasPrefix(p.SHA, sha) {
+			return &jobs[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package lib_test
+
+import (
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestPages(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	pages, err := client.GetPages(gitlabtest.NestedProjectPath)
+	if err != nil {
+		t.Fatalf("GetPages: %v", err)
+	}
+	if pages == nil || pages.URL == "" || !pages.ForceHTTPS || pages.LatestDeployment() == nil {
+		t.Fatalf("GetPages = %+v", pages)
+	}
+	if pages, err := client.GetPages(gitlabtest.ProjectPath); pages != nil || err != nil {
+		t.Errorf("GetPages without a site = %+v, %v; want nil", pages, err)
+	}
+
+	jobs, err := client.ListPagesJobs(gitlabtest.NestedProjectPath, lib.PagesJobName, 0)
+	if err != nil {
+		t.Fatalf("ListPagesJobs: %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].Status != "failed" || jobs[1].Status != "success" || jobs[0].Pipeline == nil {
+		t.Fatalf("ListPagesJobs = %+v", jobs)
+	}
+	if jobs, _ := client.ListPagesJobs(gitlabtest.NestedProjectPath, lib.PagesJobName, 1); len(jobs) != 1 {
+		t.Errorf("ListPagesJobs with a limit of 1 returned %d jobs", len(jobs))
+	}
+
+	if j := lib.PagesJobFor(jobs, "d0c5a"); j == nil || j.ID != 3101 {
+		t.Errorf("PagesJobFor(d0c5a) = %+v", j)
+	}
+	if j := lib.PagesJobFor(jobs, "fff"); j != nil {
+		t.Errorf("PagesJobFor(fff) = %+v, want nil", j)
+	}
+}
+
+func TestLatestPagesDeployment(t *testing.T) {
+	now := time.Now()
+	pages := lib.Pages{Deployments: []lib.PagesDeployment{
+		{CreatedAt: now.Add(-time.Hour), PathPrefix: "v1"},
+		{CreatedAt: now},
+		{CreatedAt: now.Add(-2 * time.Hour)},
+	}}
+	if d := pages.LatestDeployment(); d == nil || !d.CreatedAt.Equal(now) {
+		t.Errorf("LatestDeployment = %+v", d)
+	}
+	if d := (&lib.Pages{}).LatestDeployment(); d != nil {
+		t.Errorf("LatestDeployment without deployments = %+v", d)
+	}
+}
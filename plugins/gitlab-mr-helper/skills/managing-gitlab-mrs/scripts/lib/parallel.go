@@ -0,0 +1,52 @@
+package lib
+
+import "sync"
+
+// DefaultConcurrency is how many requests multi-item commands run at once
+const DefaultConcurrency = 4
+
+// MaxConcurrency caps --concurrency to stay clear of GitLab's rate limits
+const MaxConcurrency = 16
+
+// Concurrency returns how many workers ForEach runs. Debug traces are
+// only readable one request at a time, so --debug forces a single worker.
+func (c *Client) Concurrency() int {
+	switch {
+	case c.config.Debug:
+		return 1
+	case c.config.Concurrency <= 0:
+		return DefaultConcurrency
+	case c.config.Concurrency > MaxConcurrency:
+		return MaxConcurrency
+	}
+	return c.config.Concurrency
+}
+
+// ForEach calls fn(i) for every i in [0, n) on a bounded pool of workers
+// and returns the errors by index, nil for the calls that succeeded. fn
+// runs concurrently, so it must only write state belonging to index i.
+func (c *Client) ForEach(n int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	workers := c.Concurrency()
+	if workers > n {
+		workers = n
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				errs[i] = fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,85 @@
+package lib_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestConcurrency(t *testing.T) {
+	tests := []struct {
+		name   string
+		config lib.Config
+		want   int
+	}{
+		{name: "default", want: lib.DefaultConcurrency},
+		{name: "configured", config: lib.Config{Concurrency: 8}, want: 8},
+		{name: "capped", config: lib.Config{Concurrency: 100}, want: lib.MaxConcurrency},
+		{name: "debug", config: lib.Config{Concurrency: 8, Debug: true}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lib.NewClient(&tt.config).Concurrency(); got != tt.want {
+				t.Errorf("Concurrency() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForEach(t *testing.T) {
+	client := lib.NewClient(&lib.Config{Concurrency: 3})
+
+	var running, peak int32
+	boom := errors.New("boom")
+	errs := client.ForEach(10, func(i int) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if i%4 == 0 {
+			return boom
+		}
+		return nil
+	})
+
+	if peak > 3 {
+		t.Errorf("peak concurrency = %d, want at most 3", peak)
+	}
+	for i, err := range errs {
+		if want := i%4 == 0; (err != nil) != want {
+			t.Errorf("errs[%d] = %v", i, err)
+		}
+	}
+	if errs := client.ForEach(0, func(int) error { return boom }); len(errs) != 0 {
+		t.Errorf("ForEach(0) = %v", errs)
+	}
+}
+
+func TestForEachRequests(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	iids := []int{1, 2, 3, 99}
+	titles := make([]string, len(iids))
+	errs := client.ForEach(len(iids), func(i int) error {
+		mr, err := client.GetMR(gitlabtest.ProjectPath, iids[i])
+		if err == nil {
+			titles[i] = mr.Title
+		}
+		return err
+	})
+	if titles[0] != "Add login page" || titles[2] != "Old work" || errs[0] != nil {
+		t.Errorf("titles = %q, errs = %v", titles, errs)
+	}
+	wantExit(t, errs[3], lib.ExitNotFound)
+}
@@ -0,0 +1,234 @@
+package lib
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Pipeline represents a GitLab CI pipeline
+type Pipeline struct {
+	ID        int       `json:"id"`
+	IID       int       `json:"iid"`
+	ProjectID int       `json:"project_id"`
+	Status    string    `json:"status"`
+	Ref       string    `json:"ref"`
+	SHA       string    `json:"sha"`
+	Source    string    `json:"source"`
+	WebURL    string    `json:"web_url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// StartedAt and Duration (seconds) are not set in listings
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Duration  int        `json:"duration,omitempty"`
+}
+
+// Job is a job of a pipeline
+type Job struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	Stage        string    `json:"stage"`
+	Status       string    `json:"status"`
+	AllowFailure bool      `json:"allow_failure"`
+	WebURL       string    `json:"web_url"`
+	Ref          string    `json:"ref"`
+	CreatedAt    time.Time `json:"created_at"`
+	// Pipeline is only set in project-wide job listings
+	Pipeline          *Pipeline     `json:"pipeline,omitempty"`
+	Artifacts         []JobArtifact `json:"artifacts"`
+	ArtifactsExpireAt *time.Time    `json:"artifacts_expire_at"`
+}
+
+// PipelineListOptions holds filters for pipeline listings
+type PipelineListOptions struct {
+	Ref          string
+	Status       string // created, pending, running, success, failed, canceled, ...
+	Source       string // push, merge_request_event, schedule, ...
+	UpdatedAfter time.Time
+	Limit        int // 0 means no limit
+}
+
+func (o *PipelineListOptions) query() url.Values {
+	q := url.Values{}
+	if o.Ref != "" {
+		q.Set("ref", o.Ref)
+	}
+	if o.Status != "" {
+		q.Set("status", o.Status)
+	}
+	if o.Source != "" {
+		q.Set("source", o.Source)
+	}
+	if !o.UpdatedAfter.IsZero() {
+		q.Set("updated_after", o.UpdatedAfter.UTC().Format(time.RFC3339))
+	}
+	return q
+}
+
+// ListPipelines lists a project's pipelines, newest first
+func (c *Client) ListPipelines(projectPath string, opts *PipelineListOptions) ([]Pipeline, error) {
+	if c.config.Offline {
+		return c.cachedPipelines(projectPath, opts)
+	}
+	endpoint := c.apiURL("/projects/%s/pipelines", url.PathEscape(projectPath))
+	return getAll[Pipeline](c, endpoint, opts.query(), opts.Limit)
+}
+
+// GetPipeline gets a single pipeline by ID
+func (c *Client) GetPipeline(projectPath string, pipelineID int) (*Pipeline, error) {
+	endpoint := c.apiURL("/projects/%s/pipelines/%d", url.PathEscape(projectPath), pipelineID)
+
+	var pipeline Pipeline
+	if err := c.do("GET", endpoint, nil, &pipeline, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &pipeline, nil
+}
+
+// PipelineFinished reports whether a pipeline or job with this status has
+// stopped, for good or until someone acts on it (manual)
+func PipelineFinished(status string) bool {
+	switch status {
+	case "success", "failed", "canceled", "skipped", "manual":
+		return true
+	}
+	return false
+}
+
+// TypicalPipelineDuration returns how long the last successful pipelines
+// of ref took (the median of three), or 0 when there are none
+func (c *Client) TypicalPipelineDuration(projectPath, ref string) (time.Duration, error) {
+	pipelines, err := c.ListPipelines(projectPath, &PipelineListOptions{Ref: ref, Status: "success", Limit: 3})
+	if err != nil {
+		return 0, err
+	}
+	var durations []int
+	for _, p := range pipelines {
+		full, err := c.GetPipeline(projectPath, p.ID)
+		if err != nil {
+			return 0, err
+		}
+		if full.Duration > 0 {
+			durations = append(durations, full.Duration)
+		}
+	}
+	if len(durations) == 0 {
+		return 0, nil
+	}
+	slices.Sort(durations)
+	return time.Duration(durations[len(durations)/2]) * time.Second, nil
+}
+
+// PipelineWait returns how long to wait before checking on a running
+// pipeline again (see Poller): half the time it is still expected to need,
+// going by typical (see TypicalPipelineDuration), so checks come quicker
+// as it nears its end, and as soon as allowed once it is overdue. A
+// pipeline waiting for runners, or of unknown length, is checked less and
+// less often.
+func PipelineWait(p *Pipeline, typical time.Duration, now time.Time) time.Duration {
+	if p.Status != "running" || p.StartedAt == nil || typical <= 0 {
+		return PollBackoff
+	}
+	return max(typical-now.Sub(*p.StartedAt), 0) / 2
+}
+
+// PipelineVariable is a CI/CD variable passed to a new pipeline
+type PipelineVariable struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// CreatePipeline runs a new pipeline for ref, with variables (may be nil)
+func (c *Client) CreatePipeline(projectPath, ref string, variables map[string]string) (*Pipeline, error) {
+	endpoint := c.apiURL("/projects/%s/pipeline", url.PathEscape(projectPath))
+	body := struct {
+		Ref       string             `json:"ref"`
+		Variables []PipelineVariable `json:"variables,omitempty"`
+	}{Ref: ref}
+	for key, value := range variables {
+		body.Variables = append(body.Variables, PipelineVariable{Key: key, Value: value})
+	}
+	slices.SortFunc(body.Variables, func(a, b PipelineVariable) int { return strings.Compare(a.Key, b.Key) })
+
+	var pipeline Pipeline
+	if err := c.do("POST", endpoint, body, &pipeline, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &pipeline, nil
+}
+
+// LatestFinishedPipeline returns the newest of the last 20 pipelines of ref
+// that succeeded or failed, or nil when there is none. Running, canceled and
+// skipped pipelines say nothing about the state of the branch.
+func (c *Client) LatestFinishedPipeline(projectPath, ref string) (*Pipeline, error) {
+	pipelines, err := c.ListPipelines(projectPath, &PipelineListOptions{Ref: ref, Limit: 20})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pipelines {
+		if s := pipelines[i].Status; s == "success" || s == "failed" {
+			return &pipelines[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// ListPipelineJobs lists the jobs of a pipeline, only those with the given
+// status (e.g. "failed") unless scope is empty
+func (c *Client) ListPipelineJobs(projectPath string, pipelineID int, scope string) ([]Job, error) {
+	endpoint := c.apiURL("/projects/%s/pipelines/%d/jobs", url.PathEscape(projectPath), pipelineID)
+	q := url.Values{}
+	if scope != "" {
+		q.Set("scope[]", scope)
+	}
+	return getAll[Job](c, endpoint, q, 0)
+}
+
+// GetJob gets a single CI job by ID
+func (c *Client) GetJob(projectPath string, jobID int) (*Job, error) {
+	endpoint := c.apiURL("/projects/%s/jobs/%d", url.PathEscape(projectPath), jobID)
+
+	var job Job
+	if err := c.do("GET", endpoint, nil, &job, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FindPipelineJob returns the job of a pipeline with the given name, the
+// latest attempt when it was retried
+func (c *Client) FindPipelineJob(projectPath string, pipelineID int, name string) (*Job, error) {
+	jobs, err := c.ListPipelineJobs(projectPath, pipelineID, "")
+	if err != nil {
+		return nil, err
+	}
+	var found *Job
+	for i := range jobs {
+		if jobs[i].Name == name && (found == nil || jobs[i].ID > found.ID) {
+			found = &jobs[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: pipeline #%d has no job %q", ErrNotFound, pipelineID, name)
+	}
+	return found, nil
+}
+
+// FindFirstRed finds where a branch broke in its pipelines, newest first:
+// red is the oldest failed pipeline since the newest successful one, green.
+// Pipelines that neither succeeded nor failed are ignored. red is nil when
+// the branch is green, and green is nil when no pipeline succeeded.
+func FindFirstRed(pipelines []Pipeline) (green, red *Pipeline) {
+	for i := range pipelines {
+		switch pipelines[i].Status {
+		case "success":
+			return &pipelines[i], red
+		case "failed":
+			red = &pipelines[i]
+		}
+	}
+	return nil, red
+}
@@ -0,0 +1,250 @@
+package lib_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestListPipelines(t *testing.T) {
+	tests := []struct {
+		name     string
+		project  string
+		opts     lib.PipelineListOptions
+		wantIDs  []int
+		wantExit int
+	}{
+		{name: "newest first", project: gitlabtest.ProjectPath, wantIDs: []int{902, 901, 900}},
+		{name: "by ref", project: gitlabtest.ProjectPath, opts: lib.PipelineListOptions{Ref: "main"}, wantIDs: []int{901, 900}},
+		{name: "by status", project: gitlabtest.ProjectPath, opts: lib.PipelineListOptions{Status: "failed"}, wantIDs: []int{901}},
+		{name: "limit", project: gitlabtest.ProjectPath, opts: lib.PipelineListOptions{Limit: 1}, wantIDs: []int{902}},
+		{name: "unknown project", project: "nope/nope", wantExit: lib.ExitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			pipelines, err := srv.Client().ListPipelines(tt.project, &tt.opts)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("ListPipelines: %v", err)
+			}
+			var got []int
+			for _, p := range pipelines {
+				got = append(got, p.ID)
+			}
+			if !equalInts(got, tt.wantIDs) {
+				t.Errorf("pipelines = %v, want %v", got, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestListPipelinesPaging(t *testing.T) {
+	// Pipelines are paged by offset, following the page numbers
+	srv := gitlabtest.NewServer(t)
+	p := srv.Project(gitlabtest.ProjectPath)
+	for i := 0; i < 150; i++ {
+		p.Pipelines = append(p.Pipelines, lib.Pipeline{ID: 10000 + i, Ref: "main", Status: "success"})
+	}
+
+	pipelines, err := srv.Client().ListPipelines(gitlabtest.ProjectPath, &lib.PipelineListOptions{})
+	if err != nil {
+		t.Fatalf("ListPipelines: %v", err)
+	}
+	seen := make(map[int]bool)
+	for _, pl := range pipelines {
+		seen[pl.ID] = true
+	}
+	if len(pipelines) != 153 || len(seen) != 153 {
+		t.Errorf("got %d pipelines, %d distinct; want 153", len(pipelines), len(seen))
+	}
+	for _, r := range srv.Requests() {
+		if q := r.URL.Query(); q.Has("pagination") || q.Has("order_by") {
+			t.Errorf("pipelines requested with %s; GitLab has no keyset pagination for them", r.URL.RawQuery)
+		}
+	}
+}
+
+func TestGetPipeline(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	p, err := srv.Client().GetPipeline(gitlabtest.ProjectPath, 901)
+	if err != nil {
+		t.Fatalf("GetPipeline: %v", err)
+	}
+	if p.Status != "failed" || p.Ref != "main" {
+		t.Errorf("got %s on %s, want failed on main", p.Status, p.Ref)
+	}
+
+	_, err = srv.Client().GetPipeline(gitlabtest.ProjectPath, 1)
+	wantExit(t, err, lib.ExitNotFound)
+}
+
+func TestGetJob(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	job, err := srv.Client().GetJob(gitlabtest.ProjectPath, 3001)
+	if err != nil {
+		t.Fatalf("GetJob: %v", err)
+	}
+	if job.Name == "" || job.Status != "failed" || job.Pipeline == nil || job.Pipeline.ID != 901 {
+		t.Errorf("got %+v, want a failed job of pipeline 901", job)
+	}
+
+	_, err = srv.Client().GetJob(gitlabtest.ProjectPath, 1)
+	wantExit(t, err, lib.ExitNotFound)
+}
+
+func TestTypicalPipelineDuration(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+	got, err := client.TypicalPipelineDuration(gitlabtest.ProjectPath, "main")
+	if err != nil || got != 10*time.Minute {
+		t.Errorf("main: got %s, %v; want 10m", got, err)
+	}
+
+	// Three successful pipelines: the median wins over an outlier
+	p := srv.Project(gitlabtest.ProjectPath)
+	for i, minutes := range []int{30, 8} {
+		p.Pipelines = append(p.Pipelines, lib.Pipeline{ID: 910 + i, Status: "success", Ref: "main", Duration: minutes * 60})
+	}
+	if got, err = client.TypicalPipelineDuration(gitlabtest.ProjectPath, "main"); err != nil || got != 10*time.Minute {
+		t.Errorf("main with an outlier: got %s, %v; want 10m", got, err)
+	}
+
+	got, err = client.TypicalPipelineDuration(gitlabtest.ProjectPath, "feature/login")
+	if err != nil || got != 0 {
+		t.Errorf("feature/login: got %s, %v; want 0 without a successful pipeline", got, err)
+	}
+}
+
+func TestPipelineWait(t *testing.T) {
+	now := time.Now()
+	started := now.Add(-6 * time.Minute)
+	tests := []struct {
+		name     string
+		pipeline lib.Pipeline
+		typical  time.Duration
+		want     time.Duration
+	}{
+		{"pending", lib.Pipeline{Status: "pending"}, 10 * time.Minute, lib.PollBackoff},
+		{"unknown length", lib.Pipeline{Status: "running", StartedAt: &started}, 0, lib.PollBackoff},
+		{"half of the rest", lib.Pipeline{Status: "running", StartedAt: &started}, 10 * time.Minute, 2 * time.Minute},
+		{"overdue", lib.Pipeline{Status: "running", StartedAt: &started}, 5 * time.Minute, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lib.PipelineWait(&tt.pipeline, tt.typical, now); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreatePipeline(t *testing.T) {
+	tests := []struct {
+		name     string
+		ref      string
+		wantSHA  string
+		wantExit int
+	}{
+		{name: "tag", ref: "v1.0.0", wantSHA: "aaa111"},
+		{name: "unknown ref", ref: "nope", wantExit: lib.ExitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			p, err := srv.Client().CreatePipeline(gitlabtest.ProjectPath, tt.ref, nil)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreatePipeline: %v", err)
+			}
+			if p.Ref != tt.ref || p.SHA != tt.wantSHA || p.Status != "created" {
+				t.Errorf("pipeline = %+v", p)
+			}
+		})
+	}
+}
+
+func TestLatestFinishedPipeline(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	p := srv.Project(gitlabtest.ProjectPath)
+	p.Pipelines = append(p.Pipelines, lib.Pipeline{ID: 903, Status: "running", Ref: "main"})
+
+	tests := []struct {
+		ref    string
+		wantID int
+	}{
+		{ref: "main", wantID: 901},        // the running 903 is skipped
+		{ref: "feature/login", wantID: 0}, // only a running pipeline
+	}
+	for _, tt := range tests {
+		got, err := srv.Client().LatestFinishedPipeline(gitlabtest.ProjectPath, tt.ref)
+		if err != nil {
+			t.Fatalf("LatestFinishedPipeline(%s): %v", tt.ref, err)
+		}
+		if (got == nil && tt.wantID != 0) || (got != nil && got.ID != tt.wantID) {
+			t.Errorf("LatestFinishedPipeline(%s) = %+v, want #%d", tt.ref, got, tt.wantID)
+		}
+	}
+}
+
+func TestListPipelineJobs(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	for scope, want := range map[string][]string{"": {"lint", "unit", "size"}, "failed": {"unit"}} {
+		jobs, err := srv.Client().ListPipelineJobs(gitlabtest.ProjectPath, 901, scope)
+		if err != nil {
+			t.Fatalf("ListPipelineJobs(%q): %v", scope, err)
+		}
+		var names []string
+		for _, j := range jobs {
+			names = append(names, j.Name)
+		}
+		if !equalStrings(names, want) {
+			t.Errorf("ListPipelineJobs(%q) = %v, want %v", scope, names, want)
+		}
+	}
+}
+
+func TestFindFirstRed(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  string // newest first
+		wantGreen int    // index, -1 for none
+		wantRed   int
+	}{
+		{name: "green", statuses: "success failed", wantGreen: 0, wantRed: -1},
+		{name: "broken", statuses: "failed failed success failed", wantGreen: 2, wantRed: 1},
+		{name: "running and canceled ignored", statuses: "running failed canceled success", wantGreen: 3, wantRed: 1},
+		{name: "no green", statuses: "failed failed", wantGreen: -1, wantRed: 1},
+		{name: "empty", statuses: "", wantGreen: -1, wantRed: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pipelines []lib.Pipeline
+			for i, status := range strings.Fields(tt.statuses) {
+				pipelines = append(pipelines, lib.Pipeline{ID: i, Status: status})
+			}
+			green, red := lib.FindFirstRed(pipelines)
+			if pipelineIndex(green) != tt.wantGreen || pipelineIndex(red) != tt.wantRed {
+				t.Errorf("FindFirstRed = green %d, red %d; want %d, %d", pipelineIndex(green), pipelineIndex(red), tt.wantGreen, tt.wantRed)
+			}
+		})
+	}
+}
+
+func pipelineIndex(p *lib.Pipeline) int {
+	if p == nil {
+		return -1
+	}
+	return p.ID
+}
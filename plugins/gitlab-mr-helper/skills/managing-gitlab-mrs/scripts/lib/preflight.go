@@ -0,0 +1,164 @@
+package lib
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Diagnosis explains why a request failed and what to do about it
+type Diagnosis struct {
+	Problem string // e.g. "DNS lookup failed for gitlab.example.com"
+	Hint    string // remediation
+}
+
+// Diagnose classifies a request error into DNS, TLS, connection, gateway
+// and authentication failures. It returns nil for errors it has nothing
+// specific to say about.
+func Diagnose(err error) *Diagnosis {
+	if err == nil {
+		return nil
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return &Diagnosis{
+			Problem: fmt.Sprintf("DNS lookup failed for %s", dnsErr.Name),
+			Hint:    "Check the host name in GITLAB_URL, and your VPN or DNS settings if the instance is internal",
+		}
+	}
+
+	var (
+		unknownCA   x509.UnknownAuthorityError
+		hostnameErr x509.HostnameError
+		invalidCert x509.CertificateInvalidError
+		recordErr   tls.RecordHeaderError
+	)
+	switch {
+	// net/http reports plain HTTP answers to TLS handshakes as a bare string
+	case strings.Contains(err.Error(), "server gave HTTP response to HTTPS client"), errors.As(err, &recordErr):
+		return &Diagnosis{
+			Problem: "TLS handshake failed: the server does not speak HTTPS",
+			Hint:    "Use an http:// GITLAB_URL, or the HTTPS port of the instance",
+		}
+	case errors.As(err, &unknownCA):
+		return &Diagnosis{
+			Problem: "TLS certificate signed by an unknown authority",
+			Hint:    "The instance uses a private CA or self-signed certificate; point SSL_CERT_FILE at the CA bundle",
+		}
+	case errors.As(err, &hostnameErr):
+		return &Diagnosis{
+			Problem: fmt.Sprintf("TLS certificate is not valid for %s", hostnameErr.Host),
+			Hint:    "Use the host name the certificate was issued for in GITLAB_URL",
+		}
+	case errors.As(err, &invalidCert):
+		return &Diagnosis{
+			Problem: "TLS certificate is invalid or expired",
+			Hint:    "Renew the instance's certificate, or check the system clock",
+		}
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return &Diagnosis{
+			Problem: "Connection refused",
+			Hint:    "Check the host and port in GITLAB_URL and that the instance is running",
+		}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return &Diagnosis{
+			Problem: "Request timed out",
+			Hint:    "The instance is slow or unreachable; check your network, VPN and HTTPS_PROXY settings",
+		}
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized:
+			return &Diagnosis{
+				Problem: "Token rejected (401)",
+				Hint:    "The token is invalid, expired or revoked; create a new one or update GITLAB_TOKEN, ~/.netrc or ~/.git-credentials",
+			}
+		case http.StatusForbidden:
+			return &Diagnosis{
+				Problem: "Access denied (403)",
+				Hint:    "The token lacks the api scope or the role needed for this project",
+			}
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return &Diagnosis{
+				Problem: fmt.Sprintf("GitLab instance unavailable (%d)", apiErr.StatusCode),
+				Hint:    "The instance or its proxy is down, restarting or in maintenance; retry later",
+			}
+		}
+	}
+	return nil
+}
+
+// Instance describes a reachable GitLab instance and the token's user
+type Instance struct {
+	URL      string
+	Version  string `json:"version"`
+	Revision string `json:"revision"`
+	User     *User
+	Latency  time.Duration // of the version request
+}
+
+// Preflight checks that the instance is reachable and accepts the token.
+// Failures are diagnosed and returned as *PreflightError.
+func (c *Client) Preflight() (*Instance, error) {
+	inst := &Instance{URL: c.config.URL}
+	if _, err := url.ParseRequestURI(c.config.URL); err != nil {
+		return nil, &PreflightError{Check: "url", Err: err, Diagnosis: &Diagnosis{
+			Problem: fmt.Sprintf("Invalid GITLAB_URL %q", c.config.URL),
+			Hint:    "Set GITLAB_URL to the root of the instance, e.g. https://gitlab.example.com",
+		}}
+	}
+
+	start := time.Now()
+	err := c.do("GET", c.apiURL("/version"), nil, inst, http.StatusOK)
+	inst.Latency = time.Since(start)
+	if err != nil {
+		d := Diagnose(err)
+		var apiErr *APIError
+		if d == nil && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			d = &Diagnosis{
+				Problem: "No GitLab API found at " + c.apiURL(""),
+				Hint:    "Set GITLAB_URL to the root of the instance, without /api/v4",
+			}
+		}
+		return nil, &PreflightError{Check: "version", Err: err, Diagnosis: d}
+	}
+
+	user, err := c.GetCurrentUser()
+	if err != nil {
+		return nil, &PreflightError{Check: "user", Err: err, Diagnosis: Diagnose(err)}
+	}
+	inst.User = user
+	return inst, nil
+}
+
+// PreflightError is a failed Preflight check
+type PreflightError struct {
+	Check     string // url, version or user
+	Err       error
+	Diagnosis *Diagnosis // nil when the failure is not recognized
+}
+
+func (e *PreflightError) Error() string {
+	if e.Diagnosis != nil {
+		return e.Diagnosis.Problem
+	}
+	return e.Err.Error()
+}
+
+func (e *PreflightError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,102 @@
+package lib_test
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestPreflight(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	inst, err := srv.Client().Preflight()
+	if err != nil {
+		t.Fatalf("Preflight: %v", err)
+	}
+	if inst.Version != gitlabtest.Version || inst.User.Username != "alice" {
+		t.Errorf("instance = %+v", inst)
+	}
+}
+
+func TestPreflightFailures(t *testing.T) {
+	tlsSrv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	tlsSrv.Config.ErrorLog = log.New(io.Discard, "", 0) // rejected handshakes
+	tlsSrv.StartTLS()
+	t.Cleanup(tlsSrv.Close)
+	plainSrv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(plainSrv.Close)
+	// A port nothing listens on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedURL := "http://" + ln.Addr().String()
+	ln.Close()
+
+	tests := []struct {
+		name        string
+		url         string
+		token       string
+		status      int // served by the fake for /version
+		wantProblem string
+		wantExit    int
+	}{
+		{name: "invalid url", url: "gitlab.example.com", wantProblem: "Invalid GITLAB_URL", wantExit: lib.ExitError},
+		{name: "connection refused", url: closedURL, wantProblem: "Connection refused", wantExit: lib.ExitError},
+		{name: "unknown CA", url: tlsSrv.URL, wantProblem: "unknown authority", wantExit: lib.ExitError},
+		{name: "plain HTTP", url: strings.Replace(plainSrv.URL, "http:", "https:", 1), wantProblem: "does not speak HTTPS", wantExit: lib.ExitError},
+		{name: "not gitlab", url: plainSrv.URL, wantProblem: "No GitLab API", wantExit: lib.ExitNotFound},
+		{name: "bad gateway", status: http.StatusBadGateway, wantProblem: "unavailable (502)", wantExit: lib.ExitError},
+		{name: "bad token", token: "wrong", wantProblem: "Token rejected", wantExit: lib.ExitAuth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			if tt.status != 0 {
+				srv.Handle("GET /version", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+					w.WriteHeader(tt.status)
+					w.Write([]byte("<html>502 Bad Gateway</html>"))
+				})
+			}
+			config := &lib.Config{URL: srv.URL, Token: gitlabtest.Token}
+			if tt.url != "" {
+				config.URL = tt.url
+			}
+			if tt.token != "" {
+				config.Token = tt.token
+			}
+
+			_, err := lib.NewClient(config).Preflight()
+			var pfErr *lib.PreflightError
+			if !errors.As(err, &pfErr) || pfErr.Diagnosis == nil {
+				t.Fatalf("err = %v, want a diagnosed PreflightError", err)
+			}
+			if !strings.Contains(pfErr.Diagnosis.Problem, tt.wantProblem) || pfErr.Diagnosis.Hint == "" {
+				t.Errorf("diagnosis = %+v, want %q", pfErr.Diagnosis, tt.wantProblem)
+			}
+			wantExit(t, err, tt.wantExit)
+		})
+	}
+}
+
+func TestDiagnoseDNS(t *testing.T) {
+	err := &url.Error{Op: "Get", URL: "https://gitlab.invalid/api/v4/user", Err: &net.OpError{
+		Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "gitlab.invalid", IsNotFound: true},
+	}}
+	d := lib.Diagnose(err)
+	if d == nil || d.Problem != "DNS lookup failed for gitlab.invalid" {
+		t.Errorf("diagnosis = %+v", d)
+	}
+	if d := lib.Diagnose(errors.New("boom")); d != nil {
+		t.Errorf("unrecognized error diagnosed as %+v", d)
+	}
+}
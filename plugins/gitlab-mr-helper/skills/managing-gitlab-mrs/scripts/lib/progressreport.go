@@ -0,0 +1,158 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PipelineFailure is a failed pipeline with its failed jobs
+type PipelineFailure struct {
+	Pipeline Pipeline
+	Jobs     []Job
+}
+
+// ProgressReport is the progress of a milestone or label over a period, as
+// report.go renders it
+type ProgressReport struct {
+	// Scope names what the report covers, e.g. "Milestone v1.2"
+	Scope string
+	// Milestone is set when the scope is one, for its due date
+	Milestone    *Milestone
+	Since, Until time.Time
+	// Issues and MRs are all those of the scope, in any state
+	Issues []Issue
+	MRs    []MergeRequest
+	// BlockedLabel marks blocked issues and MRs; MRs with conflicts are
+	// blocked too
+	BlockedLabel string
+	// Failures are the notable failed pipelines of the period, newest
+	// first
+	Failures []PipelineFailure
+}
+
+// Markdown renders the report: progress, the issues closed in the period,
+// the open ones, the MRs merged in the period, blocked items and pipeline
+// failures. Empty sections say so rather than disappear, so readers know
+// they were checked.
+func (r *ProgressReport) Markdown() string {
+	var b strings.Builder
+	day := func(t time.Time) string { return t.Local().Format("2006-01-02") }
+	fmt.Fprintf(&b, "## Status: %s (%s – %s)\n\n", r.Scope, day(r.Since), day(r.Until))
+
+	var closed, open, blocked []Issue
+	done := 0
+	for _, issue := range r.Issues {
+		switch {
+		case issue.State == "closed":
+			done++
+			if issue.ClosedAt != nil && !issue.ClosedAt.Before(r.Since) {
+				closed = append(closed, issue)
+			}
+		case r.BlockedLabel != "" && ContainsString(issue.Labels, r.BlockedLabel):
+			blocked = append(blocked, issue)
+		default:
+			open = append(open, issue)
+		}
+	}
+	var merged, blockedMRs []MergeRequest
+	for _, mr := range r.MRs {
+		switch {
+		case mr.State == "merged" && mr.MergedAt != nil && !mr.MergedAt.Before(r.Since):
+			merged = append(merged, mr)
+		case mr.State == "opened" && (mr.HasConflicts || r.BlockedLabel != "" && ContainsString(mr.Labels, r.BlockedLabel)):
+			blockedMRs = append(blockedMRs, mr)
+		}
+	}
+
+	progress := fmt.Sprintf("**Progress:** %d of %d issue(s) closed", done, len(r.Issues))
+	if len(r.Issues) > 0 {
+		progress += fmt.Sprintf(" (%d%%)", done*100/len(r.Issues))
+	}
+	if m := r.Milestone; m != nil && !m.DueDate.IsZero() {
+		days := int(m.DueDate.Sub(r.Until).Hours() / 24)
+		switch {
+		case m.State == "closed":
+			progress += fmt.Sprintf(", milestone closed (due %s)", m.DueDate.Format("2006-01-02"))
+		case days < 0:
+			progress += fmt.Sprintf(", **overdue** since %s", m.DueDate.Format("2006-01-02"))
+		default:
+			progress += fmt.Sprintf(", due %s (%d day(s) left)", m.DueDate.Format("2006-01-02"), days)
+		}
+	}
+	fmt.Fprintf(&b, "%s  \n%d closed this period, %d open, %d blocked, %d MR(s) merged\n", progress, len(closed), len(open), len(blocked)+len(blockedMRs), len(merged))
+
+	section := func(title string, empty string, lines []string) {
+		fmt.Fprintf(&b, "\n### %s\n\n", title)
+		if len(lines) == 0 {
+			fmt.Fprintf(&b, "_%s_\n", empty)
+			return
+		}
+		for _, l := range lines {
+			fmt.Fprintf(&b, "- %s\n", l)
+		}
+	}
+
+	sort.Slice(closed, func(i, j int) bool { return closed[i].ClosedAt.Before(*closed[j].ClosedAt) })
+	var lines []string
+	for _, issue := range closed {
+		lines = append(lines, issueLine(&issue))
+	}
+	section("Completed", "Nothing closed this period", lines)
+
+	lines = nil
+	for _, issue := range open {
+		lines = append(lines, issueLine(&issue))
+	}
+	section("Open", "No open issues", lines)
+
+	sort.Slice(merged, func(i, j int) bool { return merged[i].MergedAt.Before(*merged[j].MergedAt) })
+	lines = nil
+	for _, mr := range merged {
+		lines = append(lines, fmt.Sprintf("[!%d](%s) %s — @%s", mr.IID, mr.WebURL, mr.Title, mr.Author.Username))
+	}
+	section("Merged", "No MRs merged this period", lines)
+
+	lines = nil
+	for _, issue := range blocked {
+		lines = append(lines, issueLine(&issue))
+	}
+	for _, mr := range blockedMRs {
+		why := "~" + r.BlockedLabel
+		if mr.HasConflicts {
+			why = "merge conflicts"
+		}
+		lines = append(lines, fmt.Sprintf("[!%d](%s) %s — @%s (%s)", mr.IID, mr.WebURL, mr.Title, mr.Author.Username, why))
+	}
+	section("Blocked", "Nothing blocked", lines)
+
+	lines = nil
+	for _, f := range r.Failures {
+		p := f.Pipeline
+		line := fmt.Sprintf("[Pipeline #%d](%s) on `%s` failed %s", p.ID, p.WebURL, p.Ref, p.UpdatedAt.Local().Format("2006-01-02 15:04"))
+		if len(f.Jobs) > 0 {
+			names := make([]string, len(f.Jobs))
+			for i, j := range f.Jobs {
+				names[i] = fmt.Sprintf("[%s](%s)", j.Name, j.WebURL)
+			}
+			line += ": " + strings.Join(names, ", ")
+		}
+		lines = append(lines, line)
+	}
+	section("Pipeline failures", "No pipeline failures", lines)
+	return b.String()
+}
+
+// issueLine renders an issue as a markdown list item body
+func issueLine(issue *Issue) string {
+	line := fmt.Sprintf("[#%d](%s) %s", issue.IID, issue.WebURL, issue.Title)
+	if len(issue.Assignees) > 0 {
+		names := make([]string, len(issue.Assignees))
+		for i, u := range issue.Assignees {
+			names[i] = "@" + u.Username
+		}
+		line += " — " + strings.Join(names, ", ")
+	}
+	return line
+}
@@ -0,0 +1,59 @@
+package lib_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestProgressReportMarkdown(t *testing.T) {
+	until := time.Date(2024, 3, 8, 12, 0, 0, 0, time.UTC)
+	since := until.AddDate(0, 0, -7)
+	earlier, recent := since.AddDate(0, 0, -3), since.AddDate(0, 0, 2)
+	alice := lib.User{ID: 1, Username: "alice"}
+
+	r := &lib.ProgressReport{
+		Scope:     "Milestone v1.0",
+		Milestone: &lib.Milestone{Title: "v1.0", State: "active", DueDate: lib.Date{Time: until.AddDate(0, 0, 5)}},
+		Since:     since,
+		Until:     until,
+		Issues: []lib.Issue{
+			{IID: 1, Title: "Closed long ago", State: "closed", ClosedAt: &earlier},
+			{IID: 2, Title: "Closed this week", State: "closed", ClosedAt: &recent, Assignees: []lib.User{alice}},
+			{IID: 3, Title: "Still open", State: "opened"},
+			{IID: 4, Title: "Waiting on vendor", State: "opened", Labels: []string{"blocked"}},
+		},
+		MRs: []lib.MergeRequest{
+			{IID: 7, Title: "Merged this week", State: "merged", MergedAt: &recent, Author: alice},
+			{IID: 8, Title: "Merged long ago", State: "merged", MergedAt: &earlier, Author: alice},
+			{IID: 9, Title: "Conflicting", State: "opened", HasConflicts: true, Author: alice},
+		},
+		BlockedLabel: "blocked",
+	}
+
+	md := r.Markdown()
+	for _, want := range []string{
+		"## Status: Milestone v1.0 (2024-03-01 – 2024-03-08)",
+		"**Progress:** 2 of 4 issue(s) closed (50%), due 2024-03-13 (5 day(s) left)",
+		"1 closed this period, 1 open, 2 blocked, 1 MR(s) merged",
+		"### Completed\n\n- [#2]() Closed this week — @alice\n",
+		"### Open\n\n- [#3]() Still open\n",
+		"### Merged\n\n- [!7]() Merged this week — @alice\n",
+		"### Blocked\n\n- [#4]() Waiting on vendor\n- [!9]() Conflicting — @alice (merge conflicts)\n",
+		"### Pipeline failures\n\n_No pipeline failures_\n",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("missing %q in:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "long ago") {
+		t.Errorf("items from before the period listed:\n%s", md)
+	}
+
+	r.Milestone.DueDate = lib.Date{Time: since}
+	if md := r.Markdown(); !strings.Contains(md, "**overdue** since 2024-03-01") {
+		t.Errorf("overdue milestone not flagged:\n%s", md)
+	}
+}
@@ -0,0 +1,198 @@
+package lib
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Label represents a project label
+type Label struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+}
+
+// Branch represents a repository branch
+type Branch struct {
+	Name      string `json:"name"`
+	Default   bool   `json:"default"`
+	Protected bool   `json:"protected"`
+	Merged    bool   `json:"merged"`
+	Commit    Commit `json:"commit"`
+}
+
+// Project is a project listed in a group
+type Project struct {
+	ID                int    `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	WebURL            string `json:"web_url"`
+	Archived          bool   `json:"archived"`
+	// Statistics is only set when requested, e.g. by
+	// ListGroupProjectStatistics
+	Statistics *ProjectStatistics `json:"statistics,omitempty"`
+}
+
+// Access levels used by members, tokens and protected branches
+const (
+	AccessNoOne      = 0
+	AccessGuest      = 10
+	AccessReporter   = 20
+	AccessDeveloper  = 30
+	AccessMaintainer = 40
+	AccessOwner      = 50
+)
+
+var accessLevelNames = map[int]string{
+	AccessNoOne:      "none",
+	AccessGuest:      "guest",
+	AccessReporter:   "reporter",
+	AccessDeveloper:  "developer",
+	AccessMaintainer: "maintainer",
+	AccessOwner:      "owner",
+}
+
+// AccessLevelName names an access level ("developer"), or returns the
+// number for unknown levels
+func AccessLevelName(level int) string {
+	if name, ok := accessLevelNames[level]; ok {
+		return name
+	}
+	return strconv.Itoa(level)
+}
+
+// ParseAccessLevel accepts an access level name or number
+func ParseAccessLevel(s string) (int, error) {
+	for level, name := range accessLevelNames {
+		if strings.EqualFold(s, name) {
+			return level, nil
+		}
+	}
+	if level, err := strconv.Atoi(s); err == nil {
+		return level, nil
+	}
+	return 0, fmt.Errorf("invalid access level %q (expected guest, reporter, developer, maintainer or owner)", s)
+}
+
+// Member is a project member with its access level (10 guest … 50 owner)
+type Member struct {
+	User
+	State       string `json:"state"`
+	AccessLevel int    `json:"access_level"`
+}
+
+// ListLabels lists the labels available in a project, including inherited
+// group labels
+func (c *Client) ListLabels(projectPath string) ([]Label, error) {
+	endpoint := c.apiURL("/projects/%s/labels", url.PathEscape(projectPath))
+	labels, err := memoize(c, endpoint, func() ([]Label, error) {
+		return getAll[Label](c, endpoint, nil, 0)
+	})
+	return slices.Clone(labels), err
+}
+
+// ListBranches lists repository branches, optionally filtered by a search term
+func (c *Client) ListBranches(projectPath, search string) ([]Branch, error) {
+	endpoint := c.apiURL("/projects/%s/repository/branches", url.PathEscape(projectPath))
+	query := url.Values{}
+	if search != "" {
+		query.Set("search", search)
+	}
+	return getAll[Branch](c, endpoint, query, 0)
+}
+
+// ListGroupProjects lists the projects of a group and its subgroups,
+// skipping archived ones
+func (c *Client) ListGroupProjects(group string) ([]Project, error) {
+	endpoint := c.apiURL("/groups/%s/projects", url.PathEscape(group))
+	query := url.Values{}
+	query.Set("include_subgroups", "true")
+	query.Set("archived", "false")
+	return getAll[Project](c, endpoint, query, 0)
+}
+
+// DefaultBranch returns the name of the project's default branch
+func (c *Client) DefaultBranch(projectPath string) (string, error) {
+	return memoize(c, "default-branch:"+projectPath, func() (string, error) {
+		branches, err := c.ListBranches(projectPath, "")
+		if err != nil {
+			return "", err
+		}
+		for _, b := range branches {
+			if b.Default {
+				return b.Name, nil
+			}
+		}
+		return "", fmt.Errorf("%w: project has no default branch", ErrNotFound)
+	})
+}
+
+// CreateBranch creates a branch from ref (a branch, tag or commit SHA)
+func (c *Client) CreateBranch(projectPath, branch, ref string) (*Branch, error) {
+	endpoint := c.apiURL("/projects/%s/repository/branches", url.PathEscape(projectPath))
+	body := map[string]string{"branch": branch, "ref": ref}
+
+	var b Branch
+	if err := c.do("POST", endpoint, body, &b, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// DeleteBranch deletes a branch
+func (c *Client) DeleteBranch(projectPath, branch string) error {
+	endpoint := c.apiURL("/projects/%s/repository/branches/%s", url.PathEscape(projectPath), url.PathEscape(branch))
+	return c.do("DELETE", endpoint, nil, nil, http.StatusNoContent)
+}
+
+// ProtectBranch protects a branch, allowing pushes and merges from the given
+// access levels and up. GitLab answers 409 when it is already protected.
+func (c *Client) ProtectBranch(projectPath, branch string, pushLevel, mergeLevel int) error {
+	return c.protectBranch(projectPath, branch, pushLevel, mergeLevel, false)
+}
+
+// protectBranch is ProtectBranch, also allowing force pushes when
+// allowForcePush
+func (c *Client) protectBranch(projectPath, branch string, pushLevel, mergeLevel int, allowForcePush bool) error {
+	endpoint := c.apiURL("/projects/%s/protected_branches", url.PathEscape(projectPath))
+	body := map[string]interface{}{"name": branch, "push_access_level": pushLevel, "merge_access_level": mergeLevel, "allow_force_push": allowForcePush}
+	return c.do("POST", endpoint, body, nil, http.StatusCreated)
+}
+
+// ListProjectMembers lists project members, including inherited ones
+func (c *Client) ListProjectMembers(projectPath string) ([]Member, error) {
+	endpoint := c.apiURL("/projects/%s/members/all", url.PathEscape(projectPath))
+	members, err := memoize(c, endpoint, func() ([]Member, error) {
+		return getAll[Member](c, endpoint, nil, 0)
+	})
+	return slices.Clone(members), err
+}
+
+// MemberIDs maps usernames (with or without "@") to the user IDs of project
+// members, in order. Usernames that are not members are returned in unknown.
+func (c *Client) MemberIDs(projectPath string, usernames []string) (ids []int, unknown []string, err error) {
+	if len(usernames) == 0 {
+		return nil, nil, nil
+	}
+	members, err := c.ListProjectMembers(projectPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	byName := make(map[string]int)
+	for _, m := range members {
+		byName[m.Username] = m.ID
+	}
+	for _, u := range usernames {
+		id, ok := byName[strings.TrimPrefix(u, "@")]
+		if !ok {
+			unknown = append(unknown, u)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, unknown, nil
+}
@@ -0,0 +1,184 @@
+package lib_test
+
+import (
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestListLabels(t *testing.T) {
+	tests := []struct {
+		name     string
+		project  string
+		want     []string
+		wantExit int
+	}{
+		{name: "project labels", project: gitlabtest.ProjectPath, want: []string{"backend", "bug", "frontend"}},
+		{name: "no labels", project: gitlabtest.NestedProjectPath},
+		{name: "unknown project", project: "nope/nope", wantExit: lib.ExitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			labels, err := srv.Client().ListLabels(tt.project)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("ListLabels: %v", err)
+			}
+			var got []string
+			for _, l := range labels {
+				got = append(got, l.Name)
+			}
+			if !equalStrings(got, tt.want) {
+				t.Errorf("labels = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListBranches(t *testing.T) {
+	tests := []struct {
+		name    string
+		search  string
+		want    []string
+		wantDef string
+	}{
+		{name: "all", want: []string{"main", "develop", "feature/login", "fix/crash"}, wantDef: "main"},
+		{name: "search", search: "fix", want: []string{"fix/crash"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			branches, err := srv.Client().ListBranches(gitlabtest.ProjectPath, tt.search)
+			if err != nil {
+				t.Fatalf("ListBranches: %v", err)
+			}
+			var got []string
+			def := ""
+			for _, b := range branches {
+				got = append(got, b.Name)
+				if b.Default {
+					def = b.Name
+				}
+			}
+			if !equalStrings(got, tt.want) || def != tt.wantDef {
+				t.Errorf("branches = %v (default %q), want %v (default %q)", got, def, tt.want, tt.wantDef)
+			}
+		})
+	}
+}
+
+func TestDefaultBranch(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	if got, err := srv.Client().DefaultBranch(gitlabtest.ProjectPath); err != nil || got != "main" {
+		t.Errorf("DefaultBranch = %q, %v; want main", got, err)
+	}
+	srv.Project(gitlabtest.ProjectPath).Branches = nil
+	_, err := srv.Client().DefaultBranch(gitlabtest.ProjectPath)
+	wantExit(t, err, lib.ExitNotFound)
+}
+
+func TestListProjectMembers(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	members, err := srv.Client().ListProjectMembers(gitlabtest.ProjectPath)
+	if err != nil {
+		t.Fatalf("ListProjectMembers: %v", err)
+	}
+	if len(members) != 2 || members[0].Username != "alice" || members[0].AccessLevel != 50 || members[1].ID != gitlabtest.Bob.ID {
+		t.Errorf("members = %+v", members)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCreateAndDeleteBranch(t *testing.T) {
+	tests := []struct {
+		name     string
+		branch   string
+		ref      string
+		wantExit int
+	}{
+		{name: "from branch", branch: "revert-mr-3", ref: "main"},
+		{name: "already exists", branch: "develop", ref: "main", wantExit: lib.ExitError},
+		{name: "unknown ref", branch: "new", ref: "nope", wantExit: lib.ExitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			client := srv.Client()
+			b, err := client.CreateBranch(gitlabtest.ProjectPath, tt.branch, tt.ref)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateBranch: %v", err)
+			}
+			if b.Name != tt.branch {
+				t.Errorf("branch = %q, want %q", b.Name, tt.branch)
+			}
+			if err := client.DeleteBranch(gitlabtest.ProjectPath, tt.branch); err != nil {
+				t.Fatalf("DeleteBranch: %v", err)
+			}
+			wantExit(t, client.DeleteBranch(gitlabtest.ProjectPath, tt.branch), lib.ExitNotFound)
+		})
+	}
+}
+
+func TestProtectBranch(t *testing.T) {
+	tests := []struct {
+		name     string
+		branch   string
+		wantExit int
+	}{
+		{name: "unprotected", branch: "feature/login"},
+		{name: "already protected", branch: "main", wantExit: lib.ExitConflict},
+		{name: "unknown branch", branch: "nope", wantExit: lib.ExitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gitlabtest.NewServer(t)
+			err := srv.Client().ProtectBranch(gitlabtest.ProjectPath, tt.branch, lib.AccessNoOne, lib.AccessMaintainer)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProtectBranch: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseAccessLevel(t *testing.T) {
+	tests := map[string]int{"developer": lib.AccessDeveloper, "Maintainer": lib.AccessMaintainer, "20": lib.AccessReporter}
+	for in, want := range tests {
+		if got, err := lib.ParseAccessLevel(in); err != nil || got != want {
+			t.Errorf("ParseAccessLevel(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	if _, err := lib.ParseAccessLevel("admin"); err == nil {
+		t.Error("ParseAccessLevel(admin): want error")
+	}
+	if got := lib.AccessLevelName(lib.AccessOwner); got != "owner" {
+		t.Errorf("AccessLevelName(50) = %q", got)
+	}
+}
@@ -0,0 +1,194 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Export and import states of a project; exports go through queued,
+// started (or regeneration_in_progress) to finished, imports through
+// scheduled and started
+const (
+	TransferNone      = "none"
+	TransferScheduled = "scheduled"
+	TransferQueued    = "queued"
+	TransferStarted   = "started"
+	TransferFinished  = "finished"
+	TransferFailed    = "failed"
+)
+
+// ProjectExport is the export state of a project
+type ProjectExport struct {
+	ID                int    `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	ExportStatus      string `json:"export_status"`
+}
+
+// ProjectImport is the import state of a project
+type ProjectImport struct {
+	ID                int    `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	ImportStatus      string `json:"import_status"`
+	ImportError       string `json:"import_error"`
+}
+
+// ImportProjectRequest places an imported project
+type ImportProjectRequest struct {
+	Namespace string // group or user path (default: the token's user)
+	Path      string // required
+	Name      string // default: Path
+	// Overwrite replaces an existing project at the same path
+	Overwrite bool
+}
+
+// transferClient returns a client without the request timeout, for archive
+// downloads and uploads that take longer than API calls
+func (c *Client) transferClient() *Client {
+	return &Client{config: c.config, httpClient: &http.Client{Transport: c.httpClient.Transport}}
+}
+
+// ExportProject schedules an export of a project, replacing the previous
+// archive. Wait for it with WaitProjectExport.
+func (c *Client) ExportProject(projectPath string) error {
+	endpoint := c.apiURL("/projects/%s/export", url.PathEscape(projectPath))
+	return c.do("POST", endpoint, nil, nil, http.StatusAccepted)
+}
+
+// GetProjectExport returns the export state of a project
+func (c *Client) GetProjectExport(projectPath string) (*ProjectExport, error) {
+	endpoint := c.apiURL("/projects/%s/export", url.PathEscape(projectPath))
+	var export ProjectExport
+	if err := c.do("GET", endpoint, nil, &export, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// WaitProjectExport polls a project until its export has finished or
+// failed
+func (c *Client) WaitProjectExport(projectPath string, interval, timeout time.Duration) error {
+	err := Poll(interval, timeout, func() (bool, error) {
+		export, err := c.GetProjectExport(projectPath)
+		if err != nil {
+			return false, err
+		}
+		switch export.ExportStatus {
+		case TransferFinished:
+			return true, nil
+		case TransferFailed:
+			return false, fmt.Errorf("export failed")
+		case TransferNone:
+			return false, fmt.Errorf("%w: no export was scheduled", ErrNotFound)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("export of %s: %w", projectPath, err)
+	}
+	return nil
+}
+
+// DownloadProjectExport streams the finished export archive (.tar.gz) of
+// a project. The caller must close it.
+func (c *Client) DownloadProjectExport(projectPath string) (io.ReadCloser, error) {
+	endpoint := c.apiURL("/projects/%s/export/download", url.PathEscape(projectPath))
+	resp, err := c.transferClient().send("GET", endpoint, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// SaveProjectExport downloads the finished export archive of a project to
+// path, resuming interrupted transfers
+func (c *Client) SaveProjectExport(projectPath, path string, opts *DownloadOptions) (*Download, error) {
+	endpoint := c.apiURL("/projects/%s/export/download", url.PathEscape(projectPath))
+	return c.downloadFile(endpoint, path, opts)
+}
+
+// ImportProject creates a project from an export archive, streamed from
+// archive. The import runs in the background; wait for it with
+// WaitProjectImport.
+func (c *Client) ImportProject(req *ImportProjectRequest, archive io.Reader) (*ProjectImport, error) {
+	endpoint := c.apiURL("/projects/import")
+	fields := map[string]string{"path": req.Path}
+	if req.Namespace != "" {
+		fields["namespace"] = req.Namespace
+	}
+	if req.Name != "" {
+		fields["name"] = req.Name
+	}
+	if req.Overwrite {
+		fields["overwrite"] = "true"
+	}
+
+	// Stream the archive instead of holding it in memory
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		for k, v := range fields {
+			if err := mw.WriteField(k, v); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		part, err := mw.CreateFormFile("file", req.Path+"_export.tar.gz")
+		if err == nil {
+			_, err = io.Copy(part, archive)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	resp, err := c.transferClient().sendRaw("POST", endpoint, mw.FormDataContentType(), pr, http.StatusCreated)
+	pr.Close()
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var imp ProjectImport
+	if err := json.NewDecoder(capReader(resp.Body, MaxJSONBytes)).Decode(&imp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &imp, nil
+}
+
+// GetProjectImport returns the import state of a project
+func (c *Client) GetProjectImport(projectPath string) (*ProjectImport, error) {
+	endpoint := c.apiURL("/projects/%s/import", url.PathEscape(projectPath))
+	var imp ProjectImport
+	if err := c.do("GET", endpoint, nil, &imp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
+
+// WaitProjectImport polls a project until its import has finished. A
+// failed import is an error carrying GitLab's import_error.
+func (c *Client) WaitProjectImport(projectPath string, interval, timeout time.Duration) (*ProjectImport, error) {
+	var imp *ProjectImport
+	err := Poll(interval, timeout, func() (bool, error) {
+		var err error
+		if imp, err = c.GetProjectImport(projectPath); err != nil {
+			return false, err
+		}
+		switch imp.ImportStatus {
+		case TransferFinished, TransferNone:
+			return true, nil
+		case TransferFailed:
+			return false, fmt.Errorf("import failed: %s", imp.ImportError)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("import of %s: %w", projectPath, err)
+	}
+	return imp, nil
+}
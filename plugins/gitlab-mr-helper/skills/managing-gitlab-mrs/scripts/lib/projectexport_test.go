@@ -0,0 +1,64 @@
+package lib_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestExportImportProject(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	if err := client.WaitProjectExport(gitlabtest.ProjectPath, time.Millisecond, time.Second); err == nil {
+		t.Error("waiting without an export succeeded")
+	}
+	if err := client.ExportProject(gitlabtest.ProjectPath); err != nil {
+		t.Fatalf("ExportProject: %v", err)
+	}
+	if _, err := client.DownloadProjectExport(gitlabtest.ProjectPath); err == nil {
+		t.Error("download of an unfinished export succeeded")
+	}
+	if err := client.WaitProjectExport(gitlabtest.ProjectPath, time.Millisecond, time.Second); err != nil {
+		t.Fatalf("WaitProjectExport: %v", err)
+	}
+	body, err := client.DownloadProjectExport(gitlabtest.ProjectPath)
+	if err != nil {
+		t.Fatalf("DownloadProjectExport: %v", err)
+	}
+	archive, _ := io.ReadAll(body)
+	body.Close()
+
+	req := &lib.ImportProjectRequest{Namespace: "archive", Path: "project"}
+	imp, err := client.ImportProject(req, strings.NewReader(string(archive)))
+	if err != nil {
+		t.Fatalf("ImportProject: %v", err)
+	}
+	if imp.PathWithNamespace != "archive/project" || imp.ImportStatus != lib.TransferScheduled {
+		t.Errorf("import = %+v", imp)
+	}
+	imp, err = client.WaitProjectImport("archive/project", time.Millisecond, time.Second)
+	if err != nil {
+		t.Fatalf("WaitProjectImport: %v", err)
+	}
+	if imp.ImportStatus != lib.TransferFinished || srv.Project("archive/project").Archive != string(archive) {
+		t.Errorf("import = %+v", imp)
+	}
+
+	// An existing path needs Overwrite
+	if _, err := client.ImportProject(req, strings.NewReader(string(archive))); err == nil {
+		t.Error("import over an existing project succeeded")
+	}
+	req.Overwrite = true
+	if _, err := client.ImportProject(req, strings.NewReader("not an export")); err != nil {
+		t.Fatalf("ImportProject with Overwrite: %v", err)
+	}
+	_, err = client.WaitProjectImport("archive/project", time.Millisecond, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "not a valid project export") {
+		t.Errorf("WaitProjectImport of a bad archive: %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ProjectMeta is what describes a project in listings and search: its
+// description, topics, avatar and visibility
+type ProjectMeta struct {
+	ID                int      `json:"id"`
+	PathWithNamespace string   `json:"path_with_namespace"`
+	WebURL            string   `json:"web_url"`
+	Description       string   `json:"description"`
+	Topics            []string `json:"topics"`
+	AvatarURL         string   `json:"avatar_url"` // empty without an avatar
+	Visibility        string   `json:"visibility"`
+}
+
+// Project visibility levels
+const (
+	VisibilityPrivate  = "private"
+	VisibilityInternal = "internal"
+	VisibilityPublic   = "public"
+)
+
+// GetProjectMeta gets the description, topics, avatar and visibility of a
+// project
+func (c *Client) GetProjectMeta(projectPath string) (*ProjectMeta, error) {
+	endpoint := c.apiURL("/projects/%s", url.PathEscape(projectPath))
+	var m ProjectMeta
+	if err := c.do("GET", endpoint, nil, &m, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// SetProjectAvatar uploads an image as the avatar of a project
+func (c *Client) SetProjectAvatar(projectPath, name string, data io.Reader) error {
+	endpoint := c.apiURL("/projects/%s", url.PathEscape(projectPath))
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("avatar", name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, data); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	resp, err := c.sendRaw("PUT", endpoint, mw.FormDataContentType(), &body, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// projectAvatarSum returns the SHA256 of a project's avatar, or nil when
+// it has none. Instances older than GitLab 16.9 cannot serve it, which
+// also returns nil.
+func (c *Client) projectAvatarSum(projectPath string) ([]byte, error) {
+	endpoint := c.apiURL("/projects/%s/avatar", url.PathEscape(projectPath))
+	resp, err := c.send("GET", endpoint, nil, http.StatusOK)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// ProjectMetaTemplate is the metadata to give projects; zero fields are
+// left alone
+type ProjectMetaTemplate struct {
+	Description *string
+	// Topics replaces every topic when not nil; AddTopics and RemoveTopics
+	// change some, keeping the others
+	Topics                  []string
+	AddTopics, RemoveTopics []string
+	Visibility              string
+	// Avatar is the path of an image file to use as avatar
+	Avatar string
+}
+
+// DiffProjectMeta compares a project with the template and returns the
+// changes that would make it match, for ApplySettings. Topics are compared
+// ignoring their order, and the avatar by content.
+func (c *Client) DiffProjectMeta(projectPath string, t *ProjectMetaTemplate) ([]SettingChange, error) {
+	m, err := c.GetProjectMeta(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	var changes []SettingChange
+	if t.Description != nil && *t.Description != m.Description {
+		changes = append(changes, projectChange("description", m.Description, *t.Description))
+	}
+
+	if topics := t.topics(m.Topics); !sameTopics(topics, m.Topics) {
+		changes = append(changes, SettingChange{Setting: "topics", From: strings.Join(m.Topics, ", "), To: strings.Join(topics, ", "), apply: func(c *Client, projectPath string) error {
+			return c.UpdateProjectSettings(projectPath, map[string]interface{}{"topics": topics})
+		}})
+	}
+
+	if t.Visibility != "" && t.Visibility != m.Visibility {
+		changes = append(changes, projectChange("visibility", m.Visibility, t.Visibility))
+	}
+
+	if t.Avatar != "" {
+		data, err := os.ReadFile(t.Avatar)
+		if err != nil {
+			return nil, err
+		}
+		var current []byte
+		if m.AvatarURL != "" {
+			if current, err = c.projectAvatarSum(projectPath); err != nil {
+				return nil, err
+			}
+		}
+		if sum := sha256.Sum256(data); !bytes.Equal(current, sum[:]) {
+			name := filepath.Base(t.Avatar)
+			changes = append(changes, SettingChange{Setting: "avatar", From: m.AvatarURL, To: name, apply: func(c *Client, projectPath string) error {
+				return c.SetProjectAvatar(projectPath, name, bytes.NewReader(data))
+			}})
+		}
+	}
+	return changes, nil
+}
+
+// topics returns the topics a project with current ones gets
+func (t *ProjectMetaTemplate) topics(current []string) []string {
+	topics := []string{}
+	if t.Topics != nil {
+		topics = append(topics, t.Topics...)
+	} else {
+		topics = append(topics, current...)
+	}
+	for _, add := range t.AddTopics {
+		if !slices.Contains(topics, add) {
+			topics = append(topics, add)
+		}
+	}
+	return slices.DeleteFunc(topics, func(topic string) bool {
+		return slices.Contains(t.RemoveTopics, topic)
+	})
+}
+
+func sameTopics(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
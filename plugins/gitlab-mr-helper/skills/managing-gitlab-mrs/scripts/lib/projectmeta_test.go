@@ -0,0 +1,63 @@
+package lib_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestDiffProjectMeta(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	m, err := client.GetProjectMeta(gitlabtest.ProjectPath)
+	if err != nil {
+		t.Fatalf("GetProjectMeta: %v", err)
+	}
+	if m.Description != "The main project" || !slices.Equal(m.Topics, []string{"go", "backend"}) || m.Visibility != lib.VisibilityInternal || m.AvatarURL != "" {
+		t.Fatalf("GetProjectMeta = %+v", m)
+	}
+
+	avatar := filepath.Join(t.TempDir(), "logo.png")
+	if err := os.WriteFile(avatar, []byte("png data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	description := "Login service"
+	tmpl := &lib.ProjectMetaTemplate{Description: &description, AddTopics: []string{"auth", "go"}, RemoveTopics: []string{"backend"},
+		Visibility: lib.VisibilityPrivate, Avatar: avatar}
+	changes, err := client.DiffProjectMeta(gitlabtest.ProjectPath, tmpl)
+	if err != nil {
+		t.Fatalf("DiffProjectMeta: %v", err)
+	}
+	var settings []string
+	for _, ch := range changes {
+		settings = append(settings, ch.Setting)
+	}
+	if !slices.Equal(settings, []string{"description", "topics", "visibility", "avatar"}) || changes[1].To != "go, auth" {
+		t.Fatalf("DiffProjectMeta = %+v", changes)
+	}
+	if err := client.ApplySettings(gitlabtest.ProjectPath, changes); err != nil {
+		t.Fatalf("ApplySettings: %v", err)
+	}
+
+	m, err = client.GetProjectMeta(gitlabtest.ProjectPath)
+	if err != nil || m.Description != description || !slices.Equal(m.Topics, []string{"go", "auth"}) || m.Visibility != lib.VisibilityPrivate || m.AvatarURL == "" {
+		t.Fatalf("after applying: %+v, %v", m, err)
+	}
+
+	// Applied metadata, the same topics in another order and the same
+	// avatar need no change
+	tmpl.Topics, tmpl.AddTopics, tmpl.RemoveTopics = []string{"auth", "go"}, nil, nil
+	if changes, err := client.DiffProjectMeta(gitlabtest.ProjectPath, tmpl); err != nil || len(changes) != 0 {
+		t.Errorf("DiffProjectMeta after applying = %+v, %v; want none", changes, err)
+	}
+
+	tmpl = &lib.ProjectMetaTemplate{Topics: []string{}}
+	if changes, err := client.DiffProjectMeta(gitlabtest.ProjectPath, tmpl); err != nil || len(changes) != 1 || changes[0].To != "" {
+		t.Errorf("DiffProjectMeta clearing topics = %+v, %v", changes, err)
+	}
+}
@@ -0,0 +1,231 @@
+package lib
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+)
+
+// ProjectSettings are the merge settings of a project
+type ProjectSettings struct {
+	ID                                        int    `json:"id"`
+	PathWithNamespace                         string `json:"path_with_namespace"`
+	DefaultBranch                             string `json:"default_branch"`
+	MergeMethod                               string `json:"merge_method"` // merge, rebase_merge or ff
+	OnlyAllowMergeIfPipelineSucceeds          bool   `json:"only_allow_merge_if_pipeline_succeeds"`
+	OnlyAllowMergeIfAllDiscussionsAreResolved bool   `json:"only_allow_merge_if_all_discussions_are_resolved"`
+	RemoveSourceBranchAfterMerge              bool   `json:"remove_source_branch_after_merge"`
+	// The templates of merge and squash commit messages, empty for
+	// GitLab's defaults, and the default description of new MRs (Premium)
+	MergeCommitTemplate   string `json:"merge_commit_template"`
+	SquashCommitTemplate  string `json:"squash_commit_template"`
+	MergeRequestsTemplate string `json:"merge_requests_template"`
+}
+
+// CommitTemplateMaxLength is the longest merge or squash commit template
+// GitLab accepts
+const CommitTemplateMaxLength = 500
+
+// CommitTemplateVariables are the %{variables} GitLab replaces in merge
+// and squash commit templates
+var CommitTemplateVariables = []string{
+	"source_branch", "target_branch", "title", "issues", "description", "reference", "local_reference",
+	"source_project_id", "first_commit", "first_multiline_commit", "first_multiline_commit_description",
+	"url", "reviewed_by", "approved_by", "merged_by", "merge_request_author", "co_authored_by", "all_commits",
+}
+
+var templateVariable = regexp.MustCompile(`%\{([^}]*)\}`)
+
+// UnknownTemplateVariables returns the variables of a commit template that
+// GitLab does not know, and would leave as they are
+func UnknownTemplateVariables(template string) []string {
+	var unknown []string
+	for _, m := range templateVariable.FindAllStringSubmatch(template, -1) {
+		if !slices.Contains(CommitTemplateVariables, m[1]) && !slices.Contains(unknown, m[1]) {
+			unknown = append(unknown, m[1])
+		}
+	}
+	return unknown
+}
+
+// ApprovalSettings are the project-wide MR approval settings (Premium)
+type ApprovalSettings struct {
+	ResetApprovalsOnPush                      bool `json:"reset_approvals_on_push"`
+	MergeRequestsAuthorApproval               bool `json:"merge_requests_author_approval"`
+	DisableOverridingApproversPerMergeRequest bool `json:"disable_overriding_approvers_per_merge_request"`
+}
+
+// ApprovalRule is a project approval rule. The any_approver rule counts
+// approvals from any eligible member.
+type ApprovalRule struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	RuleType          string `json:"rule_type"` // any_approver, regular, ...
+	ApprovalsRequired int    `json:"approvals_required"`
+}
+
+// BranchAccessLevel is one entry of who may push to or merge into a
+// protected branch: a role, or a specific user or group
+type BranchAccessLevel struct {
+	AccessLevel int    `json:"access_level"`
+	Description string `json:"access_level_description"`
+	UserID      *int   `json:"user_id"`
+	GroupID     *int   `json:"group_id"`
+}
+
+// ProtectedBranch is a protected branch name or wildcard
+type ProtectedBranch struct {
+	ID                int                 `json:"id"`
+	Name              string              `json:"name"`
+	PushAccessLevels  []BranchAccessLevel `json:"push_access_levels"`
+	MergeAccessLevels []BranchAccessLevel `json:"merge_access_levels"`
+	AllowForcePush    bool                `json:"allow_force_push"`
+}
+
+// PushLevel is the lowest role allowed to push, AccessNoOne when no role
+// is
+func (b *ProtectedBranch) PushLevel() int {
+	return roleLevel(b.PushAccessLevels)
+}
+
+// MergeLevel is the lowest role allowed to merge, AccessNoOne when no
+// role is
+func (b *ProtectedBranch) MergeLevel() int {
+	return roleLevel(b.MergeAccessLevels)
+}
+
+// RoleLevels returns the push and merge levels, and whether they say it
+// all: ok is false when a user or group is allowed too, or several roles
+// are, which one level per list cannot express
+func (b *ProtectedBranch) RoleLevels() (push, merge int, ok bool) {
+	for _, levels := range [][]BranchAccessLevel{b.PushAccessLevels, b.MergeAccessLevels} {
+		if len(levels) > 1 || len(levels) == 1 && (levels[0].UserID != nil || levels[0].GroupID != nil) {
+			return 0, 0, false
+		}
+	}
+	return b.PushLevel(), b.MergeLevel(), true
+}
+
+func roleLevel(levels []BranchAccessLevel) int {
+	level := -1
+	for _, l := range levels {
+		if l.UserID == nil && l.GroupID == nil && (level < 0 || l.AccessLevel < level) {
+			level = l.AccessLevel
+		}
+	}
+	if level < 0 {
+		return AccessNoOne
+	}
+	return level
+}
+
+// Variable is a project CI/CD variable
+type Variable struct {
+	Key              string `json:"key"`
+	Value            string `json:"value"`
+	VariableType     string `json:"variable_type,omitempty"` // env_var or file
+	Protected        bool   `json:"protected"`
+	Masked           bool   `json:"masked"`
+	EnvironmentScope string `json:"environment_scope,omitempty"`
+}
+
+// GetProjectSettings gets the merge settings of a project
+func (c *Client) GetProjectSettings(projectPath string) (*ProjectSettings, error) {
+	endpoint := c.apiURL("/projects/%s", url.PathEscape(projectPath))
+	s, err := memoize(c, endpoint, func() (ProjectSettings, error) {
+		var s ProjectSettings
+		err := c.do("GET", endpoint, nil, &s, http.StatusOK)
+		return s, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// UpdateProjectSettings changes project attributes, e.g. {"merge_method":
+// "ff"}
+func (c *Client) UpdateProjectSettings(projectPath string, changes map[string]interface{}) error {
+	endpoint := c.apiURL("/projects/%s", url.PathEscape(projectPath))
+	return c.do("PUT", endpoint, changes, nil, http.StatusOK)
+}
+
+// GetApprovalSettings gets the project-wide approval settings
+func (c *Client) GetApprovalSettings(projectPath string) (*ApprovalSettings, error) {
+	endpoint := c.apiURL("/projects/%s/approvals", url.PathEscape(projectPath))
+	var s ApprovalSettings
+	if err := c.do("GET", endpoint, nil, &s, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// UpdateApprovalSettings changes approval settings, e.g.
+// {"reset_approvals_on_push": true}
+func (c *Client) UpdateApprovalSettings(projectPath string, changes map[string]interface{}) error {
+	endpoint := c.apiURL("/projects/%s/approvals", url.PathEscape(projectPath))
+	return c.do("POST", endpoint, changes, nil, http.StatusCreated)
+}
+
+// ListApprovalRules lists the approval rules of a project
+func (c *Client) ListApprovalRules(projectPath string) ([]ApprovalRule, error) {
+	endpoint := c.apiURL("/projects/%s/approval_rules", url.PathEscape(projectPath))
+	return getAll[ApprovalRule](c, endpoint, nil, 0)
+}
+
+// CreateApprovalRule creates an approval rule; rule.ID is ignored
+func (c *Client) CreateApprovalRule(projectPath string, rule *ApprovalRule) (*ApprovalRule, error) {
+	endpoint := c.apiURL("/projects/%s/approval_rules", url.PathEscape(projectPath))
+	body := map[string]interface{}{"name": rule.Name, "approvals_required": rule.ApprovalsRequired}
+	if rule.RuleType != "" {
+		body["rule_type"] = rule.RuleType
+	}
+	var created ApprovalRule
+	if err := c.do("POST", endpoint, body, &created, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// SetApprovalsRequired changes how many approvals a rule requires
+func (c *Client) SetApprovalsRequired(projectPath string, ruleID, required int) error {
+	endpoint := c.apiURL("/projects/%s/approval_rules/%d", url.PathEscape(projectPath), ruleID)
+	return c.do("PUT", endpoint, map[string]int{"approvals_required": required}, nil, http.StatusOK)
+}
+
+// ListProtectedBranches lists the protected branches and wildcards of a
+// project
+func (c *Client) ListProtectedBranches(projectPath string) ([]ProtectedBranch, error) {
+	endpoint := c.apiURL("/projects/%s/protected_branches", url.PathEscape(projectPath))
+	return getAll[ProtectedBranch](c, endpoint, nil, 0)
+}
+
+// UnprotectBranch removes the protection of a branch or wildcard
+func (c *Client) UnprotectBranch(projectPath, name string) error {
+	endpoint := c.apiURL("/projects/%s/protected_branches/%s", url.PathEscape(projectPath), url.PathEscape(name))
+	return c.do("DELETE", endpoint, nil, nil, http.StatusNoContent)
+}
+
+// ListProjectVariables lists the CI/CD variables of a project, with their
+// values
+func (c *Client) ListProjectVariables(projectPath string) ([]Variable, error) {
+	endpoint := c.apiURL("/projects/%s/variables", url.PathEscape(projectPath))
+	return getAll[Variable](c, endpoint, nil, 0)
+}
+
+// CreateProjectVariable creates a CI/CD variable
+func (c *Client) CreateProjectVariable(projectPath string, v *Variable) error {
+	endpoint := c.apiURL("/projects/%s/variables", url.PathEscape(projectPath))
+	return c.do("POST", endpoint, v, nil, http.StatusCreated)
+}
+
+// UpdateProjectVariable changes the value and flags of a CI/CD variable.
+// Variables scoped to an environment other than "*" are matched by scope.
+func (c *Client) UpdateProjectVariable(projectPath string, v *Variable) error {
+	endpoint := c.apiURL("/projects/%s/variables/%s", url.PathEscape(projectPath), url.PathEscape(v.Key))
+	if v.EnvironmentScope != "" && v.EnvironmentScope != "*" {
+		endpoint += "?filter%5Benvironment_scope%5D=" + url.QueryEscape(v.EnvironmentScope)
+	}
+	return c.do("PUT", endpoint, v, nil, http.StatusOK)
+}
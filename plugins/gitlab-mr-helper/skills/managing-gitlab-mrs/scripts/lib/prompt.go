@@ -0,0 +1,152 @@
+package lib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Prompter asks questions on an interactive terminal
+type Prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewPrompter creates a prompter reading answers from in and writing
+// questions to out
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{in: bufio.NewReader(in), out: out}
+}
+
+// readLine reads one answer; io.EOF is only returned when nothing was typed
+func (p *Prompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// Ask prompts for a free-form answer, returning def when the answer is empty
+func (p *Prompter) Ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	answer, err := p.readLine()
+	if err != nil {
+		return "", err
+	}
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// Confirm prompts for a yes/no answer
+func (p *Prompter) Confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, hint)
+		answer, err := p.readLine()
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(p.out, "Please answer y or n.")
+	}
+}
+
+// Choose prompts for one of options by number or name, returning def when
+// the answer is empty
+func (p *Prompter) Choose(question string, options []string, def string) (string, error) {
+	p.listOptions(options)
+	for {
+		answer, err := p.Ask(question, def)
+		if err != nil {
+			return "", err
+		}
+		picked, err := pickOptions(answer, options)
+		if err == nil && len(picked) == 1 {
+			return picked[0], nil
+		}
+		if answer == def && def != "" {
+			// Defaults outside the list (e.g. a branch not yet listed) are accepted
+			return def, nil
+		}
+		fmt.Fprintln(p.out, "Pick one option by number or name.")
+	}
+}
+
+// ChooseMany prompts for any number of options as a comma-separated list of
+// numbers or names. An empty answer selects def.
+func (p *Prompter) ChooseMany(question string, options []string, def []string) ([]string, error) {
+	if len(options) == 0 {
+		return def, nil
+	}
+	p.listOptions(options)
+	for {
+		answer, err := p.Ask(question+" (comma-separated, '-' for none)", strings.Join(def, ","))
+		if err != nil {
+			return nil, err
+		}
+		if answer == "-" {
+			return nil, nil
+		}
+		picked, err := pickOptions(answer, options)
+		if err == nil {
+			return picked, nil
+		}
+		fmt.Fprintln(p.out, err)
+	}
+}
+
+func (p *Prompter) listOptions(options []string) {
+	for i, o := range options {
+		fmt.Fprintf(p.out, "  %2d) %s\n", i+1, o)
+	}
+}
+
+// pickOptions resolves a comma-separated list of 1-based numbers or option
+// names
+func pickOptions(answer string, options []string) ([]string, error) {
+	var picked []string
+	for _, field := range strings.Split(answer, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(field); err == nil {
+			if n < 1 || n > len(options) {
+				return nil, fmt.Errorf("no option %d", n)
+			}
+			picked = append(picked, options[n-1])
+			continue
+		}
+		found := false
+		for _, o := range options {
+			if strings.EqualFold(o, field) {
+				picked = append(picked, o)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown option %q", field)
+		}
+	}
+	return picked, nil
+}
@@ -0,0 +1,102 @@
+package lib_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestPrompter(t *testing.T) {
+	options := []string{"backend", "bug", "frontend"}
+
+	tests := []struct {
+		name  string
+		input string
+		run   func(p *lib.Prompter) (interface{}, error)
+		want  string
+	}{
+		{
+			name:  "ask default",
+			input: "\n",
+			run:   func(p *lib.Prompter) (interface{}, error) { return p.Ask("Title", "Add login") },
+			want:  "Add login",
+		},
+		{
+			name:  "ask answer",
+			input: "  Custom title \n",
+			run:   func(p *lib.Prompter) (interface{}, error) { return p.Ask("Title", "Add login") },
+			want:  "Custom title",
+		},
+		{
+			name:  "confirm retries",
+			input: "maybe\nyes\n",
+			run:   func(p *lib.Prompter) (interface{}, error) { return p.Confirm("Create?", false) },
+			want:  "true",
+		},
+		{
+			name:  "confirm default",
+			input: "\n",
+			run:   func(p *lib.Prompter) (interface{}, error) { return p.Confirm("Create?", true) },
+			want:  "true",
+		},
+		{
+			name:  "choose by number",
+			input: "2\n",
+			run:   func(p *lib.Prompter) (interface{}, error) { return p.Choose("Label", options, "") },
+			want:  "bug",
+		},
+		{
+			name:  "choose retries unknown",
+			input: "nope\nFrontend\n",
+			run:   func(p *lib.Prompter) (interface{}, error) { return p.Choose("Label", options, "") },
+			want:  "frontend",
+		},
+		{
+			name:  "choose default outside list",
+			input: "\n",
+			run:   func(p *lib.Prompter) (interface{}, error) { return p.Choose("Target", options, "release") },
+			want:  "release",
+		},
+		{
+			name:  "choose many",
+			input: "1, frontend\n",
+			run: func(p *lib.Prompter) (interface{}, error) {
+				picked, err := p.ChooseMany("Labels", options, nil)
+				return strings.Join(picked, ","), err
+			},
+			want: "backend,frontend",
+		},
+		{
+			name:  "choose many none",
+			input: "-\n",
+			run: func(p *lib.Prompter) (interface{}, error) {
+				picked, err := p.ChooseMany("Labels", options, []string{"bug"})
+				return strings.Join(picked, ","), err
+			},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := lib.NewPrompter(strings.NewReader(tt.input), io.Discard)
+			got, err := tt.run(p)
+			if err != nil {
+				t.Fatalf("prompt: %v", err)
+			}
+			if s := fmt.Sprint(got); s != tt.want {
+				t.Errorf("got %q, want %q", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrompterEOF(t *testing.T) {
+	p := lib.NewPrompter(strings.NewReader(""), io.Discard)
+	if _, err := p.Ask("Title", "x"); err != io.EOF {
+		t.Errorf("err = %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,112 @@
+package lib
+
+import (
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit caps the requests sent to one host, so bulk commands stay
+// clear of an instance's abuse detection or IP bans
+type RateLimit struct {
+	// PerSecond is the sustained rate, e.g. 0.5 for one request every two
+	// seconds
+	PerSecond float64 `json:"per_second"`
+	// Burst is how many requests may go at once after a quiet period
+	// (default: PerSecond rounded up)
+	Burst int `json:"burst"`
+}
+
+// rateLimits returns the rate limits by host: those of the settings, and
+// GITLAB_RATE_LIMIT (requests per second) for the host of baseURL
+func rateLimits(settings map[string]RateLimit, baseURL string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit, len(settings)+1)
+	for host, l := range settings {
+		if l.PerSecond <= 0 || l.Burst < 0 {
+			return nil, UsageErrorf("invalid rate limit for %s: per_second must be positive and burst not negative", host)
+		}
+		limits[host] = l
+	}
+	if v := os.Getenv("GITLAB_RATE_LIMIT"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate <= 0 {
+			return nil, UsageErrorf("invalid GITLAB_RATE_LIMIT %q (expected requests per second, e.g. 5 or 0.5)", v)
+		}
+		if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+			limits[u.Host] = RateLimit{PerSecond: rate}
+		}
+	}
+	return limits, nil
+}
+
+// rateLimitTransport delays requests to the hosts of its buckets so they
+// keep to their rate limits. Redirects, e.g. to object storage, go through
+// it too and count against their own host.
+type rateLimitTransport struct {
+	next    http.RoundTripper
+	buckets map[string]*tokenBucket
+}
+
+// newRateLimitTransport wraps next with the rate limits, by host
+func newRateLimitTransport(next http.RoundTripper, limits map[string]RateLimit) *rateLimitTransport {
+	t := &rateLimitTransport{next: next, buckets: make(map[string]*tokenBucket, len(limits))}
+	now := time.Now()
+	for host, l := range limits {
+		burst := float64(l.Burst)
+		if burst == 0 {
+			burst = math.Ceil(l.PerSecond)
+		}
+		t.buckets[host] = &tokenBucket{rate: l.PerSecond, burst: burst, tokens: burst, last: now}
+	}
+	return t
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A limit on "host" also covers "host:port"
+	b := t.buckets[req.URL.Host]
+	if b == nil {
+		b = t.buckets[req.URL.Hostname()]
+	}
+	if b != nil {
+		if wait := b.take(time.Now()); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-req.Context().Done():
+				timer.Stop()
+				return nil, req.Context().Err()
+			}
+		}
+	}
+	return t.next.RoundTrip(req)
+}
+
+// tokenBucket is a token bucket that hands out reservations: a request
+// takes a token even when none is left and waits until it would have been
+// there, so concurrent requests go out in the order they came
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens added per second
+	burst  float64 // most tokens held
+	tokens float64 // negative while requests wait
+	last   time.Time
+}
+
+// take reserves a token and returns how long to wait before using it
+func (b *tokenBucket) take(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if now.After(b.last) {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
@@ -0,0 +1,90 @@
+package lib_test
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestRateLimit(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	host := srv.Listener.Addr().String()
+
+	// getMRs times n requests made by 4 workers
+	getMRs := func(limits map[string]lib.RateLimit, n int) time.Duration {
+		client := lib.NewClient(&lib.Config{URL: srv.URL, Token: srv.CurrentToken(), Concurrency: 4, RateLimits: limits})
+		start := time.Now()
+		for _, err := range client.ForEach(n, func(i int) error {
+			_, err := client.GetMR(gitlabtest.ProjectPath, 1)
+			return err
+		}) {
+			if err != nil {
+				t.Fatalf("GetMR: %v", err)
+			}
+		}
+		return time.Since(start)
+	}
+
+	// A burst of 2, then one request every 50ms
+	if d := getMRs(map[string]lib.RateLimit{host: {PerSecond: 20, Burst: 2}}, 6); d < 190*time.Millisecond {
+		t.Errorf("6 requests at 20/s with a burst of 2 took %v, want at least 200ms", d)
+	}
+	// Limits match the host name without the port, and only their host
+	u, _ := url.Parse(srv.URL)
+	if d := getMRs(map[string]lib.RateLimit{u.Hostname(): {PerSecond: 10}}, 12); d < 190*time.Millisecond {
+		t.Errorf("12 requests at 10/s took %v, want at least 200ms", d)
+	}
+	if d := getMRs(map[string]lib.RateLimit{"gitlab.example.com": {PerSecond: 1}}, 6); d > time.Second {
+		t.Errorf("requests to a host without a limit took %v", d)
+	}
+}
+
+func TestRateLimitsConfig(t *testing.T) {
+	configDir := t.TempDir()
+	os.MkdirAll(filepath.Join(configDir, "gitlab-helper"), 0o755)
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("GITLAB_URL", "https://gitlab.example.com")
+	t.Setenv("GITLAB_TOKEN", "glpat-test")
+	t.Setenv("GITLAB_TOKEN_WARN_DAYS", "0")
+	writeSettings := func(data string) {
+		if err := os.WriteFile(filepath.Join(configDir, "gitlab-helper", "config.json"), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeSettings(`{"rate_limits": {"gitlab.example.com": {"per_second": 5, "burst": 10}, "gitlab.com": {"per_second": 0.5}}}`)
+	t.Setenv("GITLAB_RATE_LIMIT", "")
+	config, err := lib.GetConfig()
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	if got := config.RateLimits; len(got) != 2 || got["gitlab.example.com"] != (lib.RateLimit{PerSecond: 5, Burst: 10}) || got["gitlab.com"].PerSecond != 0.5 {
+		t.Errorf("RateLimits = %v", got)
+	}
+
+	// The environment sets the limit of GITLAB_URL's host
+	t.Setenv("GITLAB_RATE_LIMIT", "2.5")
+	if config, err = lib.GetConfig(); err != nil || config.RateLimits["gitlab.example.com"] != (lib.RateLimit{PerSecond: 2.5}) {
+		t.Errorf("GetConfig with GITLAB_RATE_LIMIT = %v, %v", config.RateLimits, err)
+	}
+
+	for _, bad := range []struct{ env, settings string }{
+		{env: "fast"},
+		{env: "-1"},
+		{settings: `{"rate_limits": {"gitlab.example.com": {"per_second": 0}}}`},
+	} {
+		t.Setenv("GITLAB_RATE_LIMIT", bad.env)
+		writeSettings(bad.settings + "\n")
+		if bad.settings == "" {
+			writeSettings("{}")
+		}
+		if _, err := lib.GetConfig(); lib.ExitCode(err) != lib.ExitUsage {
+			t.Errorf("GetConfig with %+v error = %v, want a usage error", bad, err)
+		}
+	}
+}
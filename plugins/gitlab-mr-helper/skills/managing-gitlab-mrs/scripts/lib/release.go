@@ -0,0 +1,174 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultReleaseBranch is the branch cut_release.go creates for a version
+	DefaultReleaseBranch = "release/{major}.{minor}"
+	// DefaultHotfixTarget is the release branch hotfixes for a tag target
+	DefaultHotfixTarget = DefaultReleaseBranch
+	// DefaultVersionPattern matches the version in a version file
+	DefaultVersionPattern = `\d+\.\d+\.\d+`
+)
+
+// Version is a major.minor.patch version, as found in release tags
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// DefaultReleaseChecklist is the task list of release tracking issues
+var DefaultReleaseChecklist = []string{
+	"Release branch created and version bumped",
+	"Release notes written",
+	"Pipeline green on the release branch",
+	"Deployed to staging and smoke-tested",
+	"Tag created and deployed to production",
+	"Release announced",
+}
+
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ParseVersion extracts the first x.y or x.y.z version from s, e.g. from a
+// tag like "v1.4.2" or "release-1.4"
+func ParseVersion(s string) (Version, error) {
+	m := versionPattern.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("no version found in %q", s)
+	}
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	return v, nil
+}
+
+// Less reports whether v is a lower version than o
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Patch < o.Patch
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Expand replaces the {major}, {minor}, {patch} and {version} placeholders
+// in template
+func (v Version) Expand(template string) string {
+	return strings.NewReplacer(
+		"{major}", strconv.Itoa(v.Major),
+		"{minor}", strconv.Itoa(v.Minor),
+		"{patch}", strconv.Itoa(v.Patch),
+		"{version}", v.String(),
+	).Replace(template)
+}
+
+// BumpVersion replaces the version matched by pattern in content. When the
+// pattern has a capture group only the first group is replaced, so context
+// like `"version": "(.+?)"` can pin down the right occurrence. Only the first
+// match is changed; changed is false when nothing matched or the version was
+// already current.
+func BumpVersion(content, pattern, version string) (out string, changed bool, err error) {
+	if pattern == "" {
+		pattern = DefaultVersionPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid version pattern %q: %w", pattern, err)
+	}
+	loc := re.FindStringSubmatchIndex(content)
+	if loc == nil {
+		return content, false, nil
+	}
+	start, end := loc[0], loc[1]
+	if len(loc) >= 4 && loc[2] >= 0 {
+		start, end = loc[2], loc[3]
+	}
+	if content[start:end] == version {
+		return content, false, nil
+	}
+	return content[:start] + version + content[end:], true, nil
+}
+
+// ReleaseLink is an asset link of a release
+type ReleaseLink struct {
+	ID       int    `json:"id,omitempty"`
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	LinkType string `json:"link_type,omitempty"` // other, runbook, image or package
+}
+
+// ProjectUpload is a file uploaded to a project, as attached to comments
+type ProjectUpload struct {
+	Alt      string `json:"alt"`
+	URL      string `json:"url"`       // relative to the project's web URL
+	FullPath string `json:"full_path"` // relative to the instance URL
+	Markdown string `json:"markdown"`
+}
+
+// UploadProjectFile uploads a file to a project
+func (c *Client) UploadProjectFile(projectPath, name string, data io.Reader) (*ProjectUpload, error) {
+	endpoint := c.apiURL("/projects/%s/uploads", url.PathEscape(projectPath))
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, data); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.sendRaw("POST", endpoint, mw.FormDataContentType(), &body, http.StatusCreated)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var upload ProjectUpload
+	if err := json.NewDecoder(capReader(resp.Body, MaxJSONBytes)).Decode(&upload); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &upload, nil
+}
+
+// CreateReleaseLink adds an asset link to the release of a tag
+func (c *Client) CreateReleaseLink(projectPath, tag string, link ReleaseLink) (*ReleaseLink, error) {
+	endpoint := c.apiURL("/projects/%s/releases/%s/assets/links", url.PathEscape(projectPath), url.PathEscape(tag))
+	var created ReleaseLink
+	if err := c.do("POST", endpoint, link, &created, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// AttachReleaseFile uploads a file to a project and links it from the
+// release of tag
+func (c *Client) AttachReleaseFile(projectPath, tag, name string, data io.Reader) (*ReleaseLink, error) {
+	upload, err := c.UploadProjectFile(projectPath, name, data)
+	if err != nil {
+		return nil, fmt.Errorf("uploading %s: %w", name, err)
+	}
+	return c.CreateReleaseLink(projectPath, tag, ReleaseLink{Name: name, URL: c.config.URL + upload.FullPath, LinkType: "other"})
+}
@@ -0,0 +1,87 @@
+package lib_test
+
+import (
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    lib.Version
+		wantErr bool
+	}{
+		{in: "v1.4.2", want: lib.Version{Major: 1, Minor: 4, Patch: 2}},
+		{in: "1.4", want: lib.Version{Major: 1, Minor: 4}},
+		{in: "release-2.10.0-rc1", want: lib.Version{Major: 2, Minor: 10}},
+		{in: "nightly", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := lib.ParseVersion(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseVersion(%q) = %v, want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseVersion(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionExpand(t *testing.T) {
+	v := lib.Version{Major: 1, Minor: 4, Patch: 2}
+	tests := map[string]string{
+		lib.DefaultHotfixTarget:          "release/1.4",
+		"hotfix/{version}":               "hotfix/1.4.2",
+		"stable-{major}.{minor}.{patch}": "stable-1.4.2",
+		"main":                           "main",
+	}
+	for tmpl, want := range tests {
+		if got := v.Expand(tmpl); got != want {
+			t.Errorf("Expand(%q) = %q, want %q", tmpl, got, want)
+		}
+	}
+}
+
+func TestBumpVersion(t *testing.T) {
+	pkg := "{\n  \"version\": \"1.1.0\",\n  \"dependencies\": {\"left-pad\": \"1.3.0\"}\n}\n"
+	tests := []struct {
+		name        string
+		content     string
+		pattern     string
+		want        string
+		wantChanged bool
+		wantErr     bool
+	}{
+		{name: "default pattern", content: "1.1.0\n", want: "1.2.0\n", wantChanged: true},
+		{name: "capture group", content: pkg, pattern: `"version": "([^"]+)"`, want: strings.Replace(pkg, "1.1.0", "1.2.0", 1), wantChanged: true},
+		{name: "already current", content: "1.2.0\n", want: "1.2.0\n"},
+		{name: "no match", content: "no version here", want: "no version here"},
+		{name: "bad pattern", content: "1.1.0", pattern: "(", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := lib.BumpVersion(tt.content, tt.pattern, "1.2.0")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("BumpVersion: want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BumpVersion: %v", err)
+			}
+			if got != tt.want || changed != tt.wantChanged {
+				t.Errorf("BumpVersion = %q, %v; want %q, %v", got, changed, tt.want, tt.wantChanged)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package lib
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Commit represents a GitLab repository commit
+type Commit struct {
+	ID          string    `json:"id"`
+	ShortID     string    `json:"short_id"`
+	Title       string    `json:"title"`
+	Message     string    `json:"message"`
+	AuthorName  string    `json:"author_name"`
+	AuthorEmail string    `json:"author_email"`
+	CreatedAt   time.Time `json:"created_at"`
+	WebURL      string    `json:"web_url"`
+}
+
+// FormatAuthor renders a commit author as "Name <email>", or just the name
+// when the email is unknown
+func FormatAuthor(name, email string) string {
+	if email == "" {
+		return name
+	}
+	return fmt.Sprintf("%s <%s>", name, email)
+}
+
+// Tag represents a repository tag
+type Tag struct {
+	Name      string `json:"name"`
+	Message   string `json:"message"`
+	Target    string `json:"target"`
+	Protected bool   `json:"protected"`
+	Commit    Commit `json:"commit"`
+}
+
+// RepositoryFile is a file read through the repository files API
+type RepositoryFile struct {
+	FilePath string `json:"file_path"`
+	Ref      string `json:"ref"`
+	Encoding string `json:"encoding"`
+	Content  string `json:"content"`
+	BlobID   string `json:"blob_id"`
+}
+
+// CommitAction is one file change of a CreateCommit request
+type CommitAction struct {
+	Action   string `json:"action"` // create, update, delete, move
+	FilePath string `json:"file_path"`
+	Content  string `json:"content,omitempty"`
+}
+
+// CreateCommitRequest represents the request body for committing file
+// changes without a local checkout
+type CreateCommitRequest struct {
+	Branch        string         `json:"branch"`
+	CommitMessage string         `json:"commit_message"`
+	Actions       []CommitAction `json:"actions"`
+}
+
+// Comparison is the result of comparing two refs
+type Comparison struct {
+	Commits        []Commit `json:"commits"`
+	Diffs          []Diff   `json:"diffs"`
+	CompareSame    bool     `json:"compare_same_ref"`
+	CompareTimeout bool     `json:"compare_timeout"`
+}
+
+// CommitListOptions holds filters for repository commit listings
+type CommitListOptions struct {
+	RefName string // branch, tag or SHA (default: the default branch)
+	Path    string // only commits touching this file
+	Since   time.Time
+	Limit   int // 0 means no limit
+}
+
+func (o *CommitListOptions) query() url.Values {
+	q := url.Values{}
+	if o.RefName != "" {
+		q.Set("ref_name", o.RefName)
+	}
+	if o.Path != "" {
+		q.Set("path", o.Path)
+	}
+	if !o.Since.IsZero() {
+		q.Set("since", o.Since.UTC().Format(time.RFC3339))
+	}
+	return q
+}
+
+// ListCommits lists repository commits matching opts, newest first
+func (c *Client) ListCommits(projectPath string, opts *CommitListOptions) ([]Commit, error) {
+	endpoint := c.apiURL("/projects/%s/repository/commits", url.PathEscape(projectPath))
+	return getAll[Commit](c, endpoint, opts.query(), opts.Limit)
+}
+
+// CheckUpToDate compares the source branch of a new MR with its target. A
+// source behind the target yields a warning, or with require an ErrBlocked
+// error, since the MR would need a rebase right away; an up-to-date source
+// yields neither. A failed comparison is only a warning.
+func (c *Client) CheckUpToDate(projectPath, source, target string, require bool) (string, error) {
+	cmp, err := c.CompareRefs(projectPath, source, target)
+	if err != nil {
+		return fmt.Sprintf("could not compare %s with %s: %v", source, target, err), nil
+	}
+	behind := len(cmp.Commits)
+	if behind == 0 {
+		return "", nil
+	}
+	msg := fmt.Sprintf("%s is %d commit(s) behind %s", source, behind, target)
+	if require {
+		return "", fmt.Errorf("%w: %s; rebase first (git rebase origin/%s)", ErrBlocked, msg, target)
+	}
+	return msg + "; consider rebasing before review", nil
+}
+
+// ListCommitMRs lists the merge requests that introduced a commit
+func (c *Client) ListCommitMRs(projectPath, sha string) ([]MergeRequest, error) {
+	endpoint := c.apiURL("/projects/%s/repository/commits/%s/merge_requests", url.PathEscape(projectPath), url.PathEscape(sha))
+	return getAll[MergeRequest](c, endpoint, nil, 0)
+}
+
+// CompareRefs compares two refs. The comparison is made from the merge base
+// of from and to, so the result contains only the changes made on to.
+func (c *Client) CompareRefs(projectPath, from, to string) (*Comparison, error) {
+	endpoint := c.apiURL("/projects/%s/repository/compare?from=%s&to=%s",
+		url.PathEscape(projectPath), url.QueryEscape(from), url.QueryEscape(to))
+
+	var cmp Comparison
+	if err := c.do("GET", endpoint, nil, &cmp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	for i := range cmp.Diffs {
+		truncateDiff(&cmp.Diffs[i], MaxDiffBytes)
+	}
+	return &cmp, nil
+}
+
+// GetCommitDiff lists the file diffs a commit introduced
+func (c *Client) GetCommitDiff(projectPath, sha string) ([]Diff, error) {
+	endpoint := c.apiURL("/projects/%s/repository/commits/%s/diff", url.PathEscape(projectPath), url.PathEscape(sha))
+	return getAllEach(c, endpoint, nil, 0, func(d *Diff) { truncateDiff(d, MaxDiffBytes) })
+}
+
+// RevertCommit commits the revert of sha onto branch. GitLab answers 400
+// when the revert does not apply cleanly.
+func (c *Client) RevertCommit(projectPath, sha, branch string) (*Commit, error) {
+	endpoint := c.apiURL("/projects/%s/repository/commits/%s/revert", url.PathEscape(projectPath), url.PathEscape(sha))
+	body := map[string]string{"branch": branch}
+
+	var commit Commit
+	if err := c.do("POST", endpoint, body, &commit, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &commit, nil
+}
+
+// CherryPickCommit commits sha onto branch. GitLab answers 400 when the
+// cherry-pick does not apply cleanly.
+func (c *Client) CherryPickCommit(projectPath, sha, branch string) (*Commit, error) {
+	endpoint := c.apiURL("/projects/%s/repository/commits/%s/cherry_pick", url.PathEscape(projectPath), url.PathEscape(sha))
+	body := map[string]string{"branch": branch}
+
+	var commit Commit
+	if err := c.do("POST", endpoint, body, &commit, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &commit, nil
+}
+
+// ListTags lists repository tags, most recently updated first, optionally
+// filtered by a search term
+func (c *Client) ListTags(projectPath, search string) ([]Tag, error) {
+	endpoint := c.apiURL("/projects/%s/repository/tags", url.PathEscape(projectPath))
+	query := url.Values{}
+	if search != "" {
+		query.Set("search", search)
+	}
+	return getAll[Tag](c, endpoint, query, 0)
+}
+
+// versionTagsRead is how many tags HighestVersionTag reads. GitLab lists
+// them highest version first, so the highest is among the first unless
+// many tags carry no version.
+const versionTagsRead = 100
+
+// HighestVersionTag returns the tag with the highest version (see
+// ParseVersion) and that version, or a nil tag when no tag has one. Tags
+// are compared by version, not by when they were pushed: a hotfix of an
+// older release is not the newest version.
+func (c *Client) HighestVersionTag(projectPath string) (*Tag, Version, error) {
+	endpoint := c.apiURL("/projects/%s/repository/tags", url.PathEscape(projectPath))
+	query := url.Values{"order_by": {"version"}, "sort": {"desc"}}
+	tags, err := getAll[Tag](c, endpoint, query, versionTagsRead)
+	if err != nil {
+		return nil, Version{}, err
+	}
+	var highest *Tag
+	var version Version
+	for i := range tags {
+		v, err := ParseVersion(tags[i].Name)
+		if err == nil && (highest == nil || version.Less(v)) {
+			highest, version = &tags[i], v
+		}
+	}
+	return highest, version, nil
+}
+
+// GetFile reads a file at ref
+func (c *Client) GetFile(projectPath, filePath, ref string) (*RepositoryFile, error) {
+	endpoint := c.apiURL("/projects/%s/repository/files/%s?ref=%s",
+		url.PathEscape(projectPath), url.PathEscape(filePath), url.QueryEscape(ref))
+
+	var file RepositoryFile
+	if err := c.do("GET", endpoint, nil, &file, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// Text returns the decoded file content
+func (f *RepositoryFile) Text() (string, error) {
+	if f.Encoding != "base64" {
+		return f.Content, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(f.Content)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", f.FilePath, err)
+	}
+	return string(data), nil
+}
+
+// CreateCommit commits file changes onto a branch
+func (c *Client) CreateCommit(projectPath string, req *CreateCommitRequest) (*Commit, error) {
+	endpoint := c.apiURL("/projects/%s/repository/commits", url.PathEscape(projectPath))
+
+	var commit Commit
+	if err := c.do("POST", endpoint, req, &commit, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &commit, nil
+}
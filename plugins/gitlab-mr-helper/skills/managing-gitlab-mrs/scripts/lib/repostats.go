@@ -0,0 +1,141 @@
+package lib
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// LanguageShare is a language's share of a repository's code, by bytes
+type LanguageShare struct {
+	Name    string
+	Percent float64
+}
+
+// GetLanguages returns the languages of a repository's default branch,
+// largest share first
+func (c *Client) GetLanguages(projectPath string) ([]LanguageShare, error) {
+	endpoint := c.apiURL("/projects/%s/languages", url.PathEscape(projectPath))
+
+	var shares map[string]float64
+	if err := c.do("GET", endpoint, nil, &shares, http.StatusOK); err != nil {
+		return nil, err
+	}
+	languages := make([]LanguageShare, 0, len(shares))
+	for name, percent := range shares {
+		languages = append(languages, LanguageShare{Name: name, Percent: percent})
+	}
+	slices.SortFunc(languages, func(a, b LanguageShare) int {
+		if a.Percent != b.Percent {
+			if a.Percent > b.Percent {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return languages, nil
+}
+
+// Contributor is an author of the default branch's commits, all time
+type Contributor struct {
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Commits   int    `json:"commits"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+}
+
+// ListContributors lists the authors of the default branch, most commits
+// first. GitLab tells authors apart by email.
+func (c *Client) ListContributors(projectPath string) ([]Contributor, error) {
+	endpoint := c.apiURL("/projects/%s/repository/contributors", url.PathEscape(projectPath))
+	contributors, err := getAll[Contributor](c, endpoint, url.Values{"order_by": {"commits"}, "sort": {"desc"}}, 0)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(contributors, func(a, b Contributor) int { return b.Commits - a.Commits })
+	return contributors, nil
+}
+
+// AuthorActivity is what one author committed in a period
+type AuthorActivity struct {
+	Name    string
+	Email   string
+	Commits int
+	Last    time.Time // most recent commit
+}
+
+// CommitActivity sums up the commits of a period
+type CommitActivity struct {
+	Since, Until time.Time
+	Commits      int
+	// Weeks counts the commits of each week, oldest first; the first
+	// week may be partial
+	Weeks []int
+	// Authors are most active first
+	Authors []AuthorActivity
+}
+
+// AggregateCommits sums commits made from since to until up by week and
+// by author. Authors are told apart by email, case-insensitively, or by
+// name when commits have no email.
+func AggregateCommits(commits []Commit, since, until time.Time) *CommitActivity {
+	const week = 7 * 24 * time.Hour
+	a := &CommitActivity{Since: since, Until: until}
+	weeks := int((until.Sub(since) + week - 1) / week)
+	a.Weeks = make([]int, max(weeks, 1))
+
+	byAuthor := make(map[string]*AuthorActivity)
+	var order []string
+	for _, c := range commits {
+		if c.CreatedAt.Before(since) || c.CreatedAt.After(until) {
+			continue
+		}
+		a.Commits++
+		// Weeks are counted back from until, so the last one is whole
+		if i := len(a.Weeks) - 1 - int(until.Sub(c.CreatedAt)/week); i >= 0 {
+			a.Weeks[i]++
+		}
+		key := strings.ToLower(c.AuthorEmail)
+		if key == "" {
+			key = c.AuthorName
+		}
+		author := byAuthor[key]
+		if author == nil {
+			author = &AuthorActivity{Name: c.AuthorName, Email: c.AuthorEmail}
+			byAuthor[key] = author
+			order = append(order, key)
+		}
+		author.Commits++
+		if c.CreatedAt.After(author.Last) {
+			author.Last = c.CreatedAt
+		}
+	}
+	for _, key := range order {
+		a.Authors = append(a.Authors, *byAuthor[key])
+	}
+	slices.SortStableFunc(a.Authors, func(x, y AuthorActivity) int { return y.Commits - x.Commits })
+	return a
+}
+
+// BusFactor is the fewest authors who together made more than half of the
+// commits: how many people would have to leave for most of the work to
+// lose its authors. It is 0 without commits.
+func BusFactor(commits []int) int {
+	sorted := slices.Clone(commits)
+	slices.SortFunc(sorted, func(a, b int) int { return b - a })
+	total := 0
+	for _, n := range sorted {
+		total += n
+	}
+	sum := 0
+	for i, n := range sorted {
+		if sum += n; 2*sum > total {
+			return i + 1
+		}
+	}
+	return 0
+}
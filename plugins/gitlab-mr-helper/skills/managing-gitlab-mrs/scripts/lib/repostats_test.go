@@ -0,0 +1,85 @@
+package lib_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestGetLanguages(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	languages, err := srv.Client().GetLanguages(gitlabtest.ProjectPath)
+	if err != nil {
+		t.Fatalf("GetLanguages: %v", err)
+	}
+	if got := fmt.Sprint(languages); got != "[{Go 72.5} {HTML 20.1} {JavaScript 7.4}]" {
+		t.Errorf("GetLanguages = %s", got)
+	}
+}
+
+func TestListContributors(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	contributors, err := srv.Client().ListContributors(gitlabtest.ProjectPath)
+	if err != nil {
+		t.Fatalf("ListContributors: %v", err)
+	}
+	if len(contributors) != 3 || contributors[0].Commits != 40 || contributors[1].Commits != 12 || contributors[2].Email != "carol@example.org" {
+		t.Errorf("ListContributors = %+v, want most commits first", contributors)
+	}
+}
+
+func TestAggregateCommits(t *testing.T) {
+	until := time.Date(2024, 3, 29, 12, 0, 0, 0, time.UTC)
+	since := until.AddDate(0, 0, -28)
+	day := func(n int) time.Time { return until.AddDate(0, 0, -n) }
+	commits := []lib.Commit{
+		{AuthorName: "Alice", AuthorEmail: "alice@example.com", CreatedAt: day(1)},
+		{AuthorName: "Alice", AuthorEmail: "Alice@Example.com", CreatedAt: day(3)},
+		{AuthorName: "Bob", AuthorEmail: "bob@example.com", CreatedAt: day(9)},
+		{AuthorName: "Alice", AuthorEmail: "alice@example.com", CreatedAt: day(27)},
+		{AuthorName: "Carol", CreatedAt: day(20)},
+		{AuthorName: "Bob", AuthorEmail: "bob@example.com", CreatedAt: day(40)}, // before since
+	}
+	a := lib.AggregateCommits(commits, since, until)
+	if a.Commits != 5 {
+		t.Errorf("Commits = %d, want 5", a.Commits)
+	}
+	if got := fmt.Sprint(a.Weeks); got != "[1 1 1 2]" {
+		t.Errorf("Weeks = %s, want [1 1 1 2]", got)
+	}
+	if len(a.Authors) != 3 || a.Authors[0].Name != "Alice" || a.Authors[0].Commits != 3 || !a.Authors[0].Last.Equal(day(1)) ||
+		a.Authors[1].Name != "Bob" || a.Authors[2].Name != "Carol" {
+		t.Errorf("Authors = %+v", a.Authors)
+	}
+}
+
+func TestBusFactor(t *testing.T) {
+	tests := []struct {
+		commits []int
+		want    int
+	}{
+		{nil, 0},
+		{[]int{10}, 1},
+		{[]int{5, 5}, 2},
+		{[]int{3, 40, 12}, 1},
+		{[]int{1, 1, 1, 1, 1}, 3},
+		{[]int{30, 25, 25, 20}, 2},
+	}
+	for _, tt := range tests {
+		if got := lib.BusFactor(tt.commits); got != tt.want {
+			t.Errorf("BusFactor(%v) = %d, want %d", tt.commits, got, tt.want)
+		}
+	}
+}
+
+func TestFormatAuthor(t *testing.T) {
+	if got := lib.FormatAuthor("Alice", "alice@example.com"); got != "Alice <alice@example.com>" {
+		t.Errorf("FormatAuthor = %q", got)
+	}
+	if got := lib.FormatAuthor("Alice", ""); got != "Alice" {
+		t.Errorf("FormatAuthor without email = %q", got)
+	}
+}
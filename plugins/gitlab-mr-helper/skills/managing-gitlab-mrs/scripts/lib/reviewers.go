@@ -0,0 +1,175 @@
+package lib
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReviewerSuggestion is a past contributor to the files of an MR
+type ReviewerSuggestion struct {
+	Name       string    // commit author name
+	User       *User     // matching project member, nil when there is none
+	Commits    int       // distinct commits touching the files
+	Files      int       // files among them they touched
+	LastCommit time.Time // their most recent commit to the files
+}
+
+// SuggestReviewers ranks the authors of the commits in history (file path
+// → commits touching it) by commits, then files, then most recent commit.
+// A commit touching several files counts once. Authors are matched to
+// project members by name or by the local part of their email, and
+// members named in exclude (e.g. the MR author) are left out.
+func SuggestReviewers(history map[string][]Commit, members []Member, exclude []string) []ReviewerSuggestion {
+	byName := make(map[string]*User)
+	for i := range members {
+		u := &members[i].User
+		byName[strings.ToLower(u.Name)] = u
+		byName[strings.ToLower(u.Username)] = u
+	}
+	excluded := make(map[string]bool)
+	for _, u := range exclude {
+		excluded[strings.TrimPrefix(u, "@")] = true
+	}
+
+	type tally struct {
+		ReviewerSuggestion
+		commits map[string]bool
+	}
+	authors := make(map[string]*tally)
+	var order []string
+	paths := make([]string, 0, len(history))
+	for path := range history {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		touched := make(map[string]bool)
+		for _, c := range history[path] {
+			user := byName[strings.ToLower(c.AuthorName)]
+			if local, _, ok := strings.Cut(c.AuthorEmail, "@"); user == nil && ok {
+				user = byName[strings.ToLower(local)]
+			}
+			key := "name:" + strings.ToLower(c.AuthorName)
+			if user != nil {
+				if excluded[user.Username] {
+					continue
+				}
+				key = "user:" + user.Username
+			}
+
+			t := authors[key]
+			if t == nil {
+				t = &tally{ReviewerSuggestion: ReviewerSuggestion{Name: c.AuthorName, User: user}, commits: make(map[string]bool)}
+				authors[key] = t
+				order = append(order, key)
+			}
+			if !t.commits[c.ID] {
+				t.commits[c.ID] = true
+				t.Commits++
+			}
+			if !touched[key] {
+				touched[key] = true
+				t.Files++
+			}
+			if c.CreatedAt.After(t.LastCommit) {
+				t.LastCommit = c.CreatedAt
+			}
+		}
+	}
+
+	out := make([]ReviewerSuggestion, 0, len(order))
+	for _, key := range order {
+		out = append(out, authors[key].ReviewerSuggestion)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Commits != b.Commits {
+			return a.Commits > b.Commits
+		}
+		if a.Files != b.Files {
+			return a.Files > b.Files
+		}
+		return a.LastCommit.After(b.LastCommit)
+	})
+	return out
+}
+
+// ReviewLoad is the open MRs a user is asked to review
+type ReviewLoad struct {
+	User User
+	// Member is false for reviewers from outside the team
+	Member bool
+	MRs    []MergeRequest // oldest first
+}
+
+// Oldest returns when the oldest MR was opened, or the zero time
+func (l *ReviewLoad) Oldest() time.Time {
+	if len(l.MRs) == 0 {
+		return time.Time{}
+	}
+	return l.MRs[0].CreatedAt
+}
+
+// MedianAge returns the median time the MRs have been open at now
+func (l *ReviewLoad) MedianAge(now time.Time) time.Duration {
+	n := len(l.MRs)
+	if n == 0 {
+		return 0
+	}
+	// MRs are oldest first, so the ages are descending
+	mid := now.Sub(l.MRs[n/2].CreatedAt)
+	if n%2 == 0 {
+		mid = (mid + now.Sub(l.MRs[n/2-1].CreatedAt)) / 2
+	}
+	return mid
+}
+
+// ReviewLoads groups MRs by reviewer, most MRs first, then the oldest MR
+// first. Members of the team who review none are included, so idle
+// reviewers show up; those below the Developer role, blocked ones and
+// access token bots are not, as they cannot take reviews.
+func ReviewLoads(mrs []MergeRequest, team []Member) []ReviewLoad {
+	byUser := make(map[string]*ReviewLoad)
+	var order []string
+	add := func(u User, member bool) *ReviewLoad {
+		l := byUser[u.Username]
+		if l == nil {
+			l = &ReviewLoad{User: u}
+			byUser[u.Username] = l
+			order = append(order, u.Username)
+		}
+		l.Member = l.Member || member
+		return l
+	}
+	for _, m := range team {
+		if m.AccessLevel >= AccessDeveloper && m.State != "blocked" && !tokenBotPattern.MatchString(m.Username) {
+			add(m.User, true)
+		}
+	}
+	for _, mr := range mrs {
+		for _, r := range mr.Reviewers {
+			l := add(r, false)
+			l.MRs = append(l.MRs, mr)
+		}
+	}
+
+	out := make([]ReviewLoad, 0, len(order))
+	for _, username := range order {
+		l := byUser[username]
+		sort.SliceStable(l.MRs, func(i, j int) bool { return l.MRs[i].CreatedAt.Before(l.MRs[j].CreatedAt) })
+		out = append(out, *l)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := &out[i], &out[j]
+		if len(a.MRs) != len(b.MRs) {
+			return len(a.MRs) > len(b.MRs)
+		}
+		if !a.Oldest().Equal(b.Oldest()) {
+			return a.Oldest().Before(b.Oldest())
+		}
+		return a.User.Username < b.User.Username
+	})
+	return out
+}
@@ -0,0 +1,120 @@
+package lib_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestListCommits(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	commits, err := client.ListCommits(gitlabtest.ProjectPath, &lib.CommitListOptions{Path: "cmd/main.go"})
+	if err != nil {
+		t.Fatalf("ListCommits: %v", err)
+	}
+	if len(commits) != 3 || commits[1].AuthorName != "Carol Doe" {
+		t.Errorf("commits = %+v", commits)
+	}
+
+	commits, err = client.ListCommits(gitlabtest.ProjectPath, &lib.CommitListOptions{Path: "cmd/main.go", Since: gitlabtest.FixtureTime.Add(-36 * time.Hour)})
+	if err != nil || len(commits) != 1 {
+		t.Errorf("ListCommits(since) = %+v, %v", commits, err)
+	}
+}
+
+func TestSuggestReviewers(t *testing.T) {
+	now := time.Now()
+	members := []lib.Member{
+		{User: lib.User{ID: 1, Username: "alice", Name: "Alice Admin"}},
+		{User: lib.User{ID: 2, Username: "bob", Name: "Bob Builder"}},
+	}
+	commit := func(id, name, email string, age time.Duration) lib.Commit {
+		return lib.Commit{ID: id, AuthorName: name, AuthorEmail: email, CreatedAt: now.Add(-age)}
+	}
+	history := map[string][]lib.Commit{
+		"a.go": {
+			commit("c1", "Alice Admin", "alice@example.com", time.Hour),
+			commit("c2", "Carol", "carol@example.org", 2*time.Hour),
+			commit("c3", "B. Builder", "bob@example.com", 3*time.Hour),
+		},
+		"b.go": {
+			commit("c1", "Alice Admin", "alice@example.com", time.Hour),
+			commit("c4", "Dan", "dan@example.org", time.Minute),
+			commit("c5", "alice", "a@example.com", 4*time.Hour),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		exclude []string
+		want    []string // name, commits, files
+	}{
+		{name: "ranked", want: []string{"Alice Admin 2 2", "Dan 1 1", "Carol 1 1", "B. Builder 1 1"}},
+		{name: "author excluded", exclude: []string{"@bob"}, want: []string{"Alice Admin 2 2", "Dan 1 1", "Carol 1 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lib.SuggestReviewers(history, members, tt.exclude)
+			if len(got) != len(tt.want) {
+				t.Fatalf("SuggestReviewers = %+v, want %v", got, tt.want)
+			}
+			for i, s := range got {
+				if line := fmt.Sprintf("%s %d %d", s.Name, s.Commits, s.Files); line != tt.want[i] {
+					t.Errorf("suggestion %d = %q, want %q", i, line, tt.want[i])
+				}
+			}
+			if got[0].User == nil || got[0].User.Username != "alice" || !got[0].LastCommit.Equal(now.Add(-time.Hour)) {
+				t.Errorf("top suggestion = %+v, want member alice", got[0])
+			}
+		})
+	}
+}
+
+func TestReviewLoads(t *testing.T) {
+	now := time.Now()
+	alice, bob, carol, dave := lib.User{ID: 1, Username: "alice"}, lib.User{ID: 2, Username: "bob"}, lib.User{ID: 3, Username: "carol"}, lib.User{ID: 4, Username: "dave"}
+	mr := func(iid int, age time.Duration, reviewers ...lib.User) lib.MergeRequest {
+		return lib.MergeRequest{IID: iid, CreatedAt: now.Add(-age), Reviewers: reviewers}
+	}
+	mrs := []lib.MergeRequest{
+		mr(1, time.Hour, alice, bob),
+		mr(2, 48*time.Hour, bob),
+		mr(3, 5*time.Hour, alice),
+		mr(4, 10*time.Hour, dave),
+	}
+	team := []lib.Member{
+		{User: alice, State: "active", AccessLevel: lib.AccessMaintainer},
+		{User: bob, State: "active", AccessLevel: lib.AccessDeveloper},
+		{User: carol, State: "active", AccessLevel: lib.AccessDeveloper},
+		{User: lib.User{Username: "guest"}, State: "active", AccessLevel: lib.AccessReporter},
+		{User: lib.User{Username: "project_42_bot_1f2e"}, State: "active", AccessLevel: lib.AccessDeveloper},
+	}
+
+	loads := lib.ReviewLoads(mrs, team)
+	var got []string
+	for _, l := range loads {
+		got = append(got, fmt.Sprintf("%s:%d:%t", l.User.Username, len(l.MRs), l.Member))
+	}
+	// bob's oldest MR is older than alice's; dave is not in the team
+	want := "[bob:2:true alice:2:true dave:1:false carol:0:true]"
+	if fmt.Sprint(got) != want {
+		t.Fatalf("ReviewLoads = %v, want %s", got, want)
+	}
+	if loads[0].MRs[0].IID != 2 || !loads[0].Oldest().Equal(mrs[1].CreatedAt) {
+		t.Errorf("bob's MRs are not oldest first: %+v", loads[0].MRs)
+	}
+	if got := loads[1].MedianAge(now); got != 3*time.Hour {
+		t.Errorf("MedianAge = %v, want 3h", got)
+	}
+	if got := loads[2].MedianAge(now); got != 10*time.Hour {
+		t.Errorf("MedianAge of one MR = %v, want 10h", got)
+	}
+	if got := loads[3].MedianAge(now); got != 0 || !loads[3].Oldest().IsZero() {
+		t.Errorf("carol without MRs: median %v, oldest %v", got, loads[3].Oldest())
+	}
+}
@@ -0,0 +1,67 @@
+package lib
+
+import (
+	"sort"
+	"strconv"
+)
+
+// ReviewQueueOptions selects the MRs of a review queue
+type ReviewQueueOptions struct {
+	Group         string // "" for every project visible to the token
+	IncludeDrafts bool
+	Limit         int // 0 for no limit
+}
+
+// ReviewSkip is an MR left out of a review queue because its approvals
+// could not be read
+type ReviewSkip struct {
+	MR  MergeRequest
+	Err error
+}
+
+// ReviewQueue lists the open MRs in which reviewer is a reviewer and has
+// not approved yet, oldest first. MRs whose approvals cannot be read are
+// left out and returned in skipped.
+func (c *Client) ReviewQueue(reviewer *User, opts *ReviewQueueOptions) (queue []MergeRequest, skipped []ReviewSkip, err error) {
+	mrs, err := c.ListGroupMRs(opts.Group, &MRListOptions{
+		State:      "opened",
+		Scope:      "all",
+		ReviewerID: reviewer.ID,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Drafts are dropped first, which saves their approval requests
+	var candidates []MergeRequest
+	for _, mr := range mrs {
+		if !mr.Draft || opts.IncludeDrafts {
+			candidates = append(candidates, mr)
+		}
+	}
+	approved := make([]bool, len(candidates))
+	errs := c.ForEach(len(candidates), func(i int) error {
+		approvals, err := c.GetMRApprovals(strconv.Itoa(candidates[i].ProjectID), candidates[i].IID)
+		if err != nil {
+			return err
+		}
+		approved[i] = approvals.HasApproved(reviewer.ID)
+		return nil
+	})
+	for i, mr := range candidates {
+		switch {
+		case errs[i] != nil:
+			skipped = append(skipped, ReviewSkip{MR: mr, Err: errs[i]})
+		case !approved[i]:
+			queue = append(queue, mr)
+		}
+	}
+
+	sort.SliceStable(queue, func(i, j int) bool {
+		return queue[i].CreatedAt.Before(queue[j].CreatedAt)
+	})
+	if opts.Limit > 0 && len(queue) > opts.Limit {
+		queue = queue[:opts.Limit]
+	}
+	return queue, skipped, nil
+}
@@ -0,0 +1,91 @@
+package lib_test
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestReviewQueue(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	p := srv.Project(gitlabtest.ProjectPath)
+	alice, bob := gitlabtest.Alice, gitlabtest.Bob
+	addMR := func(iid int, created time.Time, draft bool, approvedBy ...lib.User) {
+		mr := *p.MRs[1]
+		mr.IID, mr.CreatedAt, mr.Draft = iid, created, draft
+		mr.References.Full = fmt.Sprintf("%s!%d", gitlabtest.ProjectPath, iid)
+		p.MRs = append(p.MRs, &mr)
+		approvals := &lib.Approvals{}
+		for _, u := range approvedBy {
+			approvals.ApprovedBy = append(approvals.ApprovedBy, struct {
+				User lib.User `json:"user"`
+			}{User: u})
+		}
+		p.Approvals[iid] = approvals
+	}
+	// Besides the fixtures (group/project!2 at +2h, group/sub/nested!1 at
+	// +1h), Alice reviews a draft, an MR she approved, and an MR approved
+	// by Bob only, which is the oldest
+	addMR(4, gitlabtest.FixtureTime.Add(30*time.Minute), true)
+	addMR(5, gitlabtest.FixtureTime, false, alice)
+	addMR(6, gitlabtest.FixtureTime.Add(-time.Hour), false, bob)
+
+	tests := []struct {
+		name string
+		opts lib.ReviewQueueOptions
+		want []string
+	}{
+		{name: "default", want: []string{"group/project!6", "group/sub/nested!1", "group/project!2"}},
+		{name: "include drafts", opts: lib.ReviewQueueOptions{IncludeDrafts: true},
+			want: []string{"group/project!6", "group/project!4", "group/sub/nested!1", "group/project!2"}},
+		{name: "limit", opts: lib.ReviewQueueOptions{Limit: 2}, want: []string{"group/project!6", "group/sub/nested!1"}},
+		{name: "group", opts: lib.ReviewQueueOptions{Group: "group/sub"}, want: []string{"group/sub/nested!1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue, skipped, err := srv.Client().ReviewQueue(&alice, &tt.opts)
+			if err != nil {
+				t.Fatalf("ReviewQueue: %v", err)
+			}
+			if len(skipped) != 0 {
+				t.Errorf("skipped = %+v", skipped)
+			}
+			var got []string
+			for _, mr := range queue {
+				got = append(got, mr.References.Full)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("queue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReviewQueueSkipsUnreadableApprovals(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	srv.Handle("GET /projects/:id/merge_requests/:iid/approvals", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if params["iid"] == "2" {
+			gitlabtest.WriteError(w, http.StatusInternalServerError, "500 Internal Server Error")
+			return
+		}
+		gitlabtest.WriteJSON(w, http.StatusOK, lib.Approvals{})
+	})
+
+	alice := gitlabtest.Alice
+	queue, skipped, err := srv.Client().ReviewQueue(&alice, &lib.ReviewQueueOptions{})
+	if err != nil {
+		t.Fatalf("ReviewQueue: %v", err)
+	}
+	if len(queue) != 1 || queue[0].References.Full != "group/sub/nested!1" {
+		t.Errorf("queue = %+v", queue)
+	}
+	if len(skipped) != 1 || skipped[0].MR.References.Full != "group/project!2" || skipped[0].Err == nil {
+		t.Errorf("skipped = %+v", skipped)
+	}
+}
@@ -0,0 +1,301 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RulesFileName is the repository ruleset, looked up at the root of the
+// current git work tree
+const RulesFileName = ".gitlab-helper-rules.yml"
+
+// ruleMarker ends comments posted by rules, so note rules never fire on
+// them and rules cannot trigger each other in a loop
+const ruleMarker = "<!-- gitlab-helper rule -->"
+
+// Ruleset is a list of workflow rules, e.g.
+//
+//	rules:
+//	  - name: triage
+//	    when: {label_added: needs-review}
+//	    then: {assign: [alice], comment: "@alice please take a look"}
+type Ruleset struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule runs its actions on the MR of every event matching its condition
+type Rule struct {
+	Name string        `json:"name"`
+	When RuleCondition `json:"when"`
+	Then RuleActions   `json:"then"`
+}
+
+// RuleCondition matches events. Every field set must match, and at least
+// one of Event, Pipeline, LabelAdded and LabelRemoved is required.
+type RuleCondition struct {
+	// Event is a kind or kind:action, e.g. "merge_request:open"
+	Event string `json:"event"`
+	// Pipeline is a finished pipeline status, e.g. "failed"
+	Pipeline     string `json:"pipeline"`
+	LabelAdded   string `json:"label_added"`
+	LabelRemoved string `json:"label_removed"`
+	// Branch is a glob the MR source branch or pipeline ref must match
+	Branch string `json:"branch"`
+}
+
+// RuleActions change the MR of a matching event. Users are usernames of
+// project members, with or without "@".
+type RuleActions struct {
+	Assign       []string `json:"assign"`
+	Reviewers    []string `json:"reviewers"`
+	AddLabels    []string `json:"add_labels"`
+	RemoveLabels []string `json:"remove_labels"`
+	// Comment is posted on the MR with {author}, {title}, {ref}, {status},
+	// {iid} and {url} replaced from the event
+	Comment string `json:"comment"`
+}
+
+// LoadRules reads a ruleset file. An empty path looks for RulesFileName at
+// the repository root, and a missing file there is an empty ruleset.
+func LoadRules(file string) (*Ruleset, error) {
+	data, file, err := readRepoFile(file, RulesFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+	rs := &Ruleset{}
+	if data == nil {
+		return rs, nil
+	}
+	if err := DecodeYAML(data, rs); err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %w", file, err)
+	}
+	if err := rs.validate(); err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %w", file, err)
+	}
+	return rs, nil
+}
+
+// readRepoFile reads file, or when it is empty the file name at the root
+// of the current git work tree. It returns the path read, and nil data
+// when name is not there.
+func readRepoFile(file, name string) ([]byte, string, error) {
+	explicit := file != ""
+	if !explicit {
+		output, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+		if err != nil {
+			return nil, "", nil
+		}
+		file = filepath.Join(strings.TrimSpace(string(output)), name)
+	}
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil, file, nil
+	}
+	if err != nil {
+		return nil, file, err
+	}
+	return data, file, nil
+}
+
+func (rs *Ruleset) validate() error {
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+		if r.Name == "" {
+			r.Name = "rule " + strconv.Itoa(i+1)
+		}
+		w, t := r.When, r.Then
+		if w.Event == "" && w.Pipeline == "" && w.LabelAdded == "" && w.LabelRemoved == "" {
+			return fmt.Errorf("%s: when needs event, pipeline, label_added or label_removed", r.Name)
+		}
+		if _, err := path.Match(w.Branch, ""); err != nil {
+			return fmt.Errorf("%s: invalid branch pattern %q", r.Name, w.Branch)
+		}
+		if len(t.Assign) == 0 && len(t.Reviewers) == 0 && len(t.AddLabels) == 0 && len(t.RemoveLabels) == 0 && t.Comment == "" {
+			return fmt.Errorf("%s: then has no actions", r.Name)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the rule's condition holds for ev. Comments
+// posted by rules never match.
+func (r *Rule) Matches(ev *WebhookEvent) bool {
+	w := r.When
+	switch {
+	case ev.Kind == "note" && strings.Contains(ev.Body, ruleMarker):
+		return false
+	case w.Event != "" && w.Event != ev.Kind && w.Event != ev.Kind+":"+ev.Action:
+		return false
+	case w.Pipeline != "" && (ev.Kind != "pipeline" || ev.Status != w.Pipeline):
+		return false
+	case w.LabelAdded != "" && !ContainsString(ev.AddedLabels, w.LabelAdded):
+		return false
+	case w.LabelRemoved != "" && !ContainsString(ev.RemovedLabels, w.LabelRemoved):
+		return false
+	}
+	if w.Branch != "" {
+		if ok, _ := path.Match(w.Branch, ev.Ref); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply runs the actions of every rule matching ev on the event's MR (for
+// a pipeline without one, the open MR of its ref) and returns the names of
+// the rules applied. Labels, assignees and reviewers already in place are
+// left alone, so replaying an event changes nothing but the comments.
+func (rs *Ruleset) Apply(client *Client, ev *WebhookEvent) ([]string, error) {
+	var matched []Rule
+	for _, r := range rs.Rules {
+		if r.Matches(ev) {
+			matched = append(matched, r)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, nil
+	}
+	// Each event is a run of its own: members and labels may have changed
+	// since the last one
+	client.ForgetLookups()
+
+	iid := ev.MRIID
+	if iid == 0 && ev.Ref != "" {
+		mrs, err := client.ListProjectMRs(ev.Project, &MRListOptions{State: "opened", SourceBranch: ev.Ref, Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(mrs) > 0 {
+			iid = mrs[0].IID
+		}
+	}
+	if iid == 0 {
+		return nil, nil
+	}
+	mr, err := client.GetMR(ev.Project, iid)
+	if err != nil {
+		return nil, err
+	}
+
+	var assign, reviewers, addLabels, removeLabels []string
+	for _, r := range matched {
+		for _, u := range r.Then.Assign {
+			if !hasUser(mr.Assignees, u) && !ContainsString(assign, u) {
+				assign = append(assign, u)
+			}
+		}
+		for _, u := range r.Then.Reviewers {
+			if !hasUser(mr.Reviewers, u) && !ContainsString(reviewers, u) {
+				reviewers = append(reviewers, u)
+			}
+		}
+		for _, l := range r.Then.AddLabels {
+			if !ContainsString(mr.Labels, l) && !ContainsString(addLabels, l) {
+				addLabels = append(addLabels, l)
+			}
+		}
+		for _, l := range r.Then.RemoveLabels {
+			if ContainsString(mr.Labels, l) && !ContainsString(removeLabels, l) {
+				removeLabels = append(removeLabels, l)
+			}
+		}
+	}
+
+	var errs []error
+	req := &UpdateMRRequest{AddLabels: strings.Join(addLabels, ","), RemoveLabels: strings.Join(removeLabels, ",")}
+	req.AssigneeIDs, err = client.addMembers(ev.Project, mr.Assignees, assign)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	req.ReviewerIDs, err = client.addMembers(ev.Project, mr.Reviewers, reviewers)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if req.AddLabels != "" || req.RemoveLabels != "" || req.AssigneeIDs != nil || req.ReviewerIDs != nil {
+		if _, err := client.UpdateMR(ev.Project, iid, req); err != nil {
+			return nil, err
+		}
+	}
+
+	vars := strings.NewReplacer(
+		"{author}", ev.Author,
+		"{title}", mr.Title,
+		"{ref}", mr.SourceBranch,
+		"{status}", ev.Status,
+		"{iid}", strconv.Itoa(iid),
+		"{url}", ev.URL,
+	)
+	names := make([]string, len(matched))
+	for i, r := range matched {
+		names[i] = r.Name
+		if r.Then.Comment == "" {
+			continue
+		}
+		body := strings.TrimSpace(vars.Replace(r.Then.Comment)) + "\n\n" + ruleMarker
+		if _, err := client.CreateMRNote(ev.Project, iid, body); err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", r.Name, err))
+		}
+	}
+	return names, errors.Join(errs...)
+}
+
+// addMembers returns the IDs of current plus the project members named in
+// add, or nil when add is empty. Usernames that are not members are an
+// error after the others are added.
+func (c *Client) addMembers(projectPath string, current []User, add []string) ([]int, error) {
+	if len(add) == 0 {
+		return nil, nil
+	}
+	ids, unknown, err := c.MemberIDs(projectPath, add)
+	if err != nil {
+		return nil, err
+	}
+	if len(unknown) > 0 {
+		err = fmt.Errorf("%w: not project members: %s", ErrNotFound, strings.Join(unknown, ", "))
+	}
+	if len(ids) == 0 {
+		return nil, err
+	}
+	out := make([]int, 0, len(current)+len(ids))
+	for _, u := range current {
+		out = append(out, u.ID)
+	}
+	return append(out, ids...), err
+}
+
+func hasUser(users []User, username string) bool {
+	for _, u := range users {
+		if u.Username == strings.TrimPrefix(username, "@") {
+			return true
+		}
+	}
+	return false
+}
+
+// removeString returns list without s
+func removeString(list []string, s string) []string {
+	out := list[:0:0]
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// ContainsString reports whether list holds s
+func ContainsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
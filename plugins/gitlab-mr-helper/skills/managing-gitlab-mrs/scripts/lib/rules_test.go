@@ -0,0 +1,149 @@
+package lib_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+const testRules = `rules:
+  - name: triage
+    when:
+      label_added: needs-review
+    then:
+      reviewers: ["@bob"]
+      comment: |
+        Thanks @{author}! @bob will review !{iid}.
+  - name: ci-broken
+    when:
+      pipeline: failed
+      branch: feature/*
+    then:
+      add_labels: [ci-broken]
+      remove_labels: [frontend]
+  - when:
+      event: note:comment
+    then:
+      assign: [alice]
+`
+
+func writeRules(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRules(t *testing.T) {
+	rs, err := lib.LoadRules(writeRules(t, testRules))
+	if err != nil {
+		t.Fatalf("LoadRules: %v", err)
+	}
+	if len(rs.Rules) != 3 || rs.Rules[2].Name != "rule 3" || rs.Rules[1].When.Branch != "feature/*" {
+		t.Errorf("LoadRules = %+v", rs.Rules)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "no trigger", content: "rules:\n  - name: x\n    when: {}\n    then: {comment: hi}\n", wantErr: "x: when needs"},
+		{name: "no action", content: "rules:\n  - name: x\n    when: {event: note}\n    then: {}\n", wantErr: "x: then has no actions"},
+		{name: "bad branch", content: "rules:\n  - name: x\n    when: {pipeline: failed, branch: \"[\"}\n    then: {comment: hi}\n", wantErr: "invalid branch pattern"},
+		{name: "typo", content: "rules:\n  - name: x\n    wehn: {event: note}\n", wantErr: `unknown field "wehn"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := lib.LoadRules(writeRules(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadRules error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := lib.LoadRules(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+		t.Error("LoadRules of a missing explicit file succeeded")
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	rs, err := lib.LoadRules(writeRules(t, testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		ev   lib.WebhookEvent
+		want []string
+	}{
+		{name: "label added", ev: lib.WebhookEvent{Kind: "merge_request", Action: "update", AddedLabels: []string{"needs-review"}}, want: []string{"triage"}},
+		{name: "label removed", ev: lib.WebhookEvent{Kind: "merge_request", Action: "update", RemovedLabels: []string{"needs-review"}}},
+		{name: "failed pipeline on a feature branch", ev: lib.WebhookEvent{Kind: "pipeline", Status: "failed", Ref: "feature/login"}, want: []string{"ci-broken"}},
+		{name: "failed pipeline on main", ev: lib.WebhookEvent{Kind: "pipeline", Status: "failed", Ref: "main"}},
+		{name: "comment", ev: lib.WebhookEvent{Kind: "note", Action: "comment", Body: "LGTM"}, want: []string{"rule 3"}},
+		{name: "comment posted by a rule", ev: lib.WebhookEvent{Kind: "note", Action: "comment", Body: "Thanks\n\n<!-- gitlab-helper rule -->"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range rs.Rules {
+				if r.Matches(&tt.ev) {
+					got = append(got, r.Name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matching rules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRulesetApply(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+	rs, err := lib.LoadRules(writeRules(t, testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed pipeline of feature/login is mapped to its open MR !1
+	ev := &lib.WebhookEvent{Kind: "pipeline", Action: "failed", Status: "failed", Project: gitlabtest.ProjectPath, Ref: "feature/login"}
+	applied, err := rs.Apply(client, ev)
+	if err != nil || !reflect.DeepEqual(applied, []string{"ci-broken"}) {
+		t.Fatalf("Apply(pipeline) = %v, %v", applied, err)
+	}
+	mr, _ := client.GetMR(gitlabtest.ProjectPath, 1)
+	if !reflect.DeepEqual(mr.Labels, []string{"ci-broken"}) {
+		t.Errorf("labels = %v, want [ci-broken]", mr.Labels)
+	}
+
+	ev = &lib.WebhookEvent{Kind: "merge_request", Action: "update", Project: gitlabtest.ProjectPath, MRIID: 2, Author: "alice", AddedLabels: []string{"needs-review"}}
+	if applied, err = rs.Apply(client, ev); err != nil || len(applied) != 1 {
+		t.Fatalf("Apply(label) = %v, %v", applied, err)
+	}
+	mr, _ = client.GetMR(gitlabtest.ProjectPath, 2)
+	if len(mr.Reviewers) != 2 || mr.Reviewers[1].Username != "bob" {
+		t.Errorf("reviewers = %+v, want alice (already there) and bob", mr.Reviewers)
+	}
+	notes := srv.Project(gitlabtest.ProjectPath).Notes[2]
+	if len(notes) != 1 || !strings.HasPrefix(notes[0].Body, "Thanks @alice! @bob will review !2.\n\n<!--") {
+		t.Errorf("notes = %+v", notes)
+	}
+
+	// Unknown users are reported; events without an MR do nothing
+	bad := &lib.Ruleset{Rules: []lib.Rule{{Name: "x", When: lib.RuleCondition{Event: "merge_request"}, Then: lib.RuleActions{Assign: []string{"nobody"}}}}}
+	if _, err := bad.Apply(client, &lib.WebhookEvent{Kind: "merge_request", Project: gitlabtest.ProjectPath, MRIID: 1}); lib.ExitCode(err) != lib.ExitNotFound {
+		t.Errorf("Apply with an unknown user error = %v, want not found", err)
+	}
+	if applied, err := rs.Apply(client, &lib.WebhookEvent{Kind: "pipeline", Status: "failed", Project: gitlabtest.ProjectPath, Ref: "feature/none"}); err != nil || applied != nil {
+		t.Errorf("Apply without an MR = %v, %v", applied, err)
+	}
+}
@@ -0,0 +1,212 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AllowSecretMarker on a line exempts it from the secret scan, e.g. in a
+// test fixture: `token = "glpat-..." # gitlab-helper:allow-secret`
+const AllowSecretMarker = "gitlab-helper:allow-secret"
+
+// SecretRule is a regular expression matching a kind of credential
+type SecretRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// DefaultSecretRules catch common credentials with few false positives
+var DefaultSecretRules = []SecretRule{
+	{Name: "private-key", Pattern: `-----BEGIN (RSA |EC |DSA |OPENSSH |PGP |ENCRYPTED )?PRIVATE KEY( BLOCK)?-----`},
+	{Name: "gitlab-token", Pattern: `\b(glpat|gldt|glrt|glptt|glsoat)-[0-9A-Za-z_-]{20,}`},
+	{Name: "github-token", Pattern: `\b(gh[pousr]_[0-9A-Za-z]{36}|github_pat_[0-9A-Za-z_]{60,})`},
+	{Name: "aws-access-key", Pattern: `\b(AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{Name: "google-api-key", Pattern: `\bAIza[0-9A-Za-z_-]{35}\b`},
+	{Name: "slack-token", Pattern: `\bxox[abposr]-[0-9A-Za-z-]{10,}`},
+	{Name: "stripe-key", Pattern: `\b[rs]k_live_[0-9A-Za-z]{20,}`},
+	{Name: "password-assignment", Pattern: `(?i)\b(password|passwd|pwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token)\b["']?\s*[:=]\s*["'][^"'\s$]{8,}["']`},
+}
+
+// SecretFinding is a likely credential added by a change
+type SecretFinding struct {
+	Rule  string
+	Path  string // file, or e.g. "description" for MR text
+	Line  int    // 0 when unknown
+	Match string // redacted
+}
+
+func (f SecretFinding) String() string {
+	where := f.Path
+	if f.Line > 0 {
+		where += ":" + strconv.Itoa(f.Line)
+	}
+	return fmt.Sprintf("%s: %s (%s)", where, f.Match, f.Rule)
+}
+
+// SecretScanner checks text against compiled secret rules
+type SecretScanner struct {
+	rules      []compiledRule
+	allow      []*regexp.Regexp
+	allowPaths []string
+}
+
+type compiledRule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// NewSecretScanner compiles the default rules adjusted by settings, which
+// may be nil
+func NewSecretScanner(settings *SecretSettings) (*SecretScanner, error) {
+	if settings == nil {
+		settings = &SecretSettings{}
+	}
+	disabled := make(map[string]bool)
+	for _, name := range settings.DisableRules {
+		disabled[name] = true
+	}
+	s := &SecretScanner{allowPaths: settings.AllowPaths}
+	for _, r := range append(append([]SecretRule{}, DefaultSecretRules...), settings.Rules...) {
+		if disabled[r.Name] {
+			continue
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret rule %q: %w", r.Name, err)
+		}
+		s.rules = append(s.rules, compiledRule{r.Name, re})
+	}
+	for _, a := range settings.Allow {
+		re, err := regexp.Compile(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret allow pattern %q: %w", a, err)
+		}
+		s.allow = append(s.allow, re)
+	}
+	for _, p := range settings.AllowPaths {
+		if _, err := path.Match(strings.ReplaceAll(p, "**", "*"), ""); err != nil {
+			return nil, fmt.Errorf("invalid secret allow path %q: %w", p, err)
+		}
+	}
+	return s, nil
+}
+
+// ScanDiffs checks the lines added by diffs; removed and context lines are
+// ignored, so deleting a leaked key does not trip the scan. Hunks with a
+// malformed header are skipped, and truncated diffs are only scanned as far
+// as they go (see WarnPartialScan).
+func (s *SecretScanner) ScanDiffs(diffs []Diff) []SecretFinding {
+	var findings []SecretFinding
+	for _, d := range diffs {
+		if d.DeletedFile || s.allowedPath(d.NewPath) {
+			continue
+		}
+		line, inHunk := 0, false
+		for _, l := range strings.Split(d.Diff, "\n") {
+			switch {
+			case strings.HasPrefix(l, "@@"):
+				line, inHunk = hunkStart(l)
+			case !inHunk, strings.HasPrefix(l, "+++"), strings.HasPrefix(l, "---"):
+			case strings.HasPrefix(l, "+"):
+				findings = append(findings, s.scanLine(d.NewPath, line, l[1:])...)
+				line++
+			case strings.HasPrefix(l, " "):
+				line++
+			}
+		}
+	}
+	return findings
+}
+
+// WarnPartialScan warns on stderr about the diffs cut at MaxDiffBytes: a
+// scan finding nothing in them only vouches for their start
+func WarnPartialScan(diffs []Diff) {
+	var paths []string
+	for _, d := range diffs {
+		if d.Truncated && !d.DeletedFile {
+			paths = append(paths, d.NewPath)
+		}
+	}
+	if len(paths) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: only the first %s of the diff was scanned for secrets in %s\n", FormatSize(MaxDiffBytes), FormatFileList(paths))
+	}
+}
+
+// ScanText checks every line of a text, e.g. an MR description; where
+// names it in findings
+func (s *SecretScanner) ScanText(where, text string) []SecretFinding {
+	var findings []SecretFinding
+	for i, l := range strings.Split(text, "\n") {
+		findings = append(findings, s.scanLine(where, i+1, l)...)
+	}
+	return findings
+}
+
+// ScanMR checks the lines an MR adds and its title and description
+func (s *SecretScanner) ScanMR(diffs []Diff, title, description string) []SecretFinding {
+	findings := s.ScanDiffs(diffs)
+	findings = append(findings, s.ScanText("title", title)...)
+	return append(findings, s.ScanText("description", description)...)
+}
+
+func (s *SecretScanner) scanLine(where string, line int, text string) []SecretFinding {
+	if strings.Contains(text, AllowSecretMarker) {
+		return nil
+	}
+	var findings []SecretFinding
+	for _, r := range s.rules {
+		for _, m := range r.re.FindAllString(text, -1) {
+			if s.allowed(m) {
+				continue
+			}
+			findings = append(findings, SecretFinding{Rule: r.name, Path: where, Line: line, Match: redact(m)})
+		}
+	}
+	return findings
+}
+
+func (s *SecretScanner) allowed(match string) bool {
+	for _, re := range s.allow {
+		if re.MatchString(match) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedPath matches a file against the allowed globs, see MatchGlob
+func (s *SecretScanner) allowedPath(p string) bool {
+	for _, pattern := range s.allowPaths {
+		if MatchGlob(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// hunkStart returns the first new-file line of a "@@ -a,b +c,d @@" header,
+// and false when the header is malformed
+func hunkStart(header string) (int, bool) {
+	_, after, ok := strings.Cut(header, "+")
+	fields := strings.FieldsFunc(after, func(r rune) bool { return r == ',' || r == ' ' })
+	if !ok || len(fields) == 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// redact keeps enough of a secret to recognize it
+func redact(s string) string {
+	if len(s) <= 12 {
+		return s[:min(4, len(s))] + Ellipsis
+	}
+	return s[:8] + Ellipsis
+}
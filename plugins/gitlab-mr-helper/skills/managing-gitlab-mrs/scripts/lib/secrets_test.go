@@ -0,0 +1,127 @@
+package lib_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+// Fake credentials are assembled so this file does not trip scanners itself
+var (
+	gitlabToken = "glpat-" + strings.Repeat("x1Y2", 5)
+	awsKey      = "AKIA" + strings.Repeat("ABCD", 4)
+)
+
+func TestScanDiffs(t *testing.T) {
+	hunk := "@@ -10,2 +10,3 @@ config\n context\n-old = 1\n+token = \"" + gitlabToken + "\"\n+plain = 2\n"
+	tests := []struct {
+		name     string
+		settings lib.SecretSettings
+		diff     lib.Diff
+		want     []string // "rule path:line"
+	}{
+		{name: "added token", diff: lib.Diff{NewPath: "app.cfg", Diff: hunk}, want: []string{"gitlab-token app.cfg:11"}},
+		{name: "removed token", diff: lib.Diff{NewPath: "app.cfg", Diff: "@@ -1 +1 @@\n-key = \"" + awsKey + "\"\n+key = env\n"}},
+		{name: "deleted file", diff: lib.Diff{NewPath: "app.cfg", DeletedFile: true, Diff: hunk}},
+		{name: "inline allow", diff: lib.Diff{NewPath: "a.go", Diff: "@@ -0,0 +1 @@\n+k := \"" + awsKey + "\" // gitlab-helper:allow-secret\n"}},
+		{name: "allowed path", settings: lib.SecretSettings{AllowPaths: []string{"testdata/**"}}, diff: lib.Diff{NewPath: "testdata/x/app.cfg", Diff: hunk}},
+		{name: "allowed nested directory", settings: lib.SecretSettings{AllowPaths: []string{"**/testdata/*"}}, diff: lib.Diff{NewPath: "pkg/auth/testdata/app.cfg", Diff: hunk}},
+		{name: "nested directory glob", settings: lib.SecretSettings{AllowPaths: []string{"**/testdata/*"}}, diff: lib.Diff{NewPath: "pkg/testdata/x/app.cfg", Diff: hunk}, want: []string{"gitlab-token pkg/testdata/x/app.cfg:11"}},
+		{name: "allowed base name", settings: lib.SecretSettings{AllowPaths: []string{"*.cfg"}}, diff: lib.Diff{NewPath: "conf/app.cfg", Diff: hunk}},
+		{name: "allowed match", settings: lib.SecretSettings{Allow: []string{"x1Y2x1Y2"}}, diff: lib.Diff{NewPath: "app.cfg", Diff: hunk}},
+		{name: "disabled rule", settings: lib.SecretSettings{DisableRules: []string{"gitlab-token"}}, diff: lib.Diff{NewPath: "app.cfg", Diff: hunk}},
+		{
+			name: "malformed hunk header skipped",
+			diff: lib.Diff{NewPath: "app.cfg", Diff: "@@ -1 +@@\n+token = \"" + gitlabToken + "\"\n" + hunk},
+			want: []string{"gitlab-token app.cfg:11"},
+		},
+		{name: "truncated hunk header", diff: lib.Diff{NewPath: "app.cfg", Diff: "@@ -1 +"}},
+		{
+			name:     "custom rule",
+			settings: lib.SecretSettings{Rules: []lib.SecretRule{{Name: "internal", Pattern: `ACME-[0-9]{6}`}}},
+			diff:     lib.Diff{NewPath: "b.txt", Diff: "@@ -0,0 +1,2 @@\n+id ACME-123456\n+id ACME-12\n"},
+			want:     []string{"internal b.txt:1"},
+		},
+		{
+			name: "password assignment",
+			diff: lib.Diff{NewPath: "db.yml", Diff: "@@ -0,0 +1,2 @@\n+password: \"s3cr3t-Passw0rd\"\n+password: \"$DB_PASSWORD\"\n"},
+			want: []string{"password-assignment db.yml:1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner, err := lib.NewSecretScanner(&tt.settings)
+			if err != nil {
+				t.Fatalf("NewSecretScanner: %v", err)
+			}
+			var got []string
+			for _, f := range scanner.ScanDiffs([]lib.Diff{tt.diff}) {
+				if strings.Contains(f.Match, gitlabToken) {
+					t.Errorf("match %q is not redacted", f.Match)
+				}
+				got = append(got, fmt.Sprintf("%s %s:%d", f.Rule, f.Path, f.Line))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanMRText(t *testing.T) {
+	scanner, err := lib.NewSecretScanner(nil)
+	if err != nil {
+		t.Fatalf("NewSecretScanner: %v", err)
+	}
+	findings := scanner.ScanMR(nil, "Fix login", "Steps:\n\nuse key "+awsKey)
+	if len(findings) != 1 || findings[0].Path != "description" || findings[0].Line != 3 || findings[0].Rule != "aws-access-key" {
+		t.Errorf("got %v, want aws-access-key in description:3", findings)
+	}
+}
+
+func TestNewSecretScannerInvalid(t *testing.T) {
+	for _, s := range []lib.SecretSettings{
+		{Rules: []lib.SecretRule{{Name: "bad", Pattern: "("}}},
+		{Allow: []string{"["}},
+		{AllowPaths: []string{"[a"}},
+	} {
+		if _, err := lib.NewSecretScanner(&s); err == nil {
+			t.Errorf("NewSecretScanner(%+v) succeeded, want error", s)
+		}
+	}
+}
+
+func TestParseGitDiff(t *testing.T) {
+	output := `diff --git a/new.txt b/new.txt
+new file mode 100644
+index 0000000..e69de29
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++hello
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+diff --git a/img.png b/img.png
+Binary files a/img.png and b/img.png differ
+`
+	diffs := lib.ParseGitDiff(output)
+	if len(diffs) != 3 {
+		t.Fatalf("got %d diffs, want 3", len(diffs))
+	}
+	if d := diffs[0]; d.NewPath != "new.txt" || d.OldPath != "new.txt" || !d.NewFile || d.Diff != "@@ -0,0 +1 @@\n+hello\n" {
+		t.Errorf("new file: got %+v", d)
+	}
+	if d := diffs[1]; d.NewPath != "old.txt" || !d.DeletedFile || d.Diff != "@@ -1 +0,0 @@\n-bye\n" {
+		t.Errorf("deleted file: got %+v", d)
+	}
+	if d := diffs[2]; d.Diff != "" {
+		t.Errorf("binary file: got diff %q", d.Diff)
+	}
+}
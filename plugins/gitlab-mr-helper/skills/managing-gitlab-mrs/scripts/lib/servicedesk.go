@@ -0,0 +1,69 @@
+package lib
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// SupportBot is the user GitLab files Service Desk issues and the emails
+// of their external authors as
+const SupportBot = "support-bot"
+
+// ServiceDesk is the Service Desk setup of a project
+type ServiceDesk struct {
+	Enabled bool `json:"service_desk_enabled"`
+	// Address is the email address that opens issues
+	Address string `json:"service_desk_address"`
+}
+
+// GetServiceDesk returns whether a project's Service Desk is enabled, and
+// its address
+func (c *Client) GetServiceDesk(projectPath string) (*ServiceDesk, error) {
+	endpoint := c.apiURL("/projects/%s", url.PathEscape(projectPath))
+
+	var sd ServiceDesk
+	if err := c.do("GET", endpoint, nil, &sd, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &sd, nil
+}
+
+// ListServiceDeskIssues lists the issues opened by email through Service
+// Desk, newest first
+func (c *Client) ListServiceDeskIssues(projectPath string, opts *IssueListOptions) ([]Issue, error) {
+	o := *opts
+	o.AuthorUsername = SupportBot
+	return c.ListProjectIssues(projectPath, &o)
+}
+
+// ListIssueNotes lists the notes (comments and system notes) of an issue,
+// oldest first
+func (c *Client) ListIssueNotes(projectPath string, issueIID int) ([]Note, error) {
+	endpoint := c.apiURL("/projects/%s/issues/%d/notes", url.PathEscape(projectPath), issueIID)
+	return getAll[Note](c, endpoint, url.Values{"order_by": {"created_at"}, "sort": {"asc"}}, 0)
+}
+
+// CreateInternalIssueNote adds a comment to an issue that only project
+// members see. Unlike other comments, Service Desk does not email it to
+// the external author.
+func (c *Client) CreateInternalIssueNote(projectPath string, issueIID int, body string) (*Note, error) {
+	endpoint := c.apiURL("/projects/%s/issues/%d/notes", url.PathEscape(projectPath), issueIID)
+
+	var note Note
+	if err := c.do("POST", endpoint, map[string]any{"body": body, "internal": true}, &note, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// AwaitingReply reports whether the external author of a Service Desk
+// issue spoke last: nobody answered yet, or their latest email came after
+// the team's last public comment. System and internal notes do not count.
+func AwaitingReply(notes []Note) bool {
+	for i := len(notes) - 1; i >= 0; i-- {
+		if n := notes[i]; !n.System && !n.Internal {
+			return n.Author.Username == SupportBot
+		}
+	}
+	return true
+}
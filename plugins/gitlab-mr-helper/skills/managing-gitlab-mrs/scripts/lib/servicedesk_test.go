@@ -0,0 +1,76 @@
+package lib_test
+
+import (
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestListServiceDeskIssues(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	sd, err := client.GetServiceDesk(gitlabtest.NestedProjectPath)
+	if err != nil {
+		t.Fatalf("GetServiceDesk: %v", err)
+	}
+	if !sd.Enabled || sd.Address == "" {
+		t.Errorf("GetServiceDesk = %+v, want enabled with an address", sd)
+	}
+
+	// Issues opened by project members are left out
+	if _, err := client.CreateIssue(gitlabtest.NestedProjectPath, &lib.CreateIssueRequest{Title: "Refresh docs"}); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+	issues, err := client.ListServiceDeskIssues(gitlabtest.NestedProjectPath, &lib.IssueListOptions{})
+	if err != nil {
+		t.Fatalf("ListServiceDeskIssues: %v", err)
+	}
+	if len(issues) != 2 || issues[0].IID != 2 || issues[1].ServiceDeskReplyTo != "jane@customer.example" {
+		t.Errorf("ListServiceDeskIssues = %+v", issues)
+	}
+
+	notes, err := client.ListIssueNotes(gitlabtest.NestedProjectPath, 1)
+	if err != nil {
+		t.Fatalf("ListIssueNotes: %v", err)
+	}
+	if !lib.AwaitingReply(notes) {
+		t.Errorf("issue #1 notes = %+v, want Jane awaiting a reply", notes)
+	}
+	if _, err := client.CreateInternalIssueNote(gitlabtest.NestedProjectPath, 1, "Looking into it"); err != nil {
+		t.Fatalf("CreateInternalIssueNote: %v", err)
+	}
+	if notes, err = client.ListIssueNotes(gitlabtest.NestedProjectPath, 1); err != nil || !notes[len(notes)-1].Internal || !lib.AwaitingReply(notes) {
+		t.Errorf("after an internal note: notes = %+v, %v; want still awaiting a reply", notes, err)
+	}
+	if _, err := client.CreateIssueNote(gitlabtest.NestedProjectPath, 1, "Fixed, please retry"); err != nil {
+		t.Fatalf("CreateIssueNote: %v", err)
+	}
+	if notes, err = client.ListIssueNotes(gitlabtest.NestedProjectPath, 1); err != nil || lib.AwaitingReply(notes) {
+		t.Errorf("after a reply: notes = %+v, %v; want answered", notes, err)
+	}
+}
+
+func TestAwaitingReply(t *testing.T) {
+	bot := lib.User{Username: lib.SupportBot}
+	agent := lib.User{Username: "alice"}
+	tests := []struct {
+		name  string
+		notes []lib.Note
+		want  bool
+	}{
+		{name: "no comments", want: true},
+		{name: "answered", notes: []lib.Note{{Author: bot}, {Author: agent}}, want: false},
+		{name: "wrote again", notes: []lib.Note{{Author: agent}, {Author: bot}}, want: true},
+		{name: "system note after the email", notes: []lib.Note{{Author: bot}, {Author: agent, System: true}}, want: true},
+		{name: "internal note after the email", notes: []lib.Note{{Author: bot}, {Author: agent, Internal: true}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lib.AwaitingReply(tt.notes); got != tt.want {
+				t.Errorf("AwaitingReply = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
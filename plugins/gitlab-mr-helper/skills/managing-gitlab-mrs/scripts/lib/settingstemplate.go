@@ -0,0 +1,410 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// SettingsTemplate is the policy propagate_settings.go applies to every
+// project of a group, e.g.
+//
+//	merge_method: ff
+//	only_allow_merge_if_pipeline_succeeds: true
+//	squash_commit_template: "%{title} (%{reference})"
+//	approvals:
+//	  required: 2
+//	  reset_on_push: true
+//	protected_branches:
+//	  - {name: main, push: maintainer, merge: developer}
+//	variables:
+//	  - {key: SONAR_TOKEN, value_env: SONAR_TOKEN, masked: true, protected: true}
+//
+// Settings the template leaves out are left alone, and so are protected
+// branches and variables it does not name.
+type SettingsTemplate struct {
+	MergeMethod               string             `json:"merge_method"`
+	PipelineMustSucceed       *bool              `json:"only_allow_merge_if_pipeline_succeeds"`
+	DiscussionsMustBeResolved *bool              `json:"only_allow_merge_if_all_discussions_are_resolved"`
+	RemoveSourceBranchOnMerge *bool              `json:"remove_source_branch_after_merge"`
+	Approvals                 *ApprovalsTemplate `json:"approvals"`
+	ProtectedBranches         []BranchTemplate   `json:"protected_branches"`
+	Variables                 []VariableTemplate `json:"variables"`
+
+	// The commit templates and the default MR description; "" restores
+	// GitLab's default
+	MergeCommitTemplate   *string `json:"merge_commit_template"`
+	SquashCommitTemplate  *string `json:"squash_commit_template"`
+	MergeRequestsTemplate *string `json:"merge_requests_template"`
+}
+
+// ApprovalsTemplate sets the approval settings. Required is that of the
+// any_approver rule, which is created when missing.
+type ApprovalsTemplate struct {
+	Required         *int  `json:"required"`
+	ResetOnPush      *bool `json:"reset_on_push"`
+	AuthorCanApprove *bool `json:"author_can_approve"`
+}
+
+// BranchTemplate protects a branch or wildcard. Push and Merge are the
+// lowest roles allowed (none, developer, maintainer, ...).
+type BranchTemplate struct {
+	Name  string `json:"name"`
+	Push  string `json:"push"`
+	Merge string `json:"merge"`
+
+	push, merge int
+}
+
+// VariableTemplate is a CI/CD variable. Its value is Value, or that of the
+// environment variable ValueEnv so secrets stay out of the template.
+type VariableTemplate struct {
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	ValueEnv  string `json:"value_env"`
+	Protected bool   `json:"protected"`
+	Masked    bool   `json:"masked"`
+}
+
+// anyApproverRule names the any_approver rule when it has to be created,
+// as the GitLab UI does
+const anyApproverRule = "All Members"
+
+// LoadSettingsTemplate reads a settings template file, resolving
+// value_env variables from the environment
+func LoadSettingsTemplate(file string) (*SettingsTemplate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings template: %w", err)
+	}
+	t := &SettingsTemplate{}
+	if err := DecodeYAML(data, t); err != nil {
+		return nil, fmt.Errorf("invalid settings template %s: %w", file, err)
+	}
+	if err := t.validate(); err != nil {
+		return nil, fmt.Errorf("invalid settings template %s: %w", file, err)
+	}
+	return t, nil
+}
+
+func (t *SettingsTemplate) validate() error {
+	switch t.MergeMethod {
+	case "", "merge", "rebase_merge", "ff":
+	default:
+		return fmt.Errorf("merge_method %q: expected merge, rebase_merge or ff", t.MergeMethod)
+	}
+	if tmpl := t.MergeCommitTemplate; tmpl != nil && len(*tmpl) > CommitTemplateMaxLength {
+		return fmt.Errorf("merge_commit_template is longer than %d characters", CommitTemplateMaxLength)
+	}
+	if tmpl := t.SquashCommitTemplate; tmpl != nil && len(*tmpl) > CommitTemplateMaxLength {
+		return fmt.Errorf("squash_commit_template is longer than %d characters", CommitTemplateMaxLength)
+	}
+	if a := t.Approvals; a != nil && a.Required != nil && *a.Required < 0 {
+		return errors.New("approvals.required must not be negative")
+	}
+	for i := range t.ProtectedBranches {
+		b := &t.ProtectedBranches[i]
+		if b.Name == "" {
+			return fmt.Errorf("protected_branches[%d]: name is required", i)
+		}
+		if b.Push == "" || b.Merge == "" {
+			return fmt.Errorf("protected_branches %s: push and merge are required", b.Name)
+		}
+		var err error
+		if b.push, err = ParseAccessLevel(b.Push); err != nil {
+			return fmt.Errorf("protected_branches %s: push: %w", b.Name, err)
+		}
+		if b.merge, err = ParseAccessLevel(b.Merge); err != nil {
+			return fmt.Errorf("protected_branches %s: merge: %w", b.Name, err)
+		}
+	}
+	for i := range t.Variables {
+		v := &t.Variables[i]
+		if v.Key == "" {
+			return fmt.Errorf("variables[%d]: key is required", i)
+		}
+		if v.ValueEnv != "" {
+			if v.Value != "" {
+				return fmt.Errorf("variables %s: value and value_env are exclusive", v.Key)
+			}
+			value, ok := os.LookupEnv(v.ValueEnv)
+			if !ok {
+				return fmt.Errorf("variables %s: environment variable %s is not set", v.Key, v.ValueEnv)
+			}
+			v.Value = value
+		}
+	}
+	return nil
+}
+
+// SettingChange is one difference between a project and a template
+type SettingChange struct {
+	// Setting names what changes, e.g. "merge_method" or
+	// "protected_branches main"
+	Setting string
+	// From is empty when the setting is added
+	From, To string
+	// Skipped says why the change cannot be made; ApplySettings leaves it
+	Skipped string
+
+	apply func(c *Client, projectPath string) error
+}
+
+// DiffSettings compares a project with the template and returns the
+// changes that would make it comply, in the order ApplySettings makes them
+func (c *Client) DiffSettings(projectPath string, t *SettingsTemplate) ([]SettingChange, error) {
+	var changes []SettingChange
+
+	if t.MergeMethod != "" || t.PipelineMustSucceed != nil || t.DiscussionsMustBeResolved != nil || t.RemoveSourceBranchOnMerge != nil ||
+		t.MergeCommitTemplate != nil || t.SquashCommitTemplate != nil || t.MergeRequestsTemplate != nil {
+		s, err := c.GetProjectSettings(projectPath)
+		if err != nil {
+			return nil, err
+		}
+		if t.MergeMethod != "" && t.MergeMethod != s.MergeMethod {
+			changes = append(changes, projectChange("merge_method", s.MergeMethod, t.MergeMethod))
+		}
+		for _, attr := range []struct {
+			name    string
+			current bool
+			want    *bool
+		}{
+			{"only_allow_merge_if_pipeline_succeeds", s.OnlyAllowMergeIfPipelineSucceeds, t.PipelineMustSucceed},
+			{"only_allow_merge_if_all_discussions_are_resolved", s.OnlyAllowMergeIfAllDiscussionsAreResolved, t.DiscussionsMustBeResolved},
+			{"remove_source_branch_after_merge", s.RemoveSourceBranchAfterMerge, t.RemoveSourceBranchOnMerge},
+		} {
+			if attr.want != nil && *attr.want != attr.current {
+				changes = append(changes, projectChange(attr.name, attr.current, *attr.want))
+			}
+		}
+		for _, attr := range []struct {
+			name    string
+			current string
+			want    *string
+		}{
+			{"merge_commit_template", s.MergeCommitTemplate, t.MergeCommitTemplate},
+			{"squash_commit_template", s.SquashCommitTemplate, t.SquashCommitTemplate},
+			{"merge_requests_template", s.MergeRequestsTemplate, t.MergeRequestsTemplate},
+		} {
+			if attr.want != nil && *attr.want != attr.current {
+				ch := projectChange(attr.name, attr.current, *attr.want)
+				ch.From, ch.To = quotedTemplate(attr.current), quotedTemplate(*attr.want)
+				changes = append(changes, ch)
+			}
+		}
+	}
+
+	if a := t.Approvals; a != nil {
+		approvalChanges, err := c.diffApprovals(projectPath, a)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, approvalChanges...)
+	}
+
+	if len(t.ProtectedBranches) > 0 {
+		protected, err := c.ListProtectedBranches(projectPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range t.ProtectedBranches {
+			changes = appendBranchChange(changes, protected, b)
+		}
+	}
+
+	if len(t.Variables) > 0 {
+		vars, err := c.ListProjectVariables(projectPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range t.Variables {
+			changes = appendVariableChange(changes, vars, v)
+		}
+	}
+	return changes, nil
+}
+
+// ApplySettings makes the changes DiffSettings returned. A failed change
+// does not stop the others; the errors are returned together.
+func (c *Client) ApplySettings(projectPath string, changes []SettingChange) error {
+	var errs []error
+	for _, ch := range changes {
+		if ch.Skipped != "" {
+			continue
+		}
+		if err := ch.apply(c, projectPath); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ch.Setting, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// quotedTemplate shows a template on one line, or "(default)" when empty
+func quotedTemplate(s string) string {
+	if s == "" {
+		return "(default)"
+	}
+	return strconv.Quote(s)
+}
+
+// projectChange sets a project attribute with UpdateProjectSettings
+func projectChange(attr string, from, to interface{}) SettingChange {
+	return SettingChange{Setting: attr, From: fmt.Sprint(from), To: fmt.Sprint(to), apply: func(c *Client, projectPath string) error {
+		return c.UpdateProjectSettings(projectPath, map[string]interface{}{attr: to})
+	}}
+}
+
+func (c *Client) diffApprovals(projectPath string, a *ApprovalsTemplate) ([]SettingChange, error) {
+	var changes []SettingChange
+	if a.ResetOnPush != nil || a.AuthorCanApprove != nil {
+		s, err := c.GetApprovalSettings(projectPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, attr := range []struct {
+			setting, name string
+			current       bool
+			want          *bool
+		}{
+			{"approvals reset_on_push", "reset_approvals_on_push", s.ResetApprovalsOnPush, a.ResetOnPush},
+			{"approvals author_can_approve", "merge_requests_author_approval", s.MergeRequestsAuthorApproval, a.AuthorCanApprove},
+		} {
+			if attr.want == nil || *attr.want == attr.current {
+				continue
+			}
+			name, value := attr.name, *attr.want
+			changes = append(changes, SettingChange{Setting: attr.setting, From: strconv.FormatBool(attr.current), To: strconv.FormatBool(value),
+				apply: func(c *Client, projectPath string) error {
+					return c.UpdateApprovalSettings(projectPath, map[string]interface{}{name: value})
+				}})
+		}
+	}
+	if a.Required == nil {
+		return changes, nil
+	}
+
+	required := *a.Required
+	rules, err := c.ListApprovalRules(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		if r.RuleType != "any_approver" {
+			continue
+		}
+		if r.ApprovalsRequired != required {
+			id := r.ID
+			changes = append(changes, SettingChange{Setting: "approvals required", From: strconv.Itoa(r.ApprovalsRequired), To: strconv.Itoa(required),
+				apply: func(c *Client, projectPath string) error {
+					return c.SetApprovalsRequired(projectPath, id, required)
+				}})
+		}
+		return changes, nil
+	}
+	if required > 0 {
+		changes = append(changes, SettingChange{Setting: "approvals required", From: "0", To: strconv.Itoa(required),
+			apply: func(c *Client, projectPath string) error {
+				_, err := c.CreateApprovalRule(projectPath, &ApprovalRule{Name: anyApproverRule, RuleType: "any_approver", ApprovalsRequired: required})
+				return err
+			}})
+	}
+	return changes, nil
+}
+
+// appendBranchChange protects b when it is not, or re-protects it with
+// the template's levels. GitLab cannot change the levels in place, so the
+// branch is unprotected first and protected again with its previous levels
+// when that fails. Branches that also allow users or groups are skipped,
+// as re-protecting would drop them.
+func appendBranchChange(changes []SettingChange, protected []ProtectedBranch, b BranchTemplate) []SettingChange {
+	ch := SettingChange{Setting: "protected_branches " + b.Name, To: formatLevels(b.push, b.merge)}
+	var previous *ProtectedBranch
+	for i := range protected {
+		if protected[i].Name == b.Name {
+			previous = &protected[i]
+		}
+	}
+	if previous == nil {
+		ch.apply = func(c *Client, projectPath string) error {
+			return c.ProtectBranch(projectPath, b.Name, b.push, b.merge)
+		}
+		return append(changes, ch)
+	}
+
+	push, merge, ok := previous.RoleLevels()
+	if !ok {
+		ch.From = "per-user, per-group or several role levels"
+		ch.Skipped = "re-protecting would drop them; change it in GitLab"
+		return append(changes, ch)
+	}
+	if push == b.push && merge == b.merge {
+		return changes
+	}
+	from := formatLevels(push, merge)
+	ch.From = from
+	allowForcePush := previous.AllowForcePush
+	ch.apply = func(c *Client, projectPath string) error {
+		if err := c.UnprotectBranch(projectPath, b.Name); err != nil {
+			return err
+		}
+		err := c.protectBranch(projectPath, b.Name, b.push, b.merge, allowForcePush)
+		if err == nil {
+			return nil
+		}
+		if rerr := c.protectBranch(projectPath, b.Name, push, merge, allowForcePush); rerr != nil {
+			return fmt.Errorf("%w; restoring %s also failed, the branch is unprotected: %v", err, from, rerr)
+		}
+		return fmt.Errorf("%w; restored %s", err, from)
+	}
+	return append(changes, ch)
+}
+
+// appendVariableChange creates v, or updates it when its value or flags
+// differ. Masked values are not shown.
+func appendVariableChange(changes []SettingChange, vars []Variable, v VariableTemplate) []SettingChange {
+	want := Variable{Key: v.Key, Value: v.Value, Protected: v.Protected, Masked: v.Masked}
+	for _, cur := range vars {
+		if cur.Key != v.Key || cur.EnvironmentScope != "" && cur.EnvironmentScope != "*" {
+			continue
+		}
+		if cur.Value == want.Value && cur.Protected == want.Protected && cur.Masked == want.Masked {
+			return changes
+		}
+		from, to := formatVariable(&cur, false), formatVariable(&want, cur.Value != want.Value)
+		return append(changes, SettingChange{Setting: "variables " + v.Key, From: from, To: to,
+			apply: func(c *Client, projectPath string) error {
+				return c.UpdateProjectVariable(projectPath, &want)
+			}})
+	}
+	return append(changes, SettingChange{Setting: "variables " + v.Key, To: formatVariable(&want, false),
+		apply: func(c *Client, projectPath string) error {
+			return c.CreateProjectVariable(projectPath, &want)
+		}})
+}
+
+// formatLevels describes the levels of a protected branch, e.g. "push
+// maintainer, merge developer"
+func formatLevels(push, merge int) string {
+	return "push " + AccessLevelName(push) + ", merge " + AccessLevelName(merge)
+}
+
+// formatVariable describes a variable for a diff, e.g. `"info",
+// protected`. Masked values show as **** and as "(changed)" when the new
+// value differs.
+func formatVariable(v *Variable, changed bool) string {
+	s := strconv.Quote(v.Value)
+	if v.Masked {
+		s = "****"
+		if changed {
+			s = "(changed)"
+		}
+	}
+	if v.Protected {
+		s += ", protected"
+	}
+	if v.Masked {
+		s += ", masked"
+	}
+	return s
+}
@@ -0,0 +1,207 @@
+package lib_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+const testSettingsTemplate = `merge_method: ff
+only_allow_merge_if_pipeline_succeeds: true
+approvals:
+  required: 2
+  reset_on_push: true
+protected_branches:
+  - {name: main, push: none, merge: developer}
+  - {name: "release/*", push: none, merge: maintainer}
+variables:
+  - {key: LOG_LEVEL, value: info}
+  - {key: DEPLOY_TOKEN, value_env: TEST_DEPLOY_TOKEN, masked: true, protected: true}
+`
+
+func TestLoadSettingsTemplate(t *testing.T) {
+	t.Setenv("TEST_DEPLOY_TOKEN", "n3w-deploy-token")
+	tmpl, err := lib.LoadSettingsTemplate(writeRules(t, testSettingsTemplate))
+	if err != nil {
+		t.Fatalf("LoadSettingsTemplate: %v", err)
+	}
+	if tmpl.MergeMethod != "ff" || *tmpl.Approvals.Required != 2 || len(tmpl.ProtectedBranches) != 2 || tmpl.Variables[1].Value != "n3w-deploy-token" {
+		t.Errorf("LoadSettingsTemplate = %+v", tmpl)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "merge method", content: "merge_method: squash\n", wantErr: "merge_method"},
+		{name: "access level", content: "protected_branches:\n  - {name: main, push: admin, merge: developer}\n", wantErr: "main: push"},
+		{name: "missing level", content: "protected_branches:\n  - {name: main, push: none}\n", wantErr: "push and merge are required"},
+		{name: "unset env", content: "variables:\n  - {key: X, value_env: TEST_UNSET_VARIABLE}\n", wantErr: "TEST_UNSET_VARIABLE is not set"},
+		{name: "typo", content: "merge_methd: ff\n", wantErr: "merge_methd"},
+		{name: "long template", content: "squash_commit_template: " + strings.Repeat("x", 501) + "\n", wantErr: "longer than 500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := lib.LoadSettingsTemplate(writeRules(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDiffAndApplySettings(t *testing.T) {
+	t.Setenv("TEST_DEPLOY_TOKEN", "n3w-deploy-token")
+	tmpl, err := lib.LoadSettingsTemplate(writeRules(t, testSettingsTemplate))
+	if err != nil {
+		t.Fatalf("LoadSettingsTemplate: %v", err)
+	}
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	changes, err := client.DiffSettings(gitlabtest.ProjectPath, tmpl)
+	if err != nil {
+		t.Fatalf("DiffSettings: %v", err)
+	}
+	var got []string
+	for _, ch := range changes {
+		got = append(got, ch.Setting+": "+ch.From+" → "+ch.To)
+	}
+	want := []string{
+		"merge_method: merge → ff",
+		"approvals required: 1 → 2",
+		"protected_branches main: push maintainer, merge maintainer → push none, merge developer",
+		"protected_branches release/*:  → push none, merge maintainer",
+		`variables LOG_LEVEL: "debug" → "info"`,
+		"variables DEPLOY_TOKEN: ****, protected, masked → (changed), protected, masked",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("DiffSettings =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if err := client.ApplySettings(gitlabtest.ProjectPath, changes); err != nil {
+		t.Fatalf("ApplySettings: %v", err)
+	}
+	p := srv.Project(gitlabtest.ProjectPath)
+	if p.Settings.MergeMethod != "ff" || p.ApprovalRules[0].ApprovalsRequired != 2 || p.Variables[1].Value != "n3w-deploy-token" {
+		t.Errorf("project after apply: %+v, %+v, %+v", p.Settings, p.ApprovalRules, p.Variables)
+	}
+	for _, b := range p.ProtectedBranches {
+		if b.Name == "main" && (b.PushLevel() != lib.AccessNoOne || b.MergeLevel() != lib.AccessDeveloper) {
+			t.Errorf("main protected as %+v", b)
+		}
+	}
+
+	// Applying a template twice changes nothing the second time
+	if changes, err := client.DiffSettings(gitlabtest.ProjectPath, tmpl); err != nil || len(changes) != 0 {
+		t.Errorf("DiffSettings after apply = %+v, %v", changes, err)
+	}
+}
+
+func TestDiffSettingsCreatesApprovalRule(t *testing.T) {
+	tmpl, err := lib.LoadSettingsTemplate(writeRules(t, "approvals:\n  required: 1\n  author_can_approve: false\n"))
+	if err != nil {
+		t.Fatalf("LoadSettingsTemplate: %v", err)
+	}
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	changes, err := client.DiffSettings(gitlabtest.NestedProjectPath, tmpl)
+	if err != nil || len(changes) != 1 || changes[0].From != "0" {
+		t.Fatalf("DiffSettings = %+v, %v", changes, err)
+	}
+	if err := client.ApplySettings(gitlabtest.NestedProjectPath, changes); err != nil {
+		t.Fatalf("ApplySettings: %v", err)
+	}
+	rules := srv.Project(gitlabtest.NestedProjectPath).ApprovalRules
+	if len(rules) != 1 || rules[0].RuleType != "any_approver" || rules[0].ApprovalsRequired != 1 {
+		t.Errorf("approval rules = %+v", rules)
+	}
+}
+
+func TestDiffSettingsCommitTemplates(t *testing.T) {
+	tmpl, err := lib.LoadSettingsTemplate(writeRules(t, "merge_commit_template: |\n  Merge %{source_branch}\n\n  %{title}\nsquash_commit_template: \"\"\n"))
+	if err != nil {
+		t.Fatalf("LoadSettingsTemplate: %v", err)
+	}
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	// The squash commit template is already the default
+	changes, err := client.DiffSettings(gitlabtest.ProjectPath, tmpl)
+	if err != nil || len(changes) != 1 || changes[0].From != "(default)" || changes[0].To != `"Merge %{source_branch}\n\n%{title}\n"` {
+		t.Fatalf("DiffSettings = %+v, %v", changes, err)
+	}
+	if err := client.ApplySettings(gitlabtest.ProjectPath, changes); err != nil {
+		t.Fatalf("ApplySettings: %v", err)
+	}
+	if got := srv.Project(gitlabtest.ProjectPath).Settings.MergeCommitTemplate; got != "Merge %{source_branch}\n\n%{title}\n" {
+		t.Errorf("merge commit template = %q", got)
+	}
+}
+
+func TestUnknownTemplateVariables(t *testing.T) {
+	got := lib.UnknownTemplateVariables("%{title} (%{reference})\n\n%{titel}\n%{approved_by} %{titel} %{}")
+	if strings.Join(got, ",") != "titel," {
+		t.Errorf("UnknownTemplateVariables = %q", got)
+	}
+}
+
+func TestApplySettingsReprotectsBranches(t *testing.T) {
+	tmpl, err := lib.LoadSettingsTemplate(writeRules(t, "protected_branches:\n  - {name: main, push: none, merge: developer}\n"))
+	if err != nil {
+		t.Fatalf("LoadSettingsTemplate: %v", err)
+	}
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+	p := srv.Project(gitlabtest.ProjectPath)
+	p.ProtectedBranches[0].AllowForcePush = true
+
+	// GitLab refuses the new levels: the previous ones are restored
+	srv.Handle("POST /projects/:id/protected_branches", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var req struct {
+			Name           string `json:"name"`
+			Push           int    `json:"push_access_level"`
+			Merge          int    `json:"merge_access_level"`
+			AllowForcePush bool   `json:"allow_force_push"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Push == lib.AccessNoOne {
+			gitlabtest.WriteError(w, http.StatusUnprocessableEntity, "push_access_levels is invalid")
+			return
+		}
+		level := func(l int) []lib.BranchAccessLevel { return []lib.BranchAccessLevel{{AccessLevel: l}} }
+		p.ProtectedBranches = append(p.ProtectedBranches, lib.ProtectedBranch{Name: req.Name, PushAccessLevels: level(req.Push), MergeAccessLevels: level(req.Merge), AllowForcePush: req.AllowForcePush})
+		w.WriteHeader(http.StatusCreated)
+	})
+	changes, err := client.DiffSettings(gitlabtest.ProjectPath, tmpl)
+	if err != nil || len(changes) != 1 {
+		t.Fatalf("DiffSettings = %+v, %v", changes, err)
+	}
+	err = client.ApplySettings(gitlabtest.ProjectPath, changes)
+	if err == nil || !strings.Contains(err.Error(), "restored push maintainer, merge maintainer") {
+		t.Errorf("ApplySettings error = %v, want the restore reported", err)
+	}
+	branch := p.ProtectedBranches[len(p.ProtectedBranches)-1]
+	if push, merge, _ := branch.RoleLevels(); branch.Name != "main" || push != lib.AccessMaintainer || merge != lib.AccessMaintainer || !branch.AllowForcePush {
+		t.Errorf("main after the failed change = %+v, want it protected as before", branch)
+	}
+
+	// Per-user access cannot be expressed as one level: skipped, not dropped
+	user := 2
+	branch.PushAccessLevels = append(branch.PushAccessLevels, lib.BranchAccessLevel{AccessLevel: lib.AccessDeveloper, UserID: &user})
+	p.ProtectedBranches = []lib.ProtectedBranch{branch}
+	changes, err = client.DiffSettings(gitlabtest.ProjectPath, tmpl)
+	if err != nil || len(changes) != 1 || changes[0].Skipped == "" {
+		t.Fatalf("DiffSettings = %+v, %v; want main skipped", changes, err)
+	}
+	requests := len(srv.Requests())
+	if err := client.ApplySettings(gitlabtest.ProjectPath, changes); err != nil || len(srv.Requests()) != requests {
+		t.Errorf("ApplySettings of a skipped change = %v, %d request(s)", err, len(srv.Requests())-requests)
+	}
+}
@@ -0,0 +1,113 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// CommitSignature is the signature of a commit as GitLab verified it
+type CommitSignature struct {
+	SignatureType      string `json:"signature_type"` // PGP, X509 or SSH
+	VerificationStatus string `json:"verification_status"`
+	// GPG keys name their owner, X.509 certificates their subject, and SSH
+	// signatures the key they were made with
+	GPGKeyUserName       string           `json:"gpg_key_user_name"`
+	GPGKeyUserEmail      string           `json:"gpg_key_user_email"`
+	GPGKeyPrimaryKeyID   string           `json:"gpg_key_primary_keyid"`
+	X509Certificate      *X509Certificate `json:"x509_certificate"`
+	Key                  *SigningKey      `json:"key"`
+	KeyFingerprintSHA256 string           `json:"key_fingerprint_sha256"`
+}
+
+// X509Certificate is the certificate of an X.509 commit signature
+type X509Certificate struct {
+	Subject string `json:"subject"`
+	Email   string `json:"email"`
+}
+
+// SigningKey is the SSH key of an SSH commit signature
+type SigningKey struct {
+	Title string `json:"title"`
+}
+
+// Verified reports whether GitLab verified the signature against a key of
+// the committer, or signed the commit itself (web UI commits)
+func (s *CommitSignature) Verified() bool {
+	return s.VerificationStatus == "verified" || s.VerificationStatus == "verified_system"
+}
+
+// Signer describes who or what made the signature
+func (s *CommitSignature) Signer() string {
+	switch {
+	case s.GPGKeyUserName != "" || s.GPGKeyUserEmail != "":
+		return fmt.Sprintf("%s <%s>, key %s", s.GPGKeyUserName, s.GPGKeyUserEmail, s.GPGKeyPrimaryKeyID)
+	case s.X509Certificate != nil:
+		return s.X509Certificate.Subject
+	case s.Key != nil:
+		return fmt.Sprintf("key %q", s.Key.Title)
+	case s.KeyFingerprintSHA256 != "":
+		return "key SHA256:" + s.KeyFingerprintSHA256
+	}
+	return ""
+}
+
+// SignedCommit is a commit with its signature, nil when it is unsigned
+type SignedCommit struct {
+	Commit
+	Signature *CommitSignature
+}
+
+// Verified reports whether the commit carries a verified signature
+func (c *SignedCommit) Verified() bool {
+	return c.Signature != nil && c.Signature.Verified()
+}
+
+// Status is "unsigned" or the verification status of the signature
+func (c *SignedCommit) Status() string {
+	if c.Signature == nil {
+		return "unsigned"
+	}
+	return c.Signature.VerificationStatus
+}
+
+// GetCommitSignature returns the signature of a commit, nil when the
+// commit is not signed
+func (c *Client) GetCommitSignature(projectPath, sha string) (*CommitSignature, error) {
+	endpoint := c.apiURL("/projects/%s/repository/commits/%s/signature", url.PathEscape(projectPath), url.PathEscape(sha))
+
+	var sig CommitSignature
+	err := c.do("GET", endpoint, nil, &sig, http.StatusOK)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sig, nil
+}
+
+// ListMRSignatures lists the commits of an MR, newest first, with their
+// signatures
+func (c *Client) ListMRSignatures(projectPath string, mrIID int) ([]SignedCommit, error) {
+	commits, err := c.ListMRCommits(projectPath, mrIID)
+	if err != nil {
+		return nil, err
+	}
+	signed := make([]SignedCommit, len(commits))
+	errs := c.ForEach(len(commits), func(i int) error {
+		signed[i].Commit = commits[i]
+		sig, err := c.GetCommitSignature(projectPath, commits[i].ID)
+		if err != nil {
+			return fmt.Errorf("commit %s: %w", commits[i].ShortID, err)
+		}
+		signed[i].Signature = sig
+		return nil
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
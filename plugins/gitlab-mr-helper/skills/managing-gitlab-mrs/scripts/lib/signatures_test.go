@@ -0,0 +1,59 @@
+package lib_test
+
+import (
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestListMRSignatures(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	commits, err := srv.Client().ListMRSignatures(gitlabtest.ProjectPath, 1)
+	if err != nil {
+		t.Fatalf("ListMRSignatures: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("got %d commits, want 2", len(commits))
+	}
+	if c := commits[0]; c.ShortID != "abc777a" || !c.Verified() || c.Status() != "verified" || c.Signature.Signer() != `key "alice@laptop"` {
+		t.Errorf("signed commit = %+v, signature %+v", c.Commit, c.Signature)
+	}
+	if c := commits[1]; c.ShortID != "ccc333c" || c.Verified() || c.Status() != "unsigned" {
+		t.Errorf("unsigned commit = %+v, signature %+v", c.Commit, c.Signature)
+	}
+}
+
+func TestCommitSignature(t *testing.T) {
+	tests := []struct {
+		sig      lib.CommitSignature
+		verified bool
+		signer   string
+	}{
+		{
+			sig:      lib.CommitSignature{SignatureType: "PGP", VerificationStatus: "verified", GPGKeyUserName: "Alice", GPGKeyUserEmail: "alice@example.com", GPGKeyPrimaryKeyID: "8254AAB3FBD54AC9"},
+			verified: true,
+			signer:   "Alice <alice@example.com>, key 8254AAB3FBD54AC9",
+		},
+		{
+			sig:    lib.CommitSignature{SignatureType: "X509", VerificationStatus: "unverified", X509Certificate: &lib.X509Certificate{Subject: "CN=alice,O=Example"}},
+			signer: "CN=alice,O=Example",
+		},
+		{
+			sig:      lib.CommitSignature{SignatureType: "SSH", VerificationStatus: "verified_system"},
+			verified: true,
+		},
+		{
+			sig:    lib.CommitSignature{SignatureType: "SSH", VerificationStatus: "unknown_key", KeyFingerprintSHA256: "abc"},
+			signer: "key SHA256:abc",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.sig.Verified(); got != tt.verified {
+			t.Errorf("%s %s: Verified() = %v, want %v", tt.sig.SignatureType, tt.sig.VerificationStatus, got, tt.verified)
+		}
+		if got := tt.sig.Signer(); got != tt.signer {
+			t.Errorf("%s %s: Signer() = %q, want %q", tt.sig.SignatureType, tt.sig.VerificationStatus, got, tt.signer)
+		}
+	}
+}
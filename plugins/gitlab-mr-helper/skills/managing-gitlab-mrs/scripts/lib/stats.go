@@ -0,0 +1,162 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stats records the API calls of a command, for the summary --stats
+// prints when it exits
+type Stats struct {
+	start time.Time
+
+	mu    sync.Mutex
+	calls map[string]*CallStats
+}
+
+// CallStats counts the calls to one endpoint
+type CallStats struct {
+	Method   string
+	Endpoint string // path with IDs replaced, e.g. /projects/:id/merge_requests/:id
+	Calls    int
+	Failed   int           // network errors and error statuses
+	Time     time.Duration // until the response headers arrived
+}
+
+// NewStats starts recording
+func NewStats() *Stats {
+	return &Stats{start: time.Now(), calls: map[string]*CallStats{}}
+}
+
+// commandStats records the calls of this command when --stats or
+// GITLAB_STATS is set; see ReportStats
+var commandStats *Stats
+
+func (s *Stats) record(method, endpoint string, elapsed time.Duration, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := method + " " + endpoint
+	cs := s.calls[key]
+	if cs == nil {
+		cs = &CallStats{Method: method, Endpoint: endpoint}
+		s.calls[key] = cs
+	}
+	cs.Calls++
+	cs.Time += elapsed
+	if failed {
+		cs.Failed++
+	}
+}
+
+// Calls returns the recorded calls by endpoint, the most called first
+func (s *Stats) Calls() []CallStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var calls []CallStats
+	for _, cs := range s.calls {
+		calls = append(calls, *cs)
+	}
+	sort.Slice(calls, func(i, j int) bool {
+		if calls[i].Calls != calls[j].Calls {
+			return calls[i].Calls > calls[j].Calls
+		}
+		if calls[i].Time != calls[j].Time {
+			return calls[i].Time > calls[j].Time
+		}
+		return calls[i].Method+calls[i].Endpoint < calls[j].Method+calls[j].Endpoint
+	})
+	return calls
+}
+
+// Write prints the summary: the totals, then the calls by endpoint
+func (s *Stats) Write(w io.Writer) {
+	calls := s.Calls()
+	total, failed := 0, 0
+	var api time.Duration
+	for _, cs := range calls {
+		total += cs.Calls
+		failed += cs.Failed
+		api += cs.Time
+	}
+	line := fmt.Sprintf("Stats: %d API call(s) in %s, %s waiting on the API", total, roundDuration(time.Since(s.start)), roundDuration(api))
+	if failed > 0 {
+		line += fmt.Sprintf(", %d failed", failed)
+	}
+	fmt.Fprintln(w, line)
+	for _, cs := range calls {
+		line := fmt.Sprintf("  %4d "+Times+" %-6s %s (%s)", cs.Calls, cs.Method, cs.Endpoint, roundDuration(cs.Time))
+		if cs.Failed > 0 {
+			line += fmt.Sprintf(", %d failed", cs.Failed)
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// ReportStats prints the summary of this command to stderr when --stats or
+// GITLAB_STATS is set. Scripts defer it in main; ExitWith calls it for the
+// runs that end with os.Exit.
+func ReportStats() {
+	if commandStats == nil {
+		return
+	}
+	commandStats.Write(os.Stderr)
+	commandStats = nil
+}
+
+// statsTransport records every request it sends
+type statsTransport struct {
+	next   http.RoundTripper
+	stats  *Stats
+	prefix string // path of the API base, left out of endpoints
+}
+
+func (t *statsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	t.stats.record(req.Method, statsEndpoint(strings.TrimPrefix(req.URL.EscapedPath(), t.prefix)), time.Since(start), err != nil || resp.StatusCode >= 400)
+	return resp, err
+}
+
+// idSegments are the path segments always followed by an ID or a name
+var idSegments = map[string]bool{
+	"projects":     true,
+	"groups":       true,
+	"users":        true,
+	"namespaces":   true,
+	"branches":     true,
+	"tags":         true,
+	"files":        true,
+	"variables":    true,
+	"environments": true,
+}
+
+// statsEndpoint groups request paths by endpoint, replacing IDs, escaped
+// paths and names with :id
+func statsEndpoint(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if seg == "" {
+			continue
+		}
+		numeric := strings.Trim(seg, "0123456789") == ""
+		if numeric || strings.Contains(seg, "%") || (i > 0 && idSegments[segments[i-1]]) {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// roundDuration rounds to what a summary needs: milliseconds below a
+// second, tenths of a second above
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(100 * time.Millisecond)
+}
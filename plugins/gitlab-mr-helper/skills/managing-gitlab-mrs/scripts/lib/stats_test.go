@@ -0,0 +1,45 @@
+package lib_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestStats(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	stats := lib.NewStats()
+	client := lib.NewClient(&lib.Config{URL: srv.URL, Token: srv.CurrentToken(), Stats: stats})
+
+	for _, iid := range []int{1, 2} {
+		if _, err := client.GetMR(gitlabtest.ProjectPath, iid); err != nil {
+			t.Fatalf("GetMR !%d: %v", iid, err)
+		}
+	}
+	if _, err := client.GetMR(gitlabtest.ProjectPath, 999); err == nil {
+		t.Fatal("GetMR !999 succeeded")
+	}
+	if _, err := client.GetCurrentUser(); err != nil {
+		t.Fatalf("GetCurrentUser: %v", err)
+	}
+
+	calls := stats.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("Calls = %+v, want 2 endpoints", calls)
+	}
+	if c := calls[0]; c.Method != "GET" || c.Endpoint != "/projects/:id/merge_requests/:id" || c.Calls != 3 || c.Failed != 1 {
+		t.Errorf("Calls[0] = %+v", c)
+	}
+	if c := calls[1]; c.Endpoint != "/user" || c.Calls != 1 || c.Failed != 0 {
+		t.Errorf("Calls[1] = %+v", c)
+	}
+
+	var out bytes.Buffer
+	stats.Write(&out)
+	if !strings.HasPrefix(out.String(), "Stats: 4 API call(s) in ") || !strings.Contains(out.String(), ", 1 failed\n") {
+		t.Errorf("Write = %q", out.String())
+	}
+}
@@ -0,0 +1,193 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// StatusRow is one line of an MR status report
+type StatusRow struct {
+	Name    string
+	Status  string // success, failed, running, ... or empty
+	Details string // markdown
+}
+
+// StatusReport is the table kept in a single bot comment on an MR
+type StatusReport struct {
+	ID        string // distinguishes several reports on one MR
+	Title     string
+	Rows      []StatusRow
+	UpdatedAt time.Time
+}
+
+// statusIcons decorate well-known statuses
+var statusIcons = map[string]string{
+	"success": "✅", "passed": "✅", "ok": "✅",
+	"failed": "❌", "error": "❌",
+	"warning": "⚠️", "manual": "⚠️",
+	"running": "⏳", "pending": "⏳", "created": "⏳",
+	"canceled": "⛔", "skipped": "⏭️",
+}
+
+// StatusMarker returns the hidden HTML comment identifying a report
+func StatusMarker(id string) string {
+	if id == "" {
+		return "<!-- gitlab-helper:status -->"
+	}
+	return fmt.Sprintf("<!-- gitlab-helper:status:%s -->", id)
+}
+
+// Set adds or replaces the row with the given name
+func (r *StatusReport) Set(row StatusRow) {
+	for i := range r.Rows {
+		if strings.EqualFold(r.Rows[i].Name, row.Name) {
+			r.Rows[i] = row
+			return
+		}
+	}
+	r.Rows = append(r.Rows, row)
+}
+
+// Render formats the report as a markdown comment starting with its marker
+func (r *StatusReport) Render() string {
+	var sb strings.Builder
+	sb.WriteString(StatusMarker(r.ID) + "\n")
+	fmt.Fprintf(&sb, "### %s\n\n", r.Title)
+	sb.WriteString("| Check | Status | Details |\n|-------|--------|---------|\n")
+	for _, row := range r.Rows {
+		status := row.Status
+		if icon := statusIcons[strings.ToLower(status)]; icon != "" {
+			status = icon + " " + status
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %s |\n", escapeCell(row.Name), escapeCell(status), escapeCell(row.Details))
+	}
+	fmt.Fprintf(&sb, "\n_Updated %s_\n", r.UpdatedAt.UTC().Format("2006-01-02 15:04 UTC"))
+	return sb.String()
+}
+
+// ParseStatusReport reads the rows back from a rendered report. It returns
+// nil when body does not carry the marker of the given report ID.
+func ParseStatusReport(body, id string) *StatusReport {
+	if !strings.HasPrefix(strings.TrimSpace(body), StatusMarker(id)) {
+		return nil
+	}
+	r := &StatusReport{ID: id}
+	for _, line := range strings.Split(body, "\n") {
+		if title, ok := strings.CutPrefix(line, "### "); ok && r.Title == "" {
+			r.Title = title
+			continue
+		}
+		if !strings.HasPrefix(line, "| ") || strings.HasPrefix(line, "| Check |") {
+			continue
+		}
+		cells := splitCells(line)
+		if len(cells) != 3 {
+			continue
+		}
+		status := cells[1]
+		if icon, rest, ok := strings.Cut(status, " "); ok && isStatusIcon(icon) {
+			status = rest
+		}
+		r.Rows = append(r.Rows, StatusRow{Name: cells[0], Status: status, Details: cells[2]})
+	}
+	return r
+}
+
+// FindStatusReport searches the notes of an MR by the token user for the
+// status report with the given ID. It returns the note and its parsed
+// report, or nils when there is none yet.
+func (c *Client) FindStatusReport(projectPath string, mrIID int, id string) (*Note, *StatusReport, error) {
+	note, err := c.findMarkedNote(projectPath, mrIID, StatusMarker(id))
+	if err != nil || note == nil {
+		return nil, nil, err
+	}
+	return note, ParseStatusReport(note.Body, id), nil
+}
+
+// UpsertStatusReport writes report to the MR's note holding the report with
+// its ID, or posts a new note. The note is found by its marker alone, so
+// nothing needs to be remembered between runs. Reports that only matter when something is
+// wrong pass onlyIfExisting when all is well: an earlier report is then
+// updated, but no note is posted, and the returned note is nil. created
+// reports whether the note is new.
+func (c *Client) UpsertStatusReport(projectPath string, mrIID int, report *StatusReport, onlyIfExisting bool) (note *Note, created bool, err error) {
+	return c.UpsertMarkedNote(projectPath, mrIID, StatusMarker(report.ID), report.Render(), onlyIfExisting)
+}
+
+// UpsertMarkedNote is UpsertStatusReport for notes of any format: body
+// replaces the token user's note starting with marker, which body must
+// start with too.
+func (c *Client) UpsertMarkedNote(projectPath string, mrIID int, marker, body string, onlyIfExisting bool) (note *Note, created bool, err error) {
+	existing, err := c.findMarkedNote(projectPath, mrIID, marker)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		note, err := c.UpdateMRNote(projectPath, mrIID, existing.ID, body)
+		return note, false, err
+	}
+	if onlyIfExisting {
+		return nil, false, nil
+	}
+	note, err = c.CreateMRNote(projectPath, mrIID, body)
+	return note, err == nil, err
+}
+
+// findMarkedNote returns the token user's first note on an MR that starts
+// with marker, or nil
+func (c *Client) findMarkedNote(projectPath string, mrIID int, marker string) (*Note, error) {
+	me, err := c.GetCurrentUser()
+	if err != nil {
+		return nil, err
+	}
+	notes, err := c.ListMRNotes(projectPath, mrIID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range notes {
+		if notes[i].Author.ID == me.ID && strings.HasPrefix(strings.TrimSpace(notes[i].Body), marker) {
+			return &notes[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func isStatusIcon(s string) bool {
+	for _, icon := range statusIcons {
+		if s == icon {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeCell keeps a value on one table row
+func escapeCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// splitCells splits a markdown table row on unescaped pipes
+func splitCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+
+	var cells []string
+	var cur strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) && line[i+1] == '|' {
+			cur.WriteByte('|')
+			i++
+			continue
+		}
+		if line[i] == '|' {
+			cells = append(cells, strings.TrimSpace(cur.String()))
+			cur.Reset()
+			continue
+		}
+		cur.WriteByte(line[i])
+	}
+	return append(cells, strings.TrimSpace(cur.String()))
+}
@@ -0,0 +1,104 @@
+package lib_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestStatusReportRoundTrip(t *testing.T) {
+	r := &lib.StatusReport{Title: "Automation status", UpdatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	r.Set(lib.StatusRow{Name: "Pipeline", Status: "running", Details: "[#902](https://example.com/902)"})
+	r.Set(lib.StatusRow{Name: "Coverage", Details: "+1.2% | 84.0%"})
+	r.Set(lib.StatusRow{Name: "pipeline", Status: "success", Details: "[#902](https://example.com/902)"})
+
+	body := r.Render()
+	if !strings.HasPrefix(body, "<!-- gitlab-helper:status -->\n### Automation status") || !strings.Contains(body, "| pipeline | ✅ success |") {
+		t.Fatalf("unexpected render:\n%s", body)
+	}
+
+	parsed := lib.ParseStatusReport(body, "")
+	if parsed == nil {
+		t.Fatal("marker not recognized")
+	}
+	if parsed.Title != "Automation status" || len(parsed.Rows) != 2 {
+		t.Fatalf("parsed = %+v", parsed)
+	}
+	if got := parsed.Rows[0]; got.Name != "pipeline" || got.Status != "success" {
+		t.Errorf("row 0 = %+v", got)
+	}
+	if got := parsed.Rows[1]; got.Status != "" || got.Details != "+1.2% | 84.0%" {
+		t.Errorf("row 1 = %+v", got)
+	}
+
+	if lib.ParseStatusReport(body, "security") != nil {
+		t.Error("report matched a different ID")
+	}
+	if lib.ParseStatusReport("Looks good", "") != nil {
+		t.Error("plain comment matched")
+	}
+}
+
+func TestFindStatusReport(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+	note, _, err := client.FindStatusReport(gitlabtest.ProjectPath, 1, "mr-lint")
+	if err != nil || note != nil {
+		t.Fatalf("before posting: got %v, %v; want none", note, err)
+	}
+
+	report := &lib.StatusReport{ID: "mr-lint", Title: "MR checks", Rows: []lib.StatusRow{{Name: "Labels", Status: "failed"}}}
+	posted, err := client.CreateMRNote(gitlabtest.ProjectPath, 1, report.Render())
+	if err != nil {
+		t.Fatalf("CreateMRNote: %v", err)
+	}
+	client.CreateMRNote(gitlabtest.ProjectPath, 1, (&lib.StatusReport{Title: "Other"}).Render())
+
+	note, parsed, err := client.FindStatusReport(gitlabtest.ProjectPath, 1, "mr-lint")
+	if err != nil || note == nil || note.ID != posted.ID {
+		t.Fatalf("got %v, %v; want note %d", note, err, posted.ID)
+	}
+	if len(parsed.Rows) != 1 || parsed.Rows[0].Status != "failed" {
+		t.Errorf("parsed rows %+v, want the Labels row", parsed.Rows)
+	}
+}
+
+func TestUpsertStatusReport(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+	report := &lib.StatusReport{ID: "mr-lint", Title: "MR checks", Rows: []lib.StatusRow{{Name: "Labels", Status: "passed"}}}
+
+	// Nothing to update yet, so a passing report posts nothing
+	note, created, err := client.UpsertStatusReport(gitlabtest.ProjectPath, 1, report, true)
+	if err != nil || note != nil || created {
+		t.Fatalf("onlyIfExisting without a report = %v, %v, %v; want nothing", note, created, err)
+	}
+
+	report.Rows[0].Status = "failed"
+	posted, created, err := client.UpsertStatusReport(gitlabtest.ProjectPath, 1, report, false)
+	if err != nil || posted == nil || !created {
+		t.Fatalf("first report = %v, %v, %v; want a new note", posted, created, err)
+	}
+
+	report.Rows[0].Status = "passed"
+	note, created, err = client.UpsertStatusReport(gitlabtest.ProjectPath, 1, report, true)
+	if err != nil || note == nil || created || note.ID != posted.ID {
+		t.Fatalf("second report = %v, %v, %v; want note %d updated", note, created, err, posted.ID)
+	}
+	if _, parsed, _ := client.FindStatusReport(gitlabtest.ProjectPath, 1, "mr-lint"); parsed == nil || parsed.Rows[0].Status != "passed" {
+		t.Errorf("report after update = %+v", parsed)
+	}
+	notes, _ := client.ListMRNotes(gitlabtest.ProjectPath, 1)
+	count := 0
+	for _, n := range notes {
+		if strings.HasPrefix(n.Body, lib.StatusMarker("mr-lint")) {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("%d report notes, want 1", count)
+	}
+}
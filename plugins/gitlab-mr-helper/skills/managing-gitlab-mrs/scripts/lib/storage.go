@@ -0,0 +1,143 @@
+package lib
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ProjectStatistics is the storage used by a project, in bytes
+type ProjectStatistics struct {
+	StorageSize           int64 `json:"storage_size"` // everything below, counted against the quota
+	RepositorySize        int64 `json:"repository_size"`
+	WikiSize              int64 `json:"wiki_size"`
+	LFSObjectsSize        int64 `json:"lfs_objects_size"`
+	JobArtifactsSize      int64 `json:"job_artifacts_size"`
+	PipelineArtifactsSize int64 `json:"pipeline_artifacts_size"`
+	PackagesSize          int64 `json:"packages_size"`
+	SnippetsSize          int64 `json:"snippets_size"`
+	UploadsSize           int64 `json:"uploads_size"`
+	ContainerRegistrySize int64 `json:"container_registry_size"`
+}
+
+// Add adds the sizes of other to s
+func (s *ProjectStatistics) Add(other ProjectStatistics) {
+	s.StorageSize += other.StorageSize
+	s.RepositorySize += other.RepositorySize
+	s.WikiSize += other.WikiSize
+	s.LFSObjectsSize += other.LFSObjectsSize
+	s.JobArtifactsSize += other.JobArtifactsSize
+	s.PipelineArtifactsSize += other.PipelineArtifactsSize
+	s.PackagesSize += other.PackagesSize
+	s.SnippetsSize += other.SnippetsSize
+	s.UploadsSize += other.UploadsSize
+	s.ContainerRegistrySize += other.ContainerRegistrySize
+}
+
+// StorageColumns are the storage kinds reported per project, in table
+// order; "artifacts" includes pipeline artifacts
+var StorageColumns = []string{"storage", "repository", "artifacts", "lfs", "registry", "packages", "wiki", "other"}
+
+// Column returns the size of one of StorageColumns
+func (s *ProjectStatistics) Column(name string) int64 {
+	switch name {
+	case "storage":
+		return s.StorageSize
+	case "repository":
+		return s.RepositorySize
+	case "artifacts":
+		return s.JobArtifactsSize + s.PipelineArtifactsSize
+	case "lfs":
+		return s.LFSObjectsSize
+	case "registry":
+		return s.ContainerRegistrySize
+	case "packages":
+		return s.PackagesSize
+	case "wiki":
+		return s.WikiSize
+	case "other":
+		return s.SnippetsSize + s.UploadsSize
+	}
+	return 0
+}
+
+// SortProjectsByStorage sorts projects by one of StorageColumns, largest
+// first
+func SortProjectsByStorage(projects []Project, column string) error {
+	if !ContainsString(StorageColumns, column) {
+		return UsageErrorf("invalid sort column %q (expected %s)", column, strings.Join(StorageColumns, ", "))
+	}
+	size := func(p Project) int64 {
+		if p.Statistics == nil {
+			return 0
+		}
+		return p.Statistics.Column(column)
+	}
+	sort.SliceStable(projects, func(i, j int) bool { return size(projects[i]) > size(projects[j]) })
+	return nil
+}
+
+// ListGroupProjectStatistics lists the projects of a group and its
+// subgroups with their storage statistics, archived ones included since
+// they still use storage. It needs the Reporter role on each project.
+func (c *Client) ListGroupProjectStatistics(group string) ([]Project, error) {
+	endpoint := c.apiURL("/groups/%s/projects", url.PathEscape(group))
+	query := url.Values{}
+	query.Set("include_subgroups", "true")
+	query.Set("statistics", "true")
+	return getAll[Project](c, endpoint, query, 0)
+}
+
+// NamespaceStorage is the storage quota of a top-level namespace
+type NamespaceStorage struct {
+	FullPath string
+	// StorageSize is what the whole namespace uses, and Limit the quota
+	// including purchased storage; 0 means no limit is enforced
+	StorageSize int64
+	Limit       int64
+}
+
+const namespaceStorageQuery = `query NamespaceStorage($fullPath: ID!) {
+  namespace(fullPath: $fullPath) {
+    fullPath
+    storageSizeLimit
+    additionalPurchasedStorageSize
+    rootStorageStatistics { storageSize }
+  }
+}`
+
+// GetNamespaceStorage returns the storage used and allowed by the top-level
+// namespace of path. Quotas are only reported over GraphQL, and only
+// enforced on GitLab.com and instances with namespace storage limits.
+func (c *Client) GetNamespaceStorage(path string) (*NamespaceStorage, error) {
+	root, _, _ := strings.Cut(path, "/")
+	var data struct {
+		Namespace *struct {
+			FullPath                       string   `json:"fullPath"`
+			StorageSizeLimit               *float64 `json:"storageSizeLimit"`
+			AdditionalPurchasedStorageSize *float64 `json:"additionalPurchasedStorageSize"`
+			RootStorageStatistics          *struct {
+				StorageSize float64 `json:"storageSize"`
+			} `json:"rootStorageStatistics"`
+		} `json:"namespace"`
+	}
+	if err := c.GraphQL("NamespaceStorage", namespaceStorageQuery, map[string]interface{}{"fullPath": root}, &data); err != nil {
+		return nil, err
+	}
+	ns := data.Namespace
+	if ns == nil {
+		return nil, fmt.Errorf("%w: namespace %s", ErrNotFound, root)
+	}
+	out := &NamespaceStorage{FullPath: ns.FullPath}
+	if ns.RootStorageStatistics != nil {
+		out.StorageSize = int64(ns.RootStorageStatistics.StorageSize)
+	}
+	if ns.StorageSizeLimit != nil && *ns.StorageSizeLimit > 0 {
+		out.Limit = int64(*ns.StorageSizeLimit)
+		if ns.AdditionalPurchasedStorageSize != nil {
+			out.Limit += int64(*ns.AdditionalPurchasedStorageSize)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,91 @@
+package lib_test
+
+import (
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestListGroupProjectStatistics(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	projects, err := client.ListGroupProjectStatistics("group")
+	if err != nil {
+		t.Fatalf("ListGroupProjectStatistics: %v", err)
+	}
+	if len(projects) != 2 {
+		t.Fatalf("projects = %+v", projects)
+	}
+	for _, p := range projects {
+		if p.Statistics == nil {
+			t.Fatalf("%s has no statistics", p.PathWithNamespace)
+		}
+	}
+
+	if err := lib.SortProjectsByStorage(projects, "wiki"); err != nil {
+		t.Fatalf("SortProjectsByStorage: %v", err)
+	}
+	if projects[0].PathWithNamespace != gitlabtest.NestedProjectPath {
+		t.Errorf("largest wiki = %s, want %s", projects[0].PathWithNamespace, gitlabtest.NestedProjectPath)
+	}
+	if err := lib.SortProjectsByStorage(projects, "artifacts"); err != nil {
+		t.Fatalf("SortProjectsByStorage: %v", err)
+	}
+	if projects[0].PathWithNamespace != gitlabtest.ProjectPath || projects[0].Statistics.Column("artifacts") != 1824<<20 {
+		t.Errorf("largest artifacts = %s (%d)", projects[0].PathWithNamespace, projects[0].Statistics.Column("artifacts"))
+	}
+	wantExit(t, lib.SortProjectsByStorage(projects, "size"), lib.ExitUsage)
+
+	var total lib.ProjectStatistics
+	for _, p := range projects {
+		total.Add(*p.Statistics)
+	}
+	if total.StorageSize != 3<<30+120<<20 || total.Column("other") != 48<<20 {
+		t.Errorf("total = %+v", total)
+	}
+
+	_, err = client.ListGroupProjectStatistics("missing")
+	wantExit(t, err, lib.ExitNotFound)
+}
+
+func TestGetNamespaceStorage(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	// A subgroup reports the quota of its top-level group
+	ns, err := client.GetNamespaceStorage("group/sub")
+	if err != nil {
+		t.Fatalf("GetNamespaceStorage: %v", err)
+	}
+	if ns.FullPath != "group" || ns.StorageSize != 3<<30+120<<20 || ns.Limit != 5<<30 {
+		t.Errorf("namespace storage = %+v", ns)
+	}
+
+	_, err = client.GetNamespaceStorage("missing")
+	wantExit(t, err, lib.ExitNotFound)
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"512", 512},
+		{"100KB", 100 << 10},
+		{"1.5 GB", 3 << 29},
+		{"2GiB", 2 << 30},
+		{"10g", 10 << 30},
+		{" 3 TB ", 3 << 40},
+	}
+	for _, tt := range tests {
+		if got, err := lib.ParseSize(tt.in); err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "GB", "ten", "5 XB", "-1GB"} {
+		_, err := lib.ParseSize(in)
+		wantExit(t, err, lib.ExitUsage)
+	}
+}
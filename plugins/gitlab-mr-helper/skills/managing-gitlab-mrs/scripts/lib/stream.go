@@ -0,0 +1,227 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// MaxJSONBytes caps a single JSON response; larger ones fail with
+	// ErrResponseTooLarge instead of exhausting memory
+	MaxJSONBytes = 64 << 20
+
+	// MaxDiffBytes caps the patch kept for one file of a diff listing
+	MaxDiffBytes = 512 << 10
+
+	// maxErrorBytes caps the body kept in an APIError
+	maxErrorBytes = 64 << 10
+)
+
+// ErrResponseTooLarge is returned when a response exceeds MaxJSONBytes
+var ErrResponseTooLarge = errors.New("response too large")
+
+// cappedReader fails once more than max bytes have been read
+type cappedReader struct {
+	r    io.Reader
+	left int64
+	max  int64
+}
+
+func capReader(r io.Reader, max int64) io.Reader {
+	return &cappedReader{r: r, left: max, max: max}
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.left <= 0 {
+		// Only an exhausted source may end exactly at the cap
+		var probe [1]byte
+		if n, err := c.r.Read(probe[:]); n == 0 && err != nil {
+			return 0, err
+		}
+		return 0, fmt.Errorf("%w (over %s)", ErrResponseTooLarge, FormatSize(c.max))
+	}
+	if int64(len(p)) > c.left {
+		p = p[:c.left]
+	}
+	n, err := c.r.Read(p)
+	c.left -= int64(n)
+	return n, err
+}
+
+// decodePage decodes a JSON array response one element at a time, calling
+// fn on each, and returns the number of elements and the response headers
+func decodePage[T any](c *Client, endpoint string, fn func(*T)) (int, http.Header, error) {
+	resp, err := c.send("GET", endpoint, nil, http.StatusOK)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(capReader(resp.Body, MaxJSONBytes))
+	if tok, err := dec.Token(); err != nil {
+		return 0, nil, fmt.Errorf("failed to decode response: %w", err)
+	} else if tok != json.Delim('[') {
+		return 0, nil, fmt.Errorf("failed to decode response: expected an array, got %v", tok)
+	}
+	n := 0
+	for dec.More() {
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			return 0, nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		fn(&item)
+		n++
+	}
+	if _, err := dec.Token(); err != nil {
+		return 0, nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return n, resp.Header, nil
+}
+
+// truncationMarker is the line that replaces dropped output
+func truncationMarker(dropped int64) string {
+	return fmt.Sprintf("[... truncated: %s not shown ...]", FormatSize(dropped))
+}
+
+// truncateDiff cuts a patch longer than max at a line boundary and marks
+// it as truncated
+func truncateDiff(d *Diff, max int) {
+	if len(d.Diff) <= max {
+		return
+	}
+	cut := strings.LastIndexByte(d.Diff[:max], '\n') + 1
+	dropped := int64(len(d.Diff) - cut)
+	d.Diff = d.Diff[:cut] + truncationMarker(dropped) + "\n"
+	d.Truncated = true
+}
+
+// headReader yields the first max bytes of r, then a truncation marker
+type headReader struct {
+	r      io.Reader
+	left   int64
+	last   byte // last byte passed through
+	marker io.Reader
+}
+
+// HeadReader returns a reader yielding the first max bytes of r followed,
+// if r is longer, by a line saying how much was dropped. The rest of r is
+// drained without being kept in memory.
+func HeadReader(r io.Reader, max int64) io.Reader {
+	return &headReader{r: r, left: max}
+}
+
+func (h *headReader) Read(p []byte) (int, error) {
+	if h.left > 0 {
+		if int64(len(p)) > h.left {
+			p = p[:h.left]
+		}
+		n, err := h.r.Read(p)
+		h.left -= int64(n)
+		if n > 0 {
+			h.last = p[n-1]
+		}
+		return n, err
+	}
+	if h.marker == nil {
+		dropped, err := io.Copy(io.Discard, h.r)
+		if err != nil {
+			return 0, err
+		}
+		h.marker = strings.NewReader("")
+		if dropped > 0 {
+			marker := truncationMarker(dropped) + "\n"
+			if h.last != '\n' {
+				marker = "\n" + marker
+			}
+			h.marker = strings.NewReader(marker)
+		}
+	}
+	return h.marker.Read(p)
+}
+
+// WriteTail copies the last max bytes of r to w, preceded by a line saying
+// how much was dropped, keeping at most max bytes in memory. Use it for
+// logs, whose end matters most.
+func WriteTail(w io.Writer, r io.Reader, max int64) error {
+	ring := make([]byte, max)
+	var total int64
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := r.Read(buf)
+		for chunk := buf[:n]; len(chunk) > 0; {
+			k := copy(ring[total%max:], chunk)
+			chunk = chunk[k:]
+			total += int64(k)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if total <= max {
+		_, err := w.Write(ring[:total])
+		return err
+	}
+	start := total % max
+	tail := append(ring[start:len(ring):len(ring)], ring[:start]...)
+	// Start after the first newline so the output begins with a whole line
+	if i := bytes.IndexByte(tail, '\n'); i >= 0 && i < len(tail)-1 {
+		tail = tail[i+1:]
+	}
+	if _, err := fmt.Fprintln(w, truncationMarker(total-int64(len(tail)))); err != nil {
+		return err
+	}
+	_, err := w.Write(tail)
+	return err
+}
+
+// GetJobTrace streams the log of a CI job. The caller must close it. Like
+// downloads, streams are not cut off by the request timeout.
+func (c *Client) GetJobTrace(projectPath string, jobID int) (io.ReadCloser, error) {
+	endpoint := c.apiURL("/projects/%s/jobs/%d/trace", url.PathEscape(projectPath), jobID)
+	resp, err := c.transferClient().send("GET", endpoint, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// GetJobArtifact streams one file from the artifacts archive of a CI job.
+// The caller must close it.
+func (c *Client) GetJobArtifact(projectPath string, jobID int, path string) (io.ReadCloser, error) {
+	resp, err := c.transferClient().send("GET", c.jobArtifactsEndpoint(projectPath, jobID, path), nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// SaveJobArtifacts downloads the artifacts archive (.zip) of a CI job to
+// path, or one file of it when artifactPath is set, resuming interrupted
+// transfers
+func (c *Client) SaveJobArtifacts(projectPath string, jobID int, artifactPath, path string, opts *DownloadOptions) (*Download, error) {
+	return c.downloadFile(c.jobArtifactsEndpoint(projectPath, jobID, artifactPath), path, opts)
+}
+
+// jobArtifactsEndpoint returns the URL of a job's artifacts archive, or of
+// the file at path inside it
+func (c *Client) jobArtifactsEndpoint(projectPath string, jobID int, path string) string {
+	endpoint := c.apiURL("/projects/%s/jobs/%d/artifacts", url.PathEscape(projectPath), jobID)
+	if path == "" {
+		return endpoint
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	return endpoint + "/" + strings.Join(segments, "/")
+}
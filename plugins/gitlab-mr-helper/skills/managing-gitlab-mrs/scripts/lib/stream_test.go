@@ -0,0 +1,145 @@
+package lib_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestHeadReader(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		max  int64
+		want string
+	}{
+		{name: "short", in: "abc", max: 10, want: "abc"},
+		{name: "exact", in: "abcdef", max: 6, want: "abcdef"},
+		{name: "cut at newline", in: "ab\ncd\n", max: 3, want: "ab\n[... truncated: 3 B not shown ...]\n"},
+		{name: "long", in: strings.Repeat("x", 2100), max: 100, want: strings.Repeat("x", 100) + "\n[... truncated: 2.0 KB not shown ...]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := io.ReadAll(lib.HeadReader(strings.NewReader(tt.in), tt.max))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteTail(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		max  int64
+		want string
+	}{
+		{name: "short", in: "a\nb\n", max: 10, want: "a\nb\n"},
+		{name: "whole lines", in: "line1\nline2\nline3\n", max: 9, want: "[... truncated: 12 B not shown ...]\nline3\n"},
+		{name: "ring wraps", in: "0123456789\nabc\n", max: 6, want: "[... truncated: 11 B not shown ...]\nabc\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := lib.WriteTail(&out, strings.NewReader(tt.in), tt.max); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestGetJobTrace(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	trace, err := client.GetJobTrace(gitlabtest.ProjectPath, 3001)
+	if err != nil {
+		t.Fatalf("GetJobTrace: %v", err)
+	}
+	defer trace.Close()
+	var out bytes.Buffer
+	if err := lib.WriteTail(&out, trace, 40); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out.String(), "ERROR: Job failed: exit code 1\n") || !strings.HasPrefix(out.String(), "[... truncated") {
+		t.Errorf("tail = %q", out.String())
+	}
+
+	_, err = client.GetJobTrace(gitlabtest.ProjectPath, 1)
+	wantExit(t, err, lib.ExitNotFound)
+}
+
+func TestGetJobArtifact(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	job, err := client.FindPipelineJob(gitlabtest.ProjectPath, 901, "size")
+	if err != nil || job.ID != 3002 {
+		t.Fatalf("FindPipelineJob = %+v, %v", job, err)
+	}
+	body, err := client.GetJobArtifact(gitlabtest.ProjectPath, job.ID, "reports/size.json")
+	if err != nil {
+		t.Fatalf("GetJobArtifact: %v", err)
+	}
+	defer body.Close()
+	data, _ := io.ReadAll(body)
+	if !strings.Contains(string(data), `"total": 6260`) {
+		t.Errorf("artifact = %s", data)
+	}
+
+	_, err = client.GetJobArtifact(gitlabtest.ProjectPath, job.ID, "reports/missing.json")
+	wantExit(t, err, lib.ExitNotFound)
+	_, err = client.FindPipelineJob(gitlabtest.ProjectPath, 901, "deploy")
+	wantExit(t, err, lib.ExitNotFound)
+}
+
+func TestLargeDiffsTruncated(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	huge := "@@ -0,0 +1 @@\n" + strings.Repeat("+generated line\n", lib.MaxDiffBytes/8)
+	srv.Project(gitlabtest.ProjectPath).Diffs[1] = []lib.Diff{{OldPath: "gen.txt", NewPath: "gen.txt", Diff: huge}}
+
+	diffs, err := srv.Client().GetMRDiffs(gitlabtest.ProjectPath, 1)
+	if err != nil {
+		t.Fatalf("GetMRDiffs: %v", err)
+	}
+	d := diffs[0]
+	if !d.Truncated || len(d.Diff) > lib.MaxDiffBytes+100 || !strings.HasSuffix(d.Diff, "not shown ...]\n") {
+		t.Errorf("diff not truncated: %d bytes, truncated=%v, ends %q", len(d.Diff), d.Truncated, d.Diff[len(d.Diff)-40:])
+	}
+	lines := strings.Split(strings.TrimSuffix(d.Diff, "\n"), "\n")
+	if lines[len(lines)-2] != "+generated line" {
+		t.Errorf("diff not cut at a line boundary: %q", lines[len(lines)-2])
+	}
+}
+
+func TestResponseTooLarge(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	srv.Handle("GET /user", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		w.Write([]byte(`{"username":"`))
+		chunk := bytes.Repeat([]byte("a"), 1<<20)
+		for i := 0; i <= lib.MaxJSONBytes>>20; i++ {
+			w.Write(chunk)
+		}
+		w.Write([]byte(`"}`))
+	})
+
+	_, err := srv.Client().GetCurrentUser()
+	if !errors.Is(err, lib.ErrResponseTooLarge) {
+		t.Errorf("err = %v, want ErrResponseTooLarge", err)
+	}
+}
@@ -0,0 +1,91 @@
+package lib
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Table output formats accepted by --output
+const (
+	OutputText = "text"
+	OutputTSV  = "tsv"
+	OutputCSV  = "csv"
+)
+
+// ValidateOutputFormat checks an --output value
+func ValidateOutputFormat(format string) error {
+	switch format {
+	case OutputText, OutputTSV, OutputCSV:
+		return nil
+	default:
+		return UsageErrorf("invalid --output %q (expected text, tsv or csv)", format)
+	}
+}
+
+// tsvEscaper keeps every TSV record on one line with a fixed number of fields
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// WriteTable writes a header row followed by rows as TSV or CSV
+func WriteTable(w io.Writer, format string, header []string, rows [][]string) error {
+	switch format {
+	case OutputCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	case OutputTSV:
+		for _, row := range append([][]string{header}, rows...) {
+			fields := make([]string, len(row))
+			for i, f := range row {
+				fields[i] = tsvEscaper.Replace(f)
+			}
+			if _, err := fmt.Fprintln(w, strings.Join(fields, "\t")); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported table format %q", format)
+	}
+}
+
+// MRTableHeader is the stable column order for merge request tables
+var MRTableHeader = []string{
+	"reference", "iid", "title", "state", "draft", "source_branch", "target_branch",
+	"author", "labels", "created_at", "updated_at", "web_url",
+}
+
+// MRTableRow renders a merge request in MRTableHeader column order
+func MRTableRow(mr MergeRequest) []string {
+	return []string{
+		mr.References.Full,
+		strconv.Itoa(mr.IID),
+		mr.Title,
+		mr.State,
+		strconv.FormatBool(mr.Draft),
+		mr.SourceBranch,
+		mr.TargetBranch,
+		mr.Author.Username,
+		strings.Join(mr.Labels, ","),
+		mr.CreatedAt.UTC().Format(time.RFC3339),
+		mr.UpdatedAt.UTC().Format(time.RFC3339),
+		mr.WebURL,
+	}
+}
+
+// MRTableRows renders merge requests in MRTableHeader column order
+func MRTableRows(mrs []MergeRequest) [][]string {
+	rows := make([][]string, 0, len(mrs))
+	for _, mr := range mrs {
+		rows = append(rows, MRTableRow(mr))
+	}
+	return rows
+}
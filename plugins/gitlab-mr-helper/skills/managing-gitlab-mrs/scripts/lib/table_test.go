@@ -0,0 +1,84 @@
+package lib_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestWriteTable(t *testing.T) {
+	header := []string{"name", "value"}
+	rows := [][]string{
+		{"plain", "text"},
+		{"tab\there", "new\nline"},
+		{`back\slash`, "cr\rend"},
+		{"comma, here", `say "hi"`},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{lib.OutputTSV, "name\tvalue\n" +
+			"plain\ttext\n" +
+			`tab\there` + "\t" + `new\nline` + "\n" +
+			`back\\slash` + "\t" + `cr\rend` + "\n" +
+			"comma, here\tsay \"hi\"\n"},
+		{lib.OutputCSV, "name,value\n" +
+			"plain,text\n" +
+			"tab\there,\"new\nline\"\n" +
+			"back\\slash,\"cr\rend\"\n" +
+			"\"comma, here\",\"say \"\"hi\"\"\"\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := lib.WriteTable(&buf, tt.format, header, rows); err != nil {
+				t.Fatalf("WriteTable: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("WriteTable =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteTableUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := lib.WriteTable(&buf, lib.OutputText, []string{"a"}, nil); err == nil {
+		t.Error("WriteTable accepted the text format")
+	}
+	if err := lib.ValidateOutputFormat("json"); err == nil {
+		t.Error("ValidateOutputFormat accepted json")
+	}
+}
+
+func TestMRTableRow(t *testing.T) {
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.FixedZone("CET", 3600))
+	mr := lib.MergeRequest{
+		IID: 7, Title: "Add login", State: "opened", Draft: true,
+		SourceBranch: "feature/login", TargetBranch: "main",
+		Labels: []string{"frontend", "ux"}, WebURL: "https://gitlab.example.com/group/project/-/merge_requests/7",
+		CreatedAt: created, UpdatedAt: created.Add(time.Hour),
+	}
+	mr.References.Full = "group/project!7"
+	mr.Author.Username = "alice"
+
+	want := []string{
+		"group/project!7", "7", "Add login", "opened", "true", "feature/login", "main",
+		"alice", "frontend,ux", "2024-03-01T09:00:00Z", "2024-03-01T10:00:00Z",
+		"https://gitlab.example.com/group/project/-/merge_requests/7",
+	}
+	got := lib.MRTableRow(mr)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MRTableRow = %q, want %q", got, want)
+	}
+	if len(got) != len(lib.MRTableHeader) {
+		t.Errorf("MRTableRow has %d columns, MRTableHeader %d", len(got), len(lib.MRTableHeader))
+	}
+	if rows := lib.MRTableRows([]lib.MergeRequest{mr, mr}); len(rows) != 2 || !reflect.DeepEqual(rows[1], want) {
+		t.Errorf("MRTableRows = %q", rows)
+	}
+}
@@ -0,0 +1,94 @@
+package lib
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Task is one item of a markdown task list
+type Task struct {
+	Index   int    // 1-based position among the description's tasks
+	Line    int    // 0-based line number in the description
+	Checked bool   // "[x]"
+	Text    string // item text after the checkbox
+}
+
+// taskRe matches "- [ ] text", "* [x] text" and "1. [ ] text" items
+var taskRe = regexp.MustCompile(`^(\s*(?:[-*+]|\d+[.)])\s+\[)([ xX])(\]\s+)(.*)$`)
+
+// ParseTasks returns the task list items of a markdown description, skipping
+// fenced code blocks
+func ParseTasks(description string) []Task {
+	var tasks []Task
+	inFence := false
+	for i, line := range strings.Split(description, "\n") {
+		if isFence(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		m := taskRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		tasks = append(tasks, Task{
+			Index:   len(tasks) + 1,
+			Line:    i,
+			Checked: m[2] != " ",
+			Text:    strings.TrimSpace(m[4]),
+		})
+	}
+	return tasks
+}
+
+// FindTasks selects tasks by 1-based index or case-insensitive text match.
+// Exact text matches win over substring matches.
+func FindTasks(tasks []Task, selector string) []Task {
+	if n, err := strconv.Atoi(selector); err == nil {
+		if n >= 1 && n <= len(tasks) {
+			return []Task{tasks[n-1]}
+		}
+		return nil
+	}
+
+	var exact, partial []Task
+	needle := strings.ToLower(strings.TrimSpace(selector))
+	for _, t := range tasks {
+		text := strings.ToLower(t.Text)
+		switch {
+		case text == needle:
+			exact = append(exact, t)
+		case strings.Contains(text, needle):
+			partial = append(partial, t)
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return partial
+}
+
+// SetTasks checks or unchecks the given tasks and returns the updated
+// description
+func SetTasks(description string, tasks []Task, checked bool) (string, error) {
+	mark := " "
+	if checked {
+		mark = "x"
+	}
+	lines := strings.Split(description, "\n")
+	for _, t := range tasks {
+		if t.Line < 0 || t.Line >= len(lines) {
+			return "", fmt.Errorf("task %d is out of range", t.Index)
+		}
+		m := taskRe.FindStringSubmatch(lines[t.Line])
+		if m == nil {
+			return "", fmt.Errorf("line %d is not a task", t.Line+1)
+		}
+		lines[t.Line] = m[1] + mark + m[3] + m[4]
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,76 @@
+package lib_test
+
+import (
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+const taskDescription = `## Checklist
+
+- [ ] Run migration
+- [x] Update docs
+* [ ] Run migration dry-run
+1. [ ] Notify ops
+
+` + "```" + `
+- [ ] not a task
+` + "```"
+
+func TestParseTasks(t *testing.T) {
+	tasks := lib.ParseTasks(taskDescription)
+	if len(tasks) != 4 {
+		t.Fatalf("got %d tasks, want 4: %+v", len(tasks), tasks)
+	}
+	if tasks[0].Text != "Run migration" || tasks[0].Checked || !tasks[1].Checked || tasks[3].Text != "Notify ops" {
+		t.Errorf("unexpected tasks: %+v", tasks)
+	}
+}
+
+func TestFindTasks(t *testing.T) {
+	tasks := lib.ParseTasks(taskDescription)
+	tests := []struct {
+		selector string
+		want     []int
+	}{
+		{selector: "2", want: []int{2}},
+		{selector: "9", want: nil},
+		{selector: "run migration", want: []int{1}},
+		{selector: "migration", want: []int{1, 3}},
+		{selector: "OPS", want: []int{4}},
+		{selector: "deploy", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			var got []int
+			for _, task := range lib.FindTasks(tasks, tt.selector) {
+				got = append(got, task.Index)
+			}
+			if !equalInts(got, tt.want) {
+				t.Errorf("FindTasks(%q) = %v, want %v", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetTasks(t *testing.T) {
+	tasks := lib.ParseTasks(taskDescription)
+
+	got, err := lib.SetTasks(taskDescription, []lib.Task{tasks[0], tasks[3]}, true)
+	if err != nil {
+		t.Fatalf("SetTasks: %v", err)
+	}
+	updated := lib.ParseTasks(got)
+	if !updated[0].Checked || !updated[3].Checked || updated[2].Checked {
+		t.Errorf("unexpected state after check: %+v", updated)
+	}
+
+	got, err = lib.SetTasks(got, []lib.Task{updated[1]}, false)
+	if err != nil {
+		t.Fatalf("SetTasks: %v", err)
+	}
+	if lib.ParseTasks(got)[1].Checked {
+		t.Error("task 2 still checked after uncheck")
+	}
+}
@@ -0,0 +1,53 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are the helpers available to --format templates
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"age":   FormatAge,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// OutputTemplate renders API objects through a user-supplied Go text/template,
+// one object per line
+type OutputTemplate struct {
+	tmpl *template.Template
+}
+
+// ParseOutputTemplate parses a --format value such as '{{.IID}} {{.Title}}'
+func ParseOutputTemplate(format string) (*OutputTemplate, error) {
+	// Allow shell-friendly escapes since the format usually comes from a flag
+	format = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(format)
+
+	tmpl, err := template.New("format").Funcs(templateFuncs).Option("missingkey=error").Parse(format)
+	if err != nil {
+		return nil, UsageErrorf("invalid --format template: %v", err)
+	}
+	return &OutputTemplate{tmpl: tmpl}, nil
+}
+
+// Execute renders v to w, terminating the output with a newline
+func (t *OutputTemplate) Execute(w io.Writer, v interface{}) error {
+	var sb strings.Builder
+	if err := t.tmpl.Execute(&sb, v); err != nil {
+		return fmt.Errorf("failed to render --format template: %w", err)
+	}
+	out := sb.String()
+	if !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	_, err := io.WriteString(w, out)
+	return err
+}
@@ -0,0 +1,57 @@
+package lib_test
+
+import (
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestOutputTemplate(t *testing.T) {
+	mr := lib.MergeRequest{IID: 7, Title: "Add login", Labels: []string{"bug", "ui"}, Author: lib.User{Username: "alice"}}
+	tests := []struct {
+		name     string
+		format   string
+		data     interface{}
+		want     string
+		parseErr bool
+		execErr  bool
+	}{
+		{name: "fields", format: "{{.IID}} {{.Title}}", data: mr, want: "7 Add login\n"},
+		{name: "nested field", format: "@{{.Author.Username}}", data: mr, want: "@alice\n"},
+		{name: "shell escapes", format: `{{.IID}}\t{{.Title}}\n`, data: mr, want: "7\tAdd login\n"},
+		{name: "helpers", format: `{{join .Labels ","}} {{upper .Author.Username}} {{lower .Title}}`, data: mr, want: "bug,ui ALICE add login\n"},
+		{name: "json", format: "{{json .Labels}}", data: mr, want: `["bug","ui"]` + "\n"},
+		{name: "unclosed action", format: "{{.IID", parseErr: true},
+		{name: "unknown function", format: "{{nope .IID}}", parseErr: true},
+		{name: "missing field", format: "{{.Nope}}", data: mr, execErr: true},
+		{name: "missing map key", format: "{{.nope}}", data: map[string]interface{}{"iid": 7}, execErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := lib.ParseOutputTemplate(tt.format)
+			if tt.parseErr {
+				wantExit(t, err, lib.ExitUsage)
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseOutputTemplate: %v", err)
+			}
+			var sb strings.Builder
+			err = tmpl.Execute(&sb, tt.data)
+			if tt.execErr {
+				if err == nil {
+					t.Fatalf("Execute succeeded with %q, want an error", sb.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if sb.String() != tt.want {
+				t.Errorf("output = %q, want %q", sb.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package lib
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// TerraformState is a Terraform state stored by GitLab's HTTP backend
+type TerraformState struct {
+	ID        int
+	Name      string
+	UpdatedAt time.Time
+	// LockedAt and LockedBy are set while a Terraform run, or a stuck
+	// one, holds the lock
+	LockedAt *time.Time
+	LockedBy string
+	// The latest version: its serial, when and by whom it was written,
+	// and the CI job that wrote it, if any
+	Serial    int
+	WrittenAt *time.Time
+	WrittenBy string
+	Job       string
+}
+
+// Locked reports whether the state is locked
+func (s *TerraformState) Locked() bool {
+	return s.LockedAt != nil
+}
+
+type terraformStateNode struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	LockedAt     *time.Time `json:"lockedAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+	LockedByUser *struct {
+		Username string `json:"username"`
+	} `json:"lockedByUser"`
+	LatestVersion *struct {
+		Serial        int        `json:"serial"`
+		CreatedAt     *time.Time `json:"createdAt"`
+		CreatedByUser *struct {
+			Username string `json:"username"`
+		} `json:"createdByUser"`
+		Job *struct {
+			Name string `json:"name"`
+		} `json:"job"`
+	} `json:"latestVersion"`
+}
+
+func (n *terraformStateNode) state() (TerraformState, error) {
+	id, err := ParseGlobalID(n.ID)
+	if err != nil {
+		return TerraformState{}, err
+	}
+	s := TerraformState{ID: id, Name: n.Name, UpdatedAt: n.UpdatedAt, LockedAt: n.LockedAt}
+	if n.LockedByUser != nil {
+		s.LockedBy = n.LockedByUser.Username
+	}
+	if v := n.LatestVersion; v != nil {
+		s.Serial, s.WrittenAt = v.Serial, v.CreatedAt
+		if v.CreatedByUser != nil {
+			s.WrittenBy = v.CreatedByUser.Username
+		}
+		if v.Job != nil {
+			s.Job = v.Job.Name
+		}
+	}
+	return s, nil
+}
+
+const terraformStatesQuery = `query ProjectTerraformStates($fullPath: ID!, $after: String) {
+  project(fullPath: $fullPath) {
+    terraformStates(first: 100, after: $after) {
+      nodes {
+        id name lockedAt updatedAt
+        lockedByUser { username }
+        latestVersion { serial createdAt createdByUser { username } job { name } }
+      }
+      pageInfo { hasNextPage endCursor }
+    }
+  }
+}`
+
+// ListTerraformStates lists the Terraform states of a project. The REST API
+// only serves single states to Terraform, so they are listed over GraphQL.
+func (c *Client) ListTerraformStates(projectPath string) ([]TerraformState, error) {
+	vars := map[string]interface{}{"fullPath": projectPath}
+	var all []TerraformState
+	for {
+		var data struct {
+			Project *struct {
+				TerraformStates struct {
+					Nodes    []terraformStateNode `json:"nodes"`
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+				} `json:"terraformStates"`
+			} `json:"project"`
+		}
+		if err := c.GraphQL("ProjectTerraformStates", terraformStatesQuery, vars, &data); err != nil {
+			return nil, err
+		}
+		if data.Project == nil {
+			return nil, fmt.Errorf("%w: project %s", ErrNotFound, projectPath)
+		}
+		page := data.Project.TerraformStates
+		for i := range page.Nodes {
+			s, err := page.Nodes[i].state()
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, s)
+		}
+		if !page.PageInfo.HasNextPage {
+			return all, nil
+		}
+		vars["after"] = page.PageInfo.EndCursor
+	}
+}
+
+// FindTerraformState finds a project's Terraform state by name
+func (c *Client) FindTerraformState(projectPath, name string) (*TerraformState, error) {
+	states, err := c.ListTerraformStates(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	for i := range states {
+		if states[i].Name == name {
+			return &states[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: Terraform state %q in %s", ErrNotFound, name, projectPath)
+}
+
+// LockTerraformState locks a Terraform state, so Terraform runs wait
+func (c *Client) LockTerraformState(id int) error {
+	return c.terraformStateMutation("LockTerraformState", "terraformStateLock", id)
+}
+
+// UnlockTerraformState removes the lock of a Terraform state, whoever
+// holds it, like terraform force-unlock
+func (c *Client) UnlockTerraformState(id int) error {
+	return c.terraformStateMutation("UnlockTerraformState", "terraformStateUnlock", id)
+}
+
+func (c *Client) terraformStateMutation(operation, mutation string, id int) error {
+	query := fmt.Sprintf(`mutation %s($input: %sInput!) {
+  result: %s(input: $input) { errors }
+}`, operation, "T"+mutation[1:], mutation)
+	var data struct {
+		Result *struct {
+			Errors []string `json:"errors"`
+		} `json:"result"`
+	}
+	input := map[string]interface{}{"id": GlobalID("Terraform::State", id)}
+	if err := c.GraphQL(operation, query, map[string]interface{}{"input": input}, &data); err != nil {
+		return err
+	}
+	if data.Result == nil {
+		return fmt.Errorf("%w: Terraform state %d", ErrNotFound, id)
+	}
+	return mutationErrors(data.Result.Errors)
+}
+
+// TerraformStateAddress is the HTTP backend address Terraform stores a
+// state at
+func (c *Client) TerraformStateAddress(projectPath, name string) string {
+	return c.apiURL("/projects/%s/terraform/state/%s", url.PathEscape(projectPath), url.PathEscape(name))
+}
@@ -0,0 +1,60 @@
+package lib_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestTerraformStates(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	states, err := client.ListTerraformStates(gitlabtest.ProjectPath)
+	if err != nil {
+		t.Fatalf("ListTerraformStates: %v", err)
+	}
+	if len(states) != 2 || !states[0].Locked() || states[0].LockedBy != "bob" || states[0].Serial != 41 || states[1].Locked() || states[1].Job != "apply:staging" {
+		t.Fatalf("ListTerraformStates = %+v", states)
+	}
+
+	// Unlocking works whoever holds the lock
+	prod, err := client.FindTerraformState(gitlabtest.ProjectPath, "production")
+	if err != nil {
+		t.Fatalf("FindTerraformState: %v", err)
+	}
+	if err := client.UnlockTerraformState(prod.ID); err != nil {
+		t.Fatalf("UnlockTerraformState: %v", err)
+	}
+	if prod, err = client.FindTerraformState(gitlabtest.ProjectPath, "production"); err != nil || prod.Locked() {
+		t.Errorf("after unlocking: %+v, %v", prod, err)
+	}
+
+	if err := client.LockTerraformState(prod.ID); err != nil {
+		t.Fatalf("LockTerraformState: %v", err)
+	}
+	if prod, err = client.FindTerraformState(gitlabtest.ProjectPath, "production"); err != nil || prod.LockedBy != gitlabtest.Alice.Username {
+		t.Errorf("after locking: %+v, %v", prod, err)
+	}
+	var gqlErr *lib.GraphQLError
+	if err := client.LockTerraformState(prod.ID); !errors.As(err, &gqlErr) {
+		t.Errorf("locking a locked state: err = %v, want a GraphQL error", err)
+	}
+
+	if _, err := client.FindTerraformState(gitlabtest.ProjectPath, "nope"); !errors.Is(err, lib.ErrNotFound) {
+		t.Errorf("FindTerraformState(nope): err = %v, want ErrNotFound", err)
+	}
+	if err := client.UnlockTerraformState(99); !errors.Is(err, lib.ErrNotFound) {
+		t.Errorf("UnlockTerraformState(99): err = %v, want ErrNotFound", err)
+	}
+	if _, err := client.ListTerraformStates("nope/nope"); !errors.Is(err, lib.ErrNotFound) {
+		t.Errorf("ListTerraformStates(nope/nope): err = %v, want ErrNotFound", err)
+	}
+
+	if addr := client.TerraformStateAddress(gitlabtest.ProjectPath, "production"); !strings.HasSuffix(addr, "/api/v4/projects/group%2Fproject/terraform/state/production") {
+		t.Errorf("TerraformStateAddress = %s", addr)
+	}
+}
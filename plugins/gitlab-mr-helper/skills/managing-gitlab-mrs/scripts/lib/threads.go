@@ -0,0 +1,271 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ThreadDigestOptions controls WriteThreadDigest
+type ThreadDigestOptions struct {
+	Context         int  // diff lines shown on each side of a commented line
+	IncludeResolved bool // also list resolved threads, marked as such
+}
+
+// WriteThreadDigest writes the review threads of an MR as compact markdown
+// meant as context for generating fixes: one section per thread with its
+// location, the comments and the diff lines around the commented line.
+// Standalone comments and system notes are left out.
+func WriteThreadDigest(w io.Writer, mr *MergeRequest, discussions []Discussion, diffs []Diff, opts ThreadDigestOptions) error {
+	var threads []Discussion
+	for _, d := range discussions {
+		if d.IndividualNote || !d.resolvable() || (d.Resolved() && !opts.IncludeResolved) {
+			continue
+		}
+		threads = append(threads, d)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# !%d %s\n", mr.IID, mr.Title)
+	fmt.Fprintf(&sb, "%s "+Arrow+" %s, %d %s\n", mr.SourceBranch, mr.TargetBranch, len(threads), plural(len(threads), "thread", "threads"))
+
+	for _, d := range threads {
+		first := d.Notes[0]
+		where := "General"
+		if first.Position != nil {
+			where = first.Position.Location()
+		}
+		state := ""
+		if d.Resolved() {
+			state = ", resolved"
+		}
+		fmt.Fprintf(&sb, "\n## %s (thread %s%s)\n", where, d.ID, state)
+		for _, n := range d.Notes {
+			if n.System {
+				continue
+			}
+			fmt.Fprintf(&sb, "**@%s:** %s\n", n.Author.Username, strings.TrimSpace(n.Body))
+		}
+
+		if pos := first.Position; pos != nil {
+			hunk := ""
+			for _, diff := range diffs {
+				if diff.NewPath == pos.NewPath || diff.OldPath == pos.OldPath {
+					hunk = DiffHunk(diff.Diff, pos.OldLine, pos.NewLine, opts.Context)
+					break
+				}
+			}
+			if hunk == "" {
+				sb.WriteString("(outdated: the line is no longer in the diff)\n")
+			} else {
+				fmt.Fprintf(&sb, "```diff\n%s```\n", hunk)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// resolvable reports whether the thread has any resolvable note, which
+// tells review threads from plain comments
+func (d *Discussion) resolvable() bool {
+	for _, n := range d.Notes {
+		if n.Resolvable {
+			return true
+		}
+	}
+	return false
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// AddressedThread is an unresolved review thread and the commits that
+// changed its lines after it was started, oldest first
+type AddressedThread struct {
+	Discussion Discussion
+	Commits    []Commit
+}
+
+// AddressedPrefix starts the reply that links a thread to the commits
+// addressing it
+const AddressedPrefix = "Addressed in "
+
+// MatchThreadCommits maps the unresolved diff threads of an MR to the
+// commits that may address them: commits made after the thread was started
+// whose diff (in commitDiffs, by commit ID) changes the commented file
+// within window lines of the commented line. Threads on a removed line are
+// matched on its old line, and commits an AddressedPrefix reply of the
+// thread already names are skipped. Every unresolved diff thread is
+// returned, those no commit touched with no commits.
+func MatchThreadCommits(discussions []Discussion, commits []Commit, commitDiffs map[string][]Diff, window int) []AddressedThread {
+	ordered := append([]Commit(nil), commits...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].CreatedAt.Before(ordered[j].CreatedAt) })
+
+	var out []AddressedThread
+	for _, d := range discussions {
+		if d.IndividualNote || !d.resolvable() || d.Resolved() || d.Notes[0].Position == nil {
+			continue
+		}
+		first := d.Notes[0]
+		pos := first.Position
+		path, line := pos.NewPath, pos.NewLine
+		if line == 0 {
+			path, line = pos.OldPath, pos.OldLine
+		}
+
+		t := AddressedThread{Discussion: d}
+		for _, c := range ordered {
+			if !c.CreatedAt.After(first.CreatedAt) || d.addressedIn(c) {
+				continue
+			}
+			for _, diff := range commitDiffs[c.ID] {
+				if diff.OldPath == path && changesLines(diff.Diff, line-window, line+window) {
+					t.Commits = append(t.Commits, c)
+					break
+				}
+			}
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// addressedIn reports whether a reply of the thread already says it was
+// addressed in commit c
+func (d *Discussion) addressedIn(c Commit) bool {
+	for _, n := range d.Notes {
+		if rest, ok := strings.CutPrefix(n.Body, AddressedPrefix); ok && c.ShortID != "" && strings.Contains(rest, c.ShortID) {
+			return true
+		}
+	}
+	return false
+}
+
+// changesLines reports whether a unified diff removes a line of the old
+// file in [from, to] or adds lines next to one
+func changesLines(diff string, from, to int) bool {
+	for _, l := range parseDiff(diff) {
+		// An added line sits before old line l.old, so it counts for the
+		// lines on both sides of it
+		switch {
+		case l.hunk < 0 || l.isOld == l.isNew:
+		case l.isOld && l.old >= from && l.old <= to:
+			return true
+		case l.isNew && l.old >= from && l.old-1 <= to:
+			return true
+		}
+	}
+	return false
+}
+
+// diffLine is a line of a unified diff with its position in the old and
+// new file before the line
+type diffLine struct {
+	text         string
+	old, new     int
+	hunk         int // index of the hunk header among the lines
+	isOld, isNew bool
+}
+
+// DiffHunk returns the lines of a unified diff around the given line of the
+// new file (or, when newLine is 0, of the old file) with context lines on
+// each side, under a hunk header for just those lines. It returns "" when
+// the line is not part of the diff.
+func DiffHunk(diff string, oldLine, newLine, context int) string {
+	lines := parseDiff(diff)
+
+	target := -1
+	for i, l := range lines {
+		if l.hunk < 0 {
+			continue
+		}
+		if (newLine > 0 && l.isNew && l.new == newLine) || (newLine == 0 && oldLine > 0 && l.isOld && !l.isNew && l.old == oldLine) {
+			target = i
+			break
+		}
+	}
+	if target < 0 {
+		return ""
+	}
+
+	start, end := target, target
+	for start > 0 && target-start < context && lines[start-1].hunk == lines[target].hunk {
+		start--
+	}
+	for end < len(lines)-1 && end-target < context && lines[end+1].hunk == lines[target].hunk {
+		end++
+	}
+
+	var body strings.Builder
+	oldCount, newCount := 0, 0
+	for _, l := range lines[start : end+1] {
+		body.WriteString(l.text + "\n")
+		if l.isOld {
+			oldCount++
+		}
+		if l.isNew {
+			newCount++
+		}
+	}
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", lines[start].old, oldCount, lines[start].new, newCount)
+	if _, section, ok := strings.Cut(strings.TrimPrefix(lines[lines[target].hunk].text, "@@"), "@@"); ok && strings.TrimSpace(section) != "" {
+		header += section
+	}
+	return header + "\n" + body.String()
+}
+
+// parseDiff splits a unified diff into lines numbered in the old and new
+// file
+func parseDiff(diff string) []diffLine {
+	var lines []diffLine
+	var old, new, hunk int
+	for _, text := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		if strings.HasPrefix(text, "@@") {
+			old, new = parseHunkHeader(text)
+			hunk = len(lines)
+			lines = append(lines, diffLine{text: text, hunk: -1})
+			continue
+		}
+		l := diffLine{text: text, old: old, new: new, hunk: hunk}
+		switch {
+		case strings.HasPrefix(text, "-"):
+			l.isOld = true
+		case strings.HasPrefix(text, "+"):
+			l.isNew = true
+		case strings.HasPrefix(text, `\`): // \ No newline at end of file
+		default:
+			l.isOld, l.isNew = true, true
+		}
+		if l.isOld {
+			old++
+		}
+		if l.isNew {
+			new++
+		}
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+// parseHunkHeader returns the first old and new line of a hunk header
+// such as "@@ -12,7 +12,9 @@ func main() {"
+func parseHunkHeader(header string) (old, new int) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 {
+		return 0, 0
+	}
+	start := func(field string) int {
+		n, _, _ := strings.Cut(field[1:], ",")
+		v, _ := strconv.Atoi(n)
+		return v
+	}
+	return start(fields[1]), start(fields[2])
+}
@@ -0,0 +1,198 @@
+package lib_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+const hunkDiff = `@@ -10,7 +10,8 @@ func login() {
+ 	user := form.User()
+ 	if user == "" {
+-		return nil
++		return errEmptyUser
+ 	}
++	log(user)
+ 	check(user)
+ 	save(user)
+ 	done()
+@@ -40,3 +41,3 @@ func logout() {
+ 	a()
+-	b()
++	c()
+`
+
+func TestDiffHunk(t *testing.T) {
+	tests := []struct {
+		name             string
+		oldLine, newLine int
+		context          int
+		want             string
+	}{
+		{
+			name: "added line", newLine: 12, context: 1,
+			want: "@@ -12,2 +12,2 @@ func login() {\n-\t\treturn nil\n+\t\treturn errEmptyUser\n \t}\n",
+		},
+		{
+			name: "removed line", oldLine: 12, context: 0,
+			want: "@@ -12,1 +12,0 @@ func login() {\n-\t\treturn nil\n",
+		},
+		{
+			name: "context clipped at the hunk start", newLine: 10, context: 2,
+			want: "@@ -10,3 +10,2 @@ func login() {\n \tuser := form.User()\n \tif user == \"\" {\n-\t\treturn nil\n",
+		},
+		{
+			name: "second hunk", newLine: 42, context: 5,
+			want: "@@ -40,2 +41,2 @@ func logout() {\n \ta()\n-\tb()\n+\tc()\n",
+		},
+		{name: "line outside the diff", newLine: 30, context: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lib.DiffHunk(hunkDiff, tt.oldLine, tt.newLine, tt.context); got != tt.want {
+				t.Errorf("DiffHunk =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteThreadDigest(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	mr, err := client.GetMR(gitlabtest.ProjectPath, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	discussions, err := client.ListMRDiscussions(gitlabtest.ProjectPath, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := client.GetMRDiffs(gitlabtest.ProjectPath, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	if err := lib.WriteThreadDigest(&sb, mr, discussions, diffs, lib.ThreadDigestOptions{Context: 3}); err != nil {
+		t.Fatal(err)
+	}
+	want := "# !1 Add login page\n" +
+		"feature/login → main, 1 thread\n" +
+		"\n## web/login.html:1 (thread d1)\n" +
+		"**@bob:** Please add a test for the error path\n" +
+		"**@alice:** Will do\n" +
+		"```diff\n@@ -0,0 +1,1 @@\n+<form></form>\n```\n"
+	if sb.String() != want {
+		t.Errorf("digest =\n%s\nwant\n%s", sb.String(), want)
+	}
+
+	// Resolved threads on request; a thread whose line left the diff is outdated
+	sb.Reset()
+	lib.WriteThreadDigest(&sb, mr, discussions, nil, lib.ThreadDigestOptions{IncludeResolved: true})
+	for _, s := range []string{"2 threads", "## General (thread d3, resolved)", "(outdated: the line is no longer in the diff)"} {
+		if !strings.Contains(sb.String(), s) {
+			t.Errorf("digest with resolved threads lacks %q:\n%s", s, sb.String())
+		}
+	}
+}
+
+func TestMatchThreadCommits(t *testing.T) {
+	started := gitlabtest.FixtureTime
+	thread := func(id, path string, newLine, oldLine int, resolved bool) lib.Discussion {
+		return lib.Discussion{ID: id, Notes: []lib.Note{{
+			Type: "DiffNote", Resolvable: true, Resolved: resolved, CreatedAt: started,
+			Position: &lib.NotePosition{OldPath: path, NewPath: path, NewLine: newLine, OldLine: oldLine},
+		}}}
+	}
+	replied := func(d lib.Discussion, body string) lib.Discussion {
+		d.Notes = append(d.Notes, lib.Note{Body: body, Resolvable: true})
+		return d
+	}
+	commits := []lib.Commit{
+		{ID: "later", ShortID: "later1", CreatedAt: started.Add(2 * time.Hour)},
+		{ID: "fix", ShortID: "fix1", CreatedAt: started.Add(time.Hour)},
+		{ID: "before", ShortID: "before1", CreatedAt: started.Add(-time.Hour)},
+	}
+	diffs := map[string][]lib.Diff{
+		"fix":    {{OldPath: "login.go", NewPath: "login.go", Diff: hunkDiff}},
+		"later":  {{OldPath: "login.go", NewPath: "login.go", Diff: "@@ -1 +1 @@\n-a\n+b\n"}},
+		"before": {{OldPath: "login.go", NewPath: "login.go", Diff: hunkDiff}},
+	}
+
+	tests := []struct {
+		name   string
+		thread lib.Discussion
+		window int
+		want   []string // commit IDs, or nil for an unaddressed thread
+	}{
+		{name: "removed line", thread: thread("t", "login.go", 12, 0, false), want: []string{"fix"}},
+		{name: "next to an added line", thread: thread("t", "login.go", 14, 0, false), want: []string{"fix"}},
+		{name: "untouched line", thread: thread("t", "login.go", 20, 0, false)},
+		{name: "within the window", thread: thread("t", "login.go", 38, 0, false), window: 3, want: []string{"fix"}},
+		{name: "both commits", thread: thread("t", "login.go", 4, 0, false), window: 9, want: []string{"fix", "later"}},
+		{name: "comment on a removed line", thread: thread("t", "login.go", 0, 41, false), want: []string{"fix"}},
+		{name: "other file", thread: thread("t", "main.go", 12, 0, false)},
+		{name: "already replied to", thread: replied(thread("t", "login.go", 4, 0, false), "Addressed in fix1"), window: 9, want: []string{"later"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lib.MatchThreadCommits([]lib.Discussion{tt.thread}, commits, diffs, tt.window)
+			if len(got) != 1 {
+				t.Fatalf("MatchThreadCommits returned %d threads, want 1", len(got))
+			}
+			var ids []string
+			for _, c := range got[0].Commits {
+				ids = append(ids, c.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("commits = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	// Resolved threads and general comments are left out
+	general := lib.Discussion{ID: "g", Notes: []lib.Note{{Resolvable: true, CreatedAt: started}}}
+	if got := lib.MatchThreadCommits([]lib.Discussion{thread("r", "login.go", 12, 0, true), general}, commits, diffs, 0); len(got) != 0 {
+		t.Errorf("MatchThreadCommits = %+v, want no threads", got)
+	}
+}
+
+func TestReplyToDiscussion(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	commits, err := client.ListMRCommits(gitlabtest.ProjectPath, 1)
+	if err != nil || len(commits) != 2 {
+		t.Fatalf("ListMRCommits = %d commits, %v", len(commits), err)
+	}
+	diffs, err := client.GetCommitDiff(gitlabtest.ProjectPath, commits[0].ID)
+	if err != nil || len(diffs) != 2 {
+		t.Fatalf("GetCommitDiff = %d diffs, %v", len(diffs), err)
+	}
+	if _, err := client.GetCommitDiff(gitlabtest.ProjectPath, "unknown"); lib.ExitCode(err) != lib.ExitNotFound {
+		t.Errorf("GetCommitDiff(unknown) error = %v, want not found", err)
+	}
+
+	note, err := client.ReplyToDiscussion(gitlabtest.ProjectPath, 1, "d1", "Addressed in abc777a")
+	if err != nil {
+		t.Fatalf("ReplyToDiscussion: %v", err)
+	}
+	if note.Body != "Addressed in abc777a" {
+		t.Errorf("note body = %q", note.Body)
+	}
+	if err := client.ResolveDiscussion(gitlabtest.ProjectPath, 1, "d1", true); err != nil {
+		t.Fatalf("ResolveDiscussion: %v", err)
+	}
+	if _, err := client.ReplyToDiscussion(gitlabtest.ProjectPath, 1, "nope", "x"); lib.ExitCode(err) != lib.ExitNotFound {
+		t.Errorf("ReplyToDiscussion(nope) error = %v, want not found", err)
+	}
+
+	d := srv.Project(gitlabtest.ProjectPath).Discussions[1][0]
+	if len(d.Notes) != 3 || !d.Resolved() {
+		t.Errorf("thread d1 = %d notes, resolved %v; want 3 notes, resolved", len(d.Notes), d.Resolved())
+	}
+}
@@ -0,0 +1,108 @@
+package lib
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// DefaultTitleTemplate keeps conventional-commit types from the branch
+// prefix and appends the ticket ID when one is found
+const DefaultTitleTemplate = `{{if .Type}}{{.Type}}: {{end}}{{.Summary}}{{if .Ticket}} ({{.Ticket}}){{end}}`
+
+// conventionalBranchTypes maps branch prefixes to conventional-commit types.
+// Only literal conventional types set TitleParts.Type; the long-form aliases
+// are stripped without adding a type.
+var conventionalBranchTypes = map[string]string{
+	"build": "build", "chore": "chore", "ci": "ci", "docs": "docs", "feat": "feat", "fix": "fix",
+	"perf": "perf", "refactor": "refactor", "revert": "revert", "style": "style", "test": "test",
+}
+
+// strippedBranchPrefixes are removed from titles without implying a type
+var strippedBranchPrefixes = map[string]bool{"feature": true, "bugfix": true, "hotfix": true}
+
+var (
+	// jiraTicket*Re match tracker keys like ABC-123 (see ticketKeyPattern)
+	jiraTicketPrefixRe = regexp.MustCompile(`^(` + ticketKeyPattern + `)(?:[-_]|$)`)
+	jiraTicketRe       = regexp.MustCompile(DefaultTicketPattern)
+	// issueTicketRe matches GitLab issue branches like 456-fix-bug or #456
+	issueTicketPrefixRe = regexp.MustCompile(`^#?(\d+)(?:[-_]|$)`)
+)
+
+// TitleParts are the pieces extracted from a branch name, available to
+// title templates as {{.Type}}, {{.Summary}}, {{.Ticket}} and {{.Branch}}
+type TitleParts struct {
+	Type    string // conventional-commit type, e.g. "feat"
+	Summary string // human-readable summary, first letter capitalized
+	Ticket  string // "ABC-123" or "#456"
+	Branch  string // original branch name
+}
+
+// ParseBranch splits a branch name into title parts
+func ParseBranch(branch string) TitleParts {
+	parts := TitleParts{Branch: branch}
+	slug := branch
+
+	// Type prefix (feat/..., feature/...)
+	if prefix, rest, ok := strings.Cut(slug, "/"); ok {
+		lower := strings.ToLower(prefix)
+		if t, ok := conventionalBranchTypes[lower]; ok {
+			parts.Type = t
+			slug = rest
+		} else if strippedBranchPrefixes[lower] {
+			slug = rest
+		}
+	}
+
+	// Ticket at the start of the slug, or a tracker key anywhere
+	if m := jiraTicketPrefixRe.FindStringSubmatch(slug); m != nil && !issueTicketPrefixRe.MatchString(slug) {
+		parts.Ticket = m[1]
+		slug = strings.TrimPrefix(slug[len(m[1]):], "-")
+		slug = strings.TrimPrefix(slug, "_")
+	} else if m := issueTicketPrefixRe.FindStringSubmatch(slug); m != nil {
+		parts.Ticket = "#" + m[1]
+		slug = strings.TrimPrefix(slug, "#")
+		slug = strings.TrimPrefix(slug[len(m[1]):], "-")
+		slug = strings.TrimPrefix(slug, "_")
+	} else if key := jiraTicketRe.FindString(slug); key != "" {
+		parts.Ticket = key
+		slug = strings.Replace(slug, key, "", 1)
+	}
+
+	// Replace separators with spaces
+	summary := strings.NewReplacer("-", " ", "_", " ", "/", " ").Replace(slug)
+	summary = strings.Join(strings.Fields(summary), " ")
+
+	// Capitalize first letter
+	if len(summary) > 0 {
+		summary = strings.ToUpper(summary[:1]) + summary[1:]
+	} else {
+		// Branch is just a ticket; it becomes the summary
+		summary, parts.Ticket = parts.Ticket, ""
+	}
+	parts.Summary = summary
+	return parts
+}
+
+// BuildTitle renders a title for a branch with a text/template (empty means
+// DefaultTitleTemplate)
+func BuildTitle(branch, tmpl string) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultTitleTemplate
+	}
+	t, err := template.New("title").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid title template: %w", err)
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, ParseBranch(branch)); err != nil {
+		return "", fmt.Errorf("failed to render title template: %w", err)
+	}
+	title := strings.Join(strings.Fields(sb.String()), " ")
+	if title == "" {
+		title = branch
+	}
+	return title, nil
+}
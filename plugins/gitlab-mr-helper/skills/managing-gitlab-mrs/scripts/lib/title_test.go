@@ -0,0 +1,57 @@
+package lib_test
+
+import (
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestBuildTitle(t *testing.T) {
+	tests := []struct {
+		branch   string
+		template string
+		want     string
+	}{
+		{branch: "feature/add-login-page", want: "Add login page"},
+		{branch: "bugfix/null_pointer", want: "Null pointer"},
+		{branch: "feat/add-login", want: "feat: Add login"},
+		{branch: "fix/ABC-123-crash-on-start", want: "fix: Crash on start (ABC-123)"},
+		{branch: "feature/abc-42-lowercase-key", want: "Abc 42 lowercase key"},
+		{branch: "feat/node-18-upgrade", want: "feat: Node 18 upgrade"},
+		{branch: "chore/python3-12-support", want: "chore: Python3 12 support"},
+		{branch: "fix/step-2-of-wizard", want: "fix: Step 2 of wizard"},
+		{branch: "456-fix-the-bug", want: "Fix the bug (#456)"},
+		{branch: "docs/#12-readme", want: "docs: Readme (#12)"},
+		{branch: "feature/update-PROJ-9-docs", want: "Update docs (PROJ-9)"},
+		{branch: "plain", want: "Plain"},
+		{branch: "ABC-1", want: "ABC-1"},
+		{
+			branch:   "feat/ABC-7-add-search",
+			template: "[{{.Ticket}}] {{.Type}}: {{.Summary}}",
+			want:     "[ABC-7] feat: Add search",
+		},
+		{
+			branch:   "chore/bump-deps",
+			template: "{{.Summary}} [{{.Branch}}]",
+			want:     "Bump deps [chore/bump-deps]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.branch, func(t *testing.T) {
+			got, err := lib.BuildTitle(tt.branch, tt.template)
+			if err != nil {
+				t.Fatalf("BuildTitle: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("BuildTitle(%q) = %q, want %q", tt.branch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildTitleInvalidTemplate(t *testing.T) {
+	if _, err := lib.BuildTitle("x", "{{.Nope}}"); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
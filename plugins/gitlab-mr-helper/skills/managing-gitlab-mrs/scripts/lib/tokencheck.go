@@ -0,0 +1,77 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// DefaultTokenWarnDays is how many days before expiry GetConfig starts
+// warning; GITLAB_TOKEN_WARN_DAYS overrides it and 0 disables the check
+const DefaultTokenWarnDays = 14
+
+// tokenCheckInterval is how often the token expiry is fetched from GitLab
+const tokenCheckInterval = 24 * time.Hour
+
+// tokenCheck caches the expiry of a token. The token itself is never
+// stored, only a hash to notice when it changed.
+type tokenCheck struct {
+	TokenHash string    `json:"token_hash"`
+	CheckedAt time.Time `json:"checked_at"`
+	ExpiresAt Date      `json:"expires_at"`
+}
+
+func tokenWarnDays() int {
+	if v := os.Getenv("GITLAB_TOKEN_WARN_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return DefaultTokenWarnDays
+}
+
+// CheckTokenExpiry writes a warning to w when the configured token expires
+// within warnDays. The expiry is fetched at most once a day and cached
+// next to the sync.go cache; lookup failures (e.g. tokens that are not
+// personal access tokens) are cached too and never reported.
+func CheckTokenExpiry(config *Config, warnDays int, w io.Writer) {
+	if warnDays <= 0 {
+		return
+	}
+	dir, err := cacheDir(config.URL)
+	if err != nil {
+		return
+	}
+	path := filepath.Join(dir, "token.json")
+	sum := sha256.Sum256([]byte(config.Token))
+	hash := hex.EncodeToString(sum[:8])
+
+	var check tokenCheck
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &check)
+	}
+	if check.TokenHash != hash || time.Since(check.CheckedAt) > tokenCheckInterval {
+		check = tokenCheck{TokenHash: hash, CheckedAt: time.Now()}
+		quick := *config
+		quick.Debug = false
+		quick.Sudo = "" // the token's own expiry, not the impersonated user's
+		client := NewClient(&quick)
+		client.httpClient.Timeout = 5 * time.Second
+		if token, err := client.GetCurrentToken(); err == nil {
+			check.ExpiresAt = token.ExpiresAt
+		}
+		if data, err := json.Marshal(&check); err == nil && os.MkdirAll(dir, 0o700) == nil {
+			os.WriteFile(path, data, 0o600)
+		}
+	}
+
+	if status, soon := ExpiryStatus(check.ExpiresAt, time.Now(), warnDays); soon {
+		fmt.Fprintf(w, "Warning: GitLab token %s (%s); rotate it with rotate_token.go\n", status, check.ExpiresAt)
+	}
+}
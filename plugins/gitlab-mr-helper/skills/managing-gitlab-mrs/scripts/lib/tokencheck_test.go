@@ -0,0 +1,87 @@
+package lib_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestCheckTokenExpiry(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	srv := gitlabtest.NewServer(t)
+	config := &lib.Config{URL: srv.URL, Token: gitlabtest.Token}
+
+	var out bytes.Buffer
+	lib.CheckTokenExpiry(config, 14, &out)
+	if !strings.Contains(out.String(), "Warning: GitLab token expires in 5d") {
+		t.Errorf("warning = %q", out.String())
+	}
+
+	// The second run warns again from the cache without asking GitLab
+	out.Reset()
+	lib.CheckTokenExpiry(config, 14, &out)
+	if !strings.Contains(out.String(), "expires in 5d") {
+		t.Errorf("cached warning = %q", out.String())
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+
+	out.Reset()
+	lib.CheckTokenExpiry(config, 3, &out)
+	if out.Len() != 0 {
+		t.Errorf("warned outside the window: %q", out.String())
+	}
+}
+
+func TestRotateCurrentToken(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	current, err := client.GetCurrentToken()
+	if err != nil {
+		t.Fatalf("GetCurrentToken: %v", err)
+	}
+	if current.Name != "laptop" || current.Token != "" {
+		t.Fatalf("current = %+v", current)
+	}
+
+	expires, _ := lib.ParseExpiry("90d", time.Now())
+	rotated, err := client.RotateCurrentToken(expires)
+	if err != nil {
+		t.Fatalf("RotateCurrentToken: %v", err)
+	}
+	if rotated.Token == "" || rotated.Token != srv.CurrentToken() || rotated.ExpiresAt.DaysLeft(time.Now()) < 89 {
+		t.Errorf("rotated = %+v", rotated)
+	}
+
+	// The old token is revoked by the rotation
+	_, err = client.GetCurrentToken()
+	wantExit(t, err, lib.ExitAuth)
+}
+
+func TestUpdateStoredToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".netrc")
+	netrc := "machine gitlab.com login oauth2 password glpat-old\nmachine example.com password other\n"
+	if err := os.WriteFile(path, []byte(netrc), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lib.UpdateStoredToken(path, "glpat-old", "glpat-new"); err != nil {
+		t.Fatalf("UpdateStoredToken: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if want := strings.Replace(netrc, "glpat-old", "glpat-new", 1); string(data) != want {
+		t.Errorf("netrc = %q, want %q", data, want)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v", info.Mode())
+	}
+	wantExit(t, lib.UpdateStoredToken(path, "glpat-old", "x"), lib.ExitNotFound)
+}
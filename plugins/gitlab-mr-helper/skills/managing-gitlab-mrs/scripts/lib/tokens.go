@@ -0,0 +1,213 @@
+package lib
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Date is a calendar date as used by token expiry fields ("2025-01-31").
+// It also accepts full timestamps, which some endpoints return instead.
+type Date struct {
+	time.Time
+}
+
+const dateLayout = "2006-01-02"
+
+// UnmarshalJSON parses a date, a timestamp or null
+func (d *Date) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" || s == "" {
+		d.Time = time.Time{}
+		return nil
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		if t, err = time.Parse(time.RFC3339, s); err != nil {
+			return fmt.Errorf("invalid date %q", s)
+		}
+	}
+	d.Time = t
+	return nil
+}
+
+// MarshalJSON formats the date as YYYY-MM-DD, or null when unset
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(`"` + d.Format(dateLayout) + `"`), nil
+}
+
+func (d Date) String() string {
+	if d.IsZero() {
+		return "never"
+	}
+	return d.Format(dateLayout)
+}
+
+// DaysLeft is the number of whole days from now until the date; negative
+// once it has passed
+func (d Date) DaysLeft(now time.Time) int {
+	y, m, day := now.Date()
+	today := time.Date(y, m, day, 0, 0, 0, 0, time.UTC)
+	y, m, day = d.Date()
+	return int(time.Date(y, m, day, 0, 0, 0, 0, time.UTC).Sub(today).Hours() / 24)
+}
+
+// ParseExpiry parses an expiry given as a date ("2025-01-31") or a number of
+// days from now ("90d")
+func ParseExpiry(s string, now time.Time) (Date, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return Date{}, fmt.Errorf("invalid expiry %q (expected YYYY-MM-DD or <days>d)", s)
+		}
+		return Date{now.AddDate(0, 0, n)}, nil
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid expiry %q (expected YYYY-MM-DD or <days>d)", s)
+	}
+	return Date{t}, nil
+}
+
+// ExpiryStatus describes an expiry date relative to now: "expired",
+// "expires in 5d", "expires in 120d" or "no expiry". soon reports whether
+// the credential expired or expires within warnDays.
+func ExpiryStatus(expires Date, now time.Time, warnDays int) (status string, soon bool) {
+	if expires.IsZero() {
+		return "no expiry", false
+	}
+	days := expires.DaysLeft(now)
+	switch {
+	case days < 0:
+		return "expired", true
+	case days == 0:
+		return "expires today", true
+	default:
+		return fmt.Sprintf("expires in %dd", days), days <= warnDays
+	}
+}
+
+// AccessToken is a project access token. Token is only set in the response
+// to CreateProjectAccessToken.
+type AccessToken struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Scopes      []string  `json:"scopes"`
+	AccessLevel int       `json:"access_level"`
+	Active      bool      `json:"active"`
+	Revoked     bool      `json:"revoked"`
+	ExpiresAt   Date      `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastUsedAt  time.Time `json:"last_used_at"`
+	Token       string    `json:"token,omitempty"`
+}
+
+// CreateAccessTokenRequest represents the request body for creating a
+// project access token
+type CreateAccessTokenRequest struct {
+	Name        string   `json:"name"`
+	Scopes      []string `json:"scopes"`
+	AccessLevel int      `json:"access_level,omitempty"`
+	ExpiresAt   Date     `json:"expires_at"`
+}
+
+// DeployKey is an SSH key with read (or write) access to one project
+type DeployKey struct {
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	Key         string    `json:"key"`
+	Fingerprint string    `json:"fingerprint"`
+	CanPush     bool      `json:"can_push"`
+	ExpiresAt   Date      `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateDeployKeyRequest represents the request body for adding a deploy key
+type CreateDeployKeyRequest struct {
+	Title     string `json:"title"`
+	Key       string `json:"key"`
+	CanPush   bool   `json:"can_push,omitempty"`
+	ExpiresAt *Date  `json:"expires_at,omitempty"`
+}
+
+// ListProjectAccessTokens lists a project's access tokens
+func (c *Client) ListProjectAccessTokens(projectPath string) ([]AccessToken, error) {
+	endpoint := c.apiURL("/projects/%s/access_tokens", url.PathEscape(projectPath))
+	return getAll[AccessToken](c, endpoint, nil, 0)
+}
+
+// CreateProjectAccessToken creates a project access token. The token value
+// is only ever returned here.
+func (c *Client) CreateProjectAccessToken(projectPath string, req *CreateAccessTokenRequest) (*AccessToken, error) {
+	endpoint := c.apiURL("/projects/%s/access_tokens", url.PathEscape(projectPath))
+
+	var token AccessToken
+	if err := c.do("POST", endpoint, req, &token, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RevokeProjectAccessToken revokes a project access token
+func (c *Client) RevokeProjectAccessToken(projectPath string, tokenID int) error {
+	endpoint := c.apiURL("/projects/%s/access_tokens/%d", url.PathEscape(projectPath), tokenID)
+	return c.do("DELETE", endpoint, nil, nil, http.StatusNoContent)
+}
+
+// ListDeployKeys lists a project's deploy keys
+func (c *Client) ListDeployKeys(projectPath string) ([]DeployKey, error) {
+	endpoint := c.apiURL("/projects/%s/deploy_keys", url.PathEscape(projectPath))
+	return getAll[DeployKey](c, endpoint, nil, 0)
+}
+
+// CreateDeployKey adds a deploy key to a project
+func (c *Client) CreateDeployKey(projectPath string, req *CreateDeployKeyRequest) (*DeployKey, error) {
+	endpoint := c.apiURL("/projects/%s/deploy_keys", url.PathEscape(projectPath))
+
+	var key DeployKey
+	if err := c.do("POST", endpoint, req, &key, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// DeleteDeployKey removes a deploy key from a project
+func (c *Client) DeleteDeployKey(projectPath string, keyID int) error {
+	endpoint := c.apiURL("/projects/%s/deploy_keys/%d", url.PathEscape(projectPath), keyID)
+	return c.do("DELETE", endpoint, nil, nil, http.StatusNoContent)
+}
+
+// GetCurrentToken gets the personal access token the client authenticates
+// with
+func (c *Client) GetCurrentToken() (*AccessToken, error) {
+	endpoint := c.apiURL("/personal_access_tokens/self")
+
+	var token AccessToken
+	if err := c.do("GET", endpoint, nil, &token, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RotateCurrentToken replaces the client's personal access token with a new
+// one expiring at expiresAt (GitLab's default when zero). The old token
+// stops working immediately; the returned token carries the new value.
+func (c *Client) RotateCurrentToken(expiresAt Date) (*AccessToken, error) {
+	endpoint := c.apiURL("/personal_access_tokens/self/rotate")
+	var body interface{}
+	if !expiresAt.IsZero() {
+		body = map[string]Date{"expires_at": expiresAt}
+	}
+
+	var token AccessToken
+	if err := c.do("POST", endpoint, body, &token, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
@@ -0,0 +1,143 @@
+package lib_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestDateJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: `"2025-01-31"`, want: `"2025-01-31"`},
+		{in: `"2025-01-31T10:00:00.000Z"`, want: `"2025-01-31"`},
+		{in: `null`, want: `null`},
+	}
+	for _, tt := range tests {
+		var d lib.Date
+		if err := json.Unmarshal([]byte(tt.in), &d); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", tt.in, err)
+		}
+		out, _ := json.Marshal(d)
+		if string(out) != tt.want {
+			t.Errorf("round trip of %s = %s, want %s", tt.in, out, tt.want)
+		}
+	}
+
+	var d lib.Date
+	if err := json.Unmarshal([]byte(`"soon"`), &d); err == nil {
+		t.Error("Unmarshal of an invalid date: want error")
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	now := time.Date(2024, 3, 1, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "2024-06-30", want: "2024-06-30"},
+		{in: "90d", want: "2024-05-30"},
+		{in: "0d", wantErr: true},
+		{in: "next week", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := lib.ParseExpiry(tt.in, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseExpiry(%q) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got.String() != tt.want {
+			t.Errorf("ParseExpiry(%q) = %v, %v; want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestExpiryStatus(t *testing.T) {
+	now := time.Date(2024, 3, 1, 15, 0, 0, 0, time.UTC)
+	day := func(n int) lib.Date { return lib.Date{Time: time.Date(2024, 3, 1+n, 0, 0, 0, 0, time.UTC)} }
+	tests := []struct {
+		name       string
+		expires    lib.Date
+		wantStatus string
+		wantSoon   bool
+	}{
+		{name: "never", wantStatus: "no expiry"},
+		{name: "expired", expires: day(-1), wantStatus: "expired", wantSoon: true},
+		{name: "today", expires: day(0), wantStatus: "expires today", wantSoon: true},
+		{name: "soon", expires: day(7), wantStatus: "expires in 7d", wantSoon: true},
+		{name: "later", expires: day(60), wantStatus: "expires in 60d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, soon := lib.ExpiryStatus(tt.expires, now, 30)
+			if status != tt.wantStatus || soon != tt.wantSoon {
+				t.Errorf("ExpiryStatus = %q, %v; want %q, %v", status, soon, tt.wantStatus, tt.wantSoon)
+			}
+		})
+	}
+}
+
+func TestProjectAccessTokens(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	tokens, err := client.ListProjectAccessTokens(gitlabtest.ProjectPath)
+	if err != nil {
+		t.Fatalf("ListProjectAccessTokens: %v", err)
+	}
+	if len(tokens) != 2 || tokens[0].Name != "ci-bot" || tokens[0].ExpiresAt.IsZero() {
+		t.Fatalf("tokens = %+v", tokens)
+	}
+
+	expires, _ := lib.ParseExpiry("30d", time.Now())
+	created, err := client.CreateProjectAccessToken(gitlabtest.ProjectPath, &lib.CreateAccessTokenRequest{
+		Name: "deployer", Scopes: []string{"read_registry"}, AccessLevel: lib.AccessReporter, ExpiresAt: expires,
+	})
+	if err != nil {
+		t.Fatalf("CreateProjectAccessToken: %v", err)
+	}
+	if created.Token == "" || created.AccessLevel != lib.AccessReporter {
+		t.Errorf("created = %+v", created)
+	}
+	_, err = client.CreateProjectAccessToken(gitlabtest.ProjectPath, &lib.CreateAccessTokenRequest{Name: "no-scopes", ExpiresAt: expires})
+	wantExit(t, err, lib.ExitError)
+
+	if err := client.RevokeProjectAccessToken(gitlabtest.ProjectPath, created.ID); err != nil {
+		t.Fatalf("RevokeProjectAccessToken: %v", err)
+	}
+	wantExit(t, client.RevokeProjectAccessToken(gitlabtest.ProjectPath, created.ID), lib.ExitNotFound)
+}
+
+func TestDeployKeys(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	keys, err := client.ListDeployKeys(gitlabtest.ProjectPath)
+	if err != nil {
+		t.Fatalf("ListDeployKeys: %v", err)
+	}
+	if len(keys) != 2 || !keys[0].ExpiresAt.IsZero() || !keys[1].CanPush {
+		t.Fatalf("keys = %+v", keys)
+	}
+
+	created, err := client.CreateDeployKey(gitlabtest.ProjectPath, &lib.CreateDeployKeyRequest{Title: "ci", Key: "ssh-ed25519 AAAAnew ci@runner"})
+	if err != nil {
+		t.Fatalf("CreateDeployKey: %v", err)
+	}
+	_, err = client.CreateDeployKey(gitlabtest.ProjectPath, &lib.CreateDeployKeyRequest{Title: "dup", Key: keys[0].Key})
+	wantExit(t, err, lib.ExitError)
+
+	if err := client.DeleteDeployKey(gitlabtest.ProjectPath, created.ID); err != nil {
+		t.Fatalf("DeleteDeployKey: %v", err)
+	}
+	wantExit(t, client.DeleteDeployKey(gitlabtest.ProjectPath, created.ID), lib.ExitNotFound)
+}
@@ -0,0 +1,80 @@
+package lib
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ticketKeyPattern is an external tracker key like ABC-123. It is upper
+// case only, so slugs like node-18 or step-2 are not taken for tickets.
+const ticketKeyPattern = `[A-Z][A-Z0-9]+-\d+`
+
+// DefaultTicketPattern matches external tracker keys like ABC-123, the same
+// keys titles generated from branch names pick up
+const DefaultTicketPattern = `\b` + ticketKeyPattern + `\b`
+
+// Enabled reports whether an external tracker is configured
+func (s *TrackerSettings) Enabled() bool {
+	return s.URL != ""
+}
+
+// Link returns the tracker URL for a ticket
+func (s *TrackerSettings) Link(ticket string) string {
+	return strings.ReplaceAll(s.URL, "{ticket}", ticket)
+}
+
+// FindTickets returns the distinct ticket IDs referenced by a branch name and
+// commit messages, in order of first appearance
+func FindTickets(s *TrackerSettings, branch string, commits []Commit) ([]string, error) {
+	pattern := s.TicketPattern
+	if pattern == "" {
+		pattern = DefaultTicketPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tracker.ticket_pattern: %w", err)
+	}
+
+	var tickets []string
+	seen := make(map[string]bool)
+	add := func(ticket string) {
+		if ticket != "" && !seen[ticket] {
+			seen[ticket] = true
+			tickets = append(tickets, ticket)
+		}
+	}
+
+	for _, t := range re.FindAllString(branch, -1) {
+		add(t)
+	}
+	for _, c := range commits {
+		text := c.Message
+		if text == "" {
+			text = c.Title
+		}
+		for _, t := range re.FindAllString(text, -1) {
+			add(t)
+		}
+	}
+	return tickets, nil
+}
+
+// AppendTicketRefs appends a "Refs:" line per ticket to an MR description,
+// skipping tickets whose link is already present
+func AppendTicketRefs(s *TrackerSettings, description string, tickets []string) string {
+	var refs []string
+	for _, t := range tickets {
+		link := s.Link(t)
+		if !strings.Contains(description, link) {
+			refs = append(refs, "Refs: "+link)
+		}
+	}
+	if len(refs) == 0 {
+		return description
+	}
+	if description == "" {
+		return strings.Join(refs, "\n")
+	}
+	return strings.TrimRight(description, "\n") + "\n\n" + strings.Join(refs, "\n")
+}
@@ -0,0 +1,98 @@
+package lib_test
+
+import (
+	"reflect"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestFindTickets(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		branch  string
+		commits []string
+		want    []string
+	}{
+		{name: "none", branch: "feature/login", commits: []string{"Add login"}},
+		{name: "branch key", branch: "feature/ABC-123-login", want: []string{"ABC-123"}},
+		{name: "lower-case branch slug", branch: "abc-7-login"},
+		{
+			name:    "commits deduplicated",
+			branch:  "fix/ABC-1-crash",
+			commits: []string{"Fix crash\n\nRefs ABC-1, ABC-2", "ABC-2: follow-up"},
+			want:    []string{"ABC-1", "ABC-2"},
+		},
+		{
+			name:    "custom pattern",
+			pattern: `GH-\d+`,
+			branch:  "feature/login",
+			commits: []string{"Closes GH-42"},
+			want:    []string{"GH-42"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var commits []lib.Commit
+			for _, m := range tt.commits {
+				commits = append(commits, lib.Commit{Message: m})
+			}
+			s := &lib.TrackerSettings{URL: "https://jira.example.com/browse/{ticket}", TicketPattern: tt.pattern}
+			got, err := lib.FindTickets(s, tt.branch, commits)
+			if err != nil {
+				t.Fatalf("FindTickets: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindTickets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTicketRefsSkipBranchWords(t *testing.T) {
+	s := &lib.TrackerSettings{URL: "https://jira.example.com/browse/{ticket}"}
+	for _, branch := range []string{"feat/node-18-upgrade", "fix/step-2-of-wizard", "chore/python3-12-support"} {
+		tickets, err := lib.FindTickets(s, branch, []lib.Commit{{Message: "Upgrade to node 18"}})
+		if err != nil {
+			t.Fatalf("FindTickets: %v", err)
+		}
+		if got := lib.AppendTicketRefs(s, "Description", tickets); got != "Description" {
+			t.Errorf("%s: description = %q, want no links", branch, got)
+		}
+	}
+}
+
+func TestAppendTicketRefs(t *testing.T) {
+	s := &lib.TrackerSettings{URL: "https://jira.example.com/browse/{ticket}"}
+	tests := []struct {
+		name        string
+		description string
+		tickets     []string
+		want        string
+	}{
+		{name: "no tickets", description: "Body", want: "Body"},
+		{name: "empty description", tickets: []string{"ABC-1"}, want: "Refs: https://jira.example.com/browse/ABC-1"},
+		{
+			name:        "appended",
+			description: "Body\n",
+			tickets:     []string{"ABC-1", "ABC-2"},
+			want:        "Body\n\nRefs: https://jira.example.com/browse/ABC-1\nRefs: https://jira.example.com/browse/ABC-2",
+		},
+		{
+			name:        "already linked",
+			description: "See https://jira.example.com/browse/ABC-1",
+			tickets:     []string{"ABC-1"},
+			want:        "See https://jira.example.com/browse/ABC-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lib.AppendTicketRefs(s, tt.description, tt.tickets); got != tt.want {
+				t.Errorf("AppendTicketRefs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
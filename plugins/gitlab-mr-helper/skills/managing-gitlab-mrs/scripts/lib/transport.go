@@ -0,0 +1,73 @@
+package lib
+
+import (
+	"crypto/tls"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DefaultMaxConns is how many connections to the instance the client
+// keeps. Go's default of two idle connections per host makes parallel
+// workers beyond the second reconnect, with a new TLS handshake, for every
+// request; this keeps one per worker at the highest --concurrency.
+const DefaultMaxConns = MaxConcurrency
+
+// DefaultIdleTimeout is how long an unused connection is kept open
+const DefaultIdleTimeout = 90 * time.Second
+
+// NewTransport returns the HTTP transport of API clients: connections are
+// reused across requests, up to config.MaxConns of them, and HTTP/2 is
+// negotiated where the instance offers it unless config.NoHTTP2 is set
+// (e.g. for proxies that break it). Over HTTP/2 all requests share one
+// connection.
+func NewTransport(config *Config) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	conns := config.MaxConns
+	if conns <= 0 {
+		conns = DefaultMaxConns
+	}
+	t.MaxConnsPerHost = conns
+	t.MaxIdleConnsPerHost = conns
+	t.IdleConnTimeout = DefaultIdleTimeout
+	if config.IdleTimeout > 0 {
+		t.IdleConnTimeout = config.IdleTimeout
+	}
+
+	t.ForceAttemptHTTP2 = !config.NoHTTP2
+	if config.NoHTTP2 {
+		// A non-nil empty map turns the built-in HTTP/2 support off
+		t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	return t
+}
+
+// connectionConfig reads the connection tuning of config from the
+// environment: GITLAB_MAX_CONNS, GITLAB_IDLE_TIMEOUT (a duration) and
+// GITLAB_HTTP2 (0 or false to use HTTP/1.1 only)
+func connectionConfig(config *Config) error {
+	if v := os.Getenv("GITLAB_MAX_CONNS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return UsageErrorf("invalid GITLAB_MAX_CONNS %q (expected a positive number)", v)
+		}
+		config.MaxConns = n
+	}
+	if v := os.Getenv("GITLAB_IDLE_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return UsageErrorf("invalid GITLAB_IDLE_TIMEOUT %q (expected a duration such as 30s or 2m)", v)
+		}
+		config.IdleTimeout = d
+	}
+	if v := os.Getenv("GITLAB_HTTP2"); v != "" {
+		on, err := strconv.ParseBool(v)
+		if err != nil {
+			return UsageErrorf("invalid GITLAB_HTTP2 %q (expected 1 or 0)", v)
+		}
+		config.NoHTTP2 = !on
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package lib_test
+
+import (
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestNewTransport(t *testing.T) {
+	tr := lib.NewTransport(&lib.Config{})
+	if tr.MaxConnsPerHost != lib.DefaultMaxConns || tr.MaxIdleConnsPerHost != lib.DefaultMaxConns ||
+		tr.IdleConnTimeout != lib.DefaultIdleTimeout || !tr.ForceAttemptHTTP2 {
+		t.Errorf("default transport: %d conns, %d idle, idle for %v, HTTP/2 %v",
+			tr.MaxConnsPerHost, tr.MaxIdleConnsPerHost, tr.IdleConnTimeout, tr.ForceAttemptHTTP2)
+	}
+
+	tr = lib.NewTransport(&lib.Config{MaxConns: 4, IdleTimeout: 30 * time.Second, NoHTTP2: true})
+	if tr.MaxConnsPerHost != 4 || tr.MaxIdleConnsPerHost != 4 || tr.IdleConnTimeout != 30*time.Second ||
+		tr.ForceAttemptHTTP2 || tr.TLSNextProto == nil {
+		t.Errorf("tuned transport: %d conns, %d idle, idle for %v, HTTP/2 %v",
+			tr.MaxConnsPerHost, tr.MaxIdleConnsPerHost, tr.IdleConnTimeout, tr.ForceAttemptHTTP2)
+	}
+}
+
+func TestConnectionReuse(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := lib.NewClient(&lib.Config{URL: srv.URL, Token: srv.CurrentToken(), Concurrency: 8})
+
+	errs := client.ForEach(64, func(i int) error {
+		_, err := client.GetMR(gitlabtest.ProjectPath, 1+i%2)
+		return err
+	})
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("GetMR: %v", err)
+		}
+	}
+
+	// Every connection has its own client port
+	conns := make(map[string]bool)
+	for _, r := range srv.Requests() {
+		conns[r.RemoteAddr] = true
+	}
+	if len(conns) > 8 {
+		t.Errorf("64 requests on 8 workers opened %d connections, want at most 8", len(conns))
+	}
+}
+
+func TestConnectionConfig(t *testing.T) {
+	t.Setenv("GITLAB_TOKEN", "glpat-test")
+	t.Setenv("GITLAB_TOKEN_WARN_DAYS", "0")
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    lib.Config
+		wantErr bool
+	}{
+		{name: "defaults"},
+		{name: "tuned", env: map[string]string{"GITLAB_MAX_CONNS": "4", "GITLAB_IDLE_TIMEOUT": "30s", "GITLAB_HTTP2": "0"},
+			want: lib.Config{MaxConns: 4, IdleTimeout: 30 * time.Second, NoHTTP2: true}},
+		{name: "http2 on", env: map[string]string{"GITLAB_HTTP2": "true"}},
+		{name: "bad conns", env: map[string]string{"GITLAB_MAX_CONNS": "0"}, wantErr: true},
+		{name: "bad timeout", env: map[string]string{"GITLAB_IDLE_TIMEOUT": "soon"}, wantErr: true},
+		{name: "bad http2", env: map[string]string{"GITLAB_HTTP2": "maybe"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"GITLAB_MAX_CONNS", "GITLAB_IDLE_TIMEOUT", "GITLAB_HTTP2"} {
+				t.Setenv(name, tt.env[name])
+			}
+			config, err := lib.GetConfig()
+			if tt.wantErr {
+				if lib.ExitCode(err) != lib.ExitUsage {
+					t.Errorf("GetConfig error = %v, want a usage error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetConfig: %v", err)
+			}
+			if config.MaxConns != tt.want.MaxConns || config.IdleTimeout != tt.want.IdleTimeout || config.NoHTTP2 != tt.want.NoHTTP2 {
+				t.Errorf("GetConfig = %d conns, idle for %v, no HTTP/2 %v; want %d, %v, %v", config.MaxConns, config.IdleTimeout, config.NoHTTP2,
+					tt.want.MaxConns, tt.want.IdleTimeout, tt.want.NoHTTP2)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TriageFileName is the repository's issue triage ruleset, looked up at
+// the root of the current git work tree
+const TriageFileName = ".gitlab-helper-triage.yml"
+
+// TriageRuleset is a list of issue triage rules, e.g.
+//
+//	rules:
+//	  - name: bugs
+//	    when: {title: "(?i)crash|panic"}
+//	    then: {add_labels: [bug]}
+//	  - name: backend owner
+//	    when: {label: "component::backend", unassigned: true}
+//	    then: {assign: [alice]}
+//	  - name: stale
+//	    when: {stale_days: 90}
+//	    then: {close: true, comment: "Closing after {days} days without activity."}
+type TriageRuleset struct {
+	Rules []TriageRule `json:"rules"`
+}
+
+// TriageRule runs its actions on every open issue matching its condition
+type TriageRule struct {
+	Name string          `json:"name"`
+	When TriageCondition `json:"when"`
+	Then TriageActions   `json:"then"`
+
+	title *regexp.Regexp
+}
+
+// TriageCondition matches open issues. Every field set must match, and at
+// least one is required.
+type TriageCondition struct {
+	// Title is a regular expression the title must match
+	Title string `json:"title"`
+	// Label must be on the issue, and NoLabel must not
+	Label   string `json:"label"`
+	NoLabel string `json:"no_label"`
+	// Unassigned matches issues nobody is assigned to
+	Unassigned bool `json:"unassigned"`
+	// StaleDays matches issues not updated for that many days
+	StaleDays int `json:"stale_days"`
+}
+
+// TriageActions change a matching issue. Users are usernames of project
+// members, with or without "@".
+type TriageActions struct {
+	AddLabels    []string `json:"add_labels"`
+	RemoveLabels []string `json:"remove_labels"`
+	Assign       []string `json:"assign"`
+	// Comment is posted on the issue with {author}, {title}, {iid}, {days}
+	// (since the last update) and {url} replaced
+	Comment string `json:"comment"`
+	Close   bool   `json:"close"`
+}
+
+// LoadTriageRules reads a triage ruleset file. An empty path looks for
+// TriageFileName at the repository root, which must exist.
+func LoadTriageRules(file string) (*TriageRuleset, error) {
+	data, path, err := readRepoFile(file, TriageFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read triage rules: %w", err)
+	}
+	if data == nil {
+		return nil, UsageErrorf("no triage rules: create %s at the repository root or pass --rules", TriageFileName)
+	}
+	rs := &TriageRuleset{}
+	if err := DecodeYAML(data, rs); err != nil {
+		return nil, fmt.Errorf("invalid triage rules file %s: %w", path, err)
+	}
+	if err := rs.validate(); err != nil {
+		return nil, fmt.Errorf("invalid triage rules file %s: %w", path, err)
+	}
+	return rs, nil
+}
+
+func (rs *TriageRuleset) validate() error {
+	if len(rs.Rules) == 0 {
+		return errors.New("no rules")
+	}
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+		if r.Name == "" {
+			r.Name = "rule " + strconv.Itoa(i+1)
+		}
+		w, t := r.When, r.Then
+		if w.Title == "" && w.Label == "" && w.NoLabel == "" && !w.Unassigned && w.StaleDays == 0 {
+			return fmt.Errorf("%s: when needs title, label, no_label, unassigned or stale_days", r.Name)
+		}
+		if w.StaleDays < 0 {
+			return fmt.Errorf("%s: stale_days must be positive", r.Name)
+		}
+		if w.Title != "" {
+			re, err := regexp.Compile(w.Title)
+			if err != nil {
+				return fmt.Errorf("%s: invalid title pattern: %w", r.Name, err)
+			}
+			r.title = re
+		}
+		if len(t.AddLabels) == 0 && len(t.RemoveLabels) == 0 && len(t.Assign) == 0 && t.Comment == "" && !t.Close {
+			return fmt.Errorf("%s: then has no actions", r.Name)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the rule's condition holds for issue at now
+func (r *TriageRule) Matches(issue *Issue, now time.Time) bool {
+	w := r.When
+	switch {
+	case issue.State != "opened":
+		return false
+	case r.title != nil && !r.title.MatchString(issue.Title):
+		return false
+	case w.Label != "" && !ContainsString(issue.Labels, w.Label):
+		return false
+	case w.NoLabel != "" && ContainsString(issue.Labels, w.NoLabel):
+		return false
+	case w.Unassigned && len(issue.Assignees) > 0:
+		return false
+	case w.StaleDays > 0 && now.Sub(issue.UpdatedAt) < time.Duration(w.StaleDays)*24*time.Hour:
+		return false
+	}
+	return true
+}
+
+// TriagePlan is what the rules do to one issue
+type TriagePlan struct {
+	Issue Issue
+	// Rules are the names of the rules that matched
+	Rules []string
+	// AddLabels, RemoveLabels and Assign only hold actual changes
+	AddLabels    []string
+	RemoveLabels []string
+	Assign       []string
+	Comments     []string
+	Close        bool
+}
+
+// Changes describes the plan, e.g. "+bug, -needs-info, assign @alice,
+// comment, close"
+func (p *TriagePlan) Changes() string {
+	var parts []string
+	for _, l := range p.AddLabels {
+		parts = append(parts, "+"+l)
+	}
+	for _, l := range p.RemoveLabels {
+		parts = append(parts, "-"+l)
+	}
+	for _, u := range p.Assign {
+		parts = append(parts, "assign @"+u)
+	}
+	if len(p.Comments) > 0 {
+		parts = append(parts, "comment")
+	}
+	if p.Close {
+		parts = append(parts, "close")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Plan runs the rules on each open issue, in order, and returns the plans
+// of the issues they would change. Each rule sees the labels and
+// assignees set by the rules before it, so a rule labeling by title can
+// feed one assigning by label. Labels and assignees already in place are
+// left alone, so triaging twice only changes what new issues and time
+// bring.
+func (rs *TriageRuleset) Plan(issues []Issue, now time.Time) []TriagePlan {
+	var plans []TriagePlan
+	for _, issue := range issues {
+		if issue.State != "opened" {
+			continue
+		}
+		plan := TriagePlan{Issue: issue}
+		// Rules match the issue as the previous ones left it
+		current := issue
+		current.Labels = append([]string{}, issue.Labels...)
+		current.Assignees = append([]User{}, issue.Assignees...)
+		days := strconv.Itoa(int(now.Sub(issue.UpdatedAt).Hours() / 24))
+		vars := strings.NewReplacer("{author}", issue.Author.Username, "{title}", issue.Title,
+			"{iid}", strconv.Itoa(issue.IID), "{days}", days, "{url}", issue.WebURL)
+
+		for i := range rs.Rules {
+			r := &rs.Rules[i]
+			if !r.Matches(&current, now) {
+				continue
+			}
+			plan.Rules = append(plan.Rules, r.Name)
+			for _, l := range r.Then.AddLabels {
+				if !ContainsString(current.Labels, l) {
+					current.Labels = append(current.Labels, l)
+				}
+			}
+			for _, l := range r.Then.RemoveLabels {
+				current.Labels = removeString(current.Labels, l)
+			}
+			for _, u := range r.Then.Assign {
+				if u = strings.TrimPrefix(u, "@"); !hasUser(current.Assignees, u) {
+					current.Assignees = append(current.Assignees, User{Username: u})
+				}
+			}
+			if r.Then.Comment != "" {
+				plan.Comments = append(plan.Comments, strings.TrimSpace(vars.Replace(r.Then.Comment))+"\n\n"+ruleMarker)
+			}
+			if r.Then.Close {
+				plan.Close = true
+				current.State = "closed"
+			}
+		}
+
+		for _, l := range current.Labels {
+			if !ContainsString(issue.Labels, l) {
+				plan.AddLabels = append(plan.AddLabels, l)
+			}
+		}
+		for _, l := range issue.Labels {
+			if !ContainsString(current.Labels, l) {
+				plan.RemoveLabels = append(plan.RemoveLabels, l)
+			}
+		}
+		for _, u := range current.Assignees {
+			if !hasUser(issue.Assignees, u.Username) {
+				plan.Assign = append(plan.Assign, u.Username)
+			}
+		}
+		if len(plan.AddLabels) > 0 || len(plan.RemoveLabels) > 0 || len(plan.Assign) > 0 || len(plan.Comments) > 0 || plan.Close {
+			plans = append(plans, plan)
+		}
+	}
+	return plans
+}
+
+// ApplyTriage carries out a plan: comments first, so they land before the
+// issue is closed, then one update for labels, assignees and state.
+// Assignees who are not project members are an error after the rest is
+// done.
+func (c *Client) ApplyTriage(projectPath string, plan *TriagePlan) error {
+	issue := &plan.Issue
+	for _, body := range plan.Comments {
+		if _, err := c.CreateIssueNote(projectPath, issue.IID, body); err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	req := &UpdateIssueRequest{}
+	if len(plan.AddLabels) > 0 || len(plan.RemoveLabels) > 0 {
+		labels := []string{}
+		for _, l := range issue.Labels {
+			if !ContainsString(plan.RemoveLabels, l) {
+				labels = append(labels, l)
+			}
+		}
+		labels = append(labels, plan.AddLabels...)
+		req.Labels = &labels
+	}
+	ids, err := c.addMembers(projectPath, issue.Assignees, plan.Assign)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if ids != nil {
+		req.AssigneeIDs = &ids
+	}
+	if plan.Close {
+		req.StateEvent = "close"
+	}
+	if req.Labels != nil || req.AssigneeIDs != nil || req.StateEvent != "" {
+		if _, err := c.UpdateIssue(projectPath, issue.IID, req); err != nil {
+			return err
+		}
+	}
+	return errors.Join(errs...)
+}
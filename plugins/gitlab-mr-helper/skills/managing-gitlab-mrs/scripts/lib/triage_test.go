@@ -0,0 +1,128 @@
+package lib_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+const testTriageRules = `rules:
+  - name: bugs
+    when: {title: "(?i)crash|panic"}
+    then: {add_labels: ["component::backend", bug]}
+  - name: backend owner
+    when: {label: "component::backend", unassigned: true}
+    then: {assign: ["@bob"]}
+  - name: stale
+    when: {stale_days: 90, no_label: pinned}
+    then:
+      remove_labels: [needs-info]
+      close: true
+      comment: Closing {title} after {days} days without activity.
+`
+
+func TestLoadTriageRules(t *testing.T) {
+	rs, err := lib.LoadTriageRules(writeRules(t, testTriageRules))
+	if err != nil {
+		t.Fatalf("LoadTriageRules: %v", err)
+	}
+	if len(rs.Rules) != 3 || rs.Rules[2].When.StaleDays != 90 || !rs.Rules[2].Then.Close {
+		t.Errorf("LoadTriageRules = %+v", rs.Rules)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "empty", content: "rules: []\n", wantErr: "no rules"},
+		{name: "no condition", content: "rules:\n  - name: x\n    when: {}\n    then: {close: true}\n", wantErr: "x: when needs"},
+		{name: "no action", content: "rules:\n  - name: x\n    when: {unassigned: true}\n    then: {}\n", wantErr: "x: then has no actions"},
+		{name: "bad title", content: "rules:\n  - when: {title: \"(\"}\n    then: {close: true}\n", wantErr: "rule 1: invalid title pattern"},
+		{name: "typo", content: "rules:\n  - when: {stale: 3}\n    then: {close: true}\n", wantErr: "stale"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := lib.LoadTriageRules(writeRules(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTriagePlan(t *testing.T) {
+	rs, err := lib.LoadTriageRules(writeRules(t, testTriageRules))
+	if err != nil {
+		t.Fatalf("LoadTriageRules: %v", err)
+	}
+	now := gitlabtest.FixtureTime
+	fresh, old := now.Add(-24*time.Hour), now.AddDate(0, 0, -100)
+	issues := []lib.Issue{
+		{IID: 1, Title: "Crash on start", State: "opened", UpdatedAt: fresh},
+		{IID: 2, Title: "Panic in parser", State: "opened", Labels: []string{"bug", "component::backend"}, Assignees: []lib.User{gitlabtest.Alice}, UpdatedAt: fresh},
+		{IID: 3, Title: "Dark mode", State: "opened", Labels: []string{"needs-info", "ui"}, UpdatedAt: old},
+		{IID: 4, Title: "Roadmap", State: "opened", Labels: []string{"pinned"}, UpdatedAt: old},
+		{IID: 5, Title: "Crash when closed", State: "closed", UpdatedAt: old},
+	}
+
+	plans := rs.Plan(issues, now)
+	if len(plans) != 2 {
+		t.Fatalf("plans = %+v", plans)
+	}
+
+	// The label added by title feeds the rule assigning by label
+	crash := plans[0]
+	if crash.Issue.IID != 1 || !reflect.DeepEqual(crash.Rules, []string{"bugs", "backend owner"}) ||
+		!reflect.DeepEqual(crash.AddLabels, []string{"component::backend", "bug"}) || !reflect.DeepEqual(crash.Assign, []string{"bob"}) {
+		t.Errorf("crash plan = %+v", crash)
+	}
+	if got := crash.Changes(); got != "+component::backend, +bug, assign @bob" {
+		t.Errorf("Changes() = %q", got)
+	}
+
+	stale := plans[1]
+	if stale.Issue.IID != 3 || !stale.Close || !reflect.DeepEqual(stale.RemoveLabels, []string{"needs-info"}) || len(stale.Comments) != 1 ||
+		!strings.HasPrefix(stale.Comments[0], "Closing Dark mode after 100 days without activity.") {
+		t.Errorf("stale plan = %+v", stale)
+	}
+}
+
+func TestApplyTriage(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+	for _, req := range []lib.CreateIssueRequest{
+		{Title: "Crash on start", Labels: []string{"needs-info"}},
+		{Title: "Dark mode", AssigneeIDs: []int{gitlabtest.Alice.ID}},
+	} {
+		if _, err := client.CreateIssue(gitlabtest.ProjectPath, &req); err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
+	}
+	issues, err := client.ListProjectIssues(gitlabtest.ProjectPath, &lib.IssueListOptions{State: "opened"})
+	if err != nil {
+		t.Fatalf("ListProjectIssues: %v", err)
+	}
+
+	plan := &lib.TriagePlan{Issue: issues[1], AddLabels: []string{"bug"}, RemoveLabels: []string{"needs-info"}, Assign: []string{"bob"}, Comments: []string{"Closing"}, Close: true}
+	if err := client.ApplyTriage(gitlabtest.ProjectPath, plan); err != nil {
+		t.Fatalf("ApplyTriage: %v", err)
+	}
+	p := srv.Project(gitlabtest.ProjectPath)
+	issue := p.Issues[0]
+	if issue.State != "closed" || !reflect.DeepEqual(issue.Labels, []string{"bug"}) || len(issue.Assignees) != 1 || len(p.IssueNotes[1]) != 1 {
+		t.Errorf("triaged issue = %+v, notes %+v", issue, p.IssueNotes[1])
+	}
+
+	// Assignments add to the current assignees; non-members are an error
+	// after the rest is applied
+	plan = &lib.TriagePlan{Issue: issues[0], AddLabels: []string{"ui"}, Assign: []string{"bob", "mallory"}}
+	wantExit(t, client.ApplyTriage(gitlabtest.ProjectPath, plan), lib.ExitNotFound)
+	if issue := p.Issues[1]; len(issue.Assignees) != 2 || !reflect.DeepEqual(issue.Labels, []string{"ui"}) {
+		t.Errorf("partially triaged issue = %+v", issue)
+	}
+}
@@ -0,0 +1,161 @@
+package lib
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// ANSI color codes used by UI
+const (
+	colorRed    = "31"
+	colorGreen  = "32"
+	colorYellow = "33"
+	colorPurple = "35"
+)
+
+// Glyphs of text output. SetASCII replaces them with plain ASCII for
+// terminals and log processors that mangle Unicode.
+var (
+	Arrow     = "→"
+	BackArrow = "←"
+	Bullet    = "•"
+	Ellipsis  = "…"
+	Dot       = "·"
+	Times     = "×"
+)
+
+// asciiOutput is set by SetASCII
+var asciiOutput bool
+
+// SetASCII switches text output to the ASCII profile, or back: the glyphs
+// above become "->", "<-", "*", "...", "|" and "x", markers become [ok],
+// [fail] and [warn], and --pretty shows states as [open], [merged] or
+// [closed] instead of emoji
+func SetASCII(ascii bool) {
+	asciiOutput = ascii
+	if ascii {
+		Arrow, BackArrow, Bullet, Ellipsis, Dot, Times = "->", "<-", "*", "...", "|", "x"
+	} else {
+		Arrow, BackArrow, Bullet, Ellipsis, Dot, Times = "→", "←", "•", "…", "·", "×"
+	}
+}
+
+// UI renders human-readable command output according to the --quiet,
+// --pretty and --no-color flags
+type UI struct {
+	Quiet   bool
+	Pretty  bool
+	NoColor bool
+}
+
+// RegisterUIFlags registers the output style flags on the default flag set.
+// Call it before flag.Parse.
+func RegisterUIFlags() *UI {
+	u := &UI{}
+	flag.BoolVar(&u.Quiet, "quiet", false, "Print only the essential identifier/URL of the result")
+	flag.BoolVar(&u.Pretty, "pretty", false, "Decorate output with emoji icons")
+	flag.BoolVar(&u.NoColor, "no-color", false, "Disable colored output (also honors NO_COLOR)")
+	return u
+}
+
+// Printf prints informational output, suppressed in quiet mode
+func (u *UI) Printf(format string, a ...interface{}) {
+	if !u.Quiet {
+		fmt.Printf(format, a...)
+	}
+}
+
+// Println prints informational output, suppressed in quiet mode
+func (u *UI) Println(a ...interface{}) {
+	if !u.Quiet {
+		fmt.Println(a...)
+	}
+}
+
+// Success renders a success marker followed by text
+func (u *UI) Success(text string) string {
+	return u.colorize(colorGreen, marker("✓", "[ok]")) + " " + text
+}
+
+// Failure renders a failure marker followed by text
+func (u *UI) Failure(text string) string {
+	return u.colorize(colorRed, marker("✗", "[fail]")) + " " + text
+}
+
+// Warning renders a warning marker followed by text
+func (u *UI) Warning(text string) string {
+	return u.colorize(colorYellow, marker("!", "[warn]")) + " " + text
+}
+
+// marker picks the ASCII form of a marker under SetASCII
+func marker(unicode, ascii string) string {
+	if asciiOutput {
+		return ascii
+	}
+	return unicode
+}
+
+// StateIcon returns the emoji for an MR state followed by a space in pretty
+// mode, and an empty string otherwise
+func (u *UI) StateIcon(state string) string {
+	if !u.Pretty {
+		return ""
+	}
+	if asciiOutput {
+		if state == "opened" {
+			return "[open] "
+		}
+		return "[" + state + "] "
+	}
+	switch state {
+	case "opened":
+		return "🟢 "
+	case "merged":
+		return "🟣 "
+	case "closed":
+		return "🔴 "
+	default:
+		return "⚪ "
+	}
+}
+
+// State renders an MR state name, colored by state
+func (u *UI) State(state string) string {
+	switch state {
+	case "opened":
+		return u.colorize(colorGreen, state)
+	case "merged":
+		return u.colorize(colorPurple, state)
+	case "closed":
+		return u.colorize(colorRed, state)
+	default:
+		return u.colorize(colorYellow, state)
+	}
+}
+
+func (u *UI) colorize(code, text string) string {
+	if !u.colorEnabled() {
+		return text
+	}
+	return "\033[" + code + "m" + text + "\033[0m"
+}
+
+// colorEnabled reports whether stdout should receive ANSI colors. Colors are
+// off when requested by flag or NO_COLOR (https://no-color.org), and when
+// stdout is not a terminal.
+func (u *UI) colorEnabled() bool {
+	if u.NoColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return IsTerminal(os.Stdout)
+}
+
+// IsTerminal reports whether f is a terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,33 @@
+package lib_test
+
+import (
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestASCIIProfile(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	ui := &lib.UI{Pretty: true}
+
+	if got := ui.Success("done") + " " + ui.StateIcon("merged") + lib.Arrow; got != "✓ done 🟣 →" {
+		t.Errorf("default profile = %q", got)
+	}
+
+	lib.SetASCII(true)
+	t.Cleanup(func() { lib.SetASCII(false) })
+	tests := []struct{ got, want string }{
+		{ui.Success("done"), "[ok] done"},
+		{ui.Failure("broken"), "[fail] broken"},
+		{ui.Warning("careful"), "[warn] careful"},
+		{ui.StateIcon("opened"), "[open] "},
+		{ui.StateIcon("merged"), "[merged] "},
+		{ui.StateIcon("locked"), "[locked] "},
+		{lib.Arrow + lib.Bullet + lib.Ellipsis, "->*..."},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("ASCII profile: got %q, want %q", tt.got, tt.want)
+		}
+	}
+}
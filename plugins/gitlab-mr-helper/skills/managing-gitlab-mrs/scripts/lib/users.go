@@ -0,0 +1,110 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// CreateUserRequest creates a regular user account for automation. The
+// account gets a random password and needs no email confirmation, so it
+// can only sign in with tokens.
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+}
+
+// FindUser looks a user up by username
+func (c *Client) FindUser(username string) (*User, error) {
+	endpoint := c.apiURL("/users")
+	query := url.Values{}
+	query.Set("username", username)
+	user, err := memoize(c, endpoint+"?"+query.Encode(), func() (User, error) {
+		users, err := getAll[User](c, endpoint, query, 1)
+		if err != nil {
+			return User{}, err
+		}
+		if len(users) == 0 {
+			return User{}, fmt.Errorf("%w: user %q", ErrNotFound, username)
+		}
+		return users[0], nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CreateUser creates a user account (administrators only)
+func (c *Client) CreateUser(req *CreateUserRequest) (*User, error) {
+	endpoint := c.apiURL("/users")
+	body := map[string]interface{}{
+		"username":              req.Username,
+		"name":                  req.Name,
+		"email":                 req.Email,
+		"force_random_password": true,
+		"skip_confirmation":     true,
+	}
+	var u User
+	if err := c.do("POST", endpoint, body, &u, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// CreateServiceAccount creates an instance service account (administrators
+// only, GitLab Premium). Service accounts are bots: they use no license
+// seat and cannot sign in. An empty username lets GitLab generate one.
+func (c *Client) CreateServiceAccount(username, name string) (*User, error) {
+	endpoint := c.apiURL("/service_accounts")
+	body := map[string]string{}
+	if username != "" {
+		body["username"] = username
+	}
+	if name != "" {
+		body["name"] = name
+	}
+	var u User
+	if err := c.do("POST", endpoint, body, &u, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// AddGroupMember gives a user a role in a group, changing the role of a
+// user who is already a member. The membership expires at expiresAt, or
+// never when it is zero.
+func (c *Client) AddGroupMember(group string, userID, accessLevel int, expiresAt Date) (*Member, error) {
+	endpoint := c.apiURL("/groups/%s/members", url.PathEscape(group))
+	body := map[string]interface{}{"user_id": userID, "access_level": accessLevel}
+	if !expiresAt.IsZero() {
+		body["expires_at"] = expiresAt
+	}
+
+	var m Member
+	err := c.do("POST", endpoint, body, &m, http.StatusCreated)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+		delete(body, "user_id")
+		err = c.do("PUT", fmt.Sprintf("%s/%d", endpoint, userID), body, &m, http.StatusOK)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// CreateUserToken creates a personal access token for another user
+// (administrators only), e.g. a bot account. req.AccessLevel is ignored.
+func (c *Client) CreateUserToken(userID int, req *CreateAccessTokenRequest) (*AccessToken, error) {
+	endpoint := c.apiURL("/users/%d/personal_access_tokens", userID)
+	body := map[string]interface{}{"name": req.Name, "scopes": req.Scopes, "expires_at": req.ExpiresAt}
+
+	var token AccessToken
+	if err := c.do("POST", endpoint, body, &token, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
@@ -0,0 +1,94 @@
+package lib_test
+
+import (
+	"testing"
+	"time"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestFindUser(t *testing.T) {
+	client := gitlabtest.NewServer(t).Client()
+
+	u, err := client.FindUser("bob")
+	if err != nil || u.ID != gitlabtest.Bob.ID {
+		t.Fatalf("FindUser(bob) = %+v, %v", u, err)
+	}
+	if _, err := client.FindUser("nobody"); lib.ExitCode(err) != lib.ExitNotFound {
+		t.Errorf("FindUser(nobody) error = %v, want not found", err)
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	u, err := client.CreateUser(&lib.CreateUserRequest{Username: "ci-bot", Name: "CI Bot", Email: "ci-bot@example.com"})
+	if err != nil || u.Username != "ci-bot" || u.ID == 0 {
+		t.Fatalf("CreateUser = %+v, %v", u, err)
+	}
+	if found, err := client.FindUser("ci-bot"); err != nil || found.ID != u.ID {
+		t.Errorf("FindUser(ci-bot) = %+v, %v", found, err)
+	}
+	_, err = client.CreateUser(&lib.CreateUserRequest{Username: "ci-bot", Name: "CI Bot", Email: "other@example.com"})
+	if lib.ExitCode(err) != lib.ExitConflict {
+		t.Errorf("CreateUser of a taken username error = %v, want conflict", err)
+	}
+
+	sa, err := client.CreateServiceAccount("", "Deploy Bot")
+	if err != nil || sa.Username == "" || sa.Name != "Deploy Bot" {
+		t.Fatalf("CreateServiceAccount = %+v, %v", sa, err)
+	}
+
+	srv.SetAdmin(false)
+	if _, err := client.CreateUser(&lib.CreateUserRequest{Username: "other-bot", Name: "Other", Email: "o@example.com"}); lib.ExitCode(err) != lib.ExitAuth {
+		t.Errorf("CreateUser without admin error = %v, want auth failure", err)
+	}
+}
+
+func TestAddGroupMember(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+	expires := lib.Date{}
+
+	m, err := client.AddGroupMember("group/sub", gitlabtest.Bob.ID, lib.AccessDeveloper, expires)
+	if err != nil || m.AccessLevel != lib.AccessDeveloper {
+		t.Fatalf("AddGroupMember = %+v, %v", m, err)
+	}
+	// Adding an existing member changes their role
+	if _, err := client.AddGroupMember("group/sub", gitlabtest.Bob.ID, lib.AccessMaintainer, expires); err != nil {
+		t.Fatalf("AddGroupMember of a member: %v", err)
+	}
+	members := srv.GroupMembers("group/sub")
+	if len(members) != 1 || members[0].ID != gitlabtest.Bob.ID || members[0].AccessLevel != lib.AccessMaintainer {
+		t.Errorf("group members = %+v", members)
+	}
+
+	if _, err := client.AddGroupMember("nogroup", gitlabtest.Bob.ID, lib.AccessDeveloper, expires); lib.ExitCode(err) != lib.ExitNotFound {
+		t.Errorf("AddGroupMember to an unknown group error = %v, want not found", err)
+	}
+}
+
+func TestCreateUserToken(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	expires, err := lib.ParseExpiry("2030-01-31", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &lib.CreateAccessTokenRequest{Name: "automation", Scopes: []string{"api"}, ExpiresAt: expires}
+	token, err := client.CreateUserToken(gitlabtest.Bob.ID, req)
+	if err != nil || token.Token == "" || token.Name != "automation" {
+		t.Fatalf("CreateUserToken = %+v, %v", token, err)
+	}
+	if tokens := srv.UserTokens(gitlabtest.Bob.ID); len(tokens) != 1 || tokens[0].ExpiresAt.String() != "2030-01-31" {
+		t.Errorf("tokens of bob = %+v", tokens)
+	}
+
+	srv.SetAdmin(false)
+	if _, err := client.CreateUserToken(gitlabtest.Bob.ID, req); lib.ExitCode(err) != lib.ExitAuth {
+		t.Errorf("CreateUserToken without admin error = %v, want auth failure", err)
+	}
+}
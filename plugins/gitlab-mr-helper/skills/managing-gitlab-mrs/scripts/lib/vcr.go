@@ -0,0 +1,210 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// VCR modes selected with GITLAB_VCR
+const (
+	VCRRecord = "record"
+	VCRReplay = "replay"
+)
+
+// Cassette is a recorded sequence of API interactions
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request/response pair. URLs are stored without
+// scheme and host so cassettes replay against any instance.
+type Interaction struct {
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+		Body   string `json:"body,omitempty"`
+	} `json:"request"`
+	Response struct {
+		Status  int               `json:"status"`
+		Headers map[string]string `json:"headers,omitempty"`
+		Body    string            `json:"body"`
+	} `json:"response"`
+}
+
+// recordedHeaders are the response headers kept in cassettes
+var recordedHeaders = []string{"Content-Type", "X-Next-Page", "X-Page", "X-Total", "X-Total-Pages", "Link", "Etag"}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// recordTransport performs real requests and appends each interaction to a
+// cassette file, rewriting it after every call so nothing is lost if the
+// command exits early
+type recordTransport struct {
+	next     http.RoundTripper
+	path     string
+	secrets  []string
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecordTransport returns a transport that records interactions to path.
+// Every occurrence of the given secrets (e.g. the token) is redacted, as are
+// the values of the request headers that debug traces redact.
+func NewRecordTransport(next http.RoundTripper, path string, secrets ...string) http.RoundTripper {
+	return &recordTransport{next: next, path: path, secrets: secrets}
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := drainBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := drainBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := append(headerSecrets(req.Header), t.secrets...)
+	var in Interaction
+	in.Request.Method = req.Method
+	in.Request.URL = sanitize(req.URL.RequestURI(), secrets)
+	in.Request.Body = sanitize(reqBody, secrets)
+	in.Response.Status = resp.StatusCode
+	in.Response.Body = sanitize(respBody, secrets)
+	for _, h := range recordedHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			if in.Response.Headers == nil {
+				in.Response.Headers = make(map[string]string)
+			}
+			in.Response.Headers[h] = sanitize(v, secrets)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cassette.Interactions = append(t.cassette.Interactions, in)
+	data, err := json.MarshalIndent(&t.cassette, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if err := os.WriteFile(t.path, data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return resp, nil
+}
+
+// headerSecrets returns the values of the redacted headers of a request,
+// with the credential of an Authorization header on its own too
+func headerSecrets(h http.Header) []string {
+	var secrets []string
+	for name := range redactedHeaders {
+		for _, v := range h.Values(name) {
+			secrets = append(secrets, v)
+			if _, cred, ok := strings.Cut(v, " "); ok && name == "Authorization" {
+				secrets = append(secrets, cred)
+			}
+		}
+	}
+	return secrets
+}
+
+func sanitize(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "[REDACTED]")
+		}
+	}
+	return s
+}
+
+// replayTransport answers requests from a cassette without touching the
+// network. Each interaction is used at most once, in recorded order.
+type replayTransport struct {
+	mu       sync.Mutex
+	path     string // loaded on first use
+	cassette *Cassette
+	used     []bool
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := drainBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	uri := req.URL.RequestURI()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.cassette == nil {
+		c, err := LoadCassette(t.path)
+		if err != nil {
+			return nil, err
+		}
+		t.cassette = c
+		t.used = make([]bool, len(c.Interactions))
+	}
+
+	for i, in := range t.cassette.Interactions {
+		if t.used[i] || in.Request.Method != req.Method || in.Request.URL != uri {
+			continue
+		}
+		if in.Request.Body != "" && in.Request.Body != reqBody {
+			continue
+		}
+		t.used[i] = true
+
+		header := make(http.Header)
+		for k, v := range in.Response.Headers {
+			header.Set(k, v)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+			StatusCode:    in.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("no recorded interaction for %s %s", req.Method, uri)
+}
+
+// drainBody reads and replaces a request/response body so it can be read again
+func drainBody(body *io.ReadCloser) (string, error) {
+	if *body == nil || *body == http.NoBody {
+		return "", nil
+	}
+	data, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to read body: %w", err)
+	}
+	*body = io.NopCloser(bytes.NewReader(data))
+	return string(data), nil
+}
@@ -0,0 +1,120 @@
+package lib_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestVCRRecordThenReplay(t *testing.T) {
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+
+	srv := gitlabtest.NewServer(t)
+	recorder := lib.NewClient(&lib.Config{URL: srv.URL, Token: gitlabtest.Token, VCRMode: lib.VCRRecord, VCRCassette: cassette})
+	if _, err := recorder.GetMR(gitlabtest.ProjectPath, 1); err != nil {
+		t.Fatalf("recording GetMR: %v", err)
+	}
+	if _, err := recorder.ListMRs(gitlabtest.NestedProjectPath, "opened", 0); err != nil {
+		t.Fatalf("recording ListMRs: %v", err)
+	}
+	srv.Close()
+
+	data, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatalf("reading cassette: %v", err)
+	}
+	if strings.Contains(string(data), gitlabtest.Token) {
+		t.Errorf("cassette contains the token")
+	}
+
+	// Replay against an unreachable host with no token
+	player := lib.NewClient(&lib.Config{URL: "http://gitlab.invalid", VCRMode: lib.VCRReplay, VCRCassette: cassette})
+	mr, err := player.GetMR(gitlabtest.ProjectPath, 1)
+	if err != nil {
+		t.Fatalf("replaying GetMR: %v", err)
+	}
+	if mr.Title != "Add login page" {
+		t.Errorf("replayed title = %q", mr.Title)
+	}
+	mrs, err := player.ListMRs(gitlabtest.NestedProjectPath, "opened", 0)
+	if err != nil {
+		t.Fatalf("replaying ListMRs: %v", err)
+	}
+	if len(mrs) != 1 {
+		t.Errorf("replayed %d MRs, want 1", len(mrs))
+	}
+
+	// Each interaction replays once
+	if _, err := player.GetMR(gitlabtest.ProjectPath, 1); err == nil {
+		t.Errorf("expected an error once the interaction is used up")
+	}
+}
+
+func TestVCRRedactsCredentialHeaders(t *testing.T) {
+	// A server echoing the credentials it was sent, as some error pages do
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"auth":%q,"sudo":%q,"job":%q}`, r.Header.Get("Authorization"), r.Header.Get("Sudo"), r.Header.Get("Job-Token"))
+	}))
+	defer srv.Close()
+
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+	client := &http.Client{Transport: lib.NewRecordTransport(http.DefaultTransport, cassette)}
+	req, err := http.NewRequest("GET", srv.URL+"/api/v4/user", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer oauth-secret")
+	req.Header.Set("Sudo", "bob")
+	req.Header.Set("Job-Token", "job-secret")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("recording: %v", err)
+	}
+	resp.Body.Close()
+
+	data, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatalf("reading cassette: %v", err)
+	}
+	for _, secret := range []string{"oauth-secret", "bob", "job-secret"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, data)
+		}
+	}
+}
+
+func TestReplayCheckedInCassette(t *testing.T) {
+	tests := []struct {
+		name      string
+		iid       int
+		wantTitle string
+		wantExit  int
+	}{
+		{name: "recorded MR", iid: 7, wantTitle: "Bump dependencies"},
+		{name: "recorded 404", iid: 8, wantExit: lib.ExitNotFound},
+	}
+
+	client := lib.NewClient(&lib.Config{URL: "https://gitlab.example.com", VCRMode: lib.VCRReplay, VCRCassette: "testdata/get_mr.cassette.json"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr, err := client.GetMR("group/project", tt.iid)
+			if tt.wantExit != 0 {
+				wantExit(t, err, tt.wantExit)
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetMR: %v", err)
+			}
+			if mr.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", mr.Title, tt.wantTitle)
+			}
+		})
+	}
+}
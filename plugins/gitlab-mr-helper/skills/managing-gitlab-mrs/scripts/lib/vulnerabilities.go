@@ -0,0 +1,302 @@
+package lib
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Vulnerability states and dismissal reasons, as accepted by the script
+// flags; GraphQL spells them in upper case
+const (
+	VulnerabilityDetected  = "detected"
+	VulnerabilityConfirmed = "confirmed"
+	VulnerabilityResolved  = "resolved"
+	VulnerabilityDismissed = "dismissed"
+)
+
+// DismissalReasons are the reasons a vulnerability can be dismissed for
+var DismissalReasons = []string{"acceptable_risk", "false_positive", "mitigating_control", "used_in_tests", "not_applicable"}
+
+// Vulnerability is a finding on the default branch that is tracked for
+// triage
+type Vulnerability struct {
+	ID              int
+	Title           string
+	State           string // detected, confirmed, resolved or dismissed
+	Severity        string // critical, high, medium, low, info or unknown
+	ReportType      string // sast, dependency_scanning, secret_detection, ...
+	Scanner         string
+	Identifier      string // e.g. a CVE
+	DismissalReason string
+	DetectedAt      time.Time
+	WebURL          string
+	Issues          []Issue // linked issues; only IID and WebURL are set
+}
+
+// VulnerabilityListOptions filters ListVulnerabilities. Values are lower
+// case, like the fields of Vulnerability.
+type VulnerabilityListOptions struct {
+	States      []string
+	Severities  []string
+	ReportTypes []string
+	Limit       int // 0 means no limit
+}
+
+// VulnerabilityFinding is a finding of a pipeline's security reports
+type VulnerabilityFinding struct {
+	UUID       string `json:"uuid"`
+	Name       string `json:"name"`
+	Severity   string `json:"severity"`
+	ReportType string `json:"report_type"`
+	Scanner    struct {
+		Name string `json:"name"`
+	} `json:"scanner"`
+	Identifiers []struct {
+		Name string `json:"name"`
+	} `json:"identifiers"`
+	Location struct {
+		File       string `json:"file"`
+		StartLine  int    `json:"start_line"`
+		Dependency struct {
+			Package struct {
+				Name string `json:"name"`
+			} `json:"package"`
+			Version string `json:"version"`
+		} `json:"dependency"`
+	} `json:"location"`
+}
+
+// Where describes the location of a finding, e.g. "app/login.rb:42" or
+// "lodash 4.17.20 in package-lock.json"
+func (f *VulnerabilityFinding) Where() string {
+	loc := f.Location
+	switch {
+	case loc.Dependency.Package.Name != "":
+		return fmt.Sprintf("%s %s in %s", loc.Dependency.Package.Name, loc.Dependency.Version, loc.File)
+	case loc.StartLine > 0:
+		return fmt.Sprintf("%s:%d", loc.File, loc.StartLine)
+	}
+	return loc.File
+}
+
+const vulnerabilitiesQuery = `query ProjectVulnerabilities($fullPath: ID!, $state: [VulnerabilityState!], $severity: [VulnerabilitySeverity!], $reportType: [VulnerabilityReportType!], $first: Int, $after: String) {
+  project(fullPath: $fullPath) {
+    vulnerabilities(state: $state, severity: $severity, reportType: $reportType, first: $first, after: $after) {
+      nodes {
+        id title state severity reportType detectedAt webUrl dismissalReason
+        scanner { name }
+        primaryIdentifier { name }
+        issueLinks { nodes { issue { iid webUrl } } }
+      }
+      pageInfo { hasNextPage endCursor }
+    }
+  }
+}`
+
+// vulnerabilityNode is a vulnerability as returned by GraphQL
+type vulnerabilityNode struct {
+	ID              string    `json:"id"`
+	Title           string    `json:"title"`
+	State           string    `json:"state"`
+	Severity        string    `json:"severity"`
+	ReportType      string    `json:"reportType"`
+	DetectedAt      time.Time `json:"detectedAt"`
+	WebURL          string    `json:"webUrl"`
+	DismissalReason string    `json:"dismissalReason"`
+	Scanner         *struct {
+		Name string `json:"name"`
+	} `json:"scanner"`
+	PrimaryIdentifier *struct {
+		Name string `json:"name"`
+	} `json:"primaryIdentifier"`
+	IssueLinks struct {
+		Nodes []struct {
+			Issue struct {
+				IID    string `json:"iid"`
+				WebURL string `json:"webUrl"`
+			} `json:"issue"`
+		} `json:"nodes"`
+	} `json:"issueLinks"`
+}
+
+func (n *vulnerabilityNode) vulnerability() (Vulnerability, error) {
+	id, err := ParseGlobalID(n.ID)
+	if err != nil {
+		return Vulnerability{}, err
+	}
+	v := Vulnerability{
+		ID:              id,
+		Title:           n.Title,
+		State:           strings.ToLower(n.State),
+		Severity:        strings.ToLower(n.Severity),
+		ReportType:      strings.ToLower(n.ReportType),
+		DismissalReason: strings.ToLower(n.DismissalReason),
+		DetectedAt:      n.DetectedAt,
+		WebURL:          n.WebURL,
+	}
+	if n.Scanner != nil {
+		v.Scanner = n.Scanner.Name
+	}
+	if n.PrimaryIdentifier != nil {
+		v.Identifier = n.PrimaryIdentifier.Name
+	}
+	for _, l := range n.IssueLinks.Nodes {
+		var iid int
+		fmt.Sscan(l.Issue.IID, &iid)
+		v.Issues = append(v.Issues, Issue{IID: iid, WebURL: l.Issue.WebURL})
+	}
+	return v, nil
+}
+
+// ListVulnerabilities lists the vulnerabilities of a project, most severe
+// first
+func (c *Client) ListVulnerabilities(projectPath string, opts *VulnerabilityListOptions) ([]Vulnerability, error) {
+	if opts == nil {
+		opts = &VulnerabilityListOptions{}
+	}
+	vars := map[string]interface{}{"fullPath": projectPath, "first": 100}
+	for name, values := range map[string][]string{"state": opts.States, "severity": opts.Severities, "reportType": opts.ReportTypes} {
+		if len(values) > 0 {
+			vars[name] = upper(values)
+		}
+	}
+
+	var all []Vulnerability
+	for {
+		var data struct {
+			Project *struct {
+				Vulnerabilities struct {
+					Nodes    []vulnerabilityNode `json:"nodes"`
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+				} `json:"vulnerabilities"`
+			} `json:"project"`
+		}
+		if err := c.GraphQL("ProjectVulnerabilities", vulnerabilitiesQuery, vars, &data); err != nil {
+			return nil, err
+		}
+		if data.Project == nil {
+			return nil, fmt.Errorf("%w: project %s", ErrNotFound, projectPath)
+		}
+		page := data.Project.Vulnerabilities
+		for i := range page.Nodes {
+			v, err := page.Nodes[i].vulnerability()
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, v)
+			if opts.Limit > 0 && len(all) == opts.Limit {
+				return all, nil
+			}
+		}
+		if !page.PageInfo.HasNextPage {
+			return all, nil
+		}
+		vars["after"] = page.PageInfo.EndCursor
+	}
+}
+
+// vulnerabilityMutations maps the states a vulnerability can be moved to
+// to the mutations doing it
+var vulnerabilityMutations = map[string]string{
+	VulnerabilityConfirmed: "vulnerabilityConfirm",
+	VulnerabilityResolved:  "vulnerabilityResolve",
+	VulnerabilityDismissed: "vulnerabilityDismiss",
+	VulnerabilityDetected:  "vulnerabilityRevertToDetected",
+}
+
+// SetVulnerabilityState confirms, resolves, dismisses or reverts a
+// vulnerability to detected. reason (one of DismissalReasons) only applies
+// to dismissals; comment is recorded with the change.
+func (c *Client) SetVulnerabilityState(id int, state, reason, comment string) (*Vulnerability, error) {
+	mutation, ok := vulnerabilityMutations[state]
+	if !ok {
+		return nil, fmt.Errorf("invalid vulnerability state %q", state)
+	}
+	input := map[string]interface{}{"id": GlobalID("Vulnerability", id)}
+	if comment != "" {
+		input["comment"] = comment
+	}
+	if reason != "" {
+		if state != VulnerabilityDismissed {
+			return nil, fmt.Errorf("a reason only applies to dismissals")
+		}
+		input["dismissalReason"] = strings.ToUpper(reason)
+	}
+	query := fmt.Sprintf(`mutation SetVulnerabilityState($input: %s!) {
+  result: %s(input: $input) {
+    vulnerability { id title state severity reportType detectedAt webUrl dismissalReason }
+    errors
+  }
+}`, strings.ToUpper(mutation[:1])+mutation[1:]+"Input", mutation)
+
+	var data struct {
+		Result struct {
+			Vulnerability *vulnerabilityNode `json:"vulnerability"`
+			Errors        []string           `json:"errors"`
+		} `json:"result"`
+	}
+	if err := c.GraphQL("SetVulnerabilityState", query, map[string]interface{}{"input": input}, &data); err != nil {
+		return nil, err
+	}
+	if err := mutationErrors(data.Result.Errors); err != nil {
+		return nil, err
+	}
+	if data.Result.Vulnerability == nil {
+		return nil, fmt.Errorf("%w: vulnerability %d", ErrNotFound, id)
+	}
+	v, err := data.Result.Vulnerability.vulnerability()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// LinkVulnerabilityIssue links an issue (by global ID) to vulnerabilities
+func (c *Client) LinkVulnerabilityIssue(issueID int, vulnerabilityIDs ...int) error {
+	ids := make([]string, len(vulnerabilityIDs))
+	for i, id := range vulnerabilityIDs {
+		ids[i] = GlobalID("Vulnerability", id)
+	}
+	const query = `mutation LinkVulnerabilityIssue($input: VulnerabilityIssueLinkCreateInput!) {
+  vulnerabilityIssueLinkCreate(input: $input) { errors }
+}`
+	var data struct {
+		Result struct {
+			Errors []string `json:"errors"`
+		} `json:"vulnerabilityIssueLinkCreate"`
+	}
+	input := map[string]interface{}{"issueId": GlobalID("Issue", issueID), "vulnerabilityIds": ids}
+	if err := c.GraphQL("LinkVulnerabilityIssue", query, map[string]interface{}{"input": input}, &data); err != nil {
+		return err
+	}
+	return mutationErrors(data.Result.Errors)
+}
+
+// ListPipelineFindings lists the security findings reported by a pipeline,
+// including those not yet on the default branch
+func (c *Client) ListPipelineFindings(projectPath string, pipelineID int, severities, reportTypes []string) ([]VulnerabilityFinding, error) {
+	endpoint := c.apiURL("/projects/%s/vulnerability_findings", url.PathEscape(projectPath))
+	q := url.Values{}
+	q.Set("pipeline_id", fmt.Sprint(pipelineID))
+	for _, s := range severities {
+		q.Add("severity[]", s)
+	}
+	for _, t := range reportTypes {
+		q.Add("report_type[]", t)
+	}
+	return getAll[VulnerabilityFinding](c, endpoint, q, 0)
+}
+
+// upper returns values in upper case, as GraphQL enums
+func upper(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strings.ToUpper(v)
+	}
+	return out
+}
@@ -0,0 +1,101 @@
+package lib_test
+
+import (
+	"errors"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+	"gitlab-mr-helper/lib/gitlabtest"
+)
+
+func TestListVulnerabilities(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	tests := []struct {
+		name string
+		opts *lib.VulnerabilityListOptions
+		want []int
+	}{
+		{name: "all", want: []int{72, 71, 73}},
+		{name: "open", opts: &lib.VulnerabilityListOptions{States: []string{"detected", "confirmed"}}, want: []int{72, 71}},
+		{name: "by severity and type", opts: &lib.VulnerabilityListOptions{Severities: []string{"high", "medium"}, ReportTypes: []string{"secret_detection"}}, want: []int{73}},
+		{name: "limit", opts: &lib.VulnerabilityListOptions{Limit: 1}, want: []int{72}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vulns, err := client.ListVulnerabilities(gitlabtest.ProjectPath, tt.opts)
+			if err != nil {
+				t.Fatalf("ListVulnerabilities: %v", err)
+			}
+			var ids []int
+			for _, v := range vulns {
+				ids = append(ids, v.ID)
+			}
+			if !equalInts(ids, tt.want) {
+				t.Errorf("ListVulnerabilities = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	vulns, _ := client.ListVulnerabilities(gitlabtest.ProjectPath, &lib.VulnerabilityListOptions{States: []string{"dismissed"}})
+	if v := vulns[0]; v.DismissalReason != "used_in_tests" || v.Severity != "medium" || v.Scanner != "Gitleaks" {
+		t.Errorf("dismissed vulnerability = %+v", v)
+	}
+	_, err := client.ListVulnerabilities("no/such", nil)
+	wantExit(t, err, lib.ExitNotFound)
+}
+
+func TestSetVulnerabilityState(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	v, err := client.SetVulnerabilityState(71, lib.VulnerabilityDismissed, "false_positive", "not reachable")
+	if err != nil || v.State != lib.VulnerabilityDismissed || v.DismissalReason != "false_positive" {
+		t.Fatalf("SetVulnerabilityState = %+v, %v", v, err)
+	}
+	_, err = client.SetVulnerabilityState(71, lib.VulnerabilityDismissed, "", "")
+	var gqlErr *lib.GraphQLError
+	if !errors.As(err, &gqlErr) {
+		t.Errorf("dismissing twice: error = %v, want a GraphQL error", err)
+	}
+	if _, err := client.SetVulnerabilityState(72, lib.VulnerabilityConfirmed, "false_positive", ""); err == nil {
+		t.Error("SetVulnerabilityState accepted a reason for a confirmation")
+	}
+	_, err = client.SetVulnerabilityState(999, lib.VulnerabilityResolved, "", "")
+	wantExit(t, err, lib.ExitNotFound)
+}
+
+func TestLinkVulnerabilityIssue(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := srv.Client()
+
+	issue, err := client.CreateIssue(gitlabtest.ProjectPath, &lib.CreateIssueRequest{Title: "Fix SQL injection"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.LinkVulnerabilityIssue(issue.ID, 72); err != nil {
+		t.Fatalf("LinkVulnerabilityIssue: %v", err)
+	}
+	vulns, _ := client.ListVulnerabilities(gitlabtest.ProjectPath, &lib.VulnerabilityListOptions{Limit: 1})
+	if len(vulns[0].Issues) != 1 || vulns[0].Issues[0].IID != issue.IID {
+		t.Errorf("linked issues = %+v", vulns[0].Issues)
+	}
+	if err := client.LinkVulnerabilityIssue(issue.ID, 72); err == nil {
+		t.Error("linking twice succeeded")
+	}
+}
+
+func TestListPipelineFindings(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	findings, err := srv.Client().ListPipelineFindings(gitlabtest.ProjectPath, 902, []string{"high"}, nil)
+	if err != nil || len(findings) != 2 {
+		t.Fatalf("ListPipelineFindings = %+v, %v", findings, err)
+	}
+	if got := findings[0].Where(); got != "app/login.js:42" {
+		t.Errorf("Where() = %q", got)
+	}
+	if got := findings[1].Where(); got != "lodash 4.17.20 in package-lock.json" {
+		t.Errorf("Where() = %q", got)
+	}
+}
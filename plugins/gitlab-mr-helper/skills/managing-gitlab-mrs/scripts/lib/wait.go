@@ -0,0 +1,70 @@
+package lib
+
+import (
+	"fmt"
+	"time"
+)
+
+// PollBackoff is the wait a poll check returns when nothing is expected to
+// change soon: the poll waits twice as long as the last time
+const PollBackoff time.Duration = -1
+
+// Poller waits for something by checking on it repeatedly. The check says
+// how long to wait before the next one, within Interval and MaxInterval:
+// long while the awaited thing has far to go, short when it is about to
+// finish, so long waits cost few requests without noticing the end late.
+type Poller struct {
+	// Interval is the shortest wait between checks, and MaxInterval the
+	// longest (Interval when shorter)
+	Interval    time.Duration
+	MaxInterval time.Duration
+	// Timeout is how long to wait in all; zero waits forever
+	Timeout time.Duration
+}
+
+// Poll calls check until it reports done or returns an error. next is how
+// long to wait before checking again: PollBackoff doubles the last wait,
+// and other waits are kept within the bounds, so zero checks again after
+// Interval. No wait passes the deadline: the last check is made when it
+// comes, and Poll then returns an error wrapping ErrTimeout.
+func (p Poller) Poll(check func() (done bool, next time.Duration, err error)) error {
+	var deadline time.Time
+	if p.Timeout > 0 {
+		deadline = time.Now().Add(p.Timeout)
+	}
+	longest := max(p.MaxInterval, p.Interval)
+	var wait time.Duration
+	for {
+		done, next, err := check()
+		if err != nil || done {
+			return err
+		}
+		switch {
+		case next == PollBackoff && wait > 0:
+			wait = min(2*wait, longest)
+		case next == PollBackoff:
+			wait = p.Interval
+		default:
+			wait = min(max(next, p.Interval), longest)
+		}
+		sleep := wait
+		if !deadline.IsZero() {
+			left := time.Until(deadline)
+			if left <= 0 {
+				return fmt.Errorf("%w after %s", ErrTimeout, p.Timeout)
+			}
+			sleep = min(sleep, left)
+		}
+		time.Sleep(sleep)
+	}
+}
+
+// Poll calls check every interval until it reports done or returns an error.
+// It gives up after timeout with an error wrapping ErrTimeout; a zero
+// timeout waits forever.
+func Poll(interval, timeout time.Duration, check func() (done bool, err error)) error {
+	return Poller{Interval: interval, Timeout: timeout}.Poll(func() (bool, time.Duration, error) {
+		done, err := check()
+		return done, 0, err
+	})
+}
@@ -0,0 +1,118 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WatchState remembers which events watch_events.go has already emitted
+type WatchState struct {
+	Since time.Time            `json:"since"` // events older than this are ignored
+	Seen  map[string]time.Time `json:"seen"`  // dedup key → first seen
+	// Polled is when the last successful poll started
+	Polled time.Time `json:"polled,omitempty"`
+	// Responses makes repeated listings conditional requests
+	Responses *ETagCache `json:"responses,omitempty"`
+}
+
+// pollOverlap is how far before the last poll UpdatedSince reaches, for
+// clock skew and updates committed while it ran; the seen keys drop what
+// is listed twice
+const pollOverlap = time.Minute
+
+// WatchHorizon is how long seen keys are kept. Anything older is skipped:
+// once its key is pruned it could not be told apart from a new event.
+const WatchHorizon = 7 * 24 * time.Hour
+
+// WatchStatePath returns the default state file of a watched project
+func WatchStatePath(baseURL, projectPath string) (string, error) {
+	dir, err := cacheDir(baseURL)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "watch", filepath.FromSlash(projectPath)+".json"), nil
+}
+
+// LoadWatchState reads a state file; a missing file yields a fresh state
+// starting now
+func LoadWatchState(path string) (*WatchState, error) {
+	state := &WatchState{Since: time.Now().UTC(), Seen: make(map[string]time.Time), Responses: &ETagCache{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watch state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid watch state %s: %w", path, err)
+	}
+	if state.Seen == nil {
+		state.Seen = make(map[string]time.Time)
+	}
+	if state.Responses == nil {
+		state.Responses = &ETagCache{}
+	}
+	return state, nil
+}
+
+// Save writes the state file, creating its directory
+func (s *WatchState) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode watch state: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write watch state: %w", err)
+	}
+	return nil
+}
+
+// Mark records key and reports whether it was new
+func (s *WatchState) Mark(key string, now time.Time) bool {
+	if _, ok := s.Seen[key]; ok {
+		return false
+	}
+	s.Seen[key] = now
+	return true
+}
+
+// Fresh reports whether the item of key, created or updated at t, is new
+// as of now, and records it. Items from before Since or older than
+// WatchHorizon are never new.
+func (s *WatchState) Fresh(key string, t, now time.Time) bool {
+	if t.Before(s.Since) || t.Before(now.Add(-WatchHorizon)) {
+		return false
+	}
+	return s.Mark(key, now)
+}
+
+// UpdatedSince returns the time from which to list the objects changed
+// since the last poll, or since Since before the first one, but not before
+// horizon
+func (s *WatchState) UpdatedSince(horizon time.Time) time.Time {
+	since := s.Since
+	if !s.Polled.IsZero() {
+		since = s.Polled.Add(-pollOverlap)
+	}
+	if since.Before(horizon) {
+		return horizon
+	}
+	return since
+}
+
+// Prune forgets keys first seen before cutoff
+func (s *WatchState) Prune(cutoff time.Time) {
+	for k, t := range s.Seen {
+		if t.Before(cutoff) {
+			delete(s.Seen, k)
+		}
+	}
+}
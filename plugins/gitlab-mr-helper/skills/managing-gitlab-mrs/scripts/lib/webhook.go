@@ -0,0 +1,228 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// WebhookEvent is a GitLab webhook payload normalized to the fields scripts
+// and hooks care about
+type WebhookEvent struct {
+	Kind       string    `json:"kind"`             // merge_request, pipeline, note
+	Action     string    `json:"action,omitempty"` // MR action (open, update, merge, ...), pipeline status, or "comment"
+	Project    string    `json:"project"`
+	MRIID      int       `json:"mr_iid,omitempty"`
+	PipelineID int       `json:"pipeline_id,omitempty"`
+	Title      string    `json:"title,omitempty"`
+	Ref        string    `json:"ref,omitempty"`
+	SHA        string    `json:"sha,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	Author     string    `json:"author,omitempty"`
+	Body       string    `json:"body,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	ReceivedAt time.Time `json:"received_at"`
+	// AddedLabels and RemovedLabels are the label changes of an MR update
+	AddedLabels   []string `json:"added_labels,omitempty"`
+	RemovedLabels []string `json:"removed_labels,omitempty"`
+}
+
+// webhookPayload covers the subset of the merge request, pipeline and note
+// hook payloads that WebhookEvent is built from
+type webhookPayload struct {
+	ObjectKind string `json:"object_kind"`
+	User       struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+		WebURL            string `json:"web_url"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		ID           int    `json:"id"`
+		IID          int    `json:"iid"`
+		Title        string `json:"title"`
+		Action       string `json:"action"`
+		State        string `json:"state"`
+		Status       string `json:"status"`
+		Ref          string `json:"ref"`
+		SHA          string `json:"sha"`
+		SourceBranch string `json:"source_branch"`
+		URL          string `json:"url"`
+		Note         string `json:"note"`
+		NoteableType string `json:"noteable_type"`
+		LastCommit   struct {
+			ID string `json:"id"`
+		} `json:"last_commit"`
+	} `json:"object_attributes"`
+	Changes struct {
+		Labels *struct {
+			Previous []webhookLabel `json:"previous"`
+			Current  []webhookLabel `json:"current"`
+		} `json:"labels"`
+	} `json:"changes"`
+	MergeRequest *struct {
+		IID   int    `json:"iid"`
+		Title string `json:"title"`
+		URL   string `json:"url"`
+	} `json:"merge_request"`
+}
+
+type webhookLabel struct {
+	Title string `json:"title"`
+}
+
+// ParseWebhook normalizes a webhook body. Unsupported kinds (push, issue,
+// ...) return a nil event and no error.
+func ParseWebhook(body []byte) (*WebhookEvent, error) {
+	var p webhookPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+
+	attrs := p.ObjectAttributes
+	ev := &WebhookEvent{
+		Kind:       p.ObjectKind,
+		Project:    p.Project.PathWithNamespace,
+		Author:     p.User.Username,
+		ReceivedAt: time.Now().UTC(),
+	}
+
+	switch p.ObjectKind {
+	case "merge_request":
+		ev.Action = attrs.Action
+		ev.MRIID = attrs.IID
+		ev.Title = attrs.Title
+		ev.Ref = attrs.SourceBranch
+		ev.SHA = attrs.LastCommit.ID
+		ev.Status = attrs.State
+		ev.URL = attrs.URL
+		if labels := p.Changes.Labels; labels != nil {
+			ev.AddedLabels = labelDiff(labels.Current, labels.Previous)
+			ev.RemovedLabels = labelDiff(labels.Previous, labels.Current)
+		}
+	case "pipeline":
+		ev.Action = attrs.Status
+		ev.PipelineID = attrs.ID
+		ev.Ref = attrs.Ref
+		ev.SHA = attrs.SHA
+		ev.Status = attrs.Status
+		ev.URL = attrs.URL
+		if ev.URL == "" && p.Project.WebURL != "" {
+			ev.URL = fmt.Sprintf("%s/-/pipelines/%d", p.Project.WebURL, attrs.ID)
+		}
+		if p.MergeRequest != nil {
+			ev.MRIID = p.MergeRequest.IID
+			ev.Title = p.MergeRequest.Title
+		}
+	case "note":
+		if attrs.NoteableType != "MergeRequest" || p.MergeRequest == nil {
+			return nil, nil
+		}
+		ev.Action = "comment"
+		ev.MRIID = p.MergeRequest.IID
+		ev.Title = p.MergeRequest.Title
+		ev.Body = attrs.Note
+		ev.URL = attrs.URL
+	default:
+		return nil, nil
+	}
+	return ev, nil
+}
+
+// labelDiff returns the titles of the labels in a that are not in b
+func labelDiff(a, b []webhookLabel) []string {
+	var out []string
+	for _, l := range a {
+		found := false
+		for _, other := range b {
+			if other.Title == l.Title {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, l.Title)
+		}
+	}
+	return out
+}
+
+// WebhookHandler verifies the X-Gitlab-Token header against secret (when
+// set), normalizes the payload and passes supported events to handle.
+// Handling must be quick: GitLab times out hooks after a few seconds.
+func WebhookHandler(secret string, handle func(*WebhookEvent)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if secret != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Gitlab-Token")), []byte(secret)) != 1 {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 10<<20))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		ev, err := ParseWebhook(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if ev == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		handle(ev)
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// HooksFor returns the configured commands for an event: the "kind:action"
+// hook first, then the "kind" hook
+func (s *ServeSettings) HooksFor(ev *WebhookEvent) []string {
+	var commands []string
+	if cmd := s.Hooks[ev.Kind+":"+ev.Action]; cmd != "" {
+		commands = append(commands, cmd)
+	}
+	if cmd := s.Hooks[ev.Kind]; cmd != "" {
+		commands = append(commands, cmd)
+	}
+	return commands
+}
+
+// RunEventHook runs a hook command through sh with the event as JSON on stdin
+// and its main fields in GITLAB_EVENT_* environment variables. Hook output
+// goes to stderr so stdout stays a clean event stream.
+func RunEventHook(command string, ev *WebhookEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"GITLAB_EVENT_KIND="+ev.Kind,
+		"GITLAB_EVENT_ACTION="+ev.Action,
+		"GITLAB_EVENT_PROJECT="+ev.Project,
+		"GITLAB_EVENT_MR_IID="+strconv.Itoa(ev.MRIID),
+		"GITLAB_EVENT_URL="+ev.URL,
+	)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("hook %q failed: %w", command, err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package lib_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+const (
+	mrHookBody = `{"object_kind":"merge_request","user":{"username":"bob"},
+		"project":{"path_with_namespace":"group/project","web_url":"https://gitlab.example.com/group/project"},
+		"object_attributes":{"iid":7,"title":"Add login","action":"open","state":"opened","source_branch":"feature/login",
+			"url":"https://gitlab.example.com/group/project/-/merge_requests/7","last_commit":{"id":"abc123"}}}`
+	pipelineHookBody = `{"object_kind":"pipeline","user":{"username":"alice"},
+		"project":{"path_with_namespace":"group/project","web_url":"https://gitlab.example.com/group/project"},
+		"object_attributes":{"id":901,"ref":"main","sha":"bbb222","status":"failed"},
+		"merge_request":{"iid":7,"title":"Add login"}}`
+	noteHookBody = `{"object_kind":"note","user":{"username":"bob"},
+		"project":{"path_with_namespace":"group/project"},
+		"object_attributes":{"note":"LGTM","noteable_type":"MergeRequest","url":"https://gitlab.example.com/n/1"},
+		"merge_request":{"iid":7,"title":"Add login"}}`
+	labelHookBody = `{"object_kind":"merge_request","user":{"username":"alice"},
+		"project":{"path_with_namespace":"group/project"},
+		"object_attributes":{"iid":7,"title":"Add login","action":"update","state":"opened","source_branch":"feature/login"},
+		"changes":{"labels":{"previous":[{"title":"frontend"},{"title":"wip"}],"current":[{"title":"frontend"},{"title":"needs-review"}]}}}`
+	pushHookBody = `{"object_kind":"push","project":{"path_with_namespace":"group/project"}}`
+)
+
+func TestParseWebhook(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want *lib.WebhookEvent // ReceivedAt is ignored
+	}{
+		{
+			name: "merge request",
+			body: mrHookBody,
+			want: &lib.WebhookEvent{Kind: "merge_request", Action: "open", Project: "group/project", MRIID: 7, Title: "Add login",
+				Ref: "feature/login", SHA: "abc123", Status: "opened", Author: "bob", URL: "https://gitlab.example.com/group/project/-/merge_requests/7"},
+		},
+		{
+			name: "pipeline",
+			body: pipelineHookBody,
+			want: &lib.WebhookEvent{Kind: "pipeline", Action: "failed", Project: "group/project", MRIID: 7, PipelineID: 901, Title: "Add login",
+				Ref: "main", SHA: "bbb222", Status: "failed", Author: "alice", URL: "https://gitlab.example.com/group/project/-/pipelines/901"},
+		},
+		{
+			name: "note",
+			body: noteHookBody,
+			want: &lib.WebhookEvent{Kind: "note", Action: "comment", Project: "group/project", MRIID: 7, Title: "Add login",
+				Author: "bob", Body: "LGTM", URL: "https://gitlab.example.com/n/1"},
+		},
+		{
+			name: "label change",
+			body: labelHookBody,
+			want: &lib.WebhookEvent{Kind: "merge_request", Action: "update", Project: "group/project", MRIID: 7, Title: "Add login",
+				Ref: "feature/login", Status: "opened", Author: "alice", AddedLabels: []string{"needs-review"}, RemovedLabels: []string{"wip"}},
+		},
+		{name: "unsupported kind", body: pushHookBody},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lib.ParseWebhook([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("ParseWebhook: %v", err)
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("got %+v, want nil", got)
+				}
+				return
+			}
+			got.ReceivedAt = tt.want.ReceivedAt
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		token      string
+		body       string
+		wantStatus int
+		wantEvents int
+	}{
+		{name: "accepted", method: "POST", token: "s3cret", body: mrHookBody, wantStatus: http.StatusAccepted, wantEvents: 1},
+		{name: "wrong token", method: "POST", token: "nope", body: mrHookBody, wantStatus: http.StatusUnauthorized},
+		{name: "ignored kind", method: "POST", token: "s3cret", body: pushHookBody, wantStatus: http.StatusNoContent},
+		{name: "bad payload", method: "POST", token: "s3cret", body: "{", wantStatus: http.StatusBadRequest},
+		{name: "GET", method: "GET", token: "s3cret", wantStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []*lib.WebhookEvent
+			h := lib.WebhookHandler("s3cret", func(ev *lib.WebhookEvent) { events = append(events, ev) })
+
+			req := httptest.NewRequest(tt.method, "/webhook", strings.NewReader(tt.body))
+			req.Header.Set("X-Gitlab-Token", tt.token)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus || len(events) != tt.wantEvents {
+				t.Errorf("got status %d with %d events, want %d with %d", rec.Code, len(events), tt.wantStatus, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestRunEventHook(t *testing.T) {
+	out := t.TempDir() + "/event"
+	ev := &lib.WebhookEvent{Kind: "pipeline", Action: "failed", Project: "group/project", MRIID: 7}
+
+	s := &lib.ServeSettings{Hooks: map[string]string{
+		"pipeline:failed": `cat > "` + out + `"; test "$GITLAB_EVENT_MR_IID" = 7`,
+		"pipeline":        "true",
+		"merge_request":   "false",
+	}}
+	hooks := s.HooksFor(ev)
+	if len(hooks) != 2 || hooks[1] != "true" {
+		t.Fatalf("HooksFor = %q", hooks)
+	}
+	for _, h := range hooks {
+		if err := lib.RunEventHook(h, ev); err != nil {
+			t.Fatalf("RunEventHook: %v", err)
+		}
+	}
+	data, err := os.ReadFile(out)
+	if err != nil || !strings.Contains(string(data), `"action":"failed"`) {
+		t.Errorf("hook stdin = %q, %v", data, err)
+	}
+
+	if err := lib.RunEventHook("false", ev); err == nil {
+		t.Error("expected an error from a failing hook")
+	}
+}
@@ -0,0 +1,436 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DecodeYAML decodes the subset of YAML used by rule and membership files
+// into v, through the JSON decoder so v is tagged like the settings structs.
+// Supported: block mappings and sequences, [a, b] and {k: v} flow
+// collections, plain, 'single' and "double" quoted scalars, | and > block
+// scalars and # comments. Anchors, aliases, tags and multiple documents
+// are not. Plain scalars true, false, null and numbers are typed, so quote
+// strings that look like them. Unknown fields are an error, which catches
+// typos.
+func DecodeYAML(data []byte, v interface{}) error {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if content := strings.TrimLeft(raw, " \t"); content != "" && strings.Contains(raw[:len(raw)-len(content)], "\t") {
+			return fmt.Errorf("yaml line %d: tabs are not allowed for indentation", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{num: i + 1, raw: raw})
+	}
+
+	var value interface{}
+	if p.next() {
+		l := p.lines[p.pos]
+		if l.indent != 0 {
+			return fmt.Errorf("yaml line %d: unexpected indentation", l.num)
+		}
+		var err error
+		if value, err = p.parseNode(0); err != nil {
+			return err
+		}
+		if p.next() {
+			return fmt.Errorf("yaml line %d: unexpected indentation", p.lines[p.pos].num)
+		}
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("yaml: %w", err)
+	}
+	return nil
+}
+
+type yamlLine struct {
+	num    int
+	raw    string
+	indent int
+	text   string // without indentation and comment
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// next skips blank and comment lines and document markers, and reports
+// whether a content line is left
+func (p *yamlParser) next() bool {
+	for ; p.pos < len(p.lines); p.pos++ {
+		l := &p.lines[p.pos]
+		trimmed := strings.TrimLeft(l.raw, " ")
+		l.indent = len(l.raw) - len(trimmed)
+		l.text = strings.TrimSpace(stripYAMLComment(trimmed))
+		if l.text != "" && l.text != "---" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNode parses the sequence or mapping starting at the current line,
+// whose indentation is indent
+func (p *yamlParser) parseNode(indent int) (interface{}, error) {
+	l := p.lines[p.pos]
+	if l.text == "-" || strings.HasPrefix(l.text, "- ") {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func (p *yamlParser) parseSequence(indent int) (interface{}, error) {
+	out := []interface{}{}
+	for p.next() {
+		l := &p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, fmt.Errorf("yaml line %d: unexpected indentation", l.num)
+		}
+		if l.text != "-" && !strings.HasPrefix(l.text, "- ") {
+			break
+		}
+
+		rest := strings.TrimLeft(l.text[1:], " ")
+		switch {
+		case rest == "":
+			p.pos++
+			value, err := p.parseChild(indent)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, value)
+		case isYAMLMappingEntry(rest) || rest == "-" || strings.HasPrefix(rest, "- "):
+			// "- key: value" starts a mapping indented to the key, "- - a"
+			// a nested sequence
+			l.indent += len(l.text) - len(rest)
+			l.raw, l.text = strings.Repeat(" ", l.indent)+rest, rest
+			value, err := p.parseNode(l.indent)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, value)
+		default:
+			value, err := p.parseValue(rest, indent)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, value)
+		}
+	}
+	return out, nil
+}
+
+func (p *yamlParser) parseMapping(indent int) (interface{}, error) {
+	out := map[string]interface{}{}
+	for p.next() {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, fmt.Errorf("yaml line %d: unexpected indentation", l.num)
+		}
+		key, rest, ok := cutYAMLMappingEntry(l.text)
+		if !ok {
+			return nil, fmt.Errorf("yaml line %d: expected \"key: value\"", l.num)
+		}
+		if _, dup := out[key]; dup {
+			return nil, fmt.Errorf("yaml line %d: duplicate key %q", l.num, key)
+		}
+
+		var value interface{}
+		var err error
+		if rest == "" {
+			p.pos++
+			value, err = p.parseChild(indent)
+		} else {
+			value, err = p.parseValue(rest, indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		out[key] = value
+	}
+	return out, nil
+}
+
+// parseChild parses the node nested under a "key:" or "-" line, which is
+// more indented than its parent (a sequence may also sit at the same
+// indentation as its key). A missing node is null.
+func (p *yamlParser) parseChild(parent int) (interface{}, error) {
+	if !p.next() {
+		return nil, nil
+	}
+	l := p.lines[p.pos]
+	if l.indent > parent || (l.indent == parent && (l.text == "-" || strings.HasPrefix(l.text, "- "))) {
+		return p.parseNode(l.indent)
+	}
+	return nil, nil
+}
+
+// parseValue parses the inline value of a line and moves past it, reading
+// the following lines for a block scalar
+func (p *yamlParser) parseValue(text string, indent int) (interface{}, error) {
+	l := p.lines[p.pos]
+	p.pos++
+	if text[0] == '|' || text[0] == '>' {
+		switch text[1:] {
+		case "", "-", "+":
+		default:
+			return nil, fmt.Errorf("yaml line %d: unsupported block scalar header %q", l.num, text)
+		}
+		return p.blockScalar(text[0] == '>', text[1:], indent), nil
+	}
+	value, err := parseYAMLScalar(text)
+	if err != nil {
+		return nil, fmt.Errorf("yaml line %d: %w", l.num, err)
+	}
+	return value, nil
+}
+
+// blockScalar reads the lines of a | (literal) or > (folded) block scalar
+// indented deeper than parent. chomp is "" (single final newline), "-"
+// (none) or "+" (keep trailing blank lines).
+func (p *yamlParser) blockScalar(folded bool, chomp string, parent int) string {
+	var lines []string
+	indent := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		raw := p.lines[p.pos].raw
+		trimmed := strings.TrimLeft(raw, " ")
+		if trimmed == "" {
+			lines = append(lines, "")
+			continue
+		}
+		n := len(raw) - len(trimmed)
+		if indent < 0 {
+			indent = n
+		}
+		if n <= parent || n < indent {
+			break
+		}
+		lines = append(lines, raw[indent:])
+	}
+
+	// Trailing blank lines belong to the scalar only with "+"
+	end := len(lines)
+	for end > 0 && lines[end-1] == "" {
+		end--
+	}
+	trailing := len(lines) - end
+	lines = lines[:end]
+	if len(lines) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	for i, line := range lines {
+		// Folding joins lines with a space; a blank line stands for one
+		// newline, and more indented lines are kept as they are
+		switch {
+		case i == 0:
+		case !folded || strings.HasPrefix(line, " ") || strings.HasPrefix(lines[i-1], " "):
+			sb.WriteString("\n")
+		case line == "":
+			sb.WriteString("\n")
+		case lines[i-1] != "":
+			sb.WriteString(" ")
+		}
+		sb.WriteString(line)
+	}
+	switch chomp {
+	case "":
+		sb.WriteString("\n")
+	case "+":
+		sb.WriteString(strings.Repeat("\n", trailing+1))
+	}
+	return sb.String()
+}
+
+// isYAMLMappingEntry reports whether text is a "key: value" or "key:" line
+func isYAMLMappingEntry(text string) bool {
+	_, _, ok := cutYAMLMappingEntry(text)
+	return ok
+}
+
+// cutYAMLMappingEntry splits "key: value" on the first ": " (or a final
+// ":") outside quotes
+func cutYAMLMappingEntry(text string) (key, rest string, ok bool) {
+	if text == "" || text[0] == '[' || text[0] == '{' {
+		return "", "", false
+	}
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && i == 0:
+			quote = c
+		case c == ':' && (i == len(text)-1 || text[i+1] == ' '):
+			key = strings.TrimSpace(text[:i])
+			if unquoted, err := parseYAMLScalar(key); err == nil && key != "" && (key[0] == '"' || key[0] == '\'') {
+				key = unquoted.(string)
+			}
+			return key, strings.TrimSpace(text[i+1:]), key != ""
+		}
+	}
+	return "", "", false
+}
+
+// stripYAMLComment removes a # comment that starts the line or follows a
+// space outside quotes
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == '\'' && quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++ // '' is an escaped quote
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.ContainsRune(" [,:-", rune(text[i-1])) {
+				quote = c
+			}
+		case c == '#' && (i == 0 || text[i-1] == ' '):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// parseYAMLScalar parses an inline value: a quoted or plain scalar or a
+// flow sequence or mapping
+func parseYAMLScalar(text string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("unterminated flow sequence %s", text)
+		}
+		out := []interface{}{}
+		items, err := splitYAMLFlow(text[1 : len(text)-1])
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			value, err := parseYAMLScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, value)
+		}
+		return out, nil
+	case strings.HasPrefix(text, "{"):
+		if !strings.HasSuffix(text, "}") {
+			return nil, fmt.Errorf("unterminated flow mapping %s", text)
+		}
+		out := map[string]interface{}{}
+		items, err := splitYAMLFlow(text[1 : len(text)-1])
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			key, rest, ok := cutYAMLMappingEntry(item)
+			if !ok {
+				return nil, fmt.Errorf("expected \"key: value\" in %s", text)
+			}
+			if out[key], err = parseYAMLScalar(rest); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case strings.HasPrefix(text, `"`):
+		if len(text) < 2 || !strings.HasSuffix(text, `"`) {
+			return nil, fmt.Errorf("unterminated string %s", text)
+		}
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s", text)
+		}
+		return s, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return nil, fmt.Errorf("unterminated string %s", text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case strings.HasPrefix(text, "&"), strings.HasPrefix(text, "*"), strings.HasPrefix(text, "!"):
+		return nil, fmt.Errorf("anchors, aliases and tags are not supported: %s", text)
+	}
+
+	switch text {
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil && !strings.ContainsAny(text, "xXpP_") {
+		return f, nil
+	}
+	return text, nil
+}
+
+// splitYAMLFlow splits the items of a flow collection on commas outside
+// quotes and nested collections
+func splitYAMLFlow(text string) ([]string, error) {
+	var items []string
+	var quote byte
+	start, depth := 0, 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == '\'' && quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++ // '' is an escaped quote
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated string in %s", text)
+	}
+	if last := strings.TrimSpace(text[start:]); last != "" || len(items) > 0 {
+		items = append(items, last)
+	}
+	for _, item := range items {
+		if item == "" {
+			return nil, fmt.Errorf("empty item in %s", text)
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,101 @@
+package lib_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gitlab-mr-helper/lib"
+)
+
+func TestDecodeYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    string // the decoded value as JSON
+		wantErr string
+	}{
+		{
+			name: "nested mappings and sequences",
+			yaml: `# ruleset
+rules:
+  - name: triage   # trailing comment
+    when:
+      label_added: "needs review"
+    then:
+      assign: [alice, '@bob']
+      add_labels:
+      - triaged
+  - name: ci
+    then: {}
+`,
+			want: `{"rules":[{"name":"triage","then":{"add_labels":["triaged"],"assign":["alice","@bob"]},"when":{"label_added":"needs review"}},{"name":"ci","then":{}}]}`,
+		},
+		{
+			name: "typed scalars",
+			yaml: "a: true\nb: 42\nc: 1.5\nd: ~\ne: '42'\nf: v1.2.3\ng: http://x:80/#frag\n",
+			want: `{"a":true,"b":42,"c":1.5,"d":null,"e":"42","f":"v1.2.3","g":"http://x:80/#frag"}`,
+		},
+		{
+			name: "quoted strings",
+			yaml: `a: "tab\there # not a comment"` + "\nb: 'it''s'\n\"c: d\": e\ncomment: 'don''t merge # yet'\n",
+			want: `{"a":"tab\there # not a comment","b":"it's","c: d":"e","comment":"don't merge # yet"}`,
+		},
+		{
+			name: "literal block scalar",
+			yaml: "comment: |\n  Thanks!\n\n    indented\nnext: x\n",
+			want: `{"comment":"Thanks!\n\n  indented\n","next":"x"}`,
+		},
+		{
+			name: "folded block scalar without final newline",
+			yaml: "comment: >-\n  one\n  two\n\n  three\n",
+			want: `{"comment":"one two\nthree"}`,
+		},
+		{
+			name: "sequence of sequences",
+			yaml: "- - a\n  - b\n- [c]\n",
+			want: `[["a","b"],["c"]]`,
+		},
+		{
+			name: "flow collections",
+			yaml: "when: {label_added: \"a, b\", branches: [x, {y: z}]}\nempty: []\n",
+			want: `{"empty":[],"when":{"branches":["x",{"y":"z"}],"label_added":"a, b"}}`,
+		},
+		{name: "empty document", yaml: "# nothing\n---\n", want: `null`},
+		{name: "bad indentation", yaml: "a:\n  b: 1\n   c: 2\n", wantErr: "line 3: unexpected indentation"},
+		{name: "tab indentation", yaml: "a:\n\tb: 1\n", wantErr: "line 2: tabs are not allowed"},
+		{name: "duplicate key", yaml: "a: 1\na: 2\n", wantErr: `line 2: duplicate key "a"`},
+		{name: "not a mapping entry", yaml: "a: 1\njust text\n", wantErr: "line 2: expected"},
+		{name: "alias", yaml: "a: *ref\n", wantErr: "aliases"},
+		{name: "unterminated string", yaml: "a: \"open\n", wantErr: "unterminated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got interface{}
+			err := lib.DecodeYAML([]byte(tt.yaml), &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("DecodeYAML error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeYAML: %v", err)
+			}
+			data, _ := json.Marshal(got)
+			if string(data) != tt.want {
+				t.Errorf("DecodeYAML =\n%s\nwant\n%s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeYAMLUnknownField(t *testing.T) {
+	var v struct {
+		Name string `json:"name"`
+	}
+	err := lib.DecodeYAML([]byte("name: x\nnmae: y\n"), &v)
+	if err == nil || !strings.Contains(err.Error(), `unknown field "nmae"`) {
+		t.Errorf("DecodeYAML error = %v, want unknown field", err)
+	}
+}
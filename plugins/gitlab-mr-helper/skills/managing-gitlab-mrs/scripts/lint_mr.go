@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+// reportID tells the lint comment apart from other status comments
+const reportID = "mr-lint"
+
+func main() {
+	// Flags
+	mrFlag := lib.RegisterMRFlag("Merge request IID, web URL or source branch (required; in a merge request pipeline, that MR)")
+	sections := flag.String("sections", "", "Comma-separated description headings that must have text (default from lint.mr.sections, else the template's headings)")
+	template := flag.String("template", "", "MR description template in .gitlab/merge_request_templates/ (default from lint.mr.template, else Default)")
+	requireIssue := flag.Bool("require-issue", false, "Require a linked issue or tracker ticket (default from lint.mr.require_issue)")
+	labels := flag.String("labels", "", "Comma-separated label patterns each matched by some label, e.g. 'type::*' (default from lint.mr.labels)")
+	conventional := flag.Bool("conventional-title", false, "Require a conventional-commit title (default from lint.mr.conventional_title)")
+	comment := flag.Bool("comment", false, "Post the results as a comment on failure, and keep an existing one up to date")
+	warnOnly := flag.Bool("warn-only", false, "Exit 0 even when checks fail")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Validate MR reference
+	if err := mrFlag.Parse(); err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	settings, err := lib.LoadSettings()
+	if err != nil {
+		lib.Exit("Error loading settings", err)
+	}
+	rules := &settings.Lint.MR
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "sections":
+			rules.Sections = lib.SplitList(*sections)
+		case "template":
+			rules.Template = *template
+		case "require-issue":
+			rules.RequireIssue = *requireIssue
+		case "labels":
+			rules.Labels = lib.SplitList(*labels)
+		case "conventional-title":
+			rules.ConventionalTitle = *conventional
+		}
+	})
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	mrIID, err := mrFlag.Resolve(client, projectPath)
+	if err != nil {
+		lib.Exit("Error finding MR", err)
+	}
+	mr, err := client.GetMR(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR", err)
+	}
+
+	// The template marks its placeholder text even when the sections are
+	// configured
+	name := rules.Template
+	if name == "" {
+		name = lib.DefaultMRTemplate
+	}
+	text, err := client.GetMRTemplate(projectPath, name)
+	if err != nil {
+		lib.Exit("Error reading MR template", err)
+	}
+	if text == "" && rules.Template != "" {
+		lib.Exit("Error", fmt.Errorf("%w: no MR template %s", lib.ErrNotFound, lib.MRTemplatePath(name)))
+	}
+
+	checks, err := lib.LintMR(&settings.Lint, &settings.Tracker, mr, text)
+	if err != nil {
+		lib.Exit("Error linting MR", err)
+	}
+	if len(checks) == 0 {
+		lib.Usagef("no MR rules to check: configure lint.mr in settings, add %s or pass --sections, --require-issue, --labels or --conventional-title", lib.MRTemplatePath(lib.DefaultMRTemplate))
+	}
+
+	failed := 0
+	for _, c := range checks {
+		line := fmt.Sprintf("%s: %s", c.Name, c.Details)
+		switch {
+		case c.Passed:
+			ui.Printf("  %s\n", ui.Success(line))
+		case ui.Quiet:
+			fmt.Println(line)
+		default:
+			fmt.Printf("  %s\n", ui.Failure(line))
+		}
+		if !c.Passed {
+			failed++
+		}
+	}
+	if failed == 0 {
+		ui.Printf("%s\n", ui.Success(fmt.Sprintf("MR !%d passes %d check(s)", mr.IID, len(checks))))
+	} else {
+		ui.Printf("MR !%d fails %d of %d check(s)\n", mr.IID, failed, len(checks))
+	}
+
+	if *comment {
+		report := &lib.StatusReport{ID: reportID, Title: "MR checks", UpdatedAt: time.Now()}
+		for _, c := range checks {
+			report.Set(lib.StatusRow{Name: c.Name, Status: c.Status(), Details: c.Details})
+		}
+		note, _, err := client.UpsertStatusReport(projectPath, mr.IID, report, failed == 0)
+		if err != nil {
+			lib.Exit("Error writing lint comment", err)
+		}
+		if note != nil {
+			ui.Printf("  Comment: %s#note_%d\n", mr.WebURL, note.ID)
+		}
+	}
+
+	if failed > 0 && !*warnOnly {
+		lib.Exit("Error", fmt.Errorf("%w: MR !%d fails %d check(s)", lib.ErrBlocked, mr.IID, failed))
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	mrFlag := lib.RegisterMRFlag("Merge request IID, web URL or source branch (required)")
+	all := flag.Bool("all", false, "List overlapping files even when GitLab reports no conflicts")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Validate MR reference
+	if err := mrFlag.Parse(); err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	mrIID, err := mrFlag.Resolve(client, projectPath)
+	if err != nil {
+		lib.Exit("Error finding MR", err)
+	}
+	mr, err := client.GetMR(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR", err)
+	}
+
+	// has_conflicts can be stale right after a push; the merge ref is not
+	conflicts := mr.HasConflicts
+	if !conflicts && mr.State == "opened" {
+		mergeable, err := client.CheckMergeRef(projectPath, mr.IID)
+		if err != nil {
+			lib.Exit("Error checking the merge ref", err)
+		}
+		conflicts = !mergeable
+	}
+
+	ui.Printf("MR !%d: %s "+lib.Arrow+" %s\n", mr.IID, mr.SourceBranch, mr.TargetBranch)
+	if !conflicts {
+		ui.Printf("%s\n", ui.Success(fmt.Sprintf("No conflicts reported (merge status: %s)", mergeStatus(mr))))
+		if !*all {
+			return
+		}
+	} else {
+		ui.Printf("%s\n", ui.Failure(fmt.Sprintf("Conflicts reported (merge status: %s)", mergeStatus(mr))))
+	}
+
+	// Files touched by the MR itself
+	mrDiffs, err := client.GetMRDiffs(projectPath, mr.IID)
+	if err != nil {
+		lib.Exit("Error getting MR diffs", err)
+	}
+
+	// Files touched on the target branch since the merge base
+	cmp, err := client.CompareRefs(projectPath, mr.SourceBranch, mr.TargetBranch)
+	if err != nil {
+		lib.Exit("Error comparing branches", err)
+	}
+
+	files := lib.OverlappingPaths(mrDiffs, cmp.Diffs)
+
+	if ui.Quiet {
+		for _, f := range files {
+			fmt.Println(f)
+		}
+		return
+	}
+
+	fmt.Println()
+	if len(files) == 0 {
+		fmt.Println("No files changed on both sides; conflicts may come from renames or deleted files.")
+	} else {
+		fmt.Printf("Files changed on both %s and %s (%d):\n", mr.SourceBranch, mr.TargetBranch, len(files))
+		for _, f := range files {
+			fmt.Printf("  "+lib.Bullet+" %s\n", f)
+		}
+	}
+
+	if len(cmp.Commits) > 0 {
+		// The remote the project was detected from, as create_mr.go pushes to
+		remote := "origin"
+		if name, _, err := lib.FindGitLabRemote(projectFlags.Remote); err == nil {
+			remote = name
+		}
+		fmt.Printf("\n%s is %d commit(s) behind %s. Suggested resolution:\n", mr.SourceBranch, len(cmp.Commits), mr.TargetBranch)
+		fmt.Printf("  git fetch %s\n", remote)
+		fmt.Printf("  git checkout %s\n", mr.SourceBranch)
+		fmt.Printf("  git rebase %s/%s\n", remote, mr.TargetBranch)
+	}
+}
+
+func mergeStatus(mr *lib.MergeRequest) string {
+	if mr.DetailedMergeStatus != "" {
+		return mr.DetailedMergeStatus
+	}
+	return mr.MergeStatus
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 	"strings"
-	"time"
 
 	"gitlab-mr-helper/lib"
 )
@@ -14,40 +13,71 @@ func main() {
 	// Flags
 	state := flag.String("state", "opened", "MR state: opened, closed, merged, all")
 	limit := flag.Int("limit", 20, "Maximum number of MRs to list")
-	auto := flag.Bool("auto", false, "Auto-detect project from git remote")
+	projectFlags := lib.RegisterProjectFlags()
+	output := flag.String("output", "text", "Output format: text, tsv, csv")
+	format := flag.String("format", "", "Go template applied to each MR (e.g. '{{.IID}} {{.Title}}')")
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
 
 	flag.Parse()
+	defer lib.ReportStats()
+
+	if err := lib.ValidateOutputFormat(*output); err != nil {
+		lib.Exit("Error", err)
+	}
+
+	var tmpl *lib.OutputTemplate
+	if *format != "" {
+		var err error
+		tmpl, err = lib.ParseOutputTemplate(*format)
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+	}
 
 	// Get configuration
 	config, err := lib.GetConfig()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		lib.Exit("Error", err)
 	}
 
 	// Get project path
-	var projectPath string
-	if *auto {
-		projectPath, err = lib.GetProjectFromGit()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error resolving project: %v\n", err)
-			os.Exit(1)
-		}
-		fmt.Printf("✓ Project: %s\n\n", projectPath)
-	} else {
-		projectPath = flag.Arg(0)
-		if projectPath == "" {
-			fmt.Fprintf(os.Stderr, "Error: project path required (use --auto or provide as argument)\n")
-			os.Exit(1)
-		}
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected && tmpl == nil && *output == lib.OutputText {
+		ui.Printf("%s\n\n", ui.Success("Project: "+projectPath))
 	}
 
 	// Create API client and list MRs
 	client := lib.NewClient(config)
 	mrs, err := client.ListMRs(projectPath, *state, *limit)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error listing MRs: %v\n", err)
-		os.Exit(1)
+		lib.Exit("Error listing MRs", err)
+	}
+
+	if *output != lib.OutputText {
+		if err := lib.WriteTable(os.Stdout, *output, lib.MRTableHeader, lib.MRTableRows(mrs)); err != nil {
+			lib.Exit("Error", err)
+		}
+		return
+	}
+
+	if tmpl != nil {
+		for _, mr := range mrs {
+			if err := tmpl.Execute(os.Stdout, mr); err != nil {
+				lib.Exit("Error", err)
+			}
+		}
+		return
+	}
+
+	if ui.Quiet {
+		for _, mr := range mrs {
+			fmt.Println(mr.IID)
+		}
+		return
 	}
 
 	if len(mrs) == 0 {
@@ -59,17 +89,16 @@ func main() {
 	fmt.Println(strings.Repeat("-", 80))
 
 	for _, mr := range mrs {
-		stateIcon := getStateIcon(mr.State)
 		draftPrefix := ""
 		if mr.Draft {
 			draftPrefix = "[Draft] "
 		}
 
-		age := formatAge(mr.CreatedAt)
+		age := lib.FormatAge(mr.CreatedAt)
 
-		fmt.Printf("%s !%d  %s%s\n", stateIcon, mr.IID, draftPrefix, mr.Title)
-		fmt.Printf("     %s → %s  |  @%s  |  %s\n",
-			mr.SourceBranch, mr.TargetBranch, mr.Author.Username, age)
+		fmt.Printf("%s!%d  %s%s\n", ui.StateIcon(mr.State), mr.IID, draftPrefix, mr.Title)
+		fmt.Printf("     %s  |  %s "+lib.Arrow+" %s  |  @%s  |  %s\n",
+			ui.State(mr.State), mr.SourceBranch, mr.TargetBranch, mr.Author.Username, age)
 
 		if len(mr.Labels) > 0 {
 			fmt.Printf("     Labels: %s\n", strings.Join(mr.Labels, ", "))
@@ -79,30 +108,3 @@ func main() {
 
 	fmt.Printf("Total: %d merge request(s)\n", len(mrs))
 }
-
-func getStateIcon(state string) string {
-	switch state {
-	case "opened":
-		return "🟢"
-	case "merged":
-		return "🟣"
-	case "closed":
-		return "🔴"
-	default:
-		return "⚪"
-	}
-}
-
-func formatAge(t time.Time) string {
-	duration := time.Since(t)
-
-	if duration < time.Hour {
-		return fmt.Sprintf("%dm ago", int(duration.Minutes()))
-	} else if duration < 24*time.Hour {
-		return fmt.Sprintf("%dh ago", int(duration.Hours()))
-	} else if duration < 7*24*time.Hour {
-		return fmt.Sprintf("%dd ago", int(duration.Hours()/24))
-	} else {
-		return t.Format("Jan 2, 2006")
-	}
-}
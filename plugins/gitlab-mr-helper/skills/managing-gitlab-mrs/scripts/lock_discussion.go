@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	mrFlag := lib.RegisterMRFlag("Merge request IID, web URL or source branch whose discussion to lock (in a merge request pipeline, that MR)")
+	issueIID := flag.Int("issue", 0, "Lock the discussion of this issue instead of an MR")
+	unlock := flag.Bool("unlock", false, "Unlock the discussion instead")
+	comment := flag.String("comment", "", "Explain the change in a comment, posted first")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *issueIID < 0 {
+		lib.Usagef("invalid --issue %d", *issueIID)
+	}
+	if *issueIID == 0 {
+		if err := mrFlag.Parse(); err != nil {
+			lib.Exit("Error", err)
+		}
+	} else if mrFlag.Value != "" {
+		lib.Usagef("--mr and --issue are mutually exclusive")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	locked := !*unlock
+	action := map[bool]string{true: "locked", false: "unlocked"}[locked]
+
+	// Both kinds of item go through the same steps: read the current
+	// state, comment, update
+	var (
+		name, webURL string
+		current      bool
+		addNote      func(body string) error
+		update       func() (string, error)
+	)
+	if *issueIID > 0 {
+		issue, err := client.GetIssue(projectPath, *issueIID)
+		if err != nil {
+			lib.Exit("Error getting issue", err)
+		}
+		name, webURL, current = fmt.Sprintf("Issue #%d", issue.IID), issue.WebURL, issue.DiscussionLocked
+		addNote = func(body string) error {
+			_, err := client.CreateIssueNote(projectPath, issue.IID, body)
+			return err
+		}
+		update = func() (string, error) {
+			issue, err := client.UpdateIssue(projectPath, issue.IID, &lib.UpdateIssueRequest{DiscussionLocked: &locked})
+			if err != nil {
+				return "", err
+			}
+			return issue.WebURL, nil
+		}
+	} else {
+		mrIID, err := mrFlag.Resolve(client, projectPath)
+		if err != nil {
+			lib.Exit("Error finding MR", err)
+		}
+		mr, err := client.GetMR(projectPath, mrIID)
+		if err != nil {
+			lib.Exit("Error getting MR", err)
+		}
+		name, webURL, current = fmt.Sprintf("MR !%d", mr.IID), mr.WebURL, mr.DiscussionLocked
+		addNote = func(body string) error {
+			_, err := client.CreateMRNote(projectPath, mr.IID, body)
+			return err
+		}
+		update = func() (string, error) {
+			mr, err := client.UpdateMR(projectPath, mr.IID, &lib.UpdateMRRequest{DiscussionLocked: &locked})
+			if err != nil {
+				return "", err
+			}
+			return mr.WebURL, nil
+		}
+	}
+
+	if current == locked {
+		if ui.Quiet {
+			fmt.Println(webURL)
+		}
+		ui.Printf("%s: discussion already %s\n", name, action)
+		return
+	}
+	if *comment != "" {
+		if err := addNote(*comment); err != nil {
+			lib.Exit("Error commenting", err)
+		}
+	}
+	if webURL, err = update(); err != nil {
+		lib.Exit("Error updating "+name, err)
+	}
+	if ui.Quiet {
+		fmt.Println(webURL)
+		return
+	}
+	fmt.Printf("%s\n", ui.Success(fmt.Sprintf("%s: discussion %s", name, action)))
+	if locked {
+		fmt.Println("  Only project members can comment now")
+	}
+	fmt.Printf("  URL: %s\n", webURL)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// The action comes before the flags: maintenance.go announce --message "..."
+	action := "status"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	// Flags
+	message := flag.String("message", "", "announce: text of the broadcast message (required); on: maintenance banner text (default: keep the current one)")
+	starts := flag.String("starts", "now", "announce: start: now, an RFC 3339 time or a duration from now (30m, 2h)")
+	ends := flag.String("ends", "1h", "announce: end: an RFC 3339 time or a duration after the start")
+	kind := flag.String("type", "banner", "announce: banner (top of every page) or notification (popup)")
+	theme := flag.String("theme", "", "announce: banner color theme, e.g. indigo, red, dark (default: GitLab's)")
+	dismissable := flag.Bool("dismissable", false, "announce: let users dismiss the message")
+	ids := flag.String("id", "", "remove: comma-separated broadcast message IDs")
+	all := flag.Bool("all", false, "remove: remove every active and scheduled broadcast message")
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.CommandLine.Parse(args)
+	defer lib.ReportStats()
+	if flag.NArg() > 0 {
+		lib.Usagef("unexpected argument %q", flag.Arg(0))
+	}
+
+	var req *lib.CreateBroadcastRequest
+	var remove []int
+	switch action {
+	case "status", "on", "off":
+	case "announce":
+		if *message == "" {
+			lib.Usagef("announce needs --message")
+		}
+		if *kind != "banner" && *kind != "notification" {
+			lib.Usagef("invalid --type %q (expected banner or notification)", *kind)
+		}
+		startsAt, err := lib.ParseScheduleTime(*starts, time.Now())
+		if err != nil {
+			lib.Usagef("--starts: %v", err)
+		}
+		endsAt, err := lib.ParseScheduleTime(*ends, startsAt)
+		if err != nil {
+			lib.Usagef("--ends: %v", err)
+		}
+		if !endsAt.After(startsAt) {
+			lib.Usagef("--ends must be after --starts")
+		}
+		req = &lib.CreateBroadcastRequest{Message: *message, BroadcastType: *kind, Theme: *theme, Dismissable: *dismissable,
+			StartsAt: startsAt.UTC(), EndsAt: endsAt.UTC()}
+	case "remove":
+		if (*ids == "") == !*all {
+			lib.Usagef("remove needs --id or --all")
+		}
+		if *ids != "" {
+			for _, s := range lib.SplitList(*ids) {
+				id, err := strconv.Atoi(s)
+				if err != nil {
+					lib.Usagef("invalid broadcast message ID %q", s)
+				}
+				remove = append(remove, id)
+			}
+		}
+	default:
+		lib.Usagef("unknown action %q (expected status, announce, remove, on or off)", action)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	client := lib.NewClient(config)
+
+	switch action {
+	case "status":
+		status(client, ui)
+	case "announce":
+		m, err := client.CreateBroadcastMessage(req)
+		if err != nil {
+			lib.Exit("Error creating broadcast message", err)
+		}
+		if ui.Quiet {
+			fmt.Println(m.ID)
+			return
+		}
+		fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Broadcast message #%d %s", m.ID, schedule(m))))
+	case "remove":
+		if *all {
+			messages, err := client.ListBroadcastMessages()
+			if err != nil {
+				lib.Exit("Error listing broadcast messages", err)
+			}
+			now := time.Now()
+			for _, m := range messages {
+				if m.EndsAt.After(now) {
+					remove = append(remove, m.ID)
+				}
+			}
+		}
+		var firstErr error
+		for _, id := range remove {
+			if err := client.DeleteBroadcastMessage(id); err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", ui.Failure(fmt.Sprintf("#%d: %v", id, err)))
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			if ui.Quiet {
+				fmt.Println(id)
+				continue
+			}
+			fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Broadcast message #%d removed", id)))
+		}
+		if firstErr != nil {
+			lib.ExitWith(lib.ExitCode(firstErr))
+		}
+	case "on", "off":
+		m, err := client.SetMaintenanceMode(action == "on", *message)
+		if err != nil {
+			lib.Exit("Error changing maintenance mode", err)
+		}
+		if ui.Quiet {
+			fmt.Println(action)
+			return
+		}
+		if m.Enabled {
+			fmt.Printf("%s\n", ui.Success("Maintenance mode on: the instance is read-only"))
+			if m.Message != "" {
+				fmt.Printf("  Message: %s\n", m.Message)
+			}
+		} else {
+			fmt.Printf("%s\n", ui.Success("Maintenance mode off"))
+		}
+	}
+}
+
+// status prints the maintenance mode and the broadcast messages that have
+// not ended
+func status(client *lib.Client, ui *lib.UI) {
+	mode, err := client.GetMaintenanceMode()
+	if err != nil {
+		lib.Exit("Error reading maintenance mode", err)
+	}
+	messages, err := client.ListBroadcastMessages()
+	if err != nil {
+		lib.Exit("Error listing broadcast messages", err)
+	}
+
+	state := "off"
+	if mode.Enabled {
+		state = "on"
+	}
+	if ui.Quiet {
+		fmt.Println(state)
+		return
+	}
+	fmt.Printf("Maintenance mode: %s\n", state)
+	if mode.Enabled && mode.Message != "" {
+		fmt.Printf("  Message: %s\n", mode.Message)
+	}
+
+	now := time.Now()
+	fmt.Println("\nBroadcast messages:")
+	shown := 0
+	for _, m := range messages {
+		if !m.EndsAt.After(now) {
+			continue
+		}
+		shown++
+		fmt.Printf("  #%d %s %s\n", m.ID, m.BroadcastType, schedule(&m))
+		fmt.Printf("      %s\n", m.Message)
+	}
+	if shown == 0 {
+		fmt.Println("  (none active or scheduled)")
+	}
+}
+
+// schedule describes when a broadcast message shows
+func schedule(m *lib.BroadcastMessage) string {
+	const layout = "2006-01-02 15:04 MST"
+	if m.Active {
+		return "active until " + m.EndsAt.Local().Format(layout)
+	}
+	return fmt.Sprintf("scheduled %s to %s", m.StartsAt.Local().Format(layout), m.EndsAt.Local().Format(layout))
+}
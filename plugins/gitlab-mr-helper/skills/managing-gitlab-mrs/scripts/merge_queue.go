@@ -0,0 +1,312 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	mrList := flag.String("mrs", "", "Comma-separated MR IIDs to merge, in order (or pass them as arguments)")
+	rebase := flag.Bool("rebase", true, "Rebase each MR onto its target before merging (--rebase=false to skip)")
+	waitPipeline := flag.Bool("wait-pipeline", true, "Wait for a green pipeline on the MR's HEAD before merging (--wait-pipeline=false to skip)")
+	timeout := flag.Duration("timeout", 30*time.Minute, "Maximum wait per MR for the rebase and pipeline")
+	interval := flag.Duration("interval", 15*time.Second, "Shortest polling interval while waiting, used as a pipeline nears its end")
+	maxInterval := flag.Duration("max-interval", 2*time.Minute, "Longest polling interval, while a pipeline is pending or far from done")
+	squash := flag.Bool("squash", false, "Squash commits on merge")
+	removeSource := flag.Bool("remove-source-branch", false, "Remove source branches after merge")
+	notify := flag.Bool("notify", false, "Post a summary to the configured Slack/Mattermost webhook when done")
+	requireGreen := flag.Bool("require-green-target", false, "Refuse to merge while the latest pipeline of the target branch is failing (default from merge.require_green_target)")
+	runHooks := flag.Bool("hooks", true, "Fire the merge hooks configured in settings after each merge (--hooks=false to skip)")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Positional arguments are the project path and MR IIDs or web URLs
+	var projectArg string
+	var iids []int
+	args := flag.Args()
+	if *mrList != "" {
+		args = append(args, lib.SplitList(*mrList)...)
+	}
+	for _, arg := range args {
+		arg = strings.TrimPrefix(strings.TrimSpace(arg), "!")
+		if iid, err := strconv.Atoi(arg); err == nil {
+			iids = append(iids, iid)
+			continue
+		}
+		if strings.Contains(arg, "://") {
+			project, iid, err := lib.ParseWebURL(arg)
+			if err != nil {
+				lib.Exit("Error", err)
+			}
+			if projectArg != "" && project != projectArg {
+				lib.Usagef("MRs of different projects: %s and %s", projectArg, project)
+			}
+			projectArg = project
+			if iid > 0 {
+				iids = append(iids, iid)
+			}
+			continue
+		}
+		if projectArg == "" {
+			projectArg = arg
+		} else {
+			lib.Usagef("invalid MR IID %q", arg)
+		}
+	}
+	if len(iids) == 0 {
+		lib.Usagef("at least one MR IID is required (use --mrs or provide them as arguments)")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	settings, err := lib.LoadSettings()
+	if err != nil {
+		lib.Exit("Error loading settings", err)
+	}
+	var notifier *lib.Notifier
+	if *notify {
+		notifier = lib.NewNotifier(settings.Notify)
+		if notifier == nil {
+			lib.Usagef("--notify needs notify.webhook_url in settings or GITLAB_NOTIFY_WEBHOOK")
+		}
+	}
+	var hooks *lib.Hooks
+	if *runHooks {
+		hooks = lib.NewHooks(settings)
+	}
+	requireGreenSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "require-green-target" {
+			requireGreenSet = true
+		}
+	})
+	if !requireGreenSet {
+		*requireGreen = settings.Merge.RequireGreenTarget
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(projectArg)
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	q := &queue{
+		client:  client,
+		project: projectPath,
+		ui:      ui,
+		opts: queueOptions{
+			rebase:       *rebase,
+			waitPipeline: *waitPipeline,
+			timeout:      *timeout,
+			interval:     *interval,
+			maxInterval:  *maxInterval,
+			requireGreen: *requireGreen,
+			merge:        lib.MergeMRRequest{Squash: *squash, ShouldRemoveSourceBranch: *removeSource},
+		},
+	}
+
+	// Failures skip the MR and move on; only auth errors stop the queue
+	var merged, summary []string
+	var firstErr error
+	for i, iid := range iids {
+		ui.Printf("\n[%d/%d] MR !%d\n", i+1, len(iids), iid)
+		mr, err := q.process(iid)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.Failure(fmt.Sprintf("!%d skipped: %v", iid, err)))
+			summary = append(summary, fmt.Sprintf("!%d skipped: %v", iid, err))
+			if firstErr == nil {
+				firstErr = err
+			}
+			if lib.ExitCode(err) == lib.ExitAuth {
+				break
+			}
+			continue
+		}
+		merged = append(merged, mr.WebURL)
+		summary = append(summary, fmt.Sprintf("!%d merged: %s", iid, mr.Title))
+		if ui.Quiet {
+			fmt.Println(mr.WebURL)
+		} else {
+			fmt.Printf("%s\n", ui.Success(fmt.Sprintf("!%d merged: %s", iid, mr.Title)))
+		}
+
+		// The merge stands even when a hook fails
+		if hooks != nil {
+			fired, err := hooks.Fire(lib.HookMerge, projectPath, mr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: merge hooks of !%d: %v\n", iid, err)
+			}
+			if fired > 0 {
+				ui.Printf("  Hooks fired: %d\n", fired)
+			}
+		}
+	}
+
+	ui.Printf("\nMerged %d of %d MR(s)\n", len(merged), len(iids))
+	for _, line := range summary {
+		ui.Printf("  "+lib.Bullet+" %s\n", line)
+	}
+
+	if notifier != nil {
+		title := fmt.Sprintf("merge_queue.go merged %d of %d MRs in %s", len(merged), len(iids), projectPath)
+		if err := notifier.Send(lib.Notification{Title: title, Text: strings.Join(summary, "\n"), Success: firstErr == nil}); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	if firstErr != nil {
+		if lib.ExitCode(firstErr) == lib.ExitAuth {
+			lib.ExitWith(lib.ExitAuth)
+		}
+		lib.Exit("Error", fmt.Errorf("%w: %d of %d MR(s) not merged", lib.ErrBlocked, len(iids)-len(merged), len(iids)))
+	}
+}
+
+type queueOptions struct {
+	rebase       bool
+	waitPipeline bool
+	timeout      time.Duration
+	interval     time.Duration
+	maxInterval  time.Duration
+	requireGreen bool
+	merge        lib.MergeMRRequest
+}
+
+// queue merges MRs of one project one after another
+type queue struct {
+	client  *lib.Client
+	project string
+	ui      *lib.UI
+	opts    queueOptions
+}
+
+// process rebases, waits for and merges one MR. The returned error explains
+// why the MR was skipped.
+func (q *queue) process(iid int) (*lib.MergeRequest, error) {
+	mr, err := q.client.GetMR(q.project, iid)
+	if err != nil {
+		return nil, err
+	}
+	q.ui.Printf("  %s (%s "+lib.Arrow+" %s)\n", mr.Title, mr.SourceBranch, mr.TargetBranch)
+	switch {
+	case mr.State != "opened":
+		return nil, fmt.Errorf("MR is %s", mr.State)
+	case mr.Draft:
+		return nil, fmt.Errorf("MR is a draft")
+	case mr.HasConflicts:
+		return nil, fmt.Errorf("MR has conflicts")
+	}
+
+	if q.opts.rebase {
+		q.ui.Printf("  Rebasing onto %s...\n", mr.TargetBranch)
+		if mr, err = q.rebase(iid); err != nil {
+			return nil, err
+		}
+	}
+
+	if q.opts.waitPipeline {
+		if mr, err = q.waitForPipeline(iid); err != nil {
+			return nil, err
+		}
+	}
+
+	// Checked last, as the target may have broken while we waited
+	if q.opts.requireGreen {
+		if err := q.checkTarget(mr.TargetBranch); err != nil {
+			return nil, err
+		}
+	}
+
+	req := q.opts.merge
+	req.SHA = mr.SHA
+	q.ui.Printf("  Merging...\n")
+	return q.client.MergeMR(q.project, iid, &req)
+}
+
+// checkTarget fails when the latest finished pipeline of the target branch
+// failed, so no MR is stacked onto a broken branch
+func (q *queue) checkTarget(branch string) error {
+	p, err := q.client.LatestFinishedPipeline(q.project, branch)
+	if err != nil {
+		return fmt.Errorf("checking %s: %w", branch, err)
+	}
+	if p != nil && p.Status == "failed" {
+		return fmt.Errorf("%w: %s is red, pipeline #%d failed: %s", lib.ErrBlocked, branch, p.ID, p.WebURL)
+	}
+	return nil
+}
+
+// rebase starts a rebase and waits for it to finish
+func (q *queue) rebase(iid int) (*lib.MergeRequest, error) {
+	if err := q.client.RebaseMR(q.project, iid); err != nil {
+		return nil, fmt.Errorf("rebase failed: %w", err)
+	}
+	var mr *lib.MergeRequest
+	err := lib.Poll(q.opts.interval, q.opts.timeout, func() (bool, error) {
+		var err error
+		mr, err = q.client.GetMRRebaseStatus(q.project, iid)
+		return err == nil && !mr.RebaseInProgress, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("waiting for rebase: %w", err)
+	}
+	if mr.MergeError != "" {
+		return nil, fmt.Errorf("rebase failed: %s", mr.MergeError)
+	}
+	return mr, nil
+}
+
+// waitForPipeline waits until the pipeline for the MR's current HEAD has
+// finished and fails unless it succeeded. Checks come less often while the
+// pipeline has far to go, going by how long its ref's pipelines usually
+// take.
+func (q *queue) waitForPipeline(iid int) (*lib.MergeRequest, error) {
+	var mr *lib.MergeRequest
+	var typical time.Duration
+	reported := 0
+	poller := lib.Poller{Interval: q.opts.interval, MaxInterval: q.opts.maxInterval, Timeout: q.opts.timeout}
+	err := poller.Poll(func() (bool, time.Duration, error) {
+		var err error
+		if mr, err = q.client.GetMR(q.project, iid); err != nil {
+			return false, 0, err
+		}
+		p := mr.HeadPipeline
+		if p == nil || p.SHA != mr.SHA {
+			return false, 0, nil
+		}
+		if p.ID != reported {
+			q.ui.Printf("  Waiting for pipeline #%d (%s)...\n", p.ID, p.Status)
+			reported = p.ID
+			if typical, err = q.client.TypicalPipelineDuration(q.project, p.Ref); err != nil {
+				return false, 0, err
+			}
+		}
+		return lib.PipelineFinished(p.Status), lib.PipelineWait(p, typical, time.Now()), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("waiting for pipeline: %w", err)
+	}
+	if p := mr.HeadPipeline; p.Status != "success" {
+		return nil, fmt.Errorf("pipeline #%d %s: %s", p.ID, p.Status, p.WebURL)
+	}
+	return mr, nil
+}
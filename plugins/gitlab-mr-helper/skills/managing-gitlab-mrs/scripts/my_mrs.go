@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	group := flag.String("group", "", "Group path to search, subgroups included (default: all projects visible to you)")
+	roles := flag.String("roles", "author,assignee", "Comma-separated roles to list MRs for: author, assignee, reviewer, or all")
+	state := flag.String("state", "opened", "MR state: opened, closed, merged, all")
+	skipDrafts := flag.Bool("skip-drafts", false, "Leave out draft MRs")
+	limit := flag.Int("limit", 0, "Maximum number of MRs to show (0 = no limit)")
+	output := flag.String("output", "text", "Output format: text, tsv, csv")
+	format := flag.String("format", "", "Go template applied to each MR (e.g. '{{.WebURL}}')")
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if err := lib.ValidateOutputFormat(*output); err != nil {
+		lib.Exit("Error", err)
+	}
+	switch *state {
+	case "opened", "closed", "merged", "all":
+	default:
+		lib.Usagef("--state must be opened, closed, merged or all")
+	}
+	if *limit < 0 {
+		lib.Usagef("--limit must not be negative")
+	}
+	roleList := lib.SplitList(*roles)
+	if *roles == "all" {
+		roleList = []string{lib.RoleAuthor, lib.RoleAssignee, lib.RoleReviewer}
+	}
+	if len(roleList) == 0 {
+		lib.Usagef("--roles needs at least one of author, assignee, reviewer")
+	}
+
+	var tmpl *lib.OutputTemplate
+	if *format != "" {
+		var err error
+		tmpl, err = lib.ParseOutputTemplate(*format)
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+	}
+
+	if *group == "" && flag.NArg() > 0 {
+		*group = flag.Arg(0)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	client := lib.NewClient(config)
+	mine, err := client.ListMyMRs(*group, roleList, lib.MRListOptions{State: *state, NotDraft: *skipDrafts, Limit: *limit})
+	if err != nil {
+		lib.Exit("Error listing MRs", err)
+	}
+
+	if *output != lib.OutputText {
+		header := append(append([]string{}, lib.MRTableHeader...), "roles")
+		var rows [][]string
+		for _, mr := range mine {
+			rows = append(rows, append(lib.MRTableRow(mr.MergeRequest), strings.Join(mr.Roles, ",")))
+		}
+		if err := lib.WriteTable(os.Stdout, *output, header, rows); err != nil {
+			lib.Exit("Error", err)
+		}
+		return
+	}
+
+	if tmpl != nil {
+		for _, mr := range mine {
+			if err := tmpl.Execute(os.Stdout, mr); err != nil {
+				lib.Exit("Error", err)
+			}
+		}
+		return
+	}
+
+	if ui.Quiet {
+		for _, mr := range mine {
+			fmt.Println(mr.WebURL)
+		}
+		return
+	}
+
+	scope := *group
+	if scope == "" {
+		scope = "all projects"
+	}
+	if len(mine) == 0 {
+		fmt.Printf("No %s MRs where you are %s (%s)\n", *state, strings.Join(roleList, " or "), scope)
+		return
+	}
+
+	fmt.Printf("Your %s MRs (%s):\n", *state, scope)
+	fmt.Println(strings.Repeat("-", 80))
+	for _, mr := range mine {
+		draftPrefix := ""
+		if mr.Draft {
+			draftPrefix = "[Draft] "
+		}
+		fmt.Printf("%s%s  %s%s\n", ui.StateIcon(mr.State), mr.References.Full, draftPrefix, mr.Title)
+		fmt.Printf("     %s  |  %s  |  @%s  |  updated %s\n",
+			ui.State(mr.State), strings.Join(mr.Roles, ", "), mr.Author.Username, lib.FormatAge(mr.UpdatedAt))
+		fmt.Printf("     %s\n", mr.WebURL)
+		fmt.Println()
+	}
+	fmt.Printf("Total: %d merge request(s)\n", len(mine))
+}
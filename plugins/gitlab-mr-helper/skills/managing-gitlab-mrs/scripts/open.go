@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	mrFlag := lib.RegisterMRFlag("Merge request IID, web URL or source branch (default: the MR of the current branch)")
+	pipeline := flag.String("pipeline", "", "Pipeline ID to open, or 'latest' for the newest pipeline of the MR or current branch")
+	jobID := flag.Int("job", 0, "Job ID to open")
+	noBrowser := flag.Bool("no-browser", false, "Only print the URL")
+	projectFlags := lib.RegisterProjectFlags()
+	lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *pipeline != "" && *jobID != 0 {
+		lib.Usagef("--pipeline and --job are mutually exclusive")
+	}
+	var pipelineID int
+	if *pipeline != "" && *pipeline != "latest" {
+		id, err := strconv.Atoi(*pipeline)
+		if err != nil || id <= 0 {
+			lib.Usagef("--pipeline must be a pipeline ID or 'latest'")
+		}
+		pipelineID = id
+	}
+	if *jobID < 0 {
+		lib.Usagef("--job must be a job ID")
+	}
+	// Without a target the MR of the current branch is opened, and the
+	// project page when there is no such MR
+	explicitMR := mrFlag.Value != "" || lib.MRArg() != 0
+	if explicitMR {
+		if err := mrFlag.Parse(); err != nil {
+			lib.Exit("Error", err)
+		}
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, _, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+
+	client := lib.NewClient(config)
+
+	// mr returns the MR named on the command line or, failing that, the one
+	// of the current branch; nil when the current branch has none
+	mr := func() *lib.MergeRequest {
+		var iid int
+		if explicitMR {
+			if iid, err = mrFlag.Resolve(client, projectPath); err != nil {
+				lib.Exit("Error finding MR", err)
+			}
+		} else {
+			branch, err := lib.GetCurrentBranch()
+			if err != nil {
+				return nil
+			}
+			found, err := client.FindMRByBranch(projectPath, branch)
+			if errors.Is(err, lib.ErrNotFound) {
+				return nil
+			}
+			if err != nil {
+				lib.Exit("Error finding MR", err)
+			}
+			iid = found.IID
+		}
+		// Only the single-MR endpoint has the head pipeline
+		mr, err := client.GetMR(projectPath, iid)
+		if err != nil {
+			lib.Exit("Error getting MR", err)
+		}
+		return mr
+	}
+
+	var target string
+	switch {
+	case *jobID != 0:
+		job, err := client.GetJob(projectPath, *jobID)
+		if err != nil {
+			lib.Exit("Error getting job", err)
+		}
+		target = job.WebURL
+	case pipelineID != 0:
+		p, err := client.GetPipeline(projectPath, pipelineID)
+		if err != nil {
+			lib.Exit("Error getting pipeline", err)
+		}
+		target = p.WebURL
+	case *pipeline == "latest":
+		target = latestPipelineURL(client, projectPath, mr())
+	default:
+		if m := mr(); m != nil {
+			target = m.WebURL
+			break
+		}
+		meta, err := client.GetProjectMeta(projectPath)
+		if err != nil {
+			lib.Exit("Error getting project", err)
+		}
+		target = meta.WebURL
+	}
+
+	fmt.Println(target)
+	if *noBrowser {
+		return
+	}
+	lib.OpenURLOrWarn(target)
+}
+
+// latestPipelineURL returns the URL of the MR's head pipeline or, without
+// an MR, of the newest pipeline of the current branch
+func latestPipelineURL(client *lib.Client, projectPath string, mr *lib.MergeRequest) string {
+	if mr != nil {
+		if mr.HeadPipeline == nil {
+			lib.Exit("Error", fmt.Errorf("%w: MR !%d has no pipeline", lib.ErrNotFound, mr.IID))
+		}
+		return mr.HeadPipeline.WebURL
+	}
+	branch, err := lib.GetCurrentBranch()
+	if err != nil {
+		lib.Usagef("--pipeline latest needs --mr outside a git checkout")
+	}
+	pipelines, err := client.ListPipelines(projectPath, &lib.PipelineListOptions{Ref: branch, Limit: 1})
+	if err != nil {
+		lib.Exit("Error listing pipelines", err)
+	}
+	if len(pipelines) == 0 {
+		lib.Exit("Error", fmt.Errorf("%w: no pipeline for branch %s", lib.ErrNotFound, branch))
+	}
+	return pipelines[0].WebURL
+}
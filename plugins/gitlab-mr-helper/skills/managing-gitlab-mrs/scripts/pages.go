@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	jobName := flag.String("job", lib.PagesJobName, "Name of the job that publishes the site")
+	jobs := flag.Int("jobs", 5, "Number of recent Pages jobs to show")
+	sha := flag.String("sha", "", "Verify the site was published from this commit, e.g. the merge commit of an MR")
+	check := flag.Bool("check", false, "Exit with code 1 when the site is not deployed, its latest job failed, or --sha was not published")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *jobs < 1 {
+		lib.Usagef("--jobs must be at least 1")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	var pages *lib.Pages
+	var pagesJobs []lib.Job
+	errs := client.ForEach(2, func(i int) error {
+		var err error
+		if i == 0 {
+			pages, err = client.GetPages(projectPath)
+			return err
+		}
+		pagesJobs, err = client.ListPagesJobs(projectPath, *jobName, 0)
+		return err
+	})
+	if err := errors.Join(errs...); err != nil {
+		lib.Exit("Error getting Pages", err)
+	}
+
+	var problems []string
+	if pages == nil {
+		problems = append(problems, "the site is not deployed")
+		ui.Printf("%s\n", ui.Warning("Pages has no deployment in "+projectPath+": it is disabled or nothing was published yet"))
+	} else {
+		if ui.Quiet {
+			fmt.Println(pages.URL)
+		}
+		ui.Printf("Pages site of %s: %s\n", projectPath, pages.URL)
+		ui.Printf("  HTTPS only: %s, unique domain: %s\n", yesNo(pages.ForceHTTPS), yesNo(pages.IsUniqueDomainEnabled))
+		for _, d := range pages.Deployments {
+			line := d.URL
+			if d.PathPrefix != "" {
+				line += fmt.Sprintf(" (parallel deployment %s)", d.PathPrefix)
+			}
+			ui.Printf("  deployed %s: %s, from %s/\n", lib.FormatAge(d.CreatedAt), line, d.RootDirectory)
+		}
+	}
+
+	if len(pagesJobs) == 0 {
+		ui.Printf("\nNo recent %s jobs; if another job publishes the site, name it with --job\n", *jobName)
+	} else {
+		ui.Printf("\nRecent %s jobs:\n", *jobName)
+		// A commit given with --sha is checked on its own below: a later
+		// failure leaves its deployment in place
+		if latest := pagesJobs[0]; *sha == "" && failed(latest.Status) {
+			problems = append(problems, fmt.Sprintf("the latest %s job %s", *jobName, latest.Status))
+		}
+	}
+	for i, j := range pagesJobs {
+		if i == *jobs {
+			break
+		}
+		ui.Printf("  %s\n", jobLine(ui, &j))
+	}
+
+	if *sha != "" {
+		ui.Printf("\n")
+		j := lib.PagesJobFor(pagesJobs, *sha)
+		switch {
+		case j == nil:
+			problems = append(problems, "no "+*jobName+" job ran for "+*sha)
+			ui.Printf("%s\n", ui.Failure(fmt.Sprintf("No recent %s job ran for %s: its pipeline did not run the job, or has not started", *jobName, *sha)))
+		case j.Status == "success" && !servedSince(pages, j.CreatedAt):
+			// The job succeeded, but pages:deploy has not replaced the site yet
+			problems = append(problems, fmt.Sprintf("the deployment of job %d for %s is not served yet", j.ID, *sha))
+			ui.Printf("%s\n", ui.Warning(fmt.Sprintf("%s was built by job %d %s, but the site still serves an older deployment", lib.ShortSHA(j.Pipeline.SHA), j.ID, lib.FormatAge(j.CreatedAt))))
+		case j.Status == "success":
+			ui.Printf("%s\n", ui.Success(fmt.Sprintf("%s was published by job %d %s", lib.ShortSHA(j.Pipeline.SHA), j.ID, lib.FormatAge(j.CreatedAt))))
+		case failed(j.Status):
+			problems = append(problems, fmt.Sprintf("job %d for %s %s", j.ID, *sha, j.Status))
+			ui.Printf("%s\n", ui.Failure(fmt.Sprintf("%s was not published: job %d %s (%s)", lib.ShortSHA(j.Pipeline.SHA), j.ID, j.Status, j.WebURL)))
+		default:
+			problems = append(problems, fmt.Sprintf("job %d for %s is %s", j.ID, *sha, j.Status))
+			ui.Printf("%s\n", ui.Warning(fmt.Sprintf("%s is not published yet: job %d is %s", lib.ShortSHA(j.Pipeline.SHA), j.ID, j.Status)))
+		}
+	}
+
+	if *check && len(problems) > 0 {
+		lib.Exit("Error", fmt.Errorf("%s in %s", problems[0], projectPath))
+	}
+}
+
+// jobLine describes a Pages job and its pipeline
+func jobLine(ui *lib.UI, j *lib.Job) string {
+	line := fmt.Sprintf("job %d %s on %s", j.ID, j.Status, j.Ref)
+	if p := j.Pipeline; p != nil {
+		line += fmt.Sprintf(", pipeline #%d (%s)", p.ID, lib.ShortSHA(p.SHA))
+	}
+	line += ", " + lib.FormatAge(j.CreatedAt)
+	switch {
+	case j.Status == "success":
+		return ui.Success(line)
+	case failed(j.Status):
+		return ui.Failure(line + ": " + j.WebURL)
+	}
+	return ui.Warning(line)
+}
+
+// servedSince reports whether the site's latest deployment was made after
+// t, e.g. by a job created at t
+func servedSince(pages *lib.Pages, t time.Time) bool {
+	if pages == nil {
+		return false
+	}
+	d := pages.LatestDeployment()
+	return d != nil && !d.CreatedAt.Before(t)
+}
+
+// failed reports whether a job status means the job did not publish
+func failed(status string) bool {
+	return status == "failed" || status == "canceled"
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+	if config.Offline || config.VCRMode != "" {
+		lib.Usagef("ping needs a live connection")
+	}
+
+	client := lib.NewClient(config)
+
+	inst, err := client.Preflight()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", ui.Failure(fmt.Sprintf("%s: %v", config.URL, err)))
+		var pfErr *lib.PreflightError
+		if errors.As(err, &pfErr) && pfErr.Diagnosis != nil {
+			fmt.Fprintf(os.Stderr, "  Cause: %v\n", pfErr.Err)
+			fmt.Fprintf(os.Stderr, "  Hint: %s\n", pfErr.Diagnosis.Hint)
+		}
+		lib.ExitWith(lib.ExitCode(err))
+	}
+
+	if ui.Quiet {
+		fmt.Println(inst.Version)
+		return
+	}
+	fmt.Printf("%s\n", ui.Success(fmt.Sprintf("GitLab %s at %s (%dms)", inst.Version, inst.URL, inst.Latency.Milliseconds())))
+	fmt.Printf("  User: @%s (%s)\n", inst.User.Username, inst.User.Name)
+	if config.Sudo != "" {
+		fmt.Printf("  Sudo: @%s\n", config.Sudo)
+		return
+	}
+	// Only personal access tokens have an expiry to report
+	if token, err := client.GetCurrentToken(); err == nil {
+		expiry := token.ExpiresAt.String()
+		if !token.ExpiresAt.IsZero() {
+			expiry += fmt.Sprintf(" (%dd)", token.ExpiresAt.DaysLeft(time.Now()))
+		}
+		fmt.Printf("  Token: %s, expires %s\n", token.Name, expiry)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+// checkFlags collects repeated --check values
+type checkFlags []string
+
+func (c *checkFlags) String() string     { return strings.Join(*c, ", ") }
+func (c *checkFlags) Set(v string) error { *c = append(*c, v); return nil }
+
+func main() {
+	// Flags
+	var checks checkFlags
+	mrFlag := lib.RegisterMRFlag("Merge request IID, web URL or source branch (required)")
+	flag.Var(&checks, "check", "Status row as 'Name=status' or 'Name=status:details' (repeatable)")
+	pipeline := flag.Bool("pipeline", false, "Add a Pipeline row from the MR's head pipeline")
+	coverage := flag.String("coverage", "", "Coverage row details, e.g. '+1.2% (84.0%)'")
+	size := flag.String("size", "", "Size row details, e.g. '12 files, +340/-20'")
+	title := flag.String("title", "Automation status", "Heading of the status comment")
+	id := flag.String("id", "", "Report ID, to keep several independent status comments on one MR")
+	reset := flag.Bool("reset", false, "Drop rows from the existing comment instead of merging with them")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Validate MR reference
+	if err := mrFlag.Parse(); err != nil {
+		lib.Exit("Error", err)
+	}
+
+	var rows []lib.StatusRow
+	for _, c := range checks {
+		name, value, ok := strings.Cut(c, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			lib.Usagef("invalid --check %q (expected Name=status[:details])", c)
+		}
+		status, details, _ := strings.Cut(value, ":")
+		rows = append(rows, lib.StatusRow{Name: strings.TrimSpace(name), Status: strings.TrimSpace(status), Details: strings.TrimSpace(details)})
+	}
+	if *coverage != "" {
+		rows = append(rows, lib.StatusRow{Name: "Coverage", Details: *coverage})
+	}
+	if *size != "" {
+		rows = append(rows, lib.StatusRow{Name: "Size", Details: *size})
+	}
+	if len(rows) == 0 && !*pipeline {
+		lib.Usagef("nothing to report (use --pipeline, --check, --coverage or --size)")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	mrIID, err := mrFlag.Resolve(client, projectPath)
+	if err != nil {
+		lib.Exit("Error finding MR", err)
+	}
+	mr, err := client.GetMR(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR", err)
+	}
+
+	if *pipeline {
+		row := lib.StatusRow{Name: "Pipeline", Status: "none", Details: "no pipeline for the head commit"}
+		if p := mr.HeadPipeline; p != nil {
+			row = lib.StatusRow{Name: "Pipeline", Status: p.Status, Details: fmt.Sprintf("[#%d](%s)", p.ID, p.WebURL)}
+		}
+		rows = append([]lib.StatusRow{row}, rows...)
+	}
+
+	// New rows are merged into our previous report unless --reset
+	existing, previous, err := client.FindStatusReport(projectPath, mr.IID, *id)
+	if err != nil {
+		lib.Exit("Error finding status comment", err)
+	}
+	report := &lib.StatusReport{ID: *id}
+	if existing != nil && !*reset {
+		report = previous
+	}
+
+	report.Title = *title
+	report.UpdatedAt = time.Now()
+	for _, row := range rows {
+		report.Set(row)
+	}
+
+	note, created, err := client.UpsertStatusReport(projectPath, mr.IID, report, false)
+	if err != nil {
+		lib.Exit("Error writing status comment", err)
+	}
+	action := "updated"
+	if created {
+		action = "posted"
+	}
+
+	noteURL := fmt.Sprintf("%s#note_%d", mr.WebURL, note.ID)
+	if ui.Quiet {
+		fmt.Println(noteURL)
+		return
+	}
+	fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Status comment %s on MR !%d (%d rows)", action, mr.IID, len(report.Rows))))
+	fmt.Printf("  URL: %s\n", noteURL)
+}
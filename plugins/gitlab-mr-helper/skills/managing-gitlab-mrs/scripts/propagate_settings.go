@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	group := flag.String("group", "", "Group whose projects to configure, subgroups included (required)")
+	templateFile := flag.String("template", "", "YAML settings template to apply (required)")
+	exclude := flag.String("exclude", "", "Skip the projects whose path matches this regular expression")
+	dryRun := flag.Bool("dry-run", false, "Show the differences without changing anything")
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *group == "" || *templateFile == "" {
+		lib.Usagef("--group and --template are required")
+	}
+	var skip *regexp.Regexp
+	if *exclude != "" {
+		var err error
+		if skip, err = regexp.Compile(*exclude); err != nil {
+			lib.Usagef("invalid --exclude pattern: %v", err)
+		}
+	}
+	tmpl, err := lib.LoadSettingsTemplate(*templateFile)
+	if err != nil {
+		lib.Exit("Error loading settings template", err)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	client := lib.NewClient(config)
+
+	all, err := client.ListGroupProjects(*group)
+	if err != nil {
+		lib.Exit("Error listing projects", err)
+	}
+	var projects []lib.Project
+	for _, p := range all {
+		if skip == nil || !skip.MatchString(p.PathWithNamespace) {
+			projects = append(projects, p)
+		}
+	}
+
+	// Each project is compared, then changed unless dry-running. A project
+	// that fails is reported and the run continues; the first failure
+	// decides the exit code.
+	changes := make([][]lib.SettingChange, len(projects))
+	errs := client.ForEach(len(projects), func(i int) error {
+		path := projects[i].PathWithNamespace
+		var err error
+		if changes[i], err = client.DiffSettings(path, tmpl); err != nil || *dryRun {
+			return err
+		}
+		return client.ApplySettings(path, changes[i])
+	})
+
+	prefix := ""
+	if *dryRun {
+		prefix = "[dry-run] "
+	}
+
+	var firstErr error
+	changed := 0
+	for i, p := range projects {
+		if len(changes[i]) > 0 {
+			changed++
+		}
+		if errs[i] != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.Failure(fmt.Sprintf("%s: %v", p.PathWithNamespace, errs[i])))
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+		}
+		if len(changes[i]) == 0 {
+			continue
+		}
+		if ui.Quiet {
+			fmt.Println(p.PathWithNamespace)
+			continue
+		}
+		header := fmt.Sprintf("%s: %d change(s)", p.PathWithNamespace, len(changes[i]))
+		if errs[i] == nil {
+			header = ui.Success(header)
+		}
+		fmt.Printf("%s%s\n", prefix, header)
+		for _, ch := range changes[i] {
+			if ch.Skipped != "" {
+				fmt.Printf("    ! %s: %s, skipped: %s\n", ch.Setting, ch.From, ch.Skipped)
+				continue
+			}
+			if ch.From == "" {
+				fmt.Printf("    + %s: %s\n", ch.Setting, ch.To)
+			} else {
+				fmt.Printf("    ~ %s: %s "+lib.Arrow+" %s\n", ch.Setting, ch.From, ch.To)
+			}
+		}
+	}
+
+	ui.Printf("\n%s%s: changed %d of %d project(s)\n", prefix, *group, changed, len(projects))
+
+	if firstErr != nil {
+		lib.ExitWith(lib.ExitCode(firstErr))
+	}
+}
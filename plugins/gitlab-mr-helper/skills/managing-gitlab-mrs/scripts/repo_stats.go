@@ -0,0 +1,164 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	since := flag.String("since", "90d", "Start of the activity period: YYYY-MM-DD, an RFC 3339 time, or e.g. 30d, 12w")
+	ref := flag.String("ref", "", "Branch whose commits make up the activity (default: the default branch)")
+	top := flag.Int("top", 10, "Show the N most active contributors (0 for all)")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	now := time.Now()
+	start, err := lib.ParseTimeBound(*since, now)
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+	if *top < 0 {
+		lib.Usagef("--top must not be negative")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	if *ref == "" {
+		if *ref, err = client.DefaultBranch(projectPath); err != nil {
+			lib.Exit("Error getting default branch", err)
+		}
+	}
+
+	var (
+		languages    []lib.LanguageShare
+		contributors []lib.Contributor
+		commits      []lib.Commit
+	)
+	errs := client.ForEach(3, func(i int) error {
+		var err error
+		switch i {
+		case 0:
+			languages, err = client.GetLanguages(projectPath)
+		case 1:
+			contributors, err = client.ListContributors(projectPath)
+		case 2:
+			commits, err = client.ListCommits(projectPath, &lib.CommitListOptions{RefName: *ref, Since: start})
+		}
+		return err
+	})
+	for i, what := range []string{"languages", "contributors", "commits"} {
+		if errs[i] != nil {
+			lib.Exit("Error getting "+what, errs[i])
+		}
+	}
+	activity := lib.AggregateCommits(commits, start, now)
+
+	counts := make([]int, len(activity.Authors))
+	for i, a := range activity.Authors {
+		counts[i] = a.Commits
+	}
+	busFactor := lib.BusFactor(counts)
+	if ui.Quiet {
+		fmt.Println(busFactor)
+		return
+	}
+
+	fmt.Printf("Languages of %s:\n", projectPath)
+	if len(languages) == 0 {
+		fmt.Println("  (none detected)")
+	}
+	width := 0
+	for _, l := range languages {
+		width = max(width, len(l.Name))
+	}
+	for _, l := range languages {
+		fmt.Printf("  %-*s  %5.1f%%\n", width, l.Name, l.Percent)
+	}
+
+	total := 0
+	allCounts := make([]int, len(contributors))
+	for i, c := range contributors {
+		total += c.Commits
+		allCounts[i] = c.Commits
+	}
+	// The contributors API only covers the default branch
+	fmt.Printf("\nContributors of all time: %d, %d commit(s), bus factor %d\n", len(contributors), total, lib.BusFactor(allCounts))
+	shown := contributors
+	if *top > 0 && len(shown) > *top {
+		shown = shown[:*top]
+	}
+	authors := activity.Authors
+	if *top > 0 && len(authors) > *top {
+		authors = authors[:*top]
+	}
+	width = 0
+	for _, c := range shown {
+		width = max(width, len(lib.FormatAuthor(c.Name, c.Email)))
+	}
+	for _, a := range authors {
+		width = max(width, len(lib.FormatAuthor(a.Name, a.Email)))
+	}
+	recent := make(map[string]bool)
+	for _, a := range activity.Authors {
+		recent[strings.ToLower(a.Email)] = true
+	}
+	for _, c := range shown {
+		share := 0
+		if total > 0 {
+			share = c.Commits * 100 / total
+		}
+		idle := ""
+		if !recent[strings.ToLower(c.Email)] {
+			idle = "  (no commits in the period)"
+		}
+		fmt.Printf("  %-*s  %5d commit(s) %3d%%  +%d/-%d%s\n", width, lib.FormatAuthor(c.Name, c.Email), c.Commits, share, c.Additions, c.Deletions, idle)
+	}
+	if len(shown) < len(contributors) {
+		fmt.Printf("  ... and %d more\n", len(contributors)-len(shown))
+	}
+
+	fmt.Printf("\nActivity on %s since %s: %d commit(s) by %d author(s), bus factor %d\n",
+		*ref, start.Format("2006-01-02"), activity.Commits, len(activity.Authors), busFactor)
+	if activity.Commits == 0 {
+		return
+	}
+	// Half a year of weeks still fits on a line
+	weeks := activity.Weeks[max(0, len(activity.Weeks)-26):]
+	perWeek := make([]string, len(weeks))
+	for i, n := range weeks {
+		perWeek[i] = fmt.Sprint(n)
+	}
+	fmt.Printf("  Commits per week, last %d week(s), oldest first: %s\n", len(weeks), strings.Join(perWeek, " "))
+	for _, a := range authors {
+		fmt.Printf("  %-*s  %5d commit(s), last %s\n", width, lib.FormatAuthor(a.Name, a.Email), a.Commits, lib.FormatAge(a.Last))
+	}
+	if len(authors) < len(activity.Authors) {
+		fmt.Printf("  ... and %d more\n", len(activity.Authors)-len(authors))
+	}
+	if busFactor == 1 {
+		fmt.Printf("%s\n", ui.Warning(fmt.Sprintf("%s made most of the recent commits", activity.Authors[0].Name)))
+	}
+}
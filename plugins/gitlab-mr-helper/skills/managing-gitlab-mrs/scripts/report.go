@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	milestone := flag.String("milestone", "", "Report on this milestone")
+	label := flag.String("label", "", "Report on the issues and MRs with this label")
+	since := flag.String("since", "7d", "Start of the period: YYYY-MM-DD, an RFC 3339 time, or e.g. 7d, 2w")
+	blockedLabel := flag.String("blocked-label", "blocked", "Label marking blocked issues and MRs")
+	ref := flag.String("ref", "", "Branch whose failed pipelines to report (default: the default branch)")
+	maxFailures := flag.Int("max-failures", 5, "Maximum number of failed pipelines to list")
+	projectFlags := lib.RegisterProjectFlags()
+	lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if (*milestone == "") == (*label == "") {
+		lib.Usagef("exactly one of --milestone and --label is required")
+	}
+	now := time.Now()
+	start, err := lib.ParseTimeBound(*since, now)
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path; the report goes to stdout, so notes go to stderr
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		fmt.Fprintf(os.Stderr, "Project: %s\n", projectPath)
+	}
+
+	client := lib.NewClient(config)
+	report := &lib.ProgressReport{Since: start, Until: now, BlockedLabel: *blockedLabel}
+	issueOpts := &lib.IssueListOptions{State: "all"}
+	mrOpts := &lib.MRListOptions{State: "all"}
+	if *milestone != "" {
+		if report.Milestone, err = client.GetMilestone(projectPath, *milestone); err != nil {
+			lib.Exit("Error", err)
+		}
+		report.Scope = "Milestone " + *milestone
+		issueOpts.Milestone, mrOpts.Milestone = *milestone, *milestone
+	} else {
+		report.Scope = "Label ~" + *label
+		issueOpts.Labels, mrOpts.Labels = []string{*label}, []string{*label}
+	}
+
+	if report.Issues, err = client.ListProjectIssues(projectPath, issueOpts); err != nil {
+		lib.Exit("Error listing issues", err)
+	}
+	if report.MRs, err = client.ListProjectMRs(projectPath, mrOpts); err != nil {
+		lib.Exit("Error listing MRs", err)
+	}
+
+	if *ref == "" {
+		if *ref, err = client.DefaultBranch(projectPath); err != nil {
+			lib.Exit("Error", err)
+		}
+	}
+	pipelines, err := client.ListPipelines(projectPath, &lib.PipelineListOptions{Ref: *ref, Status: "failed", UpdatedAfter: start, Limit: *maxFailures})
+	if err != nil {
+		lib.Exit("Error listing pipelines", err)
+	}
+	for _, p := range pipelines {
+		jobs, err := client.ListPipelineJobs(projectPath, p.ID, "failed")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cannot list the failed jobs of pipeline #%d: %v\n", p.ID, err)
+		}
+		// Jobs allowed to fail did not fail the pipeline
+		failure := lib.PipelineFailure{Pipeline: p}
+		for _, j := range jobs {
+			if !j.AllowFailure {
+				failure.Jobs = append(failure.Jobs, j)
+			}
+		}
+		report.Failures = append(report.Failures, failure)
+	}
+
+	fmt.Print(report.Markdown())
+}
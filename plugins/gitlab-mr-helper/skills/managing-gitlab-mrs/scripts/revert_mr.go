@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	mrFlag := lib.RegisterMRFlag("Merged MR to revert: IID, web URL or source branch (required)")
+	branch := flag.String("branch", "", "Branch for the revert (default: revert-mr-<iid>)")
+	reason := flag.String("reason", "", "Why the MR is reverted, added to the revert MR description")
+	labels := flag.String("labels", "", "Comma-separated labels for the revert MR")
+	comment := flag.Bool("comment", true, "Link the revert MR from a comment on the original MR (--comment=false to skip)")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Validate MR reference
+	if err := mrFlag.Parse(); err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	mrIID, err := mrFlag.Resolve(client, projectPath)
+	if err != nil {
+		lib.Exit("Error finding MR", err)
+	}
+	orig, err := client.GetMR(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR", err)
+	}
+	if orig.State != "merged" {
+		lib.Exit("Error", fmt.Errorf("%w: MR !%d is %s; only merged MRs can be reverted", lib.ErrBlocked, orig.IID, orig.State))
+	}
+
+	// Squash-and-merge without a merge commit leaves only the squash commit;
+	// fast-forward merges of several commits have no single commit to revert
+	sha := orig.MergeCommitSHA
+	if sha == "" {
+		sha = orig.SquashCommitSHA
+	}
+	if sha == "" {
+		lib.Exit("Error", fmt.Errorf("%w: MR !%d has no merge or squash commit (fast-forward merge); revert its commits locally", lib.ErrBlocked, orig.IID))
+	}
+
+	revertBranch := *branch
+	if revertBranch == "" {
+		revertBranch = fmt.Sprintf("revert-mr-%d", orig.IID)
+	}
+
+	// Running again after a partial failure returns the existing revert MR
+	existing, err := client.FindOpenMR(projectPath, revertBranch, orig.TargetBranch)
+	if err != nil {
+		lib.Exit("Error checking for existing MR", err)
+	}
+	if existing != nil {
+		if ui.Quiet {
+			fmt.Println(existing.WebURL)
+			return
+		}
+		fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Revert MR !%d already exists for !%d", existing.IID, orig.IID)))
+		fmt.Printf("  URL: %s\n", existing.WebURL)
+		return
+	}
+
+	ui.Printf("Reverting !%d (%s) merge commit %s\n", orig.IID, orig.Title, lib.ShortSHA(sha))
+	ui.Printf("  Branch: %s (from %s)\n", revertBranch, orig.TargetBranch)
+	if _, err := client.CreateBranch(projectPath, revertBranch, orig.TargetBranch); err != nil {
+		lib.Exit("Error creating branch", err)
+	}
+	if _, err := client.RevertCommit(projectPath, sha, revertBranch); err != nil {
+		// Don't leave an empty branch behind
+		if derr := client.DeleteBranch(projectPath, revertBranch); derr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not delete branch %s: %v\n", revertBranch, derr)
+		}
+		lib.Exit("Error reverting commit", err)
+	}
+
+	var labelList []string
+	if *labels != "" {
+		labelList = lib.SplitList(*labels)
+	}
+
+	desc := fmt.Sprintf("Reverts !%d (%s).\n\nThis reverts merge commit %s.", orig.IID, orig.Title, sha)
+	if *reason != "" {
+		desc += "\n\n**Reason:** " + *reason
+	}
+	mr, err := client.CreateMR(projectPath, &lib.CreateMRRequest{
+		SourceBranch:       revertBranch,
+		TargetBranch:       orig.TargetBranch,
+		Title:              fmt.Sprintf("Revert %q", orig.Title),
+		Description:        desc,
+		Labels:             labelList,
+		RemoveSourceBranch: true,
+	})
+	if err != nil {
+		lib.Exit("Error creating MR", err)
+	}
+
+	if *comment {
+		if _, err := client.CreateMRNote(projectPath, orig.IID, fmt.Sprintf("Reverted by !%d", mr.IID)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not comment on !%d: %v\n", orig.IID, err)
+		}
+	}
+
+	if ui.Quiet {
+		fmt.Println(mr.WebURL)
+		return
+	}
+
+	fmt.Printf("\n%s\n", ui.Success(fmt.Sprintf("Revert MR !%d created for !%d", mr.IID, orig.IID)))
+	fmt.Printf("  URL: %s\n", mr.WebURL)
+	fmt.Printf("  State: %s\n", ui.State(mr.State))
+}
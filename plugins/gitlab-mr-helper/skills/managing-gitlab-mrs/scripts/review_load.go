@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	group := flag.String("group", "", "Group whose open MRs to count, subgroups included (required)")
+	includeDrafts := flag.Bool("include-drafts", false, "Count draft MRs too")
+	pending := flag.Bool("pending", false, "Count only the MRs each reviewer has not approved yet (one more request per MR)")
+	reviewersOnly := flag.Bool("reviewers-only", false, "Leave out group members who review nothing")
+	over := flag.Int("over", 5, "Flag reviewers with more MRs than this as overloaded (0 to disable)")
+	showMRs := flag.Bool("mrs", false, "List each reviewer's MRs")
+	output := flag.String("output", "text", "Output format: text, tsv, csv")
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *group == "" && flag.NArg() > 0 {
+		*group = flag.Arg(0)
+	}
+	if *group == "" {
+		lib.Usagef("--group is required")
+	}
+	if *over < 0 {
+		lib.Usagef("--over must not be negative")
+	}
+	if err := lib.ValidateOutputFormat(*output); err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	client := lib.NewClient(config)
+	var all []lib.MergeRequest
+	var team []lib.Member
+	errs := client.ForEach(2, func(i int) error {
+		var err error
+		if i == 0 {
+			all, err = client.ListGroupMRs(*group, &lib.MRListOptions{State: "opened", Scope: "all"})
+			return err
+		}
+		team, err = client.ListDirectMembers(lib.MemberScope{Path: *group, Group: true})
+		return err
+	})
+	if err := errors.Join(errs...); err != nil {
+		lib.Exit("Error listing MRs and members", err)
+	}
+
+	var mrs []lib.MergeRequest
+	for _, mr := range all {
+		if len(mr.Reviewers) > 0 && (!mr.Draft || *includeDrafts) {
+			mrs = append(mrs, mr)
+		}
+	}
+	if *pending {
+		mrs = withoutApproved(client, mrs)
+	}
+	if *reviewersOnly {
+		team = nil
+	}
+	loads := lib.ReviewLoads(mrs, team)
+
+	now := time.Now()
+	if *output != lib.OutputText {
+		header := []string{"username", "name", "member", "mrs", "oldest_days", "median_days"}
+		var rows [][]string
+		for _, l := range loads {
+			oldest := ""
+			if len(l.MRs) > 0 {
+				oldest = strconv.Itoa(int(now.Sub(l.Oldest()).Hours() / 24))
+			}
+			rows = append(rows, []string{l.User.Username, l.User.Name, strconv.FormatBool(l.Member), strconv.Itoa(len(l.MRs)),
+				oldest, strconv.Itoa(int(l.MedianAge(now).Hours() / 24))})
+		}
+		if err := lib.WriteTable(os.Stdout, *output, header, rows); err != nil {
+			lib.Exit("Error", err)
+		}
+		return
+	}
+
+	// Least loaded first, so the first line is who to ask next
+	if ui.Quiet {
+		for i := len(loads) - 1; i >= 0; i-- {
+			if loads[i].Member {
+				fmt.Println(loads[i].User.Username)
+			}
+		}
+		return
+	}
+
+	if len(loads) == 0 {
+		fmt.Printf("No open MRs awaiting review in %s\n", *group)
+		return
+	}
+	fmt.Printf("Review load in %s (%d open MR(s) with reviewers):\n", *group, len(mrs))
+	overloaded := 0
+	for _, l := range loads {
+		line := fmt.Sprintf("@%-16s %3d MR(s)", l.User.Username, len(l.MRs))
+		if len(l.MRs) > 0 {
+			line += fmt.Sprintf(", oldest opened %s, median age %s", lib.FormatAge(l.Oldest()), ageText(l.MedianAge(now)))
+		}
+		if !l.Member {
+			line += " (not a group member)"
+		}
+		switch {
+		case *over > 0 && len(l.MRs) > *over:
+			overloaded++
+			fmt.Printf("  %s\n", ui.Failure(line))
+		case len(l.MRs) == 0:
+			fmt.Printf("  %s\n", ui.Success(line))
+		default:
+			fmt.Printf("    %s\n", line)
+		}
+		if !*showMRs {
+			continue
+		}
+		for _, mr := range l.MRs {
+			fmt.Printf("      %s %s (%s, by @%s)\n", mr.References.Full, mr.Title, lib.FormatAge(mr.CreatedAt), mr.Author.Username)
+		}
+	}
+
+	if overloaded > 0 {
+		var idle []string
+		for _, l := range loads {
+			if l.Member && len(l.MRs) <= *over/2 {
+				idle = append(idle, "@"+l.User.Username)
+			}
+		}
+		sort.Strings(idle)
+		fmt.Printf("\n%d reviewer(s) over %d MRs", overloaded, *over)
+		if len(idle) > 0 {
+			fmt.Printf("; room for more: %s", strings.Join(idle, ", "))
+		}
+		fmt.Println()
+	}
+}
+
+// withoutApproved drops from each MR the reviewers who already approved
+// it, and the MRs left without reviewers
+func withoutApproved(client *lib.Client, mrs []lib.MergeRequest) []lib.MergeRequest {
+	approvals := make([]*lib.Approvals, len(mrs))
+	errs := client.ForEach(len(mrs), func(i int) error {
+		var err error
+		approvals[i], err = client.GetMRApprovals(strconv.Itoa(mrs[i].ProjectID), mrs[i].IID)
+		return err
+	})
+	var out []lib.MergeRequest
+	for i, mr := range mrs {
+		if errs[i] != nil {
+			fmt.Fprintf(os.Stderr, "Warning: counting all reviewers of %s: %v\n", mr.References.Full, errs[i])
+			out = append(out, mr)
+			continue
+		}
+		var waiting []lib.User
+		for _, r := range mr.Reviewers {
+			if !approvals[i].HasApproved(r.ID) {
+				waiting = append(waiting, r)
+			}
+		}
+		if len(waiting) > 0 {
+			mr.Reviewers = waiting
+			out = append(out, mr)
+		}
+	}
+	return out
+}
+
+// ageText renders how long an MR has been open, e.g. "5h" or "3d"
+func ageText(d time.Duration) string {
+	if d < 24*time.Hour {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	group := flag.String("group", "", "Group path to search (default: all projects visible to you)")
+	includeDrafts := flag.Bool("include-drafts", false, "Include draft MRs")
+	limit := flag.Int("limit", 0, "Maximum number of MRs to show (0 = no limit)")
+	output := flag.String("output", "text", "Output format: text, tsv, csv")
+	format := flag.String("format", "", "Go template applied to each MR (e.g. '{{.WebURL}}')")
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if err := lib.ValidateOutputFormat(*output); err != nil {
+		lib.Exit("Error", err)
+	}
+
+	var tmpl *lib.OutputTemplate
+	if *format != "" {
+		var err error
+		tmpl, err = lib.ParseOutputTemplate(*format)
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+	}
+
+	if *group == "" && flag.NArg() > 0 {
+		*group = flag.Arg(0)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	client := lib.NewClient(config)
+	me, err := client.GetCurrentUser()
+	if err != nil {
+		lib.Exit("Error getting current user", err)
+	}
+
+	queue, skipped, err := client.ReviewQueue(me, &lib.ReviewQueueOptions{Group: *group, IncludeDrafts: *includeDrafts, Limit: *limit})
+	if err != nil {
+		lib.Exit("Error listing MRs", err)
+	}
+	for _, s := range skipped {
+		fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", s.MR.References.Full, s.Err)
+	}
+
+	if *output != lib.OutputText {
+		if err := lib.WriteTable(os.Stdout, *output, lib.MRTableHeader, lib.MRTableRows(queue)); err != nil {
+			lib.Exit("Error", err)
+		}
+		return
+	}
+
+	if tmpl != nil {
+		for _, mr := range queue {
+			if err := tmpl.Execute(os.Stdout, mr); err != nil {
+				lib.Exit("Error", err)
+			}
+		}
+		return
+	}
+
+	if ui.Quiet {
+		for _, mr := range queue {
+			fmt.Println(mr.WebURL)
+		}
+		return
+	}
+
+	scope := *group
+	if scope == "" {
+		scope = "all projects"
+	}
+
+	if len(queue) == 0 {
+		fmt.Println(ui.Success(fmt.Sprintf("Review queue empty for @%s (%s)", me.Username, scope)))
+		return
+	}
+
+	fmt.Printf("Review queue for @%s (%s):\n", me.Username, scope)
+	fmt.Println(strings.Repeat("-", 80))
+
+	for _, mr := range queue {
+		draftPrefix := ""
+		if mr.Draft {
+			draftPrefix = "[Draft] "
+		}
+
+		fmt.Printf("%s  %s%s\n", mr.References.Full, draftPrefix, mr.Title)
+		fmt.Printf("     @%s  |  opened %s  |  updated %s\n",
+			mr.Author.Username, lib.FormatAge(mr.CreatedAt), lib.FormatAge(mr.UpdatedAt))
+		fmt.Printf("     %s\n", mr.WebURL)
+		fmt.Println()
+	}
+
+	fmt.Printf("Total: %d merge request(s) awaiting your review\n", len(queue))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	expires := flag.String("expires", "90d", "Expiry of the new token: YYYY-MM-DD or <days>d")
+	force := flag.Bool("force", false, "Rotate a token taken from GITLAB_TOKEN, printing the new value to update it by hand")
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	expiresAt, err := lib.ParseExpiry(*expires, time.Now())
+	if err != nil {
+		lib.Usagef("%v", err)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+	if config.Offline || config.VCRMode != "" {
+		lib.Usagef("rotating a token needs a live connection")
+	}
+	if config.Sudo != "" {
+		lib.Usagef("--sudo cannot be used to rotate a token")
+	}
+	// The old token is revoked as soon as GitLab rotates it, so a token we
+	// cannot write back must be replaced by hand
+	if config.TokenSource == lib.TokenSourceEnv && !*force {
+		lib.Exit("Error", fmt.Errorf("%w: the token comes from GITLAB_TOKEN and cannot be updated in place; rerun with --force and update the variable yourself", lib.ErrBlocked))
+	}
+
+	client := lib.NewClient(config)
+
+	current, err := client.GetCurrentToken()
+	if err != nil {
+		lib.Exit("Error getting current token", err)
+	}
+	token, err := client.RotateCurrentToken(expiresAt)
+	if err != nil {
+		lib.Exit("Error rotating token", err)
+	}
+
+	stored := config.TokenSource != lib.TokenSourceEnv
+	if stored {
+		if err := lib.UpdateStoredToken(config.TokenSource, config.Token, token.Token); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			stored = false
+		}
+	}
+
+	if ui.Quiet {
+		// Never lose the only copy of the new token
+		if !stored {
+			fmt.Println(token.Token)
+		} else {
+			fmt.Println(token.ID)
+		}
+		return
+	}
+	fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Token %s rotated (#%d "+lib.Arrow+" #%d)", current.Name, current.ID, token.ID)))
+	fmt.Printf("  Expires: %s\n", token.ExpiresAt)
+	if stored {
+		fmt.Printf("  Updated: %s\n", config.TokenSource)
+		return
+	}
+	fmt.Printf("  Token: %s\n", token.Token)
+	msg := "Store the token now; the old one no longer works and this one cannot be shown again."
+	if config.TokenSource == lib.TokenSourceEnv {
+		msg = "Update GITLAB_TOKEN now; the old token no longer works and this one cannot be shown again."
+	}
+	fmt.Fprintln(os.Stderr, msg)
+}
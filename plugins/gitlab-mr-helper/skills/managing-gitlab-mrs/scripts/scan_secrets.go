@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	mrFlag := lib.RegisterMRFlag("Scan the diff of this merge request (IID, web URL or source branch) instead of the local branch")
+	target := flag.String("target", "main", "Scan the local commits not on this branch (git diff TARGET...HEAD)")
+	staged := flag.Bool("staged", false, "Scan the staged changes (git diff --cached) instead of the local commits")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if mrFlag.Value != "" && *staged {
+		lib.Usagef("--mr and --staged are mutually exclusive")
+	}
+
+	settings, err := lib.LoadSettings()
+	if err != nil {
+		lib.Exit("Error loading settings", err)
+	}
+	scanner, err := lib.NewSecretScanner(&settings.Secrets)
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Local changes need no GitLab access
+	var findings []lib.SecretFinding
+	var scanned string
+	switch {
+	case mrFlag.Value != "":
+		findings, scanned = scanMR(mrFlag, projectFlags, ui, scanner)
+	case *staged:
+		diffs, err := lib.LocalDiff("--cached")
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+		findings, scanned = scanner.ScanDiffs(diffs), "staged changes"
+	default:
+		diffs, err := lib.LocalDiff(*target + "...HEAD")
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+		findings, scanned = scanner.ScanDiffs(diffs), "commits not on "+*target
+	}
+
+	if len(findings) == 0 {
+		ui.Printf("%s\n", ui.Success("No likely secrets in "+scanned))
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Secrets: %d likely credential(s) in %s:\n", len(findings), scanned)
+	for _, f := range findings {
+		fmt.Fprintf(os.Stderr, "  "+lib.Bullet+" %s\n", f)
+	}
+	fmt.Fprintf(os.Stderr, "Remove and rotate them, or mark false positives with %q or the secrets settings\n", lib.AllowSecretMarker)
+	lib.Exit("Error", fmt.Errorf("%w: likely secrets found", lib.ErrBlocked))
+}
+
+// scanMR scans the diff, title and description of a merge request
+func scanMR(mrFlag *lib.MRFlag, projectFlags *lib.ProjectFlags, ui *lib.UI, scanner *lib.SecretScanner) ([]lib.SecretFinding, string) {
+	if err := mrFlag.Parse(); err != nil {
+		lib.Exit("Error", err)
+	}
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+	projectPath, detected, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	mrIID, err := mrFlag.Resolve(client, projectPath)
+	if err != nil {
+		lib.Exit("Error finding MR", err)
+	}
+	mr, err := client.GetMR(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR", err)
+	}
+	diffs, err := client.GetMRDiffs(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR diffs", err)
+	}
+	lib.WarnPartialScan(diffs)
+	return scanner.ScanMR(diffs, mr.Title, mr.Description), fmt.Sprintf("MR !%d", mrIID)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	listen := flag.String("listen", ":8090", "Address to listen on")
+	path := flag.String("path", "/webhook", "URL path receiving webhooks")
+	secret := flag.String("secret", "", "Secret token configured on the GitLab webhook (default: $GITLAB_WEBHOOK_SECRET)")
+	rulesFile := flag.String("rules", "", "YAML ruleset run on every event (default: "+lib.RulesFileName+" at the repository root, if any)")
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Read after parsing so -h does not print the secret as the default
+	if *secret == "" {
+		*secret = os.Getenv("GITLAB_WEBHOOK_SECRET")
+	}
+
+	settings, err := lib.LoadSettings()
+	if err != nil {
+		lib.Exit("Error loading settings", err)
+	}
+	rules, err := lib.LoadRules(*rulesFile)
+	if err != nil {
+		lib.Exit("Error loading rules", err)
+	}
+	// Rules act through the API; without any, no token is needed
+	var client *lib.Client
+	if len(rules.Rules) > 0 {
+		config, err := lib.GetConfig()
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+		client = lib.NewClient(config)
+		fmt.Fprintf(os.Stderr, "Loaded %d rule(s)\n", len(rules.Rules))
+	}
+	if *secret == "" {
+		fmt.Fprintln(os.Stderr, "Warning: no --secret set; any client can post events")
+	}
+
+	// Events are printed and handed to hooks by a single worker so the HTTP
+	// response is not held up and hooks run in arrival order
+	events := make(chan *lib.WebhookEvent, 100)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		enc := json.NewEncoder(os.Stdout)
+		for ev := range events {
+			if !ui.Quiet {
+				if err := enc.Encode(ev); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to write event: %v\n", err)
+				}
+			}
+			for _, hook := range settings.Serve.HooksFor(ev) {
+				if err := lib.RunEventHook(hook, ev); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				}
+			}
+			if client != nil {
+				applied, err := rules.Apply(client, ev)
+				if len(applied) > 0 {
+					fmt.Fprintf(os.Stderr, "Rules applied to %s:%s event: %s\n", ev.Kind, ev.Action, strings.Join(applied, ", "))
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: rules: %v\n", err)
+				}
+			}
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle(*path, lib.WebhookHandler(*secret, func(ev *lib.WebhookEvent) {
+		select {
+		case events <- ev:
+		default:
+			fmt.Fprintf(os.Stderr, "Warning: event queue full, dropping %s event for %s\n", ev.Kind, ev.Project)
+		}
+	}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+
+	server := &http.Server{Addr: *listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// ListenAndServe returns as soon as Shutdown starts; shutdownDone is
+	// closed once the handlers have returned and can no longer queue events
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: shutdown: %v\n", err)
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "Listening for GitLab webhooks on %s%s\n", *listen, *path)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		lib.Exit("Error", err)
+	}
+
+	// Let queued hooks finish before exiting
+	<-shutdownDone
+	close(events)
+	<-done
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	issueIID := flag.Int("issue", 0, "Show the thread of this Service Desk issue, or reply to it")
+	reply := flag.String("reply", "", "Reply to --issue; GitLab emails the reply to the external author")
+	replyFile := flag.String("reply-file", "", "Read the reply from a file ('-' for stdin)")
+	internal := flag.Bool("internal", false, "Post the reply as an internal note, which is not emailed")
+	closeIssue := flag.Bool("close", false, "Close --issue after replying")
+	state := flag.String("state", "opened", "Issues to list: opened, closed, all")
+	waiting := flag.Bool("waiting", false, "Only list the issues awaiting a reply")
+	limit := flag.Int("limit", 20, "List at most N issues (0 for all)")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *reply != "" && *replyFile != "" {
+		lib.Usagef("--reply and --reply-file are mutually exclusive")
+	}
+	text := *reply
+	if *replyFile != "" {
+		var err error
+		if text, err = lib.ReadTextFile(*replyFile); err != nil {
+			lib.Exit("Error", err)
+		}
+		if strings.TrimSpace(text) == "" {
+			lib.Usagef("reply file %s is empty", *replyFile)
+		}
+	}
+	if (text != "" || *internal || *closeIssue) && *issueIID <= 0 {
+		lib.Usagef("--reply, --reply-file, --internal and --close need --issue")
+	}
+	if *internal && text == "" {
+		lib.Usagef("--internal goes with --reply or --reply-file")
+	}
+	if *state != "opened" && *state != "closed" && *state != "all" {
+		lib.Usagef("invalid --state %q (expected opened, closed or all)", *state)
+	}
+	if *limit < 0 {
+		lib.Usagef("--limit must not be negative")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	switch {
+	case *issueIID > 0 && (text != "" || *closeIssue):
+		replyTo(client, ui, projectPath, *issueIID, text, *internal, *closeIssue)
+	case *issueIID > 0:
+		showThread(client, projectPath, *issueIID)
+	default:
+		listIssues(client, ui, projectPath, *state, *waiting, *limit)
+	}
+}
+
+// listIssues lists the Service Desk issues of a project, with whether
+// each one is awaiting a reply
+func listIssues(client *lib.Client, ui *lib.UI, projectPath, state string, waiting bool, limit int) {
+	sd, err := client.GetServiceDesk(projectPath)
+	if err != nil {
+		lib.Exit("Error getting Service Desk settings", err)
+	}
+	issues, err := client.ListServiceDeskIssues(projectPath, &lib.IssueListOptions{State: state, Limit: limit})
+	if err != nil {
+		lib.Exit("Error listing issues", err)
+	}
+	notes := make([][]lib.Note, len(issues))
+	errs := client.ForEach(len(issues), func(i int) error {
+		var err error
+		if notes[i], err = client.ListIssueNotes(projectPath, issues[i].IID); err != nil {
+			return fmt.Errorf("issue #%d: %w", issues[i].IID, err)
+		}
+		return nil
+	})
+	if err := errors.Join(errs...); err != nil {
+		lib.Exit("Error getting comments", err)
+	}
+
+	awaiting := 0
+	for i := range issues {
+		if issues[i].State == "opened" && lib.AwaitingReply(notes[i]) {
+			awaiting++
+		}
+	}
+	if !sd.Enabled {
+		ui.Printf("%s\n", ui.Warning("Service Desk is disabled for "+projectPath))
+	} else {
+		ui.Printf("Service Desk of %s: %s\n", projectPath, sd.Address)
+	}
+	ui.Printf("%d %s issue(s), %d awaiting a reply:\n", len(issues), map[string]string{"opened": "open", "closed": "closed", "all": "Service Desk"}[state], awaiting)
+	for i, issue := range issues {
+		open := issue.State == "opened"
+		if waiting && !(open && lib.AwaitingReply(notes[i])) {
+			continue
+		}
+		if ui.Quiet {
+			fmt.Println(issue.IID)
+			continue
+		}
+		status := lastReply(notes[i])
+		if !open {
+			status = "closed"
+			if issue.ClosedAt != nil {
+				status += " " + lib.FormatAge(*issue.ClosedAt)
+			}
+		}
+		fmt.Printf("  #%-4d %s\n", issue.IID, issue.Title)
+		fmt.Printf("         from %s, opened %s, %d comment(s), %s\n", sender(&issue), lib.FormatAge(issue.CreatedAt), issue.UserNotesCount, status)
+	}
+}
+
+// lastReply says who spoke last on a Service Desk issue
+func lastReply(notes []lib.Note) string {
+	for i := len(notes) - 1; i >= 0; i-- {
+		n := notes[i]
+		if n.System || n.Internal {
+			continue
+		}
+		if n.Author.Username == lib.SupportBot {
+			return "awaiting a reply since " + lib.FormatAge(n.CreatedAt)
+		}
+		return fmt.Sprintf("answered by %s %s", n.Author.Name, lib.FormatAge(n.CreatedAt))
+	}
+	return "not answered yet"
+}
+
+// showThread prints a Service Desk issue with its email and comments
+func showThread(client *lib.Client, projectPath string, iid int) {
+	issue, err := client.GetIssue(projectPath, iid)
+	if err != nil {
+		lib.Exit("Error getting issue", err)
+	}
+	notes, err := client.ListIssueNotes(projectPath, iid)
+	if err != nil {
+		lib.Exit("Error getting comments", err)
+	}
+
+	flags := []string{issue.State}
+	if issue.Confidential {
+		flags = append(flags, "confidential")
+	}
+	fmt.Printf("#%d %s (%s)\n", issue.IID, issue.Title, strings.Join(flags, ", "))
+	fmt.Printf("From: %s\n", sender(issue))
+	fmt.Printf("URL: %s\n", issue.WebURL)
+	if issue.Author.Username != lib.SupportBot {
+		fmt.Println("Not a Service Desk issue: replies are not emailed to anyone outside the project")
+	}
+	if desc := strings.TrimSpace(issue.Description); desc != "" {
+		fmt.Printf("\n%s, %s:\n", sender(issue), lib.FormatAge(issue.CreatedAt))
+		printBody(desc)
+	}
+	for _, n := range notes {
+		if n.System {
+			continue
+		}
+		who := n.Author.Name
+		if n.Author.Username == lib.SupportBot {
+			who = sender(issue)
+		}
+		if n.Internal {
+			who += " [internal]"
+		}
+		fmt.Printf("\n%s, %s:\n", who, lib.FormatAge(n.CreatedAt))
+		printBody(n.Body)
+	}
+	if issue.State == "opened" && lib.AwaitingReply(notes) {
+		fmt.Printf("\nAwaiting a reply: go run scripts/service_desk.go --issue %d --reply \"...\"\n", issue.IID)
+	}
+}
+
+// replyTo comments on a Service Desk issue and closes it if asked
+func replyTo(client *lib.Client, ui *lib.UI, projectPath string, iid int, text string, internal, closeIssue bool) {
+	issue, err := client.GetIssue(projectPath, iid)
+	if err != nil {
+		lib.Exit("Error getting issue", err)
+	}
+	external := issue.Author.Username == lib.SupportBot
+	if text != "" {
+		var note *lib.Note
+		if internal {
+			note, err = client.CreateInternalIssueNote(projectPath, iid, text)
+		} else {
+			note, err = client.CreateIssueNote(projectPath, iid, text)
+		}
+		if err != nil {
+			lib.Exit("Error replying", err)
+		}
+		if ui.Quiet {
+			fmt.Printf("%s#note_%d\n", issue.WebURL, note.ID)
+		}
+		switch {
+		case internal:
+			ui.Printf("%s\n", ui.Success(fmt.Sprintf("Added an internal note to #%d (not emailed)", iid)))
+		case external:
+			ui.Printf("%s\n", ui.Success(fmt.Sprintf("Replied on #%d; GitLab emails the reply to %s", iid, sender(issue))))
+		default:
+			ui.Printf("%s\n", ui.Success(fmt.Sprintf("Commented on #%d", iid)))
+			ui.Printf("%s\n", ui.Warning("Not a Service Desk issue: nobody outside the project was emailed"))
+		}
+	}
+	if closeIssue {
+		if issue.State != "opened" {
+			ui.Printf("#%d is already %s\n", iid, issue.State)
+		} else if _, err := client.UpdateIssue(projectPath, iid, &lib.UpdateIssueRequest{StateEvent: "close"}); err != nil {
+			lib.Exit("Error closing issue", err)
+		} else {
+			ui.Printf("%s\n", ui.Success(fmt.Sprintf("Closed #%d", iid)))
+		}
+	}
+	if ui.Quiet && text == "" {
+		fmt.Println(issue.WebURL)
+	}
+	ui.Printf("  URL: %s\n", issue.WebURL)
+}
+
+// sender is the external author of a Service Desk issue, or the GitLab
+// author of other issues
+func sender(issue *lib.Issue) string {
+	if issue.ServiceDeskReplyTo != "" {
+		return issue.ServiceDeskReplyTo
+	}
+	return issue.Author.Name
+}
+
+// printBody indents a comment under its heading
+func printBody(body string) {
+	for _, line := range strings.Split(strings.TrimRight(body, "\n"), "\n") {
+		fmt.Printf("  %s\n", line)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	description := flag.String("description", "", "Set the description")
+	descriptionFile := flag.String("description-file", "", "Set the description from a file (- for stdin)")
+	topics := flag.String("topics", "", "Replace the topics with these, comma-separated")
+	clearTopics := flag.Bool("clear-topics", false, "Remove every topic")
+	addTopics := flag.String("add-topics", "", "Add these comma-separated topics, keeping the others")
+	removeTopics := flag.String("remove-topics", "", "Remove these comma-separated topics")
+	avatar := flag.String("avatar", "", "Use this image file as avatar")
+	visibility := flag.String("visibility", "", "Set the visibility: private, internal or public")
+	group := flag.String("group", "", "Change every project of this group, subgroups included, instead")
+	exclude := flag.String("exclude", "", "With --group, skip the projects whose path matches this regular expression")
+	dryRun := flag.Bool("dry-run", false, "Show the differences without changing anything")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *description != "" && *descriptionFile != "" {
+		lib.Usagef("--description and --description-file are mutually exclusive")
+	}
+	if *topics != "" && *clearTopics {
+		lib.Usagef("--topics and --clear-topics are mutually exclusive")
+	}
+	switch *visibility {
+	case "", lib.VisibilityPrivate, lib.VisibilityInternal, lib.VisibilityPublic:
+	default:
+		lib.Usagef("--visibility must be private, internal or public")
+	}
+	var skip *regexp.Regexp
+	if *exclude != "" {
+		if *group == "" {
+			lib.Usagef("--exclude needs --group")
+		}
+		var err error
+		if skip, err = regexp.Compile(*exclude); err != nil {
+			lib.Usagef("invalid --exclude pattern: %v", err)
+		}
+	}
+
+	tmpl := &lib.ProjectMetaTemplate{AddTopics: lib.SplitList(*addTopics), RemoveTopics: lib.SplitList(*removeTopics), Visibility: *visibility, Avatar: *avatar}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "description" {
+			tmpl.Description = description
+		}
+	})
+	if *descriptionFile != "" {
+		text, err := lib.ReadTextFile(*descriptionFile)
+		if err != nil {
+			lib.Exit("Error reading description", err)
+		}
+		text = strings.TrimSpace(text)
+		tmpl.Description = &text
+	}
+	if *topics != "" || *clearTopics {
+		tmpl.Topics = append([]string{}, lib.SplitList(*topics)...)
+	}
+	if *avatar != "" {
+		if _, err := os.Stat(*avatar); err != nil {
+			lib.Exit("Error reading avatar", err)
+		}
+	}
+	changing := tmpl.Description != nil || tmpl.Topics != nil || len(tmpl.AddTopics) > 0 || len(tmpl.RemoveTopics) > 0 || tmpl.Visibility != "" || tmpl.Avatar != ""
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	client := lib.NewClient(config)
+
+	var paths []string
+	if *group != "" {
+		projects, err := client.ListGroupProjects(*group)
+		if err != nil {
+			lib.Exit("Error listing projects", err)
+		}
+		for _, p := range projects {
+			if skip == nil || !skip.MatchString(p.PathWithNamespace) {
+				paths = append(paths, p.PathWithNamespace)
+			}
+		}
+	} else {
+		projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+		if err != nil {
+			lib.Exit("Error resolving project", err)
+		}
+		if detected {
+			ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+		}
+		paths = []string{projectPath}
+	}
+
+	if !changing {
+		showMeta(client, ui, paths)
+		return
+	}
+
+	// Only the fields that differ are sent, so projects that already carry
+	// the description, topics and avatar are left untouched
+	changes := make([][]lib.SettingChange, len(paths))
+	errs := client.ForEach(len(paths), func(i int) error {
+		var err error
+		if changes[i], err = client.DiffProjectMeta(paths[i], tmpl); err != nil || *dryRun {
+			return err
+		}
+		return client.ApplySettings(paths[i], changes[i])
+	})
+
+	prefix := ""
+	if *dryRun {
+		prefix = "[dry-run] "
+	}
+
+	var firstErr error
+	changed := 0
+	for i, path := range paths {
+		if len(changes[i]) > 0 {
+			changed++
+		}
+		if errs[i] != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.Failure(fmt.Sprintf("%s: %v", path, errs[i])))
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+		}
+		if len(changes[i]) == 0 {
+			continue
+		}
+		if ui.Quiet {
+			fmt.Println(path)
+			continue
+		}
+		header := fmt.Sprintf("%s: %d change(s)", path, len(changes[i]))
+		if errs[i] == nil {
+			header = ui.Success(header)
+		}
+		fmt.Printf("%s%s\n", prefix, header)
+		for _, ch := range changes[i] {
+			if ch.Setting == "description" {
+				fmt.Printf("    ~ %s: %s "+lib.Arrow+" %s\n", ch.Setting, quoted(ch.From), quoted(ch.To))
+			} else {
+				fmt.Printf("    ~ %s: %s "+lib.Arrow+" %s\n", ch.Setting, orNone(ch.From), orNone(ch.To))
+			}
+		}
+	}
+
+	if *group != "" {
+		ui.Printf("\n%s%s: changed %d of %d project(s)\n", prefix, *group, changed, len(paths))
+	} else if changed == 0 && firstErr == nil {
+		ui.Printf("%s already has this metadata\n", paths[0])
+	}
+
+	if firstErr != nil {
+		lib.ExitWith(lib.ExitCode(firstErr))
+	}
+}
+
+// showMeta prints the metadata of projects
+func showMeta(client *lib.Client, ui *lib.UI, paths []string) {
+	metas := make([]*lib.ProjectMeta, len(paths))
+	errs := client.ForEach(len(paths), func(i int) error {
+		var err error
+		metas[i], err = client.GetProjectMeta(paths[i])
+		return err
+	})
+	var firstErr error
+	for i, m := range metas {
+		if errs[i] != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.Failure(fmt.Sprintf("%s: %v", paths[i], errs[i])))
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+		if ui.Quiet {
+			fmt.Println(m.PathWithNamespace)
+			continue
+		}
+		fmt.Printf("%s (%s)\n", m.PathWithNamespace, m.Visibility)
+		fmt.Printf("    description: %s\n", quoted(m.Description))
+		fmt.Printf("    topics: %s\n", orNone(strings.Join(m.Topics, ", ")))
+		fmt.Printf("    avatar: %s\n", orNone(m.AvatarURL))
+	}
+	if firstErr != nil {
+		lib.ExitWith(lib.ExitCode(firstErr))
+	}
+}
+
+// quoted shows a description on one line, or "none"
+func quoted(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return fmt.Sprintf("%q", firstLine(s))
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// firstLine shortens multi-line text to its first line
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i] + " " + lib.Ellipsis
+	}
+	return s
+}
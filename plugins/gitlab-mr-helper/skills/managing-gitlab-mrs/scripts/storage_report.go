@@ -0,0 +1,172 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	group := flag.String("group", "", "Group whose projects to report, subgroups included (required)")
+	sortBy := flag.String("sort", "storage", "Sort projects by: "+strings.Join(lib.StorageColumns, ", "))
+	top := flag.Int("top", 0, "Only show the N largest projects (0 for all)")
+	quota := flag.String("quota", "", "Storage quota to compare against, e.g. 10GB (default: the namespace's quota, if GitLab enforces one)")
+	warn := flag.Int("warn", 80, "Flag the namespace when it uses at least this percentage of its quota")
+	check := flag.Bool("check", false, "Exit with code 1 when the namespace reaches --warn percent of its quota")
+	output := flag.String("output", "text", "Output format: text, tsv, csv")
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *group == "" {
+		lib.Usagef("--group is required")
+	}
+	if err := lib.ValidateOutputFormat(*output); err != nil {
+		lib.Exit("Error", err)
+	}
+	var limit int64
+	if *quota != "" {
+		var err error
+		if limit, err = lib.ParseSize(*quota); err != nil {
+			lib.Exit("Error", err)
+		}
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	client := lib.NewClient(config)
+
+	projects, err := client.ListGroupProjectStatistics(*group)
+	if err != nil {
+		lib.Exit("Error listing projects", err)
+	}
+	if err := lib.SortProjectsByStorage(projects, *sortBy); err != nil {
+		lib.Exit("Error", err)
+	}
+	var total lib.ProjectStatistics
+	for _, p := range projects {
+		if p.Statistics != nil {
+			total.Add(*p.Statistics)
+		}
+	}
+	shown := projects
+	if *top > 0 && len(shown) > *top {
+		shown = shown[:*top]
+	}
+
+	// The quota applies to the top-level namespace, which may hold more
+	// than --group
+	ns, err := client.GetNamespaceStorage(*group)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot read the namespace quota: %v\n", err)
+		root, _, _ := strings.Cut(*group, "/")
+		ns = &lib.NamespaceStorage{FullPath: root}
+		if root == *group {
+			ns.StorageSize = total.StorageSize
+		}
+	}
+	if limit == 0 {
+		limit = ns.Limit
+	}
+	percent := 0
+	if limit > 0 {
+		percent = int(ns.StorageSize * 100 / limit)
+	}
+	over := limit > 0 && percent >= *warn
+
+	switch {
+	case *output != lib.OutputText:
+		header := append([]string{"project"}, lib.StorageColumns...)
+		var rows [][]string
+		for _, p := range shown {
+			stats := lib.ProjectStatistics{}
+			if p.Statistics != nil {
+				stats = *p.Statistics
+			}
+			row := []string{p.PathWithNamespace}
+			for _, col := range lib.StorageColumns {
+				row = append(row, strconv.FormatInt(stats.Column(col), 10))
+			}
+			rows = append(rows, row)
+		}
+		if err := lib.WriteTable(os.Stdout, *output, header, rows); err != nil {
+			lib.Exit("Error", err)
+		}
+	case ui.Quiet:
+		for _, p := range shown {
+			fmt.Println(p.PathWithNamespace)
+		}
+	default:
+		printReport(ui, *group, shown, projects, total, ns, limit, percent, over)
+	}
+
+	if *check && over {
+		lib.Exit("Error", fmt.Errorf("namespace %s uses %d%% of its storage quota", ns.FullPath, percent))
+	}
+}
+
+// printReport prints the storage table, the group totals and the namespace
+// quota usage
+func printReport(ui *lib.UI, group string, shown, projects []lib.Project, total lib.ProjectStatistics, ns *lib.NamespaceStorage, limit int64, percent int, over bool) {
+	if len(projects) == 0 {
+		fmt.Printf("No projects in %s\n", group)
+		return
+	}
+	width := len("Total")
+	for _, p := range shown {
+		width = max(width, len(p.PathWithNamespace))
+	}
+	row := func(name string, stats lib.ProjectStatistics) {
+		fmt.Printf("%-*s", width, name)
+		for _, col := range lib.StorageColumns {
+			fmt.Printf("  %10s", lib.FormatSize(stats.Column(col)))
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("Storage of %s:\n", group)
+	fmt.Printf("%-*s", width, "Project")
+	for _, col := range lib.StorageColumns {
+		heading := strings.ToUpper(col[:1]) + col[1:]
+		if col == "lfs" {
+			heading = "LFS"
+		}
+		fmt.Printf("  %10s", heading)
+	}
+	fmt.Println()
+	fmt.Println(strings.Repeat("-", width+12*len(lib.StorageColumns)))
+	for _, p := range shown {
+		if p.Statistics == nil {
+			fmt.Printf("%-*s  (no statistics: Reporter role needed)\n", width, p.PathWithNamespace)
+			continue
+		}
+		row(p.PathWithNamespace, *p.Statistics)
+	}
+	if len(shown) < len(projects) {
+		fmt.Printf("... and %d smaller project(s)\n", len(projects)-len(shown))
+	}
+	fmt.Println(strings.Repeat("-", width+12*len(lib.StorageColumns)))
+	row("Total", total)
+
+	fmt.Printf("\nTotal: %d project(s), %s\n", len(projects), lib.FormatSize(total.StorageSize))
+	if limit == 0 {
+		fmt.Printf("Namespace %s: %s used, no storage quota enforced (use --quota to compare against one)\n", ns.FullPath, lib.FormatSize(ns.StorageSize))
+		return
+	}
+	usage := fmt.Sprintf("%s of %s (%d%%)", lib.FormatSize(ns.StorageSize), lib.FormatSize(limit), percent)
+	if over {
+		usage = ui.Failure(usage)
+	}
+	fmt.Printf("Namespace %s: %s\n", ns.FullPath, usage)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	mrFlag := lib.RegisterMRFlag("Merge request IID, web URL or source branch (required)")
+	top := flag.Int("top", 3, "Number of reviewers to suggest")
+	days := flag.Int("days", 365, "Only count commits from the last N days (0 for all history)")
+	perFile := flag.Int("per-file", 20, "Most recent commits to read per changed file")
+	maxFiles := flag.Int("max-files", 50, "Most changed files to read the history of")
+	assign := flag.Bool("assign", false, "Add the suggested project members as reviewers of the MR")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Validate MR reference
+	if err := mrFlag.Parse(); err != nil {
+		lib.Exit("Error", err)
+	}
+	if *top < 1 {
+		lib.Usagef("--top must be at least 1")
+	}
+	if *days < 0 || *perFile < 1 || *maxFiles < 1 {
+		lib.Usagef("--days must not be negative, --per-file and --max-files must be at least 1")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	mrIID, err := mrFlag.Resolve(client, projectPath)
+	if err != nil {
+		lib.Exit("Error finding MR", err)
+	}
+	mr, err := client.GetMR(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR", err)
+	}
+	diffs, err := client.GetMRDiffs(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR changes", err)
+	}
+	members, err := client.ListProjectMembers(projectPath)
+	if err != nil {
+		lib.Exit("Error listing project members", err)
+	}
+
+	// History of each changed file on the target branch; deleted files
+	// only exist under their old path
+	var paths []string
+	for _, d := range diffs {
+		if d.DeletedFile {
+			paths = append(paths, d.OldPath)
+		} else {
+			paths = append(paths, d.NewPath)
+		}
+	}
+	if len(paths) > *maxFiles {
+		fmt.Fprintf(os.Stderr, "Warning: reading the history of %d of %d changed files (--max-files)\n", *maxFiles, len(paths))
+		paths = paths[:*maxFiles]
+	}
+	var since time.Time
+	if *days > 0 {
+		since = time.Now().AddDate(0, 0, -*days)
+	}
+	commits := make([][]lib.Commit, len(paths))
+	errs := client.ForEach(len(paths), func(i int) error {
+		var err error
+		commits[i], err = client.ListCommits(projectPath, &lib.CommitListOptions{RefName: mr.TargetBranch, Path: paths[i], Since: since, Limit: *perFile})
+		return err
+	})
+	history := make(map[string][]lib.Commit)
+	for i, path := range paths {
+		if errs[i] != nil {
+			lib.Exit("Error listing commits of "+path, errs[i])
+		}
+		history[path] = commits[i]
+	}
+
+	suggestions := lib.SuggestReviewers(history, members, []string{mr.Author.Username})
+	if len(suggestions) > *top {
+		suggestions = suggestions[:*top]
+	}
+	if len(suggestions) == 0 {
+		ui.Printf("No past contributors to the %d changed file(s) of !%d\n", len(paths), mrIID)
+		return
+	}
+
+	isReviewer := make(map[string]bool)
+	for _, r := range mr.Reviewers {
+		isReviewer[r.Username] = true
+	}
+	var add []lib.User
+	ui.Printf("Suggested reviewers for !%d (%d changed file(s)):\n", mrIID, len(paths))
+	for i, s := range suggestions {
+		if s.User != nil && !isReviewer[s.User.Username] {
+			add = append(add, *s.User)
+		}
+		if ui.Quiet {
+			if s.User != nil {
+				fmt.Println(s.User.Username)
+			}
+			continue
+		}
+		who := s.Name + " (not a project member)"
+		if s.User != nil {
+			who = fmt.Sprintf("@%s %s", s.User.Username, s.User.Name)
+			if isReviewer[s.User.Username] {
+				who += " (already a reviewer)"
+			}
+		}
+		fmt.Printf("  %d. %s: %d commit(s), %d file(s), last %s\n", i+1, who, s.Commits, s.Files, s.LastCommit.Format("2006-01-02"))
+	}
+
+	if !*assign {
+		return
+	}
+	if len(add) == 0 {
+		ui.Printf("\nNo reviewers to add\n")
+		return
+	}
+	ids := make([]int, 0, len(mr.Reviewers)+len(add))
+	names := make([]string, len(add))
+	for _, r := range mr.Reviewers {
+		ids = append(ids, r.ID)
+	}
+	for i, u := range add {
+		ids = append(ids, u.ID)
+		names[i] = "@" + u.Username
+	}
+	if _, err := client.UpdateMR(projectPath, mrIID, &lib.UpdateMRRequest{ReviewerIDs: ids}); err != nil {
+		lib.Exit("Error adding reviewers", err)
+	}
+	ui.Printf("\n%s\n", ui.Success("Reviewers added: "+strings.Join(names, " ")))
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	state := flag.String("state", "opened", "MR state to cache: opened, closed, merged, all")
+	limit := flag.Int("limit", 100, "Maximum MRs to cache per project (0 for all)")
+	pipelines := flag.Int("pipelines", 20, "Recent pipelines to cache per project (0 to skip)")
+	discussions := flag.Bool("discussions", true, "Cache the discussion threads of each MR (--discussions=false to skip)")
+	notify := flag.Bool("notify", false, "Post a summary to the configured Slack/Mattermost webhook when done")
+	full := flag.Bool("full", false, "Fetch everything again instead of only what changed since the last sync")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+	if config.Offline {
+		lib.Usagef("sync.go refreshes the cache and cannot run with --offline")
+	}
+
+	var notifier *lib.Notifier
+	if *notify {
+		settings, err := lib.LoadSettings()
+		if err != nil {
+			lib.Exit("Error loading settings", err)
+		}
+		notifier = lib.NewNotifier(settings.Notify)
+		if notifier == nil {
+			lib.Usagef("--notify needs notify.webhook_url in settings or GITLAB_NOTIFY_WEBHOOK")
+		}
+	}
+
+	// Get project paths
+	projects := flag.Args()
+	if len(projects) == 0 || projectFlags.Project != "" || projectFlags.Auto {
+		projectPath, detected, err := projectFlags.Resolve("")
+		if err != nil {
+			lib.Exit("Error resolving project", err)
+		}
+		if detected {
+			ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+		}
+		projects = append([]string{projectPath}, projects...)
+	}
+
+	client := lib.NewClient(config)
+	opts := syncOptions{state: *state, limit: *limit, pipelines: *pipelines, discussions: *discussions, full: *full}
+
+	// One failing project does not stop the others; the first error decides
+	// the exit code
+	var firstErr error
+	var summary []string
+	for _, projectPath := range projects {
+		pc, changed, err := syncProject(client, config.URL, projectPath, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.Failure(fmt.Sprintf("%s: %v", projectPath, err)))
+			summary = append(summary, fmt.Sprintf("%s: %v", projectPath, err))
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		path, err := pc.Save()
+		if err != nil {
+			lib.Exit("Error saving cache", err)
+		}
+
+		threads := 0
+		for _, d := range pc.Discussions {
+			threads += len(d)
+		}
+		line := fmt.Sprintf("%s: %d MRs, %d discussions, %d pipelines", projectPath, len(pc.MRs), threads, len(pc.Pipelines))
+		if changed >= 0 {
+			line += fmt.Sprintf(" (%d MRs changed since the last sync)", changed)
+		}
+		summary = append(summary, line)
+		if ui.Quiet {
+			fmt.Println(path)
+			continue
+		}
+		fmt.Printf("%s\n", ui.Success(line))
+		fmt.Printf("  Cache: %s\n", path)
+	}
+
+	if notifier != nil {
+		title := fmt.Sprintf("sync.go finished (%d projects)", len(projects))
+		if firstErr != nil {
+			title = fmt.Sprintf("sync.go failed (%d projects)", len(projects))
+		}
+		if err := notifier.Send(lib.Notification{Title: title, Text: strings.Join(summary, "\n"), Success: firstErr == nil}); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	if firstErr != nil {
+		lib.ExitWith(lib.ExitCode(firstErr))
+	}
+}
+
+type syncOptions struct {
+	state       string
+	limit       int
+	pipelines   int
+	discussions bool
+	full        bool
+}
+
+// syncOverlap is how far before the last sync a delta sync reaches, for
+// clock skew and updates committed while it ran
+const syncOverlap = time.Minute
+
+// syncProject fetches the cached data of one project. When the project was
+// synced before with the same filters, only what changed since is fetched
+// and merged into its cache, and the number of changed MRs is returned;
+// otherwise it is -1.
+func syncProject(client *lib.Client, baseURL, projectPath string, opts syncOptions) (*lib.ProjectCache, int, error) {
+	if !opts.full {
+		prev, err := lib.LoadProjectCache(baseURL, projectPath)
+		if err == nil && prev.State == opts.state && prev.Limit == opts.limit && (prev.Discussions != nil || !opts.discussions) {
+			changed, complete, err := deltaSync(client, prev, opts)
+			if err != nil || complete {
+				return prev, changed, err
+			}
+		}
+	}
+	pc, err := fullSync(client, baseURL, projectPath, opts)
+	return pc, -1, err
+}
+
+// fullSync fetches the cached data of one project
+func fullSync(client *lib.Client, baseURL, projectPath string, opts syncOptions) (*lib.ProjectCache, error) {
+	pc := &lib.ProjectCache{
+		Project:  projectPath,
+		URL:      baseURL,
+		SyncedAt: time.Now().UTC(),
+		State:    opts.state,
+		Limit:    opts.limit,
+	}
+
+	var err error
+	pc.MRs, err = client.ListProjectMRs(projectPath, &lib.MRListOptions{State: opts.state, Limit: opts.limit})
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.discussions {
+		pc.Discussions = make(map[int][]lib.Discussion)
+		iids := make([]int, len(pc.MRs))
+		for i, mr := range pc.MRs {
+			iids[i] = mr.IID
+		}
+		if err := syncDiscussions(client, pc, iids); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.pipelines > 0 {
+		pc.Pipelines, err = client.ListPipelines(projectPath, &lib.PipelineListOptions{Limit: opts.pipelines})
+		if err != nil {
+			// Projects without CI (or without access to it) still get MRs cached
+			fmt.Fprintf(os.Stderr, "Warning: could not sync pipelines of %s: %v\n", projectPath, err)
+		}
+	}
+	return pc, nil
+}
+
+// deltaSync merges what changed since pc was synced into it and returns
+// the number of changed MRs, and whether the cache is complete or needs a
+// full sync
+func deltaSync(client *lib.Client, pc *lib.ProjectCache, opts syncOptions) (int, bool, error) {
+	since := pc.SyncedAt.Add(-syncOverlap)
+	now := time.Now().UTC()
+
+	changed, err := client.ListProjectMRs(pc.Project, &lib.MRListOptions{State: "all", UpdatedAfter: since})
+	if err != nil {
+		return 0, false, err
+	}
+	updated, complete := pc.MergeMRs(changed, opts.state, opts.limit)
+	if !complete {
+		return 0, false, nil
+	}
+	if opts.discussions {
+		if err := syncDiscussions(client, pc, updated); err != nil {
+			return 0, false, err
+		}
+	}
+
+	if opts.pipelines > 0 {
+		pipelines, err := client.ListPipelines(pc.Project, &lib.PipelineListOptions{UpdatedAfter: since, Limit: opts.pipelines})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not sync pipelines of %s: %v\n", pc.Project, err)
+		} else {
+			pc.MergePipelines(pipelines, opts.pipelines)
+		}
+	}
+	pc.SyncedAt = now
+	return len(updated), true, nil
+}
+
+// syncDiscussions fetches the discussions of the given MRs into pc
+func syncDiscussions(client *lib.Client, pc *lib.ProjectCache, iids []int) error {
+	discussions := make([][]lib.Discussion, len(iids))
+	errs := client.ForEach(len(iids), func(i int) error {
+		var err error
+		discussions[i], err = client.ListMRDiscussions(pc.Project, iids[i])
+		return err
+	})
+	for i, iid := range iids {
+		if errs[i] != nil {
+			return fmt.Errorf("discussions of !%d: %w", iid, errs[i])
+		}
+		pc.Discussions[iid] = discussions[i]
+	}
+	return nil
+}
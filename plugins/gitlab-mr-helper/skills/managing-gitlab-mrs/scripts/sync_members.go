@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	file := flag.String("file", "", "YAML membership file: usernames and their roles (required)")
+	group := flag.String("group", "", "Sync the members of this group instead of a project")
+	keepUnlisted := flag.Bool("keep-unlisted", false, "Keep the members the file does not list instead of removing them")
+	dryRun := flag.Bool("dry-run", false, "Show the differences without changing anything")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	if *file == "" {
+		lib.Usagef("--file is required")
+	}
+	want, err := lib.LoadMembership(*file)
+	if err != nil {
+		lib.Exit("Error loading membership file", err)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	scope := lib.MemberScope{Path: *group, Group: true}
+	if *group == "" {
+		projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+		if err != nil {
+			lib.Exit("Error resolving project", err)
+		}
+		if detected {
+			ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+		}
+		scope = lib.MemberScope{Path: projectPath}
+	}
+
+	client := lib.NewClient(config)
+	changes, err := client.PlanMembership(scope, want, *keepUnlisted)
+	if err != nil {
+		lib.Exit("Error comparing members", err)
+	}
+
+	prefix := ""
+	if *dryRun {
+		prefix = "[dry-run] "
+	}
+	if len(changes) == 0 {
+		ui.Printf("%s\n", ui.Success(fmt.Sprintf("Members of %s match %s", scope, *file)))
+		return
+	}
+
+	// Changes are made in order, additions first; a failed change is
+	// reported and the rest still made. The first failure decides the exit
+	// code.
+	var firstErr error
+	applied := 0
+	ui.Printf("%sMembers of %s against %s:\n", prefix, scope, *file)
+	for _, ch := range changes {
+		var line string
+		switch ch.Action() {
+		case "add":
+			line = fmt.Sprintf("+ @%s %s", ch.Username, lib.AccessLevelName(ch.To))
+		case "remove":
+			line = fmt.Sprintf("- @%s (%s)", ch.Username, lib.AccessLevelName(ch.From))
+		default:
+			line = fmt.Sprintf("~ @%s %s "+lib.Arrow+" %s (%s)", ch.Username, lib.AccessLevelName(ch.From), lib.AccessLevelName(ch.To), ch.Action())
+		}
+		if *dryRun {
+			if ui.Quiet {
+				fmt.Println(ch.Username)
+			} else {
+				fmt.Printf("  %s\n", line)
+			}
+			continue
+		}
+		if err := client.ApplyMembershipChange(scope, ch); err != nil {
+			fmt.Fprintf(os.Stderr, "  %s\n", ui.Failure(fmt.Sprintf("%s: %v", line, err)))
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		applied++
+		if ui.Quiet {
+			fmt.Println(ch.Username)
+		} else {
+			fmt.Printf("  %s\n", ui.Success(line))
+		}
+	}
+
+	if *dryRun {
+		ui.Printf("\n%s%d change(s) to make\n", prefix, len(changes))
+	} else {
+		ui.Printf("\nMade %d of %d change(s)\n", applied, len(changes))
+	}
+
+	if firstErr != nil {
+		lib.ExitWith(lib.ExitCode(firstErr))
+	}
+}
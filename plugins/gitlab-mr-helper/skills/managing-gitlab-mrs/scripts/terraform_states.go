@@ -0,0 +1,168 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	state := flag.String("state", "", "Show this state's lock, latest version and backend address")
+	unlock := flag.String("unlock", "", "Force-unlock this state, whoever holds the lock")
+	lock := flag.String("lock", "", "Lock this state, so Terraform runs wait until it is unlocked")
+	stale := flag.Duration("stale", time.Hour, "Flag locks held longer than this as possibly stuck")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	actions := 0
+	for _, set := range []bool{*state != "", *unlock != "", *lock != ""} {
+		if set {
+			actions++
+		}
+	}
+	if actions > 1 {
+		lib.Usagef("--state, --unlock and --lock are mutually exclusive")
+	}
+	if *stale <= 0 {
+		lib.Usagef("--stale must be positive")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	now := time.Now()
+	switch {
+	case *unlock != "":
+		s, err := client.FindTerraformState(projectPath, *unlock)
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+		if !s.Locked() {
+			ui.Printf("%s is not locked\n", s.Name)
+			return
+		}
+		if err := client.UnlockTerraformState(s.ID); err != nil {
+			lib.Exit("Error unlocking "+s.Name, err)
+		}
+		if ui.Quiet {
+			fmt.Println(s.Name)
+			return
+		}
+		fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Unlocked %s, locked by %s %s", s.Name, lockHolder(s), lib.FormatAge(*s.LockedAt))))
+		if now.Sub(*s.LockedAt) < *stale {
+			fmt.Printf("%s\n", ui.Warning("The lock was recent: if that Terraform run is still going, two runs can now write the state at once"))
+		}
+
+	case *lock != "":
+		s, err := client.FindTerraformState(projectPath, *lock)
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+		if s.Locked() {
+			lib.Exit("Error", fmt.Errorf("%w: %s is already locked by %s %s", lib.ErrBlocked, s.Name, lockHolder(s), lib.FormatAge(*s.LockedAt)))
+		}
+		if err := client.LockTerraformState(s.ID); err != nil {
+			lib.Exit("Error locking "+s.Name, err)
+		}
+		if ui.Quiet {
+			fmt.Println(s.Name)
+			return
+		}
+		fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Locked %s; Terraform runs wait until it is unlocked with --unlock %s", s.Name, s.Name)))
+
+	case *state != "":
+		s, err := client.FindTerraformState(projectPath, *state)
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+		if ui.Quiet {
+			fmt.Println(client.TerraformStateAddress(projectPath, s.Name))
+			return
+		}
+		fmt.Printf("Terraform state %s of %s\n", s.Name, projectPath)
+		fmt.Printf("  Lock: %s\n", lockText(s, now, *stale))
+		fmt.Printf("  Latest version: %s\n", versionText(s))
+		fmt.Printf("  Backend address: %s\n", client.TerraformStateAddress(projectPath, s.Name))
+		fmt.Printf("  Lock address: %s/lock\n", client.TerraformStateAddress(projectPath, s.Name))
+
+	default:
+		states, err := client.ListTerraformStates(projectPath)
+		if err != nil {
+			lib.Exit("Error listing Terraform states", err)
+		}
+		if len(states) == 0 {
+			ui.Printf("No Terraform states stored in %s\n", projectPath)
+			return
+		}
+		locked := 0
+		ui.Printf("Terraform states of %s:\n", projectPath)
+		for i := range states {
+			s := &states[i]
+			if s.Locked() {
+				locked++
+				if ui.Quiet {
+					fmt.Println(s.Name)
+				}
+			}
+			ui.Printf("  %s\n", s.Name)
+			ui.Printf("      lock: %s\n", lockText(s, now, *stale))
+			ui.Printf("      latest version: %s\n", versionText(s))
+		}
+		ui.Printf("\n%d state(s), %d locked\n", len(states), locked)
+	}
+}
+
+// lockText describes the lock of a state, flagging old ones
+func lockText(s *lib.TerraformState, now time.Time, stale time.Duration) string {
+	if !s.Locked() {
+		return "unlocked"
+	}
+	text := fmt.Sprintf("locked by %s %s", lockHolder(s), lib.FormatAge(*s.LockedAt))
+	if now.Sub(*s.LockedAt) >= stale {
+		text += fmt.Sprintf(" (possibly stuck: --unlock %s once no run uses it)", s.Name)
+	}
+	return text
+}
+
+// lockHolder names the user holding a state's lock
+func lockHolder(s *lib.TerraformState) string {
+	if s.LockedBy == "" {
+		return "an unknown user"
+	}
+	return "@" + s.LockedBy
+}
+
+// versionText describes the latest version of a state
+func versionText(s *lib.TerraformState) string {
+	if s.WrittenAt == nil {
+		return "none"
+	}
+	text := fmt.Sprintf("serial %d, written %s", s.Serial, lib.FormatAge(*s.WrittenAt))
+	if s.WrittenBy != "" {
+		text += " by @" + s.WrittenBy
+	}
+	if s.Job != "" {
+		text += " in job " + s.Job
+	}
+	return text
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	rulesFile := flag.String("rules", "", "YAML triage ruleset (default: "+lib.TriageFileName+" at the repository root)")
+	labels := flag.String("labels", "", "Only triage issues with all of these comma-separated labels")
+	limit := flag.Int("limit", 0, "Only triage the N newest open issues (0 for all)")
+	dryRun := flag.Bool("dry-run", false, "Preview the changes without making them")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	rules, err := lib.LoadTriageRules(*rulesFile)
+	if err != nil {
+		lib.Exit("Error loading triage rules", err)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	opts := &lib.IssueListOptions{State: "opened", Limit: *limit}
+	if *labels != "" {
+		opts.Labels = lib.SplitList(*labels)
+	}
+	issues, err := client.ListProjectIssues(projectPath, opts)
+	if err != nil {
+		lib.Exit("Error listing issues", err)
+	}
+	plans := rules.Plan(issues, time.Now())
+
+	prefix := ""
+	if *dryRun {
+		prefix = "[dry-run] "
+	}
+
+	errs := make([]error, len(plans))
+	if !*dryRun {
+		errs = client.ForEach(len(plans), func(i int) error {
+			return client.ApplyTriage(projectPath, &plans[i])
+		})
+	}
+
+	// One issue that cannot be updated (e.g. a label was deleted) must not
+	// hold back the rest of the triage; it is listed on stderr instead
+	var firstErr error
+	for i, plan := range plans {
+		issue := plan.Issue
+		if errs[i] != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.Failure(fmt.Sprintf("triaging #%d: %v", issue.IID, errs[i])))
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+		if ui.Quiet {
+			fmt.Println(issue.IID)
+			continue
+		}
+		fmt.Printf("%s%s\n", prefix, ui.Success(fmt.Sprintf("#%d %s: %s (%s)", issue.IID, issue.Title, plan.Changes(), strings.Join(plan.Rules, ", "))))
+	}
+
+	ui.Printf("\n%s%s: triaged %d of %d open issue(s)\n", prefix, projectPath, len(plans), len(issues))
+
+	if firstErr != nil {
+		lib.ExitWith(lib.ExitCode(firstErr))
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"strconv"
 	"strings"
 
 	"gitlab-mr-helper/lib"
@@ -12,65 +11,131 @@ import (
 
 func main() {
 	// Flags
-	mrIID := flag.Int("mr", 0, "Merge request IID (required)")
+	mrFlag := lib.RegisterMRFlag("Merge request IID, web URL or source branch (required)")
 	title := flag.String("title", "", "New MR title")
 	description := flag.String("description", "", "New MR description")
+	descriptionFile := flag.String("description-file", "", "Read the new description from a file ('-' for stdin)")
+	edit := flag.Bool("edit", false, "Edit the current description in $EDITOR")
+	appendDesc := flag.Bool("append-description", false, "Append the new description text to the current description")
+	prependDesc := flag.Bool("prepend-description", false, "Prepend the new description text to the current description")
+	replaceSection := flag.String("replace-section", "", "Replace (or add) this markdown section with the new description text, e.g. '## Testing'")
 	targetBranch := flag.String("target", "", "New target branch")
 	labels := flag.String("labels", "", "Comma-separated labels (replaces existing)")
 	stateEvent := flag.String("state", "", "State event: close, reopen")
-	auto := flag.Bool("auto", false, "Auto-detect project from git remote")
+	skipSecretScan := flag.Bool("skip-secret-scan", false, "Update the MR even if its diff or new text contains likely credentials")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
 
 	flag.Parse()
+	defer lib.ReportStats()
 
-	// Validate MR IID
-	if *mrIID == 0 {
-		// Try to get from positional argument
-		if flag.NArg() > 0 {
-			iid, err := strconv.Atoi(flag.Arg(0))
-			if err == nil {
-				*mrIID = iid
-			}
+	// Validate MR reference
+	if err := mrFlag.Parse(); err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Check if any update fields provided
+	if *title == "" && *description == "" && *descriptionFile == "" && !*edit && *targetBranch == "" && *labels == "" && *stateEvent == "" {
+		lib.Usagef("at least one update field required (--title, --description, --description-file, --edit, --target, --labels, --state)")
+	}
+	sources := 0
+	for _, set := range []bool{*description != "", *descriptionFile != "", *edit} {
+		if set {
+			sources++
 		}
-		if *mrIID == 0 {
-			fmt.Fprintf(os.Stderr, "Error: --mr <iid> is required\n")
-			os.Exit(1)
+	}
+	if sources > 1 {
+		lib.Usagef("--description, --description-file and --edit are mutually exclusive")
+	}
+
+	newDescription := *description
+	if *descriptionFile != "" {
+		text, err := lib.ReadTextFile(*descriptionFile)
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+		if strings.TrimSpace(text) == "" {
+			lib.Usagef("description file %s is empty", *descriptionFile)
 		}
+		newDescription = text
 	}
 
-	// Check if any update fields provided
-	if *title == "" && *description == "" && *targetBranch == "" && *labels == "" && *stateEvent == "" {
-		fmt.Fprintf(os.Stderr, "Error: at least one update field required (--title, --description, --target, --labels, --state)\n")
-		os.Exit(1)
+	modes := 0
+	for _, set := range []bool{*appendDesc, *prependDesc, *replaceSection != "", *edit} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		lib.Usagef("--append-description, --prepend-description, --replace-section and --edit are mutually exclusive")
+	}
+	merge := *appendDesc || *prependDesc || *replaceSection != ""
+	if merge && newDescription == "" {
+		lib.Usagef("--append-description, --prepend-description and --replace-section need --description or --description-file")
 	}
 
 	// Get configuration
 	config, err := lib.GetConfig()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		lib.Exit("Error", err)
 	}
 
 	// Get project path
-	var projectPath string
-	if *auto {
-		projectPath, err = lib.GetProjectFromGit()
+	projectPath, detected, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	mrIID, err := mrFlag.Resolve(client, projectPath)
+	if err != nil {
+		lib.Exit("Error finding MR", err)
+	}
+
+	// Edit the current description, like git commit: an empty result aborts
+	if *edit {
+		current, err := client.GetMR(projectPath, mrIID)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error resolving project: %v\n", err)
-			os.Exit(1)
+			lib.Exit("Error getting MR", err)
 		}
-		fmt.Printf("✓ Project: %s\n", projectPath)
-	} else {
-		// Look for project in remaining args after MR IID
-		for i := 0; i < flag.NArg(); i++ {
-			arg := flag.Arg(i)
-			if _, err := strconv.Atoi(arg); err != nil {
-				projectPath = arg
-				break
-			}
+		text, err := lib.EditText(current.Description, fmt.Sprintf("mr-%d-description-*.md", mrIID))
+		if err != nil {
+			lib.Exit("Error editing description", err)
 		}
-		if projectPath == "" {
-			fmt.Fprintf(os.Stderr, "Error: project path required (use --auto or provide as argument)\n")
-			os.Exit(1)
+		switch {
+		case strings.TrimSpace(text) == "":
+			lib.Exit("Error", fmt.Errorf("empty description; update aborted"))
+		case strings.TrimRight(text, "\n") == strings.TrimRight(current.Description, "\n"):
+			fmt.Fprintln(os.Stderr, "Description unchanged")
+		default:
+			newDescription = text
+		}
+	}
+
+	// Merge the new text into the current description, keeping
+	// human-written content intact
+	if merge {
+		current, err := client.GetMR(projectPath, mrIID)
+		if err != nil {
+			lib.Exit("Error getting MR", err)
+		}
+		var merged string
+		switch {
+		case *appendDesc:
+			merged = lib.AppendText(current.Description, newDescription)
+		case *prependDesc:
+			merged = lib.PrependText(current.Description, newDescription)
+		default:
+			merged = lib.ReplaceSection(current.Description, *replaceSection, newDescription)
+		}
+		newDescription = merged
+		if strings.TrimRight(merged, "\n") == strings.TrimRight(current.Description, "\n") {
+			fmt.Fprintln(os.Stderr, "Description unchanged")
+			newDescription = ""
 		}
 	}
 
@@ -80,44 +145,86 @@ func main() {
 
 	if *title != "" {
 		req.Title = *title
-		updates = append(updates, fmt.Sprintf("title → %q", *title))
+		updates = append(updates, fmt.Sprintf("title "+lib.Arrow+" %q", *title))
 	}
-	if *description != "" {
-		req.Description = *description
+	if newDescription != "" {
+		req.Description = newDescription
 		updates = append(updates, "description updated")
 	}
 	if *targetBranch != "" {
 		req.TargetBranch = *targetBranch
-		updates = append(updates, fmt.Sprintf("target → %s", *targetBranch))
+		updates = append(updates, fmt.Sprintf("target "+lib.Arrow+" %s", *targetBranch))
 	}
 	if *labels != "" {
-		labelList := strings.Split(*labels, ",")
-		for i, l := range labelList {
-			labelList[i] = strings.TrimSpace(l)
-		}
-		req.Labels = labelList
-		updates = append(updates, fmt.Sprintf("labels → [%s]", *labels))
+		req.Labels = lib.SplitList(*labels)
+		updates = append(updates, fmt.Sprintf("labels "+lib.Arrow+" [%s]", *labels))
 	}
 	if *stateEvent != "" {
 		req.StateEvent = *stateEvent
-		updates = append(updates, fmt.Sprintf("state → %s", *stateEvent))
+		updates = append(updates, fmt.Sprintf("state "+lib.Arrow+" %s", *stateEvent))
+	}
+
+	if len(updates) == 0 {
+		// Only an unchanged description: nothing to submit
+		return
+	}
+
+	// Refuse to publish likely credentials in the new text or the MR's diff;
+	// closing an MR that leaks one stays possible
+	if !*skipSecretScan && req.StateEvent != "close" {
+		findings, err := scanSecrets(client, projectPath, mrIID, req)
+		if err != nil {
+			lib.Exit("Error scanning for secrets", err)
+		}
+		if len(findings) > 0 {
+			fmt.Fprintf(os.Stderr, "Secrets: %d likely credential(s):\n", len(findings))
+			for _, f := range findings {
+				fmt.Fprintf(os.Stderr, "  "+lib.Bullet+" %s\n", f)
+			}
+			lib.Exit("Error updating MR", fmt.Errorf("%w: likely secrets (remove them or pass --skip-secret-scan)", lib.ErrBlocked))
+		}
 	}
 
-	fmt.Printf("Updating MR !%d:\n", *mrIID)
+	ui.Printf("Updating MR !%d:\n", mrIID)
 	for _, u := range updates {
-		fmt.Printf("  • %s\n", u)
+		ui.Printf("  "+lib.Bullet+" %s\n", u)
 	}
 
-	// Create API client and update
-	client := lib.NewClient(config)
-	mr, err := client.UpdateMR(projectPath, *mrIID, req)
+	// Update
+	mr, err := client.UpdateMR(projectPath, mrIID, req)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error updating MR: %v\n", err)
-		os.Exit(1)
+		lib.Exit("Error updating MR", err)
+	}
+
+	if ui.Quiet {
+		fmt.Println(mr.WebURL)
+		return
 	}
 
-	fmt.Printf("\n✓ MR !%d updated successfully\n", mr.IID)
+	fmt.Printf("\n%s\n", ui.Success(fmt.Sprintf("MR !%d updated successfully", mr.IID)))
 	fmt.Printf("  Title: %s\n", mr.Title)
-	fmt.Printf("  State: %s\n", mr.State)
+	fmt.Printf("  State: %s\n", ui.State(mr.State))
 	fmt.Printf("  URL: %s\n", mr.WebURL)
 }
+
+// scanSecrets checks the MR's diff and the title and description being set
+// against the secret rules from settings
+func scanSecrets(client *lib.Client, projectPath string, mrIID int, req *lib.UpdateMRRequest) ([]lib.SecretFinding, error) {
+	settings, err := lib.LoadSettings()
+	if err != nil {
+		return nil, err
+	}
+	if settings.Secrets.Disabled {
+		return nil, nil
+	}
+	scanner, err := lib.NewSecretScanner(&settings.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	diffs, err := client.GetMRDiffs(projectPath, mrIID)
+	if err != nil {
+		return nil, err
+	}
+	lib.WarnPartialScan(diffs)
+	return scanner.ScanMR(diffs, req.Title, req.Description), nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	sourceBranch := flag.String("source", "", "Source branch (default: current branch)")
+	targetBranch := flag.String("target", "main", "Target branch")
+	title := flag.String("title", "", "MR title (default: derived from branch name on create, unchanged on update)")
+	description := flag.String("description", "", "MR description (unchanged on update when empty)")
+	labels := flag.String("labels", "", "Comma-separated labels (replaces existing on update)")
+	removeSource := flag.Bool("remove-source-branch", false, "Remove source branch after merge (create only)")
+	skipSecretScan := flag.Bool("skip-secret-scan", false, "Create or update the MR even if its diff or text contains likely credentials")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	source := *sourceBranch
+	if source == "" {
+		source, err = lib.GetCurrentBranch()
+		if err != nil {
+			lib.Exit("Error getting current branch", err)
+		}
+	}
+
+	// Parse labels
+	var labelList []string
+	if *labels != "" {
+		labelList = lib.SplitList(*labels)
+	}
+
+	settings, err := lib.LoadSettings()
+	if err != nil {
+		lib.Exit("Error loading settings", err)
+	}
+
+	// The title is derived up front, but only used when creating
+	mrTitle := *title
+	if mrTitle == "" {
+		mrTitle, err = lib.BuildTitle(source, settings.Title.Template)
+		if err != nil {
+			lib.Exit("Error generating title", err)
+		}
+	}
+	create := &lib.CreateMRRequest{
+		SourceBranch:       source,
+		TargetBranch:       *targetBranch,
+		Title:              mrTitle,
+		Description:        *description,
+		Labels:             labelList,
+		RemoveSourceBranch: *removeSource,
+	}
+	update := &lib.UpdateMRRequest{
+		Title:       *title,
+		Description: *description,
+		Labels:      labelList,
+	}
+
+	client := lib.NewClient(config)
+
+	// Refuse to publish likely credentials, as create_mr.go and update_mr.go do
+	if !*skipSecretScan && !settings.Secrets.Disabled {
+		scanner, err := lib.NewSecretScanner(&settings.Secrets)
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+		var diffs []lib.Diff
+		if cmp, err := client.CompareRefs(projectPath, *targetBranch, source); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not scan the diff for secrets: %v\n", err)
+		} else {
+			diffs = cmp.Diffs
+			lib.WarnPartialScan(diffs)
+		}
+		if findings := scanner.ScanMR(diffs, create.Title, create.Description); len(findings) > 0 {
+			fmt.Fprintf(os.Stderr, "Secrets: %d likely credential(s):\n", len(findings))
+			for _, f := range findings {
+				fmt.Fprintf(os.Stderr, "  "+lib.Bullet+" %s\n", f)
+			}
+			lib.Exit("Error creating or updating MR", fmt.Errorf("%w: likely secrets (remove them or pass --skip-secret-scan)", lib.ErrBlocked))
+		}
+	}
+
+	mr, action, err := client.UpsertMR(projectPath, create, update)
+	if err != nil {
+		lib.Exit("Error creating or updating MR", err)
+	}
+
+	switch action {
+	case lib.UpsertCreated:
+		ui.Printf("Created MR: %s "+lib.Arrow+" %s\n", source, *targetBranch)
+	case lib.UpsertUpdated:
+		ui.Printf("Updated MR !%d: %s "+lib.Arrow+" %s\n", mr.IID, source, *targetBranch)
+		if update.Title != "" {
+			ui.Printf("  "+lib.Bullet+" title "+lib.Arrow+" %q\n", update.Title)
+		}
+		if update.Description != "" {
+			ui.Println("  " + lib.Bullet + " description updated")
+		}
+		if update.Labels != nil {
+			ui.Printf("  "+lib.Bullet+" labels "+lib.Arrow+" [%s]\n", *labels)
+		}
+	}
+
+	if ui.Quiet {
+		fmt.Println(mr.WebURL)
+		return
+	}
+
+	fmt.Printf("\n%s\n", ui.Success(fmt.Sprintf("MR !%d %s", mr.IID, action)))
+	fmt.Printf("  Title: %s\n", mr.Title)
+	fmt.Printf("  URL: %s\n", mr.WebURL)
+	fmt.Printf("  State: %s\n", ui.State(mr.State))
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+// reportID tells the signature comment apart from other status comments
+const reportID = "signatures"
+
+func main() {
+	// Flags
+	mrFlag := lib.RegisterMRFlag("Merge request IID, web URL or source branch (required; in a merge request pipeline, that MR)")
+	allowUnverified := flag.Bool("allow-unverified", false, "Accept signed commits GitLab could not verify, e.g. by keys not added to GitLab")
+	comment := flag.Bool("comment", false, "Post the report as a comment when a commit fails, and keep an existing one up to date")
+	warnOnly := flag.Bool("warn-only", false, "Exit 0 even when commits are unsigned")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Validate MR reference
+	if err := mrFlag.Parse(); err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	mrIID, err := mrFlag.Resolve(client, projectPath)
+	if err != nil {
+		lib.Exit("Error finding MR", err)
+	}
+	mr, err := client.GetMR(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting MR", err)
+	}
+	commits, err := client.ListMRSignatures(projectPath, mrIID)
+	if err != nil {
+		lib.Exit("Error getting commit signatures", err)
+	}
+	if len(commits) == 0 {
+		ui.Printf("MR !%d has no commits\n", mr.IID)
+		return
+	}
+
+	report := &lib.StatusReport{ID: reportID, Title: "Commit signatures", UpdatedAt: time.Now()}
+	failed := 0
+	for i := range commits {
+		c := &commits[i]
+		passed := c.Verified() || (*allowUnverified && c.Signature != nil)
+		details := c.Status()
+		if c.Signature != nil {
+			details = c.Signature.SignatureType + ", " + details
+			if signer := c.Signature.Signer(); signer != "" {
+				details += ", " + signer
+			}
+		}
+		line := fmt.Sprintf("%s %s: %s", c.ShortID, c.Title, details)
+		switch {
+		case passed:
+			ui.Printf("  %s\n", ui.Success(line))
+		case ui.Quiet:
+			fmt.Println(c.ID)
+		default:
+			fmt.Printf("  %s\n", ui.Failure(line))
+		}
+
+		status := "passed"
+		if !passed {
+			status = "failed"
+			failed++
+		}
+		report.Rows = append(report.Rows, lib.StatusRow{Name: c.ShortID + " " + c.Title, Status: status, Details: details})
+	}
+
+	want := "verified"
+	if *allowUnverified {
+		want = "signed"
+	}
+	if failed == 0 {
+		ui.Printf("%s\n", ui.Success(fmt.Sprintf("MR !%d: all %d commit(s) %s", mr.IID, len(commits), want)))
+	} else {
+		ui.Printf("MR !%d: %d of %d commit(s) not %s\n", mr.IID, failed, len(commits), want)
+	}
+
+	if *comment {
+		note, _, err := client.UpsertStatusReport(projectPath, mr.IID, report, failed == 0)
+		if err != nil {
+			lib.Exit("Error writing signature comment", err)
+		}
+		if note != nil {
+			ui.Printf("  Comment: %s#note_%d\n", mr.WebURL, note.ID)
+		}
+	}
+
+	if failed > 0 && !*warnOnly {
+		lib.Exit("Error", fmt.Errorf("%w: MR !%d has %d commit(s) not %s", lib.ErrBlocked, mr.IID, failed, want))
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	state := flag.String("state", "detected,confirmed", "Comma-separated states to list: detected, confirmed, resolved, dismissed (\"all\" for every state)")
+	severity := flag.String("severity", "", "Comma-separated severities to list: critical, high, medium, low, info, unknown")
+	reportType := flag.String("report-type", "", "Comma-separated scanners to list: sast, dependency_scanning, secret_detection, container_scanning, dast, ...")
+	limit := flag.Int("limit", 50, "Maximum number of vulnerabilities to list (0 for all)")
+	pipeline := flag.Int("pipeline", 0, "List the findings of this pipeline's security reports instead")
+	confirm := flag.Int("confirm", 0, "Confirm the vulnerability with this ID")
+	resolve := flag.Int("resolve", 0, "Mark the vulnerability with this ID resolved")
+	dismiss := flag.Int("dismiss", 0, "Dismiss the vulnerability with this ID (with --reason)")
+	revert := flag.Int("revert", 0, "Revert the vulnerability with this ID to detected")
+	reason := flag.String("reason", "", "Dismissal reason: "+strings.Join(lib.DismissalReasons, ", "))
+	comment := flag.String("comment", "", "Comment recorded with a state change")
+	createIssue := flag.Int("create-issue", 0, "Open a remediation issue for the vulnerability with this ID and link it")
+	labels := flag.String("labels", "security", "Comma-separated labels of the issue created by --create-issue")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// The state to move a vulnerability to, if any
+	var id int
+	var target string
+	actions := 0
+	for s, v := range map[string]int{lib.VulnerabilityConfirmed: *confirm, lib.VulnerabilityResolved: *resolve, lib.VulnerabilityDismissed: *dismiss, lib.VulnerabilityDetected: *revert, "issue": *createIssue} {
+		if v != 0 {
+			id, target = v, s
+			actions++
+		}
+	}
+	if actions > 1 {
+		lib.Usagef("--confirm, --resolve, --dismiss, --revert and --create-issue are mutually exclusive")
+	}
+	if *dismiss == 0 && *reason != "" {
+		lib.Usagef("--reason needs --dismiss")
+	}
+	if *dismiss != 0 && !validReason(*reason) {
+		lib.Usagef("--dismiss needs --reason (%s)", strings.Join(lib.DismissalReasons, ", "))
+	}
+	if *comment != "" && (actions == 0 || *createIssue != 0) {
+		lib.Usagef("--comment needs --confirm, --resolve, --dismiss or --revert")
+	}
+	if *limit < 0 {
+		lib.Usagef("--limit must not be negative")
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+
+	switch {
+	case target == "issue":
+		openIssue(client, ui, projectPath, id, lib.SplitList(*labels))
+		return
+	case target != "":
+		v, err := client.SetVulnerabilityState(id, target, *reason, *comment)
+		if err != nil {
+			lib.Exit(fmt.Sprintf("Error updating vulnerability #%d", id), err)
+		}
+		if ui.Quiet {
+			fmt.Println(v.ID)
+			return
+		}
+		fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Vulnerability #%d %s", v.ID, v.State)))
+		fmt.Printf("  Title: %s\n", v.Title)
+		if v.DismissalReason != "" {
+			fmt.Printf("  Reason: %s\n", v.DismissalReason)
+		}
+		fmt.Printf("  URL: %s\n", v.WebURL)
+		return
+	case *pipeline != 0:
+		listFindings(client, ui, projectPath, *pipeline, lib.SplitList(strings.ToLower(*severity)), lib.SplitList(strings.ToLower(*reportType)))
+		return
+	}
+
+	opts := &lib.VulnerabilityListOptions{Severities: lib.SplitList(strings.ToLower(*severity)), ReportTypes: lib.SplitList(strings.ToLower(*reportType)), Limit: *limit}
+	if *state != "all" {
+		opts.States = lib.SplitList(strings.ToLower(*state))
+	}
+	vulns, err := client.ListVulnerabilities(projectPath, opts)
+	if err != nil {
+		lib.Exit("Error listing vulnerabilities", err)
+	}
+
+	if ui.Quiet {
+		for _, v := range vulns {
+			fmt.Println(v.ID)
+		}
+		return
+	}
+	if len(vulns) == 0 {
+		fmt.Println("No vulnerabilities found")
+		return
+	}
+	for _, v := range vulns {
+		fmt.Printf("  #%d  [%s] %s (%s, %s)\n", v.ID, v.Severity, v.Title, v.ReportType, v.State)
+		details := []string{v.Identifier, v.Scanner, "detected " + v.DetectedAt.Local().Format("2006-01-02")}
+		if v.DismissalReason != "" {
+			details = append(details, "dismissed as "+v.DismissalReason)
+		}
+		fmt.Printf("       %s\n", strings.Join(nonEmpty(details), " "+lib.Dot+" "))
+		for _, i := range v.Issues {
+			fmt.Printf("       Issue: #%d %s\n", i.IID, i.WebURL)
+		}
+	}
+	fmt.Printf("\nTotal: %d vulnerabilities\n", len(vulns))
+}
+
+// listFindings prints the findings of a pipeline's security reports
+func listFindings(client *lib.Client, ui *lib.UI, projectPath string, pipelineID int, severities, types []string) {
+	findings, err := client.ListPipelineFindings(projectPath, pipelineID, severities, types)
+	if err != nil {
+		lib.Exit("Error listing findings", err)
+	}
+	if ui.Quiet {
+		for _, f := range findings {
+			fmt.Println(f.UUID)
+		}
+		return
+	}
+	if len(findings) == 0 {
+		fmt.Printf("No findings in pipeline #%d\n", pipelineID)
+		return
+	}
+	fmt.Printf("Findings of pipeline #%d:\n", pipelineID)
+	for _, f := range findings {
+		fmt.Printf("  [%s] %s (%s, %s)\n", f.Severity, f.Name, f.ReportType, f.Scanner.Name)
+		if where := f.Where(); where != "" {
+			fmt.Printf("       %s\n", where)
+		}
+	}
+	fmt.Printf("\nTotal: %d findings\n", len(findings))
+}
+
+// openIssue creates a remediation issue for a vulnerability and links it
+func openIssue(client *lib.Client, ui *lib.UI, projectPath string, id int, labels []string) {
+	vulns, err := client.ListVulnerabilities(projectPath, nil)
+	if err != nil {
+		lib.Exit("Error listing vulnerabilities", err)
+	}
+	var v *lib.Vulnerability
+	for i := range vulns {
+		if vulns[i].ID == id {
+			v = &vulns[i]
+		}
+	}
+	if v == nil {
+		lib.Exit("Error", fmt.Errorf("%w: vulnerability #%d in %s", lib.ErrNotFound, id, projectPath))
+	}
+	if len(v.Issues) > 0 {
+		lib.Exit("Error", fmt.Errorf("%w: vulnerability #%d is already linked to issue #%d (%s)", lib.ErrBlocked, id, v.Issues[0].IID, v.Issues[0].WebURL))
+	}
+
+	var desc strings.Builder
+	fmt.Fprintf(&desc, "Remediate [%s](%s).\n\n", v.Title, v.WebURL)
+	fmt.Fprintf(&desc, "- Severity: %s\n- Report type: %s\n", v.Severity, v.ReportType)
+	if v.Identifier != "" {
+		fmt.Fprintf(&desc, "- Identifier: %s\n", v.Identifier)
+	}
+	if v.Scanner != "" {
+		fmt.Fprintf(&desc, "- Scanner: %s\n", v.Scanner)
+	}
+	fmt.Fprintf(&desc, "- Detected: %s\n", v.DetectedAt.UTC().Format("2006-01-02"))
+
+	issue, err := client.CreateIssue(projectPath, &lib.CreateIssueRequest{
+		Title:       "Resolve vulnerability: " + v.Title,
+		Description: desc.String(),
+		Labels:      labels,
+	})
+	if err != nil {
+		lib.Exit("Error creating issue", err)
+	}
+	if err := client.LinkVulnerabilityIssue(issue.ID, v.ID); err != nil {
+		lib.Exit(fmt.Sprintf("Error linking issue #%d", issue.IID), err)
+	}
+
+	if ui.Quiet {
+		fmt.Println(issue.WebURL)
+		return
+	}
+	fmt.Printf("%s\n", ui.Success(fmt.Sprintf("Issue #%d opened for vulnerability #%d", issue.IID, v.ID)))
+	fmt.Printf("  Title: %s\n", issue.Title)
+	fmt.Printf("  URL: %s\n", issue.WebURL)
+}
+
+func validReason(reason string) bool {
+	for _, r := range lib.DismissalReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+func main() {
+	// Flags
+	pipelineID := flag.Int("pipeline", 0, "Pipeline ID to wait for")
+	mrFlag := lib.RegisterMRFlag("Wait for the pipeline of this merge request's HEAD (IID, web URL or source branch)")
+	ref := flag.String("ref", "", "Wait for the latest pipeline of this branch or tag (default: the current git branch)")
+	timeout := flag.Duration("timeout", time.Hour, "Give up after this long, with exit code 6 (0 to wait forever)")
+	interval := flag.Duration("interval", 5*time.Second, "Shortest wait between checks, used as the pipeline nears its end")
+	maxInterval := flag.Duration("max-interval", 2*time.Minute, "Longest wait between checks, while the pipeline is pending or far from done")
+	notify := flag.Bool("notify", false, "Post the final status to the configured Slack/Mattermost webhook when the pipeline finishes")
+	projectFlags := lib.RegisterProjectFlags()
+	ui := lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	targets := 0
+	for _, set := range []bool{*pipelineID != 0, mrFlag.Value != "", *ref != ""} {
+		if set {
+			targets++
+		}
+	}
+	if targets > 1 {
+		lib.Usagef("--pipeline, --mr and --ref are mutually exclusive")
+	}
+	if mrFlag.Value != "" {
+		if err := mrFlag.Parse(); err != nil {
+			lib.Exit("Error", err)
+		}
+	}
+	if *interval <= 0 || *maxInterval < *interval {
+		lib.Usagef("--interval must be positive and --max-interval at least --interval")
+	}
+	if *timeout < 0 {
+		lib.Usagef("--timeout must not be negative")
+	}
+	if targets == 0 {
+		branch, err := lib.GetCurrentBranch()
+		if err != nil {
+			lib.Usagef("--pipeline, --mr or --ref is required outside a git checkout")
+		}
+		*ref = branch
+	}
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	var notifier *lib.Notifier
+	if *notify {
+		settings, err := lib.LoadSettings()
+		if err != nil {
+			lib.Exit("Error loading settings", err)
+		}
+		notifier = lib.NewNotifier(settings.Notify)
+		if notifier == nil {
+			lib.Usagef("--notify needs notify.webhook_url in settings or GITLAB_NOTIFY_WEBHOOK")
+		}
+	}
+
+	// Get project path
+	projectPath, detected, err := projectFlags.Resolve(mrFlag.ProjectArg())
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+	if detected {
+		ui.Printf("%s\n", ui.Success("Project: "+projectPath))
+	}
+
+	client := lib.NewClient(config)
+	var mrIID int
+	if mrFlag.Value != "" {
+		if mrIID, err = mrFlag.Resolve(client, projectPath); err != nil {
+			lib.Exit("Error finding MR", err)
+		}
+	}
+
+	// current returns the pipeline waited for, nil while it does not exist
+	// yet (e.g. right after a push)
+	current := func() (*lib.Pipeline, error) {
+		switch {
+		case *pipelineID != 0:
+			return client.GetPipeline(projectPath, *pipelineID)
+		case mrIID != 0:
+			mr, err := client.GetMR(projectPath, mrIID)
+			if err != nil || mr.HeadPipeline == nil || mr.HeadPipeline.SHA != mr.SHA {
+				return nil, err
+			}
+			return mr.HeadPipeline, nil
+		}
+		pipelines, err := client.ListPipelines(projectPath, &lib.PipelineListOptions{Ref: *ref, Limit: 1})
+		if err != nil || len(pipelines) == 0 {
+			return nil, err
+		}
+		// Listings have no start time
+		return client.GetPipeline(projectPath, pipelines[0].ID)
+	}
+
+	var p *lib.Pipeline
+	var typical time.Duration
+	reported := ""
+	poller := lib.Poller{Interval: *interval, MaxInterval: *maxInterval, Timeout: *timeout}
+	err = poller.Poll(func() (bool, time.Duration, error) {
+		next, err := current()
+		if err != nil || next == nil {
+			return false, lib.PollBackoff, err
+		}
+		if p == nil || next.ID != p.ID {
+			// Paced by how long the ref's pipelines usually take
+			if typical, err = client.TypicalPipelineDuration(projectPath, next.Ref); err != nil {
+				return false, 0, err
+			}
+		}
+		p = next
+		if status := fmt.Sprintf("#%d %s", p.ID, p.Status); status != reported {
+			ui.Printf("Pipeline %s (%s)\n", status, p.Ref)
+			reported = status
+		}
+		return lib.PipelineFinished(p.Status), lib.PipelineWait(p, typical, time.Now()), nil
+	})
+	if err != nil {
+		lib.Exit("Error waiting for pipeline", err)
+	}
+
+	if ui.Quiet {
+		fmt.Println(p.Status)
+	}
+	if notifier != nil {
+		title := fmt.Sprintf("wait_pipeline.go: pipeline #%d %s on %s in %s", p.ID, p.Status, p.Ref, projectPath)
+		success := p.Status == "success" || p.Status == "skipped"
+		if err := notifier.Send(lib.Notification{Title: title, URL: p.WebURL, Success: success}); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	switch p.Status {
+	case "success", "skipped":
+		ui.Printf("%s\n", ui.Success(fmt.Sprintf("Pipeline #%d %s: %s", p.ID, p.Status, p.WebURL)))
+	case "manual":
+		lib.Exit("Error", fmt.Errorf("%w: pipeline #%d waits for a manual job: %s", lib.ErrBlocked, p.ID, p.WebURL))
+	default:
+		lib.Exit("Error", fmt.Errorf("pipeline #%d %s: %s", p.ID, p.Status, p.WebURL))
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"gitlab-mr-helper/lib"
+)
+
+// finishedStatuses are the pipeline statuses worth an event
+var finishedStatuses = map[string]bool{"success": true, "failed": true, "canceled": true, "skipped": true}
+
+func main() {
+	// Flags
+	interval := flag.Duration("interval", 30*time.Second, "Polling interval")
+	once := flag.Bool("once", false, "Poll once and exit (for cron or agent loops)")
+	filter := flag.String("events", "", "Comma-separated kinds or kind:action to emit, e.g. 'pipeline:failed,merge_request:open' (default: all)")
+	stateFile := flag.String("state-file", "", "File remembering emitted events (default: under the user cache directory)")
+	rulesFile := flag.String("rules", "", "YAML ruleset run on every event (default: "+lib.RulesFileName+" at the repository root, if any)")
+	projectFlags := lib.RegisterProjectFlags()
+	lib.RegisterUIFlags()
+	lib.RegisterConfigFlags()
+
+	flag.Parse()
+	defer lib.ReportStats()
+
+	// Get configuration
+	config, err := lib.GetConfig()
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	// Get project path
+	projectPath, _, err := projectFlags.Resolve(flag.Arg(0))
+	if err != nil {
+		lib.Exit("Error resolving project", err)
+	}
+
+	statePath := *stateFile
+	if statePath == "" {
+		statePath, err = lib.WatchStatePath(config.URL, projectPath)
+		if err != nil {
+			lib.Exit("Error", err)
+		}
+	}
+	state, err := lib.LoadWatchState(statePath)
+	if err != nil {
+		lib.Exit("Error", err)
+	}
+
+	rules, err := lib.LoadRules(*rulesFile)
+	if err != nil {
+		lib.Exit("Error loading rules", err)
+	}
+
+	wanted := make(map[string]bool)
+	for _, f := range lib.SplitList(*filter) {
+		wanted[f] = true
+	}
+
+	client := lib.NewClient(config)
+	// Listings whose URL repeats (the day's events, label events of an MR)
+	// are conditional requests, answered 304 when nothing changed
+	client.UseETagCache(state.Responses)
+	enc := json.NewEncoder(os.Stdout)
+	// Rules see every event, --events only filters the output
+	emit := func(ev *lib.WebhookEvent) {
+		applied, err := rules.Apply(client, ev)
+		if len(applied) > 0 {
+			fmt.Fprintf(os.Stderr, "Rules applied to %s:%s event: %s\n", ev.Kind, ev.Action, strings.Join(applied, ", "))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: rules: %v\n", err)
+		}
+
+		if len(wanted) > 0 && !wanted[ev.Kind] && !wanted[ev.Kind+":"+ev.Action] {
+			return
+		}
+		if err := enc.Encode(ev); err != nil {
+			lib.Exit("Error writing event", err)
+		}
+	}
+	mrURL := func(iid int) string {
+		return fmt.Sprintf("%s/%s/-/merge_requests/%d", config.URL, projectPath, iid)
+	}
+
+	poll := func() error {
+		now := time.Now().UTC()
+		// Nothing older than the seen keys is listed or emitted again
+		updatedAfter := state.UpdatedSince(now.Add(-lib.WatchHorizon))
+
+		// The events API filters by day, so look back one day and rely on
+		// the seen keys for exact de-duplication
+		events, err := client.ListProjectEvents(projectPath, &lib.EventListOptions{After: updatedAfter.AddDate(0, 0, -1), Limit: 100})
+		if err != nil {
+			return fmt.Errorf("failed to list events: %w", err)
+		}
+		for i := len(events) - 1; i >= 0; i-- {
+			e := &events[i]
+			if !state.Fresh(fmt.Sprintf("event:%d", e.ID), e.CreatedAt, now) {
+				continue
+			}
+			if ev := lib.WebhookEventFromEvent(projectPath, e, mrURL); ev != nil {
+				emit(ev)
+			}
+		}
+
+		// Label changes are not in the activity feed; they are read from
+		// the MRs updated since the last poll
+		updated, err := client.ListProjectMRs(projectPath, &lib.MRListOptions{State: "opened", UpdatedAfter: updatedAfter})
+		if err != nil {
+			return fmt.Errorf("failed to list merge requests: %w", err)
+		}
+		for i := range updated {
+			mr := &updated[i]
+			labelEvents, err := client.ListMRLabelEvents(projectPath, mr.IID)
+			if err != nil {
+				return fmt.Errorf("failed to list label events of !%d: %w", mr.IID, err)
+			}
+			for j := range labelEvents {
+				e := &labelEvents[j]
+				if !state.Fresh(fmt.Sprintf("label:%d", e.ID), e.CreatedAt, now) {
+					continue
+				}
+				if ev := lib.WebhookEventFromLabelEvent(projectPath, mr, e); ev != nil {
+					emit(ev)
+				}
+			}
+		}
+
+		pipelines, err := client.ListPipelines(projectPath, &lib.PipelineListOptions{UpdatedAfter: updatedAfter, Limit: 20})
+		if err != nil {
+			return fmt.Errorf("failed to list pipelines: %w", err)
+		}
+		for i := len(pipelines) - 1; i >= 0; i-- {
+			p := &pipelines[i]
+			if finishedStatuses[p.Status] && state.Fresh(fmt.Sprintf("pipeline:%d:%s", p.ID, p.Status), p.UpdatedAt, now) {
+				emit(lib.WebhookEventFromPipeline(projectPath, p))
+			}
+		}
+
+		state.Polled = now
+		state.Prune(now.Add(-lib.WatchHorizon))
+		// Delta listings have a new URL every poll, so responses are only
+		// worth keeping while they may be asked for again
+		state.Responses.Prune(now.Add(-time.Hour))
+		return state.Save(statePath)
+	}
+
+	if *once {
+		if err := poll(); err != nil {
+			lib.Exit("Error", err)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Fprintf(os.Stderr, "Watching %s every %s\n", projectPath, *interval)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		// Transient API failures are reported and retried on the next tick
+		if err := poll(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}